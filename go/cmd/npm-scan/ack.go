@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
+)
+
+var (
+	ackStatusFlag string
+	ackTicketFlag string
+	ackNoteFlag   string
+)
+
+var ackCmd = &cobra.Command{
+	Use:   "ack <fingerprint>",
+	Short: "Record the remediation status of a finding",
+	Long: `Ack records the triage state of a finding, identified by the fingerprint
+shown in scan reports, in the remediation store.
+
+Subsequent scans annotate the finding with its status and ticket, so
+repeated reports show remediation progress.
+
+Statuses:
+  open            Not yet triaged
+  in-progress     Being remediated (default)
+  fixed           Remediated; the finding should disappear on the next scan
+  accepted-risk   Known and accepted
+  false-positive  Not actually affected`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAck,
+}
+
+func init() {
+	rootCmd.AddCommand(ackCmd)
+
+	ackCmd.Flags().StringVar(&ackStatusFlag, "status", remediation.StatusInProgress, "Remediation status")
+	ackCmd.Flags().StringVar(&ackTicketFlag, "ticket", "", "Tracking ticket, e.g. JIRA-123")
+	ackCmd.Flags().StringVar(&ackNoteFlag, "note", "", "Free-form note")
+	ackCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store file")
+}
+
+func runAck(cmd *cobra.Command, args []string) error {
+	fingerprint := args[0]
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	entry, err := store.Set(fingerprint, ackStatusFlag, ackTicketFlag, ackNoteFlag, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	if err := store.Save(remediationFileFlag); err != nil {
+		return fmt.Errorf("failed to save remediation store: %w", err)
+	}
+
+	if entry.Ticket != "" {
+		fmt.Printf("%s: %s (%s)\n", fingerprint, entry.Status, entry.Ticket)
+	} else {
+		fmt.Printf("%s: %s\n", fingerprint, entry.Status)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
+)
+
+var (
+	allowFlag     []string
+	allowlistFlag string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, imageCmd, watchCmd} {
+		cmd.Flags().StringArrayVar(&allowFlag, "allow", nil, "Approve a package name or scope (e.g. 'lodash', '@mycorp'); with any approval given, every other package is reported as UNAPPROVED and fails the scan (repeatable)")
+		cmd.Flags().StringVar(&allowlistFlag, "allowlist", "", "File of approved package names and scopes, one per line (see --allow)")
+	}
+}
+
+// loadAllowlist parses the --allowlist file and --allow entries, or
+// returns nil, leaving strict mode off, if neither is given.
+func loadAllowlist() (*allowlist.Allowlist, error) {
+	if allowlistFlag == "" && len(allowFlag) == 0 {
+		return nil, nil
+	}
+	return allowlist.Load(allowlistFlag, allowFlag)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/suppress"
+)
+
+var (
+	baselineReasonFlag  string
+	baselineExpiresFlag string
+)
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline [path...]",
+	Short: "Write the current findings to the suppression file",
+	Long: `Baseline scans the given paths (default: current directory) and adds a
+rule for every matched package version to the suppression file, so known
+findings stop failing builds while new ones still do.
+
+Existing rules are kept; findings already covered are not added again.
+Suppressed matches are still reported, in a separate "suppressed" section.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runBaseline,
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+
+	baselineCmd.Flags().StringVarP(&ignoreFileFlag, "output", "o", suppress.DefaultPath, "Suppression file to create or extend")
+	baselineCmd.Flags().StringVar(&baselineReasonFlag, "reason", "", "Reason recorded on the new rules")
+	baselineCmd.Flags().StringVar(&baselineExpiresFlag, "expires", "", "Expiry date of the new rules (YYYY-MM-DD)")
+	baselineCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only scan lockfiles, skip package.json")
+	baselineCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	baselineCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	baselineCmd.Flags().StringVar(&sourceFlag, "source", ioc.SourceCSV, "IoC sources: csv (shai-hulud list), osv (OSV.dev, exact versions only), ghsa (GitHub Advisory Database), or a comma-separated list such as csv,osv")
+	baselineCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+}
+
+func runBaseline(cmd *cobra.Command, args []string) error {
+	scanPaths := []string{"."}
+	if len(args) > 0 {
+		scanPaths = args
+	}
+	for _, scanPath := range scanPaths {
+		if _, err := os.Stat(scanPath); os.IsNotExist(err) {
+			return fmt.Errorf("path does not exist: %s", scanPath)
+		}
+	}
+
+	var expires time.Time
+	if baselineExpiresFlag != "" {
+		var err error
+		expires, err = time.Parse("2006-01-02", baselineExpiresFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --expires %q: expected YYYY-MM-DD", baselineExpiresFlag)
+		}
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	file, err := suppress.Load(ignoreFileFlag)
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	for _, scanPath := range scanPaths {
+		result, err := scanner.RunScan(scanner.ScanOptions{
+			Path:            scanPath,
+			CSVURL:          csvURLFlag,
+			CSVHeader:       csvHeader,
+			Offline:         offlineFlag,
+			DatabaseCache:   dbCache(),
+			FeedCheck:       feedCheck(),
+			Source:          sourceFlag,
+			LockfileOnly:    lockfileOnlyFlag,
+			Since:           since,
+			SkipGitMetadata: true,
+			Context:         context.Background(),
+		})
+		if err != nil {
+			return fmt.Errorf("scan of %s failed: %w", scanPath, err)
+		}
+
+		for _, match := range result.Matches {
+			rule := suppress.Rule{
+				Package: match.PackageName,
+				Version: match.Version,
+				Reason:  baselineReasonFlag,
+				Expires: expires,
+			}
+			if file.Add(rule) {
+				added++
+			}
+		}
+	}
+
+	if err := file.Save(ignoreFileFlag); err != nil {
+		return fmt.Errorf("failed to write suppression file: %w", err)
+	}
+
+	fmt.Printf("Added %d rules to %s (%d total)\n", added, ignoreFileFlag, len(file.Rules))
+	return nil
+}
@@ -2,14 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
 
 	"github.com/spf13/cobra"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/bulk"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
 )
 
 var (
 	bulkWorkersFlag  int
 	bulkOutputDirFlag string
+	bulkAlertFlag     string
+	bulkWritersFlag   int
+	bulkSyncFlag      int
+	bulkSkipFlag      string
+	bulkOrderFlag     string
 )
 
 var bulkCmd = &cobra.Command{
@@ -31,7 +43,19 @@ Example paths.txt:
 Results are written to a timestamped directory with:
   - Individual JSON result files for each path
   - Log files capturing scan output
-  - summary.json with aggregate statistics`,
+  - summary.json with aggregate statistics
+
+With --alert, the run is compared with the previous run in the same output
+directory and alerts.json lists the rules that fired, so recurring (cron)
+scans only need to notify when findings change.
+
+With --skip-unchanged <previous-run>, paths whose manifests and lockfiles
+have the same fingerprint as in that run reuse its result instead of being
+scanned again. Reused results are not checked against IoC entries added
+since, so schedule periodic full runs.
+
+With --read-only, an explicit --output is required and nothing is written
+outside it.`,
 	Args: cobra.ExactArgs(1),
 	RunE: runBulkScan,
 }
@@ -41,23 +65,133 @@ func init() {
 
 	bulkCmd.Flags().IntVar(&bulkWorkersFlag, "workers", 4, "Number of concurrent workers")
 	bulkCmd.Flags().StringVar(&bulkOutputDirFlag, "output", "results", "Output directory for results")
+	bulkCmd.Flags().IntVar(&bulkWritersFlag, "writers", 0, "Number of concurrent result writers (default: --workers)")
+	bulkCmd.Flags().IntVar(&bulkSyncFlag, "fsync-batch", 0, "Fsync result files in batches of this many files (0 disables fsync)")
+	bulkCmd.Flags().StringVar(&bulkOrderFlag, "order", bulk.OrderFile, "Order of paths with equal priority: file, or matches (most matches in the previous run first)")
+	bulkCmd.Flags().StringVar(&bulkSkipFlag, "skip-unchanged", "", "Reuse results from this previous run directory for paths whose dependency files are unchanged")
+	bulkCmd.Flags().StringVar(&bulkAlertFlag, "alert", "", "Alert rules checked against the previous run: new-direct, increase:<percent> (comma-separated)")
 
 	// Inherit CSV URL and lockfile-only flags from root
 	bulkCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	bulkCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	bulkCmd.Flags().StringVar(&sourceFlag, "source", ioc.SourceCSV, "IoC sources: csv, osv, ghsa, or a comma-separated list such as csv,osv")
 	bulkCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only scan lockfiles")
+	bulkCmd.Flags().BoolVar(&followSymlinksFlag, "follow-symlinks", false, "Descend into symlinked directories, visiting each directory once")
+	bulkCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip paths matching this gitignore-style pattern in every project, in addition to its .npmscanignore (repeatable)")
+	bulkCmd.Flags().StringArrayVar(&metaFlag, "meta", nil, "Embed key=value metadata in JSON results (repeatable)")
+	bulkCmd.Flags().BoolVar(&noGitMetaFlag, "no-git-metadata", false, "Do not record the git remote, branch and HEAD commit of scanned paths")
+	bulkCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to annotate findings (see npm-scan ack)")
+	bulkCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	bulkCmd.Flags().BoolVar(&softFailFlag, "soft-fail-fetch", false, "Report paths as not scanned when the IoC database cannot be fetched, and exit 3")
+	bulkCmd.Flags().BoolVar(&noProgressFlag, "no-progress", false, "Do not draw a progress bar on stderr (drawn only when stderr is a terminal)")
+	bulkCmd.Flags().StringVar(&timezoneFlag, "timezone", "UTC", "Time zone of the summary and result timestamps: UTC, Local or an IANA zone name such as Europe/Berlin")
 }
 
 func runBulkScan(cmd *cobra.Command, args []string) error {
 	pathsFile := args[0]
 
+	// Read-only mode only writes results to an explicitly given directory
+	if readonly.Enabled() {
+		if !cmd.Flags().Changed("output") {
+			return fmt.Errorf("--read-only requires an explicit --output directory for bulk results")
+		}
+		if err := readonly.Enable(bulkOutputDirFlag); err != nil {
+			return err
+		}
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := formatter.ParseMetadata(metaFlag)
+	if err != nil {
+		return err
+	}
+
+	alertRules, err := bulk.ParseAlertRules(bulkAlertFlag)
+	if err != nil {
+		return err
+	}
+
+	loc, err := formatter.ParseTimezone(timezoneFlag)
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	ioThrottle, err := newThrottle()
+	if err != nil {
+		return err
+	}
+
+	watched, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	required, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	approved, err := loadAllowlist()
+	if err != nil {
+		return err
+	}
+
+	detector, err := loadTyposquat()
+	if err != nil {
+		return err
+	}
+
+	bar := newProgressBar(noProgressFlag)
+	defer bar.Finish()
+
 	options := bulk.BulkOptions{
-		PathsFile:    pathsFile,
-		OutputDir:    bulkOutputDirFlag,
-		NumWorkers:   bulkWorkersFlag,
-		CSVURL:       csvURLFlag,
-		LockfileOnly: lockfileOnlyFlag,
-		Context:      context.Background(),
+		PathsFile:       pathsFile,
+		OutputDir:       bulkOutputDirFlag,
+		NumWorkers:      bulkWorkersFlag,
+		NumWriters:      bulkWritersFlag,
+		SyncBatch:       bulkSyncFlag,
+		CSVURL:          csvURLFlag,
+		CSVHeader:       csvHeader,
+		Offline:         offlineFlag,
+		DatabaseCache:   dbCache(),
+		FeedCheck:       feedCheck(),
+		Source:          sourceFlag,
+		LockfileOnly:    lockfileOnlyFlag,
+		Exclude:         excludeFlag,
+		FollowSymlinks:  followSymlinksFlag,
+		Throttle:        ioThrottle,
+		Watchlist:       watched,
+		Policy:          required,
+		Allowlist:       approved,
+		Typosquat:       detector,
+		Cache:           openCache(),
+		Since:           since,
+		Metadata:        metadata,
+		Location:        loc,
+		SoftFailFetch:   softFailFlag,
+		SkipGitMetadata: noGitMetaFlag,
+		Remediation:     store,
+		Order:           bulkOrderFlag,
+		SkipUnchanged:   bulkSkipFlag,
+		AlertRules:      alertRules,
+		Progress:        bar.Func(),
+		Output:          bar.Writer(),
+		Context:         context.Background(),
 	}
 
-	return bulk.RunBulkScan(options)
+	err = bulk.RunBulkScan(options)
+	if errors.Is(err, bulk.ErrNotScanned) {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		os.Exit(exitNotScanned)
+	}
+	return err
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var (
+	noCacheFlag  bool
+	cacheDirFlag string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, watchCmd} {
+		cmd.Flags().BoolVar(&noCacheFlag, "no-cache", false, "Scan every file instead of reusing cached results for files unchanged since an earlier scan against the same IoC database")
+		cmd.Flags().StringVar(&cacheDirFlag, "cache-dir", "", "Directory of the scan result cache (default: npm-scan/results in the user cache directory)")
+	}
+}
+
+// openCache returns the result cache selected by --cache-dir, or nil if
+// --no-cache or --read-only is set or there is no user cache directory to
+// default to.
+func openCache() *scanner.ResultCache {
+	if noCacheFlag || readonly.Enabled() {
+		return nil
+	}
+	dir := cacheDirFlag
+	if dir == "" {
+		var err error
+		if dir, err = scanner.DefaultCacheDir(); err != nil {
+			return nil
+		}
+	}
+	return scanner.OpenResultCache(dir)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check <package@version>...",
+	Short: "Check whether specific package versions are compromised",
+	Long: `Check looks up package versions in the IoC database without scanning a
+project, for quick lookups and scripting:
+
+  npm-scan check lodash@4.17.19 @ctrl/tinycolor@4.1.1
+
+Each argument is a package name and a concrete version; scoped names are
+supported. The exit code is 1 if any version is compromised and 0 if none is.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+
+	checkCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL (default: official repository)")
+	checkCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	checkCmd.Flags().StringVar(&sourceFlag, "source", ioc.SourceCSV, "IoC sources: csv (shai-hulud list), osv (OSV.dev), ghsa (GitHub Advisory Database), or a comma-separated list such as csv,osv")
+	checkCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	checkCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+	checkCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+}
+
+func runCheck(cmd *cobra.Command, args []string) error {
+	packages := make([]ioc.PackageVersion, 0, len(args))
+	for _, arg := range args {
+		pkg, err := parsePackageVersion(arg)
+		if err != nil {
+			return err
+		}
+		packages = append(packages, pkg)
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	checks, err := scanner.CheckPackages(scanner.ScanOptions{
+		CSVURL:        csvURLFlag,
+		CSVHeader:     csvHeader,
+		Offline:       offlineFlag,
+		DatabaseCache: dbCache(),
+		Source:        sourceFlag,
+		FeedCheck:     feedCheck(),
+		Since:         since,
+		Verbose:       verboseFlag && !jsonFlag,
+	}, packages)
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		output, err := formatter.FormatJSONChecks(checks)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(output)
+	} else {
+		fmt.Print(formatter.FormatHumanChecks(checks))
+	}
+
+	for _, check := range checks {
+		if check.Compromised {
+			os.Exit(1)
+		}
+	}
+	return nil
+}
+
+// parsePackageVersion parses a name@version argument. The version is taken
+// after the last @, so scoped names keep their leading @.
+func parsePackageVersion(arg string) (ioc.PackageVersion, error) {
+	at := strings.LastIndex(arg, "@")
+	if at <= 0 || at == len(arg)-1 {
+		return ioc.PackageVersion{}, fmt.Errorf("invalid package %q (expected name@version)", arg)
+	}
+	return ioc.PackageVersion{Name: arg[:at], Version: arg[at+1:]}, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/config"
+)
+
+var configFlag string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&configFlag, "config", "", "Config file (default: "+config.FileName+" in the current directory or a parent, up to the repository root)")
+}
+
+// applyConfig fills in the flags of cmd that were not given on the command
+// line from NPM_SCAN_* environment variables and the config file.
+func applyConfig(cmd *cobra.Command) error {
+	path := configFlag
+	if path == "" {
+		found, err := config.Find(".")
+		if err != nil {
+			return err
+		}
+		path = found
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if err := validateSettings(cfg); err != nil {
+		return err
+	}
+
+	var applyErr error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if applyErr != nil || f.Changed || f.Name == "config" || f.Name == "help" {
+			return
+		}
+
+		list := f.Value.Type() == "stringArray" || f.Value.Type() == "stringSlice"
+		values, source, ok := cfg.Lookup(f.Name, list)
+		if !ok {
+			return
+		}
+		if !list && (len(values) != 1 || cfg.Lists[f.Name] && source == cfg.Path) {
+			applyErr = fmt.Errorf("invalid %s in %s: expected a single value", f.Name, source)
+			return
+		}
+		for _, value := range values {
+			if err := cmd.Flags().Set(f.Name, value); err != nil {
+				applyErr = fmt.Errorf("invalid %s in %s: %w", f.Name, source, err)
+				return
+			}
+		}
+	})
+	return applyErr
+}
+
+// validateSettings rejects config file settings that are not the name of
+// any flag, so typos do not go unnoticed.
+func validateSettings(cfg *config.Config) error {
+	known := make(map[string]bool)
+	var collect func(c *cobra.Command)
+	collect = func(c *cobra.Command) {
+		c.LocalFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		c.PersistentFlags().VisitAll(func(f *pflag.Flag) { known[f.Name] = true })
+		for _, sub := range c.Commands() {
+			collect(sub)
+		}
+	}
+	collect(rootCmd)
+
+	var unknown []string
+	for setting := range cfg.Values {
+		if !known[setting] {
+			unknown = append(unknown, setting)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown settings in %s: %s", cfg.Path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// Formats of npm-scan db export.
+const (
+	dbExportJSON = "json"
+	dbExportCSV  = "csv"
+)
+
+var (
+	dbFormatFlag string
+	dbOutputFlag string
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Manage the cached IoC database",
+	Long: `Db manages the local copy of the IoC database, kept in npm-scan/ioc in the
+user cache directory, one copy per --csv-url feed. Offline scans use the copy
+of their feed instead of the snapshot embedded in the binary, so a database
+refreshed with "npm-scan db update" can be distributed to machines without
+network access.`,
+}
+
+var dbUpdateCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Fetch the IoC database into the cache",
+	Long: `Update fetches the IoC database from --csv-url (default: the official
+repository), verifies it against --csv-sha256 and --csv-public-key, runs the
+feed sanity checks selected by --feed-check, and replaces the cached copy.`,
+	Args: cobra.NoArgs,
+	RunE: runDBUpdate,
+}
+
+var dbInfoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Describe the cached and embedded IoC databases",
+	Long: `Info prints the source, entry count, fetch time and SHA-256 checksum of the
+cached copy of --csv-url and of the snapshot embedded in the binary.`,
+	Args: cobra.NoArgs,
+	RunE: runDBInfo,
+}
+
+var dbVerifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the integrity of the cached IoC database",
+	Long: `Verify checks that the cached copy of --csv-url still matches the checksum
+recorded when it was fetched and parses to the recorded number of entries,
+then runs the integrity checks a scan runs on a fetched feed: with
+--csv-sha256, the checksum must equal the given one, such as a checksum
+published alongside the feed, and with --csv-public-key the feed's signature
+must verify. The exit code is 1 if verification fails.`,
+	Args: cobra.NoArgs,
+	RunE: runDBVerify,
+}
+
+var dbExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export the cached IoC database",
+	Long: `Export writes the entries of the cached copy of --csv-url, or of the
+embedded snapshot with --offline, as a JSON array (--format json) that can
+itself be used as a --csv-url feed, or as the original CSV (--format csv) of
+CSV feeds.`,
+	Args: cobra.NoArgs,
+	RunE: runDBExport,
+}
+
+func init() {
+	rootCmd.AddCommand(dbCmd)
+	dbCmd.AddCommand(dbUpdateCmd, dbInfoCmd, dbVerifyCmd, dbExportCmd)
+
+	for _, cmd := range []*cobra.Command{dbUpdateCmd, dbInfoCmd, dbVerifyCmd, dbExportCmd} {
+		cmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL (default: official repository)")
+	}
+	dbInfoCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output the descriptions as JSON")
+	dbExportCmd.Flags().StringVar(&dbFormatFlag, "format", dbExportJSON, "Export format: json or csv")
+	dbExportCmd.Flags().StringVarP(&dbOutputFlag, "output", "o", "", "Write the export to this file instead of stdout")
+	dbExportCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Export the IoC snapshot embedded in the binary instead of the cached database")
+}
+
+// dbCache returns the IoC database cache in the user cache directory, or
+// nil if there is no user cache directory.
+func dbCache() *ioc.DBCache {
+	dir, err := ioc.DefaultDBCacheDir()
+	if err != nil {
+		return nil
+	}
+	return ioc.OpenDBCache(dir)
+}
+
+func runDBUpdate(cmd *cobra.Command, args []string) error {
+	cache := dbCache()
+	if cache == nil {
+		return fmt.Errorf("no user cache directory to store the IoC database in")
+	}
+
+	feed, err := ioc.FetchFeed(csvURLFlag, csvHeader)
+	if err != nil {
+		return err
+	}
+	format := feed.Format()
+	check := feedCheck()
+	if err := check.Verify(feed.Data, csvURLFlag, csvHeader); err != nil {
+		return fmt.Errorf("IoC database failed integrity verification, cache not updated: %w", err)
+	}
+	if err := check.CheckFormat(feed.Data, csvURLFlag, format); err != nil {
+		if check.Mode != ioc.FeedCheckWarn {
+			return fmt.Errorf("IoC database failed sanity checks, cache not updated: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "WARNING: IoC database failed sanity checks, results may be incomplete: %v\n", err)
+	}
+
+	info, err := cache.Store(csvURLFlag, feed.Data, format, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to cache IoC database: %w", err)
+	}
+	fmt.Printf("Updated IoC database from %s: %d entries, sha256 %s\n", info.URL, info.Entries, info.SHA256)
+	return nil
+}
+
+func runDBInfo(cmd *cobra.Command, args []string) error {
+	var infos []ioc.DBInfo
+	cached, err := dbCache().Info(csvURLFlag)
+	if err != nil && !errors.Is(err, ioc.ErrNotCached) {
+		return err
+	}
+	if err == nil {
+		infos = append(infos, cached)
+	}
+	snapshot, err := ioc.SnapshotInfo()
+	if err != nil && !errors.Is(err, ioc.ErrNoSnapshot) {
+		return err
+	}
+	if err == nil {
+		infos = append(infos, snapshot)
+	}
+
+	if jsonFlag {
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if cached.URL == "" {
+		fmt.Println("Cached database:   none (run npm-scan db update)")
+		fmt.Println()
+	}
+	for _, info := range infos {
+		label, fetched := "Cached database:", "Fetched:"
+		if info.URL == ioc.SnapshotURL {
+			label, fetched = "Embedded snapshot:", "Taken:"
+		}
+		fmt.Printf("%-18s %s\n", label, info.URL)
+		fmt.Printf("  %-16s %d\n", "Entries:", info.Entries)
+		if info.Format != "" {
+			fmt.Printf("  %-16s %s\n", "Format:", strings.ToUpper(info.Format))
+		}
+		fmt.Printf("  %-16s %s\n", fetched, info.FetchedAt.Format(time.RFC3339))
+		fmt.Printf("  %-16s %s\n", "SHA-256:", info.SHA256)
+		fmt.Println()
+	}
+	return nil
+}
+
+func runDBVerify(cmd *cobra.Command, args []string) error {
+	cache := dbCache()
+	info, err := cache.Verify(csvURLFlag)
+	if err != nil {
+		return err
+	}
+	data, _, err := cache.Load(csvURLFlag)
+	if err != nil {
+		return err
+	}
+	if err := feedCheck().Verify(data, csvURLFlag, csvHeader); err != nil {
+		return fmt.Errorf("cached IoC database of %s failed integrity verification: %w", info.URL, err)
+	}
+	fmt.Printf("Verified IoC database of %s: %d entries, sha256 %s\n", info.URL, info.Entries, info.SHA256)
+	return nil
+}
+
+func runDBExport(cmd *cobra.Command, args []string) error {
+	if dbFormatFlag != dbExportJSON && dbFormatFlag != dbExportCSV {
+		return fmt.Errorf("invalid --format %q: expected json or csv", dbFormatFlag)
+	}
+
+	var data []byte
+	var info ioc.DBInfo
+	var err error
+	if offlineFlag {
+		data, err = ioc.SnapshotData()
+		info.Format = ioc.FormatCSV
+	} else {
+		data, info, err = dbCache().Load(csvURLFlag)
+	}
+	if err != nil {
+		return err
+	}
+
+	output := data
+	if dbFormatFlag == dbExportCSV && info.Format != "" && info.Format != ioc.FormatCSV {
+		return fmt.Errorf("the cached IoC database of %s is %s, not CSV: export it with --format json", info.URL, strings.ToUpper(info.Format))
+	}
+	if dbFormatFlag == dbExportJSON {
+		entries, err := ioc.ParseFeed(data, info.Format)
+		if err != nil {
+			return fmt.Errorf("failed to parse IoC database: %w", err)
+		}
+		for i := range entries {
+			entries[i].Source = ioc.SourceCSV
+		}
+		if output, err = ioc.ExportJSON(entries); err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		output = append(output, '\n')
+	}
+
+	if dbOutputFlag == "" {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+	if err := readonly.WriteFile(dbOutputFlag, output, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported IoC database to %s\n", dbOutputFlag)
+	return nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
+)
+
+var (
+	feedbackFormatFlag string
+	feedbackOutputFlag string
+)
+
+var feedbackCmd = &cobra.Command{
+	Use:   "feedback <report>...",
+	Short: "Export false positives for the IoC feed maintainers",
+	Long: `Feedback exports the findings triaged as false positives (npm-scan ack
+--status false-positive) so they can be reported back to the maintainers of
+the IoC feed.
+
+Reports are JSON scan results (npm-scan --json, including --per-root) or
+bulk run directories. Findings are grouped by package and version; file
+locations are left out so the export can be shared outside the
+organization.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runFeedback,
+}
+
+func init() {
+	rootCmd.AddCommand(feedbackCmd)
+
+	feedbackCmd.Flags().StringVar(&feedbackFormatFlag, "format", "json", "Output format: json or csv")
+	feedbackCmd.Flags().StringVarP(&feedbackOutputFlag, "output", "o", "", "Write the export to this file instead of stdout")
+	feedbackCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store file")
+}
+
+func runFeedback(cmd *cobra.Command, args []string) error {
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	var results []*formatter.ScanResult
+	for _, arg := range args {
+		loaded, err := loadReports(arg)
+		if err != nil {
+			return err
+		}
+		results = append(results, loaded...)
+	}
+
+	falsePositives := remediation.CollectFalsePositives(store, results)
+
+	var output string
+	switch feedbackFormatFlag {
+	case "json":
+		output, err = remediation.FormatFeedbackJSON(falsePositives, time.Now().UTC())
+		output += "\n"
+	case "csv":
+		output, err = remediation.FormatFeedbackCSV(falsePositives)
+	default:
+		return fmt.Errorf("invalid --format %q (expected json or csv)", feedbackFormatFlag)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to format feedback: %w", err)
+	}
+
+	if feedbackOutputFlag == "" {
+		fmt.Print(output)
+		return nil
+	}
+	if err := readonly.WriteFile(feedbackOutputFlag, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write feedback: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d false positives to %s\n", len(falsePositives), feedbackOutputFlag)
+	return nil
+}
+
+// loadReports reads the scan results in a JSON report, or in every per-path
+// result file of a bulk run directory.
+func loadReports(path string) ([]*formatter.ScanResult, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return loadReport(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []*formatter.ScanResult
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".json") || name == "summary.json" || name == "alerts.json" {
+			continue
+		}
+		loaded, err := loadReport(filepath.Join(path, name))
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, loaded...)
+	}
+	return results, nil
+}
+
+// loadReport reads a single JSON report: a scan result, or an array of
+// per-root results.
+func loadReport(path string) ([]*formatter.ScanResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		var roots []formatter.RootResult
+		if err := json.Unmarshal(data, &roots); err != nil {
+			return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+		}
+		results := make([]*formatter.ScanResult, 0, len(roots))
+		for _, root := range roots {
+			results = append(results, root.Result)
+		}
+		return results, nil
+	}
+
+	var result formatter.ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return []*formatter.ScanResult{&result}, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/fix"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var (
+	fixSetFlag            []string
+	fixDryRunFlag         bool
+	fixOverridesFlag      bool
+	fixUpdateLockfileFlag bool
+	fixFormatFlag         string
+)
+
+var fixCmd = &cobra.Command{
+	Use:   "fix [path]",
+	Short: "Update compromised dependency declarations in package.json files",
+	Long: `Fix sets the declared version of the given packages in every package.json
+under path, e.g. npm-scan fix --set lodash@4.17.21.
+
+Before anything is written, each proposed manifest is verified in memory:
+matching is re-run against it to confirm the matches of the changed packages
+disappear and no new matches (such as POTENTIAL hits from a loosened range)
+are introduced. A before/after summary is printed, and no file is changed
+unless every manifest verifies.
+
+Workspaces are updated together: every manifest under path is planned and
+verified before any is written, and a failed write restores the manifests
+already changed. Override entries for the packages in the root manifest
+("overrides" for npm, "resolutions" for yarn, "pnpm.overrides" for pnpm,
+detected from the root lockfile) are updated too; --overrides adds missing
+ones, forcing transitive copies to the new version. --update-lockfile then
+runs the package manager's lockfile update (with lifecycle scripts
+disabled), rolling back every change if it fails.
+
+Use --dry-run to only print the verification, or --format patch to print
+the changes as a unified diff instead of writing them, so they can be
+reviewed and applied with git apply without giving the scanner write access
+to the tree. The verification summary then goes to stderr.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runFix,
+}
+
+func init() {
+	rootCmd.AddCommand(fixCmd)
+
+	fixCmd.Flags().StringArrayVar(&fixSetFlag, "set", nil, "Set a package to a version spec, as name@version (repeatable)")
+	fixCmd.Flags().BoolVar(&fixDryRunFlag, "dry-run", false, "Verify and print the changes without writing them")
+	fixCmd.Flags().BoolVar(&fixOverridesFlag, "overrides", false, "Add missing overrides/resolutions for the packages to the root package.json")
+	fixCmd.Flags().BoolVar(&fixUpdateLockfileFlag, "update-lockfile", false, "Run the package manager's lockfile update after writing the manifests")
+	fixCmd.Flags().StringVar(&fixFormatFlag, "format", "text", "Output format: text (apply the changes) or patch (print a unified diff)")
+	fixCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+	fixCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	fixCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+}
+
+func runFix(cmd *cobra.Command, args []string) error {
+	scanPath := "."
+	if len(args) > 0 {
+		scanPath = args[0]
+	}
+
+	patch := false
+	switch fixFormatFlag {
+	case "text":
+	case "patch":
+		patch = true
+		if fixUpdateLockfileFlag {
+			return fmt.Errorf("--update-lockfile cannot be used with --format patch")
+		}
+	default:
+		return fmt.Errorf("invalid --format %q (expected text or patch)", fixFormatFlag)
+	}
+
+	if len(fixSetFlag) == 0 {
+		return fmt.Errorf("at least one --set name@version is required")
+	}
+	targets := make(map[string]string)
+	for _, target := range fixSetFlag {
+		name, spec, err := fix.ParseTarget(target)
+		if err != nil {
+			return err
+		}
+		targets[name] = spec
+	}
+
+	iocDB, err := scanner.LoadDatabase(scanner.ScanOptions{
+		CSVURL:        csvURLFlag,
+		CSVHeader:     csvHeader,
+		Offline:       offlineFlag,
+		DatabaseCache: dbCache(),
+		FeedCheck:     feedCheck(),
+		// Progress goes to stdout, which holds the patch
+		Verbose: verboseFlag && !patch,
+	})
+	if err != nil {
+		return err
+	}
+
+	manifestPaths, err := scanner.FindManifests(scanPath)
+	if err != nil {
+		return fmt.Errorf("failed to find manifests: %w", err)
+	}
+
+	rootManifest := filepath.Join(scanPath, "package.json")
+	manager := fix.DetectPackageManager(scanPath)
+
+	var plans []*fix.Plan
+	var verifications []*fix.Verification
+	verified := true
+	for _, path := range manifestPaths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		plan, err := fix.PlanManifest(path, content, targets)
+		if err != nil {
+			return err
+		}
+		if path == rootManifest {
+			if err := fix.PlanOverrides(plan, manager, targets, fixOverridesFlag); err != nil {
+				return err
+			}
+		}
+		if len(plan.Changes) == 0 {
+			continue
+		}
+
+		v, err := fix.Verify(plan, iocDB)
+		if err != nil {
+			return err
+		}
+		plans = append(plans, plan)
+		verifications = append(verifications, v)
+		verified = verified && v.OK()
+	}
+
+	if patch {
+		fmt.Fprint(os.Stderr, fix.FormatVerification(plans, verifications))
+	} else {
+		fmt.Print(fix.FormatVerification(plans, verifications))
+	}
+
+	if !verified {
+		return fmt.Errorf("verification failed; no files were changed")
+	}
+	if patch {
+		fmt.Print(fix.FormatPatch(plans, scanPath))
+		return nil
+	}
+	if fixDryRunFlag {
+		fmt.Println("Dry run: no files were changed.")
+		return nil
+	}
+
+	if err := fix.ApplyAll(plans); err != nil {
+		return err
+	}
+
+	if !fixUpdateLockfileFlag {
+		fmt.Printf("Updated %d manifests. Reinstall to refresh the lockfiles.\n", len(plans))
+		return nil
+	}
+	if verboseFlag {
+		fmt.Printf("Updating the %s lockfile in %s...\n", manager, scanPath)
+	}
+	if err := fix.UpdateLockfile(scanPath, manager); err != nil {
+		if rerr := fix.Revert(plans); rerr != nil {
+			return fmt.Errorf("%w (rollback failed: %v)", err, rerr)
+		}
+		return fmt.Errorf("%w; manifests were restored", err)
+	}
+	fmt.Printf("Updated %d manifests and the %s lockfile.\n", len(plans), manager)
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/oci"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/suppress"
+)
+
+var platformFlag string
+
+var imageCmd = &cobra.Command{
+	Use:   "image [reference|archive]",
+	Short: "Scan the npm files of a container image",
+	Long: `Image scans the filesystem of a built container image: package.json files,
+lockfiles and the packages installed in node_modules directories, merged
+across the image's layers with deleted files left out.
+
+The argument is an image archive written by docker save, podman save or an
+OCI layout exporter, or else an image reference such as node:20-app or
+ghcr.io/org/app:1.4.0, pulled anonymously from its registry. Nothing is
+written to disk; only the npm files are kept, in memory.
+
+Matches are located at their path in the image. The image reference and
+manifest digest are recorded in the result metadata.
+
+Use "npm-scan sbom --image" to scan an image's SBOM attestations instead of
+its layers.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runImageScan,
+}
+
+func init() {
+	rootCmd.AddCommand(imageCmd)
+
+	imageCmd.Flags().StringVar(&platformFlag, "platform", oci.DefaultPlatform(), "Platform to pull from multi-platform images (os/arch[/variant])")
+	imageCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only scan lockfiles and installed packages, skip package.json declarations")
+	imageCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+	imageCmd.Flags().BoolVar(&grypeFlag, "grype", false, "Output results as grype-compatible match JSON")
+	imageCmd.Flags().StringVar(&formatFlag, "format", "", "Output format: human, json, grype, sarif or attest-min (overrides --json and --grype)")
+	imageCmd.Flags().StringVar(&failOnFlag, "fail-on", "potential", "Minimum match severity that exits 1: direct, transitive, potential or none")
+	imageCmd.Flags().IntVar(&topFlag, "top", 0, "Show only the N most significant matches per severity in human output (0: all)")
+	imageCmd.Flags().StringVar(&timezoneFlag, "timezone", "UTC", "Time zone of report timestamps: UTC, Local or an IANA zone name such as Europe/Berlin")
+	imageCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+	imageCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	imageCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	imageCmd.Flags().StringArrayVar(&metaFlag, "meta", nil, "Embed key=value metadata in JSON output (repeatable)")
+	imageCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	imageCmd.Flags().StringVar(&ignoreFileFlag, "ignore-file", suppress.DefaultPath, "Suppression file of acknowledged findings (see npm-scan baseline)")
+	imageCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to annotate findings (see npm-scan ack)")
+}
+
+func runImageScan(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat()
+	if err != nil {
+		return err
+	}
+
+	failOn, err := formatter.ParseFailOn(failOnFlag)
+	if err != nil {
+		return err
+	}
+	if topFlag < 0 {
+		return fmt.Errorf("invalid --top %d: must not be negative", topFlag)
+	}
+
+	loc, err := formatter.ParseTimezone(timezoneFlag)
+	if err != nil {
+		return err
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := formatter.ParseMetadata(metaFlag)
+	if err != nil {
+		return err
+	}
+
+	watched, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	required, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	approved, err := loadAllowlist()
+	if err != nil {
+		return err
+	}
+
+	detector, err := loadTyposquat()
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	popularityLookup, err := popularityClient()
+	if err != nil {
+		return err
+	}
+
+	suppressions, err := suppress.Load(ignoreFileFlag)
+	if err != nil {
+		return err
+	}
+
+	result, err := scanner.RunContainerScan(scanner.ScanOptions{
+		Path:          args[0],
+		Platform:      platformFlag,
+		CSVURL:        csvURLFlag,
+		CSVHeader:     csvHeader,
+		Offline:       offlineFlag,
+		DatabaseCache: dbCache(),
+		FeedCheck:     feedCheck(),
+		LockfileOnly:  lockfileOnlyFlag,
+		Watchlist:     watched,
+		Policy:        required,
+		Allowlist:     approved,
+		Typosquat:     detector,
+		Verbose:       verboseFlag,
+		Logger:        scanLogger(format),
+		Since:         since,
+		Context:       context.Background(),
+	})
+	if err != nil {
+		return fmt.Errorf("image scan failed: %w", err)
+	}
+	for key, value := range metadata {
+		result.Metadata[key] = value
+	}
+	store.Annotate(result)
+	annotatePopularity(popularityLookup, result)
+	warnExpired(suppressions.Apply(result, time.Now()))
+	formatter.InLocation(result, loc)
+
+	switch format {
+	case formatGrype:
+		output, err := formatter.FormatGrypeJSON(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format grype output: %w", err)
+		}
+		fmt.Println(output)
+	case formatSARIF:
+		output, err := formatter.FormatSARIF(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format SARIF output: %w", err)
+		}
+		fmt.Println(output)
+	case formatAttestMin:
+		output, err := formatter.FormatAttestation(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format attestation: %w", err)
+		}
+		fmt.Println(output)
+	case formatJSON:
+		output, err := formatter.FormatJSON(result)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(output)
+	default:
+		fmt.Print(formatter.FormatHumanWith(result, formatter.HumanOptions{Top: topFlag}))
+	}
+
+	if formatter.Fails(result, failOn) {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/maintainer"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+var (
+	maintainerIOCsFlag string
+	maintainerFlag     []string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&maintainerIOCsFlag, "maintainer-iocs", "", "File or URL listing compromised npm accounts, one user name or email per line; report packages they published or maintain, looked up in the registry and cached for a week")
+	rootCmd.Flags().StringArrayVar(&maintainerFlag, "maintainer", nil, "Compromised npm account (user name or email) to report packages of, added to --maintainer-iocs (repeatable)")
+}
+
+// maintainerChecker returns the checker of --maintainer-iocs and
+// --maintainer, or nil without them. Lookups are cached unless --no-cache
+// or --read-only is set.
+func maintainerChecker() (*maintainer.Checker, error) {
+	if maintainerIOCsFlag == "" && len(maintainerFlag) == 0 {
+		return nil, nil
+	}
+	if offlineFlag {
+		return nil, fmt.Errorf("--maintainer-iocs looks up packages online and cannot be used with --offline")
+	}
+	list, err := maintainer.Load(maintainerIOCsFlag, maintainerFlag)
+	if err != nil {
+		return nil, err
+	}
+	if list.Len() == 0 {
+		return nil, fmt.Errorf("--maintainer-iocs %s lists no account", maintainerIOCsFlag)
+	}
+
+	checker := &maintainer.Checker{Registry: &registry.Client{URL: registryFlag}, List: list}
+	if !noCacheFlag && !readonly.Enabled() {
+		if path, err := maintainer.DefaultCachePath(); err == nil {
+			checker.Cache = maintainer.LoadCache(path, maintainer.DefaultTTL)
+		}
+	}
+	return checker, nil
+}
+
+// saveMaintainerCache saves the lookups of checker, if set.
+func saveMaintainerCache(checker *maintainer.Checker) {
+	if checker == nil {
+		return
+	}
+	if err := checker.Cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save maintainer cache: %v\n", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+var (
+	proxyFlag       string
+	rewriteURLFlag  []string
+	caCertFlag      []string
+	httpTimeoutFlag time.Duration
+	httpRetriesFlag int
+)
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&proxyFlag, "proxy", "", "Proxy for all outbound requests (default: HTTP_PROXY/HTTPS_PROXY, honoring NO_PROXY)")
+	rootCmd.PersistentFlags().StringArrayVar(&rewriteURLFlag, "rewrite-url", nil, "Rewrite outbound URLs with this prefix, as from=to (repeatable)")
+	rootCmd.PersistentFlags().StringArrayVar(&caCertFlag, "ca-cert", nil, "Also trust the CA certificates in this PEM file for outbound TLS, e.g. of an intercepting proxy (repeatable)")
+	rootCmd.PersistentFlags().DurationVar(&httpTimeoutFlag, "http-timeout", transport.DefaultTimeout, "How long an outbound request may take, including reading the response (0: no limit)")
+	rootCmd.PersistentFlags().IntVar(&httpRetriesFlag, "http-retries", 3, "Retries of an outbound request after a network error or a 429/5xx response, with exponential backoff")
+}
+
+// configureNetwork sets up the shared transport before any command makes a
+// request. --proxy is exported as HTTP_PROXY/HTTPS_PROXY so NO_PROXY still
+// applies and subprocesses (cosign, package managers) use the same proxy;
+// --ca-cert, --http-timeout and --http-retries apply to npm-scan's own
+// requests only.
+func configureNetwork(cmd *cobra.Command, args []string) error {
+	if proxyFlag != "" {
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "http_proxy", "https_proxy"} {
+			if err := os.Setenv(name, proxyFlag); err != nil {
+				return err
+			}
+		}
+	}
+
+	rewrite, err := transport.ParseRewrites(rewriteURLFlag)
+	if err != nil {
+		return err
+	}
+	if httpTimeoutFlag < 0 {
+		return fmt.Errorf("invalid --http-timeout %s: must not be negative", httpTimeoutFlag)
+	}
+	if httpRetriesFlag < 0 {
+		return fmt.Errorf("invalid --http-retries %d: must not be negative", httpRetriesFlag)
+	}
+
+	cfg := transport.Config{Rewrite: rewrite, Timeout: httpTimeoutFlag, Retries: httpRetriesFlag}
+	if httpTimeoutFlag == 0 {
+		cfg.Timeout = -1
+	}
+	if len(caCertFlag) > 0 {
+		if cfg.RootCAs, err = transport.LoadCertPool(caCertFlag); err != nil {
+			return fmt.Errorf("invalid --ca-cert: %w", err)
+		}
+	}
+	transport.Configure(cfg)
+	return nil
+}
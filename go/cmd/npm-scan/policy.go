@@ -0,0 +1,23 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+)
+
+var requireVersionFlag []string
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, imageCmd, watchCmd} {
+		cmd.Flags().StringArrayVar(&requireVersionFlag, "require-version", nil, "Require every copy of a package to satisfy a version range, as name@range (e.g. 'lodash@>=4.17.21'); violations fail the scan (repeatable)")
+	}
+}
+
+// loadPolicy parses the --require-version requirements, or returns nil if
+// there are none.
+func loadPolicy() (*policy.Policy, error) {
+	if len(requireVersionFlag) == 0 {
+		return nil, nil
+	}
+	return policy.New(requireVersionFlag)
+}
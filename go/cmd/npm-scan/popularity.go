@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/popularity"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+var popularityFlag bool
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, sbomCmd, imageCmd} {
+		cmd.Flags().BoolVar(&popularityFlag, "popularity", false, "Annotate matches with weekly npm downloads and dependent counts, looked up online and cached for a day")
+	}
+}
+
+// popularityClient returns the client annotating matches for --popularity,
+// or nil without it. Lookups are cached unless --no-cache or --read-only
+// is set.
+func popularityClient() (*popularity.Client, error) {
+	if !popularityFlag {
+		return nil, nil
+	}
+	if offlineFlag {
+		return nil, fmt.Errorf("--popularity looks up packages online and cannot be used with --offline")
+	}
+	client := &popularity.Client{}
+	if !noCacheFlag && !readonly.Enabled() {
+		if path, err := popularity.DefaultCachePath(); err == nil {
+			client.Cache = popularity.LoadCache(path, popularity.DefaultTTL)
+		}
+	}
+	return client, nil
+}
+
+// annotatePopularity annotates the matches of result with client, if set.
+// Popularity only helps triage, so failed lookups are warnings.
+func annotatePopularity(client *popularity.Client, result *formatter.ScanResult) {
+	if client == nil {
+		return
+	}
+	if err := client.Annotate(context.Background(), result); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: popularity lookup failed, some matches are not annotated: %v\n", err)
+	}
+	if err := client.Cache.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to save popularity cache: %v\n", err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var noProgressFlag bool
+
+// progressWidth is the number of cells of the progress bar.
+const progressWidth = 30
+
+// progressFileWidth is the widest file name shown next to the bar.
+const progressFileWidth = 48
+
+// progressBar draws a single-line progress bar on stderr, so long scans
+// visibly advance. Lines written to it are printed to stdout above the bar
+// instead of running into it. The bar is removed once the work completes.
+type progressBar struct {
+	mu      sync.Mutex
+	out     *os.File
+	lines   io.Writer
+	visible bool
+	current int
+	total   int
+	file    string
+}
+
+// newProgressBar returns a progress bar unless disabled or stderr is not a
+// terminal, in which case it returns nil. The methods of a nil bar do
+// nothing.
+func newProgressBar(disabled bool) *progressBar {
+	if disabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progressBar{out: os.Stderr, lines: os.Stdout}
+}
+
+// isTerminal reports whether f is a character device such as a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Func returns the bar's update function for scanner.ScanOptions.Progress,
+// or nil for a nil bar.
+func (p *progressBar) Func() scanner.ProgressFunc {
+	if p == nil {
+		return nil
+	}
+	return p.update
+}
+
+// Writer returns a writer printing lines above the bar, or nil for a nil
+// bar.
+func (p *progressBar) Writer() io.Writer {
+	if p == nil {
+		return nil
+	}
+	return p
+}
+
+// update redraws the bar for current of total, naming file. The bar is
+// removed once current reaches total.
+func (p *progressBar) update(current, total int, file string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.current, p.total, p.file = current, total, file
+	if current >= total {
+		p.clear()
+		return
+	}
+	p.draw()
+}
+
+// Write prints b to stdout above the bar.
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	visible := p.visible
+	p.clear()
+	n, err := p.lines.Write(b)
+	if visible {
+		p.draw()
+	}
+	return n, err
+}
+
+// Finish removes the bar.
+func (p *progressBar) Finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+// draw renders the bar. The caller must hold the lock.
+func (p *progressBar) draw() {
+	filled := 0
+	if p.total > 0 {
+		filled = progressWidth * p.current / p.total
+	}
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressWidth-filled)
+	fmt.Fprintf(p.out, "\r\x1b[K%s %d/%d %s", bar, p.current, p.total, shortenPath(p.file, progressFileWidth))
+	p.visible = true
+}
+
+// clear erases the bar. The caller must hold the lock.
+func (p *progressBar) clear() {
+	if p.visible {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.visible = false
+	}
+}
+
+// shortenPath keeps the end of path, which names the file, within width
+// characters.
+func shortenPath(path string, width int) string {
+	runes := []rune(path)
+	if len(runes) <= width {
+		return path
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
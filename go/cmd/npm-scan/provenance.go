@@ -0,0 +1,58 @@
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/provenance"
+)
+
+// Values of --provenance.
+const (
+	provenanceOff     = "off"
+	provenanceMatched = "matched"
+	provenanceAll     = "all"
+)
+
+var (
+	provenanceFlag      string
+	provenanceRootsFlag string
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&provenanceFlag, "provenance", provenanceOff, "Verify the npm provenance of matched packages (matched) or also of every direct dependency (all), and report versions without sound provenance; off disables the check")
+	rootCmd.Flags().StringVar(&provenanceRootsFlag, "provenance-roots", "", "PEM file of trusted roots (the sigstore Fulcio roots) provenance signing certificates must chain to")
+}
+
+// provenanceClient returns the client verifying provenance for
+// --provenance, or nil when it is off, and whether every direct dependency
+// is checked.
+func provenanceClient() (client *provenance.Client, all bool, err error) {
+	switch provenanceFlag {
+	case provenanceOff:
+		if provenanceRootsFlag != "" {
+			return nil, false, fmt.Errorf("--provenance-roots requires --provenance")
+		}
+		return nil, false, nil
+	case provenanceMatched, provenanceAll:
+	default:
+		return nil, false, fmt.Errorf("invalid --provenance %q: expected off, matched or all", provenanceFlag)
+	}
+	if offlineFlag {
+		return nil, false, fmt.Errorf("--provenance looks up packages online and cannot be used with --offline")
+	}
+
+	client = &provenance.Client{URL: registryFlag}
+	if provenanceRootsFlag != "" {
+		data, err := os.ReadFile(provenanceRootsFlag)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read --provenance-roots: %w", err)
+		}
+		client.Roots = x509.NewCertPool()
+		if !client.Roots.AppendCertsFromPEM(data) {
+			return nil, false, fmt.Errorf("--provenance-roots %s holds no PEM certificate", provenanceRootsFlag)
+		}
+	}
+	return client, provenanceFlag == provenanceAll, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+var (
+	registryStatusFlag   bool
+	resolvePotentialFlag bool
+	registryFlag         string
+)
+
+func init() {
+	rootCmd.Flags().BoolVar(&registryStatusFlag, "registry-status", false, "Report direct dependencies whose version is deprecated or was unpublished, looked up in the registry")
+	rootCmd.Flags().BoolVar(&resolvePotentialFlag, "resolve-potential", false, "Only report a POTENTIAL match when the highest published version in its range, the one npm would install, is compromised, looked up in the registry")
+	rootCmd.Flags().StringVar(&registryFlag, "registry", "", "npm registry consulted by --registry-status, --resolve-potential, --provenance and --maintainer-iocs (default: "+registry.DefaultURL+")")
+}
+
+// registryClient returns the client checking direct dependencies for
+// --registry-status, or nil without it.
+func registryClient() (*registry.Client, error) {
+	if !registryStatusFlag {
+		return nil, nil
+	}
+	if offlineFlag {
+		return nil, fmt.Errorf("--registry-status looks up packages online and cannot be used with --offline")
+	}
+	return &registry.Client{URL: registryFlag}, nil
+}
+
+// potentialClient returns the client resolving POTENTIAL matches for
+// --resolve-potential, or nil without it.
+func potentialClient() (*registry.Client, error) {
+	if !resolvePotentialFlag {
+		return nil, nil
+	}
+	if offlineFlag {
+		return nil, fmt.Errorf("--resolve-potential looks up packages online and cannot be used with --offline")
+	}
+	return &registry.Client{URL: registryFlag}, nil
+}
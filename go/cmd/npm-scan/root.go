@@ -2,12 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/forensic"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/suppress"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
 )
 
 var (
@@ -16,11 +26,55 @@ var (
 	jsonFlag         bool
 	verboseFlag      bool
 	csvURLFlag       string
+	offlineFlag      bool
 	lockfileOnlyFlag bool
+	redactFlag       string
+	redactMapFlag    string
+	perRootFlag      bool
+	perProjectFlag   bool
+	hygieneFlag      bool
+	lockfileAgeFlag  bool
+	campaignFlag     string
+	sinceFlag        string
+	exposureFlag     bool
+	grypeFlag        bool
+	metaFlag         []string
+	noGitMetaFlag    bool
+	sourceFlag       string
+	formatFlag       string
+	failOnFlag       string
+	topFlag          int
+	timezoneFlag     string
+	softFailFlag     bool
+	parseWorkersFlag int
+	timeoutFlag      time.Duration
+	fileTimeoutFlag  time.Duration
+
+	uncheckedBundledFlag bool
+	excludeFlag          []string
+	followSymlinksFlag   bool
+	installedFlag        bool
+	remediationFileFlag  string
+	ignoreFileFlag       string
+	evidenceDirFlag      string
+
+	feedCheckFlag   string
+	feedMinRowsFlag int
+	readOnlyFlag    bool
+
+	csvSHA256Flag       string
+	csvPublicKeyFlag    string
+	csvSignatureURLFlag string
+	csvHeaderFlag       []string
+
+	// csvPublicKey is the key loaded from --csv-public-key
+	csvPublicKey crypto.PublicKey
+	// csvHeader holds the headers parsed from --csv-header
+	csvHeader http.Header
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "npm-scan [path]",
+	Use:   "npm-scan [path...]",
 	Short: "Scan npm projects for compromised packages",
 	Long: `npm-scan is a vulnerability scanner for npm packages.
 It checks your npm projects against an IoC (Indicators of Compromise) database
@@ -29,70 +83,509 @@ to detect malicious packages that may have been compromised.
 The scanner supports three types of detections:
   - DIRECT: Exact version matches in package.json
   - TRANSITIVE: Resolved packages in lockfiles
-  - POTENTIAL: Version ranges that could resolve to vulnerable versions`,
-	Args: cobra.MaximumNArgs(1),
+  - POTENTIAL: Version ranges that could resolve to vulnerable versions
+
+Multiple paths may be given to scan several roots in one invocation. Results
+are merged unless --per-root is set.`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runScan,
 }
 
 func init() {
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := applyConfig(cmd); err != nil {
+			return err
+		}
+		if err := validateFeedCheck(); err != nil {
+			return err
+		}
+		if readOnlyFlag {
+			if err := readonly.Enable(""); err != nil {
+				return err
+			}
+		}
+		return configureNetwork(cmd, args)
+	}
+
+	rootCmd.PersistentFlags().StringVar(&feedCheckFlag, "feed-check", ioc.FeedCheckStrict, "Sanity checks on the fetched IoC CSV: strict (reject a suspicious feed), warn or off")
+	rootCmd.PersistentFlags().BoolVar(&readOnlyFlag, "read-only", false, "Write nothing but stdout/stderr (bulk: only below an explicit --output), for locked-down forensic environments")
+	rootCmd.PersistentFlags().IntVar(&feedMinRowsFlag, "feed-min-rows", 0, "Fewest rows the fetched IoC CSV must have (default: 500 for the default feed, 1 for --csv-url feeds)")
+	rootCmd.PersistentFlags().StringVar(&csvSHA256Flag, "csv-sha256", "", "Reject a fetched IoC CSV whose SHA-256 checksum is not this hex digest")
+	rootCmd.PersistentFlags().StringVar(&csvPublicKeyFlag, "csv-public-key", "", "PEM public key (ECDSA, RSA or Ed25519) that must have signed the fetched IoC CSV")
+	rootCmd.PersistentFlags().StringArrayVar(&csvHeaderFlag, "csv-header", nil, "Send this header with IoC CSV requests, as \"Name: value\", e.g. \"Authorization: Bearer <token>\" for a private feed (repeatable)")
+	rootCmd.PersistentFlags().StringVar(&csvSignatureURLFlag, "csv-signature-url", "", "URL of the detached IoC CSV signature checked with --csv-public-key (default: the CSV URL with .sig appended)")
+
 	// Define flags
-	rootCmd.Flags().StringVarP(&pathFlag, "path", "p", ".", "Path to scan (default: current directory)")
+	rootCmd.Flags().StringVarP(&pathFlag, "path", "p", ".", "Directory, package.json or lockfile to scan (default: current directory)")
 	rootCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+	rootCmd.Flags().BoolVar(&grypeFlag, "grype", false, "Output results as grype-compatible match JSON")
+	rootCmd.Flags().StringVar(&formatFlag, "format", "", "Output format: human, json, grype, sarif or attest-min (overrides --json and --grype)")
+	rootCmd.Flags().StringVar(&failOnFlag, "fail-on", "potential", "Minimum match severity that exits 1: direct, transitive, potential or none")
+	rootCmd.Flags().IntVar(&topFlag, "top", 0, "Show only the N most significant matches per severity in human output (0: all)")
+	rootCmd.Flags().StringVar(&timezoneFlag, "timezone", "UTC", "Time zone of report timestamps: UTC, Local or an IANA zone name such as Europe/Berlin")
 	rootCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+	rootCmd.Flags().BoolVar(&noProgressFlag, "no-progress", false, "Do not draw a progress bar on stderr (drawn only when stderr is a terminal)")
 	rootCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL (default: official repository)")
+	rootCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	rootCmd.Flags().BoolVar(&softFailFlag, "soft-fail-fetch", false, "If the IoC database cannot be fetched, report the scan as not scanned and exit 3 instead of failing")
+	rootCmd.Flags().StringVar(&sourceFlag, "source", ioc.SourceCSV, "IoC sources: csv (shai-hulud list), osv (OSV.dev, exact versions only), ghsa (GitHub Advisory Database), or a comma-separated list such as csv,osv")
 	rootCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only scan lockfiles, skip package.json")
+	rootCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip paths matching this gitignore-style pattern, in addition to .npmscanignore (repeatable)")
+	rootCmd.Flags().BoolVar(&followSymlinksFlag, "follow-symlinks", false, "Descend into symlinked directories, visiting each directory once")
+	rootCmd.Flags().IntVar(&parseWorkersFlag, "parse-workers", 0, "Number of files parsed and matched concurrently (default: number of CPUs)")
+	rootCmd.Flags().DurationVar(&timeoutFlag, "timeout", 0, "Fail if scanning all paths takes longer than this, e.g. 10m (0: no limit)")
+	rootCmd.Flags().DurationVar(&fileTimeoutFlag, "file-timeout", 0, "Skip a package.json or lockfile taking longer than this to parse and match, reporting it as a warning (0: no limit)")
+	rootCmd.Flags().BoolVar(&perRootFlag, "per-root", false, "Report each scanned path in its own section instead of merging")
+	rootCmd.Flags().BoolVar(&perProjectFlag, "per-project", false, "Break results down by project (nearest package.json ancestor)")
+	rootCmd.Flags().BoolVar(&hygieneFlag, "hygiene", false, "Audit for unpinned dependencies and missing lockfiles")
+	rootCmd.Flags().BoolVar(&installedFlag, "installed", false, "Also check packages installed in node_modules and warn on versions the lockfile does not resolve (SHADOWED)")
+	rootCmd.Flags().BoolVar(&uncheckedBundledFlag, "unchecked-bundled", false, "Report bundledDependencies that are not installed in node_modules and so could not be checked (UNCHECKED_BUNDLED)")
+	rootCmd.Flags().BoolVar(&lockfileAgeFlag, "lockfile-age", false, "Report lockfile age and warn on lockfiles predating the IoC campaign")
+	rootCmd.Flags().StringVar(&campaignFlag, "campaign-start", ioc.DefaultCampaignStart, "Start of the IoC campaign window (YYYY-MM-DD)")
+	rootCmd.Flags().BoolVar(&exposureFlag, "exposure-window", false, "Trace when each TRANSITIVE match was introduced and removed using lockfile git history")
+	rootCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	rootCmd.Flags().StringArrayVar(&metaFlag, "meta", nil, "Embed key=value metadata in JSON output (repeatable)")
+	rootCmd.Flags().BoolVar(&noGitMetaFlag, "no-git-metadata", false, "Do not record the git remote, branch and HEAD commit of scanned paths")
+	rootCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to annotate findings (see npm-scan ack)")
+	rootCmd.Flags().StringVar(&ignoreFileFlag, "ignore-file", suppress.DefaultPath, "Suppression file of acknowledged findings (see npm-scan baseline)")
+	rootCmd.Flags().StringVar(&evidenceDirFlag, "evidence-dir", "", "Copy the files behind DIRECT and TRANSITIVE matches into a hashed, timestamped evidence bundle in this directory")
+	rootCmd.Flags().StringVar(&redactFlag, "redact", "", "Redact output: paths, projectnames (comma-separated)")
+	rootCmd.Flags().StringVar(&redactMapFlag, "redact-map", "npm-scan-redact-map.json", "File to write the de-redaction mapping to")
 }
 
 func runScan(cmd *cobra.Command, args []string) error {
-	// Determine the scan path
-	scanPath := pathFlag
+	// Determine the scan paths
+	scanPaths := []string{pathFlag}
 	if len(args) > 0 {
-		scanPath = args[0]
+		scanPaths = args
+	}
+
+	// Verify paths exist; "-" reads a single file from standard input
+	stdinPaths := 0
+	for _, scanPath := range scanPaths {
+		if scanPath == stdinPath {
+			if stdinPaths++; stdinPaths > 1 {
+				return fmt.Errorf("standard input (-) can only be scanned once")
+			}
+			if _, err := stdinFile(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Stat(scanPath); os.IsNotExist(err) {
+			return fmt.Errorf("path does not exist: %s", scanPath)
+		}
+	}
+
+	format, err := outputFormat()
+	if err != nil {
+		return err
+	}
+
+	failOn, err := formatter.ParseFailOn(failOnFlag)
+	if err != nil {
+		return err
+	}
+	if topFlag < 0 {
+		return fmt.Errorf("invalid --top %d: must not be negative", topFlag)
+	}
+	if timeoutFlag < 0 || fileTimeoutFlag < 0 {
+		return fmt.Errorf("invalid --timeout or --file-timeout: must not be negative")
+	}
+
+	loc, err := formatter.ParseTimezone(timezoneFlag)
+	if err != nil {
+		return err
 	}
 
-	// Verify path exists
-	if _, err := os.Stat(scanPath); os.IsNotExist(err) {
-		return fmt.Errorf("path does not exist: %s", scanPath)
+	redactModes, err := formatter.ParseRedactModes(redactFlag)
+	if err != nil {
+		return err
 	}
 
-	// Configure scan options
-	options := scanner.ScanOptions{
-		Path:         scanPath,
-		CSVURL:       csvURLFlag,
-		LockfileOnly: lockfileOnlyFlag,
-		Verbose:      verboseFlag,
-		Context:      context.Background(),
+	campaignStart, err := time.Parse("2006-01-02", campaignFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --campaign-start %q: expected YYYY-MM-DD", campaignFlag)
 	}
 
-	// Run the scan
-	result, err := scanner.RunScan(options)
+	since, err := parseSince(sinceFlag)
 	if err != nil {
-		return fmt.Errorf("scan failed: %w", err)
+		return err
+	}
+
+	// The evidence directory is the one place a read-only scan may write
+	if evidenceDirFlag != "" && readOnlyFlag {
+		if err := readonly.Enable(evidenceDirFlag); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := formatter.ParseMetadata(metaFlag)
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	popularityLookup, err := popularityClient()
+	if err != nil {
+		return err
+	}
+
+	registryLookup, err := registryClient()
+	if err != nil {
+		return err
+	}
+
+	potentialLookup, err := potentialClient()
+	if err != nil {
+		return err
+	}
+
+	provenanceCheck, provenanceAllDeps, err := provenanceClient()
+	if err != nil {
+		return err
+	}
+
+	maintainers, err := maintainerChecker()
+	if err != nil {
+		return err
+	}
+
+	suppressions, err := suppress.Load(ignoreFileFlag)
+	if err != nil {
+		return err
+	}
+
+	ioThrottle, err := newThrottle()
+	if err != nil {
+		return err
+	}
+
+	watched, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	required, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	approved, err := loadAllowlist()
+	if err != nil {
+		return err
+	}
+
+	detector, err := loadTyposquat()
+	if err != nil {
+		return err
+	}
+
+	cache := openCache()
+
+	// --timeout bounds the scans of all paths together
+	ctx := context.Background()
+	if timeoutFlag > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeoutFlag)
+		defer cancel()
+	}
+
+	base := scanner.ScanOptions{
+		CSVURL:           csvURLFlag,
+		CSVHeader:        csvHeader,
+		Offline:          offlineFlag,
+		DatabaseCache:    dbCache(),
+		FeedCheck:        feedCheck(),
+		Source:           sourceFlag,
+		LockfileOnly:     lockfileOnlyFlag,
+		Exclude:          excludeFlag,
+		FollowSymlinks:   followSymlinksFlag,
+		Throttle:         ioThrottle,
+		Watchlist:        watched,
+		Policy:           required,
+		Allowlist:        approved,
+		Typosquat:        detector,
+		Registry:         registryLookup,
+		ResolvePotential: potentialLookup,
+		Provenance:       provenanceCheck,
+		ProvenanceAll:    provenanceAllDeps,
+		Maintainers:      maintainers,
+		Cache:            cache,
+		Verbose:          verboseFlag,
+		Logger:           scanLogger(format),
+		PerProject:       perProjectFlag,
+		Hygiene:          hygieneFlag,
+		UncheckedBundled: uncheckedBundledFlag,
+		Installed:        installedFlag,
+		LockfileAge:      lockfileAgeFlag,
+		CampaignStart:    campaignStart,
+		Since:            since,
+		ExposureWindow:   exposureFlag,
+		SkipGitMetadata:  noGitMetaFlag,
+		NumWorkers:       parseWorkersFlag,
+		FileTimeout:      fileTimeoutFlag,
+		Context:          ctx,
+	}
+
+	// Every root is checked against the same IoC database, fetched once
+	base.Database, err = sharedDatabase(base)
+	databaseErr := err
+
+	// Run a scan for each root
+	var roots []formatter.RootResult
+	for _, scanPath := range scanPaths {
+		bar := newProgressBar(noProgressFlag || verboseFlag)
+		options := base
+		options.Path = scanPath
+		options.Progress = bar.Func()
+
+		var result *formatter.ScanResult
+		switch {
+		case databaseErr != nil:
+			err = databaseErr
+		case scanPath == stdinPath:
+			result, err = scanStdin(options)
+		default:
+			result, err = scanner.RunScan(options)
+		}
+		bar.Finish()
+		if err != nil && softFailFlag && errors.Is(err, scanner.ErrDatabaseUnavailable) {
+			fmt.Fprintf(os.Stderr, "Warning: %s not scanned: %v\n", scanPath, err)
+			result, err = formatter.NotScannedResult(err), nil
+		}
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("scan of %s failed: timed out after --timeout %s", scanPath, timeoutFlag)
+		}
+		if err != nil {
+			return fmt.Errorf("scan of %s failed: %w", scanPath, err)
+		}
+		result.Metadata = formatter.MergeMetadata(result.Metadata, metadata)
+		store.Annotate(result)
+		annotatePopularity(popularityLookup, result)
+		warnExpired(suppressions.Apply(result, time.Now()))
+		roots = append(roots, formatter.RootResult{Path: scanPath, Result: result})
+	}
+
+	saveMaintainerCache(maintainers)
+
+	result := scanner.MergeResults(roots)
+
+	// Preserve evidence before redaction, which rewrites the locations
+	if evidenceDirFlag != "" {
+		bundle, err := forensic.Export(result, evidenceDirFlag, version, time.Now())
+		if err != nil {
+			return fmt.Errorf("failed to export evidence: %w", err)
+		}
+		if bundle != nil {
+			fmt.Fprintf(os.Stderr, "Evidence bundle: %s (manifest sha256 %s)\n", bundle.Dir, bundle.ManifestSHA256)
+		}
+	}
+
+	formatter.InLocation(result, loc)
+	for _, root := range roots {
+		formatter.InLocation(root.Result, loc)
+	}
+
+	// Redact sensitive locations before formatting
+	report := result
+	if len(redactModes) > 0 {
+		redactor := formatter.NewRedactor(redactModes)
+		report = redactor.RedactResult(result)
+		for i := range roots {
+			roots[i].Path = redactor.RedactDir(roots[i].Path)
+			roots[i].Result = redactor.RedactResult(roots[i].Result)
+		}
+		if err := redactor.WriteMapping(redactMapFlag); err != nil {
+			return fmt.Errorf("failed to write redaction mapping: %w", err)
+		}
 	}
 
 	// Format and print results
-	if jsonFlag {
-		output, err := formatter.FormatJSON(result)
+	if format == formatGrype {
+		output, err := formatter.FormatGrypeJSON(report, version)
+		if err != nil {
+			return fmt.Errorf("failed to format grype output: %w", err)
+		}
+		fmt.Println(output)
+	} else if format == formatSARIF {
+		output, err := formatter.FormatSARIF(report, version)
+		if err != nil {
+			return fmt.Errorf("failed to format SARIF output: %w", err)
+		}
+		fmt.Println(output)
+	} else if format == formatAttestMin {
+		output, err := formatter.FormatAttestation(report, version)
+		if err != nil {
+			return fmt.Errorf("failed to format attestation: %w", err)
+		}
+		fmt.Println(output)
+	} else if perRootFlag && len(roots) > 1 {
+		if format == formatJSON {
+			output, err := formatter.FormatJSONRoots(roots)
+			if err != nil {
+				return fmt.Errorf("failed to format JSON output: %w", err)
+			}
+			fmt.Println(output)
+		} else {
+			fmt.Print(formatter.FormatHumanRootsWith(roots, formatter.HumanOptions{Top: topFlag}))
+		}
+	} else if format == formatJSON {
+		output, err := formatter.FormatJSON(report)
 		if err != nil {
 			return fmt.Errorf("failed to format JSON output: %w", err)
 		}
 		fmt.Println(output)
 	} else {
-		output := formatter.FormatHuman(result)
+		output := formatter.FormatHumanWith(report, formatter.HumanOptions{Top: topFlag})
 		fmt.Print(output)
 	}
 
 	// Determine exit code
 	// 0 = clean (no vulnerabilities)
-	// 1 = vulnerabilities found
+	// 1 = vulnerabilities found at or above the --fail-on severity
 	// 2 = error (already handled by returning error above)
-	if len(result.Matches) > 0 {
+	// 3 = not scanned, the IoC database was unavailable (--soft-fail-fetch)
+	if formatter.Fails(result, failOn) {
 		os.Exit(1)
 	}
+	if result.NotScanned != "" {
+		os.Exit(exitNotScanned)
+	}
 
 	return nil
 }
 
+// exitNotScanned is the exit code of scans that soft-failed because the IoC
+// database was unavailable.
+const exitNotScanned = 3
+
+// warnExpired reports suppression rules that have expired, so their
+// findings show up again for a reason.
+func warnExpired(rules []suppress.Rule) {
+	for _, rule := range rules {
+		fmt.Fprintf(os.Stderr, "Warning: suppression of %s expired on %s\n", rule, rule.Expires.Format("2006-01-02"))
+	}
+}
+
+// Output formats accepted by --format.
+const (
+	formatHuman = "human"
+	formatJSON  = "json"
+	formatGrype = "grype"
+	formatSARIF = "sarif"
+	// formatAttestMin is a one-object status record for build gates
+	formatAttestMin = "attest-min"
+)
+
+// outputFormat resolves the output format from --format, falling back to
+// the --json and --grype shorthands.
+func outputFormat() (string, error) {
+	switch formatFlag {
+	case "":
+		if grypeFlag {
+			return formatGrype, nil
+		}
+		if jsonFlag {
+			return formatJSON, nil
+		}
+		return formatHuman, nil
+	case formatHuman, formatJSON, formatGrype, formatSARIF, formatAttestMin:
+		return formatFlag, nil
+	}
+	return "", fmt.Errorf("invalid --format %q (expected %s, %s, %s, %s or %s)", formatFlag, formatHuman, formatJSON, formatGrype, formatSARIF, formatAttestMin)
+}
+
+// scanLogger returns the Logger for the verbose output of scans: stderr
+// when results are machine-readable, so they can be piped, or nil (stdout)
+// for human output.
+func scanLogger(format string) scanner.Logger {
+	if format == formatHuman {
+		return nil
+	}
+	return scanner.NewWriterLogger(os.Stderr)
+}
+
+// validateFeedCheck checks the --feed-check, --feed-min-rows and IoC CSV
+// integrity and header flags, loading the --csv-public-key and parsing the
+// --csv-header values.
+func validateFeedCheck() error {
+	if _, err := ioc.ParseFeedCheckMode(feedCheckFlag); err != nil {
+		return err
+	}
+	if feedMinRowsFlag < 0 {
+		return fmt.Errorf("invalid --feed-min-rows %d: must not be negative", feedMinRowsFlag)
+	}
+	if csvSHA256Flag != "" {
+		if _, err := ioc.ParseSHA256(csvSHA256Flag); err != nil {
+			return fmt.Errorf("invalid --csv-sha256: %w", err)
+		}
+	}
+	header, err := transport.ParseHeaders(csvHeaderFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --csv-header: %w", err)
+	}
+	csvHeader = header
+	if csvSignatureURLFlag != "" && csvPublicKeyFlag == "" {
+		return fmt.Errorf("--csv-signature-url requires --csv-public-key")
+	}
+	if csvPublicKeyFlag != "" {
+		data, err := os.ReadFile(csvPublicKeyFlag)
+		if err != nil {
+			return fmt.Errorf("failed to read --csv-public-key: %w", err)
+		}
+		if csvPublicKey, err = ioc.ParsePublicKey(data); err != nil {
+			return fmt.Errorf("invalid --csv-public-key %s: %w", csvPublicKeyFlag, err)
+		}
+	}
+	return nil
+}
+
+// sharedDatabase loads the IoC database for options, to share between the
+// scans of every path, or returns nil when --source leaves out the CSV
+// feed.
+func sharedDatabase(options scanner.ScanOptions) (*ioc.Database, error) {
+	sources, err := ioc.ParseSources(options.Source)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source == ioc.SourceCSV {
+			return scanner.LoadDatabase(options)
+		}
+	}
+	return nil, nil
+}
+
+// feedCheck returns the IoC feed sanity checks selected by --feed-check and
+// --feed-min-rows, and the integrity checks selected by --csv-sha256,
+// --csv-public-key and --csv-signature-url.
+func feedCheck() ioc.FeedCheck {
+	return ioc.FeedCheck{
+		Mode:         feedCheckFlag,
+		MinRows:      feedMinRowsFlag,
+		SHA256:       csvSHA256Flag,
+		PublicKey:    csvPublicKey,
+		SignatureURL: csvSignatureURLFlag,
+	}
+}
+
+// parseSince parses the --since flag. An empty value disables filtering.
+func parseSince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: expected YYYY-MM-DD", value)
+	}
+	return since, nil
+}
+
 // Execute runs the root command
 func Execute() error {
 	return rootCmd.Execute()
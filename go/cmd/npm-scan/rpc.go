@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/rpc"
+)
+
+var rpcCmd = &cobra.Command{
+	Use:   "rpc",
+	Short: "Serve the scan engine over JSON-RPC on stdin/stdout",
+	Long: `Rpc serves the scan engine over JSON-RPC 2.0 on stdin and stdout, so
+tools written in other languages can delegate detection to it instead of
+reimplementing it.
+
+Each request is one JSON object per line on stdin; each response is written
+as one line on stdout. Methods:
+
+  version        {} -> {"version", "methods"}
+  scan           {"path", "csvUrl", "offline", "since", "source",
+                  "lockfileOnly", "perProject", "hygiene"} -> scan result
+  scanInventory  {"manifests": [{"path", "manifest"}],
+                  "lockfiles": [{"path", "lockfile"}],
+                  "packages": [{"name", "version", "lockfilePath"}],
+                  "csvUrl", "offline", "since"} -> scan result
+
+Scan results have the same shape as npm-scan --json. The session ends when
+stdin is closed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rpc.NewServer(version).Serve(context.Background(), os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(rpcCmd)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/suppress"
+)
+
+var (
+	sbomImageFlag  string
+	sbomOutputFlag string
+)
+
+var sbomCmd = &cobra.Command{
+	Use:   "sbom [file]",
+	Short: "Scan the npm components of an existing SBOM",
+	Long: `Sbom matches the npm components of a CycloneDX or SPDX JSON SBOM, or of
+syft JSON output, against the IoC database. No access to the project's
+filesystem is needed, so SBOMs already generated in CI can be checked directly.
+
+Use --grype to emit grype-compatible match JSON for pipelines standardized on
+Anchore tooling.
+
+Components are identified by their pkg:npm package URL and reported as
+TRANSITIVE matches located at the SBOM file.
+
+Use "npm-scan sbom export" to generate an SBOM of a project instead.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSBOMScan,
+}
+
+var sbomExportCmd = &cobra.Command{
+	Use:   "export [path]",
+	Short: "Export a CycloneDX SBOM with VEX data for a project",
+	Long: `Export writes a CycloneDX 1.5 JSON BOM of every package version discovered
+under path: exact pins from package.json files and every resolved lockfile
+entry. The BOM is produced even when nothing matches the IoC database.
+
+Compromised components (DIRECT and TRANSITIVE matches) are marked with VEX
+entries in the BOM's vulnerabilities, whose analysis state follows the
+remediation status recorded with npm-scan ack. The exit code is 1 when
+compromised components are found, as for a scan.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSBOMExport,
+}
+
+func init() {
+	rootCmd.AddCommand(sbomCmd)
+	sbomCmd.AddCommand(sbomExportCmd)
+
+	sbomCmd.Flags().StringVar(&sbomImageFlag, "image", "", "Scan the SBOM attestations of this container image (requires cosign)")
+	sbomCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+	sbomCmd.Flags().BoolVar(&grypeFlag, "grype", false, "Output results as grype-compatible match JSON")
+	sbomCmd.Flags().StringVar(&formatFlag, "format", "", "Output format: human, json, grype, sarif or attest-min (overrides --json and --grype)")
+	sbomCmd.Flags().StringVar(&failOnFlag, "fail-on", "potential", "Minimum match severity that exits 1: direct, transitive, potential or none")
+	sbomCmd.Flags().IntVar(&topFlag, "top", 0, "Show only the N most significant matches per severity in human output (0: all)")
+	sbomCmd.Flags().StringVar(&timezoneFlag, "timezone", "UTC", "Time zone of report timestamps: UTC, Local or an IANA zone name such as Europe/Berlin")
+	sbomCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+	sbomCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	sbomCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	sbomCmd.Flags().StringArrayVar(&metaFlag, "meta", nil, "Embed key=value metadata in JSON output (repeatable)")
+	sbomCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	sbomCmd.Flags().StringVar(&ignoreFileFlag, "ignore-file", suppress.DefaultPath, "Suppression file of acknowledged findings (see npm-scan baseline)")
+	sbomCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to annotate findings (see npm-scan ack)")
+}
+
+func init() {
+	sbomExportCmd.Flags().StringVarP(&sbomOutputFlag, "output", "o", "", "Write the BOM to this file instead of stdout")
+	sbomExportCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only inventory lockfiles, skip package.json")
+	sbomExportCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	sbomExportCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	sbomExportCmd.Flags().StringVar(&sourceFlag, "source", ioc.SourceCSV, "IoC sources: csv (shai-hulud list), osv (OSV.dev, exact versions only), ghsa (GitHub Advisory Database), or a comma-separated list such as csv,osv")
+	sbomExportCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	sbomExportCmd.Flags().StringVar(&failOnFlag, "fail-on", "potential", "Minimum match severity that exits 1: direct, transitive, potential or none")
+	sbomExportCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to set the VEX analysis state (see npm-scan ack)")
+}
+
+func runSBOMScan(cmd *cobra.Command, args []string) error {
+	format, err := outputFormat()
+	if err != nil {
+		return err
+	}
+
+	failOn, err := formatter.ParseFailOn(failOnFlag)
+	if err != nil {
+		return err
+	}
+	if topFlag < 0 {
+		return fmt.Errorf("invalid --top %d: must not be negative", topFlag)
+	}
+
+	loc, err := formatter.ParseTimezone(timezoneFlag)
+	if err != nil {
+		return err
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	metadata, err := formatter.ParseMetadata(metaFlag)
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	popularityLookup, err := popularityClient()
+	if err != nil {
+		return err
+	}
+
+	suppressions, err := suppress.Load(ignoreFileFlag)
+	if err != nil {
+		return err
+	}
+
+	options := scanner.ScanOptions{
+		CSVURL:        csvURLFlag,
+		CSVHeader:     csvHeader,
+		Offline:       offlineFlag,
+		DatabaseCache: dbCache(),
+		FeedCheck:     feedCheck(),
+		Verbose:       verboseFlag,
+		Logger:        scanLogger(format),
+		Since:         since,
+		Context:       context.Background(),
+	}
+
+	var result *formatter.ScanResult
+	switch {
+	case sbomImageFlag != "" && len(args) > 0:
+		return fmt.Errorf("specify either an SBOM file or --image, not both")
+	case sbomImageFlag != "":
+		options.Path = sbomImageFlag
+		result, err = scanner.RunImageScan(options)
+	case len(args) > 0:
+		options.Path = args[0]
+		result, err = scanner.RunSBOMScan(options)
+	default:
+		return fmt.Errorf("an SBOM file or --image is required")
+	}
+	if err != nil {
+		return fmt.Errorf("SBOM scan failed: %w", err)
+	}
+	result.Metadata = metadata
+	store.Annotate(result)
+	annotatePopularity(popularityLookup, result)
+	warnExpired(suppressions.Apply(result, time.Now()))
+	formatter.InLocation(result, loc)
+
+	switch format {
+	case formatGrype:
+		output, err := formatter.FormatGrypeJSON(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format grype output: %w", err)
+		}
+		fmt.Println(output)
+	case formatSARIF:
+		output, err := formatter.FormatSARIF(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format SARIF output: %w", err)
+		}
+		fmt.Println(output)
+	case formatAttestMin:
+		output, err := formatter.FormatAttestation(result, version)
+		if err != nil {
+			return fmt.Errorf("failed to format attestation: %w", err)
+		}
+		fmt.Println(output)
+	case formatJSON:
+		output, err := formatter.FormatJSON(result)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(output)
+	default:
+		fmt.Print(formatter.FormatHumanWith(result, formatter.HumanOptions{Top: topFlag}))
+	}
+
+	if formatter.Fails(result, failOn) {
+		os.Exit(1)
+	}
+
+	return nil
+}
+
+func runSBOMExport(cmd *cobra.Command, args []string) error {
+	scanPath := "."
+	if len(args) > 0 {
+		scanPath = args[0]
+	}
+	if _, err := os.Stat(scanPath); os.IsNotExist(err) {
+		return fmt.Errorf("path does not exist: %s", scanPath)
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	failOn, err := formatter.ParseFailOn(failOnFlag)
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	components, err := scanner.CollectComponents(scanPath, lockfileOnlyFlag)
+	if err != nil {
+		return err
+	}
+
+	result, err := scanner.RunScan(scanner.ScanOptions{
+		Path:            scanPath,
+		CSVURL:          csvURLFlag,
+		CSVHeader:       csvHeader,
+		Offline:         offlineFlag,
+		DatabaseCache:   dbCache(),
+		FeedCheck:       feedCheck(),
+		Source:          sourceFlag,
+		LockfileOnly:    lockfileOnlyFlag,
+		Since:           since,
+		SkipGitMetadata: true,
+		Context:         context.Background(),
+	})
+	if err != nil {
+		return fmt.Errorf("scan of %s failed: %w", scanPath, err)
+	}
+	store.Annotate(result)
+
+	output, err := formatter.FormatCycloneDX(components, result, version)
+	if err != nil {
+		return fmt.Errorf("failed to format CycloneDX output: %w", err)
+	}
+
+	if sbomOutputFlag == "" {
+		fmt.Println(output)
+	} else {
+		if err := readonly.WriteFile(sbomOutputFlag, []byte(output+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write SBOM: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d components to %s\n", len(components), sbomOutputFlag)
+	}
+
+	if formatter.Fails(result, failOn) {
+		os.Exit(1)
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/serve"
+)
+
+var (
+	serveListenFlag string
+	serveTTLFlag    time.Duration
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve scans over HTTP with the IoC database kept in memory",
+	Long: `Serve runs a long-lived HTTP server, so internal tooling can request scans
+instead of starting npm-scan for each one. The IoC database is loaded at
+startup, kept in memory and reloaded every --ttl; if a reload fails, the
+previous copy stays in use.
+
+Endpoints:
+
+  POST /scan     {"path"} scans a directory on the server;
+                 {"files": [{"path", "content"}]} scans uploaded
+                 package.json, package-lock.json, npm-shrinkwrap.json and
+                 yarn.lock contents. Optional: "lockfileOnly", "perProject",
+                 "hygiene", "since". Returns the npm-scan --json result.
+  GET  /healthz  {"status", "version", "iocCount", "loadedAt", "error"}
+
+Directory scans read the server's filesystem with its permissions, so the
+server listens on localhost unless --listen says otherwise.`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveListenFlag, "listen", "127.0.0.1:8080", "Address to listen on")
+	serveCmd.Flags().DurationVar(&serveTTLFlag, "ttl", time.Hour, "How often to reload the IoC database (0 disables reloading)")
+	serveCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	serveCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	serveCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	serveCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if serveTTLFlag < 0 {
+		return fmt.Errorf("invalid --ttl %s: must not be negative", serveTTLFlag)
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	server := serve.NewServer(serve.Options{
+		Database: scanner.ScanOptions{
+			CSVURL:        csvURLFlag,
+			CSVHeader:     csvHeader,
+			Offline:       offlineFlag,
+			DatabaseCache: dbCache(),
+			FeedCheck:     feedCheck(),
+			Since:         since,
+			Verbose:       verboseFlag,
+		},
+		TTL:     serveTTLFlag,
+		Version: version,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Serving on http://%s\n", serveListenFlag)
+	return server.ListenAndServe(ctx, serveListenFlag)
+}
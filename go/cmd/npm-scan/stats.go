@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var statsWorkersFlag []int
+
+var statsCmd = &cobra.Command{
+	Use:   "stats [path...]",
+	Short: "Count dependency files and estimate the scan duration",
+	Long: `Stats walks the given paths like a scan would and reports how many manifests,
+lockfiles and node_modules trees they hold, their total size, and the
+estimated scan duration per worker count, to plan fleet sweeps and pick
+worker counts before committing to a full run.
+
+The IoC database is not fetched. A sample of the files is parsed to measure
+throughput; estimates exclude the database fetch and are rough, since this
+walk warms filesystem caches for the next one.`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+	statsCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Plan a scan of lockfiles only")
+	statsCmd.Flags().BoolVar(&installedFlag, "installed", false, "Also count the packages installed in node_modules, as scanned by --installed")
+	statsCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip paths matching this gitignore-style pattern, in addition to .npmscanignore (repeatable)")
+	statsCmd.Flags().BoolVar(&followSymlinksFlag, "follow-symlinks", false, "Descend into symlinked directories, visiting each directory once")
+	statsCmd.Flags().IntSliceVar(&statsWorkersFlag, "workers", nil, "Worker counts to estimate durations for, e.g. 4,16,64 (default: 1, 2, 4, ... up to the number of CPUs)")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	paths := args
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+
+	ioThrottle, err := newThrottle()
+	if err != nil {
+		return err
+	}
+
+	stats, err := scanner.EstimateScan(paths, scanner.EstimateOptions{
+		Find: scanner.FindOptions{
+			Exclude:        excludeFlag,
+			FollowSymlinks: followSymlinksFlag,
+			Throttle:       ioThrottle,
+		},
+		LockfileOnly: lockfileOnlyFlag,
+		Installed:    installedFlag,
+		Workers:      statsWorkersFlag,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to collect statistics: %w", err)
+	}
+
+	if jsonFlag {
+		output, err := formatter.FormatJSONDiscovery(stats)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(output)
+	} else {
+		fmt.Print(formatter.FormatHumanDiscovery(stats))
+	}
+
+	return nil
+}
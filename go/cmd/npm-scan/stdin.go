@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var stdinTypeFlag string
+
+func init() {
+	rootCmd.Flags().StringVar(&stdinTypeFlag, "stdin-type", "", "Type of the file read from standard input with path -: package-json, package-lock, npm-shrinkwrap or yarn-lock")
+}
+
+// stdinPath is the scan path that reads a single file from standard input.
+const stdinPath = "-"
+
+// stdinLocation is the directory recorded in the locations of matches in a
+// file read from standard input.
+const stdinLocation = "(stdin)"
+
+// stdinFileNames maps the --stdin-type values to the file names that select
+// the parser.
+var stdinFileNames = map[string]string{
+	"package-json":   "package.json",
+	"package-lock":   "package-lock.json",
+	"npm-shrinkwrap": "npm-shrinkwrap.json",
+	"yarn-lock":      "yarn.lock",
+}
+
+// stdinFile returns the location of the file read from standard input, as
+// given by --stdin-type.
+func stdinFile() (string, error) {
+	if stdinTypeFlag == "" {
+		return "", fmt.Errorf("scanning standard input requires --stdin-type: package-json, package-lock, npm-shrinkwrap or yarn-lock")
+	}
+	name, ok := stdinFileNames[stdinTypeFlag]
+	if !ok {
+		return "", fmt.Errorf("invalid --stdin-type %q: expected package-json, package-lock, npm-shrinkwrap or yarn-lock", stdinTypeFlag)
+	}
+	return path.Join(stdinLocation, name), nil
+}
+
+// scanStdin scans the manifest or lockfile read from standard input, for
+// pipelines and pre-receive hooks where the file is not on disk.
+func scanStdin(options scanner.ScanOptions) (*formatter.ScanResult, error) {
+	location, err := stdinFile()
+	if err != nil {
+		return nil, err
+	}
+	content, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read standard input: %w", err)
+	}
+	return scanner.ScanContent(options, location, content)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/throttle"
+)
+
+var (
+	ioRateFlag  int
+	ioPauseFlag time.Duration
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, statsCmd, watchCmd} {
+		cmd.Flags().IntVar(&ioRateFlag, "io-rate", 0, "Visit at most this many files per second while walking directories, to spare live production volumes (0: no limit)")
+		cmd.Flags().DurationVar(&ioPauseFlag, "io-pause", 0, "Pause this long after each directory read, e.g. 10ms (0: no pause)")
+	}
+}
+
+// newThrottle returns the filesystem throttle selected by --io-rate and
+// --io-pause, shared by every scan of the invocation, or nil.
+func newThrottle() (*throttle.Throttle, error) {
+	t, err := throttle.New(ioRateFlag, ioPauseFlag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --io-rate or --io-pause: %w", err)
+	}
+	return t, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+var (
+	topLimitFlag int
+	topSortFlag  string
+)
+
+var topCmd = &cobra.Command{
+	Use:   "top [path]",
+	Short: "Rank projects by dependency exposure",
+	Long: `Top lists the projects with the largest resolved dependency counts and the
+highest proportion of wide version ranges (^, >=, *, x-ranges).
+
+This is an informational report and does not consult the IoC database. It
+helps prioritize where supply-chain exposure is greatest even when there are
+no current matches.
+
+Sort keys:
+  resolved  Number of resolved packages in lockfiles (default)
+  wide      Proportion of declared dependencies using wide ranges`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runTop,
+}
+
+func init() {
+	rootCmd.AddCommand(topCmd)
+
+	topCmd.Flags().IntVarP(&topLimitFlag, "limit", "n", 10, "Number of projects to list (0 for all)")
+	topCmd.Flags().StringVar(&topSortFlag, "sort", scanner.SortByResolved, "Sort key: resolved or wide")
+	topCmd.Flags().BoolVar(&jsonFlag, "json", false, "Output results as JSON")
+}
+
+func runTop(cmd *cobra.Command, args []string) error {
+	scanPath := "."
+	if len(args) > 0 {
+		scanPath = args[0]
+	}
+
+	stats, err := scanner.CollectProjectStats(scanPath)
+	if err != nil {
+		return fmt.Errorf("failed to collect statistics: %w", err)
+	}
+
+	top, err := scanner.TopProjects(stats, topSortFlag, topLimitFlag)
+	if err != nil {
+		return err
+	}
+
+	if jsonFlag {
+		output, err := formatter.FormatJSONStats(top)
+		if err != nil {
+			return fmt.Errorf("failed to format JSON output: %w", err)
+		}
+		fmt.Println(output)
+	} else {
+		fmt.Print(formatter.FormatHumanStats(top))
+	}
+
+	return nil
+}
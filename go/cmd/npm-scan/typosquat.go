@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+)
+
+var (
+	typosquatFlag       bool
+	popularPackagesFlag string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, imageCmd, watchCmd} {
+		cmd.Flags().BoolVar(&typosquatFlag, "typosquat", false, "Report declared dependencies named one typo away from a popular package (e.g. 'lodahs') as WARNING findings")
+		cmd.Flags().StringVar(&popularPackagesFlag, "popular-packages", "", "File of package names added to the bundled popular list, one per line; names on the list are never reported (implies --typosquat)")
+	}
+}
+
+// loadTyposquat returns the detector of the bundled popular package list
+// and the --popular-packages file, or nil, leaving detection off, if
+// neither --typosquat nor --popular-packages is given.
+func loadTyposquat() (*typosquat.Detector, error) {
+	if !typosquatFlag && popularPackagesFlag == "" {
+		return nil, nil
+	}
+	if popularPackagesFlag == "" {
+		return typosquat.Default(), nil
+	}
+	return typosquat.Load(popularPackagesFlag, nil)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/suppress"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watch"
+)
+
+var (
+	watchIntervalFlag time.Duration
+	watchWebhookFlag  string
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [path]",
+	Short: "Rescan whenever package.json files or lockfiles change",
+	Long: `Watch scans a path, then keeps rescanning it whenever a package.json or
+lockfile under it is added, removed or modified, until interrupted. After
+the initial report, each rescan prints only what changed: matches that are
+new and matches that are resolved.
+
+Files are polled every --interval; a burst of writes, such as an npm
+install, is rescanned once it has settled. The IoC database is loaded once
+at startup; restart the watch to pick up new entries.
+
+With --webhook, updates with new or resolved matches are also POSTed to the
+URL as JSON. With --json, every update is printed as one JSON line.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().DurationVar(&watchIntervalFlag, "interval", watch.DefaultInterval, "How often to check the watched files for changes")
+	watchCmd.Flags().StringVar(&watchWebhookFlag, "webhook", "", "POST updates with new or resolved matches to this URL as JSON")
+	watchCmd.Flags().BoolVar(&lockfileOnlyFlag, "lockfile-only", false, "Only watch and scan lockfiles, skip package.json declarations")
+	watchCmd.Flags().StringArrayVar(&excludeFlag, "exclude", nil, "Skip paths matching this gitignore-style pattern, in addition to .npmscanignore (repeatable)")
+	watchCmd.Flags().BoolVar(&followSymlinksFlag, "follow-symlinks", false, "Descend into symlinked directories, visiting each directory once")
+	watchCmd.Flags().BoolVar(&jsonFlag, "json", false, "Print each update as a line of JSON")
+	watchCmd.Flags().IntVar(&topFlag, "top", 0, "Show only the N most significant matches per severity in the initial report (0: all)")
+	watchCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "Enable verbose output")
+	watchCmd.Flags().StringVar(&csvURLFlag, "csv-url", "", "Custom IoC CSV URL")
+	watchCmd.Flags().BoolVar(&offlineFlag, "offline", false, "Use the IoC snapshot embedded in the binary instead of fetching the database (may be stale)")
+	watchCmd.Flags().StringVar(&sinceFlag, "since", "", "Only consider IoC entries added on or after this date (YYYY-MM-DD)")
+	watchCmd.Flags().StringVar(&ignoreFileFlag, "ignore-file", suppress.DefaultPath, "Suppression file of acknowledged findings (see npm-scan baseline)")
+	watchCmd.Flags().StringVar(&remediationFileFlag, "remediation-file", remediation.DefaultStorePath, "Remediation store used to annotate findings (see npm-scan ack)")
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	path := "."
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("cannot watch %s: %w", path, err)
+	}
+	if watchIntervalFlag <= 0 {
+		return fmt.Errorf("invalid --interval %s: must be positive", watchIntervalFlag)
+	}
+	if topFlag < 0 {
+		return fmt.Errorf("invalid --top %d: must not be negative", topFlag)
+	}
+
+	since, err := parseSince(sinceFlag)
+	if err != nil {
+		return err
+	}
+
+	ioThrottle, err := newThrottle()
+	if err != nil {
+		return err
+	}
+
+	watched, err := loadWatchlist()
+	if err != nil {
+		return err
+	}
+
+	required, err := loadPolicy()
+	if err != nil {
+		return err
+	}
+
+	approved, err := loadAllowlist()
+	if err != nil {
+		return err
+	}
+
+	detector, err := loadTyposquat()
+	if err != nil {
+		return err
+	}
+
+	store, err := remediation.Load(remediationFileFlag)
+	if err != nil {
+		return err
+	}
+
+	suppressions, err := suppress.Load(ignoreFileFlag)
+	if err != nil {
+		return err
+	}
+
+	options := scanner.ScanOptions{
+		Path:            path,
+		CSVURL:          csvURLFlag,
+		CSVHeader:       csvHeader,
+		Offline:         offlineFlag,
+		DatabaseCache:   dbCache(),
+		FeedCheck:       feedCheck(),
+		LockfileOnly:    lockfileOnlyFlag,
+		Exclude:         excludeFlag,
+		FollowSymlinks:  followSymlinksFlag,
+		Throttle:        ioThrottle,
+		Watchlist:       watched,
+		Policy:          required,
+		Allowlist:       approved,
+		Typosquat:       detector,
+		Cache:           openCache(),
+		Verbose:         verboseFlag,
+		Since:           since,
+		SkipGitMetadata: true,
+	}
+	if jsonFlag {
+		options.Logger = scanner.NewWriterLogger(os.Stderr)
+	}
+	options.Database, err = scanner.LoadDatabase(options)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	scan := func(ctx context.Context) (*formatter.ScanResult, error) {
+		scanOptions := options
+		scanOptions.Context = ctx
+		result, err := scanner.RunScan(scanOptions)
+		if err != nil {
+			return nil, err
+		}
+		store.Annotate(result)
+		warnExpired(suppressions.Apply(result, time.Now()))
+		return result, nil
+	}
+
+	initial := true
+	report := func(update watch.Update) {
+		if jsonFlag {
+			line, err := json.Marshal(update)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to encode update: %v\n", err)
+			} else {
+				fmt.Println(string(line))
+			}
+		} else {
+			printWatchUpdate(update, initial)
+		}
+		if initial && !jsonFlag {
+			fmt.Fprintf(os.Stderr, "Watching %s for changes every %s (Ctrl-C to stop)\n", path, watchIntervalFlag)
+		}
+		initial = false
+
+		if watchWebhookFlag != "" && (len(update.New) > 0 || len(update.Resolved) > 0) {
+			if err := watch.Notify(ctx, transport.Client(), watchWebhookFlag, update); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: webhook failed: %v\n", err)
+			}
+		}
+	}
+
+	err = watch.Run(ctx, watch.Options{
+		Root:         path,
+		Find:         scanner.FindOptions{Exclude: excludeFlag, FollowSymlinks: followSymlinksFlag, Throttle: ioThrottle},
+		LockfileOnly: lockfileOnlyFlag,
+		Interval:     watchIntervalFlag,
+	}, scan, report)
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
+
+// printWatchUpdate prints the full report of the initial scan, and the
+// new and resolved matches of each rescan.
+func printWatchUpdate(update watch.Update, initial bool) {
+	stamp := update.Time.Format("15:04:05")
+	if update.Error != "" {
+		fmt.Fprintf(os.Stderr, "[%s] Scan failed: %s\n", stamp, update.Error)
+		return
+	}
+	if initial {
+		fmt.Print(formatter.FormatHumanWith(update.Result, formatter.HumanOptions{Top: topFlag}))
+		return
+	}
+
+	fmt.Printf("[%s] %s changed: %d new, %d resolved, %d matches in total\n",
+		stamp, describeChanged(update.Changed), len(update.New), len(update.Resolved), update.Summary.TotalMatches)
+	for _, match := range update.New {
+		fmt.Printf("  + %s %s@%s  %s\n", match.Severity, match.PackageName, match.Version, match.Location)
+	}
+	for _, match := range update.Resolved {
+		fmt.Printf("  - %s %s@%s  %s\n", match.Severity, match.PackageName, match.Version, match.Location)
+	}
+}
+
+// describeChanged names the changed files, or the first and how many more.
+func describeChanged(changed []string) string {
+	switch len(changed) {
+	case 0:
+		return "Nothing"
+	case 1, 2:
+		return strings.Join(changed, ", ")
+	}
+	return fmt.Sprintf("%s and %d more files", changed[0], len(changed)-1)
+}
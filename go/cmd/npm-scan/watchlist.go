@@ -0,0 +1,27 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
+)
+
+var (
+	watchFlag     []string
+	watchlistFlag string
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{rootCmd, bulkCmd, imageCmd, watchCmd} {
+		cmd.Flags().StringArrayVar(&watchFlag, "watch", nil, "Report packages whose name matches this glob (e.g. '@mycorp-*') or re:<regexp>, whatever their version (repeatable)")
+		cmd.Flags().StringVar(&watchlistFlag, "watchlist", "", "File of package name patterns to report, one per line (see --watch)")
+	}
+}
+
+// loadWatchlist compiles the --watchlist file and --watch patterns, or
+// returns nil if neither is given.
+func loadWatchlist() (*watchlist.Watchlist, error) {
+	if watchlistFlag == "" && len(watchFlag) == 0 {
+		return nil, nil
+	}
+	return watchlist.Load(watchlistFlag, watchFlag)
+}
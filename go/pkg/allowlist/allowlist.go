@@ -0,0 +1,114 @@
+// Package allowlist gates dependency trees on a list of approved packages:
+// in strict mode every package that is not on the list is reported as
+// UNAPPROVED, so a scan can double as a dependency gate. Entries are
+// package names or whole scopes:
+//
+//	# Comments and blank lines are ignored
+//	lodash
+//	@mycorp
+//	@vendor/*
+//
+// A scope, written with or without a trailing "/*", approves every package
+// published under it. Names are compared exactly; there are no wildcards,
+// so the gate approves nothing by accident.
+package allowlist
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Allowlist is a set of approved package names and scopes. A nil
+// Allowlist approves every package; an empty one approves none.
+type Allowlist struct {
+	names  map[string]bool
+	scopes map[string]bool
+}
+
+// New parses entries, one per element, skipping blank lines and comments.
+func New(entries []string) (*Allowlist, error) {
+	a := &Allowlist{names: make(map[string]bool), scopes: make(map[string]bool)}
+	for _, line := range entries {
+		entry := strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if strings.ContainsAny(entry, " \t?") || (strings.Contains(entry, "*") && !strings.HasSuffix(entry, "/*")) {
+			return nil, fmt.Errorf("invalid allowlist entry %q: expected a package name, @scope or @scope/*", entry)
+		}
+
+		if scope, ok := parseScope(entry); ok {
+			a.scopes[scope] = true
+			continue
+		}
+		if strings.HasSuffix(entry, "/*") || strings.HasSuffix(entry, "/") || entry == "@" {
+			return nil, fmt.Errorf("invalid allowlist entry %q: expected a package name, @scope or @scope/*", entry)
+		}
+		a.names[entry] = true
+	}
+	return a, nil
+}
+
+// parseScope returns the scope an "@scope" or "@scope/*" entry approves.
+func parseScope(entry string) (string, bool) {
+	if !strings.HasPrefix(entry, "@") {
+		return "", false
+	}
+	scope := strings.TrimSuffix(entry, "/*")
+	if scope == "@" || strings.Contains(scope, "/") {
+		return "", false
+	}
+	return scope, true
+}
+
+// Load reads the allowlist file at path, if path is not empty, followed by
+// the extra entries.
+func Load(path string, extra []string) (*Allowlist, error) {
+	var lines []string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read allowlist: %w", err)
+		}
+		lines = strings.Split(string(data), "\n")
+	}
+	return New(append(lines, extra...))
+}
+
+// Len returns the number of approved names and scopes.
+func (a *Allowlist) Len() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.names) + len(a.scopes)
+}
+
+// Entries returns the approved names and scopes, sorted.
+func (a *Allowlist) Entries() []string {
+	if a == nil {
+		return nil
+	}
+	entries := make([]string, 0, a.Len())
+	for name := range a.names {
+		entries = append(entries, name)
+	}
+	for scope := range a.scopes {
+		entries = append(entries, scope)
+	}
+	sort.Strings(entries)
+	return entries
+}
+
+// Allows reports whether the package name is approved, by name or by
+// scope.
+func (a *Allowlist) Allows(name string) bool {
+	if a == nil || a.names[name] {
+		return true
+	}
+	if scope, _, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return a.scopes[scope]
+	}
+	return false
+}
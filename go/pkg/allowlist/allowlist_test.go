@@ -0,0 +1,93 @@
+package allowlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry string
+	}{
+		{"glob", "lodash*"},
+		{"glob inside scope", "@corp/ui-*"},
+		{"bare at", "@"},
+		{"empty scope wildcard", "@/*"},
+		{"trailing slash", "@corp/"},
+		{"unscoped wildcard", "lodash/*"},
+		{"whitespace", "lo dash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]string{tt.entry}); err == nil {
+				t.Errorf("New(%q) succeeded, want an error", tt.entry)
+			}
+		})
+	}
+}
+
+func TestAllows(t *testing.T) {
+	list, err := New([]string{"# approved", "", "lodash", "@mycorp", "@vendor/*", "@other/tool"})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if got := list.Len(); got != 4 {
+		t.Errorf("Len() = %d, want 4", got)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"lodash", true},
+		{"lodash-es", false},
+		{"@mycorp/ui", true},
+		{"@mycorp-evil/ui", false},
+		{"@vendor/sdk", true},
+		{"@other/tool", true},
+		{"@other/tools", false},
+		{"mycorp", false},
+		{"express", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.Allows(tt.name); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+
+	var none *Allowlist
+	if !none.Allows("anything") {
+		t.Error("nil Allowlist does not allow every package")
+	}
+	empty, _ := New(nil)
+	if empty.Allows("lodash") {
+		t.Error("empty Allowlist allows a package")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "allowlist.txt")
+	if err := os.WriteFile(path, []byte("# approved\r\nlodash\r\n@mycorp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := Load(path, []string{"express"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for _, name := range []string{"lodash", "@mycorp/ui", "express"} {
+		if !list.Allows(name) {
+			t.Errorf("Allows(%q) = false, want true", name)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("Load of a missing file succeeded, want an error")
+	}
+}
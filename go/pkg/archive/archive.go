@@ -0,0 +1,184 @@
+// Package archive reads the npm files of release artifacts, so tarballs
+// produced by npm pack, downloaded from a registry or built for deployment
+// can be scanned before they are installed or shipped.
+//
+// Gzip-compressed tarballs (.tgz, .tar.gz), plain tarballs (.tar) and zip
+// archives (.zip) are supported. Only the files a caller keeps are read, in
+// memory; nothing is extracted to disk.
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxFileSize bounds the size of a single file read from an archive.
+// Larger files are skipped.
+const maxFileSize = 64 << 20
+
+// Archive is the view of an archive restricted to the files kept from it.
+type Archive struct {
+	// Path is the path of the archive file
+	Path string
+	// Files maps the clean, relative slash-separated paths of the kept
+	// entries to their contents
+	Files map[string][]byte
+}
+
+// Paths returns the paths of the archive's files, sorted.
+func (a *Archive) Paths() []string {
+	paths := make([]string, 0, len(a.Files))
+	for p := range a.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// IsArchive reports whether a file name has the extension of a supported
+// archive format.
+func IsArchive(name string) bool {
+	return format(name) != ""
+}
+
+// format returns the archive format selected by the extension of name, or
+// "" if it is not an archive.
+func format(name string) string {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tgz"), strings.HasSuffix(lower, ".tar.gz"):
+		return "tgz"
+	case strings.HasSuffix(lower, ".tar"):
+		return "tar"
+	case strings.HasSuffix(lower, ".zip"):
+		return "zip"
+	}
+	return ""
+}
+
+// Read reads the regular files of the archive at archivePath whose base
+// name keep accepts. The format is selected by the file extension.
+// Entries escaping the archive root, such as ../package.json, are skipped.
+func Read(archivePath string, keep func(name string) bool) (*Archive, error) {
+	a := &Archive{Path: archivePath, Files: make(map[string][]byte)}
+	var err error
+	switch format(archivePath) {
+	case "tgz":
+		err = a.readTar(keep, true)
+	case "tar":
+		err = a.readTar(keep, false)
+	case "zip":
+		err = a.readZip(keep)
+	default:
+		return nil, fmt.Errorf("unsupported archive %s: expected .tgz, .tar.gz, .tar or .zip", archivePath)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// readTar reads the kept files of a tarball, gzip-compressed if compressed
+// is set.
+func (a *Archive) readTar(keep func(name string) bool, compressed bool) error {
+	f, err := os.Open(a.Path)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer f.Close()
+
+	var source io.Reader = f
+	if compressed {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("decompress archive %s: %w", a.Path, err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	tr := tar.NewReader(source)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read archive %s: %w", a.Path, err)
+		}
+		if header.Typeflag != tar.TypeReg || header.Size > maxFileSize {
+			continue
+		}
+		name, ok := cleanName(header.Name)
+		if !ok || !keep(path.Base(name)) {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("read %s from archive %s: %w", name, a.Path, err)
+		}
+		a.Files[name] = content
+	}
+}
+
+// readZip reads the kept files of a zip archive.
+func (a *Archive) readZip(keep func(name string) bool) error {
+	zr, err := zip.OpenReader(a.Path)
+	if err != nil {
+		return fmt.Errorf("read archive %s: %w", a.Path, err)
+	}
+	defer zr.Close()
+
+	for _, entry := range zr.File {
+		if !entry.Mode().IsRegular() || entry.UncompressedSize64 > maxFileSize {
+			continue
+		}
+		name, ok := cleanName(entry.Name)
+		if !ok || !keep(path.Base(name)) {
+			continue
+		}
+		content, err := readZipEntry(entry)
+		if err != nil {
+			return fmt.Errorf("read %s from archive %s: %w", name, a.Path, err)
+		}
+		a.Files[name] = content
+	}
+	return nil
+}
+
+// readZipEntry reads a zip entry, refusing more than maxFileSize bytes even
+// if its header understates its size.
+func readZipEntry(entry *zip.File) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxFileSize {
+		return nil, fmt.Errorf("entry exceeds %d bytes", maxFileSize)
+	}
+	return content, nil
+}
+
+// cleanName turns an entry name into a clean relative path, reporting
+// false for names that escape the archive root.
+func cleanName(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := path.Clean(strings.TrimLeft(name, "/"))
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", false
+	}
+	return cleaned, true
+}
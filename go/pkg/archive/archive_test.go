@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testEntries are the entries of the test archives: an npm pack layout with
+// a bundled dependency, an entry escaping the root and a file not kept.
+var testEntries = [][2]string{
+	{"package/package.json", `{"name":"app","version":"1.0.0"}`},
+	{"package/node_modules/evil/package.json", `{"name":"evil","version":"1.0.1"}`},
+	{"../package.json", `{}`},
+	{"package/index.js", `module.exports = {}`},
+}
+
+// keepJSON keeps package.json files.
+func keepJSON(name string) bool {
+	return name == "package.json"
+}
+
+// writeTar writes a tarball of entries to dir/name, gzip-compressed if
+// compressed is set.
+func writeTar(t *testing.T, dir, name string, compressed bool) string {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "package/", Mode: 0755, Typeflag: tar.TypeDir}); err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range testEntries {
+		header := &tar.Header{Name: entry[0], Mode: 0644, Size: int64(len(entry[1])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := buf.Bytes()
+	if compressed {
+		var gzBuf bytes.Buffer
+		gz := gzip.NewWriter(&gzBuf)
+		if _, err := gz.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+		data = gzBuf.Bytes()
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeZip writes a zip archive of entries to dir/name.
+func writeZip(t *testing.T, dir, name string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range testEntries {
+		w, err := zw.Create(entry[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestIsArchive(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"app-1.0.0.tgz", true},
+		{"release.tar.gz", true},
+		{"RELEASE.TAR.GZ", true},
+		{"release.tar", true},
+		{"release.zip", true},
+		{"package.json", false},
+		{"release.gz", false},
+		{"yarn.lock", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsArchive(tt.name); got != tt.want {
+				t.Errorf("IsArchive(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"npm pack tarball", writeTar(t, dir, "app-1.0.0.tgz", true)},
+		{"gzipped tarball", writeTar(t, dir, "release.tar.gz", true)},
+		{"plain tarball", writeTar(t, dir, "release.tar", false)},
+		{"zip archive", writeZip(t, dir, "release.zip")},
+	}
+
+	want := []string{"package/node_modules/evil/package.json", "package/package.json"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := Read(tt.path, keepJSON)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if got := a.Paths(); !reflect.DeepEqual(got, want) {
+				t.Errorf("Paths() = %v, want %v", got, want)
+			}
+			if got := string(a.Files["package/package.json"]); got != testEntries[0][1] {
+				t.Errorf("package/package.json = %q, want %q", got, testEntries[0][1])
+			}
+		})
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.tgz")
+	if err := os.WriteFile(corrupt, []byte("not gzip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	notZip := filepath.Join(dir, "corrupt.zip")
+	if err := os.WriteFile(notZip, []byte("not zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr string
+	}{
+		{"unsupported extension", filepath.Join(dir, "release.rar"), "unsupported archive"},
+		{"missing file", filepath.Join(dir, "missing.tgz"), "open archive"},
+		{"corrupt gzip", corrupt, "decompress archive"},
+		{"corrupt zip", notZip, "read archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(tt.path, keepJSON)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Read() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
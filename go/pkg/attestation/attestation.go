@@ -0,0 +1,146 @@
+// Package attestation retrieves SBOM attestations attached to container
+// images, so an image's package inventory can be scanned without pulling and
+// unpacking its layers.
+// Downloads shell out to the cosign binary and fail gracefully when cosign is
+// unavailable or the image has no attestations.
+package attestation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// Predicate types of SBOM attestations, as produced by cosign attest --type
+// cyclonedx / spdxjson and syft attest.
+const (
+	PredicateCycloneDX = "https://cyclonedx.org/bom"
+	PredicateSPDX      = "https://spdx.dev/Document"
+)
+
+// ErrNoSBOM is returned when an image has attestations but none carry an SBOM.
+var ErrNoSBOM = errors.New("no SBOM attestation found")
+
+// SBOM is the predicate of a single SBOM attestation.
+type SBOM struct {
+	PredicateType string
+	Document      []byte
+}
+
+// envelope is a DSSE envelope as printed by cosign download attestation.
+type envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+}
+
+// statement is an in-toto statement carried in an envelope payload.
+type statement struct {
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Download fetches the attestations attached to image with cosign and
+// returns the SBOMs among them. Signatures are not verified; use cosign
+// verify-attestation first when provenance matters.
+//
+// Downloading is refused in read-only mode, since cosign maintains caches
+// under the user's home directory.
+func Download(ctx context.Context, image string) ([]SBOM, error) {
+	if readonly.Enabled() {
+		return nil, fmt.Errorf("cosign download attestation %w: cosign writes caches outside the output directory", readonly.ErrReadOnly)
+	}
+
+	cmd := exec.CommandContext(ctx, "cosign", "download", "attestation", image)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("cosign download attestation: %s", msg)
+	}
+
+	return ExtractSBOMs(stdout.Bytes())
+}
+
+// ExtractSBOMs parses cosign download attestation output (one DSSE envelope
+// per line) and returns the SBOM predicates. Envelopes with other predicate
+// types, such as SLSA provenance, are skipped.
+func ExtractSBOMs(output []byte) ([]SBOM, error) {
+	var sboms []SBOM
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 1024*1024), 256*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var env envelope
+		if err := json.Unmarshal(line, &env); err != nil {
+			return nil, fmt.Errorf("parse attestation envelope: %w", err)
+		}
+
+		payload, err := base64.StdEncoding.DecodeString(env.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("decode attestation payload: %w", err)
+		}
+
+		var stmt statement
+		if err := json.Unmarshal(payload, &stmt); err != nil {
+			return nil, fmt.Errorf("parse in-toto statement: %w", err)
+		}
+
+		if !isSBOMPredicate(stmt.PredicateType) {
+			continue
+		}
+
+		document := []byte(stmt.Predicate)
+		// Some producers wrap the document as {"Data": ...}
+		var wrapped struct {
+			Data json.RawMessage `json:"Data"`
+		}
+		if json.Unmarshal(document, &wrapped) == nil && len(wrapped.Data) > 0 {
+			document = wrapped.Data
+			// ...where Data may itself be the document encoded as a string
+			var encoded string
+			if json.Unmarshal(document, &encoded) == nil {
+				document = []byte(encoded)
+			}
+		}
+
+		sboms = append(sboms, SBOM{
+			PredicateType: stmt.PredicateType,
+			Document:      document,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read attestations: %w", err)
+	}
+
+	if len(sboms) == 0 {
+		return nil, ErrNoSBOM
+	}
+
+	return sboms, nil
+}
+
+// isSBOMPredicate reports whether an in-toto predicate type is an SBOM.
+func isSBOMPredicate(predicateType string) bool {
+	return strings.HasPrefix(predicateType, PredicateCycloneDX) ||
+		strings.HasPrefix(predicateType, PredicateSPDX)
+}
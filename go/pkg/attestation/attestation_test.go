@@ -0,0 +1,67 @@
+package attestation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// envelopeLine builds one line of cosign download attestation output.
+func envelopeLine(t *testing.T, predicateType string, predicate interface{}) string {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v0.1",
+		"predicateType": predicateType,
+		"predicate":     predicate,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	line, err := json.Marshal(envelope{
+		PayloadType: "application/vnd.in-toto+json",
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(line)
+}
+
+func TestExtractSBOMs(t *testing.T) {
+	output := strings.Join([]string{
+		envelopeLine(t, "https://slsa.dev/provenance/v0.2", map[string]string{"builder": "ci"}),
+		envelopeLine(t, PredicateCycloneDX, map[string]string{"bomFormat": "CycloneDX"}),
+		envelopeLine(t, PredicateSPDX, map[string]string{"Data": `{"spdxVersion":"SPDX-2.3"}`}),
+	}, "\n")
+
+	sboms, err := ExtractSBOMs([]byte(output))
+	if err != nil {
+		t.Fatalf("ExtractSBOMs failed: %v", err)
+	}
+	if len(sboms) != 2 {
+		t.Fatalf("expected 2 SBOMs, got %d", len(sboms))
+	}
+	if !strings.Contains(string(sboms[0].Document), `"bomFormat":"CycloneDX"`) {
+		t.Errorf("unexpected CycloneDX document: %s", sboms[0].Document)
+	}
+	if string(sboms[1].Document) != `{"spdxVersion":"SPDX-2.3"}` {
+		t.Errorf("expected wrapped SPDX document to be unwrapped, got %s", sboms[1].Document)
+	}
+}
+
+func TestExtractSBOMs_NoSBOM(t *testing.T) {
+	output := envelopeLine(t, "https://slsa.dev/provenance/v0.2", map[string]string{})
+
+	if _, err := ExtractSBOMs([]byte(output)); !errors.Is(err, ErrNoSBOM) {
+		t.Errorf("expected ErrNoSBOM, got %v", err)
+	}
+}
+
+func TestExtractSBOMs_Malformed(t *testing.T) {
+	if _, err := ExtractSBOMs([]byte("not json")); err == nil {
+		t.Error("expected error for malformed output")
+	}
+}
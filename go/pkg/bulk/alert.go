@@ -0,0 +1,157 @@
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// AlertKind selects what an alert rule checks.
+type AlertKind string
+
+const (
+	// AlertNewDirect fires when a path has more DIRECT matches than in the
+	// previous run
+	AlertNewDirect AlertKind = "new-direct"
+	// AlertIncrease fires when total matches grow by more than a percentage
+	// of the previous run's total
+	AlertIncrease AlertKind = "increase"
+)
+
+// AlertRule is a single threshold rule evaluated against the previous run.
+type AlertRule struct {
+	Kind AlertKind
+	// Percent is the growth threshold for AlertIncrease
+	Percent float64
+}
+
+// Alert is a rule that fired for the current run.
+type Alert struct {
+	Rule    string `json:"rule"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// ParseAlertRules parses a comma-separated list of alert rules, e.g.
+// "new-direct" or "new-direct,increase:10%".
+func ParseAlertRules(spec string) ([]AlertRule, error) {
+	var rules []AlertRule
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+
+		kind, arg, _ := strings.Cut(part, ":")
+		switch AlertKind(kind) {
+		case AlertNewDirect:
+			rules = append(rules, AlertRule{Kind: AlertNewDirect})
+		case AlertIncrease:
+			percent, err := strconv.ParseFloat(strings.TrimSuffix(arg, "%"), 64)
+			if err != nil || percent < 0 {
+				return nil, fmt.Errorf("invalid alert rule %q (expected increase:<percent>)", part)
+			}
+			rules = append(rules, AlertRule{Kind: AlertIncrease, Percent: percent})
+		default:
+			return nil, fmt.Errorf("unknown alert rule %q (expected new-direct or increase:<percent>)", part)
+		}
+	}
+	return rules, nil
+}
+
+// EvaluateAlerts checks the current run against the previous one. previous
+// may be nil for a first run, in which case every DIRECT match is new and
+// increase rules have no baseline and never fire.
+func EvaluateAlerts(rules []AlertRule, previous, current *BulkSummary) []Alert {
+	var alerts []Alert
+
+	for _, rule := range rules {
+		switch rule.Kind {
+		case AlertNewDirect:
+			paths := make([]string, 0, len(current.PathResults))
+			for path := range current.PathResults {
+				paths = append(paths, path)
+			}
+			sort.Strings(paths)
+
+			for _, path := range paths {
+				now := current.PathResults[path].DirectMatches
+				before := 0
+				if previous != nil && previous.PathResults[path] != nil {
+					before = previous.PathResults[path].DirectMatches
+				}
+				if now > before {
+					alerts = append(alerts, Alert{
+						Rule:    string(AlertNewDirect),
+						Path:    path,
+						Message: fmt.Sprintf("DIRECT matches rose from %d to %d", before, now),
+					})
+				}
+			}
+
+		case AlertIncrease:
+			if previous == nil {
+				continue
+			}
+			before, now := previous.TotalMatches, current.TotalMatches
+			if now <= before {
+				continue
+			}
+			if before == 0 || float64(now-before)*100/float64(before) > rule.Percent {
+				alerts = append(alerts, Alert{
+					Rule:    fmt.Sprintf("%s:%g%%", AlertIncrease, rule.Percent),
+					Message: fmt.Sprintf("total matches rose from %d to %d", before, now),
+				})
+			}
+		}
+	}
+
+	return alerts
+}
+
+// loadPreviousSummary reads the summary.json of the most recent run in
+// outputDir before currentDir. Run directories are named by timestamp, so
+// lexical order is chronological. Returns nil if there is no earlier run.
+func loadPreviousSummary(outputDir, currentDir string) (*BulkSummary, error) {
+	entries, err := os.ReadDir(outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	current := filepath.Base(currentDir)
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if !entry.IsDir() || entry.Name() >= current {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(outputDir, entry.Name(), "summary.json"))
+		if err != nil {
+			// Interrupted runs leave no summary; keep looking
+			continue
+		}
+
+		var summary BulkSummary
+		if err := json.Unmarshal(data, &summary); err != nil {
+			return nil, fmt.Errorf("failed to parse %s summary: %w", entry.Name(), err)
+		}
+		return &summary, nil
+	}
+
+	return nil, nil
+}
+
+// writeAlerts writes the fired alerts to a JSON file.
+func writeAlerts(alerts []Alert, path string) error {
+	data, err := json.MarshalIndent(alerts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return readonly.WriteFile(path, data, 0644)
+}
@@ -5,14 +5,25 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/remediation"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/throttle"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
 )
 
 // BulkOptions configures bulk scan behavior.
@@ -26,16 +37,122 @@ type BulkOptions struct {
 	// NumWorkers is the number of concurrent workers (goroutines) to use
 	NumWorkers int
 
+	// NumWriters is the number of goroutines writing result files
+	// (defaults to NumWorkers)
+	NumWriters int
+
+	// SyncBatch fsyncs result files in batches of this many files.
+	// If zero, files are not explicitly synced.
+	SyncBatch int
+
 	// CSVURL is the IoC database URL (passed to scanner)
 	CSVURL string
 
+	// CSVHeader holds extra IoC database request headers (passed to scanner)
+	CSVHeader http.Header
+
+	// Offline uses the embedded IoC snapshot (passed to scanner)
+	Offline bool
+
+	// DatabaseCache holds the IoC databases used offline (passed to scanner)
+	DatabaseCache *ioc.DBCache
+
+	// FeedCheck configures the IoC feed sanity checks (passed to scanner)
+	FeedCheck ioc.FeedCheck
+
+	// Source selects the IoC source (passed to scanner)
+	Source string
+
+	// Database is a preloaded IoC database for the csv source. If nil, it
+	// is loaded once from CSVURL (or the embedded snapshot when Offline)
+	// and shared by every path, instead of being fetched once per path.
+	Database *ioc.Database
+
 	// LockfileOnly determines whether to skip manifests (passed to scanner)
 	LockfileOnly bool
 
+	// Exclude holds gitignore-style patterns of paths to skip in every
+	// scanned path (passed to scanner)
+	Exclude []string
+
+	// FollowSymlinks descends into symlinked directories (passed to scanner)
+	FollowSymlinks bool
+
+	// Throttle limits the pace of file discovery, shared by all workers
+	// (passed to scanner)
+	Throttle *throttle.Throttle
+
+	// Watchlist reports packages whose name matches a pattern (passed to scanner)
+	Watchlist *watchlist.Watchlist
+
+	// Policy reports package versions violating a required safe version
+	// range (passed to scanner)
+	Policy *policy.Policy
+
+	// Allowlist reports packages missing from the approved list (passed to
+	// scanner)
+	Allowlist *allowlist.Allowlist
+
+	// Typosquat reports dependencies named like popular packages (passed to
+	// scanner)
+	Typosquat *typosquat.Detector
+
+	// Cache reuses the results of unchanged files across runs (passed to
+	// scanner)
+	Cache *scanner.ResultCache
+
+	// Since restricts matching to IoC entries added on or after this date (passed to scanner)
+	Since time.Time
+
+	// Metadata is embedded in every JSON result and the summary
+	Metadata map[string]string
+
+	// SoftFailFetch reports paths that could not be scanned because the IoC
+	// database was unavailable as "not-scanned" rather than failed;
+	// RunBulkScan then returns ErrNotScanned once the results are written
+	SoftFailFetch bool
+
+	// Location is the time zone of the timestamps in the summary, the JSON
+	// results and the run directory name; nil means UTC
+	Location *time.Location
+
+	// SkipGitMetadata disables git repository metadata (passed to scanner)
+	SkipGitMetadata bool
+
+	// Remediation, if set, annotates findings with their recorded triage state
+	Remediation *remediation.Store
+
+	// Order breaks ties between paths of equal priority: OrderFile (the
+	// default) keeps paths file order, OrderMatches scans paths with the
+	// most matches in the previous run first
+	Order string
+
+	// SkipUnchanged is a previous run directory. Paths whose dependency
+	// files have the same fingerprint as in that run reuse its result
+	// instead of being scanned again.
+	SkipUnchanged string
+
+	// AlertRules are evaluated against the previous run in OutputDir; fired
+	// alerts are printed and written to alerts.json
+	AlertRules []AlertRule
+
+	// Progress, if set, is called as each path completes, with the number
+	// of completed paths and their total
+	Progress scanner.ProgressFunc
+
+	// Output receives the status line printed as each path completes; if
+	// nil, os.Stdout is used. Callers drawing a progress display can keep
+	// the lines from interleaving with it.
+	Output io.Writer
+
 	// Context for cancellation
 	Context context.Context
 }
 
+// ErrNotScanned is returned by RunBulkScan with SoftFailFetch set when some
+// paths were not scanned because the IoC database was unavailable.
+var ErrNotScanned = errors.New("some paths were not scanned: IoC database unavailable")
+
 // BulkSummary represents the summary.json output for bulk scans.
 type BulkSummary struct {
 	StartTime        time.Time                  `json:"startTime"`
@@ -44,21 +161,62 @@ type BulkSummary struct {
 	TotalPaths       int                        `json:"totalPaths"`
 	SuccessfulScans  int                        `json:"successfulScans"`
 	FailedScans      int                        `json:"failedScans"`
+	UnchangedScans   int                        `json:"unchangedScans,omitempty"`
+	NotScannedScans  int                        `json:"notScannedScans,omitempty"`
 	TotalMatches     int                        `json:"totalMatches"`
+	ErrorCounts      map[ErrorType]int          `json:"errorCounts,omitempty"`
 	PathResults      map[string]*PathSummary    `json:"pathResults"`
+	Metadata         map[string]string          `json:"metadata,omitempty"`
 }
 
 // PathSummary represents the summary for a single scanned path.
 type PathSummary struct {
 	Path              string              `json:"path"`
-	Status            string              `json:"status"` // "success" or "error"
+	Status            string              `json:"status"` // "success", "error" or "not-scanned"
 	Error             string              `json:"error,omitempty"`
+	ErrorType         ErrorType           `json:"errorType,omitempty"`
 	ManifestsScanned  int                 `json:"manifestsScanned"`
 	LockfilesScanned  int                 `json:"lockfilesScanned"`
 	PackagesChecked   int                 `json:"packagesChecked"`
 	MatchesFound      int                 `json:"matchesFound"`
+	DirectMatches     int                 `json:"directMatches"`
 	ResultFile        string              `json:"resultFile,omitempty"`
 	OutputFile        string              `json:"outputFile,omitempty"`
+	// Fingerprint hashes the path's manifests and lockfiles
+	Fingerprint       string              `json:"fingerprint,omitempty"`
+	// Unchanged is set when the result was reused from the previous run
+	Unchanged         bool                `json:"unchanged,omitempty"`
+}
+
+// loadDatabase returns the IoC database shared by the scans of a bulk run,
+// restricted to options.Since: options.Database, or the csv source loaded as
+// a scan would. Returns nil if the csv source is not selected, leaving any
+// other source to each scan.
+func loadDatabase(options BulkOptions) (*ioc.Database, error) {
+	if options.Database != nil {
+		if !options.Since.IsZero() {
+			return options.Database.Since(options.Since), nil
+		}
+		return options.Database, nil
+	}
+
+	sources, err := ioc.ParseSources(options.Source)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source == ioc.SourceCSV {
+			return scanner.LoadDatabase(scanner.ScanOptions{
+				CSVURL:    options.CSVURL,
+				CSVHeader:     options.CSVHeader,
+				Offline:       options.Offline,
+				DatabaseCache: options.DatabaseCache,
+				FeedCheck:     options.FeedCheck,
+				Since:         options.Since,
+			})
+		}
+	}
+	return nil, nil
 }
 
 // RunBulkScan executes bulk scanning for multiple paths concurrently.
@@ -74,31 +232,72 @@ func RunBulkScan(options BulkOptions) error {
 	if options.OutputDir == "" {
 		options.OutputDir = "results"
 	}
+	if options.NumWriters == 0 {
+		options.NumWriters = options.NumWorkers
+	}
+	if options.Location == nil {
+		options.Location = time.UTC
+	}
 	if options.Context == nil {
 		options.Context = context.Background()
 	}
+	if options.Output == nil {
+		options.Output = os.Stdout
+	}
+	if options.Order == "" {
+		options.Order = OrderFile
+	}
+	if options.Order != OrderFile && options.Order != OrderMatches {
+		return fmt.Errorf("unknown order %q (expected %s or %s)", options.Order, OrderFile, OrderMatches)
+	}
 
 	// Read paths from file
-	paths, err := readPathsFile(options.PathsFile)
+	entries, err := readPathsFile(options.PathsFile)
 	if err != nil {
 		return fmt.Errorf("failed to read paths file: %w", err)
 	}
 
-	if len(paths) == 0 {
+	if len(entries) == 0 {
 		return fmt.Errorf("no paths found in %s", options.PathsFile)
 	}
 
-	fmt.Printf("Starting bulk scan of %d paths with %d workers...\n", len(paths), options.NumWorkers)
+	var previousRun *BulkSummary
+	if options.SkipUnchanged != "" {
+		previousRun, err = loadRunSummary(options.SkipUnchanged)
+		if err != nil {
+			return fmt.Errorf("failed to load previous run: %w", err)
+		}
+	}
 
 	// Create timestamped output directory
-	timestamp := startTime.Format("20060102-150405")
+	timestamp := startTime.In(options.Location).Format("20060102-150405")
 	resultsDir := filepath.Join(options.OutputDir, timestamp)
-	if err := os.MkdirAll(resultsDir, 0755); err != nil {
+	if err := readonly.MkdirAll(resultsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create results directory: %w", err)
 	}
 
+	// Scan high-priority paths first so their results are available early
+	ranking := previousRun
+	if options.Order == OrderMatches && ranking == nil {
+		ranking, err = loadPreviousSummary(options.OutputDir, resultsDir)
+		if err != nil {
+			return fmt.Errorf("failed to load previous run: %w", err)
+		}
+	}
+	paths := orderPaths(entries, options.Order, ranking)
+
+	fmt.Printf("Starting bulk scan of %d paths with %d workers...\n", len(paths), options.NumWorkers)
 	fmt.Printf("Results will be written to: %s\n\n", resultsDir)
 
+	// Load the IoC database once for every path. If it is unavailable, each
+	// path reports the error, as not-scanned with SoftFailFetch.
+	database, databaseErr := loadDatabase(options)
+	since := options.Since
+	if database != nil {
+		// Already restricted to Since
+		since = time.Time{}
+	}
+
 	// Initialize worker pool
 	pool := NewWorkerPool(options.NumWorkers)
 	pool.Start()
@@ -108,14 +307,35 @@ func RunBulkScan(options BulkOptions) error {
 		for _, path := range paths {
 			job := ScanJob{
 				Path: path,
+				Err:  databaseErr,
 				Options: scanner.ScanOptions{
-					Path:         path,
-					CSVURL:       options.CSVURL,
-					LockfileOnly: options.LockfileOnly,
-					Verbose:      false, // Worker will override this
-					Context:      options.Context,
+					Path:            path,
+					CSVURL:          options.CSVURL,
+					CSVHeader:       options.CSVHeader,
+					Offline:         options.Offline,
+					DatabaseCache:   options.DatabaseCache,
+					FeedCheck:       options.FeedCheck,
+					Source:          options.Source,
+					Database:        database,
+					LockfileOnly:    options.LockfileOnly,
+					Exclude:         options.Exclude,
+					FollowSymlinks:  options.FollowSymlinks,
+					Throttle:        options.Throttle,
+					Watchlist:       options.Watchlist,
+					Policy:          options.Policy,
+					Allowlist:       options.Allowlist,
+					Typosquat:       options.Typosquat,
+					Cache:           options.Cache,
+					Since:           since,
+					SkipGitMetadata: options.SkipGitMetadata,
+					Verbose:         false, // Worker will override this
+					Context:         options.Context,
 				},
 			}
+			if previousRun != nil {
+				job.Previous = previousRun.PathResults[path]
+				job.PreviousRun = options.SkipUnchanged
+			}
 			if err := pool.Submit(job); err != nil {
 				fmt.Fprintf(os.Stderr, "Warning: failed to submit job for %s: %v\n", path, err)
 			}
@@ -124,24 +344,69 @@ func RunBulkScan(options BulkOptions) error {
 
 	// Collect results
 	summary := &BulkSummary{
-		StartTime:   startTime,
+		StartTime:   startTime.In(options.Location),
 		PathResults: make(map[string]*PathSummary),
+		Metadata:    options.Metadata,
+	}
+
+	// Write results from several goroutines so slow (e.g. NFS) output
+	// directories don't serialize the run on the collector
+	writer := newResultWriter(resultsDir, options.SyncBatch)
+	summaries := make(chan *PathSummary)
+	done := make(chan struct{})
+	defer close(done)
+
+	for w := 0; w < options.NumWriters; w++ {
+		go func() {
+			for {
+				select {
+				case result := <-pool.Results():
+					if scanResult, ok := result.Result.(*formatter.ScanResult); ok && scanResult != nil {
+						scanResult.Metadata = formatter.MergeMetadata(scanResult.Metadata, options.Metadata)
+						formatter.InLocation(scanResult, options.Location)
+						if options.Remediation != nil {
+							options.Remediation.Annotate(scanResult)
+						}
+					}
+					select {
+					case summaries <- processResult(result, writer, options.SoftFailFetch):
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
 	}
 
 	for i := 0; i < len(paths); i++ {
 		select {
-		case result := <-pool.Results():
-			pathSummary := processResult(result, resultsDir)
-			summary.PathResults[result.Job.Path] = pathSummary
+		case pathSummary := <-summaries:
+			summary.PathResults[pathSummary.Path] = pathSummary
 
+			status := pathSummary.Status
 			if pathSummary.Status == "success" {
 				summary.SuccessfulScans++
 				summary.TotalMatches += pathSummary.MatchesFound
+				if pathSummary.Unchanged {
+					summary.UnchangedScans++
+					status += " (unchanged)"
+				}
+			} else if pathSummary.Status == "not-scanned" {
+				summary.NotScannedScans++
 			} else {
 				summary.FailedScans++
+				if summary.ErrorCounts == nil {
+					summary.ErrorCounts = make(map[ErrorType]int)
+				}
+				summary.ErrorCounts[pathSummary.ErrorType]++
 			}
 
-			fmt.Printf("[%d/%d] %s: %s\n", i+1, len(paths), result.Job.Path, pathSummary.Status)
+			fmt.Fprintf(options.Output, "[%d/%d] %s: %s\n", i+1, len(paths), pathSummary.Path, status)
+			if options.Progress != nil {
+				options.Progress(i+1, len(paths), pathSummary.Path)
+			}
 
 		case <-options.Context.Done():
 			pool.Close()
@@ -152,8 +417,8 @@ func RunBulkScan(options BulkOptions) error {
 	pool.Close()
 
 	// Finalize summary
-	summary.EndTime = time.Now()
-	summary.Duration = summary.EndTime.Sub(summary.StartTime).String()
+	summary.EndTime = time.Now().In(options.Location)
+	summary.Duration = time.Since(startTime).String()
 	summary.TotalPaths = len(paths)
 
 	// Write summary.json
@@ -161,33 +426,73 @@ func RunBulkScan(options BulkOptions) error {
 	if err := writeSummary(summary, summaryPath); err != nil {
 		return fmt.Errorf("failed to write summary: %w", err)
 	}
+	writer.track(summaryPath)
+	if err := writer.flush(); err != nil {
+		return fmt.Errorf("failed to write results: %w", err)
+	}
 
 	// Print final summary
 	fmt.Printf("\n=== Bulk Scan Complete ===\n")
 	fmt.Printf("Duration: %s\n", summary.Duration)
 	fmt.Printf("Paths scanned: %d\n", summary.TotalPaths)
 	fmt.Printf("Successful: %d\n", summary.SuccessfulScans)
-	fmt.Printf("Failed: %d\n", summary.FailedScans)
+	if options.SkipUnchanged != "" {
+		fmt.Printf("Unchanged (reused): %d\n", summary.UnchangedScans)
+	}
+	fmt.Printf("Failed: %d%s\n", summary.FailedScans, formatErrorCounts(summary.ErrorCounts))
+	if summary.NotScannedScans > 0 {
+		fmt.Printf("Not scanned (database unavailable): %d\n", summary.NotScannedScans)
+	}
 	fmt.Printf("Total matches: %d\n", summary.TotalMatches)
 	fmt.Printf("Results: %s\n", resultsDir)
 
+	if len(options.AlertRules) > 0 {
+		previous, err := loadPreviousSummary(options.OutputDir, resultsDir)
+		if err != nil {
+			return fmt.Errorf("failed to load previous run: %w", err)
+		}
+
+		alerts := EvaluateAlerts(options.AlertRules, previous, summary)
+		if len(alerts) > 0 {
+			fmt.Printf("\n=== Alerts (%d) ===\n", len(alerts))
+			for _, alert := range alerts {
+				if alert.Path != "" {
+					fmt.Printf("[%s] %s: %s\n", alert.Rule, alert.Path, alert.Message)
+				} else {
+					fmt.Printf("[%s] %s\n", alert.Rule, alert.Message)
+				}
+			}
+			if err := writeAlerts(alerts, filepath.Join(resultsDir, "alerts.json")); err != nil {
+				return fmt.Errorf("failed to write alerts: %w", err)
+			}
+		}
+	}
+
+	if summary.NotScannedScans > 0 {
+		return ErrNotScanned
+	}
 	return nil
 }
 
-// readPathsFile reads paths from a newline-separated file.
-func readPathsFile(pathsFile string) ([]string, error) {
+// readPathsFile reads paths, with optional priorities, from a
+// newline-separated file.
+func readPathsFile(pathsFile string) ([]pathEntry, error) {
 	file, err := os.Open(pathsFile)
 	if err != nil {
 		return nil, err
 	}
 	defer file.Close()
 
-	var paths []string
+	var entries []pathEntry
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line != "" && !strings.HasPrefix(line, "#") {
-			paths = append(paths, line)
+			entry, err := parsePathLine(line)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, entry)
 		}
 	}
 
@@ -195,26 +500,36 @@ func readPathsFile(pathsFile string) ([]string, error) {
 		return nil, err
 	}
 
-	return paths, nil
+	return entries, nil
 }
 
-// processResult processes a scan result and writes output files.
-func processResult(result ScanJobResult, resultsDir string) *PathSummary {
+// processResult processes a scan result and writes output files. With
+// softFail, scans that failed because the IoC database was unavailable
+// are reported as not scanned.
+func processResult(result ScanJobResult, writer *resultWriter, softFail bool) *PathSummary {
 	summary := &PathSummary{
-		Path: result.Job.Path,
+		Path:        result.Job.Path,
+		Fingerprint: result.Fingerprint,
+		Unchanged:   result.Unchanged,
 	}
 
 	// Sanitize path for filename
 	sanitized := sanitizePath(result.Job.Path)
 
+	if result.Error != nil && softFail && errors.Is(result.Error, scanner.ErrDatabaseUnavailable) {
+		summary.Status = "not-scanned"
+		summary.Error = result.Error.Error()
+		resultJSON, _ := formatter.FormatJSON(formatter.NotScannedResult(result.Error))
+		summary.ResultFile = writer.writeFile(sanitized+".json", []byte(resultJSON))
+		return summary
+	}
 	if result.Error != nil {
 		summary.Status = "error"
 		summary.Error = result.Error.Error()
+		summary.ErrorType = classifyError(result.Error)
 
 		// Write error log
-		errorFile := filepath.Join(resultsDir, sanitized+".error.txt")
-		os.WriteFile(errorFile, []byte(result.Error.Error()), 0644)
-		summary.OutputFile = errorFile
+		summary.OutputFile = writer.writeFile(sanitized+".error.txt", []byte(result.Error.Error()))
 		return summary
 	}
 
@@ -225,6 +540,7 @@ func processResult(result ScanJobResult, resultsDir string) *PathSummary {
 	if !ok {
 		summary.Status = "error"
 		summary.Error = "invalid result type"
+		summary.ErrorType = ErrorUnknown
 		return summary
 	}
 
@@ -232,17 +548,18 @@ func processResult(result ScanJobResult, resultsDir string) *PathSummary {
 	summary.LockfilesScanned = scanResult.LockfilesScanned
 	summary.PackagesChecked = scanResult.PackagesChecked
 	summary.MatchesFound = len(scanResult.Matches)
+	for _, match := range scanResult.Matches {
+		if match.Severity == formatter.SeverityDirect {
+			summary.DirectMatches++
+		}
+	}
 
 	// Write JSON result
-	resultFile := filepath.Join(resultsDir, sanitized+".json")
 	resultJSON, _ := formatter.FormatJSON(scanResult)
-	os.WriteFile(resultFile, []byte(resultJSON), 0644)
-	summary.ResultFile = resultFile
+	summary.ResultFile = writer.writeFile(sanitized+".json", []byte(resultJSON))
 
 	// Write output log
-	outputFile := filepath.Join(resultsDir, sanitized+".log")
-	os.WriteFile(outputFile, []byte(result.Output), 0644)
-	summary.OutputFile = outputFile
+	summary.OutputFile = writer.writeFile(sanitized+".log", []byte(result.Output))
 
 	return summary
 }
@@ -266,5 +583,5 @@ func writeSummary(summary *BulkSummary, path string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return readonly.WriteFile(path, data, 0644)
 }
@@ -1,10 +1,25 @@
 package bulk
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
 )
 
 func TestNewCapturingLogger(t *testing.T) {
@@ -42,10 +57,11 @@ func TestReadPathsFile(t *testing.T) {
 
 	content := `# Comment line
 /path/one
-/path/two
+/path/two priority=5
 
 # Another comment
 /path/three
+/path/with space   priority=-1
 `
 	if err := os.WriteFile(pathsFile, []byte(content), 0644); err != nil {
 		t.Fatalf("Failed to create test file: %v", err)
@@ -56,18 +72,68 @@ func TestReadPathsFile(t *testing.T) {
 		t.Fatalf("readPathsFile failed: %v", err)
 	}
 
-	expected := []string{"/path/one", "/path/two", "/path/three"}
+	expected := []pathEntry{
+		{Path: "/path/one"},
+		{Path: "/path/two", Priority: 5},
+		{Path: "/path/three"},
+		{Path: "/path/with space", Priority: -1},
+	}
 	if len(paths) != len(expected) {
 		t.Fatalf("Expected %d paths, got %d", len(expected), len(paths))
 	}
 
 	for i, path := range paths {
 		if path != expected[i] {
-			t.Errorf("Path %d: expected %q, got %q", i, expected[i], path)
+			t.Errorf("Path %d: expected %+v, got %+v", i, expected[i], path)
 		}
 	}
 }
 
+// TestReadPathsFile_InvalidPriority tests that malformed priorities are rejected.
+func TestReadPathsFile_InvalidPriority(t *testing.T) {
+	pathsFile := filepath.Join(t.TempDir(), "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte("/path/one priority=high\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readPathsFile(pathsFile); err == nil {
+		t.Error("Expected error for invalid priority")
+	}
+}
+
+// TestOrderPaths tests scan ordering by priority and previous matches.
+func TestOrderPaths(t *testing.T) {
+	entries := []pathEntry{
+		{Path: "/a"},
+		{Path: "/b"},
+		{Path: "/c", Priority: 1},
+		{Path: "/d"},
+	}
+	previous := &BulkSummary{PathResults: map[string]*PathSummary{
+		"/b": {MatchesFound: 3},
+		"/d": {MatchesFound: 1, DirectMatches: 1},
+	}}
+
+	tests := []struct {
+		name     string
+		order    string
+		previous *BulkSummary
+		want     []string
+	}{
+		{"file order", OrderFile, previous, []string{"/c", "/a", "/b", "/d"}},
+		{"by matches", OrderMatches, previous, []string{"/c", "/d", "/b", "/a"}},
+		{"by matches without previous run", OrderMatches, nil, []string{"/c", "/a", "/b", "/d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orderPaths(entries, tt.order, tt.previous)
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("orderPaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestReadPathsFile_NonExistent(t *testing.T) {
 	_, err := readPathsFile("/nonexistent/file.txt")
 	if err == nil {
@@ -166,3 +232,423 @@ func TestWriteSummary(t *testing.T) {
 		t.Error("Expected non-empty summary file")
 	}
 }
+
+func TestParseAlertRules(t *testing.T) {
+	rules, err := ParseAlertRules("new-direct, increase:10%")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Kind != AlertNewDirect || rules[1].Percent != 10 {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	for _, spec := range []string{"increase", "increase:lots", "everything"} {
+		if _, err := ParseAlertRules(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestEvaluateAlerts(t *testing.T) {
+	rules := []AlertRule{{Kind: AlertNewDirect}, {Kind: AlertIncrease, Percent: 10}}
+
+	previous := &BulkSummary{
+		TotalMatches: 10,
+		PathResults: map[string]*PathSummary{
+			"/a": {DirectMatches: 1},
+			"/b": {DirectMatches: 0},
+		},
+	}
+	unchanged := &BulkSummary{
+		TotalMatches: 10,
+		PathResults: map[string]*PathSummary{
+			"/a": {DirectMatches: 1},
+			"/b": {DirectMatches: 0},
+		},
+	}
+	if alerts := EvaluateAlerts(rules, previous, unchanged); len(alerts) != 0 {
+		t.Errorf("expected no alerts for unchanged findings, got %+v", alerts)
+	}
+
+	worse := &BulkSummary{
+		TotalMatches: 12,
+		PathResults: map[string]*PathSummary{
+			"/a": {DirectMatches: 1},
+			"/b": {DirectMatches: 2},
+		},
+	}
+	alerts := EvaluateAlerts(rules, previous, worse)
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %+v", alerts)
+	}
+	if alerts[0].Path != "/b" {
+		t.Errorf("expected new DIRECT alert for /b, got %+v", alerts[0])
+	}
+
+	// First run: every DIRECT match is new, increase has no baseline
+	if alerts := EvaluateAlerts(rules, nil, worse); len(alerts) != 2 {
+		t.Errorf("expected an alert per path with DIRECT matches on first run, got %+v", alerts)
+	}
+}
+
+// TestReusableResult tests which previous results --skip-unchanged reuses.
+func TestReusableResult(t *testing.T) {
+	runDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(runDir, "app.json"), []byte(`{"packagesChecked": 7, "summary": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := &PathSummary{
+		Path:        "/app",
+		Status:      "success",
+		Fingerprint: "sha256:abc",
+		ResultFile:  "results/20250101-000000/app.json",
+	}
+	failed := *previous
+	failed.Status = "error"
+
+	tests := []struct {
+		name        string
+		previous    *PathSummary
+		fingerprint string
+		wantReuse   bool
+	}{
+		{"unchanged", previous, "sha256:abc", true},
+		{"changed", previous, "sha256:def", false},
+		{"no fingerprint", previous, "", false},
+		{"not in previous run", nil, "sha256:abc", false},
+		{"previous scan failed", &failed, "sha256:abc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := ScanJob{Path: "/app", Previous: tt.previous, PreviousRun: runDir}
+			got := reusableResult(job, tt.fingerprint)
+			if (got != nil) != tt.wantReuse {
+				t.Fatalf("reusableResult() = %v, wantReuse %v", got, tt.wantReuse)
+			}
+			if got != nil && (got.PackagesChecked != 7 || got.Summary != nil) {
+				t.Errorf("unexpected reused result: %+v", got)
+			}
+		})
+	}
+}
+
+func TestLoadPreviousSummary(t *testing.T) {
+	outputDir := t.TempDir()
+	for _, run := range []string{"20250101-000000", "20250102-000000", "20250103-000000"} {
+		dir := filepath.Join(outputDir, run)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if run == "20250103-000000" {
+			continue // current run, no summary yet
+		}
+		summary := &BulkSummary{TotalMatches: len(run), Duration: run}
+		if err := writeSummary(summary, filepath.Join(dir, "summary.json")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	previous, err := loadPreviousSummary(outputDir, filepath.Join(outputDir, "20250103-000000"))
+	if err != nil {
+		t.Fatalf("loadPreviousSummary failed: %v", err)
+	}
+	if previous == nil || previous.Duration != "20250102-000000" {
+		t.Errorf("expected the most recent earlier run, got %+v", previous)
+	}
+
+	first, err := loadPreviousSummary(outputDir, filepath.Join(outputDir, "20250101-000000"))
+	if err != nil || first != nil {
+		t.Errorf("expected no previous run, got %+v (err %v)", first, err)
+	}
+}
+
+func TestResultWriter(t *testing.T) {
+	dir := t.TempDir()
+	writer := newResultWriter(dir, 2)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			writer.writeFile(fmt.Sprintf("result-%d.json", i), []byte("{}"))
+		}(i)
+	}
+	wg.Wait()
+
+	if err := writer.flush(); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if len(writer.pending) != 0 {
+		t.Errorf("expected no pending files after flush, got %d", len(writer.pending))
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 {
+		t.Errorf("expected 5 files, got %d", len(entries))
+	}
+
+	// Write errors surface on flush
+	broken := newResultWriter(filepath.Join(dir, "missing"), 0)
+	broken.writeFile("result.json", []byte("{}"))
+	if err := broken.flush(); err == nil {
+		t.Error("expected error writing into a missing directory")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	_, statErr := os.Stat(filepath.Join(t.TempDir(), "missing"))
+	jsonErr := json.Unmarshal([]byte("{"), &struct{}{})
+
+	tests := []struct {
+		name string
+		err  error
+		want ErrorType
+	}{
+		{"missing path", fmt.Errorf("failed to find manifests: %w", statErr), ErrorPathMissing},
+		{"permission", fmt.Errorf("find lockfiles: %w", &os.PathError{Op: "open", Path: "/x", Err: os.ErrPermission}), ErrorPermission},
+		{"parse", fmt.Errorf("failed to parse npm lockfile: %w", jsonErr), ErrorParse},
+		{"network", fmt.Errorf("failed to fetch IoC database: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}), ErrorNetwork},
+		{"http status", errors.New("failed to fetch IoC database: fetch IoC database: HTTP 503: 503 Service Unavailable"), ErrorNetwork},
+		{"timeout", fmt.Errorf("scan: %w", context.DeadlineExceeded), ErrorTimeout},
+		{"panic", &panicError{value: "boom"}, ErrorPanic},
+		{"other", errors.New("something else"), ErrorUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessResult_SoftFail(t *testing.T) {
+	writer := newResultWriter(t.TempDir(), 0)
+	unavailable := ScanJobResult{
+		Job:   ScanJob{Path: "apps/web"},
+		Error: fmt.Errorf("failed to fetch IoC database: %w", scanner.ErrDatabaseUnavailable),
+	}
+
+	if summary := processResult(unavailable, writer, false); summary.Status != "error" || summary.ErrorType != ErrorNetwork {
+		t.Errorf("without soft-fail: status %q, type %q; want error, network", summary.Status, summary.ErrorType)
+	}
+
+	summary := processResult(unavailable, writer, true)
+	if summary.Status != "not-scanned" || summary.ResultFile == "" {
+		t.Fatalf("with soft-fail: %+v, want a not-scanned result file", summary)
+	}
+	data, err := os.ReadFile(summary.ResultFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		NotScanned string
+		Summary    struct{ Verdict string }
+	}
+	if err := json.Unmarshal(data, &result); err != nil || result.NotScanned == "" || result.Summary.Verdict != "not-scanned" {
+		t.Errorf("unexpected not-scanned result %s (%v)", data, err)
+	}
+
+	// Other errors still fail the path
+	other := ScanJobResult{Job: ScanJob{Path: "apps/api"}, Error: errors.New("something else")}
+	if summary := processResult(other, writer, true); summary.Status != "error" {
+		t.Errorf("with soft-fail, other errors: status %q, want error", summary.Status)
+	}
+}
+
+// TestRunBulkScan_Progress tests that each completed path is reported to
+// Progress and its status line written to Output
+func TestRunBulkScan_Progress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Package,Version\nevil,= 1.0.1\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a", "b", "c"} {
+		project := filepath.Join(dir, name)
+		if err := os.MkdirAll(project, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, project)
+	}
+	pathsFile := filepath.Join(dir, "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte(strings.Join(paths, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	var reported []string
+	err := RunBulkScan(BulkOptions{
+		PathsFile:       pathsFile,
+		OutputDir:       filepath.Join(dir, "results"),
+		NumWorkers:      2,
+		CSVURL:          server.URL,
+		SkipGitMetadata: true,
+		Output:          &output,
+		Progress: func(current, total int, file string) {
+			reported = append(reported, fmt.Sprintf("%d/%d", current, total))
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunBulkScan failed: %v", err)
+	}
+
+	if want := []string{"1/3", "2/3", "3/3"}; !reflect.DeepEqual(reported, want) {
+		t.Errorf("Progress calls = %v, want %v", reported, want)
+	}
+	for _, path := range paths {
+		if !strings.Contains(output.String(), path+": success") {
+			t.Errorf("Expected a status line for %s in Output:\n%s", path, output.String())
+		}
+	}
+}
+
+// TestRunBulkScan_SharedDatabase tests that the IoC database is fetched
+// once per run rather than once per path, and that a failed fetch is
+// reported for every path
+func TestRunBulkScan_SharedDatabase(t *testing.T) {
+	var fetches atomic.Int32
+	var unavailable atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if unavailable.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "Package,Version\nevil,= 1.0.1\n")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	var paths []string
+	for _, name := range []string{"a", "b", "c", "d"} {
+		project := filepath.Join(dir, name)
+		if err := os.MkdirAll(project, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(project, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, project)
+	}
+	pathsFile := filepath.Join(dir, "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte(strings.Join(paths, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var output bytes.Buffer
+	options := BulkOptions{
+		PathsFile:       pathsFile,
+		OutputDir:       filepath.Join(dir, "results"),
+		NumWorkers:      2,
+		CSVURL:          server.URL,
+		SkipGitMetadata: true,
+		Output:          &output,
+	}
+	if err := RunBulkScan(options); err != nil {
+		t.Fatalf("RunBulkScan failed: %v", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch of the IoC database for %d paths, got %d", len(paths), got)
+	}
+	if got := strings.Count(output.String(), ": success"); got != len(paths) {
+		t.Errorf("Expected %d successful paths, got %d:\n%s", len(paths), got, output.String())
+	}
+	logs, _ := filepath.Glob(filepath.Join(options.OutputDir, "*", sanitizePath(paths[0])+".log"))
+	if len(logs) != 1 {
+		t.Fatalf("Expected one output log of %s, got %v", paths[0], logs)
+	}
+	log, err := os.ReadFile(logs[0])
+	if err != nil {
+		t.Fatalf("Reading output log failed: %v", err)
+	}
+	if !strings.Contains(string(log), "Parsing "+filepath.Join(paths[0], "package.json")) {
+		t.Errorf("Expected the scan's verbose output in the output log, got:\n%s", log)
+	}
+
+	unavailable.Store(true)
+	fetches.Store(0)
+	output.Reset()
+	options.SoftFailFetch = true
+	if err := RunBulkScan(options); !errors.Is(err, ErrNotScanned) {
+		t.Fatalf("RunBulkScan error = %v, want ErrNotScanned", err)
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("Expected 1 fetch of the unavailable IoC database, got %d", got)
+	}
+	if got := strings.Count(output.String(), ": not-scanned"); got != len(paths) {
+		t.Errorf("Expected %d not-scanned paths, got %d:\n%s", len(paths), got, output.String())
+	}
+}
+
+// TestRunBulkScan_ReadOnly tests that read-only bulk scans only write below
+// the output directory
+func TestRunBulkScan_ReadOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Package,Version\nevil,= 1.0.1\n")
+	}))
+	defer server.Close()
+
+	evidence := t.TempDir()
+	if err := os.WriteFile(filepath.Join(evidence, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pathsFile := filepath.Join(evidence, "paths.txt")
+	if err := os.WriteFile(pathsFile, []byte(evidence+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+
+	defer readonly.Disable()
+	options := BulkOptions{
+		PathsFile:       pathsFile,
+		OutputDir:       out,
+		NumWorkers:      1,
+		CSVURL:          server.URL,
+		SkipGitMetadata: true,
+		Output:          &bytes.Buffer{},
+	}
+
+	// Without an allowed directory, not even the results directory is created
+	if err := readonly.Enable(""); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunBulkScan(options); !errors.Is(err, readonly.ErrReadOnly) {
+		t.Fatalf("RunBulkScan error = %v, want ErrReadOnly", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("Expected no output directory, got %v", err)
+	}
+
+	if err := readonly.Enable(out); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunBulkScan(options); err != nil {
+		t.Fatalf("RunBulkScan failed: %v", err)
+	}
+	entries, err := os.ReadDir(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected the evidence directory to be untouched, found %v", entries)
+	}
+	runs, err := os.ReadDir(out)
+	if err != nil || len(runs) != 1 {
+		t.Fatalf("Expected one run directory in %s, got %v (%v)", out, runs, err)
+	}
+	if _, err := os.Stat(filepath.Join(out, runs[0].Name(), "summary.json")); err != nil {
+		t.Errorf("Expected summary.json in the run directory: %v", err)
+	}
+}
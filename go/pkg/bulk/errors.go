@@ -0,0 +1,112 @@
+package bulk
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// ErrorType classifies why a path failed to scan, separating infrastructure
+// problems (network, timeouts) from problems with the scanned data.
+type ErrorType string
+
+const (
+	// ErrorPathMissing means the path (or a file under it) does not exist
+	ErrorPathMissing ErrorType = "path-missing"
+	// ErrorPermission means a file or directory could not be read
+	ErrorPermission ErrorType = "permission-denied"
+	// ErrorParse means a file or the IoC database could not be parsed
+	ErrorParse ErrorType = "parse-failure"
+	// ErrorNetwork means the IoC database could not be fetched, or the
+	// fetched feed failed its sanity checks
+	ErrorNetwork ErrorType = "network"
+	// ErrorTimeout means the scan or a request ran out of time
+	ErrorTimeout ErrorType = "timeout"
+	// ErrorPanic means the scan crashed
+	ErrorPanic ErrorType = "panic"
+	// ErrorUnknown covers everything else
+	ErrorUnknown ErrorType = "unknown"
+)
+
+// panicError reports a panic recovered from a scan.
+type panicError struct {
+	value interface{}
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("scan panicked: %v", e.value)
+}
+
+// classifyError returns the ErrorType of a scan error.
+func classifyError(err error) ErrorType {
+	var panicErr *panicError
+	var pathErr *os.PathError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &panicErr):
+		return ErrorPanic
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorTimeout
+	case errors.Is(err, os.ErrNotExist):
+		return ErrorPathMissing
+	case errors.Is(err, os.ErrPermission):
+		return ErrorPermission
+	case errors.As(err, &pathErr):
+		// Other filesystem errors; checked before net.Error, which
+		// *os.PathError also satisfies
+		return ErrorUnknown
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return ErrorTimeout
+		}
+		return ErrorNetwork
+	case errors.Is(err, scanner.ErrDatabaseUnavailable):
+		// Feeds failing the sanity checks
+		return ErrorNetwork
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return ErrorParse
+	}
+
+	// Errors without a typed cause, such as HTTP status failures
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "fetch IoC database"):
+		return ErrorNetwork
+	case strings.Contains(msg, "failed to parse"), strings.Contains(msg, "parse CSV"):
+		return ErrorParse
+	}
+	return ErrorUnknown
+}
+
+// formatErrorCounts renders per-type failure counts for the final summary,
+// e.g. " (network: 3, path-missing: 1)". Returns an empty string if there
+// were no failures.
+func formatErrorCounts(counts map[ErrorType]int) string {
+	if len(counts) == 0 {
+		return ""
+	}
+
+	types := make([]string, 0, len(counts))
+	for errorType := range counts {
+		types = append(types, string(errorType))
+	}
+	sort.Strings(types)
+
+	parts := make([]string, len(types))
+	for i, errorType := range types {
+		parts[i] = fmt.Sprintf("%s: %d", errorType, counts[ErrorType(errorType)])
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
@@ -6,17 +6,22 @@ import (
 	"io"
 	"os"
 	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
 )
 
 // CapturingLogger captures output to both console and an internal buffer.
 // This is used during bulk scanning to capture per-path scan output
-// while still showing real-time progress to the user.
+// while still showing real-time progress to the user. It is passed to each
+// scan as its scanner.Logger.
 type CapturingLogger struct {
 	buffer bytes.Buffer
 	mu     sync.Mutex
 	stdout io.Writer
 }
 
+var _ scanner.Logger = (*CapturingLogger)(nil)
+
 // NewCapturingLogger creates a new logger that writes to both console and buffer.
 func NewCapturingLogger() *CapturingLogger {
 	return &CapturingLogger{
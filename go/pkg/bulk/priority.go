@@ -0,0 +1,80 @@
+package bulk
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// OrderFile scans paths in paths file order (after priorities)
+	OrderFile = "file"
+	// OrderMatches scans paths with the most matches in the previous run
+	// first (after priorities)
+	OrderMatches = "matches"
+)
+
+// priorityPrefix marks the optional priority field of a paths file line,
+// e.g. "/srv/checkout priority=10"
+const priorityPrefix = "priority="
+
+// pathEntry is a line of the paths file.
+type pathEntry struct {
+	Path     string
+	Priority int
+}
+
+// parsePathLine splits a paths file line into its path and priority. The
+// priority is an optional trailing "priority=N" field; higher priorities
+// are scanned first and paths without one default to 0.
+func parsePathLine(line string) (pathEntry, error) {
+	fields := strings.Fields(line)
+	last := fields[len(fields)-1]
+	if len(fields) < 2 || !strings.HasPrefix(last, priorityPrefix) {
+		return pathEntry{Path: line}, nil
+	}
+
+	priority, err := strconv.Atoi(strings.TrimPrefix(last, priorityPrefix))
+	if err != nil {
+		return pathEntry{}, fmt.Errorf("invalid priority in %q", line)
+	}
+	path := strings.TrimSpace(strings.TrimSuffix(line, last))
+	return pathEntry{Path: path, Priority: priority}, nil
+}
+
+// orderPaths returns the paths in scan order: by descending priority, then,
+// for OrderMatches, by the DIRECT and total match counts of the previous
+// run, and otherwise in paths file order.
+func orderPaths(entries []pathEntry, order string, previous *BulkSummary) []string {
+	previousMatches := func(path string) (direct, total int) {
+		if previous == nil || previous.PathResults[path] == nil {
+			return 0, 0
+		}
+		result := previous.PathResults[path]
+		return result.DirectMatches, result.MatchesFound
+	}
+
+	sorted := make([]pathEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority > sorted[j].Priority
+		}
+		if order != OrderMatches {
+			return false
+		}
+		directI, totalI := previousMatches(sorted[i].Path)
+		directJ, totalJ := previousMatches(sorted[j].Path)
+		if directI != directJ {
+			return directI > directJ
+		}
+		return totalI > totalJ
+	})
+
+	paths := make([]string, len(sorted))
+	for i, entry := range sorted {
+		paths[i] = entry.Path
+	}
+	return paths
+}
@@ -0,0 +1,49 @@
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// loadRunSummary reads summary.json from a previous run directory.
+func loadRunSummary(runDir string) (*BulkSummary, error) {
+	data, err := os.ReadFile(filepath.Join(runDir, "summary.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var summary BulkSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("failed to parse %s summary: %w", runDir, err)
+	}
+	return &summary, nil
+}
+
+// reusableResult returns the previous scan result for a job if the path was
+// scanned successfully in the previous run with the same fingerprint. It
+// returns nil if the path has to be scanned again.
+func reusableResult(job ScanJob, fingerprint string) *formatter.ScanResult {
+	previous := job.Previous
+	if previous == nil || fingerprint == "" || previous.Status != "success" ||
+		previous.Fingerprint != fingerprint || previous.ResultFile == "" {
+		return nil
+	}
+
+	// The recorded path is relative to wherever the previous run was
+	// started, so resolve the file name against the run directory instead
+	data, err := os.ReadFile(filepath.Join(job.PreviousRun, filepath.Base(previous.ResultFile)))
+	if err != nil {
+		return nil
+	}
+
+	var result formatter.ScanResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil
+	}
+	result.Summary = nil
+	return &result
+}
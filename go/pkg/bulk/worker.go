@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
 )
 
@@ -20,6 +21,13 @@ type WorkerPool struct {
 type ScanJob struct {
 	Path    string
 	Options scanner.ScanOptions
+	// Previous is the path's summary from the run in PreviousRun; if its
+	// fingerprint still matches, the previous result is reused
+	Previous    *PathSummary
+	PreviousRun string
+	// Err, if set, is reported as the job's error without scanning, such
+	// as when the IoC database shared by the run could not be loaded
+	Err error
 }
 
 // ScanJobResult contains the result of a scan job.
@@ -28,6 +36,10 @@ type ScanJobResult struct {
 	Result interface{}
 	Error  error
 	Output string
+	// Fingerprint identifies the dependency files that were scanned
+	Fingerprint string
+	// Unchanged is set when Result was reused from the previous run
+	Unchanged bool
 }
 
 // NewWorkerPool creates a new worker pool with the specified number of workers.
@@ -66,19 +78,44 @@ func (wp *WorkerPool) worker(id int) {
 			// Update job options to use worker context
 			job.Options.Context = wp.ctx
 			job.Options.Verbose = true // Always verbose for captured output
+			job.Options.Logger = logger
 
 			// Capture output
 			logger.Printf("\n[Worker %d] Scanning: %s\n", id, job.Path)
 
+			// Fingerprint errors are left for the scan to report
+			fingerprint, _ := scanner.Fingerprint(job.Path, job.Options.LockfileOnly, scanner.FindOptions{
+				Exclude:        job.Options.Exclude,
+				FollowSymlinks: job.Options.FollowSymlinks,
+				Throttle:       job.Options.Throttle,
+			})
+
+			if previous := reusableResult(job, fingerprint); previous != nil {
+				logger.Printf("[Worker %d] Dependency files unchanged, reusing previous result: %s\n", id, job.Path)
+				wp.results <- ScanJobResult{
+					Job:         job,
+					Result:      previous,
+					Output:      logger.GetBuffer(),
+					Fingerprint: fingerprint,
+					Unchanged:   true,
+				}
+				continue
+			}
+
 			// Run the scan
-			result, err := scanner.RunScan(job.Options)
+			var result *formatter.ScanResult
+			err := job.Err
+			if err == nil {
+				result, err = runScan(job.Options)
+			}
 
 			// Send result
 			wp.results <- ScanJobResult{
-				Job:    job,
-				Result: result,
-				Error:  err,
-				Output: logger.GetBuffer(),
+				Job:         job,
+				Result:      result,
+				Error:       err,
+				Output:      logger.GetBuffer(),
+				Fingerprint: fingerprint,
 			}
 
 		case <-wp.ctx.Done():
@@ -87,6 +124,17 @@ func (wp *WorkerPool) worker(id int) {
 	}
 }
 
+// runScan runs a single scan, converting a panic into an error so one bad
+// path cannot take down the whole bulk run.
+func runScan(options scanner.ScanOptions) (result *formatter.ScanResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, &panicError{value: r}
+		}
+	}()
+	return scanner.RunScan(options)
+}
+
 // Submit adds a job to the worker pool.
 func (wp *WorkerPool) Submit(job ScanJob) error {
 	select {
@@ -0,0 +1,112 @@
+package bulk
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// resultWriter writes per-path output files into a results directory. It is
+// safe for concurrent use, so results can be written from several goroutines
+// instead of serializing on the collector.
+//
+// When syncBatch is positive, written files are fsynced in batches of that
+// many files (followed by the directory), trading a small durability window
+// for far fewer sync round trips on network filesystems. When it is zero,
+// files are left to the OS to flush.
+type resultWriter struct {
+	dir       string
+	syncBatch int
+
+	mu      sync.Mutex
+	pending []string
+	err     error
+}
+
+// newResultWriter creates a writer for dir.
+func newResultWriter(dir string, syncBatch int) *resultWriter {
+	return &resultWriter{dir: dir, syncBatch: syncBatch}
+}
+
+// writeFile writes data to name inside the results directory and returns
+// the full path. Write errors are recorded and reported by flush.
+func (w *resultWriter) writeFile(name string, data []byte) string {
+	path := filepath.Join(w.dir, name)
+	if err := readonly.WriteFile(path, data, 0644); err != nil {
+		w.setErr(err)
+		return path
+	}
+	w.track(path)
+	return path
+}
+
+// track queues a file written elsewhere for the next fsync batch.
+func (w *resultWriter) track(path string) {
+	if w.syncBatch <= 0 {
+		return
+	}
+
+	w.mu.Lock()
+	w.pending = append(w.pending, path)
+	var batch []string
+	if len(w.pending) >= w.syncBatch {
+		batch, w.pending = w.pending, nil
+	}
+	w.mu.Unlock()
+
+	if batch != nil {
+		w.setErr(w.syncFiles(batch))
+	}
+}
+
+// flush fsyncs any files still pending and returns the first error seen
+// while writing or syncing.
+func (w *resultWriter) flush() error {
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	if len(batch) > 0 {
+		w.setErr(w.syncFiles(batch))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// syncFiles fsyncs each file and then the results directory, so the new
+// directory entries are durable too.
+func (w *resultWriter) syncFiles(paths []string) error {
+	for _, path := range paths {
+		if err := syncPath(path); err != nil {
+			return err
+		}
+	}
+	return syncPath(w.dir)
+}
+
+// syncPath opens path and fsyncs it.
+func syncPath(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// setErr records the first error.
+func (w *resultWriter) setErr(err error) {
+	if err == nil {
+		return
+	}
+	w.mu.Lock()
+	if w.err == nil {
+		w.err = err
+	}
+	w.mu.Unlock()
+}
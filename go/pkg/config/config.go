@@ -0,0 +1,171 @@
+// Package config supplies default flag values from a repository config file
+// (.npmscanrc.yaml) and NPM_SCAN_* environment variables.
+//
+// Settings are named after command-line flags. The config file is a flat
+// YAML subset, parsed without a YAML dependency:
+//
+//	# Comments and blank lines are ignored
+//	csv-url: https://mirror.example.com/iocs.csv
+//	fail-on: transitive
+//	meta:
+//	  - team=payments
+//	  - tier=1
+//
+// The environment variable of a setting is its name upper-cased with dashes
+// replaced by underscores, after the NPM_SCAN_ prefix (NPM_SCAN_CSV_URL).
+// List values in environment variables are comma-separated. Flags given on
+// the command line take precedence over environment variables, which take
+// precedence over the config file.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/yamlite"
+)
+
+// FileName is the name of the config file looked up by Find.
+const FileName = ".npmscanrc.yaml"
+
+// EnvPrefix prefixes the environment variable of every setting.
+const EnvPrefix = "NPM_SCAN_"
+
+// Config holds the settings of a config file.
+type Config struct {
+	// Path is the file the settings were read from; empty if there is none
+	Path string
+	// Values maps setting names to their values; scalars have one value
+	Values map[string][]string
+	// Lists records which settings were written as YAML lists
+	Lists map[string]bool
+}
+
+// Find looks for the config file in dir and its parents, stopping at the
+// root of the git repository containing dir. It returns an empty path if
+// there is no config file.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		candidate := filepath.Join(dir, FileName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return "", nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the config file at path. An empty path yields an empty config.
+func Load(path string) (*Config, error) {
+	if path == "" {
+		return &Config{Values: map[string][]string{}, Lists: map[string]bool{}}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	cfg.Path = path
+	return cfg, nil
+}
+
+// Parse parses config file data. Errors name the offending line.
+func Parse(data []byte) (*Config, error) {
+	cfg := &Config{Values: map[string][]string{}, Lists: map[string]bool{}}
+	list := ""
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line := strings.TrimRight(yamlite.StripComment(raw), " \t\r")
+		content := strings.TrimSpace(line)
+		if content == "" {
+			continue
+		}
+
+		if strings.HasPrefix(content, "- ") || content == "-" {
+			if list == "" {
+				return nil, fmt.Errorf("line %d: list item outside a list setting", lineNo)
+			}
+			value, err := yamlite.Unquote(strings.TrimSpace(strings.TrimPrefix(content, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			cfg.Values[list] = append(cfg.Values[list], value)
+			continue
+		}
+		if line != content {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+		}
+
+		key, value, ok := strings.Cut(content, ":")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected setting: value, got %q", lineNo, content)
+		}
+		if _, seen := cfg.Values[key]; seen {
+			return nil, fmt.Errorf("line %d: duplicate setting %q", lineNo, key)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// The values follow as list items
+			list = key
+			cfg.Values[key] = []string{}
+			cfg.Lists[key] = true
+			continue
+		}
+		list = ""
+		value, err := yamlite.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		cfg.Values[key] = []string{value}
+	}
+
+	return cfg, nil
+}
+
+// EnvName returns the environment variable of a setting.
+func EnvName(setting string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// Lookup returns the value of a setting from its environment variable or,
+// failing that, the config file. Environment values are split on commas
+// when list is set. ok is false if the setting is not configured.
+func (c *Config) Lookup(setting string, list bool) (values []string, source string, ok bool) {
+	if value, set := os.LookupEnv(EnvName(setting)); set {
+		if !list {
+			return []string{value}, EnvName(setting), true
+		}
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				values = append(values, part)
+			}
+		}
+		return values, EnvName(setting), true
+	}
+
+	if values, set := c.Values[setting]; set {
+		return values, c.Path, true
+	}
+	return nil, "", false
+}
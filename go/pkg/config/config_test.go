@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParse tests parsing scalar and list settings
+func TestParse(t *testing.T) {
+	data := []byte(`# Repository scan settings
+csv-url: "https://mirror.example.com/iocs.csv"  # internal mirror
+fail-on: transitive
+
+meta:
+  - team=payments
+  - 'tier=1'
+`)
+
+	cfg, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := map[string][]string{
+		"csv-url": {"https://mirror.example.com/iocs.csv"},
+		"fail-on": {"transitive"},
+		"meta":    {"team=payments", "tier=1"},
+	}
+	if !reflect.DeepEqual(cfg.Values, want) {
+		t.Errorf("Parse() values = %v, want %v", cfg.Values, want)
+	}
+	if !cfg.Lists["meta"] || cfg.Lists["fail-on"] {
+		t.Errorf("Parse() lists = %v, want only meta", cfg.Lists)
+	}
+}
+
+// TestParse_Errors tests that invalid files are rejected with their line
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"duplicate setting", "fail-on: direct\nfail-on: none\n", "line 2"},
+		{"list item outside a list", "- team=payments\n", "line 1"},
+		{"nested mapping", "network:\n  proxy: http://proxy\n", "line 2"},
+		{"missing colon", "offline\n", "line 1"},
+		{"unterminated quote", "csv-url: \"https://example.com\n", "line 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestLookup tests that environment variables take precedence over the file
+func TestLookup(t *testing.T) {
+	cfg, err := Parse([]byte("fail-on: direct\nworkers: 4\nmeta:\n  - team=payments\n"))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	cfg.Path = FileName
+	t.Setenv("NPM_SCAN_WORKERS", "8")
+	t.Setenv("NPM_SCAN_META", "team=web, tier=2,")
+
+	tests := []struct {
+		setting    string
+		list       bool
+		wantValues []string
+		wantSource string
+		wantOK     bool
+	}{
+		{"fail-on", false, []string{"direct"}, FileName, true},
+		{"workers", false, []string{"8"}, "NPM_SCAN_WORKERS", true},
+		{"meta", true, []string{"team=web", "tier=2"}, "NPM_SCAN_META", true},
+		{"csv-url", false, nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.setting, func(t *testing.T) {
+			values, source, ok := cfg.Lookup(tt.setting, tt.list)
+			if !reflect.DeepEqual(values, tt.wantValues) || source != tt.wantSource || ok != tt.wantOK {
+				t.Errorf("Lookup(%q) = %v, %q, %v; want %v, %q, %v", tt.setting, values, source, ok, tt.wantValues, tt.wantSource, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestFind tests looking up the config file up to the repository root
+func TestFind(t *testing.T) {
+	outer := t.TempDir()
+	repo := filepath.Join(outer, "repo")
+	nested := filepath.Join(repo, "packages", "app")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A config file outside the repository is not picked up
+	if err := os.WriteFile(filepath.Join(outer, FileName), []byte("offline: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := Find(nested); err != nil || path != "" {
+		t.Errorf("Find() = %q, %v; want no config file", path, err)
+	}
+
+	want := filepath.Join(repo, FileName)
+	if err := os.WriteFile(want, []byte("offline: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if path, err := Find(nested); err != nil || path != want {
+		t.Errorf("Find() = %q, %v; want %q", path, err, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Package fix proposes dependency version changes that remove IoC matches
+// from package.json manifests, and verifies them before they are applied.
+package fix
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// Change is a proposed version spec change for one dependency declaration.
+type Change struct {
+	Package string `json:"package"`
+	// Alias is the declared name when the dependency is an npm alias
+	Alias string `json:"alias,omitempty"`
+	Type  string `json:"type"` // dependencies, devDependencies, etc.
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Plan is the set of changes proposed for one manifest, with its content
+// before and after the changes. Plans are computed in memory; nothing is
+// written until Apply.
+type Plan struct {
+	Path     string
+	Original []byte
+	Proposed []byte
+	Changes  []Change
+}
+
+// ParseTarget splits a "name@spec" fix target, e.g. "lodash@4.17.21" or
+// "@scope/pkg@^2.0.1".
+func ParseTarget(target string) (name, spec string, err error) {
+	at := strings.LastIndex(target, "@")
+	if at <= 0 || at == len(target)-1 {
+		return "", "", fmt.Errorf("invalid fix target %q (expected name@version)", target)
+	}
+	return target[:at], target[at+1:], nil
+}
+
+// PlanManifest computes the changes that set every dependency of the
+// manifest listed in targets (package name to new version spec) to its
+// target spec. Declarations already at the target spec are left alone.
+func PlanManifest(path string, content []byte, targets map[string]string) (*Plan, error) {
+	var manifest parser.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	plan := &Plan{Path: path, Original: content, Proposed: content}
+	for _, dep := range parser.ExtractDependencies(&manifest, path) {
+		to, ok := targets[dep.Name]
+		if !ok || dep.VersionSpec == "" || dep.VersionSpec == to {
+			continue
+		}
+
+		key, from, value := dep.Name, dep.VersionSpec, to
+		if dep.Alias != "" {
+			key = dep.Alias
+			from = aliasSpec(dep.Name, dep.VersionSpec)
+			value = aliasSpec(dep.Name, to)
+		}
+
+		proposed, ok := replaceString(plan.Proposed, from, value, dep.Type, key)
+		if !ok {
+			return nil, fmt.Errorf("failed to locate %s in %s of %s", key, dep.Type, path)
+		}
+		plan.Proposed = proposed
+		plan.Changes = append(plan.Changes, Change{
+			Package: dep.Name,
+			Alias:   dep.Alias,
+			Type:    dep.Type,
+			From:    dep.VersionSpec,
+			To:      to,
+		})
+	}
+
+	sort.Slice(plan.Changes, func(i, j int) bool {
+		if plan.Changes[i].Package != plan.Changes[j].Package {
+			return plan.Changes[i].Package < plan.Changes[j].Package
+		}
+		return plan.Changes[i].Type < plan.Changes[j].Type
+	})
+	return plan, nil
+}
+
+// aliasSpec returns the declared value of an npm alias.
+func aliasSpec(name, spec string) string {
+	return "npm:" + name + "@" + spec
+}
+
+// Apply writes the proposed content of plan to its manifest, keeping the
+// file's permissions.
+func Apply(plan *Plan) error {
+	return writeKeepingMode(plan.Path, plan.Proposed)
+}
+
+// ApplyAll writes every plan as a single change set: if any write fails,
+// the manifests already written are restored and the error is returned.
+func ApplyAll(plans []*Plan) error {
+	for i, plan := range plans {
+		if err := Apply(plan); err != nil {
+			if rerr := Revert(plans[:i]); rerr != nil {
+				return fmt.Errorf("failed to update %s: %w (rollback failed: %v)", plan.Path, err, rerr)
+			}
+			return fmt.Errorf("failed to update %s: %w", plan.Path, err)
+		}
+	}
+	return nil
+}
+
+// Revert restores the original content of every plan's manifest.
+func Revert(plans []*Plan) error {
+	var errs []string
+	for _, plan := range plans {
+		if err := writeKeepingMode(plan.Path, plan.Original); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", plan.Path, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to restore %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// writeKeepingMode overwrites an existing file, keeping its permissions.
+func writeKeepingMode(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return readonly.WriteFile(path, content, info.Mode().Perm())
+}
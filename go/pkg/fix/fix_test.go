@@ -0,0 +1,265 @@
+package fix
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+)
+
+const testManifest = `{
+  "name": "app",
+  "dependencies": {
+    "evil": "1.0.1",
+    "safe": "^2.0.0",
+    "aliased": "npm:evil@1.0.1"
+  },
+  "devDependencies": {
+    "evil":   "1.0.1"
+  }
+}
+`
+
+// TestPlanManifest tests rewriting declarations in place, including aliases
+func TestPlanManifest(t *testing.T) {
+	plan, err := PlanManifest("package.json", []byte(testManifest), map[string]string{"evil": "1.0.2"})
+	if err != nil {
+		t.Fatalf("PlanManifest() error = %v", err)
+	}
+	if len(plan.Changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d: %+v", len(plan.Changes), plan.Changes)
+	}
+
+	want := strings.NewReplacer(
+		`"evil": "1.0.1"`, `"evil": "1.0.2"`,
+		`"npm:evil@1.0.1"`, `"npm:evil@1.0.2"`,
+		`"evil":   "1.0.1"`, `"evil":   "1.0.2"`,
+	).Replace(testManifest)
+	if string(plan.Proposed) != want {
+		t.Errorf("Proposed manifest:\n%s\nwant:\n%s", plan.Proposed, want)
+	}
+	if string(plan.Original) != testManifest {
+		t.Error("PlanManifest() should not modify the original content")
+	}
+
+	if _, _, err := ParseTarget("@scope/pkg"); err == nil {
+		t.Error("ParseTarget() should reject a target without a version")
+	}
+	if name, spec, err := ParseTarget("@scope/pkg@^2.0.1"); err != nil || name != "@scope/pkg" || spec != "^2.0.1" {
+		t.Errorf("ParseTarget(@scope/pkg@^2.0.1) = %q, %q, %v", name, spec, err)
+	}
+}
+
+// TestVerify tests the before/after comparison of proposed manifests
+func TestVerify(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1 || = 1.0.3\nsafe,= 2.0.1\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	tests := []struct {
+		name           string
+		pkg, target    string
+		wantOK         bool
+		wantResolved   int
+		wantRemaining  int
+		wantIntroduced int
+	}{
+		{name: "pin to a safe version", pkg: "evil", target: "1.0.2", wantOK: true, wantResolved: 1},
+		{name: "loosened range admits compromised versions", pkg: "evil", target: "^1.0.0", wantResolved: 1, wantIntroduced: 2},
+		{name: "pin to another compromised version", pkg: "evil", target: "1.0.3", wantResolved: 1, wantIntroduced: 1},
+		{name: "same version with an operator", pkg: "evil", target: "=1.0.1", wantRemaining: 1},
+		{name: "narrowed range still admits the version", pkg: "safe", target: "~2.0.0", wantRemaining: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := PlanManifest("package.json", []byte(testManifest), map[string]string{tt.pkg: tt.target})
+			if err != nil {
+				t.Fatalf("PlanManifest() error = %v", err)
+			}
+			v, err := Verify(plan, db)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if v.OK() != tt.wantOK || len(v.Resolved) != tt.wantResolved || len(v.Remaining) != tt.wantRemaining || len(v.Introduced) != tt.wantIntroduced {
+				t.Errorf("Verify() = ok %v, %d resolved, %d remaining, %d introduced; want %v, %d, %d, %d",
+					v.OK(), len(v.Resolved), len(v.Remaining), len(v.Introduced),
+					tt.wantOK, tt.wantResolved, tt.wantRemaining, tt.wantIntroduced)
+			}
+		})
+	}
+}
+
+// TestSetString tests editing package.json values in place
+func TestSetString(t *testing.T) {
+	const manifest = "{\n\t\"name\": \"app\",\n\t\"overrides\": {\n\t\t\"a\": \"1.0.0\",\n\t\t\"nested\": {\"a\": \"0.1.0\"}\n\t}\n}\n"
+
+	tests := []struct {
+		name  string
+		path  []string
+		key   string
+		value string
+		want  string
+	}{
+		{
+			name: "replace existing value",
+			path: []string{"overrides"}, key: "a", value: ">=1.0.1 <2",
+			want: "{\n\t\"name\": \"app\",\n\t\"overrides\": {\n\t\t\"a\": \">=1.0.1 <2\",\n\t\t\"nested\": {\"a\": \"0.1.0\"}\n\t}\n}\n",
+		},
+		{
+			name: "add to existing object",
+			path: []string{"overrides"}, key: "b", value: "2.0.0",
+			want: "{\n\t\"name\": \"app\",\n\t\"overrides\": {\n\t\t\"a\": \"1.0.0\",\n\t\t\"nested\": {\"a\": \"0.1.0\"},\n\t\t\"b\": \"2.0.0\"\n\t}\n}\n",
+		},
+		{
+			name: "create missing objects",
+			path: []string{"pnpm", "overrides"}, key: "a", value: "1.0.1",
+			want: "{\n\t\"name\": \"app\",\n\t\"overrides\": {\n\t\t\"a\": \"1.0.0\",\n\t\t\"nested\": {\"a\": \"0.1.0\"}\n\t},\n\t\"pnpm\": {\n\t\t\"overrides\": {\n\t\t\t\"a\": \"1.0.1\"\n\t\t}\n\t}\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setString([]byte(manifest), tt.path, tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("setString() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("setString() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := setString([]byte(manifest), []string{"overrides"}, "nested", "1.0.0"); err == nil {
+		t.Error("setString() should refuse to replace an object")
+	}
+}
+
+// TestPlanOverrides tests updating and adding root overrides per package manager
+func TestPlanOverrides(t *testing.T) {
+	const manifest = `{
+  "name": "root",
+  "workspaces": ["packages/*"],
+  "resolutions": {
+    "evil": "1.0.1"
+  }
+}
+`
+	targets := map[string]string{"evil": "1.0.2", "other": "3.0.0"}
+
+	tests := []struct {
+		name        string
+		manager     string
+		add         bool
+		wantChanges []string
+	}{
+		{name: "yarn updates existing resolutions", manager: ManagerYarn, wantChanges: []string{"resolutions:evil:1.0.1:1.0.2"}},
+		{name: "yarn adds missing resolutions", manager: ManagerYarn, add: true, wantChanges: []string{"resolutions:evil:1.0.1:1.0.2", "resolutions:other::3.0.0"}},
+		{name: "npm only touches overrides", manager: ManagerNPM},
+		{name: "pnpm adds nested overrides", manager: ManagerPNPM, add: true, wantChanges: []string{"pnpm.overrides:evil::1.0.2", "pnpm.overrides:other::3.0.0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := &Plan{Path: "package.json", Original: []byte(manifest), Proposed: []byte(manifest)}
+			if err := PlanOverrides(plan, tt.manager, targets, tt.add); err != nil {
+				t.Fatalf("PlanOverrides() error = %v", err)
+			}
+
+			var got []string
+			for _, c := range plan.Changes {
+				got = append(got, strings.Join([]string{c.Type, c.Package, c.From, c.To}, ":"))
+			}
+			if strings.Join(got, ",") != strings.Join(tt.wantChanges, ",") {
+				t.Errorf("PlanOverrides() changes = %v, want %v", got, tt.wantChanges)
+			}
+
+			path := overridesPath(tt.manager)
+			for _, c := range plan.Changes {
+				if v, ok := stringAt(plan.Proposed, append(path, c.Package)...); !ok || v != c.To {
+					t.Errorf("%s override = %q, want %q", c.Package, v, c.To)
+				}
+			}
+		})
+	}
+}
+
+// TestApplyAll tests that a failed write restores the manifests already written
+func TestApplyAll(t *testing.T) {
+	dir := t.TempDir()
+	written := filepath.Join(dir, "package.json")
+	if err := os.WriteFile(written, []byte("original"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plans := []*Plan{
+		{Path: written, Original: []byte("original"), Proposed: []byte("proposed")},
+		{Path: filepath.Join(dir, "missing", "package.json"), Original: []byte("{}"), Proposed: []byte("{}")},
+	}
+	if err := ApplyAll(plans); err == nil {
+		t.Fatal("ApplyAll() should fail for a missing manifest")
+	}
+
+	content, err := os.ReadFile(written)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "original" {
+		t.Errorf("ApplyAll() left %q, want the original content restored", content)
+	}
+}
+
+// TestDetectPackageManager tests picking the package manager from the root lockfile
+func TestDetectPackageManager(t *testing.T) {
+	dir := t.TempDir()
+	if got := DetectPackageManager(dir); got != ManagerNPM {
+		t.Errorf("DetectPackageManager() = %s, want npm without a lockfile", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "yarn.lock"), []byte("__metadata:\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := DetectPackageManager(dir); got != ManagerYarn {
+		t.Errorf("DetectPackageManager() = %s, want yarn", got)
+	}
+	if got := lockfileCommand(dir, ManagerYarn); strings.Join(got, " ") != "yarn install --mode=update-lockfile" {
+		t.Errorf("lockfileCommand() = %v, want the berry lockfile update", got)
+	}
+}
+
+// TestFormatPatch tests the unified diff of planned changes
+func TestFormatPatch(t *testing.T) {
+	original := "{\n  \"a\": \"1\",\n  \"b\": \"2\",\n  \"c\": \"3\",\n  \"d\": \"4\",\n  \"e\": \"5\",\n  \"f\": \"6\",\n  \"g\": \"7\",\n  \"h\": \"8\",\n  \"i\": \"9\"\n}"
+	proposed := strings.NewReplacer(`"a": "1"`, `"a": "10"`, `"i": "9"`, `"i": "90"`).Replace(original)
+
+	plans := []*Plan{
+		{Path: "/repo/packages/app/package.json", Original: []byte(original), Proposed: []byte(proposed)},
+		{Path: "/repo/package.json", Original: []byte("{}\n"), Proposed: []byte("{}\n")},
+	}
+
+	want := `diff --git a/packages/app/package.json b/packages/app/package.json
+--- a/packages/app/package.json
++++ b/packages/app/package.json
+@@ -1,5 +1,5 @@
+ {
+-  "a": "1",
++  "a": "10",
+   "b": "2",
+   "c": "3",
+   "d": "4",
+@@ -7,5 +7,5 @@
+   "f": "6",
+   "g": "7",
+   "h": "8",
+-  "i": "9"
++  "i": "90"
+ }
+\ No newline at end of file
+`
+	if got := FormatPatch(plans, "/repo"); got != want {
+		t.Errorf("FormatPatch() =\n%s\nwant:\n%s", got, want)
+	}
+}
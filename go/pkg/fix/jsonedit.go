@@ -0,0 +1,191 @@
+package fix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// The helpers below edit package.json files in place: values are located
+// by walking the JSON tokens and replaced or inserted as text, so key order,
+// indentation and formatting of the rest of the file are preserved.
+
+// valueSpan locates the value at the key path in a JSON document, returning
+// the offset of its first byte and one past its last byte. An empty path
+// locates the top-level value.
+func valueSpan(content []byte, path ...string) (start, end int, ok bool) {
+	type frame struct {
+		object    bool
+		expectKey bool
+		key       string
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(content))
+	var stack []frame
+	start, depth := -1, -1
+
+	atPath := func() bool {
+		if len(stack) != len(path) {
+			return false
+		}
+		for i, f := range stack {
+			if !f.object || f.key != path[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, false
+		}
+		delim, isDelim := tok.(json.Delim)
+
+		if n := len(stack); n > 0 && stack[n-1].object && stack[n-1].expectKey && !(isDelim && delim == '}') {
+			stack[n-1].key, _ = tok.(string)
+			stack[n-1].expectKey = false
+			continue
+		}
+
+		if isDelim && (delim == '}' || delim == ']') {
+			stack = stack[:len(stack)-1]
+			if len(stack) == depth {
+				return start, int(dec.InputOffset()), true
+			}
+		} else {
+			if start < 0 && atPath() {
+				start = before + bytes.IndexAny(content[before:], `"{[-0123456789tfn`)
+				depth = len(stack)
+			}
+			if isDelim {
+				stack = append(stack, frame{object: delim == '{', expectKey: delim == '{'})
+				continue
+			}
+			if len(stack) == depth {
+				return start, int(dec.InputOffset()), true
+			}
+		}
+
+		// A value is complete; an enclosing object expects its next key
+		if n := len(stack); n > 0 && stack[n-1].object {
+			stack[n-1].expectKey = true
+		}
+	}
+}
+
+// stringAt returns the string value at the key path. ok is false if there
+// is no value or it is not a string.
+func stringAt(content []byte, path ...string) (value string, ok bool) {
+	start, end, found := valueSpan(content, path...)
+	if !found || content[start] != '"' {
+		return "", false
+	}
+	if err := json.Unmarshal(content[start:end], &value); err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// replaceString replaces the string value at the key path if it equals
+// from. It reports false if there is no such value.
+func replaceString(content []byte, from, to string, path ...string) ([]byte, bool) {
+	if value, ok := stringAt(content, path...); !ok || value != from {
+		return content, false
+	}
+	start, end, _ := valueSpan(content, path...)
+	return splice(content, start, end, quoteJSON(to)), true
+}
+
+// setString sets the string value of key in the object at path, creating
+// the key and any missing enclosing objects.
+func setString(content []byte, path []string, key, value string) ([]byte, error) {
+	full := append(append([]string{}, path...), key)
+	if start, end, ok := valueSpan(content, full...); ok {
+		if content[start] != '"' {
+			return nil, fmt.Errorf("%s is not a string", strings.Join(full, "."))
+		}
+		return splice(content, start, end, quoteJSON(value)), nil
+	}
+
+	content, err := ensureObject(content, path)
+	if err != nil {
+		return nil, err
+	}
+	return insertMember(content, path, key, quoteJSON(value))
+}
+
+// ensureObject creates the object at path, and any missing enclosing
+// objects, if it does not exist.
+func ensureObject(content []byte, path []string) ([]byte, error) {
+	if _, _, ok := valueSpan(content, path...); ok {
+		return content, nil
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("not a JSON document")
+	}
+
+	parent := path[:len(path)-1]
+	content, err := ensureObject(content, parent)
+	if err != nil {
+		return nil, err
+	}
+	return insertMember(content, parent, path[len(path)-1], "{}")
+}
+
+// insertMember appends a key with the raw JSON value to the object at path,
+// indented like the rest of the file.
+func insertMember(content []byte, path []string, key, raw string) ([]byte, error) {
+	start, end, ok := valueSpan(content, path...)
+	if !ok || content[start] != '{' {
+		return nil, fmt.Errorf("%s is not an object", strings.Join(path, "."))
+	}
+
+	unit := indentUnit(content)
+	indent := strings.Repeat(unit, len(path)+1)
+	member := quoteJSON(key) + ": " + raw
+
+	closing := end - 1
+	last := bytes.LastIndexFunc(content[:closing], func(r rune) bool {
+		return r != ' ' && r != '\t' && r != '\n' && r != '\r'
+	})
+	if last == start {
+		// Empty object
+		body := "{\n" + indent + member + "\n" + strings.Repeat(unit, len(path)) + "}"
+		return splice(content, start, end, body), nil
+	}
+	return splice(content, last+1, last+1, ",\n"+indent+member), nil
+}
+
+// indentUnit returns the indentation of the first indented line, or two
+// spaces if there is none.
+func indentUnit(content []byte) string {
+	for _, line := range strings.Split(string(content), "\n") {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed != "" && len(trimmed) < len(line) {
+			return line[:len(line)-len(trimmed)]
+		}
+	}
+	return "  "
+}
+
+// quoteJSON encodes s as a JSON string without escaping <, > and &, which
+// are common in version ranges.
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// splice returns content with content[start:end] replaced by s.
+func splice(content []byte, start, end int, s string) []byte {
+	out := make([]byte, 0, len(content)-(end-start)+len(s))
+	out = append(out, content[:start]...)
+	out = append(out, s...)
+	return append(out, content[end:]...)
+}
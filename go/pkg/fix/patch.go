@@ -0,0 +1,180 @@
+package fix
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// patchContext is the number of unchanged lines around each hunk.
+const patchContext = 3
+
+// FormatPatch formats plans as a unified diff that git apply (or patch -p1)
+// accepts from root. Paths are relative to root with the usual a/ and b/
+// prefixes. Plans without changes are skipped.
+func FormatPatch(plans []*Plan, root string) string {
+	var b strings.Builder
+	for _, plan := range plans {
+		if string(plan.Original) == string(plan.Proposed) {
+			continue
+		}
+
+		path := plan.Path
+		if rel, err := filepath.Rel(root, plan.Path); err == nil {
+			path = rel
+		}
+		path = filepath.ToSlash(path)
+
+		b.WriteString(fmt.Sprintf("diff --git a/%s b/%s\n", path, path))
+		b.WriteString(fmt.Sprintf("--- a/%s\n", path))
+		b.WriteString(fmt.Sprintf("+++ b/%s\n", path))
+		b.WriteString(unifiedDiff(splitLines(string(plan.Original)), splitLines(string(plan.Proposed))))
+	}
+	return b.String()
+}
+
+// splitLines splits content into lines, each keeping its newline. A last
+// line without one is kept as is.
+func splitLines(content string) []string {
+	var lines []string
+	for content != "" {
+		i := strings.IndexByte(content, '\n')
+		if i < 0 {
+			lines = append(lines, content)
+			break
+		}
+		lines = append(lines, content[:i+1])
+		content = content[i+1:]
+	}
+	return lines
+}
+
+// diffOp is one line of an edit script: ' ' (kept), '-' (removed from a)
+// or '+' (added from b).
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// diffLines computes a shortest edit script from a to b using the longest
+// common subsequence of their lines. Manifests are small, so the quadratic
+// table is not a concern.
+func diffLines(a, b []string) []diffOp {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i]})
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// unifiedDiff formats the hunks of the diff from a to b.
+func unifiedDiff(a, b []string) string {
+	ops := diffLines(a, b)
+
+	var out strings.Builder
+	for start := 0; start < len(ops); {
+		// Find the next change
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		// Extend the hunk while changes are within two contexts of each other
+		last := first
+		for k := first; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				last = k
+			} else if k-last > 2*patchContext {
+				break
+			}
+		}
+
+		from := first - patchContext
+		if from < start {
+			from = start
+		}
+		if from < 0 {
+			from = 0
+		}
+		to := last + patchContext + 1
+		if to > len(ops) {
+			to = len(ops)
+		}
+
+		// Line numbers of the hunk start in a and b
+		lineA, lineB := 0, 0
+		for _, op := range ops[:from] {
+			if op.kind != '+' {
+				lineA++
+			}
+			if op.kind != '-' {
+				lineB++
+			}
+		}
+		countA, countB := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				countA++
+			}
+			if op.kind != '-' {
+				countB++
+			}
+		}
+
+		out.WriteString(fmt.Sprintf("@@ -%s +%s @@\n", hunkRange(lineA, countA), hunkRange(lineB, countB)))
+		for _, op := range ops[from:to] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				out.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		start = to
+	}
+	return out.String()
+}
+
+// hunkRange formats the start,count of one side of a hunk header. start is
+// the number of lines before the hunk; an empty side refers to the line
+// before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
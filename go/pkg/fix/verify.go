@@ -0,0 +1,168 @@
+package fix
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// Verification compares the IoC matches of a manifest before and after a
+// plan, computed by re-running matching on the proposed content in memory.
+type Verification struct {
+	Path   string
+	Before []formatter.Match
+	After  []formatter.Match
+	// Resolved are matches of changed packages that the plan removes
+	Resolved []formatter.Match
+	// Remaining are matches of changed packages still present after the plan
+	Remaining []formatter.Match
+	// Introduced are matches, such as new POTENTIAL hits from a loosened
+	// range, that only appear after the plan
+	Introduced []formatter.Match
+}
+
+// OK reports whether the plan removes the matches of every changed package
+// without introducing new ones.
+func (v *Verification) OK() bool {
+	return len(v.Remaining) == 0 && len(v.Introduced) == 0
+}
+
+// Verify re-runs DIRECT and POTENTIAL matching against the original and
+// proposed manifest of plan. It reads nothing from disk.
+func Verify(plan *Plan, iocDB *ioc.Database) (*Verification, error) {
+	before, err := matchManifest(plan.Path, plan.Original, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	after, err := matchManifest(plan.Path, plan.Proposed, iocDB)
+	if err != nil {
+		return nil, fmt.Errorf("proposed change breaks %s: %w", plan.Path, err)
+	}
+
+	changed := make(map[string]bool)
+	for _, change := range plan.Changes {
+		changed[change.Package] = true
+	}
+	beforeKeys := matchKeys(before)
+	afterKeys := matchKeys(after)
+
+	v := &Verification{Path: plan.Path, Before: before, After: after}
+	for _, match := range before {
+		if !changed[match.PackageName] {
+			continue
+		}
+		if afterKeys[matchKey(match)] {
+			v.Remaining = append(v.Remaining, match)
+		} else {
+			v.Resolved = append(v.Resolved, match)
+		}
+	}
+	for _, match := range after {
+		if !beforeKeys[matchKey(match)] {
+			v.Introduced = append(v.Introduced, match)
+		}
+	}
+	return v, nil
+}
+
+// matchManifest parses manifest content and matches it against the IoC
+// database.
+func matchManifest(path string, content []byte, iocDB *ioc.Database) ([]formatter.Match, error) {
+	var manifest parser.Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	return matcher.MatchManifest(&manifest, iocDB, path), nil
+}
+
+// matchKey identifies a match within one manifest. The declared spec is
+// left out, so a POTENTIAL match that survives a range change still counts
+// as the same match.
+func matchKey(match formatter.Match) string {
+	return strings.Join([]string{match.PackageName, match.Version, string(match.Severity)}, "\x00")
+}
+
+// matchKeys returns the set of keys of matches.
+func matchKeys(matches []formatter.Match) map[string]bool {
+	keys := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		keys[matchKey(match)] = true
+	}
+	return keys
+}
+
+// FormatVerification formats the before/after summary of verified plans.
+func FormatVerification(plans []*Plan, verifications []*Verification) string {
+	var b strings.Builder
+
+	b.WriteString("\nFIX VERIFICATION\n")
+	b.WriteString("────────────────────────────────────────────────────────\n")
+	if len(plans) == 0 {
+		b.WriteString("No declarations to change.\n")
+	}
+
+	for i, plan := range plans {
+		v := verifications[i]
+		b.WriteString("\n")
+		b.WriteString(plan.Path + "\n")
+		for _, change := range plan.Changes {
+			name := change.Package
+			if change.Alias != "" {
+				name = fmt.Sprintf("%s (alias %s)", change.Package, change.Alias)
+			}
+			if change.From == "" {
+				b.WriteString(fmt.Sprintf("   %s: add %s (%s)\n", name, change.To, change.Type))
+			} else {
+				b.WriteString(fmt.Sprintf("   %s: %s → %s (%s)\n", name, change.From, change.To, change.Type))
+			}
+		}
+		b.WriteString(fmt.Sprintf("   Before: %s\n", countMatches(v.Before)))
+		b.WriteString(fmt.Sprintf("   After:  %s\n", countMatches(v.After)))
+		for _, match := range v.Remaining {
+			b.WriteString(fmt.Sprintf("   ✗ Still matched: %s\n", describeMatch(match)))
+		}
+		for _, match := range v.Introduced {
+			b.WriteString(fmt.Sprintf("   ✗ Introduced: %s\n", describeMatch(match)))
+		}
+		if v.OK() {
+			b.WriteString(fmt.Sprintf("   ✓ Verified: %d matches resolved, none introduced\n", len(v.Resolved)))
+		}
+	}
+
+	b.WriteString("\n")
+	return b.String()
+}
+
+// countMatches summarizes matches by severity, e.g. "2 matches (1 DIRECT,
+// 1 POTENTIAL)".
+func countMatches(matches []formatter.Match) string {
+	if len(matches) == 0 {
+		return "0 matches"
+	}
+
+	counts := make(map[formatter.Severity]int)
+	for _, match := range matches {
+		counts[match.Severity]++
+	}
+	var parts []string
+	for _, severity := range []formatter.Severity{formatter.SeverityDirect, formatter.SeverityTransitive, formatter.SeverityPotential} {
+		if counts[severity] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[severity], severity))
+		}
+	}
+	return fmt.Sprintf("%d matches (%s)", len(matches), strings.Join(parts, ", "))
+}
+
+// describeMatch formats a match as "name@version (SEVERITY via spec)".
+func describeMatch(match formatter.Match) string {
+	if match.DeclaredSpec != "" {
+		return fmt.Sprintf("%s@%s (%s via %s)", match.PackageName, match.Version, match.Severity, match.DeclaredSpec)
+	}
+	return fmt.Sprintf("%s@%s (%s)", match.PackageName, match.Version, match.Severity)
+}
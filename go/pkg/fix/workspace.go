@@ -0,0 +1,138 @@
+package fix
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// Package managers, as detected from the lockfile at the workspace root.
+const (
+	ManagerNPM  = "npm"
+	ManagerYarn = "yarn"
+	ManagerPNPM = "pnpm"
+)
+
+// DetectPackageManager returns the package manager of the workspace rooted
+// at dir, from its lockfile. Without a yarn or pnpm lockfile, npm is assumed.
+func DetectPackageManager(dir string) string {
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		return ManagerPNPM
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		return ManagerYarn
+	default:
+		return ManagerNPM
+	}
+}
+
+// overridesPath returns the key path of the package manager's override
+// section in the root package.json: npm "overrides", yarn "resolutions" and
+// pnpm "pnpm.overrides".
+func overridesPath(manager string) []string {
+	switch manager {
+	case ManagerYarn:
+		return []string{"resolutions"}
+	case ManagerPNPM:
+		return []string{"pnpm", "overrides"}
+	default:
+		return []string{"overrides"}
+	}
+}
+
+// PlanOverrides extends the plan of the workspace root manifest to force
+// every target version through the package manager's override section, so
+// transitive copies are replaced as well. Existing overrides of a target
+// package are updated; missing ones are only added when add is set.
+func PlanOverrides(plan *Plan, manager string, targets map[string]string, add bool) error {
+	path := overridesPath(manager)
+	section := strings.Join(path, ".")
+
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		to := targets[name]
+		from, exists := stringAt(plan.Proposed, append(append([]string{}, path...), name)...)
+		if (exists && from == to) || (!exists && !add) {
+			continue
+		}
+
+		proposed, err := setString(plan.Proposed, path, name, to)
+		if err != nil {
+			return fmt.Errorf("failed to set %s override in %s: %w", name, plan.Path, err)
+		}
+		plan.Proposed = proposed
+		plan.Changes = append(plan.Changes, Change{Package: name, Type: section, From: from, To: to})
+	}
+	return nil
+}
+
+// lockfileCommand returns the command that refreshes the lockfile of the
+// workspace rooted at dir without installing or running lifecycle scripts
+// where the package manager allows it. Scripts are never run, since the
+// packages being replaced are known to be malicious.
+func lockfileCommand(dir, manager string) []string {
+	switch manager {
+	case ManagerPNPM:
+		return []string{"pnpm", "install", "--lockfile-only", "--ignore-scripts"}
+	case ManagerYarn:
+		if content, err := os.ReadFile(filepath.Join(dir, "yarn.lock")); err == nil && bytes.Contains(content, []byte("__metadata:")) {
+			return []string{"yarn", "install", "--mode=update-lockfile"}
+		}
+		return []string{"yarn", "install", "--ignore-scripts"}
+	default:
+		return []string{"npm", "install", "--package-lock-only", "--ignore-scripts"}
+	}
+}
+
+// UpdateLockfile runs the package manager's lockfile update in dir. If the
+// command fails, the lockfile is restored and the command output returned
+// in the error.
+func UpdateLockfile(dir, manager string) error {
+	lockfile := filepath.Join(dir, lockfileName(manager))
+	if err := readonly.Check(lockfile); err != nil {
+		return err
+	}
+	original, readErr := os.ReadFile(lockfile)
+
+	args := lockfileCommand(dir, manager)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+
+	if readErr == nil {
+		readonly.WriteFile(lockfile, original, 0644)
+	}
+	return fmt.Errorf("%s failed: %w\n%s", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+}
+
+// lockfileName returns the lockfile file name of a package manager.
+func lockfileName(manager string) string {
+	switch manager {
+	case ManagerPNPM:
+		return "pnpm-lock.yaml"
+	case ManagerYarn:
+		return "yarn.lock"
+	default:
+		return "package-lock.json"
+	}
+}
+
+// fileExists reports whether path exists and is a regular file.
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,306 @@
+// Package forensic exports evidence bundles for incident response: copies
+// of the files behind confirmed (DIRECT and TRANSITIVE) matches, with a
+// manifest of SHA-256 sums recording where each copy came from, so findings
+// keep a chain of custody after the scanned tree changes.
+//
+// A bundle is a timestamped directory:
+//
+//	evidence-20251124T223000Z/
+//	  files/...        copies, at their absolute source path
+//	  manifest.json    sources, hashes, sizes, times and matches
+//	  SHA256SUMS       sha256sum -c compatible checksums of the copies
+//
+// Manifests and lockfiles are copied as files. Matches of installed
+// packages (located at node_modules/<name>/package.json) copy the whole
+// package directory, without its nested node_modules.
+package forensic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// Bundle file names.
+const (
+	// ManifestFile lists the bundled files and their provenance
+	ManifestFile = "manifest.json"
+	// SumsFile holds sha256sum-compatible checksums of the copies
+	SumsFile = "SHA256SUMS"
+	// filesDir holds the copies inside the bundle
+	filesDir = "files"
+)
+
+// Manifest describes an evidence bundle.
+type Manifest struct {
+	CreatedAt time.Time `json:"createdAt"`
+	// Tool is the npm-scan version that created the bundle
+	Tool string `json:"tool"`
+	// ScanTimestamp and IOCDigest identify the scan and the IoC database
+	// the matches came from
+	ScanTimestamp time.Time         `json:"scanTimestamp"`
+	IOCDigest     string            `json:"iocDigest,omitempty"`
+	Host          string            `json:"host,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	Files         []File            `json:"files"`
+	// Missing lists match locations that could not be read, such as the
+	// paths of scanned SBOMs or in-memory inventories
+	Missing []string `json:"missing,omitempty"`
+}
+
+// File is a copied file.
+type File struct {
+	// Source is the absolute path the file was copied from
+	Source string `json:"source"`
+	// Path is the copy's path relative to the bundle directory
+	Path    string    `json:"path"`
+	SHA256  string    `json:"sha256"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// Matches lists the findings the file is evidence of, as
+	// "name@version (SEVERITY)"
+	Matches []string `json:"matches"`
+}
+
+// Bundle is a written evidence bundle.
+type Bundle struct {
+	// Dir is the bundle directory
+	Dir string
+	// ManifestSHA256 is the hash of manifest.json, to record in case notes
+	ManifestSHA256 string
+	Manifest       Manifest
+}
+
+// Export copies the evidence of result's DIRECT and TRANSITIVE matches
+// into a new bundle directory below outputDir and returns it. It returns
+// nil if result has no such matches. Files are written with readonly's
+// guard, so a read-only scan may export to an allowed outputDir.
+func Export(result *formatter.ScanResult, outputDir, version string, now time.Time) (*Bundle, error) {
+	sources := evidenceSources(result)
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	dir := filepath.Join(outputDir, "evidence-"+now.UTC().Format("20060102T150405Z"))
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("evidence bundle %s already exists", dir)
+	}
+	if err := readonly.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create evidence bundle: %w", err)
+	}
+
+	manifest := Manifest{
+		CreatedAt:     now.UTC(),
+		Tool:          "npm-scan " + version,
+		ScanTimestamp: result.Timestamp,
+		IOCDigest:     result.IOCDigest,
+		Metadata:      result.Metadata,
+	}
+	manifest.Host, _ = os.Hostname()
+
+	files := make(map[string]*File)
+	for _, source := range sources {
+		copied, err := copyEvidence(dir, source.path, source.packageDir)
+		if os.IsNotExist(err) {
+			manifest.Missing = append(manifest.Missing, source.path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range copied {
+			if existing, ok := files[file.Source]; ok {
+				file = existing
+			} else {
+				files[file.Source] = file
+			}
+			file.Matches = appendUnique(file.Matches, source.matches...)
+		}
+	}
+
+	for _, file := range files {
+		sort.Strings(file.Matches)
+		manifest.Files = append(manifest.Files, *file)
+	}
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	manifest.Missing = appendUnique(nil, manifest.Missing...)
+	sort.Strings(manifest.Missing)
+
+	var sums bytes.Buffer
+	for _, file := range manifest.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", file.SHA256, file.Path)
+	}
+	if err := readonly.WriteFile(filepath.Join(dir, SumsFile), sums.Bytes(), 0444); err != nil {
+		return nil, fmt.Errorf("write %s: %w", SumsFile, err)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	data = append(data, '\n')
+	if err := readonly.WriteFile(filepath.Join(dir, ManifestFile), data, 0444); err != nil {
+		return nil, fmt.Errorf("write %s: %w", ManifestFile, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return &Bundle{Dir: dir, ManifestSHA256: hex.EncodeToString(sum[:]), Manifest: manifest}, nil
+}
+
+// source is a file or installed package directory to copy.
+type source struct {
+	path string
+	// packageDir copies the directory of path, for installed packages
+	packageDir bool
+	matches    []string
+}
+
+// evidenceSources lists the files behind the confirmed matches of result,
+// in order of first appearance.
+func evidenceSources(result *formatter.ScanResult) []*source {
+	var sources []*source
+	index := make(map[string]*source)
+
+	add := func(path, match string) {
+		if path == "" {
+			return
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		s, ok := index[path]
+		if !ok {
+			s = &source{path: path, packageDir: isInstalledPackage(path)}
+			index[path] = s
+			sources = append(sources, s)
+		}
+		s.matches = appendUnique(s.matches, match)
+	}
+
+	for _, match := range result.Matches {
+		if match.Severity != formatter.SeverityDirect && match.Severity != formatter.SeverityTransitive {
+			continue
+		}
+		label := fmt.Sprintf("%s@%s (%s)", match.PackageName, match.Version, match.Severity)
+		if len(match.Evidence) == 0 {
+			add(match.Location, label)
+		}
+		for _, evidence := range match.Evidence {
+			add(evidence.File, label)
+			add(evidence.LockfilePath, label)
+		}
+	}
+
+	return sources
+}
+
+// isInstalledPackage reports whether path is the package.json of a package
+// installed in node_modules.
+func isInstalledPackage(path string) bool {
+	if filepath.Base(path) != "package.json" {
+		return false
+	}
+	parent := filepath.Dir(filepath.Dir(path))
+	if strings.HasPrefix(filepath.Base(parent), "@") {
+		parent = filepath.Dir(parent)
+	}
+	return filepath.Base(parent) == "node_modules"
+}
+
+// copyEvidence copies the file at path, or for a package directory every
+// regular file below it except nested node_modules, into the bundle.
+func copyEvidence(bundleDir, path string, packageDir bool) ([]*File, error) {
+	if !packageDir {
+		file, err := copyFile(bundleDir, path)
+		if err != nil {
+			return nil, err
+		}
+		return []*File{file}, nil
+	}
+
+	root := filepath.Dir(path)
+	if _, err := os.Stat(root); err != nil {
+		return nil, err
+	}
+	var files []*File
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "node_modules" && p != root {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file, err := copyFile(bundleDir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	return files, err
+}
+
+// copyFile copies the regular file at path to its absolute path below the
+// bundle's files directory, keeping its modification time, and hashes it.
+func copyFile(bundleDir, path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rel := filepath.Join(filesDir, strings.TrimPrefix(filepath.Clean(path), filepath.VolumeName(path)))
+	dst := filepath.Join(bundleDir, rel)
+	if err := readonly.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return nil, fmt.Errorf("copy %s: %w", path, err)
+	}
+	if err := readonly.WriteFile(dst, data, 0444); err != nil {
+		return nil, fmt.Errorf("copy %s: %w", path, err)
+	}
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return nil, fmt.Errorf("copy %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	return &File{
+		Source:  path,
+		Path:    filepath.ToSlash(rel),
+		SHA256:  hex.EncodeToString(sum[:]),
+		Size:    info.Size(),
+		ModTime: info.ModTime().UTC(),
+	}, nil
+}
+
+// appendUnique appends the values not already in list.
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, existing := range list {
+			if existing == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
@@ -0,0 +1,211 @@
+package forensic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// writeFiles creates the files below root, with paths relative to root
+func writeFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestExport tests which files end up in the bundle and how they are recorded
+func TestExport(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{
+		"package.json":                               `{"dependencies":{"evil":"1.0.1"}}`,
+		"package-lock.json":                          `{"lockfileVersion":3}`,
+		"node_modules/evil/package.json":             `{"name":"evil","version":"1.0.1"}`,
+		"node_modules/evil/index.js":                 `require("child_process")`,
+		"node_modules/evil/node_modules/dep/x.js":    `nested`,
+		"node_modules/@scope/bad/package.json":       `{"name":"@scope/bad","version":"2.0.0"}`,
+		"node_modules/@scope/bad/lib/postinstall.js": `steal()`,
+		"node_modules/clean/package.json":            `{"name":"clean","version":"1.0.0"}`,
+	})
+	mtime := time.Date(2025, 9, 16, 8, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(project, "package-lock.json"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := filepath.Join(project, "package.json")
+	lockfile := filepath.Join(project, "package-lock.json")
+	result := &formatter.ScanResult{
+		Timestamp: time.Date(2025, 11, 24, 22, 29, 0, 0, time.UTC),
+		IOCDigest: "abc123",
+		Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: manifest,
+				Evidence: []formatter.Evidence{{File: manifest, LockfilePath: lockfile}}},
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive, Location: lockfile},
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive,
+				Location: filepath.Join(project, "node_modules", "evil", "package.json")},
+			{PackageName: "@scope/bad", Version: "2.0.0", Severity: formatter.SeverityTransitive,
+				Location: filepath.Join(project, "node_modules", "@scope", "bad", "package.json")},
+			{PackageName: "gone", Version: "1.0.0", Severity: formatter.SeverityTransitive,
+				Location: filepath.Join(project, "missing-lock.json")},
+			{PackageName: "maybe", Version: "1.0.0", Severity: formatter.SeverityPotential,
+				Location: filepath.Join(project, "node_modules", "clean", "package.json")},
+		},
+	}
+
+	out := t.TempDir()
+	now := time.Date(2025, 11, 24, 22, 30, 0, 0, time.UTC)
+	bundle, err := Export(result, out, "test", now)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+	if bundle == nil {
+		t.Fatal("Export() = nil, want a bundle")
+	}
+	if want := filepath.Join(out, "evidence-20251124T223000Z"); bundle.Dir != want {
+		t.Errorf("Dir = %s, want %s", bundle.Dir, want)
+	}
+
+	copyPath := func(source string) string {
+		return filepath.ToSlash(filepath.Join(filesDir, filepath.Join(project, source)))
+	}
+	wantFiles := map[string][]string{
+		copyPath("package.json"):                               {"evil@1.0.1 (DIRECT)"},
+		copyPath("package-lock.json"):                          {"evil@1.0.1 (DIRECT)", "evil@1.0.1 (TRANSITIVE)"},
+		copyPath("node_modules/evil/package.json"):             {"evil@1.0.1 (TRANSITIVE)"},
+		copyPath("node_modules/evil/index.js"):                 {"evil@1.0.1 (TRANSITIVE)"},
+		copyPath("node_modules/@scope/bad/package.json"):       {"@scope/bad@2.0.0 (TRANSITIVE)"},
+		copyPath("node_modules/@scope/bad/lib/postinstall.js"): {"@scope/bad@2.0.0 (TRANSITIVE)"},
+	}
+	if len(bundle.Manifest.Files) != len(wantFiles) {
+		t.Errorf("bundled %d files, want %d: %+v", len(bundle.Manifest.Files), len(wantFiles), bundle.Manifest.Files)
+	}
+	for _, file := range bundle.Manifest.Files {
+		matches, ok := wantFiles[file.Path]
+		if !ok {
+			t.Errorf("unexpected file %s in bundle", file.Path)
+			continue
+		}
+		if strings.Join(file.Matches, ", ") != strings.Join(matches, ", ") {
+			t.Errorf("%s matches = %v, want %v", file.Path, file.Matches, matches)
+		}
+
+		data, err := os.ReadFile(filepath.Join(bundle.Dir, filepath.FromSlash(file.Path)))
+		if err != nil {
+			t.Fatalf("copy of %s: %v", file.Source, err)
+		}
+		original, _ := os.ReadFile(file.Source)
+		if string(data) != string(original) {
+			t.Errorf("copy of %s differs from the original", file.Source)
+		}
+		sum := sha256.Sum256(data)
+		if file.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s sha256 = %s, want %x", file.Path, file.SHA256, sum)
+		}
+	}
+
+	if want := []string{filepath.Join(project, "missing-lock.json")}; strings.Join(bundle.Manifest.Missing, ",") != strings.Join(want, ",") {
+		t.Errorf("Missing = %v, want %v", bundle.Manifest.Missing, want)
+	}
+
+	// The lockfile copy keeps its modification time
+	info, err := os.Stat(filepath.Join(bundle.Dir, filepath.FromSlash(copyPath("package-lock.json"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(mtime) {
+		t.Errorf("copy mtime = %s, want %s", info.ModTime(), mtime)
+	}
+
+	// SHA256SUMS lists every copy
+	sums, err := os.ReadFile(filepath.Join(bundle.Dir, SumsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(sums)), "\n"); len(lines) != len(wantFiles) {
+		t.Errorf("%s has %d lines, want %d", SumsFile, len(lines), len(wantFiles))
+	}
+
+	// The manifest on disk matches the returned hash and contents
+	data, err := os.ReadFile(filepath.Join(bundle.Dir, ManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	if bundle.ManifestSHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("ManifestSHA256 = %s, want %x", bundle.ManifestSHA256, sum)
+	}
+	var written Manifest
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("manifest.json: %v", err)
+	}
+	if written.IOCDigest != "abc123" || !written.ScanTimestamp.Equal(result.Timestamp) || !written.CreatedAt.Equal(now) {
+		t.Errorf("manifest header = %+v", written)
+	}
+
+	// A second export in the same second must not overwrite the bundle
+	if _, err := Export(result, out, "test", now); err == nil {
+		t.Error("Export() into an existing bundle succeeded, want an error")
+	}
+}
+
+// TestExport_NoConfirmedMatches tests that no bundle is created without DIRECT or TRANSITIVE matches
+func TestExport_NoConfirmedMatches(t *testing.T) {
+	result := &formatter.ScanResult{
+		Matches: []formatter.Match{
+			{PackageName: "maybe", Version: "1.0.0", Severity: formatter.SeverityPotential, Location: "package.json"},
+		},
+	}
+
+	out := t.TempDir()
+	bundle, err := Export(result, out, "test", time.Now())
+	if err != nil || bundle != nil {
+		t.Fatalf("Export() = %v, %v, want nil, nil", bundle, err)
+	}
+	entries, _ := os.ReadDir(out)
+	if len(entries) != 0 {
+		t.Errorf("output directory has %d entries, want none", len(entries))
+	}
+}
+
+// TestExport_ReadOnly tests that read-only mode confines the bundle to the allowed directory
+func TestExport_ReadOnly(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, map[string]string{"package-lock.json": `{}`})
+	result := &formatter.ScanResult{
+		Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive, Location: filepath.Join(project, "package-lock.json")},
+		},
+	}
+
+	out := t.TempDir()
+	defer readonly.Disable()
+
+	if err := readonly.Enable(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(result, out, "test", time.Now()); !errors.Is(err, readonly.ErrReadOnly) {
+		t.Errorf("Export() without an allowed directory error = %v, want ErrReadOnly", err)
+	}
+
+	if err := readonly.Enable(out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Export(result, out, "test", time.Now()); err != nil {
+		t.Errorf("Export() into the allowed directory error = %v", err)
+	}
+}
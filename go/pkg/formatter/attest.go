@@ -0,0 +1,41 @@
+package formatter
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Attestation is the minimal record of a scan's outcome, meant to be kept
+// as a build gate artifact without the match and inventory payload.
+type Attestation struct {
+	// Status is the scan verdict (see Summary.Verdict)
+	Status string `json:"status"`
+	// DBHash identifies the IoC data the scan ran against
+	DBHash string `json:"dbHash,omitempty"`
+	// Commit is the git HEAD commit of the scanned repository, if known
+	Commit    string    `json:"commit,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// Version is the npm-scan version that produced the result
+	Version string `json:"version,omitempty"`
+}
+
+// NewAttestation summarizes result as an Attestation.
+func NewAttestation(result *ScanResult, version string) Attestation {
+	return Attestation{
+		Status:    Summarize(result).Verdict,
+		DBHash:    result.IOCDigest,
+		Commit:    result.Metadata[MetaGitCommit],
+		Timestamp: result.Timestamp,
+		Version:   version,
+	}
+}
+
+// FormatAttestation formats result as a single-line Attestation JSON
+// object.
+func FormatAttestation(result *ScanResult, version string) (string, error) {
+	data, err := json.Marshal(NewAttestation(result, version))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
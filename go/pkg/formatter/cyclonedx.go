@@ -0,0 +1,262 @@
+package formatter
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cycloneDXSpecVersion is the CycloneDX version of exported BOMs; 1.5 is the
+// first to carry VEX data (vulnerabilities with analysis) inside the BOM.
+const cycloneDXSpecVersion = "1.5"
+
+// cycloneDXDocument mirrors the subset of a CycloneDX 1.5 BOM that is exported.
+type cycloneDXDocument struct {
+	BOMFormat       string                   `json:"bomFormat"`
+	SpecVersion     string                   `json:"specVersion"`
+	Version         int                      `json:"version"`
+	Metadata        cycloneDXMetadata        `json:"metadata"`
+	Components      []cycloneDXComponent     `json:"components"`
+	Vulnerabilities []cycloneDXVulnerability `json:"vulnerabilities"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string         `json:"timestamp"`
+	Tools     cycloneDXTools `json:"tools"`
+}
+
+type cycloneDXTools struct {
+	Components []cycloneDXTool `json:"components"`
+}
+
+type cycloneDXTool struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cycloneDXComponent struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref"`
+	Group      string              `json:"group,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version"`
+	PURL       string              `json:"purl"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cycloneDXVulnerability struct {
+	BOMRef      string             `json:"bom-ref"`
+	ID          string             `json:"id"`
+	Source      *cycloneDXSource   `json:"source,omitempty"`
+	References  []cycloneDXRef     `json:"references,omitempty"`
+	Ratings     []cycloneDXRating  `json:"ratings,omitempty"`
+	Description string             `json:"description"`
+	Analysis    cycloneDXAnalysis  `json:"analysis"`
+	Affects     []cycloneDXAffects `json:"affects"`
+}
+
+type cycloneDXSource struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+type cycloneDXRating struct {
+	Severity string `json:"severity"`
+	Method   string `json:"method"`
+}
+
+// cycloneDXSeverities are the severities a CycloneDX rating accepts.
+var cycloneDXSeverities = map[string]bool{
+	"critical": true,
+	"high":     true,
+	"medium":   true,
+	"low":      true,
+	"info":     true,
+	"none":     true,
+}
+
+type cycloneDXRef struct {
+	ID     string          `json:"id"`
+	Source cycloneDXSource `json:"source"`
+}
+
+type cycloneDXAnalysis struct {
+	State    string   `json:"state"`
+	Response []string `json:"response,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+type cycloneDXAffects struct {
+	Ref string `json:"ref"`
+}
+
+// FormatCycloneDX formats the discovered components as a CycloneDX 1.5 JSON
+// BOM with embedded VEX data.
+//
+// Every component becomes a library identified by its package URL, listing
+// the files it was found in as "npm-scan:location" properties. Each
+// compromised component (a DIRECT or TRANSITIVE match) gets a vulnerability
+// whose analysis state reflects its remediation status: exploitable unless
+// triaged as fixed (resolved) or a false positive. POTENTIAL matches are
+// left out since the matched version is not necessarily installed.
+//
+// The BOM is produced even when result has no matches. toolVersion is
+// reported in the metadata.
+func FormatCycloneDX(components []Component, result *ScanResult, toolVersion string) (string, error) {
+	doc := cycloneDXDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: cycloneDXSpecVersion,
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: result.Timestamp.UTC().Format(time.RFC3339),
+			Tools: cycloneDXTools{Components: []cycloneDXTool{
+				{Type: "application", Name: "npm-scan", Version: toolVersion},
+			}},
+		},
+		Components:      []cycloneDXComponent{},
+		Vulnerabilities: []cycloneDXVulnerability{},
+	}
+
+	// Matched versions are components too, even if the inventory missed them
+	listed := make(map[string]bool)
+	for _, component := range components {
+		listed[npmPurl(component.Name, component.Version)] = true
+	}
+	for _, match := range result.Matches {
+		purl := npmPurl(match.PackageName, match.Version)
+		if match.Severity == SeverityPotential || listed[purl] {
+			continue
+		}
+		listed[purl] = true
+		components = append(components, Component{Name: match.PackageName, Version: match.Version, Locations: []string{match.Location}})
+	}
+	sort.SliceStable(components, func(i, j int) bool {
+		if components[i].Name != components[j].Name {
+			return components[i].Name < components[j].Name
+		}
+		return components[i].Version < components[j].Version
+	})
+
+	for _, component := range components {
+		doc.Components = append(doc.Components, cycloneDXComponentFor(component))
+	}
+
+	byPurl := make(map[string][]Match)
+	var purls []string
+	for _, match := range result.Matches {
+		if match.Severity == SeverityPotential {
+			continue
+		}
+		purl := npmPurl(match.PackageName, match.Version)
+		if _, ok := byPurl[purl]; !ok {
+			purls = append(purls, purl)
+		}
+		byPurl[purl] = append(byPurl[purl], match)
+	}
+	sort.Strings(purls)
+	for _, purl := range purls {
+		doc.Vulnerabilities = append(doc.Vulnerabilities, cycloneDXVulnerabilityFor(purl, byPurl[purl]))
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// cycloneDXComponentFor builds the BOM entry of a component, splitting the
+// scope into the CycloneDX group as npm tooling does.
+func cycloneDXComponentFor(component Component) cycloneDXComponent {
+	purl := npmPurl(component.Name, component.Version)
+	c := cycloneDXComponent{
+		Type:    "library",
+		BOMRef:  purl,
+		Name:    component.Name,
+		Version: component.Version,
+		PURL:    purl,
+	}
+	if scope, name, ok := strings.Cut(component.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+		c.Group, c.Name = scope, name
+	}
+	for _, location := range component.Locations {
+		c.Properties = append(c.Properties, cycloneDXProperty{Name: "npm-scan:location", Value: location})
+	}
+	return c
+}
+
+// cycloneDXVulnerabilityFor builds the VEX entry of a compromised component
+// from its matches. The first advisory ID identifies the vulnerability; the
+// others are listed as references.
+func cycloneDXVulnerabilityFor(purl string, matches []Match) cycloneDXVulnerability {
+	first := matches[0]
+	v := cycloneDXVulnerability{
+		BOMRef:      "vex/" + purl,
+		ID:          "ioc/" + first.PackageName + "@" + first.Version,
+		Description: first.PackageName + " " + first.Version + " is listed in the IoC database of compromised npm packages.",
+		Affects:     []cycloneDXAffects{{Ref: purl}},
+	}
+
+	var advisory *Advisory
+	var locations []string
+	for _, match := range matches {
+		if advisory == nil {
+			advisory = match.Advisory
+		}
+		locations = append(locations, match.Files()...)
+	}
+	if advisory != nil {
+		if len(advisory.IDs) > 0 {
+			v.ID = advisory.IDs[0]
+			for _, id := range advisory.IDs[1:] {
+				v.References = append(v.References, cycloneDXRef{ID: id, Source: cycloneDXSource{URL: advisory.URL}})
+			}
+		}
+		if advisory.URL != "" || advisory.Campaign != "" {
+			v.Source = &cycloneDXSource{Name: advisory.Campaign, URL: advisory.URL}
+		}
+		if cycloneDXSeverities[advisory.Severity] {
+			v.Ratings = []cycloneDXRating{{Severity: advisory.Severity, Method: "other"}}
+		}
+	}
+
+	v.Analysis = cycloneDXAnalysisFor(matches)
+	v.Analysis.Detail = "Found in " + strings.Join(locations, ", ")
+	return v
+}
+
+// cycloneDXAnalysisFor maps the remediation status of a component's matches
+// to a VEX analysis. A component is only marked resolved or a false
+// positive when every match was triaged that way.
+func cycloneDXAnalysisFor(matches []Match) cycloneDXAnalysis {
+	status := ""
+	for i, match := range matches {
+		s := ""
+		if match.Remediation != nil {
+			s = match.Remediation.Status
+		}
+		if i > 0 && s != status {
+			status = ""
+			break
+		}
+		status = s
+	}
+
+	switch status {
+	case "fixed":
+		return cycloneDXAnalysis{State: "resolved", Response: []string{"update"}}
+	case "false-positive":
+		return cycloneDXAnalysis{State: "false_positive"}
+	case "accepted-risk":
+		return cycloneDXAnalysis{State: "exploitable", Response: []string{"will_not_fix"}}
+	default:
+		return cycloneDXAnalysis{State: "exploitable"}
+	}
+}
@@ -0,0 +1,74 @@
+package formatter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path/filepath"
+	"strings"
+)
+
+// fingerprintLength is the number of hex digits kept from the match hash;
+// short enough to type on the command line, long enough to stay unique
+// across a fleet.
+const fingerprintLength = 16
+
+// Fingerprint returns an identifier for a match derived from its package,
+// version, severity and location. The location is normalized against root
+// (see NormalizeLocation), so the same finding gets the same fingerprint on
+// every scan, from any checkout directory and on any OS. Baselines,
+// acknowledgements and ticket deduplication all key on it.
+func Fingerprint(match Match, root string) string {
+	h := sha256.New()
+	h.Write([]byte(strings.Join([]string{
+		match.PackageName,
+		match.Version,
+		string(match.Severity),
+		NormalizeLocation(match.Location, root),
+	}, "\x00")))
+	return hex.EncodeToString(h.Sum(nil))[:fingerprintLength]
+}
+
+// NormalizeLocation returns location relative to the scan root, with forward
+// slashes. If root is the location itself (a single scanned file), the file
+// name is used. Locations outside root, or when root is empty, are only
+// cleaned.
+func NormalizeLocation(location, root string) string {
+	if root == "" {
+		return filepath.ToSlash(filepath.Clean(location))
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(location))
+	}
+	absLocation, err := filepath.Abs(location)
+	if err != nil {
+		return filepath.ToSlash(filepath.Clean(location))
+	}
+
+	rel, err := filepath.Rel(absRoot, absLocation)
+	switch {
+	case err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)):
+		return filepath.ToSlash(filepath.Clean(location))
+	case rel == ".":
+		return filepath.Base(absLocation)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// AssignFingerprints sets the Fingerprint of every match and hygiene finding
+// in result, including the per-project copies, normalizing locations
+// against the scan root.
+func AssignFingerprints(result *ScanResult, root string) {
+	assign := func(matches []Match) {
+		for i := range matches {
+			matches[i].Fingerprint = Fingerprint(matches[i], root)
+		}
+	}
+
+	assign(result.Matches)
+	assign(result.Hygiene)
+	for i := range result.Projects {
+		assign(result.Projects[i].Matches)
+	}
+}
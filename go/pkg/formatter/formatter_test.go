@@ -1,7 +1,11 @@
 package formatter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -77,6 +81,99 @@ func TestFormatHuman_DirectMatches(t *testing.T) {
 	}
 }
 
+func TestFormatHuman_Snapshot(t *testing.T) {
+	taken := time.Date(2025, 11, 26, 0, 0, 0, 0, time.UTC)
+	result := &ScanResult{IOCCount: 795, IOCSnapshot: &taken}
+
+	output := FormatHuman(result)
+	if !strings.Contains(output, "2025-11-26 (offline, may be stale)") {
+		t.Error("expected snapshot date and staleness warning")
+	}
+
+	result.IOCSnapshot = nil
+	if strings.Contains(FormatHuman(result), "IoC Snapshot") {
+		t.Error("unexpected snapshot line for a fetched database")
+	}
+}
+
+func TestFormatHuman_Advisory(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{{
+			PackageName: "evil",
+			Version:     "1.0.1",
+			Severity:    SeverityTransitive,
+			Location:    "package-lock.json",
+			Advisory: &Advisory{
+				IDs:      []string{"GHSA-aaaa-bbbb-cccc", "CVE-2025-0001"},
+				URL:      "https://example.com/advisory",
+				Campaign: "shai-hulud-2",
+				Severity: "critical",
+			},
+		}},
+	}
+
+	output := FormatHuman(result)
+	if !strings.Contains(output, "GHSA-aaaa-bbbb-cccc, CVE-2025-0001 (https://example.com/advisory)") {
+		t.Error("expected advisory IDs and URL")
+	}
+	if !strings.Contains(output, "shai-hulud-2") {
+		t.Error("expected campaign")
+	}
+	if !strings.Contains(output, "Severity:\x1b[0m critical") {
+		t.Error("expected severity")
+	}
+
+	result.Matches[0].Advisory = nil
+	if strings.Contains(FormatHuman(result), "Advisory:") {
+		t.Error("unexpected advisory line for a match without one")
+	}
+	if strings.Contains(FormatHuman(result), "Sources:") {
+		t.Error("unexpected sources line for a match from one source")
+	}
+
+	result.Matches[0].Evidence = []Evidence{{File: "package-lock.json", Line: 12}, {File: "web/package-lock.json", Line: 30}}
+	output = FormatHuman(result)
+	if !strings.Contains(output, "Resolved:\x1b[0m package-lock.json:12") || !strings.Contains(output, "Also in:\x1b[0m web/package-lock.json:30") {
+		t.Error("expected evidence lines and merged occurrences")
+	}
+
+	result.Matches[0].Sources = []SourceReport{{Source: "csv"}, {Source: "osv"}}
+	if !strings.Contains(FormatHuman(result), "Sources:\x1b[0m csv, osv") {
+		t.Error("expected the reporting sources of a correlated match")
+	}
+}
+
+// TestFormatHumanWith_Top tests limiting each severity section to its top matches
+func TestFormatHumanWith_Top(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "ranged", Version: "1.0.0", Severity: SeverityTransitive, Location: "a/package-lock.json", IOCRange: "< 2.0.0"},
+			{PackageName: "single", Version: "1.0.0", Severity: SeverityTransitive, Location: "a/package-lock.json"},
+			{PackageName: "spread", Version: "1.0.0", Severity: SeverityTransitive, Location: "a/package-lock.json",
+				Evidence: []Evidence{{File: "a/package-lock.json"}, {File: "b/package-lock.json"}}},
+			{PackageName: "pinned", Version: "1.0.0", Severity: SeverityDirect, Location: "a/package.json"},
+		},
+	}
+
+	output := FormatHumanWith(result, HumanOptions{Top: 2})
+	if !strings.Contains(output, "TRANSITIVE DEPENDENCIES (3)") {
+		t.Error("expected the section count to include hidden matches")
+	}
+	if !strings.Contains(output, "1. spread@1.0.0") || !strings.Contains(output, "2. single@1.0.0") || strings.Contains(output, "ranged@1.0.0") {
+		t.Errorf("expected the most widespread and exact matches first, got:\n%s", output)
+	}
+	if !strings.Contains(output, "... and 1 more, see the JSON report") {
+		t.Error("expected a footer for the hidden match")
+	}
+	if !strings.Contains(output, "1. pinned@1.0.0") || strings.Count(output, "more, see the JSON report") != 1 {
+		t.Error("expected sections within the limit to be shown in full without a footer")
+	}
+
+	if output := FormatHumanWith(result, HumanOptions{}); output != FormatHuman(result) || !strings.Contains(output, "1. ranged@1.0.0") {
+		t.Error("expected no limit by default, in the original order")
+	}
+}
+
 func TestFormatHuman_TransitiveMatches(t *testing.T) {
 	result := &ScanResult{
 		ManifestsScanned: 1,
@@ -304,6 +401,87 @@ func TestFormatJSON_WithMatches(t *testing.T) {
 	}
 }
 
+func TestFormatJSON_Summary(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "a", Version: "1.0.0", Severity: SeverityTransitive, Location: "./package-lock.json"},
+			{PackageName: "b", Version: "1.0.0", Severity: SeverityTransitive, Location: "./package-lock.json"},
+			{PackageName: "c", Version: "2.0.0", Severity: SeverityPotential, Location: "./package.json"},
+		},
+		Timestamp: time.Date(2025, 11, 28, 3, 50, 0, 0, time.UTC),
+	}
+
+	output, err := FormatJSON(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded ScanResult
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if result.Summary != nil {
+		t.Error("expected FormatJSON not to modify its input")
+	}
+
+	summary := decoded.Summary
+	if summary == nil {
+		t.Fatal("expected summary object")
+	}
+	if summary.Verdict != VerdictAffected || summary.TotalMatches != 3 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if summary.BySeverity[SeverityTransitive] != 2 || summary.BySeverity[SeverityDirect] != 0 {
+		t.Errorf("unexpected severity counts: %v", summary.BySeverity)
+	}
+	if summary.ByFile["./package-lock.json"] != 2 || summary.ByFile["./package.json"] != 1 {
+		t.Errorf("unexpected file counts: %v", summary.ByFile)
+	}
+
+	if got := Summarize(&ScanResult{Matches: result.Matches[2:]}).Verdict; got != VerdictAtRisk {
+		t.Errorf("expected at-risk verdict for POTENTIAL-only matches, got %s", got)
+	}
+	if got := Summarize(&ScanResult{}).Verdict; got != VerdictClean {
+		t.Errorf("expected clean verdict, got %s", got)
+	}
+}
+
+// TestSummarize_Verdict tests that only a complete scan that does not fail
+// is clean
+func TestSummarize_Verdict(t *testing.T) {
+	warnings := []FileError{{Path: "package-lock.json", Error: "parse timed out"}}
+	policy := []Match{{PackageName: "lodash", Version: "4.17.20", Severity: SeverityPolicy}}
+	unapproved := []Match{{PackageName: "left-pad", Version: "1.3.0", Severity: SeverityUnapproved}}
+	potential := []Match{{PackageName: "evil", Version: "1.0.1", Severity: SeverityPotential}}
+
+	tests := []struct {
+		name   string
+		result *ScanResult
+		want   string
+	}{
+		{"clean", &ScanResult{}, VerdictClean},
+		{"warnings", &ScanResult{Warnings: warnings}, VerdictIncomplete},
+		{"warnings and potential", &ScanResult{Warnings: warnings, Matches: potential}, VerdictAtRisk},
+		{"policy", &ScanResult{PolicyViolations: policy, Warnings: warnings}, VerdictFailing},
+		{"unapproved", &ScanResult{Unapproved: unapproved, Matches: potential}, VerdictFailing},
+		{"not scanned", &ScanResult{NotScanned: "feed unavailable", Warnings: warnings}, VerdictNotScanned},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.result).Verdict; got != tt.want {
+				t.Errorf("Summarize().Verdict = %q, want %q", got, tt.want)
+			}
+			if got := NewAttestation(tt.result, "").Status; got != tt.want {
+				t.Errorf("NewAttestation().Status = %q, want %q", got, tt.want)
+			}
+			// A scan that fails at any threshold is never clean
+			if Fails(tt.result, SeverityDirect) && tt.want == VerdictClean {
+				t.Errorf("clean verdict for a failing scan")
+			}
+		})
+	}
+}
+
 func TestFormatJSON_PrettyPrinted(t *testing.T) {
 	result := &ScanResult{
 		ManifestsScanned: 1,
@@ -364,6 +542,197 @@ func TestFormatJSON_NilMatches(t *testing.T) {
 	}
 }
 
+func TestFormatGrypeJSON(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "@scope/pkg", Version: "1.0.0", Severity: SeverityTransitive, Location: "package-lock.json", Fingerprint: "0123456789abcdef"},
+			{PackageName: "lodash", Version: "4.17.19", Severity: SeverityPotential, Location: "package.json", DeclaredSpec: "^4.17.0",
+				Advisory: &Advisory{IDs: []string{"GHSA-aaaa-bbbb-cccc"}, URL: "https://example.com/advisory"}},
+		},
+		Timestamp: time.Date(2025, 11, 28, 3, 50, 0, 0, time.UTC),
+	}
+
+	output, err := FormatGrypeJSON(result, "1.2.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded grypeDocument
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+
+	if decoded.Descriptor.Name != "npm-scan" || decoded.Descriptor.Version != "1.2.3" {
+		t.Errorf("unexpected descriptor: %+v", decoded.Descriptor)
+	}
+	if len(decoded.Matches) != 2 {
+		t.Fatalf("expected 2 matches, got %d", len(decoded.Matches))
+	}
+
+	first := decoded.Matches[0]
+	if first.Artifact.PURL != "pkg:npm/%40scope/pkg@1.0.0" {
+		t.Errorf("expected encoded scoped purl, got %s", first.Artifact.PURL)
+	}
+	if first.Vulnerability.Severity != "Critical" || first.MatchDetails[0].Type != "exact-indirect-match" {
+		t.Errorf("unexpected transitive match: %+v", first)
+	}
+	if first.MatchDetails[0].Found["fingerprint"] != "0123456789abcdef" {
+		t.Errorf("expected fingerprint in match details, got %v", first.MatchDetails[0].Found)
+	}
+	if first.Vulnerability.ID != "IOC-@scope/pkg@1.0.0" {
+		t.Errorf("expected synthetic ID without an advisory, got %s", first.Vulnerability.ID)
+	}
+	if decoded.Matches[1].Vulnerability.Severity != "Medium" {
+		t.Errorf("expected POTENTIAL match to be Medium, got %s", decoded.Matches[1].Vulnerability.Severity)
+	}
+	if v := decoded.Matches[1].Vulnerability; v.ID != "GHSA-aaaa-bbbb-cccc" || v.DataSource != "https://example.com/advisory" {
+		t.Errorf("expected advisory ID and URL, got %+v", v)
+	}
+}
+
+func TestFormatSARIF(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "lodash", Version: "4.17.19", Severity: SeverityPotential, Location: "package.json", DeclaredSpec: "^4.17.0"},
+			{PackageName: "@scope/pkg", Version: "1.0.0", Severity: SeverityTransitive, Location: "packages/app/package-lock.json", Fingerprint: "0123456789abcdef",
+				Advisory: &Advisory{IDs: []string{"GHSA-aaaa-bbbb-cccc"}, URL: "https://example.com/advisory"}},
+			{PackageName: "lodash", Version: "4.17.20", Severity: SeverityTransitive, Location: "/abs/package-lock.json"},
+		},
+	}
+
+	output, err := FormatSARIF(result, "1.2.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded sarifDocument
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if decoded.Version != "2.1.0" || len(decoded.Runs) != 1 {
+		t.Fatalf("unexpected document: version %s, %d runs", decoded.Version, len(decoded.Runs))
+	}
+
+	run := decoded.Runs[0]
+	if run.Tool.Driver.Name != "npm-scan" || run.Tool.Driver.Version != "1.2.3" {
+		t.Errorf("unexpected driver: %+v", run.Tool.Driver)
+	}
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "ioc/@scope/pkg" || run.Tool.Driver.Rules[1].ID != "ioc/lodash" {
+		t.Fatalf("expected one rule per package, got %+v", run.Tool.Driver.Rules)
+	}
+	if rule := run.Tool.Driver.Rules[0]; rule.HelpURI != "https://example.com/advisory" {
+		t.Errorf("expected advisory help URI, got %q", rule.HelpURI)
+	}
+	if rule := run.Tool.Driver.Rules[1]; rule.DefaultConfiguration.Level != "error" || rule.Properties.SecuritySeverity != "9.8" {
+		t.Errorf("expected the most severe lodash match to set the rule level, got %+v", rule)
+	}
+
+	if len(run.Results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(run.Results))
+	}
+	first := run.Results[0]
+	if first.RuleIndex != 0 || first.Level != "error" || first.PartialFingerprints["npmScanFingerprint/v1"] != "0123456789abcdef" {
+		t.Errorf("unexpected result: %+v", first)
+	}
+	if uri := first.Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "packages/app/package-lock.json" {
+		t.Errorf("expected relative URI, got %s", uri)
+	}
+	if potential := run.Results[1]; potential.RuleID != "ioc/lodash" || potential.Level != "warning" {
+		t.Errorf("expected POTENTIAL match as a warning, got %+v", potential)
+	}
+	if uri := run.Results[2].Locations[0].PhysicalLocation.ArtifactLocation.URI; uri != "file:///abs/package-lock.json" {
+		t.Errorf("expected file URI for an absolute location, got %s", uri)
+	}
+	if first.Locations[0].PhysicalLocation.Region != nil {
+		t.Errorf("unexpected region for a match without evidence: %+v", first.Locations[0].PhysicalLocation.Region)
+	}
+
+	result.Matches[1].Evidence = []Evidence{
+		{File: "packages/app/package-lock.json", Line: 12},
+		{File: "packages/web/package-lock.json"},
+	}
+	output, err = FormatSARIF(result, "1.2.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded = sarifDocument{}
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	locations := decoded.Runs[0].Results[0].Locations
+	if len(locations) != 2 || locations[0].PhysicalLocation.Region == nil || locations[0].PhysicalLocation.Region.StartLine != 12 ||
+		locations[1].PhysicalLocation.ArtifactLocation.URI != "packages/web/package-lock.json" || locations[1].PhysicalLocation.Region != nil {
+		t.Errorf("expected one location per evidence record, got %+v", locations)
+	}
+}
+
+func TestFormatCycloneDX(t *testing.T) {
+	components := []Component{
+		{Name: "lodash", Version: "4.17.21", Locations: []string{"package-lock.json"}},
+		{Name: "@scope/pkg", Version: "1.0.0", Locations: []string{"package.json", "package-lock.json"}},
+	}
+	result := &ScanResult{
+		Timestamp: time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC),
+		Matches: []Match{
+			{PackageName: "@scope/pkg", Version: "1.0.0", Severity: SeverityDirect, Location: "package.json",
+				Advisory: &Advisory{IDs: []string{"GHSA-aaaa-bbbb-cccc", "CVE-2025-0001"}, URL: "https://example.com/advisory", Severity: "critical"}},
+			{PackageName: "@scope/pkg", Version: "1.0.0", Severity: SeverityTransitive, Location: "package-lock.json"},
+			{PackageName: "chalk", Version: "5.6.1", Severity: SeverityTransitive, Location: "yarn.lock",
+				Remediation: &Remediation{Status: "false-positive"}},
+			{PackageName: "debug", Version: "4.4.2", Severity: SeverityPotential, Location: "package.json", DeclaredSpec: "^4.0.0"},
+		},
+	}
+
+	output, err := FormatCycloneDX(components, result, "1.2.3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var decoded cycloneDXDocument
+	if err := json.Unmarshal([]byte(output), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if decoded.BOMFormat != "CycloneDX" || decoded.SpecVersion != "1.5" || decoded.Metadata.Timestamp != "2025-09-01T12:00:00Z" {
+		t.Errorf("unexpected document header: %+v", decoded)
+	}
+
+	// The chalk match is added to the inventory; the POTENTIAL debug match is not
+	var purls []string
+	for _, c := range decoded.Components {
+		purls = append(purls, c.PURL)
+	}
+	want := []string{"pkg:npm/%40scope/pkg@1.0.0", "pkg:npm/chalk@5.6.1", "pkg:npm/lodash@4.17.21"}
+	if !reflect.DeepEqual(purls, want) {
+		t.Fatalf("components = %v, want %v", purls, want)
+	}
+	if scoped := decoded.Components[0]; scoped.Group != "@scope" || scoped.Name != "pkg" || len(scoped.Properties) != 2 {
+		t.Errorf("unexpected scoped component: %+v", scoped)
+	}
+
+	if len(decoded.Vulnerabilities) != 2 {
+		t.Fatalf("expected 2 VEX entries, got %+v", decoded.Vulnerabilities)
+	}
+	scoped := decoded.Vulnerabilities[0]
+	if scoped.ID != "GHSA-aaaa-bbbb-cccc" || len(scoped.References) != 1 || scoped.References[0].ID != "CVE-2025-0001" {
+		t.Errorf("unexpected advisory identifiers: %+v", scoped)
+	}
+	if scoped.Analysis.State != "exploitable" || scoped.Affects[0].Ref != "pkg:npm/%40scope/pkg@1.0.0" {
+		t.Errorf("unexpected VEX entry: %+v", scoped)
+	}
+	if len(scoped.Ratings) != 1 || scoped.Ratings[0].Severity != "critical" {
+		t.Errorf("expected the advisory severity as a rating, got %+v", scoped.Ratings)
+	}
+	if chalk := decoded.Vulnerabilities[1]; chalk.ID != "ioc/chalk@5.6.1" || chalk.Analysis.State != "false_positive" {
+		t.Errorf("expected a triaged false positive, got %+v", chalk)
+	}
+
+	// A BOM is produced without findings too
+	output, err = FormatCycloneDX(components, &ScanResult{}, "1.2.3")
+	if err != nil || !strings.Contains(output, `"vulnerabilities": []`) {
+		t.Errorf("expected an empty vulnerability list, got %v:\n%s", err, output)
+	}
+}
+
 func TestFilterBySeverity(t *testing.T) {
 	matches := []Match{
 		{PackageName: "a", Severity: SeverityDirect},
@@ -389,6 +758,276 @@ func TestFilterBySeverity(t *testing.T) {
 	}
 }
 
+func TestRedactor_Paths(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "a", Severity: SeverityDirect, Location: "/home/alice/acme-billing/package.json"},
+		},
+	}
+
+	redactor := NewRedactor([]RedactMode{RedactPaths})
+	redacted := redactor.RedactResult(result)
+
+	location := redacted.Matches[0].Location
+	if strings.Contains(location, "alice") || strings.Contains(location, "acme-billing") {
+		t.Errorf("expected path segments to be redacted, got %q", location)
+	}
+	if !strings.HasSuffix(location, "package.json") {
+		t.Errorf("expected file name to be preserved, got %q", location)
+	}
+	if result.Matches[0].Location != "/home/alice/acme-billing/package.json" {
+		t.Error("expected original result to be unchanged")
+	}
+	if got := Unredact(location, redactor.Mapping()); got != "/home/alice/acme-billing/package.json" {
+		t.Errorf("expected mapping to restore original path, got %q", got)
+	}
+
+	result.Matches[0].Evidence = []Evidence{{File: "/home/alice/acme-billing/package.json", Line: 4}}
+	redacted = redactor.RedactResult(result)
+	if evidence := redacted.Matches[0].Evidence[0]; evidence.File != location || evidence.Line != 4 {
+		t.Errorf("expected evidence file to be redacted like the location, got %+v", evidence)
+	}
+	if result.Matches[0].Evidence[0].File != "/home/alice/acme-billing/package.json" {
+		t.Error("expected original evidence to be unchanged")
+	}
+
+	result.Warnings = []FileError{{Path: "/home/alice/acme-billing/package.json", Error: "open /home/alice/acme-billing/package.json: permission denied"}}
+	redacted = redactor.RedactResult(result)
+	if warning := redacted.Warnings[0]; warning.Path != location || strings.Contains(warning.Error, "alice") {
+		t.Errorf("expected warning path and error to be redacted like the location, got %+v", warning)
+	}
+}
+
+func TestRedactor_ProjectNames(t *testing.T) {
+	redactor := NewRedactor([]RedactMode{RedactProjectNames})
+	location := redactor.RedactPath("/srv/repos/acme-billing/yarn.lock")
+
+	if !strings.HasPrefix(location, "/srv/repos/redacted-") {
+		t.Errorf("expected only the project directory to be redacted, got %q", location)
+	}
+	if strings.Contains(location, "acme-billing") {
+		t.Errorf("expected project name to be redacted, got %q", location)
+	}
+	if redactor.RedactPath("/srv/repos/acme-billing/yarn.lock") != location {
+		t.Error("expected redaction tokens to be stable")
+	}
+}
+
+// TestRedactor_Keyed tests that tokens are keyed per Redactor, so a guessed
+// name cannot be hashed into its token
+func TestRedactor_Keyed(t *testing.T) {
+	first := NewRedactor([]RedactMode{RedactPaths})
+	second := NewRedactor([]RedactMode{RedactPaths})
+
+	token := first.RedactDir("tmp")
+	sum := sha256.Sum256([]byte("tmp"))
+	if token == redactTokenPrefix+hex.EncodeToString(sum[:])[:12] {
+		t.Errorf("token %q is the plain hash of the value", token)
+	}
+	if first.RedactDir("tmp") != token {
+		t.Error("expected tokens of a Redactor to be stable")
+	}
+	if second.RedactDir("tmp") == token {
+		t.Error("expected Redactors to use different keys")
+	}
+	if got := first.Mapping()[token]; got != "tmp" {
+		t.Errorf("Mapping()[%s] = %q, want tmp", token, got)
+	}
+}
+
+// TestRedactor_MetadataAndChains tests redaction of the git metadata and
+// of dependency chains
+func TestRedactor_MetadataAndChains(t *testing.T) {
+	chain := []string{"@acme/secret-lib@1.0.0", "middle@2.0.0", "evil@1.0.1"}
+	result := &ScanResult{
+		Metadata: map[string]string{
+			MetaGitRemote:   "git@github.com:acme/secret-proj.git",
+			MetaGitBranch:   "feature/secret-proj",
+			MetaGitCommit:   "0123abc",
+			MetaArchivePath: "/home/alice/images/app.tgz",
+			"build":         "42",
+		},
+		Matches: []Match{{PackageName: "evil", Version: "1.0.1", Severity: SeverityTransitive, Location: "/srv/secret-proj/package-lock.json",
+			Chain: chain, Evidence: []Evidence{{File: "/srv/secret-proj/package-lock.json", Chain: chain}}}},
+	}
+
+	redactor := NewRedactor([]RedactMode{RedactPaths, RedactProjectNames})
+	output, err := FormatJSON(redactor.RedactResult(result))
+	if err != nil {
+		t.Fatalf("FormatJSON failed: %v", err)
+	}
+	for _, secret := range []string{"secret-proj", "github.com", "alice", "secret-lib", "middle"} {
+		if strings.Contains(output, secret) {
+			t.Errorf("expected %q to be redacted:\n%s", secret, output)
+		}
+	}
+	for _, kept := range []string{`"git.commit": "0123abc"`, `"build": "42"`, "evil@1.0.1", "app.tgz"} {
+		if !strings.Contains(output, kept) {
+			t.Errorf("expected %q to be kept:\n%s", kept, output)
+		}
+	}
+	if result.Metadata[MetaGitRemote] != "git@github.com:acme/secret-proj.git" || result.Matches[0].Chain[0] != chain[0] {
+		t.Error("expected original result to be unchanged")
+	}
+	if got := Unredact(output, redactor.Mapping()); !strings.Contains(got, "git@github.com:acme/secret-proj.git") || !strings.Contains(got, "@acme/secret-lib@1.0.0") {
+		t.Errorf("expected mapping to restore the remote and chain:\n%s", got)
+	}
+}
+
+func TestParseRedactModes(t *testing.T) {
+	modes, err := ParseRedactModes("paths, projectnames")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(modes) != 2 {
+		t.Errorf("expected 2 modes, got %d", len(modes))
+	}
+
+	if _, err := ParseRedactModes("emails"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+// TestParseMetadata tests parsing of --meta key=value pairs.
+func TestParseMetadata(t *testing.T) {
+	metadata, err := ParseMetadata([]string{"build=42", "url=https://ci.example.com/run?id=7", "build=43"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if metadata["build"] != "43" {
+		t.Errorf("expected later pair to win, got build=%q", metadata["build"])
+	}
+	if metadata["url"] != "https://ci.example.com/run?id=7" {
+		t.Errorf("expected value to keep '=', got %q", metadata["url"])
+	}
+
+	for _, pair := range []string{"novalue", "=value"} {
+		if _, err := ParseMetadata([]string{pair}); err == nil {
+			t.Errorf("expected error for %q", pair)
+		}
+	}
+
+	result := &ScanResult{Metadata: metadata}
+	output, err := FormatJSON(result)
+	if err != nil {
+		t.Fatalf("FormatJSON failed: %v", err)
+	}
+	if !strings.Contains(output, `"metadata"`) || !strings.Contains(output, `"build": "43"`) {
+		t.Errorf("expected metadata in JSON output, got:\n%s", output)
+	}
+}
+
+// TestMergeMetadata tests overlaying metadata maps.
+func TestMergeMetadata(t *testing.T) {
+	base := map[string]string{MetaGitBranch: "main", "build": "1"}
+	merged := MergeMetadata(base, map[string]string{"build": "2"})
+
+	if merged[MetaGitBranch] != "main" || merged["build"] != "2" {
+		t.Errorf("unexpected merge result: %v", merged)
+	}
+	if base["build"] != "1" {
+		t.Error("MergeMetadata modified its input")
+	}
+	if MergeMetadata(nil, nil) != nil {
+		t.Error("expected nil for empty inputs")
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	match := Match{PackageName: "chalk", Version: "5.6.1", Severity: SeverityTransitive, Location: "/ci/build-1/repo/package-lock.json"}
+
+	fp := Fingerprint(match, "/ci/build-1/repo")
+	if len(fp) != fingerprintLength {
+		t.Errorf("expected %d hex digits, got %q", fingerprintLength, fp)
+	}
+
+	// The same finding in another checkout has the same fingerprint
+	moved := match
+	moved.Location = "/home/dev/repo/./package-lock.json"
+	if Fingerprint(moved, "/home/dev/repo/") != fp {
+		t.Error("fingerprint depends on the checkout directory")
+	}
+
+	// Annotations don't affect the fingerprint
+	annotated := match
+	annotated.Chain = []string{"app@1.0.0", "chalk@5.6.1"}
+	if Fingerprint(annotated, "/ci/build-1/repo") != fp {
+		t.Error("fingerprint changed with match annotations")
+	}
+
+	other := match
+	other.Location = "/ci/build-1/repo/packages/web/package-lock.json"
+	if Fingerprint(other, "/ci/build-1/repo") == fp {
+		t.Error("fingerprint ignores location")
+	}
+
+	result := &ScanResult{
+		Matches:  []Match{match},
+		Projects: []ProjectResult{{Matches: []Match{match}}},
+	}
+	AssignFingerprints(result, "/ci/build-1/repo")
+	if result.Matches[0].Fingerprint != fp || result.Projects[0].Matches[0].Fingerprint != fp {
+		t.Errorf("AssignFingerprints did not set all fingerprints: %+v", result)
+	}
+}
+
+func TestNormalizeLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		root     string
+		want     string
+	}{
+		{"/repo/packages/a/package.json", "/repo", "packages/a/package.json"},
+		{"packages/a/package.json", ".", "packages/a/package.json"},
+		{"/sboms/app.cdx.json", "/sboms/app.cdx.json", "app.cdx.json"},
+		{"/elsewhere/package.json", "/repo", "/elsewhere/package.json"},
+		{"ghcr.io/org/app:1.0", "", "ghcr.io/org/app:1.0"},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeLocation(tt.location, tt.root); got != tt.want {
+			t.Errorf("NormalizeLocation(%q, %q) = %q, want %q", tt.location, tt.root, got, tt.want)
+		}
+	}
+}
+
+func TestFormatHuman_Remediation(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{
+				PackageName: "vulnerable-pkg",
+				Version:     "1.0.0",
+				Severity:    SeverityDirect,
+				Location:    "./package.json",
+				Fingerprint: "0123456789abcdef",
+				Remediation: &Remediation{
+					Status:    "in-progress",
+					Ticket:    "JIRA-123",
+					UpdatedAt: time.Date(2025, 11, 28, 0, 0, 0, 0, time.UTC),
+				},
+			},
+			{
+				PackageName: "other-pkg",
+				Version:     "2.0.0",
+				Severity:    SeverityTransitive,
+				Location:    "./package-lock.json",
+			},
+		},
+	}
+
+	output := FormatHuman(result)
+
+	for _, want := range []string{
+		"ID:\x1b[0m 0123456789abcdef",
+		"in-progress (JIRA-123), updated 2025-11-28",
+		"Triage: 1 in-progress, 1 untriaged",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output", want)
+		}
+	}
+}
+
 // Benchmark tests
 func BenchmarkFormatHuman(b *testing.B) {
 	result := &ScanResult{
@@ -447,3 +1086,499 @@ func BenchmarkFormatJSON(b *testing.B) {
 		FormatJSON(result)
 	}
 }
+
+// TestFails tests the --fail-on severity threshold
+func TestFails(t *testing.T) {
+	potentialOnly := &ScanResult{Matches: []Match{{PackageName: "a", Severity: SeverityPotential}}}
+	transitive := &ScanResult{Matches: []Match{
+		{PackageName: "a", Severity: SeverityPotential},
+		{PackageName: "b", Severity: SeverityTransitive},
+	}}
+	hygieneOnly := &ScanResult{Hygiene: []Match{{PackageName: "c", Severity: SeverityHygiene}}}
+	policyOnly := &ScanResult{PolicyViolations: []Match{{PackageName: "lodash", Version: "4.17.20", Severity: SeverityPolicy}}}
+	unapprovedOnly := &ScanResult{Unapproved: []Match{{PackageName: "left-pad", Version: "1.3.0", Severity: SeverityUnapproved}}}
+	scriptsOnly := &ScanResult{Scripts: []Match{{PackageName: "evil", Version: "1.0.1", Severity: SeverityScript, Script: "postinstall"}}}
+
+	tests := []struct {
+		failOn string
+		result *ScanResult
+		want   bool
+	}{
+		{"potential", potentialOnly, true},
+		{"transitive", potentialOnly, false},
+		{"transitive", transitive, true},
+		{"DIRECT", transitive, false},
+		{"none", transitive, false},
+		{"potential", hygieneOnly, false},
+		{"direct", policyOnly, true},
+		{"none", policyOnly, false},
+		{"potential", unapprovedOnly, true},
+		{"none", unapprovedOnly, false},
+		{"direct", scriptsOnly, true},
+		{"none", scriptsOnly, false},
+	}
+
+	for _, tt := range tests {
+		threshold, err := ParseFailOn(tt.failOn)
+		if err != nil {
+			t.Fatalf("ParseFailOn(%q) error = %v", tt.failOn, err)
+		}
+		if got := Fails(tt.result, threshold); got != tt.want {
+			t.Errorf("Fails(%d matches, %s) = %v, want %v", len(tt.result.Matches), tt.failOn, got, tt.want)
+		}
+	}
+
+	if _, err := ParseFailOn("hygiene"); err == nil {
+		t.Error("expected an error for an unknown threshold")
+	}
+}
+
+// TestFormatHuman_Suppressed tests the section of matches acknowledged in the suppression file
+func TestFormatHuman_Suppressed(t *testing.T) {
+	expires := time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	result := &ScanResult{
+		Suppressed: []Match{{
+			PackageName: "evil",
+			Version:     "1.0.1",
+			Severity:    SeverityDirect,
+			Location:    "package.json",
+			Suppression: &Suppression{Rule: "evil@1.0.1", Reason: "vendored", Expires: &expires},
+		}},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"NO VULNERABILITIES FOUND", "SUPPRESSED (1)", "evil@1.0.1 (DIRECT)", "vendored", "2026-12-31"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestParseTimezone tests resolving --timezone values
+func TestParseTimezone(t *testing.T) {
+	for _, name := range []string{"", "UTC"} {
+		if loc, err := ParseTimezone(name); err != nil || loc != time.UTC {
+			t.Errorf("ParseTimezone(%q) = %v, %v; want UTC", name, loc, err)
+		}
+	}
+	if loc, err := ParseTimezone("Local"); err != nil || loc != time.Local {
+		t.Errorf("ParseTimezone(Local) = %v, %v; want the system zone", loc, err)
+	}
+	if _, err := ParseTimezone("Mars/Olympus"); err == nil {
+		t.Error("ParseTimezone() should reject unknown zones")
+	}
+}
+
+// TestInLocation tests that timestamps render as RFC 3339 in the chosen zone
+func TestInLocation(t *testing.T) {
+	instant := time.Date(2025, 11, 24, 22, 30, 0, 0, time.UTC)
+	added := time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)
+	result := &ScanResult{
+		Timestamp: instant,
+		Matches: []Match{{
+			PackageName: "evil",
+			Version:     "1.0.1",
+			Severity:    SeverityTransitive,
+			IOCAdded:    &added,
+			Timeline:    &ExposureTimeline{IntroducedCommit: "abc", IntroducedAt: instant},
+		}},
+	}
+
+	if output := FormatHuman(result); !strings.Contains(output, "Timestamp:         2025-11-24T22:30:00.000Z\n") {
+		t.Errorf("expected a UTC timestamp in output:\n%s", output)
+	}
+
+	berlin := time.FixedZone("CET", 3600)
+	InLocation(result, berlin)
+
+	if output := FormatHuman(result); !strings.Contains(output, "Timestamp:         2025-11-24T23:30:00.000+01:00\n") {
+		t.Errorf("expected the timestamp with its offset in output:\n%s", output)
+	}
+	output, err := FormatJSON(result)
+	if err != nil {
+		t.Fatalf("FormatJSON() error = %v", err)
+	}
+	if !strings.Contains(output, `"timestamp": "2025-11-24T23:30:00+01:00"`) || !strings.Contains(output, `"introducedAt": "2025-11-24T23:30:00+01:00"`) {
+		t.Errorf("expected RFC 3339 timestamps with offsets in JSON:\n%s", output)
+	}
+	if !result.Timestamp.Equal(instant) || result.Matches[0].IOCAdded.Location() != time.UTC {
+		t.Errorf("InLocation() should keep instants and leave dates alone, got %v and %v", result.Timestamp, result.Matches[0].IOCAdded)
+	}
+}
+
+// TestNotScannedResult tests reporting a scan that could not run
+func TestNotScannedResult(t *testing.T) {
+	result := NotScannedResult(errors.New("failed to fetch IoC database: HTTP 503"))
+
+	output := FormatHuman(result)
+	if !strings.Contains(output, "NOT SCANNED — database unavailable") || !strings.Contains(output, "HTTP 503") {
+		t.Errorf("expected the not-scanned status in output:\n%s", output)
+	}
+	if strings.Contains(output, "NO VULNERABILITIES FOUND") {
+		t.Errorf("a result that was not scanned must not be reported clean:\n%s", output)
+	}
+	if verdict := Summarize(result).Verdict; verdict != VerdictNotScanned {
+		t.Errorf("Summarize() verdict = %q, want %q", verdict, VerdictNotScanned)
+	}
+
+	// Matches found in other roots still decide the verdict
+	result.Matches = []Match{{PackageName: "evil", Version: "1.0.1", Severity: SeverityDirect}}
+	if verdict := Summarize(result).Verdict; verdict != VerdictAffected {
+		t.Errorf("Summarize() verdict = %q, want %q", verdict, VerdictAffected)
+	}
+}
+
+// TestFormatChecks tests the output of package version lookups
+func TestFormatChecks(t *testing.T) {
+	checks := []PackageCheck{
+		{Package: "evil", Version: "1.0.1", Compromised: true, IOCRange: "< 2.0.0", Advisory: &Advisory{IDs: []string{"GHSA-aaaa-bbbb-cccc"}}},
+		{Package: "@scope/pkg", Version: "1.0.0"},
+	}
+
+	output := FormatHumanChecks(checks)
+	for _, want := range []string{"evil@1.0.1", "COMPROMISED", "< 2.0.0", "GHSA-aaaa-bbbb-cccc", "@scope/pkg@1.0.0", "not listed"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+
+	output, err := FormatJSONChecks(checks)
+	if err != nil {
+		t.Fatalf("FormatJSONChecks() error = %v", err)
+	}
+	if !strings.Contains(output, `"compromised": true`) || !strings.Contains(output, `"GHSA-aaaa-bbbb-cccc"`) {
+		t.Errorf("unexpected JSON output:\n%s", output)
+	}
+
+	output, err = FormatJSONChecks(nil)
+	if err != nil || output != "[]" {
+		t.Errorf("FormatJSONChecks(nil) = %q, %v, want []", output, err)
+	}
+}
+
+// TestFormatHuman_DependencyStats tests the dependency type breakdown in
+// the summary
+func TestFormatHuman_DependencyStats(t *testing.T) {
+	result := &ScanResult{
+		PackagesChecked: 9,
+		Matches:         []Match{},
+		DependencyStats: &DependencyStats{
+			Manifest: DependencyCounts{Prod: 3, Dev: 1},
+			Lockfile: DependencyCounts{Prod: 2, Optional: 1, Unknown: 2},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"3 prod, 1 dev, 0 peer, 0 optional", "2 prod, 0 dev, 0 peer, 1 optional, 2 unknown"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "SBOMs:") {
+		t.Errorf("expected no SBOM line without inventories:\n%s", output)
+	}
+}
+
+func TestFormatHuman_UncheckedBundled(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		UncheckedBundled: []Match{
+			{PackageName: "vendored-lib", Severity: SeverityUncheckedBundled, Location: "app/package.json", Reason: "bundled dependency not installed, version unknown"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"UNCHECKED BUNDLED DEPENDENCIES (1)", "1. vendored-lib", "app/package.json"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if Fails(result, SeverityPotential) {
+		t.Error("expected unchecked bundled dependencies not to fail the scan")
+	}
+}
+
+func TestFormatHuman_Shadowed(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Shadowed: []Match{
+			{PackageName: "chalk", Version: "5.6.1", Severity: SeverityShadowed, Location: "node_modules/chalk/package.json", Reason: "installed version differs from lockfile", LockedVersion: "5.6.0"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"SHADOWED DEPENDENCIES (1)", "1. chalk@5.6.1", "Locked:", "5.6.0"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_Popularity tests the popularity line of enriched matches
+func TestFormatHuman_Popularity(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "chalk", Version: "5.6.1", Severity: SeverityTransitive, Location: "package-lock.json",
+				Popularity: &Popularity{WeeklyDownloads: 299_400_000, Dependents: 1234, DirectDependents: 56}},
+			{PackageName: "obscure", Version: "0.0.1", Severity: SeverityDirect, Location: "package.json",
+				Popularity: &Popularity{WeeklyDownloads: 12}},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"299.4M weekly downloads, 1.2k dependents (56 direct)", "12 weekly downloads, 0 dependents (0 direct)"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_RegistryStatus tests the section of deprecated and unpublished direct dependencies
+func TestFormatHuman_RegistryStatus(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		RegistryStatus: []Match{
+			{PackageName: "request", Version: "2.88.2", Severity: SeverityDeprecated, Location: "package.json", DeclaredSpec: "^2.88.0", Reason: "deprecated: request has been deprecated"},
+			{PackageName: "evil", Version: "1.0.1", Severity: SeverityUnpublished, Location: "package.json", DeclaredSpec: "1.0.1", Reason: "version is no longer published in the registry"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"DEPRECATED OR UNPUBLISHED DEPENDENCIES (2)", "request@2.88.2", "[DEPRECATED]", "evil@1.0.1", "[UNPUBLISHED]", "request has been deprecated"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_Provenance tests the section of packages without sound provenance
+func TestFormatHuman_Provenance(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Provenance: []Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: SeverityProvenance, Location: "package-lock.json", Reason: "published without provenance"},
+			{PackageName: "moved", Version: "2.0.0", Severity: SeverityProvenance, Location: "package.json", DeclaredSpec: "^2.0.0", Reason: "provenance names repository https://github.com/other/moved, but the package declares github:owner/moved"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"PROVENANCE ISSUES (2)", "evil@1.0.1", "published without provenance", "moved@2.0.0", "Declared:", "github.com/other/moved"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_Maintainers tests the section of packages behind a flagged account
+func TestFormatHuman_Maintainers(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Maintainers: []Match{
+			{PackageName: "hijacked", Version: "1.0.0", Severity: SeverityMaintainer, Location: "package-lock.json", Reason: "published by flagged account mallory"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"FLAGGED MAINTAINERS (1)", "hijacked@1.0.0", "published by flagged account mallory"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_Scripts tests the section of scripts running a malicious
+// command, which also replaces the all-clear
+func TestFormatHuman_Scripts(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Scripts: []Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: SeverityScript, Location: "node_modules/evil/package.json", Script: "postinstall",
+				ScriptIndicator: "node bundle.js", Reason: `postinstall script runs "node bundle.js"`, Advisory: &Advisory{Campaign: "shai-hulud"}},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"MALICIOUS SCRIPTS (1)", "evil@1.0.1", "[postinstall]", `runs "node bundle.js"`, "IoC:", "shai-hulud"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "NO VULNERABILITIES FOUND") {
+		t.Errorf("expected no all-clear with malicious scripts:\n%s", output)
+	}
+	if got := Summarize(result); got.Scripts != 1 || got.Verdict != VerdictAffected {
+		t.Errorf("Summarize() = %+v, want 1 script and an affected verdict", got)
+	}
+}
+
+// TestFormatHuman_Override tests the override line of matches forced by overrides
+func TestFormatHuman_Override(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: SeverityDirect, Location: "package.json", Override: "overrides > safe > evil"},
+			{PackageName: "chalk", Version: "5.6.1", Severity: SeverityPotential, Location: "package.json", DeclaredSpec: "^5.6.0", Override: "resolutions > **/chalk"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"forced by overrides > safe > evil", "forced by resolutions > **/chalk"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_Warnings tests the count and section of files that could not be parsed
+func TestFormatHuman_Warnings(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Warnings: []FileError{
+			{Path: "app/package-lock.json", Error: "failed to parse npm lockfile: unexpected end of JSON input"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"Skipped Files:     1", "SKIPPED FILES (1)", "app/package-lock.json", "unexpected end of JSON input"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatHuman_PolicyViolations tests the section of packages below a required safe version
+func TestFormatHuman_PolicyViolations(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		PolicyViolations: []Match{
+			{PackageName: "lodash", Version: "4.17.20", Severity: SeverityPolicy, Location: "package-lock.json", Required: ">=4.17.21"},
+			{PackageName: "lodash", Version: "4.17.4", Severity: SeverityPolicy, Location: "package.json", DeclaredSpec: "4.17.4", Required: ">=4.17.21"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"POLICY VIOLATIONS (2)", "1. lodash@4.17.20", "Required:", ">=4.17.21", "2. lodash@4.17.4", "Declared:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if summary := Summarize(result); summary.PolicyViolations != 2 || summary.TotalMatches != 0 {
+		t.Errorf("Summarize() = %+v, want 2 policy violations and no matches", summary)
+	}
+}
+
+// TestFormatHuman_Unapproved tests the allowlist section of the human report
+func TestFormatHuman_Unapproved(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Unapproved: []Match{
+			{PackageName: "left-pad", Severity: SeverityUnapproved, Location: "package.json", DeclaredSpec: "^1.3.0"},
+			{PackageName: "left-pad", Version: "1.3.0", Severity: SeverityUnapproved, Location: "package-lock.json"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"UNAPPROVED PACKAGES (2)", "1. left-pad", "Declared:", "^1.3.0", "2. left-pad@1.3.0", "get the package approved"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if summary := Summarize(result); summary.Unapproved != 2 || summary.TotalMatches != 0 {
+		t.Errorf("Summarize() = %+v, want 2 unapproved packages and no matches", summary)
+	}
+}
+
+// TestFormatHuman_Watchlist tests the watchlist section of the human report
+func TestFormatHuman_Watchlist(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Watchlist: []Match{
+			{PackageName: "@mycorp-ui/button", Severity: SeverityWatchlist, Location: "package.json", DeclaredSpec: "^2.0.0", Reason: "name matches watchlist pattern @mycorp-*"},
+			{PackageName: "array-utils-js", Version: "1.0.0", Severity: SeverityWatchlist, Location: "package-lock.json", Reason: "name matches watchlist pattern *-utils-js"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"WATCHLIST PACKAGES (2)", "1. @mycorp-ui/button", "Declared:", "^2.0.0", "2. array-utils-js@1.0.0", "pattern *-utils-js"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if Fails(result, SeverityPotential) {
+		t.Error("watchlist findings must not fail the scan")
+	}
+}
+
+// TestFormatHuman_Typosquats tests the typosquat section of the human report
+func TestFormatHuman_Typosquats(t *testing.T) {
+	result := &ScanResult{
+		Matches: []Match{},
+		Typosquats: []Match{
+			{PackageName: "lodahs", Severity: SeverityWarning, Location: "package.json", DeclaredSpec: "^4.17.21", Resembles: "lodash", Reason: "name resembles popular package lodash (transposed characters)"},
+		},
+	}
+
+	output := FormatHuman(result)
+	for _, want := range []string{"POSSIBLE TYPOSQUATS (1)", "1. lodahs", "Declared:", "^4.17.21", "Resembles:", "transposed characters"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if Fails(result, SeverityPotential) {
+		t.Error("typosquat findings must not fail the scan")
+	}
+}
+
+// TestFormatHumanDiscovery tests the discovery statistics report
+func TestFormatHumanDiscovery(t *testing.T) {
+	stats := &DiscoveryStats{
+		Paths:               2,
+		Manifests:           12,
+		ManifestBytes:       3 * 1024,
+		Lockfiles:           4,
+		LockfileBytes:       5 * 1024 * 1024,
+		NodeModules:         3,
+		InstalledPackages:   812,
+		DiscoverySeconds:    0.25,
+		ParseBytesPerSecond: 20 * 1024 * 1024,
+		Estimates:           []ScanEstimate{{Workers: 1, Seconds: 0.5}, {Workers: 8, Seconds: 75.04}},
+	}
+
+	output := FormatHumanDiscovery(stats)
+	for _, want := range []string{
+		"Manifests:         12 files (3.0 KiB)",
+		"Lockfiles:         4 files (5.0 MiB)",
+		"3 trees (812 installed packages)",
+		"Discovery:         250ms",
+		"20.0 MiB/s per worker",
+		"  1 worker:  500ms",
+		"  8 workers: 1m15s",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected %q in output:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatAttestation tests the minimal build gate record
+func TestFormatAttestation(t *testing.T) {
+	result := &ScanResult{
+		Matches:   []Match{},
+		Timestamp: time.Date(2025, 11, 24, 22, 30, 0, 0, time.UTC),
+		IOCDigest: "sha256:abc",
+		Metadata:  map[string]string{MetaGitCommit: "0123abc", "build": "42"},
+	}
+
+	output, err := FormatAttestation(result, "1.2.3")
+	if err != nil {
+		t.Fatalf("FormatAttestation() error = %v", err)
+	}
+	want := `{"status":"clean","dbHash":"sha256:abc","commit":"0123abc","timestamp":"2025-11-24T22:30:00Z","version":"1.2.3"}`
+	if output != want {
+		t.Errorf("FormatAttestation() = %s, want %s", output, want)
+	}
+
+	result.Matches = []Match{{PackageName: "evil", Version: "1.0.1", Severity: SeverityTransitive}}
+	if attestation := NewAttestation(result, ""); attestation.Status != VerdictAffected {
+		t.Errorf("NewAttestation() status = %q, want %q", attestation.Status, VerdictAffected)
+	}
+}
@@ -0,0 +1,169 @@
+package formatter
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// grypeNamespace identifies npm-scan IoC findings among other grype data sources.
+const grypeNamespace = "npm-scan:ioc"
+
+// grypeDocument mirrors the top level of grype's JSON output.
+type grypeDocument struct {
+	Matches    []grypeMatch    `json:"matches"`
+	Descriptor grypeDescriptor `json:"descriptor"`
+}
+
+type grypeMatch struct {
+	Vulnerability grypeVulnerability `json:"vulnerability"`
+	MatchDetails  []grypeMatchDetail `json:"matchDetails"`
+	Artifact      grypeArtifact      `json:"artifact"`
+}
+
+type grypeVulnerability struct {
+	ID          string `json:"id"`
+	DataSource  string `json:"dataSource"`
+	Namespace   string `json:"namespace"`
+	Severity    string `json:"severity"`
+	Description string `json:"description"`
+}
+
+type grypeMatchDetail struct {
+	Type       string            `json:"type"`
+	Matcher    string            `json:"matcher"`
+	SearchedBy map[string]string `json:"searchedBy"`
+	Found      map[string]string `json:"found"`
+}
+
+type grypeArtifact struct {
+	Name      string          `json:"name"`
+	Version   string          `json:"version"`
+	Type      string          `json:"type"`
+	Language  string          `json:"language"`
+	Locations []grypeLocation `json:"locations"`
+	PURL      string          `json:"purl"`
+}
+
+type grypeLocation struct {
+	Path string `json:"path"`
+}
+
+type grypeDescriptor struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Timestamp string `json:"timestamp"`
+}
+
+// FormatGrypeJSON formats scan results in grype's JSON match format, so they
+// can be consumed by pipelines built around Anchore tooling.
+//
+// Matches whose IoC entry has an advisory use its first ID (e.g. a GHSA ID)
+// and URL; others get a synthetic "IOC-<package>@<version>" ID in the
+// npm-scan:ioc namespace.
+// DIRECT and TRANSITIVE matches are Critical; POTENTIAL matches are Medium.
+// toolVersion is reported in the descriptor.
+func FormatGrypeJSON(result *ScanResult, toolVersion string) (string, error) {
+	doc := grypeDocument{
+		Matches: []grypeMatch{},
+		Descriptor: grypeDescriptor{
+			Name:      "npm-scan",
+			Version:   toolVersion,
+			Timestamp: result.Timestamp.Format(TimestampLayout),
+		},
+	}
+
+	for _, match := range result.Matches {
+		doc.Matches = append(doc.Matches, grypeMatchFor(match))
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// grypeMatchFor converts a single match to grype's representation.
+func grypeMatchFor(match Match) grypeMatch {
+	severity := "Critical"
+	matchType := "exact-direct-match"
+	description := "Package version is listed in the IoC database of compromised packages"
+	switch match.Severity {
+	case SeverityTransitive:
+		matchType = "exact-indirect-match"
+	case SeverityPotential:
+		severity = "Medium"
+		matchType = "potential-match"
+		description = "Declared range " + match.DeclaredSpec + " could resolve to a compromised version"
+	}
+
+	id := "IOC-" + match.PackageName + "@" + match.Version
+	dataSource := "npm-scan"
+	if a := match.Advisory; a != nil {
+		if len(a.IDs) > 0 {
+			id = a.IDs[0]
+		}
+		if a.URL != "" {
+			dataSource = a.URL
+		}
+	}
+
+	constraint := "= " + match.Version
+	if match.IOCRange != "" {
+		constraint = match.IOCRange
+	}
+
+	found := map[string]string{
+		"vulnerabilityID":   id,
+		"versionConstraint": constraint,
+	}
+	if match.Fingerprint != "" {
+		found["fingerprint"] = match.Fingerprint
+	}
+
+	return grypeMatch{
+		Vulnerability: grypeVulnerability{
+			ID:          id,
+			DataSource:  dataSource,
+			Namespace:   grypeNamespace,
+			Severity:    severity,
+			Description: description,
+		},
+		MatchDetails: []grypeMatchDetail{{
+			Type:    matchType,
+			Matcher: "npm-scan-" + strings.ToLower(string(match.Severity)) + "-matcher",
+			SearchedBy: map[string]string{
+				"package": match.PackageName,
+				"version": match.Version,
+			},
+			Found: found,
+		}},
+		Artifact: grypeArtifact{
+			Name:      match.PackageName,
+			Version:   match.Version,
+			Type:      "npm",
+			Language:  "javascript",
+			Locations: grypeLocations(match),
+			PURL:      npmPurl(match.PackageName, match.Version),
+		},
+	}
+}
+
+// npmPurl builds the package URL for an npm package version, encoding the
+// scope's @ as required by the purl spec.
+func npmPurl(name, version string) string {
+	if strings.HasPrefix(name, "@") {
+		name = "%40" + name[1:]
+	}
+	return "pkg:npm/" + name + "@" + version
+}
+
+// grypeLocations returns the artifact locations of a match, one per file
+// it was found in.
+func grypeLocations(match Match) []grypeLocation {
+	var locations []grypeLocation
+	for _, file := range match.Files() {
+		locations = append(locations, grypeLocation{Path: file})
+	}
+	return locations
+}
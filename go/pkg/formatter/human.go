@@ -2,7 +2,10 @@ package formatter
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ANSI color codes
@@ -15,9 +18,22 @@ const (
 	colorBold   = "\x1b[1m"
 )
 
+// HumanOptions configures FormatHumanWith.
+type HumanOptions struct {
+	// Top limits each severity section to its N most significant matches
+	// (most occurrences first, exact IoC pins before ranges); the rest are
+	// summarized in a footer. Zero shows every match.
+	Top int
+}
+
 // FormatHuman formats scan results as human-readable text with box drawing characters.
 // Output matches the Node.js implementation style.
 func FormatHuman(result *ScanResult) string {
+	return FormatHumanWith(result, HumanOptions{})
+}
+
+// FormatHumanWith formats scan results like FormatHuman, with options.
+func FormatHumanWith(result *ScanResult, options HumanOptions) string {
 	var b strings.Builder
 
 	// Header
@@ -31,10 +47,26 @@ func FormatHuman(result *ScanResult) string {
 	b.WriteString(fmt.Sprintf("%sSCAN SUMMARY%s\n", colorBold, colorReset))
 	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
 	b.WriteString(fmt.Sprintf("IoC Database:      %d packages\n", result.IOCCount))
+	if result.IOCSnapshot != nil {
+		b.WriteString(fmt.Sprintf("%sIoC Snapshot:      %s (offline, may be stale)%s\n", colorYellow, result.IOCSnapshot.Format("2006-01-02"), colorReset))
+	}
 	b.WriteString(fmt.Sprintf("Manifests Scanned: %d files\n", result.ManifestsScanned))
 	b.WriteString(fmt.Sprintf("Lockfiles Scanned: %d files\n", result.LockfilesScanned))
+	if result.InventoriesScanned > 0 {
+		b.WriteString(fmt.Sprintf("SBOMs Scanned:     %d files\n", result.InventoriesScanned))
+	}
 	b.WriteString(fmt.Sprintf("Packages Checked:  %d\n", result.PackagesChecked))
-	b.WriteString(fmt.Sprintf("Timestamp:         %s\n", result.Timestamp.Format("2006-01-02T15:04:05.000Z")))
+	if stats := result.DependencyStats; stats != nil {
+		b.WriteString(formatDependencyCounts("  Manifests:", stats.Manifest))
+		b.WriteString(formatDependencyCounts("  Lockfiles:", stats.Lockfile))
+		if stats.Inventory != nil {
+			b.WriteString(formatDependencyCounts("  SBOMs:", *stats.Inventory))
+		}
+	}
+	if len(result.Warnings) > 0 {
+		b.WriteString(fmt.Sprintf("%sSkipped Files:     %d (could not be read or parsed)%s\n", colorYellow, len(result.Warnings), colorReset))
+	}
+	b.WriteString(fmt.Sprintf("Timestamp:         %s\n", result.Timestamp.Format(TimestampLayout)))
 	b.WriteString("\n")
 
 	// Categorize matches by severity
@@ -43,12 +75,20 @@ func FormatHuman(result *ScanResult) string {
 	potentialMatches := filterBySeverity(result.Matches, SeverityPotential)
 
 	// Results section
-	if len(result.Matches) == 0 {
+	if result.NotScanned != "" {
+		b.WriteString(fmt.Sprintf("%s%s⚠ NOT SCANNED — database unavailable%s\n", colorYellow, colorBold, colorReset))
+		b.WriteString(fmt.Sprintf("%s%s%s\n", colorGray, result.NotScanned, colorReset))
+		b.WriteString("\n")
+	}
+	if len(result.Matches) == 0 && len(result.Scripts) == 0 && result.NotScanned == "" {
 		b.WriteString(fmt.Sprintf("%s%s✓ NO VULNERABILITIES FOUND%s\n", colorGreen, colorBold, colorReset))
 		b.WriteString("\n")
 		b.WriteString(fmt.Sprintf("%sAll packages appear safe.%s\n", colorGreen, colorReset))
-	} else {
+	} else if len(result.Matches) > 0 {
 		b.WriteString(fmt.Sprintf("%s%s⚠ AFFECTED PACKAGES FOUND: %d%s\n", colorRed, colorBold, len(result.Matches), colorReset))
+		if triage := formatRemediationCounts(Summarize(result).ByRemediation); triage != "" {
+			b.WriteString(fmt.Sprintf("Triage: %s\n", triage))
+		}
 		b.WriteString("\n")
 
 		// Direct dependencies section
@@ -56,13 +96,40 @@ func FormatHuman(result *ScanResult) string {
 			b.WriteString(fmt.Sprintf("%s%sDIRECT DEPENDENCIES (%d)%s\n", colorRed, colorBold, len(directMatches), colorReset))
 			b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
 
-			for i, match := range directMatches {
+			shown, hidden := topMatches(directMatches, options.Top)
+			for i, match := range shown {
 				b.WriteString("\n")
 				b.WriteString(fmt.Sprintf("%s%d. %s@%s%s\n", colorRed, i+1, match.PackageName, match.Version, colorReset))
-				b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, match.Location))
-				b.WriteString(fmt.Sprintf("   %sStatus:%s Exact version pin matches IoC\n", colorRed, colorReset))
+				b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, locationOf(match)))
+				b.WriteString(formatOccurrences(match))
+				if match.Alias != "" {
+					b.WriteString(fmt.Sprintf("   %sAlias:%s declared as %s\n", colorGray, colorReset, match.Alias))
+				}
+				if match.Override != "" {
+					b.WriteString(fmt.Sprintf("   %sOverride:%s forced by %s\n", colorGray, colorReset, match.Override))
+				}
+				if match.IOCRange != "" {
+					b.WriteString(fmt.Sprintf("   %sIoC Range:%s %s\n", colorGray, colorReset, match.IOCRange))
+				}
+				if match.IOCScope != "" {
+					b.WriteString(fmt.Sprintf("   %sIoC Scope:%s %s (compromised scope)\n", colorGray, colorReset, match.IOCScope))
+				}
+				b.WriteString(formatAdvisory(match))
+				b.WriteString(formatPopularity(match))
+				if match.IOCAdded != nil {
+					b.WriteString(fmt.Sprintf("   %sIoC Added:%s %s\n", colorGray, colorReset, match.IOCAdded.Format("2006-01-02")))
+				}
+				if match.IOCRange != "" {
+					b.WriteString(fmt.Sprintf("   %sStatus:%s Exact version pin falls in an affected IoC range\n", colorRed, colorReset))
+				} else if match.IOCScope != "" {
+					b.WriteString(fmt.Sprintf("   %sStatus:%s Exact version pin is under a compromised scope\n", colorRed, colorReset))
+				} else {
+					b.WriteString(fmt.Sprintf("   %sStatus:%s Exact version pin matches IoC\n", colorRed, colorReset))
+				}
 				b.WriteString(fmt.Sprintf("   %sAction:%s Remove or update to a safe version immediately\n", colorYellow, colorReset))
+				b.WriteString(formatTriage(match))
 			}
+			b.WriteString(formatHidden(hidden))
 
 			b.WriteString("\n")
 		}
@@ -72,12 +139,42 @@ func FormatHuman(result *ScanResult) string {
 			b.WriteString(fmt.Sprintf("%s%sTRANSITIVE DEPENDENCIES (%d)%s\n", colorRed, colorBold, len(transitiveMatches), colorReset))
 			b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
 
-			for i, match := range transitiveMatches {
+			shown, hidden := topMatches(transitiveMatches, options.Top)
+			for i, match := range shown {
 				b.WriteString("\n")
 				b.WriteString(fmt.Sprintf("%s%d. %s@%s%s\n", colorRed, i+1, match.PackageName, match.Version, colorReset))
-				b.WriteString(fmt.Sprintf("   %sResolved:%s %s\n", colorGray, colorReset, match.Location))
+				b.WriteString(fmt.Sprintf("   %sResolved:%s %s\n", colorGray, colorReset, locationOf(match)))
+				b.WriteString(formatOccurrences(match))
+				if len(match.Chain) > 1 {
+					b.WriteString(fmt.Sprintf("   %sVia:%s %s\n", colorGray, colorReset, strings.Join(match.Chain, " → ")))
+				}
+				if match.Integrity != "" {
+					b.WriteString(fmt.Sprintf("   %sIntegrity:%s %s (known-malicious tarball hash)\n", colorRed, colorReset, match.Integrity))
+				}
+				if match.IOCRange != "" {
+					b.WriteString(fmt.Sprintf("   %sIoC Range:%s %s\n", colorGray, colorReset, match.IOCRange))
+				}
+				if match.IOCScope != "" {
+					b.WriteString(fmt.Sprintf("   %sIoC Scope:%s %s (compromised scope)\n", colorGray, colorReset, match.IOCScope))
+				}
+				b.WriteString(formatAdvisory(match))
+				b.WriteString(formatPopularity(match))
+				if match.IOCAdded != nil {
+					b.WriteString(fmt.Sprintf("   %sIoC Added:%s %s\n", colorGray, colorReset, match.IOCAdded.Format("2006-01-02")))
+				}
+				if match.Timeline != nil {
+					b.WriteString(fmt.Sprintf("   %sIntroduced:%s %s (%s)\n", colorGray, colorReset, match.Timeline.IntroducedAt.Format("2006-01-02"), shortCommit(match.Timeline.IntroducedCommit)))
+					if match.Timeline.RemovedAt != nil {
+						b.WriteString(fmt.Sprintf("   %sRemoved:%s %s (%s)\n", colorGray, colorReset, match.Timeline.RemovedAt.Format("2006-01-02"), shortCommit(match.Timeline.RemovedCommit)))
+					}
+					b.WriteString(fmt.Sprintf("   %sExposure:%s %d days\n", colorRed, colorReset, match.ExposureDays))
+				} else if match.ExposedSince != nil {
+					b.WriteString(fmt.Sprintf("   %sExposure:%s at least %d days (lockfile unchanged since %s)\n", colorRed, colorReset, match.ExposureDays, match.ExposedSince.Format("2006-01-02")))
+				}
 				b.WriteString(fmt.Sprintf("   %sAction:%s Update parent packages to versions that don't depend on this package\n", colorYellow, colorReset))
+				b.WriteString(formatTriage(match))
 			}
+			b.WriteString(formatHidden(hidden))
 
 			b.WriteString("\n")
 		}
@@ -87,24 +184,726 @@ func FormatHuman(result *ScanResult) string {
 			b.WriteString(fmt.Sprintf("%s%sPOTENTIAL MATCHES (%d)%s\n", colorYellow, colorBold, len(potentialMatches), colorReset))
 			b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
 
-			for i, match := range potentialMatches {
+			shown, hidden := topMatches(potentialMatches, options.Top)
+			for i, match := range shown {
 				b.WriteString("\n")
 				b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorYellow, i+1, match.PackageName, colorReset))
-				b.WriteString(fmt.Sprintf("   %sDeclared:%s %s (%s)\n", colorGray, colorReset, match.Location, match.DeclaredSpec))
-				b.WriteString(fmt.Sprintf("   %sIoC Version:%s %s\n", colorGray, colorReset, match.Version))
-				b.WriteString(fmt.Sprintf("   %sStatus:%s Range could resolve to affected version\n", colorYellow, colorReset))
+				b.WriteString(fmt.Sprintf("   %sDeclared:%s %s (%s)\n", colorGray, colorReset, locationOf(match), match.DeclaredSpec))
+				b.WriteString(formatOccurrences(match))
+				if match.Alias != "" {
+					b.WriteString(fmt.Sprintf("   %sAlias:%s declared as %s\n", colorGray, colorReset, match.Alias))
+				}
+				if match.Override != "" {
+					b.WriteString(fmt.Sprintf("   %sOverride:%s forced by %s\n", colorGray, colorReset, match.Override))
+				}
+				if match.IOCScope != "" {
+					b.WriteString(fmt.Sprintf("   %sIoC Scope:%s %s (every version compromised)\n", colorGray, colorReset, match.IOCScope))
+				} else {
+					b.WriteString(fmt.Sprintf("   %sIoC Version:%s %s\n", colorGray, colorReset, match.Version))
+				}
+				b.WriteString(formatAdvisory(match))
+				b.WriteString(formatPopularity(match))
+				if match.IOCScope != "" {
+					b.WriteString(fmt.Sprintf("   %sStatus:%s Package is under a compromised scope; any version it resolves to is affected\n", colorYellow, colorReset))
+				} else {
+					b.WriteString(fmt.Sprintf("   %sStatus:%s Range could resolve to affected version\n", colorYellow, colorReset))
+				}
 				b.WriteString(fmt.Sprintf("   %sAction:%s Check lockfile to verify resolved version, update if affected\n", colorYellow, colorReset))
+				b.WriteString(formatTriage(match))
 			}
+			b.WriteString(formatHidden(hidden))
 
 			b.WriteString("\n")
 		}
 	}
 
+	// Scripts running a known-malicious command
+	if len(result.Scripts) > 0 {
+		b.WriteString(formatScripts(result.Scripts))
+	}
+
+	// Matches acknowledged in the suppression file
+	if len(result.Suppressed) > 0 {
+		if len(result.Matches) == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(formatSuppressed(result.Suppressed))
+	}
+
+	// Packages below a required safe version
+	if len(result.PolicyViolations) > 0 {
+		if len(result.Matches) == 0 && len(result.Suppressed) == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(formatPolicyViolations(result.PolicyViolations))
+	}
+
+	// Packages missing from the allowlist
+	if len(result.Unapproved) > 0 {
+		if len(result.Matches) == 0 && len(result.Suppressed) == 0 && len(result.PolicyViolations) == 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(formatUnapproved(result.Unapproved))
+	}
+
+	// Hygiene audit findings
+	if len(result.Hygiene) > 0 {
+		b.WriteString(formatHygiene(result.Hygiene))
+	}
+
+	// Bundled dependencies that could not be checked
+	if len(result.UncheckedBundled) > 0 {
+		b.WriteString(formatUncheckedBundled(result.UncheckedBundled))
+	}
+
+	// Installed packages the lockfile does not describe
+	if len(result.Shadowed) > 0 {
+		b.WriteString(formatShadowed(result.Shadowed))
+	}
+
+	// Packages named like a watchlist pattern
+	if len(result.Watchlist) > 0 {
+		b.WriteString(formatWatchlist(result.Watchlist))
+	}
+
+	// Packages named like a popular one
+	if len(result.Typosquats) > 0 {
+		b.WriteString(formatTyposquats(result.Typosquats))
+	}
+
+	// Direct dependencies deprecated or unpublished in the registry
+	if len(result.RegistryStatus) > 0 {
+		b.WriteString(formatRegistryStatus(result.RegistryStatus))
+	}
+
+	if len(result.Provenance) > 0 {
+		b.WriteString(formatProvenance(result.Provenance))
+	}
+
+	// Packages behind a flagged npm account
+	if len(result.Maintainers) > 0 {
+		b.WriteString(formatMaintainers(result.Maintainers))
+	}
+
+	// Files whose dependencies were not checked
+	if len(result.Warnings) > 0 {
+		b.WriteString(formatWarnings(result.Warnings))
+	}
+
+	// Lockfile staleness
+	if len(result.LockfileAges) > 0 {
+		b.WriteString(formatLockfileAges(result.LockfileAges))
+	}
+
+	// Per-project breakdown
+	if len(result.Projects) > 0 {
+		b.WriteString(formatProjects(result.Projects))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// locationOf returns the location of a match, with the line of its first
+// occurrence when known.
+func locationOf(match Match) string {
+	if len(match.Evidence) > 0 && match.Evidence[0].File == match.Location && match.Evidence[0].Line > 0 {
+		return fmt.Sprintf("%s:%d", match.Location, match.Evidence[0].Line)
+	}
+	return match.Location
+}
+
+// formatOccurrences renders the occurrences of a match beyond its first,
+// which deduplication merged into its evidence.
+func formatOccurrences(match Match) string {
+	if len(match.Evidence) < 2 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, evidence := range match.Evidence[1:] {
+		location := evidence.File
+		if evidence.Line > 0 {
+			location = fmt.Sprintf("%s:%d", evidence.File, evidence.Line)
+		}
+		if evidence.DeclaredSpec != "" {
+			location += " (" + evidence.DeclaredSpec + ")"
+		}
+		b.WriteString(fmt.Sprintf("   %sAlso in:%s %s\n", colorGray, colorReset, location))
+	}
+	return b.String()
+}
+
+// formatDependencyCounts renders a summary line breaking the dependencies
+// read from one kind of file down by type, or nothing if there were none.
+func formatDependencyCounts(label string, counts DependencyCounts) string {
+	if counts.Total() == 0 {
+		return ""
+	}
+	parts := []string{
+		fmt.Sprintf("%d prod", counts.Prod),
+		fmt.Sprintf("%d dev", counts.Dev),
+		fmt.Sprintf("%d peer", counts.Peer),
+		fmt.Sprintf("%d optional", counts.Optional),
+	}
+	if counts.Bundled > 0 {
+		parts = append(parts, fmt.Sprintf("%d bundled", counts.Bundled))
+	}
+	if counts.Unknown > 0 {
+		parts = append(parts, fmt.Sprintf("%d unknown", counts.Unknown))
+	}
+	return fmt.Sprintf("%s%-19s%s%s\n", colorGray, label, strings.Join(parts, ", "), colorReset)
+}
+
+// formatAdvisory renders the IoC sources of a match, when several reported
+// it, and the advisory IDs, link and campaign of its entry, when the source
+// provided them.
+func formatAdvisory(match Match) string {
+	var b strings.Builder
+	if len(match.Sources) > 1 {
+		names := make([]string, len(match.Sources))
+		for i, source := range match.Sources {
+			names[i] = source.Source
+		}
+		b.WriteString(fmt.Sprintf("   %sSources:%s %s\n", colorGray, colorReset, strings.Join(names, ", ")))
+	}
+
+	a := match.Advisory
+	if a == nil {
+		return b.String()
+	}
+
+	if len(a.IDs) > 0 || a.URL != "" {
+		advisory := strings.Join(a.IDs, ", ")
+		switch {
+		case advisory == "":
+			advisory = a.URL
+		case a.URL != "":
+			advisory += " (" + a.URL + ")"
+		}
+		b.WriteString(fmt.Sprintf("   %sAdvisory:%s %s\n", colorGray, colorReset, advisory))
+	}
+	if a.Campaign != "" {
+		b.WriteString(fmt.Sprintf("   %sCampaign:%s %s\n", colorGray, colorReset, a.Campaign))
+	}
+	if a.Severity != "" {
+		b.WriteString(fmt.Sprintf("   %sSeverity:%s %s\n", colorGray, colorReset, a.Severity))
+	}
+	return b.String()
+}
+
+// formatPopularity renders how widely a match's package is used, when
+// popularity enrichment is enabled.
+func formatPopularity(match Match) string {
+	p := match.Popularity
+	if p == nil {
+		return ""
+	}
+	return fmt.Sprintf("   %sPopularity:%s %s weekly downloads, %s dependents (%s direct)\n",
+		colorGray, colorReset, compactCount(p.WeeklyDownloads), compactCount(int64(p.Dependents)), compactCount(int64(p.DirectDependents)))
+}
+
+// compactCount renders n for reading at a glance, such as 20.1M or 4.5k.
+func compactCount(n int64) string {
+	switch {
+	case n >= 1_000_000_000:
+		return strconv.FormatFloat(float64(n)/1e9, 'f', 1, 64) + "B"
+	case n >= 1_000_000:
+		return strconv.FormatFloat(float64(n)/1e6, 'f', 1, 64) + "M"
+	case n >= 1_000:
+		return strconv.FormatFloat(float64(n)/1e3, 'f', 1, 64) + "k"
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+// formatTriage renders a match's fingerprint, for use with "npm-scan ack",
+// and its recorded remediation state.
+func formatTriage(match Match) string {
+	var b strings.Builder
+	if match.Fingerprint != "" {
+		b.WriteString(fmt.Sprintf("   %sID:%s %s\n", colorGray, colorReset, match.Fingerprint))
+	}
+	if r := match.Remediation; r != nil {
+		triage := r.Status
+		if r.Ticket != "" {
+			triage += " (" + r.Ticket + ")"
+		}
+		if r.Note != "" {
+			triage += " - " + r.Note
+		}
+		b.WriteString(fmt.Sprintf("   %sTriage:%s %s, updated %s\n", colorGreen, colorReset, triage, r.UpdatedAt.Format("2006-01-02")))
+	}
+	return b.String()
+}
+
+// formatRemediationCounts renders remediation status counts, e.g.
+// "2 in-progress, 1 untriaged". Returns an empty string if nothing was triaged.
+func formatRemediationCounts(counts map[string]int) string {
+	statuses := make([]string, 0, len(counts))
+	for status := range counts {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	parts := make([]string, len(statuses))
+	for i, status := range statuses {
+		parts[i] = fmt.Sprintf("%d %s", counts[status], status)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatSuppressed renders matches acknowledged in the suppression file,
+// with the rule that covers each.
+func formatSuppressed(matches []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sSUPPRESSED (%d)%s\n", colorGray, colorBold, len(matches), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for i, match := range matches {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s@%s (%s)%s\n", colorGray, i+1, match.PackageName, match.Version, match.Severity, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, locationOf(match)))
+		b.WriteString(formatOccurrences(match))
+		if s := match.Suppression; s != nil {
+			if s.Reason != "" {
+				b.WriteString(fmt.Sprintf("   %sReason:%s %s\n", colorGray, colorReset, s.Reason))
+			}
+			if s.Expires != nil {
+				b.WriteString(fmt.Sprintf("   %sExpires:%s %s\n", colorGray, colorReset, s.Expires.Format("2006-01-02")))
+			}
+		}
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatPolicyViolations renders the packages whose version does not
+// satisfy a required safe version range.
+func formatPolicyViolations(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sPOLICY VIOLATIONS (%d)%s\n", colorRed, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		label := finding.PackageName
+		if finding.Version != "" {
+			label += "@" + finding.Version
+		}
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorRed, i+1, label, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		b.WriteString(fmt.Sprintf("   %sRequired:%s %s\n", colorRed, colorReset, finding.Required))
+		b.WriteString(fmt.Sprintf("   %sAction:%s Update to a version satisfying the requirement\n", colorYellow, colorReset))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatScripts renders the package.json scripts running a command the
+// IoC database lists as malicious.
+func formatScripts(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sMALICIOUS SCRIPTS (%d)%s\n", colorRed, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		label := finding.PackageName
+		if finding.Version != "" {
+			label += "@" + finding.Version
+		}
+		if label == "" {
+			label = finding.Location
+		}
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s %s[%s]%s\n", colorRed, i+1, label, colorReset, colorGray, finding.Script, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorRed, colorReset, finding.Reason))
+		b.WriteString(fmt.Sprintf("   %sIoC:%s %s\n", colorGray, colorReset, finding.ScriptIndicator))
+		b.WriteString(formatAdvisory(finding))
+		b.WriteString(fmt.Sprintf("   %sAction:%s Treat the machine that ran it as compromised; rotate credentials and reinstall clean versions\n", colorYellow, colorReset))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatUnapproved renders the packages that are not on the allowlist.
+func formatUnapproved(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sUNAPPROVED PACKAGES (%d)%s\n", colorRed, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		label := finding.PackageName
+		if finding.Version != "" {
+			label += "@" + finding.Version
+		}
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorRed, i+1, label, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		b.WriteString(fmt.Sprintf("   %sAction:%s Remove the dependency or get the package approved\n", colorYellow, colorReset))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatHygiene renders the unpinned-dependency audit section.
+func formatHygiene(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sHYGIENE FINDINGS (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	// Count findings per reason, preserving first-seen order
+	var reasons []string
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		if counts[finding.Reason] == 0 {
+			reasons = append(reasons, finding.Reason)
+		}
+		counts[finding.Reason]++
+	}
+	for _, reason := range reasons {
+		b.WriteString(fmt.Sprintf("%s%-26s%s %d\n", colorGray, reason+":", colorReset, counts[reason]))
+	}
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("%s%d. %s (%s)%s\n", colorYellow, i+1, finding.PackageName, finding.DeclaredSpec, colorReset))
+		} else {
+			b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorYellow, i+1, finding.PackageName, colorReset))
+		}
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatShadowed renders the installed packages whose version the lockfile
+// does not resolve.
+func formatShadowed(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sSHADOWED DEPENDENCIES (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%snode_modules does not match the lockfile; reinstall (npm ci) if unexpected.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s@%s%s\n", colorYellow, i+1, finding.PackageName, finding.Version, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.LockedVersion != "" {
+			b.WriteString(fmt.Sprintf("   %sLocked:%s %s\n", colorGray, colorReset, finding.LockedVersion))
+		}
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatWatchlist renders the packages whose name matches a watchlist
+// pattern.
+func formatWatchlist(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sWATCHLIST PACKAGES (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sNot known to be compromised; verify each package is legitimate.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		label := finding.PackageName
+		if finding.Version != "" {
+			label += "@" + finding.Version
+		}
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorYellow, i+1, label, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		if finding.Alias != "" {
+			b.WriteString(fmt.Sprintf("   %sAlias:%s %s\n", colorGray, colorReset, finding.Alias))
+		}
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatTyposquats renders the dependencies whose name resembles a popular
+// package.
+func formatTyposquats(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sPOSSIBLE TYPOSQUATS (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sNames one typo away from a popular package; check each is the package you meant to install.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorYellow, i+1, finding.PackageName, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		if finding.Alias != "" {
+			b.WriteString(fmt.Sprintf("   %sAlias:%s %s\n", colorGray, colorReset, finding.Alias))
+		}
+		b.WriteString(fmt.Sprintf("   %sResembles:%s %s\n", colorGray, colorReset, finding.Resembles))
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatRegistryStatus renders the direct dependencies whose version is
+// deprecated or was unpublished from the registry.
+func formatRegistryStatus(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sDEPRECATED OR UNPUBLISHED DEPENDENCIES (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sMaintainers deprecate, and the registry unpublishes, compromised releases; check why.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		label := finding.PackageName
+		if finding.Version != "" {
+			label += "@" + finding.Version
+		}
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s %s[%s]%s\n", colorYellow, i+1, label, colorReset, colorGray, finding.Severity, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		if finding.Alias != "" {
+			b.WriteString(fmt.Sprintf("   %sAlias:%s %s\n", colorGray, colorReset, finding.Alias))
+		}
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatProvenance renders the package versions whose provenance is
+// missing, does not verify or names an unexpected repository.
+func formatProvenance(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sPROVENANCE ISSUES (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sReleases published from stolen tokens lack the provenance of CI-built ones; check who published them.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s@%s%s\n", colorYellow, i+1, finding.PackageName, finding.Version, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		if finding.DeclaredSpec != "" {
+			b.WriteString(fmt.Sprintf("   %sDeclared:%s %s\n", colorGray, colorReset, finding.DeclaredSpec))
+		}
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatMaintainers renders the package versions published or maintained
+// by a flagged npm account.
+func formatMaintainers(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sFLAGGED MAINTAINERS (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sCompromised accounts can publish any of their packages; check these releases.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s@%s%s\n", colorYellow, i+1, finding.PackageName, finding.Version, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatUncheckedBundled renders the bundled dependencies that could not be
+// checked because they are not installed.
+func formatUncheckedBundled(findings []Match) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sUNCHECKED BUNDLED DEPENDENCIES (%d)%s\n", colorYellow, colorBold, len(findings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sInstall dependencies (npm ci) and scan again to check them.%s\n", colorGray, colorReset))
+
+	for i, finding := range findings {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", colorYellow, i+1, finding.PackageName, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLocation:%s %s\n", colorGray, colorReset, finding.Location))
+		b.WriteString(fmt.Sprintf("   %sIssue:%s %s\n", colorYellow, colorReset, finding.Reason))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatWarnings renders the files that could not be read or parsed.
+func formatWarnings(warnings []FileError) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%s%sSKIPPED FILES (%d)%s\n", colorYellow, colorBold, len(warnings), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%sTheir dependencies were not checked; fix or remove them and scan again.%s\n", colorGray, colorReset))
+
+	for _, warning := range warnings {
+		b.WriteString(fmt.Sprintf("%s%s%s\n", colorYellow, warning.Path, colorReset))
+		b.WriteString(fmt.Sprintf("   %sError:%s %s\n", colorGray, colorReset, warning.Error))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatLockfileAges renders lockfile ages, flagging stale lockfiles.
+func formatLockfileAges(ages []LockfileAge) string {
+	var b strings.Builder
+
+	stale := 0
+	for _, age := range ages {
+		if age.Stale {
+			stale++
+		}
+	}
+
+	b.WriteString(fmt.Sprintf("%sLOCKFILE AGE (%d stale)%s\n", colorBold, stale, colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for _, age := range ages {
+		color := colorGreen
+		if age.Stale {
+			color = colorYellow
+		}
+		b.WriteString(fmt.Sprintf("%s%s%s\n", color, age.Path, colorReset))
+		b.WriteString(fmt.Sprintf("   %sLast modified:%s %s (%d days ago, from %s)\n", colorGray, colorReset, age.LastModified.Format("2006-01-02"), age.AgeDays, age.Source))
+		if age.Stale {
+			b.WriteString(fmt.Sprintf("   %sWarning:%s Predates the IoC campaign window; a clean result may give false comfort\n", colorYellow, colorReset))
+		}
+	}
+
 	b.WriteString("\n")
 
 	return b.String()
 }
 
+// formatProjects renders the per-project summary section.
+func formatProjects(projects []ProjectResult) string {
+	var b strings.Builder
+
+	b.WriteString(fmt.Sprintf("%sPROJECTS (%d)%s\n", colorBold, len(projects), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	for _, project := range projects {
+		b.WriteString("\n")
+		label := project.Path
+		if project.Name != "" {
+			label = fmt.Sprintf("%s (%s)", project.Name, project.Path)
+		}
+
+		color := colorGreen
+		if len(project.Matches) > 0 {
+			color = colorRed
+		}
+		b.WriteString(fmt.Sprintf("%s%s%s\n", color, label, colorReset))
+		b.WriteString(fmt.Sprintf("   %sFiles:%s %d manifests, %d lockfiles\n", colorGray, colorReset, project.ManifestsScanned, project.LockfilesScanned))
+		b.WriteString(fmt.Sprintf("   %sPackages:%s %d\n", colorGray, colorReset, project.PackagesChecked))
+		b.WriteString(fmt.Sprintf("   %sMatches:%s %d\n", colorGray, colorReset, len(project.Matches)))
+		for _, match := range project.Matches {
+			b.WriteString(fmt.Sprintf("     - %s@%s [%s]\n", match.PackageName, match.Version, match.Severity))
+		}
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// shortCommit abbreviates a commit hash for display.
+func shortCommit(hash string) string {
+	if len(hash) > 12 {
+		return hash[:12]
+	}
+	return hash
+}
+
+// topMatches returns the top most significant matches of one severity and
+// the number left out. Matches found in more files come first, then exact
+// IoC pins before range matches, then by package and version. With top zero
+// or at least len(matches), every match is returned in its original order.
+func topMatches(matches []Match, top int) ([]Match, int) {
+	if top <= 0 || top >= len(matches) {
+		return matches, 0
+	}
+
+	sorted := append([]Match(nil), matches...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if na, nb := len(a.Files()), len(b.Files()); na != nb {
+			return na > nb
+		}
+		if (a.IOCRange == "") != (b.IOCRange == "") {
+			return a.IOCRange == ""
+		}
+		if a.PackageName != b.PackageName {
+			return a.PackageName < b.PackageName
+		}
+		return a.Version < b.Version
+	})
+	return sorted[:top], len(matches) - top
+}
+
+// formatHidden renders the footer of a section limited by HumanOptions.Top.
+func formatHidden(hidden int) string {
+	if hidden == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n   %s... and %d more, see the JSON report (--json) for all matches%s\n", colorGray, hidden, colorReset)
+}
+
 // filterBySeverity returns all matches with the specified severity level.
 func filterBySeverity(matches []Match, severity Severity) []Match {
 	var result []Match
@@ -115,3 +914,144 @@ func filterBySeverity(matches []Match, severity Severity) []Match {
 	}
 	return result
 }
+
+// FormatHumanRoots formats per-root scan results as consecutive
+// human-readable sections, each introduced by its root path.
+func FormatHumanRoots(roots []RootResult) string {
+	return FormatHumanRootsWith(roots, HumanOptions{})
+}
+
+// FormatHumanRootsWith formats per-root scan results like FormatHumanRoots,
+// with options applied to each root.
+func FormatHumanRootsWith(roots []RootResult, options HumanOptions) string {
+	var b strings.Builder
+
+	for _, root := range roots {
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%sROOT: %s%s\n", colorBold, root.Path, colorReset))
+		b.WriteString(FormatHumanWith(root.Result, options))
+	}
+
+	return b.String()
+}
+
+// FormatHumanChecks formats package checks as one verdict per package
+// version, with the advisory details of compromised ones.
+func FormatHumanChecks(checks []PackageCheck) string {
+	var b strings.Builder
+
+	for _, check := range checks {
+		if !check.Compromised {
+			b.WriteString(fmt.Sprintf("%s✓ %s@%s%s: not listed\n", colorGreen, check.Package, check.Version, colorReset))
+			continue
+		}
+
+		b.WriteString(fmt.Sprintf("%s%s✗ %s@%s%s: COMPROMISED\n", colorRed, colorBold, check.Package, check.Version, colorReset))
+		if check.IOCRange != "" {
+			b.WriteString(fmt.Sprintf("   %sAffected Range:%s %s\n", colorGray, colorReset, check.IOCRange))
+		}
+		if check.IOCScope != "" {
+			b.WriteString(fmt.Sprintf("   %sCompromised Scope:%s %s\n", colorGray, colorReset, check.IOCScope))
+		}
+		b.WriteString(formatAdvisory(Match{Advisory: check.Advisory, Sources: check.Sources}))
+		if check.IOCAdded != nil {
+			b.WriteString(fmt.Sprintf("   %sIoC Added:%s %s\n", colorGray, colorReset, check.IOCAdded.Format("2006-01-02")))
+		}
+	}
+
+	return b.String()
+}
+
+// FormatHumanStats formats project exposure statistics as a ranked list.
+func FormatHumanStats(stats []ProjectStats) string {
+	var b strings.Builder
+
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%sDEPENDENCY EXPOSURE (%d projects)%s\n", colorBold, len(stats), colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+
+	if len(stats) == 0 {
+		b.WriteString("No projects found.\n")
+	}
+
+	for i, s := range stats {
+		label := s.Path
+		if s.Name != "" {
+			label = fmt.Sprintf("%s (%s)", s.Name, s.Path)
+		}
+
+		color := colorReset
+		if s.WideRangeRatio >= 0.5 {
+			color = colorYellow
+		}
+
+		b.WriteString("\n")
+		b.WriteString(fmt.Sprintf("%s%d. %s%s\n", color, i+1, label, colorReset))
+		b.WriteString(fmt.Sprintf("   %sResolved:%s %d packages\n", colorGray, colorReset, s.ResolvedDependencies))
+		b.WriteString(fmt.Sprintf("   %sDeclared:%s %d (%d wide ranges, %.0f%%)\n", colorGray, colorReset, s.DeclaredDependencies, s.WideRanges, s.WideRangeRatio*100))
+	}
+
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// FormatHumanDiscovery formats discovery statistics with estimated scan
+// durations.
+func FormatHumanDiscovery(stats *DiscoveryStats) string {
+	var b strings.Builder
+
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%sDISCOVERY STATISTICS%s\n", colorBold, colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("Paths:             %d\n", stats.Paths))
+	b.WriteString(fmt.Sprintf("Manifests:         %d files (%s)\n", stats.Manifests, formatBytes(stats.ManifestBytes)))
+	b.WriteString(fmt.Sprintf("Lockfiles:         %d files (%s)\n", stats.Lockfiles, formatBytes(stats.LockfileBytes)))
+	if stats.InstalledPackages > 0 {
+		b.WriteString(fmt.Sprintf("node_modules:      %d trees (%d installed packages)\n", stats.NodeModules, stats.InstalledPackages))
+	} else {
+		b.WriteString(fmt.Sprintf("node_modules:      %d trees\n", stats.NodeModules))
+	}
+	b.WriteString(fmt.Sprintf("Discovery:         %s\n", formatSeconds(stats.DiscoverySeconds)))
+	if stats.ParseBytesPerSecond > 0 {
+		b.WriteString(fmt.Sprintf("Parse Throughput:  %s/s per worker\n", formatBytes(int64(stats.ParseBytesPerSecond))))
+	}
+
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("%sESTIMATED SCAN DURATION%s %s(excluding the IoC database fetch)%s\n", colorBold, colorReset, colorGray, colorReset))
+	b.WriteString(fmt.Sprintf("%s────────────────────────────────────────────────────────%s\n", colorGray, colorReset))
+	for _, estimate := range stats.Estimates {
+		unit := "workers"
+		if estimate.Workers == 1 {
+			unit = "worker"
+		}
+		b.WriteString(fmt.Sprintf("%3d %-8s %s\n", estimate.Workers, unit+":", formatSeconds(estimate.Seconds)))
+	}
+	b.WriteString("\n")
+
+	return b.String()
+}
+
+// formatBytes formats a size with binary units.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+// formatSeconds formats a duration in seconds, rounded to a precision
+// fitting its magnitude.
+func formatSeconds(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
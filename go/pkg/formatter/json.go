@@ -5,11 +5,68 @@ import (
 )
 
 // FormatJSON formats scan results as JSON with 2-space indentation.
-// Output is pretty-printed for readability.
+// Output is pretty-printed for readability, and includes a summary object
+// with counts by severity and file and the overall verdict.
 func FormatJSON(result *ScanResult) (string, error) {
-	data, err := json.MarshalIndent(result, "", "  ")
+	data, err := json.MarshalIndent(withSummary(result), "", "  ")
 	if err != nil {
 		return "", err
 	}
 	return string(data), nil
 }
+
+// FormatJSONRoots formats per-root scan results as a JSON array with
+// 2-space indentation. Each root's result includes its own summary.
+func FormatJSONRoots(roots []RootResult) (string, error) {
+	summarized := make([]RootResult, len(roots))
+	for i, root := range roots {
+		summarized[i] = RootResult{Path: root.Path, Result: withSummary(root.Result)}
+	}
+
+	data, err := json.MarshalIndent(summarized, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FormatJSONStats formats project exposure statistics as a JSON array with
+// 2-space indentation.
+func FormatJSONStats(stats []ProjectStats) (string, error) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FormatJSONDiscovery formats discovery statistics as indented JSON.
+func FormatJSONDiscovery(stats *DiscoveryStats) (string, error) {
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FormatJSONChecks formats package checks as an indented JSON array.
+func FormatJSONChecks(checks []PackageCheck) (string, error) {
+	if checks == nil {
+		checks = []PackageCheck{}
+	}
+	data, err := json.MarshalIndent(checks, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// withSummary returns a shallow copy of result with its Summary computed.
+func withSummary(result *ScanResult) *ScanResult {
+	if result == nil {
+		return nil
+	}
+	summarized := *result
+	summarized.Summary = Summarize(result)
+	return &summarized
+}
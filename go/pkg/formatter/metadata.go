@@ -0,0 +1,62 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Metadata keys recorded automatically when the scan root is a git repository.
+const (
+	MetaGitRemote = "git.remote"
+	MetaGitBranch = "git.branch"
+	MetaGitCommit = "git.commit"
+)
+
+// Metadata keys recorded by container image scans.
+const (
+	MetaImageReference = "image.reference"
+	MetaImageDigest    = "image.digest"
+)
+
+// Metadata keys recorded by archive scans.
+const (
+	MetaArchivePath   = "archive.path"
+	MetaArchiveDigest = "archive.digest"
+)
+
+// ParseMetadata parses repeated key=value pairs, such as those given with
+// --meta, into a metadata map. Later pairs override earlier ones with the
+// same key. Returns nil if pairs is empty.
+func ParseMetadata(pairs []string) (map[string]string, error) {
+	if len(pairs) == 0 {
+		return nil, nil
+	}
+
+	metadata := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid metadata %q (expected key=value)", pair)
+		}
+		metadata[key] = value
+	}
+	return metadata, nil
+}
+
+// MergeMetadata returns base overlaid with overrides. Neither map is
+// modified. Returns nil if both are empty.
+func MergeMetadata(base, overrides map[string]string) map[string]string {
+	if len(base) == 0 && len(overrides) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(base)+len(overrides))
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
@@ -0,0 +1,271 @@
+package formatter
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// RedactMode selects which parts of a scan result are redacted.
+type RedactMode string
+
+const (
+	// RedactPaths replaces every directory segment of a location with a token
+	RedactPaths RedactMode = "paths"
+	// RedactProjectNames replaces only the project directory (the parent of
+	// the manifest or lockfile) with a token
+	RedactProjectNames RedactMode = "projectnames"
+)
+
+// redactTokenPrefix marks values that were produced by a Redactor.
+const redactTokenPrefix = "redacted-"
+
+// ParseRedactModes parses a comma-separated list of redaction modes,
+// e.g. "paths" or "paths,projectnames".
+func ParseRedactModes(spec string) ([]RedactMode, error) {
+	var modes []RedactMode
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		switch RedactMode(part) {
+		case RedactPaths, RedactProjectNames:
+			modes = append(modes, RedactMode(part))
+		default:
+			return nil, fmt.Errorf("unknown redact mode %q (expected paths or projectnames)", part)
+		}
+	}
+	return modes, nil
+}
+
+// Redactor replaces potentially sensitive path segments in scan results with
+// hashed tokens, the same for a value throughout the Redactor's output.
+// Tokens are keyed with a random key of the Redactor, so they cannot be
+// reversed by hashing guessed names. Every replaced value is recorded so
+// that redacted output shared with third parties can be mapped back
+// internally.
+type Redactor struct {
+	paths        bool
+	projectNames bool
+	key          []byte
+	mapping      map[string]string
+}
+
+// NewRedactor creates a Redactor for the given modes.
+func NewRedactor(modes []RedactMode) *Redactor {
+	key := make([]byte, sha256.Size)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("redact: read random key: %v", err))
+	}
+	r := &Redactor{
+		key:     key,
+		mapping: make(map[string]string),
+	}
+	for _, mode := range modes {
+		switch mode {
+		case RedactPaths:
+			r.paths = true
+		case RedactProjectNames:
+			r.projectNames = true
+		}
+	}
+	return r
+}
+
+// RedactResult returns a copy of result with match locations, dependency
+// chains and the git remote, branch and archive path metadata redacted.
+// The original result is not modified.
+func (r *Redactor) RedactResult(result *ScanResult) *ScanResult {
+	redacted := *result
+	redacted.Metadata = r.redactMetadata(result.Metadata)
+	redacted.Matches = r.redactMatches(result.Matches)
+	redacted.Hygiene = r.redactMatches(result.Hygiene)
+	redacted.UncheckedBundled = r.redactMatches(result.UncheckedBundled)
+	redacted.Shadowed = r.redactMatches(result.Shadowed)
+	redacted.Watchlist = r.redactMatches(result.Watchlist)
+	redacted.RegistryStatus = r.redactMatches(result.RegistryStatus)
+	redacted.Provenance = r.redactMatches(result.Provenance)
+	redacted.Maintainers = r.redactMatches(result.Maintainers)
+	redacted.Typosquats = r.redactMatches(result.Typosquats)
+	redacted.PolicyViolations = r.redactMatches(result.PolicyViolations)
+	redacted.Unapproved = r.redactMatches(result.Unapproved)
+	redacted.Scripts = r.redactMatches(result.Scripts)
+	redacted.Suppressed = r.redactMatches(result.Suppressed)
+	if result.LockfileAges != nil {
+		redacted.LockfileAges = make([]LockfileAge, len(result.LockfileAges))
+		for i, age := range result.LockfileAges {
+			age.Path = r.RedactPath(age.Path)
+			redacted.LockfileAges[i] = age
+		}
+	}
+	if result.Warnings != nil {
+		redacted.Warnings = make([]FileError, len(result.Warnings))
+		for i, warning := range result.Warnings {
+			path := r.RedactPath(warning.Path)
+			warning.Error = strings.ReplaceAll(warning.Error, warning.Path, path)
+			warning.Path = path
+			redacted.Warnings[i] = warning
+		}
+	}
+	if result.Projects != nil {
+		redacted.Projects = make([]ProjectResult, len(result.Projects))
+		for i, project := range result.Projects {
+			project.Path = r.RedactDir(project.Path)
+			if r.projectNames && project.Name != "" {
+				project.Name = r.token(project.Name)
+			}
+			project.Matches = r.redactMatches(project.Matches)
+			redacted.Projects[i] = project
+		}
+	}
+	return &redacted
+}
+
+// redactMatches returns a copy of matches with locations, including those
+// of their evidence, redacted.
+func (r *Redactor) redactMatches(matches []Match) []Match {
+	if matches == nil {
+		return nil
+	}
+	redacted := make([]Match, len(matches))
+	for i, match := range matches {
+		match.Location = r.RedactPath(match.Location)
+		match.Chain = r.redactChain(match.Chain)
+		if match.Evidence != nil {
+			evidence := make([]Evidence, len(match.Evidence))
+			for j, e := range match.Evidence {
+				e.File = r.RedactPath(e.File)
+				if e.LockfilePath != "" {
+					e.LockfilePath = r.RedactPath(e.LockfilePath)
+				}
+				e.Chain = r.redactChain(e.Chain)
+				evidence[j] = e
+			}
+			match.Evidence = evidence
+		}
+		redacted[i] = match
+	}
+	return redacted
+}
+
+// redactChain returns a copy of a dependency chain with every package but
+// the matched one, the last, replaced with a token, since the chain starts
+// at the project's own, possibly private, dependencies.
+func (r *Redactor) redactChain(chain []string) []string {
+	if len(chain) == 0 || (!r.paths && !r.projectNames) {
+		return chain
+	}
+	redacted := make([]string, len(chain))
+	for i, entry := range chain {
+		if i < len(chain)-1 {
+			entry = r.token(entry)
+		}
+		redacted[i] = entry
+	}
+	return redacted
+}
+
+// redactMetadata returns a copy of metadata with the git remote and branch,
+// which name the repository, replaced with tokens and the archive path
+// redacted like locations.
+func (r *Redactor) redactMetadata(metadata map[string]string) map[string]string {
+	if metadata == nil || (!r.paths && !r.projectNames) {
+		return metadata
+	}
+	redacted := make(map[string]string, len(metadata))
+	for key, value := range metadata {
+		switch key {
+		case MetaGitRemote, MetaGitBranch:
+			if value != "" {
+				value = r.token(value)
+			}
+		case MetaArchivePath:
+			value = r.RedactPath(value)
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
+
+// RedactPath redacts a single file path according to the configured modes.
+// The file name itself (package.json, yarn.lock, ...) is always preserved.
+func (r *Redactor) RedactPath(path string) string {
+	return r.redactSegments(path, true)
+}
+
+// RedactDir redacts a directory path according to the configured modes.
+// Unlike RedactPath, the final segment is treated as the project directory.
+func (r *Redactor) RedactDir(path string) string {
+	return r.redactSegments(path, false)
+}
+
+// redactSegments replaces path segments with tokens. When hasFile is set the
+// final segment is a file name and is left untouched.
+func (r *Redactor) redactSegments(path string, hasFile bool) string {
+	if path == "" || (!r.paths && !r.projectNames) {
+		return path
+	}
+
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	project := len(segments) - 1
+	if hasFile {
+		project--
+	}
+
+	for i := 0; i <= project; i++ {
+		segment := segments[i]
+		if segment == "" || segment == "." || segment == ".." {
+			continue
+		}
+		if r.paths || i == project {
+			segments[i] = r.token(segment)
+		}
+	}
+
+	return filepath.FromSlash(strings.Join(segments, "/"))
+}
+
+// Mapping returns a copy of the token to original value mapping.
+func (r *Redactor) Mapping() map[string]string {
+	mapping := make(map[string]string, len(r.mapping))
+	for token, original := range r.mapping {
+		mapping[token] = original
+	}
+	return mapping
+}
+
+// WriteMapping writes the token mapping as JSON to path. The file is
+// created with owner-only permissions since it de-redacts the output.
+func (r *Redactor) WriteMapping(path string) error {
+	data, err := json.MarshalIndent(r.mapping, "", "  ")
+	if err != nil {
+		return err
+	}
+	return readonly.WriteFile(path, data, 0600)
+}
+
+// token returns the redaction token for a value, an HMAC of the value
+// keyed with the Redactor's key, and records it.
+func (r *Redactor) token(value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	token := redactTokenPrefix + hex.EncodeToString(mac.Sum(nil))[:12]
+	r.mapping[token] = value
+	return token
+}
+
+// Unredact replaces any redaction tokens in s using the given mapping.
+func Unredact(s string, mapping map[string]string) string {
+	for token, original := range mapping {
+		s = strings.ReplaceAll(s, token, original)
+	}
+	return s
+}
@@ -0,0 +1,253 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// sarifSchema is the JSON schema of SARIF 2.1.0 documents
+	sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+	// sarifInformationURI links to the project in the tool descriptor
+	sarifInformationURI = "https://github.com/tuckertucker/tkr-npm-scan"
+)
+
+// sarifDocument mirrors the subset of SARIF 2.1.0 used by GitHub Code Scanning.
+type sarifDocument struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	FullDescription      sarifMessage       `json:"fullDescription"`
+	HelpURI              string             `json:"helpUri,omitempty"`
+	Help                 sarifMessage       `json:"help"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+	Properties           sarifProperties    `json:"properties"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifProperties struct {
+	Tags []string `json:"tags"`
+	// SecuritySeverity is the CVSS-like score GitHub uses to rank alerts
+	SecuritySeverity string `json:"security-severity"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifLocations returns the locations of a match: one per evidence record,
+// or its Location when it has no evidence.
+func sarifLocations(match Match) []sarifLocation {
+	if len(match.Evidence) == 0 {
+		return []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: sarifURI(match.Location)},
+		}}}
+	}
+
+	locations := make([]sarifLocation, 0, len(match.Evidence))
+	for _, evidence := range match.Evidence {
+		location := sarifLocation{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: sarifArtifactLocation{URI: sarifURI(evidence.File)},
+		}}
+		if evidence.Line > 0 {
+			location.PhysicalLocation.Region = &sarifRegion{StartLine: evidence.Line}
+		}
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+// sarifLevel maps a match severity to a SARIF result level and a GitHub
+// security-severity score: DIRECT and TRANSITIVE matches are errors scored
+// critical, POTENTIAL matches are warnings scored medium.
+func sarifLevel(severity Severity) (level, score string) {
+	if severity == SeverityPotential {
+		return "warning", "5.0"
+	}
+	return "error", "9.8"
+}
+
+// FormatSARIF formats scan results as a SARIF 2.1.0 log that can be
+// uploaded to GitHub Code Scanning.
+//
+// Each compromised package gets one rule ("ioc/<package>"), linking to its
+// advisory when known; each match becomes a result located at its manifest
+// or lockfile, plus every other file its evidence was found in, at the
+// line of the declaration when known. The match fingerprint is reported as a partial fingerprint
+// so alerts are tracked across runs. toolVersion is reported in the driver.
+func FormatSARIF(result *ScanResult, toolVersion string) (string, error) {
+	byPackage := make(map[string][]Match)
+	for _, match := range result.Matches {
+		byPackage[match.PackageName] = append(byPackage[match.PackageName], match)
+	}
+	packages := make([]string, 0, len(byPackage))
+	for name := range byPackage {
+		packages = append(packages, name)
+	}
+	sort.Strings(packages)
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "npm-scan",
+			Version:        toolVersion,
+			InformationURI: sarifInformationURI,
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+
+	for i, name := range packages {
+		matches := byPackage[name]
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRuleFor(name, matches))
+
+		for _, match := range matches {
+			level, _ := sarifLevel(match.Severity)
+			r := sarifResult{
+				RuleID:    run.Tool.Driver.Rules[i].ID,
+				RuleIndex: i,
+				Level:     level,
+				Message:   sarifMessage{Text: sarifMessageFor(match)},
+				Locations: sarifLocations(match),
+			}
+			if match.Fingerprint != "" {
+				r.PartialFingerprints = map[string]string{"npmScanFingerprint/v1": match.Fingerprint}
+			}
+			run.Results = append(run.Results, r)
+		}
+	}
+
+	doc := sarifDocument{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// sarifRuleFor builds the rule for a compromised package from its matches.
+// The rule takes the most severe level among them.
+func sarifRuleFor(name string, matches []Match) sarifRule {
+	level, score := "warning", "5.0"
+	versions := make([]string, 0, len(matches))
+	var advisory *Advisory
+	for _, match := range matches {
+		if l, s := sarifLevel(match.Severity); l == "error" {
+			level, score = l, s
+		}
+		if !containsVersion(versions, match.Version) {
+			versions = append(versions, match.Version)
+		}
+		if advisory == nil {
+			advisory = match.Advisory
+		}
+	}
+	sort.Strings(versions)
+
+	description := fmt.Sprintf("%s %s is listed in the IoC database of compromised npm packages.", name, strings.Join(versions, ", "))
+	help := "Remove the package or update to a version that is not compromised, then regenerate the lockfile."
+	rule := sarifRule{
+		ID:                   "ioc/" + name,
+		Name:                 "CompromisedPackage",
+		ShortDescription:     sarifMessage{Text: "Compromised npm package " + name},
+		FullDescription:      sarifMessage{Text: description},
+		Help:                 sarifMessage{Text: help},
+		DefaultConfiguration: sarifConfiguration{Level: level},
+		Properties: sarifProperties{
+			Tags:             []string{"security", "supply-chain", "npm"},
+			SecuritySeverity: score,
+		},
+	}
+	if advisory != nil {
+		rule.HelpURI = advisory.URL
+		if len(advisory.IDs) > 0 {
+			rule.FullDescription.Text += " Advisory: " + strings.Join(advisory.IDs, ", ") + "."
+		}
+	}
+	return rule
+}
+
+// sarifMessageFor describes a single match.
+func sarifMessageFor(match Match) string {
+	switch match.Severity {
+	case SeverityPotential:
+		return fmt.Sprintf("Declared range %s of %s could resolve to compromised version %s", match.DeclaredSpec, match.PackageName, match.Version)
+	case SeverityTransitive:
+		return fmt.Sprintf("Compromised %s@%s is resolved in this lockfile", match.PackageName, match.Version)
+	default:
+		return fmt.Sprintf("Compromised %s@%s is pinned in this manifest", match.PackageName, match.Version)
+	}
+}
+
+// sarifURI converts a match location to an artifact URI: relative paths
+// become slash-separated relative references, absolute paths file URIs.
+func sarifURI(location string) string {
+	if filepath.IsAbs(location) {
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(location)}).String()
+	}
+	return filepath.ToSlash(filepath.Clean(location))
+}
+
+// containsVersion reports whether versions contains v.
+func containsVersion(versions []string, v string) bool {
+	for _, version := range versions {
+		if version == v {
+			return true
+		}
+	}
+	return false
+}
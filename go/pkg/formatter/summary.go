@@ -0,0 +1,156 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Verdicts reported in Summary.Verdict.
+const (
+	// VerdictClean means no matches were found
+	VerdictClean = "clean"
+	// VerdictAffected means at least one DIRECT or TRANSITIVE match, or
+	// malicious script, was found
+	VerdictAffected = "affected"
+	// VerdictAtRisk means only POTENTIAL matches were found
+	VerdictAtRisk = "at-risk"
+	// VerdictFailing means no DIRECT or TRANSITIVE match was found, but a
+	// policy violation or unapproved package fails the scan (see Fails)
+	VerdictFailing = "failing"
+	// VerdictNotScanned means no matches were found, but (part of) the scan
+	// could not run, so the result does not show the scanned paths are clean
+	VerdictNotScanned = "not-scanned"
+	// VerdictIncomplete means no matches were found, but some dependency
+	// files could not be read or parsed (see ScanResult.Warnings), so their
+	// packages were not checked
+	VerdictIncomplete = "incomplete"
+)
+
+// Summary holds aggregates over a scan result's matches, so consumers of
+// the JSON output don't need to recompute them.
+type Summary struct {
+	Verdict         string           `json:"verdict"`
+	TotalMatches    int              `json:"totalMatches"`
+	BySeverity      map[Severity]int `json:"bySeverity"`
+	ByFile          map[string]int   `json:"byFile"`
+	HygieneFindings int              `json:"hygieneFindings,omitempty"`
+	// PolicyViolations counts packages below a required safe version; they
+	// are not part of TotalMatches
+	PolicyViolations int `json:"policyViolations,omitempty"`
+	// Unapproved counts packages missing from the allowlist; they are not
+	// part of TotalMatches
+	Unapproved int `json:"unapproved,omitempty"`
+	// Scripts counts package.json scripts running a malicious command; they
+	// are not part of TotalMatches
+	Scripts int `json:"scripts,omitempty"`
+	// Suppressed counts matches acknowledged in the suppression file; they
+	// are not part of TotalMatches
+	Suppressed int `json:"suppressed,omitempty"`
+	// ByRemediation counts matches by recorded remediation status; matches
+	// without one are counted as "untriaged"
+	ByRemediation map[string]int `json:"byRemediation,omitempty"`
+}
+
+// untriaged is the ByRemediation key for matches with no recorded status.
+const untriaged = "untriaged"
+
+// Summarize computes the Summary of a scan result. Every match severity is
+// present in BySeverity, with zero counts where there are no matches.
+func Summarize(result *ScanResult) *Summary {
+	summary := &Summary{
+		TotalMatches: len(result.Matches),
+		BySeverity: map[Severity]int{
+			SeverityDirect:     0,
+			SeverityTransitive: 0,
+			SeverityPotential:  0,
+		},
+		ByFile:           make(map[string]int),
+		HygieneFindings:  len(result.Hygiene),
+		PolicyViolations: len(result.PolicyViolations),
+		Unapproved:       len(result.Unapproved),
+		Scripts:          len(result.Scripts),
+		Suppressed:       len(result.Suppressed),
+	}
+
+	triaged := false
+	for _, match := range result.Matches {
+		summary.BySeverity[match.Severity]++
+		summary.ByFile[match.Location]++
+		triaged = triaged || match.Remediation != nil
+	}
+
+	// Only break down by remediation status once something was triaged
+	if triaged {
+		summary.ByRemediation = make(map[string]int)
+		for _, match := range result.Matches {
+			if match.Remediation != nil {
+				summary.ByRemediation[match.Remediation.Status]++
+			} else {
+				summary.ByRemediation[untriaged]++
+			}
+		}
+	}
+
+	switch {
+	case summary.BySeverity[SeverityDirect] > 0 || summary.BySeverity[SeverityTransitive] > 0 || summary.Scripts > 0:
+		summary.Verdict = VerdictAffected
+	case summary.PolicyViolations > 0 || summary.Unapproved > 0:
+		summary.Verdict = VerdictFailing
+	case summary.BySeverity[SeverityPotential] > 0:
+		summary.Verdict = VerdictAtRisk
+	case result.NotScanned != "":
+		summary.Verdict = VerdictNotScanned
+	case len(result.Warnings) > 0:
+		summary.Verdict = VerdictIncomplete
+	default:
+		summary.Verdict = VerdictClean
+	}
+
+	return summary
+}
+
+// FailOnNone is the --fail-on value that never fails a scan on matches.
+const FailOnNone = "none"
+
+// severityRank orders match severities by confidence, for ParseFailOn.
+var severityRank = map[Severity]int{
+	SeverityPotential:  1,
+	SeverityTransitive: 2,
+	SeverityDirect:     3,
+}
+
+// ParseFailOn parses the minimum severity that fails a scan: direct,
+// transitive, potential or none. A threshold includes every more certain
+// severity, so transitive fails on DIRECT and TRANSITIVE matches. none
+// returns an empty Severity, which no match reaches.
+func ParseFailOn(value string) (Severity, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == FailOnNone {
+		return "", nil
+	}
+	severity := Severity(strings.ToUpper(value))
+	if _, ok := severityRank[severity]; !ok {
+		return "", fmt.Errorf("invalid --fail-on %q: expected direct, transitive, potential or none", value)
+	}
+	return severity, nil
+}
+
+// Fails reports whether result has a match at or above the threshold
+// severity returned by ParseFailOn, or a policy violation, unapproved
+// package or malicious script at any threshold but none. Hygiene findings
+// never fail a scan.
+func Fails(result *ScanResult, threshold Severity) bool {
+	minimum, ok := severityRank[threshold]
+	if !ok {
+		return false
+	}
+	if len(result.PolicyViolations) > 0 || len(result.Unapproved) > 0 || len(result.Scripts) > 0 {
+		return true
+	}
+	for _, match := range result.Matches {
+		if severityRank[match.Severity] >= minimum {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package formatter
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimestampLayout renders report timestamps as RFC 3339 with milliseconds,
+// using "Z" for UTC and a numeric offset for other zones.
+const TimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// ParseTimezone resolves a --timezone value: an IANA zone name such as
+// Europe/Berlin, Local for the system zone, or UTC (the default when empty).
+func ParseTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --timezone %q: expected UTC, Local or an IANA zone name such as Europe/Berlin", name)
+	}
+	return loc, nil
+}
+
+// InLocation converts the instants recorded in result (the scan
+// timestamp, lockfile modification times, exposure timelines and
+// remediation updates) to loc. Calendar dates such as IoC publication
+// dates are left as they are.
+func InLocation(result *ScanResult, loc *time.Location) {
+	if result == nil || loc == nil {
+		return
+	}
+
+	result.Timestamp = result.Timestamp.In(loc)
+	for i := range result.LockfileAges {
+		result.LockfileAges[i].LastModified = result.LockfileAges[i].LastModified.In(loc)
+	}
+	for _, matches := range [][]Match{result.Matches, result.Suppressed} {
+		for i := range matches {
+			matchInLocation(&matches[i], loc)
+		}
+	}
+	for i := range result.Projects {
+		for j := range result.Projects[i].Matches {
+			matchInLocation(&result.Projects[i].Matches[j], loc)
+		}
+	}
+}
+
+// matchInLocation converts the instants recorded on a match to loc.
+func matchInLocation(match *Match, loc *time.Location) {
+	if match.Timeline != nil {
+		match.Timeline.IntroducedAt = match.Timeline.IntroducedAt.In(loc)
+		if match.Timeline.RemovedAt != nil {
+			removed := match.Timeline.RemovedAt.In(loc)
+			match.Timeline.RemovedAt = &removed
+		}
+	}
+	if match.Remediation != nil {
+		match.Remediation.UpdatedAt = match.Remediation.UpdatedAt.In(loc)
+	}
+}
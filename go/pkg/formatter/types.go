@@ -14,15 +14,245 @@ const (
 	SeverityTransitive Severity = "TRANSITIVE"
 	// SeverityPotential indicates a version range that could resolve to a vulnerable version
 	SeverityPotential Severity = "POTENTIAL"
+	// SeverityHygiene indicates an unpinned dependency or missing lockfile
+	SeverityHygiene Severity = "HYGIENE"
+	// SeverityUncheckedBundled indicates a bundled dependency whose version
+	// could not be determined, so it was not checked
+	SeverityUncheckedBundled Severity = "UNCHECKED_BUNDLED"
+	// SeverityShadowed indicates an installed package whose version the
+	// lockfile does not resolve
+	SeverityShadowed Severity = "SHADOWED"
+	// SeverityWatchlist indicates a package whose name matches a watchlist
+	// pattern, whatever its version
+	SeverityWatchlist Severity = "WATCHLIST"
+	// SeverityPolicy indicates a package version that does not satisfy a
+	// required safe version range
+	SeverityPolicy Severity = "POLICY"
+	// SeverityUnapproved indicates a package that is not on the allowlist
+	SeverityUnapproved Severity = "UNAPPROVED"
+	// SeverityDeprecated indicates a direct dependency whose version is
+	// deprecated in the registry (informational)
+	SeverityDeprecated Severity = "DEPRECATED"
+	// SeverityUnpublished indicates a direct dependency whose version, or
+	// whole package, was unpublished from the registry (informational)
+	SeverityUnpublished Severity = "UNPUBLISHED"
+	// SeverityProvenance indicates a package version published without
+	// provenance, or whose provenance does not verify or names an
+	// unexpected repository (informational)
+	SeverityProvenance Severity = "PROVENANCE"
+	// SeverityMaintainer indicates a package version published, or
+	// maintained, by an npm account on a maintainer IoC list
+	// (informational)
+	SeverityMaintainer Severity = "MAINTAINER"
+	// SeverityScript indicates a package.json script running a command
+	// listed as malicious by the IoC database, such as a worm's
+	// postinstall payload
+	SeverityScript Severity = "SCRIPT"
+	// SeverityWarning indicates a dependency whose name is one typo away
+	// from a popular package, a likely typosquat (informational)
+	SeverityWarning Severity = "WARNING"
 )
 
 // Match represents a single detected vulnerability.
 type Match struct {
-	PackageName  string    `json:"packageName"`
-	Version      string    `json:"version"`
-	Severity     Severity  `json:"severity"`
-	Location     string    `json:"location"`
-	DeclaredSpec string    `json:"declaredSpec,omitempty"` // For POTENTIAL matches
+	PackageName  string   `json:"packageName"`
+	Version      string   `json:"version"`
+	Severity     Severity `json:"severity"`
+	Location     string   `json:"location"`
+	DeclaredSpec string   `json:"declaredSpec,omitempty"` // For POTENTIAL matches
+	Reason       string   `json:"reason,omitempty"`       // For HYGIENE findings
+	// Alias is the declared dependency name when the package was pulled in
+	// through an npm alias ("my-alias": "npm:real-pkg@1.2.3")
+	Alias string `json:"alias,omitempty"`
+	// Override is the key path of the npm "overrides" or yarn "resolutions"
+	// entry forcing the version, such as "resolutions > **/pkg", when a
+	// DIRECT or POTENTIAL match comes from an override
+	Override string `json:"override,omitempty"`
+	// Chain is the shortest dependency path from a root dependency to a
+	// TRANSITIVE match, as "name@version" entries ending with the match
+	Chain []string `json:"chain,omitempty"`
+	// Integrity is the lockfile tarball hash, for matches on a known-malicious hash
+	Integrity string `json:"integrity,omitempty"`
+	// IOCRange is the affected version range of the IoC entry, when the
+	// version matched a range rather than an exact pin
+	IOCRange string `json:"iocRange,omitempty"`
+	// IOCScope is the compromised-scope entry, such as "@ctrl/*", when the
+	// package matched through its scope rather than its own name
+	IOCScope string `json:"iocScope,omitempty"`
+	// LockedVersion lists the versions the lockfile resolves a SHADOWED
+	// package to, when they differ from the installed version
+	LockedVersion string `json:"lockedVersion,omitempty"`
+	// Required is the version range a POLICY finding's package is required
+	// to satisfy
+	Required string `json:"required,omitempty"`
+	// Resembles is the popular package a WARNING finding's name is one
+	// typo away from
+	Resembles string `json:"resembles,omitempty"`
+	// Script is the package.json script (such as "postinstall") of a
+	// SCRIPT finding, and ScriptIndicator the IoC it matched
+	Script          string `json:"script,omitempty"`
+	ScriptIndicator string `json:"scriptIndicator,omitempty"`
+	// Advisory links the matched IoC entry to its advisory, when the source
+	// provides one. With several sources, their advisories are merged.
+	Advisory *Advisory `json:"advisory,omitempty"`
+	// Sources lists each IoC source that reported the package version, so
+	// a version listed by several feeds is reported once
+	Sources []SourceReport `json:"sources,omitempty"`
+	// Evidence records every occurrence of the finding with the details
+	// justifying it. Location is the first occurrence's file; duplicates of
+	// the finding in other files are merged here.
+	Evidence []Evidence `json:"evidence,omitempty"`
+	// IOCAdded is when the matched entry was added to the IoC database, if known
+	IOCAdded *time.Time `json:"iocAdded,omitempty"`
+	// ExposedSince is when the lockfile holding a TRANSITIVE match was last
+	// changed in git; the compromised version has been present at least since then
+	ExposedSince *time.Time `json:"exposedSince,omitempty"`
+	ExposureDays int        `json:"exposureDays,omitempty"`
+	// Timeline records when a TRANSITIVE match entered (and left) the
+	// lockfile's git history, when exposure window tracing is enabled
+	Timeline *ExposureTimeline `json:"timeline,omitempty"`
+	// Fingerprint identifies the finding across scans (see Fingerprint)
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Remediation is the triage state recorded for the finding, if any
+	Remediation *Remediation `json:"remediation,omitempty"`
+	// Suppression is the suppression file rule covering a suppressed match
+	Suppression *Suppression `json:"suppression,omitempty"`
+	// Popularity is how widely the package is used, when popularity
+	// enrichment is enabled
+	Popularity *Popularity `json:"popularity,omitempty"`
+}
+
+// Remediation is the triage state of a finding, as recorded with
+// "npm-scan ack".
+type Remediation struct {
+	Status    string    `json:"status"`
+	Ticket    string    `json:"ticket,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Suppression describes the suppression file rule that acknowledged a match.
+type Suppression struct {
+	// Rule is the package@version the rule lists
+	Rule   string `json:"rule"`
+	Reason string `json:"reason,omitempty"`
+	// Expires is the last day the rule applies, if it expires
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+// Popularity describes how widely a matched package is used, for triage.
+type Popularity struct {
+	// WeeklyDownloads counts the package's npm downloads in the last week,
+	// across all versions
+	WeeklyDownloads int64 `json:"weeklyDownloads"`
+	// Dependents counts the packages depending on the matched version,
+	// directly or indirectly, as reported by deps.dev
+	Dependents       int `json:"dependents"`
+	DirectDependents int `json:"directDependents"`
+}
+
+// SourceReport is one IoC source's report of a matched package version.
+type SourceReport struct {
+	Source string `json:"source"`
+	// Range is the affected range the source lists the version under, for
+	// range-based entries
+	Range    string     `json:"range,omitempty"`
+	Advisory *Advisory  `json:"advisory,omitempty"`
+	Added    *time.Time `json:"added,omitempty"`
+	// Row is the line of the entry in the source data, for line-based feeds
+	Row int `json:"row,omitempty"`
+}
+
+// Files returns the files a match was found in: its Location, then the
+// other files of its evidence.
+func (m Match) Files() []string {
+	files := []string{m.Location}
+	for _, evidence := range m.Evidence {
+		duplicate := false
+		for _, file := range files {
+			if file == evidence.File {
+				duplicate = true
+				break
+			}
+		}
+		if !duplicate {
+			files = append(files, evidence.File)
+		}
+	}
+	return files
+}
+
+// Evidence is one occurrence of a match: where the package version was
+// found and which IoC entries it matched.
+type Evidence struct {
+	// File is the manifest or lockfile the occurrence was found in
+	File string `json:"file"`
+	// Line is the line of the declaration or lockfile entry in File, when
+	// the file was read from disk
+	Line int `json:"line,omitempty"`
+	// DeclaredSpec is the version spec declared in a manifest
+	DeclaredSpec string `json:"declaredSpec,omitempty"`
+	// LockfilePath is the lockfile the version was resolved in, for
+	// TRANSITIVE matches
+	LockfilePath string `json:"lockfilePath,omitempty"`
+	// Chain is the dependency path to a TRANSITIVE match (see Match.Chain)
+	Chain []string `json:"chain,omitempty"`
+	// Feed lists the IoC source rows the occurrence matched
+	Feed []FeedRow `json:"feed,omitempty"`
+}
+
+// FeedRow identifies an IoC entry in its source.
+type FeedRow struct {
+	Source string `json:"source"`
+	// Row is the line of the entry in the source data, when it has lines
+	Row int `json:"row,omitempty"`
+	// Spec is the version or range the entry lists
+	Spec string `json:"spec"`
+}
+
+// Advisory identifies the advisory and campaign behind a matched IoC entry.
+type Advisory struct {
+	// IDs are advisory identifiers such as CVE, GHSA or OSV IDs
+	IDs      []string `json:"ids,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Campaign string   `json:"campaign,omitempty"`
+	// Severity rates the advisory as its IoC source does, e.g. "critical"
+	Severity string `json:"severity,omitempty"`
+}
+
+// PackageCheck is the verdict for a single package version looked up in
+// the IoC database without scanning a project, as with "npm-scan check".
+type PackageCheck struct {
+	Package     string `json:"package"`
+	Version     string `json:"version"`
+	Compromised bool   `json:"compromised"`
+	// IOCRange is the affected version range listing the version, when it
+	// is listed through a range rather than an exact pin
+	IOCRange string `json:"iocRange,omitempty"`
+	// IOCScope is the compromised-scope entry listing the version, such as
+	// "@ctrl/*", when it is listed through the package's scope
+	IOCScope string         `json:"iocScope,omitempty"`
+	Advisory *Advisory      `json:"advisory,omitempty"`
+	Sources  []SourceReport `json:"sources,omitempty"`
+	IOCAdded *time.Time     `json:"iocAdded,omitempty"`
+}
+
+// Component is a concrete package version discovered in a scan, whether or
+// not it matched the IoC database.
+type Component struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// Locations are the manifests and lockfiles the version was found in
+	Locations []string `json:"locations,omitempty"`
+}
+
+// ExposureTimeline locates the commits that introduced and removed a
+// compromised version in a lockfile's git history.
+type ExposureTimeline struct {
+	IntroducedCommit string     `json:"introducedCommit"`
+	IntroducedAt     time.Time  `json:"introducedAt"`
+	RemovedCommit    string     `json:"removedCommit,omitempty"`
+	RemovedAt        *time.Time `json:"removedAt,omitempty"`
 }
 
 // ScanResult represents the complete results of a vulnerability scan.
@@ -33,4 +263,219 @@ type ScanResult struct {
 	Matches          []Match   `json:"matches"`
 	Timestamp        time.Time `json:"timestamp"`
 	IOCCount         int       `json:"iocCount"`
+	// IOCSnapshot is the date of the embedded IoC snapshot, or of the
+	// cached database, used by an offline scan; it is nil when the
+	// database was fetched
+	IOCSnapshot *time.Time `json:"iocSnapshot,omitempty"`
+	// IOCDigest identifies the IoC data the scan ran against (see
+	// ioc.Database.Digest)
+	IOCDigest string `json:"iocDigest,omitempty"`
+	// InventoriesScanned counts external package inventories such as SBOMs
+	InventoriesScanned int `json:"inventoriesScanned,omitempty"`
+	// Summary aggregates the matches; it is filled in by FormatJSON
+	Summary *Summary `json:"summary,omitempty"`
+	// Metadata holds caller-supplied key/value pairs (build ID, pipeline URL)
+	// that trace the result back to the run that produced it
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Projects holds per-project results when project segmentation is enabled
+	Projects []ProjectResult `json:"projects,omitempty"`
+	// Hygiene holds unpinned-dependency findings when the hygiene audit is enabled.
+	// They are reported separately from Matches since they are not compromises.
+	Hygiene []Match `json:"hygiene,omitempty"`
+	// UncheckedBundled holds bundledDependencies that are not installed and
+	// so could not be checked, when reporting them is enabled. They are
+	// informational and do not affect the exit code.
+	UncheckedBundled []Match `json:"uncheckedBundled,omitempty"`
+	// Shadowed holds installed packages whose version the lockfile does not
+	// resolve, when installed packages are scanned. They are warnings and do
+	// not affect the exit code; compromised installed versions are reported
+	// in Matches.
+	Shadowed []Match `json:"shadowed,omitempty"`
+	// Watchlist holds the packages whose name matches a watchlist pattern,
+	// when a watchlist is given. They are leads for investigation and do
+	// not affect the exit code.
+	Watchlist []Match `json:"watchlist,omitempty"`
+	// RegistryStatus holds the direct dependencies whose version is
+	// deprecated (DEPRECATED) or no longer published (UNPUBLISHED), when
+	// the registry is checked. They do not affect the exit code.
+	RegistryStatus []Match `json:"registryStatus,omitempty"`
+	// Provenance holds the package versions whose npm provenance is
+	// missing, does not verify or names an unexpected repository, when
+	// provenance is checked. They do not affect the exit code.
+	Provenance []Match `json:"provenance,omitempty"`
+	// Maintainers holds the package versions published or maintained by a
+	// flagged npm account (MAINTAINER), when maintainer IoCs are given.
+	// They do not affect the exit code.
+	Maintainers []Match `json:"maintainers,omitempty"`
+	// Typosquats holds the declared dependencies whose name resembles a
+	// popular package (WARNING), when typosquat detection is enabled. They
+	// do not affect the exit code.
+	Typosquats []Match `json:"typosquats,omitempty"`
+	// PolicyViolations holds the packages whose version does not satisfy a
+	// required safe version range, when requirements are given. Unlike the
+	// informational findings above, they fail the scan.
+	PolicyViolations []Match `json:"policyViolations,omitempty"`
+	// Unapproved holds the packages that are not on the allowlist, when
+	// one is given. Like policy violations, they fail the scan.
+	Unapproved []Match `json:"unapproved,omitempty"`
+	// Scripts holds the package.json scripts running a command the IoC
+	// database lists as malicious (SCRIPT), in scanned manifests and, when
+	// installed packages are scanned, in node_modules. Like policy
+	// violations, they fail the scan.
+	Scripts []Match `json:"scripts,omitempty"`
+	// LockfileAges holds lockfile staleness information when age reporting is enabled
+	LockfileAges []LockfileAge `json:"lockfileAges,omitempty"`
+	// Warnings holds the dependency files that could not be read or parsed.
+	// Their dependencies were not checked, so the result is incomplete.
+	Warnings []FileError `json:"warnings,omitempty"`
+	// Suppressed holds matches acknowledged in the suppression file. They
+	// are reported but do not affect the exit code.
+	Suppressed []Match `json:"suppressed,omitempty"`
+	// NotScanned is why the scan could not run, such as an IoC database
+	// that could not be fetched. It is set only when scanning soft-fails;
+	// the result then holds no matches.
+	NotScanned string `json:"notScanned,omitempty"`
+	// DependencyStats breaks PackagesChecked down by dependency type and
+	// by the kind of file the dependencies were read from
+	DependencyStats *DependencyStats `json:"dependencyStats,omitempty"`
+}
+
+// DependencyStats counts the dependencies checked in a scan by type,
+// separately for each kind of file they were read from.
+type DependencyStats struct {
+	// Manifest counts package.json declarations
+	Manifest DependencyCounts `json:"manifest"`
+	// Lockfile counts resolved lockfile entries
+	Lockfile DependencyCounts `json:"lockfile"`
+	// Inventory counts packages scanned as already resolved, such as the
+	// components of an SBOM
+	Inventory *DependencyCounts `json:"inventory,omitempty"`
+}
+
+// Add adds the counts of other to s.
+func (s *DependencyStats) Add(other *DependencyStats) {
+	if other == nil {
+		return
+	}
+	s.Manifest.Add(other.Manifest)
+	s.Lockfile.Add(other.Lockfile)
+	if other.Inventory != nil {
+		if s.Inventory == nil {
+			s.Inventory = &DependencyCounts{}
+		}
+		s.Inventory.Add(*other.Inventory)
+	}
+}
+
+// DependencyCounts counts dependencies by type.
+type DependencyCounts struct {
+	Prod     int `json:"prod"`
+	Dev      int `json:"dev"`
+	Peer     int `json:"peer"`
+	Optional int `json:"optional"`
+	// Bundled counts bundledDependencies entries of manifests
+	Bundled int `json:"bundled,omitempty"`
+	// Unknown counts entries whose file records no type, such as yarn.lock
+	// and SBOM entries
+	Unknown int `json:"unknown,omitempty"`
+}
+
+// Add adds the counts of other to c.
+func (c *DependencyCounts) Add(other DependencyCounts) {
+	c.Prod += other.Prod
+	c.Dev += other.Dev
+	c.Peer += other.Peer
+	c.Optional += other.Optional
+	c.Bundled += other.Bundled
+	c.Unknown += other.Unknown
+}
+
+// Total returns the number of dependencies counted.
+func (c DependencyCounts) Total() int {
+	return c.Prod + c.Dev + c.Peer + c.Optional + c.Bundled + c.Unknown
+}
+
+// NotScannedResult returns the result of a scan that could not run
+// because of err.
+func NotScannedResult(err error) *ScanResult {
+	return &ScanResult{
+		Matches:    []Match{},
+		Timestamp:  time.Now().UTC(),
+		NotScanned: err.Error(),
+	}
+}
+
+// RootResult pairs a scan root with its scan result, for invocations
+// that scan several paths at once.
+type RootResult struct {
+	Path   string      `json:"path"`
+	Result *ScanResult `json:"result"`
+}
+
+// ProjectResult summarizes the findings for a single project within a
+// larger scan, such as one workspace package of a monorepo.
+type ProjectResult struct {
+	Path             string  `json:"path"`
+	Name             string  `json:"name,omitempty"`
+	ManifestsScanned int     `json:"manifestsScanned"`
+	LockfilesScanned int     `json:"lockfilesScanned"`
+	PackagesChecked  int     `json:"packagesChecked"`
+	Matches          []Match `json:"matches"`
+}
+
+// ProjectStats describes the dependency exposure of a single project,
+// independent of any IoC matches.
+type ProjectStats struct {
+	Path                 string  `json:"path"`
+	Name                 string  `json:"name,omitempty"`
+	DeclaredDependencies int     `json:"declaredDependencies"`
+	WideRanges           int     `json:"wideRanges"`
+	WideRangeRatio       float64 `json:"wideRangeRatio"`
+	ResolvedDependencies int     `json:"resolvedDependencies"`
+}
+
+// DiscoveryStats describes the dependency files below a set of scan paths
+// and estimates how long scanning them takes, without scanning, to plan
+// fleet sweeps.
+type DiscoveryStats struct {
+	Paths         int   `json:"paths"`
+	Manifests     int   `json:"manifests"`
+	ManifestBytes int64 `json:"manifestBytes"`
+	Lockfiles     int   `json:"lockfiles"`
+	LockfileBytes int64 `json:"lockfileBytes"`
+	// NodeModules counts the node_modules trees next to the dependency
+	// files; InstalledPackages the packages in them, when counted
+	NodeModules       int `json:"nodeModules"`
+	InstalledPackages int `json:"installedPackages,omitempty"`
+	// DiscoverySeconds is the time a scan spends walking the paths
+	DiscoverySeconds float64 `json:"discoverySeconds"`
+	// ParseBytesPerSecond is the measured parse and match throughput of
+	// a single worker
+	ParseBytesPerSecond float64 `json:"parseBytesPerSecond"`
+	// Estimates lists the estimated scan duration per worker count,
+	// excluding the IoC database fetch
+	Estimates []ScanEstimate `json:"estimates"`
+}
+
+// ScanEstimate is the estimated duration of a scan with a number of
+// workers.
+type ScanEstimate struct {
+	Workers int     `json:"workers"`
+	Seconds float64 `json:"seconds"`
+}
+
+// FileError is a dependency file a scan skipped because it could not be
+// read or parsed.
+type FileError struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// LockfileAge describes when a lockfile was last regenerated.
+type LockfileAge struct {
+	Path         string    `json:"path"`
+	LastModified time.Time `json:"lastModified"`
+	Source       string    `json:"source"` // "git" or "mtime"
+	AgeDays      int       `json:"ageDays"`
+	Stale        bool      `json:"stale"` // Last modified before the IoC campaign window
 }
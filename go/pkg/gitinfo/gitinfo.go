@@ -0,0 +1,166 @@
+// Package gitinfo provides read-only queries against git repositories,
+// used to enrich scan results with file history.
+// All queries shell out to the git binary and fail gracefully when git is
+// unavailable or the path is not inside a work tree.
+package gitinfo
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNotTracked is returned when a file has no git history.
+var ErrNotTracked = errors.New("file is not tracked by git")
+
+// run executes git with the given arguments in dir and returns trimmed stdout.
+func run(dir string, args ...string) (string, error) {
+	out, err := output(dir, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// output executes git with the given arguments in dir and returns raw stdout.
+func output(dir string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return nil, fmt.Errorf("git %s: %s", args[0], msg)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// LastCommitTime returns the committer date of the most recent commit that
+// touched the file at path. Returns ErrNotTracked if the file has no history.
+func LastCommitTime(path string) (time.Time, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	out, err := run(dir, "log", "-1", "--format=%cI", "--", name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if out == "" {
+		return time.Time{}, ErrNotTracked
+	}
+
+	return time.Parse(time.RFC3339, out)
+}
+
+// Commit identifies a commit in a file's history.
+type Commit struct {
+	Hash string
+	Time time.Time
+}
+
+// FileHistory returns the commits that touched the file at path, oldest
+// first. Returns ErrNotTracked if the file has no history.
+func FileHistory(path string) ([]Commit, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	out, err := run(dir, "log", "--reverse", "--format=%H %cI", "--", name)
+	if err != nil {
+		return nil, err
+	}
+	if out == "" {
+		return nil, ErrNotTracked
+	}
+
+	var commits []Commit
+	for _, line := range strings.Split(out, "\n") {
+		hash, date, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, date)
+		if err != nil {
+			return nil, fmt.Errorf("parse commit date %q: %w", date, err)
+		}
+		commits = append(commits, Commit{Hash: hash, Time: t})
+	}
+
+	return commits, nil
+}
+
+// FileAt returns the contents of the file at path as of the given commit.
+func FileAt(path, commit string) ([]byte, error) {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	return output(dir, "show", commit+":./"+name)
+}
+
+// Repo describes the state of the git repository containing a directory.
+type Repo struct {
+	// RemoteURL is the URL of the origin remote (or the first remote if
+	// there is no origin), with any credentials removed
+	RemoteURL string
+	// Branch is the checked out branch; empty for a detached HEAD
+	Branch string
+	// Head is the full SHA of the HEAD commit
+	Head string
+}
+
+// Describe returns the remote URL, branch and HEAD commit of the repository
+// containing dir. Returns an error if dir is not in a work tree or the
+// repository has no commits.
+func Describe(dir string) (Repo, error) {
+	head, err := run(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return Repo{}, err
+	}
+
+	repo := Repo{Head: head}
+
+	if branch, err := run(dir, "rev-parse", "--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		repo.Branch = branch
+	}
+
+	remote, err := run(dir, "remote", "get-url", "origin")
+	if err != nil {
+		// Fall back to the first configured remote
+		if remotes, err := run(dir, "remote"); err == nil && remotes != "" {
+			name, _, _ := strings.Cut(remotes, "\n")
+			remote, _ = run(dir, "remote", "get-url", name)
+		}
+	}
+	repo.RemoteURL = stripCredentials(remote)
+
+	return repo, nil
+}
+
+// stripCredentials removes the userinfo from URL-style remotes so tokens
+// embedded in clone URLs do not leak into reports. scp-style remotes
+// (git@host:org/repo.git) are returned unchanged.
+func stripCredentials(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || u.User == nil || u.Host == "" {
+		return remote
+	}
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,182 @@
+// Package ignore excludes paths from file discovery through gitignore-style
+// patterns, read from an ignore file (.npmscanignore) at the scan root and
+// given on the command line:
+//
+//	# Comments and blank lines are ignored
+//	fixtures/*
+//	/test/**/package-lock.json
+//	!fixtures/keep/
+//
+// A pattern without a slash matches a file or directory name at any depth;
+// one with a slash is relative to the scan root. A trailing slash matches
+// directories only, "**" matches any number of directories, and a leading
+// "!" re-includes paths excluded by an earlier pattern. The last matching
+// pattern wins. Everything below an excluded directory is excluded, and
+// cannot be re-included.
+//
+// Unlike the suppression file (.npmscan-ignore.yaml), which acknowledges
+// findings, excluded files are not scanned at all.
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFile is the ignore file read from the root of a scan.
+const DefaultFile = ".npmscanignore"
+
+// pattern is a single parsed ignore pattern.
+type pattern struct {
+	// text is the pattern as written, for error messages
+	text    string
+	negate  bool
+	dirOnly bool
+	// segments are the slash-separated parts matched against a path
+	// relative to the root; unanchored patterns start with "**"
+	segments []string
+}
+
+// Matcher decides which paths below a root are excluded. A nil Matcher
+// excludes nothing.
+type Matcher struct {
+	patterns []pattern
+}
+
+// New parses patterns, one per element, skipping blank lines and comments.
+func New(patterns []string) (*Matcher, error) {
+	m := &Matcher{}
+	for _, line := range patterns {
+		p, ok, err := parsePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m.patterns = append(m.patterns, p)
+		}
+	}
+	return m, nil
+}
+
+// Parse parses the contents of an ignore file.
+func Parse(data []byte) (*Matcher, error) {
+	return New(strings.Split(string(data), "\n"))
+}
+
+// Load reads the ignore file in root, if any, followed by the extra
+// patterns, which thus take precedence. root may also be a file, whose
+// directory is used then.
+func Load(root string, extra []string) (*Matcher, error) {
+	dir := root
+	if info, err := os.Stat(root); err == nil && !info.IsDir() {
+		dir = filepath.Dir(root)
+	}
+
+	var lines []string
+	data, err := os.ReadFile(filepath.Join(dir, DefaultFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read ignore file: %w", err)
+	}
+	if err == nil {
+		lines = strings.Split(string(data), "\n")
+	}
+
+	return New(append(lines, extra...))
+}
+
+// parsePattern parses one line. ok is false for blank lines and comments.
+func parsePattern(line string) (pattern, bool, error) {
+	text := strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+	if text == "" || strings.HasPrefix(text, "#") {
+		return pattern{}, false, nil
+	}
+
+	p := pattern{text: text}
+	if strings.HasPrefix(text, "!") {
+		p.negate = true
+		text = text[1:]
+	}
+	if strings.HasSuffix(text, "/") {
+		p.dirOnly = true
+		text = strings.TrimRight(text, "/")
+	}
+	anchored := strings.Contains(text, "/")
+	text = strings.TrimPrefix(text, "/")
+	if text == "" {
+		return pattern{}, false, fmt.Errorf("invalid ignore pattern %q", p.text)
+	}
+
+	p.segments = strings.Split(text, "/")
+	if !anchored {
+		p.segments = append([]string{"**"}, p.segments...)
+	}
+	for _, segment := range p.segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return pattern{}, false, fmt.Errorf("invalid ignore pattern %q: %w", p.text, err)
+		}
+	}
+	return p, true, nil
+}
+
+// Match reports whether the slash-separated path rel, relative to the
+// root, is excluded, either itself or through an excluded parent
+// directory. isDir tells whether rel is a directory.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	rel = strings.Trim(path.Clean(rel), "/")
+	if m == nil || len(m.patterns) == 0 || rel == "." || rel == "" {
+		return false
+	}
+
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchPath(parts[:i], true) {
+			return true
+		}
+	}
+	return m.matchPath(parts, isDir)
+}
+
+// matchPath applies the patterns to a single path, the last matching
+// pattern deciding.
+func (m *Matcher) matchPath(parts []string, isDir bool) bool {
+	excluded := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(p.segments, parts) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// matchSegments matches path segments against pattern segments, where a
+// "**" segment matches zero or more path segments.
+func matchSegments(pattern, parts []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(parts); i++ {
+				if matchSegments(pattern, parts[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(parts) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], parts[0]); !ok {
+			return false
+		}
+		pattern, parts = pattern[1:], parts[1:]
+	}
+	return len(parts) == 0
+}
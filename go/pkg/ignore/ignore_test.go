@@ -0,0 +1,88 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMatch tests gitignore-style pattern semantics
+func TestMatch(t *testing.T) {
+	m, err := Parse([]byte(`# Vendored fixtures
+fixtures/*
+!fixtures/keep/
+
+/test/**/package-lock.json
+*.tmp
+build/
+`))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"fixtures/old", true, true},
+		{"fixtures/old/package.json", false, true},
+		{"fixtures/keep", true, false},
+		{"fixtures/keep/package.json", false, false},
+		{"fixtures", true, false},
+		{"test/package-lock.json", false, true},
+		{"test/a/b/package-lock.json", false, true},
+		{"src/test/package-lock.json", false, false},
+		{"test/yarn.lock", false, false},
+		{"a/b/notes.tmp", false, true},
+		{"packages/build", true, true},
+		{"packages/build/package.json", false, true},
+		{"packages/build", false, false},
+		{"package.json", false, false},
+		{".", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	var none *Matcher
+	if none.Match("anything", false) {
+		t.Error("nil Matcher should exclude nothing")
+	}
+}
+
+// TestNew_Errors tests that malformed patterns are rejected
+func TestNew_Errors(t *testing.T) {
+	for _, pattern := range []string{"/", "!", "src/[a-"} {
+		if _, err := New([]string{pattern}); err == nil {
+			t.Errorf("New(%q) expected an error", pattern)
+		}
+	}
+}
+
+// TestLoad tests that command-line patterns follow the ignore file
+func TestLoad(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, DefaultFile), []byte("vendor/\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := Load(root, []string{"!vendor/", "e2e/"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Match("vendor", true) {
+		t.Error("expected --exclude patterns to override the ignore file")
+	}
+	if !m.Match("e2e", true) {
+		t.Error("expected e2e to be excluded")
+	}
+
+	m, err = Load(t.TempDir(), nil)
+	if err != nil || m.Match("vendor", true) {
+		t.Errorf("Load() without an ignore file = %v, %v; want an empty matcher", m, err)
+	}
+}
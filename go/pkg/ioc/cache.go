@@ -0,0 +1,206 @@
+package ioc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// SnapshotURL stands for the IoC snapshot embedded in the binary in a
+// DBInfo's URL.
+const SnapshotURL = "embedded"
+
+// ErrNotCached is returned by DBCache lookups for a database that was never
+// stored.
+var ErrNotCached = errors.New("no cached IoC database (run npm-scan db update)")
+
+// DBCache keeps fetched copies of IoC databases on disk, one per URL,
+// so operations teams can refresh the database explicitly (npm-scan db
+// update) and scan offline against a copy fresher than the embedded
+// snapshot. A nil DBCache holds nothing.
+type DBCache struct {
+	dir string
+}
+
+// DBInfo describes a stored IoC database.
+type DBInfo struct {
+	// URL is the feed the database was fetched from, or SnapshotURL
+	URL string `json:"url"`
+	// FetchedAt is when the database was fetched, or the date the embedded
+	// snapshot was taken
+	FetchedAt time.Time `json:"fetchedAt"`
+	// SHA256 is the hex digest of the data
+	SHA256  string `json:"sha256"`
+	Size    int    `json:"size"`
+	Entries int    `json:"entries"`
+	// Format is the format of the data; empty means FormatCSV
+	Format string `json:"format,omitempty"`
+}
+
+// DefaultDBCacheDir returns the directory of the IoC database cache in the
+// user cache directory.
+func DefaultDBCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate cache directory: %w", err)
+	}
+	return filepath.Join(base, "npm-scan", "ioc"), nil
+}
+
+// OpenDBCache returns the cache stored in dir. The directory is created
+// when the first database is stored.
+func OpenDBCache(dir string) *DBCache {
+	return &DBCache{dir: dir}
+}
+
+// Store saves the data in format fetched from url at fetchedAt, replacing
+// any earlier copy, and returns its description.
+func (c *DBCache) Store(url string, data []byte, format string, fetchedAt time.Time) (DBInfo, error) {
+	if c == nil {
+		return DBInfo{}, errors.New("no IoC database cache")
+	}
+	entries, err := ParseFeed(data, format)
+	if err != nil {
+		return DBInfo{}, fmt.Errorf("parse IoC database: %w", err)
+	}
+
+	info := DBInfo{
+		URL:       cacheURL(url),
+		FetchedAt: fetchedAt.UTC(),
+		SHA256:    sha256Hex(data),
+		Size:      len(data),
+		Entries:   len(entries),
+		Format:    format,
+	}
+	meta, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return DBInfo{}, err
+	}
+
+	base := c.base(url)
+	if err := readonly.MkdirAll(c.dir, 0755); err != nil {
+		return DBInfo{}, err
+	}
+	if err := readonly.WriteFile(base+".data", data, 0644); err != nil {
+		return DBInfo{}, err
+	}
+	if err := readonly.WriteFile(base+".json", meta, 0644); err != nil {
+		return DBInfo{}, err
+	}
+	return info, nil
+}
+
+// Info returns the description of the copy of url, or ErrNotCached.
+func (c *DBCache) Info(url string) (DBInfo, error) {
+	if c == nil {
+		return DBInfo{}, ErrNotCached
+	}
+	meta, err := os.ReadFile(c.base(url) + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return DBInfo{}, ErrNotCached
+	}
+	if err != nil {
+		return DBInfo{}, fmt.Errorf("read cached IoC database: %w", err)
+	}
+	var info DBInfo
+	if err := json.Unmarshal(meta, &info); err != nil {
+		return DBInfo{}, fmt.Errorf("parse cached IoC database description: %w", err)
+	}
+	return info, nil
+}
+
+// Load returns the data of the copy of url and its description, or
+// ErrNotCached. Data that no longer matches its recorded checksum is an
+// error.
+func (c *DBCache) Load(url string) ([]byte, DBInfo, error) {
+	info, err := c.Info(url)
+	if err != nil {
+		return nil, DBInfo{}, err
+	}
+	data, err := os.ReadFile(c.base(url) + ".data")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, DBInfo{}, ErrNotCached
+	}
+	if err != nil {
+		return nil, DBInfo{}, fmt.Errorf("read cached IoC database: %w", err)
+	}
+	if sum := sha256Hex(data); sum != info.SHA256 {
+		return nil, DBInfo{}, fmt.Errorf("cached IoC database of %s is corrupt: sha256 %s, recorded %s", info.URL, sum, info.SHA256)
+	}
+	return data, info, nil
+}
+
+// Verify checks the copy of url: its data must match its recorded
+// checksum and still parse to the recorded number of entries.
+func (c *DBCache) Verify(url string) (DBInfo, error) {
+	data, info, err := c.Load(url)
+	if err != nil {
+		return DBInfo{}, err
+	}
+	entries, err := ParseFeed(data, info.Format)
+	if err != nil {
+		return DBInfo{}, fmt.Errorf("cached IoC database of %s does not parse: %w", info.URL, err)
+	}
+	if len(entries) != info.Entries {
+		return DBInfo{}, fmt.Errorf("cached IoC database of %s has %d entries, recorded %d", info.URL, len(entries), info.Entries)
+	}
+	return info, nil
+}
+
+// base returns the path of the copy of url, without extension.
+func (c *DBCache) base(url string) string {
+	sum := sha256.Sum256([]byte(cacheURL(url)))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:8]))
+}
+
+// cacheURL returns the feed url stands for.
+func cacheURL(url string) string {
+	if strings.TrimSpace(url) == "" {
+		return DefaultIoCURL
+	}
+	return url
+}
+
+// SnapshotInfo describes the IoC snapshot embedded in the binary, or
+// returns ErrNoSnapshot.
+func SnapshotInfo() (DBInfo, error) {
+	_, taken, err := LoadSnapshot()
+	if err != nil {
+		return DBInfo{}, err
+	}
+	entries, err := ParseEntries(snapshotCSV)
+	if err != nil {
+		return DBInfo{}, fmt.Errorf("parse IoC snapshot: %w", err)
+	}
+	return DBInfo{
+		URL:       SnapshotURL,
+		FetchedAt: taken,
+		SHA256:    sha256Hex(snapshotCSV),
+		Size:      len(snapshotCSV),
+		Entries:   len(entries),
+		Format:    FormatCSV,
+	}, nil
+}
+
+// SnapshotData returns the CSV data of the IoC snapshot embedded in the
+// binary, or ErrNoSnapshot.
+func SnapshotData() ([]byte, error) {
+	if _, _, err := LoadSnapshot(); err != nil {
+		return nil, err
+	}
+	return snapshotCSV, nil
+}
+
+// sha256Hex returns the hex SHA-256 digest of data.
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package ioc
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Feed check modes.
+const (
+	// FeedCheckStrict rejects feeds failing the sanity checks (the default)
+	FeedCheckStrict = "strict"
+	// FeedCheckWarn uses feeds failing the sanity checks after a warning
+	FeedCheckWarn = "warn"
+	// FeedCheckOff skips the sanity checks
+	FeedCheckOff = "off"
+)
+
+// DefaultMinRows is the fewest rows expected from DefaultIoCURL. The feed
+// lists close to 800 packages, so a much smaller download is almost
+// certainly incomplete.
+const DefaultMinRows = 500
+
+// utf8BOM may precede the header of CSVs exported from spreadsheets.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// FeedCheck configures the sanity checks run on a fetched IoC CSV before
+// it is trusted, so that a proxy error page or a truncated download is not
+// mistaken for a small database that matches nothing.
+type FeedCheck struct {
+	// Mode is FeedCheckStrict (when empty), FeedCheckWarn or FeedCheckOff
+	Mode string
+	// MinRows is the fewest data rows (packages in JSON and YAML feeds)
+	// accepted. Zero means DefaultMinRows for DefaultIoCURL and 1 for
+	// other feeds.
+	MinRows int
+
+	// SHA256 pins the hex SHA-256 digest of the feed. Empty accepts any
+	// content. Checked by Verify regardless of Mode.
+	SHA256 string
+	// PublicKey, when set, verifies a detached signature of the feed
+	// fetched from SignatureURL. Checked by Verify regardless of Mode.
+	PublicKey crypto.PublicKey
+	// SignatureURL locates the signature. Empty means the feed URL with
+	// ".sig" appended, as published next to cosign sign-blob output.
+	SignatureURL string
+}
+
+// ParseFeedCheckMode validates a feed check mode. An empty mode selects
+// FeedCheckStrict.
+func ParseFeedCheckMode(mode string) (string, error) {
+	switch mode {
+	case "":
+		return FeedCheckStrict, nil
+	case FeedCheckStrict, FeedCheckWarn, FeedCheckOff:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid feed check %q (expected %s, %s or %s)", mode, FeedCheckStrict, FeedCheckWarn, FeedCheckOff)
+}
+
+// Check runs the sanity checks on CSV data fetched from url: the data must
+// not be an HTML page, must start with a Package,Version header, must end
+// with a newline and must have at least MinRows data rows. The error lists
+// every failed check. Check returns nil when Mode is FeedCheckOff; handling
+// FeedCheckWarn is up to the caller.
+func (c FeedCheck) Check(data []byte, url string) error {
+	return c.CheckFormat(data, url, FormatCSV)
+}
+
+// CheckFormat is Check for data in format. JSON and YAML data must not be
+// an HTML page, must parse, and must list at least MinRows packages; YAML
+// must also end with a newline.
+func (c FeedCheck) CheckFormat(data []byte, url, format string) error {
+	if c.Mode == FeedCheckOff {
+		return nil
+	}
+
+	data = bytes.TrimPrefix(data, utf8BOM)
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return errors.New("feed is empty")
+	}
+	if trimmed[0] == '<' {
+		return errors.New("feed is an HTML or XML page, not an IoC database (an error page served by a proxy?)")
+	}
+
+	if format != "" && format != FormatCSV {
+		return c.checkStructured(data, url, format)
+	}
+
+	var problems []string
+
+	header, err := csv.NewReader(bytes.NewReader(data)).Read()
+	if err != nil || len(header) < 2 ||
+		!strings.EqualFold(strings.TrimSpace(header[0]), "package") ||
+		!strings.EqualFold(strings.TrimSpace(header[1]), "version") {
+		firstLine, _, _ := strings.Cut(string(trimmed), "\n")
+		problems = append(problems, fmt.Sprintf("unexpected header %q (expected Package,Version)", strings.TrimSpace(firstLine)))
+	}
+
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		problems = append(problems, "feed does not end with a newline, the download may be truncated")
+	}
+
+	entries, err := ParseEntries(data)
+	if err != nil {
+		return fmt.Errorf("feed is not valid CSV: %w", err)
+	}
+	rows := make(map[int]bool)
+	for _, entry := range entries {
+		rows[entry.Row] = true
+	}
+	if minRows := c.minRows(url); len(rows) < minRows {
+		problems = append(problems, fmt.Sprintf("feed has %d rows, expected at least %d", len(rows), minRows))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkStructured runs the sanity checks on JSON or YAML data that is not
+// empty and not an HTML page.
+func (c FeedCheck) checkStructured(data []byte, url, format string) error {
+	entries, err := ParseFeed(data, format)
+	if err != nil {
+		return fmt.Errorf("feed is not valid %s: %w", strings.ToUpper(format), err)
+	}
+
+	var problems []string
+	if format == FormatYAML && !bytes.HasSuffix(data, []byte("\n")) {
+		problems = append(problems, "feed does not end with a newline, the download may be truncated")
+	}
+	packages := make(map[string]bool)
+	for _, entry := range entries {
+		packages[entry.Package] = true
+	}
+	if minRows := c.minRows(url); len(packages) < minRows {
+		problems = append(problems, fmt.Sprintf("feed lists %d packages, expected at least %d", len(packages), minRows))
+	}
+
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// minRows returns the fewest data rows accepted from url.
+func (c FeedCheck) minRows(url string) int {
+	switch {
+	case c.MinRows > 0:
+		return c.MinRows
+	case url == "" || url == DefaultIoCURL:
+		return DefaultMinRows
+	}
+	return 1
+}
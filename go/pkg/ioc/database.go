@@ -1,15 +1,75 @@
 package ioc
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"sync"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
 )
 
 // Database represents an in-memory IoC database of compromised packages.
 // It stores package names mapped to lists of compromised versions.
+//
+// The entries are held in an immutable snapshot that lookups read through an
+// atomic pointer, so concurrent scans never contend for a lock. Replace swaps
+// in the entries of a reloaded database at once; a lookup sees either the
+// old or the new entries, never a mix. Snapshot pins the current entries for
+// a scan that must see one version throughout.
 type Database struct {
-	ioc map[string][]string
-	mu  sync.RWMutex
+	current atomic.Pointer[snapshot]
+}
+
+// snapshot is one immutable version of a Database's entries. It is never
+// modified once published.
+type snapshot struct {
+	// entries are the entries as listed by their sources; the same package
+	// version reported by several sources appears once per source
+	entries []Entry
+	ioc     map[string][]string
+	// ranges holds range-based entries per package
+	ranges map[string][]rangeEntry
+	// scopes holds compromised-scope entries ("@scope/*") per scope, listing
+	// versions of every package under the scope
+	scopes map[string][]scopeEntry
+	// added records when each package@version entry was added, for sources
+	// that carry a date column
+	added map[string]time.Time
+	// hashes maps normalized tarball hashes ("sha512:<hex>") to the entry
+	// they were listed for
+	hashes map[string]Entry
+	// scripts holds the malicious script indicators of the entries, each
+	// pattern once
+	scripts []scriptIndicator
+	// advisories records the advisory behind each entry, for sources that
+	// provide one
+	advisories map[string]Advisory
+	// sources records which sources listed each entry, correlating reports
+	// of the same package version across feeds
+	sources map[string][]SourceRecord
+}
+
+// SourceRecord is one source's report of a compromised package version.
+type SourceRecord struct {
+	// Source names the feed, such as SourceCSV or SourceOSV
+	Source string
+	// Range is the affected range of the entry, for range-based entries
+	Range    string
+	Advisory Advisory
+	Added    time.Time
+	// Row is the line of the first entry of the source listing the version
+	Row int
+}
+
+// rangeEntry is a parsed range-based IoC entry.
+type rangeEntry struct {
+	spec string
+	r    npmsemver.Range
 }
 
 // NewDatabase creates a new Database from raw CSV data.
@@ -23,82 +83,311 @@ type Database struct {
 //
 // Returns an error if the CSV data cannot be parsed.
 func NewDatabase(csvData []byte) (*Database, error) {
-	iocMap, err := ParseCSV(csvData)
+	entries, err := ParseEntries(csvData)
 	if err != nil {
 		return nil, fmt.Errorf("parse CSV: %w", err)
 	}
+	for i := range entries {
+		entries[i].Source = SourceCSV
+	}
+
+	return NewDatabaseFromEntries(entries), nil
+}
+
+// NewDatabaseFormat is NewDatabase for data in format (FormatCSV,
+// FormatJSON or FormatYAML). The entries are attributed to SourceCSV, the
+// source of --csv-url feeds, whatever their format.
+func NewDatabaseFormat(data []byte, format string) (*Database, error) {
+	entries, err := ParseFeed(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("parse IoC database: %w", err)
+	}
+	for i := range entries {
+		entries[i].Source = SourceCSV
+	}
+
+	return NewDatabaseFromEntries(entries), nil
+}
+
+// NewDatabaseFromEntries builds a Database from already parsed entries.
+//
+// Entries for the same package version (or range) are correlated: the
+// version is listed once, advisories are merged, and each distinct Source
+// is recorded (see SourcesFor). Entries named "@scope/*" list the version
+// or range, or with AnyVersion every version, of every package under the
+// scope.
+func NewDatabaseFromEntries(entries []Entry) *Database {
+	s := &snapshot{
+		ioc:        make(map[string][]string),
+		ranges:     make(map[string][]rangeEntry),
+		scopes:     make(map[string][]scopeEntry),
+		added:      make(map[string]time.Time),
+		hashes:     make(map[string]Entry),
+		advisories: make(map[string]Advisory),
+		sources:    make(map[string][]SourceRecord),
+	}
+	for _, entry := range entries {
+		if scope, ok := ScopePattern(entry.Package); ok {
+			scoped, err := newScopeEntry(entry)
+			if err != nil {
+				continue
+			}
+			if !s.hasScope(scope, scoped.key) {
+				s.scopes[scope] = append(s.scopes[scope], scoped)
+			}
+		} else if entry.Range != "" {
+			r, err := npmsemver.ParseRange(entry.Range)
+			if err != nil {
+				continue
+			}
+			if !s.hasRange(entry.Package, entry.Range) {
+				s.ranges[entry.Package] = append(s.ranges[entry.Package], rangeEntry{spec: entry.Range, r: r})
+			}
+		} else if !containsString(s.ioc[entry.Package], entry.Version) {
+			s.ioc[entry.Package] = append(s.ioc[entry.Package], entry.Version)
+		}
+		s.entries = append(s.entries, entry)
+		if !entry.Added.IsZero() {
+			s.added[entry.key()] = entry.Added
+		}
+		if !entry.Advisory.IsZero() {
+			s.advisories[entry.key()] = s.advisories[entry.key()].merge(entry.Advisory)
+		}
+		if entry.Source != "" {
+			s.recordSource(entry)
+		}
+		for _, hash := range entry.Hashes {
+			for _, key := range hashKeys(hash) {
+				if _, exists := s.hashes[key]; !exists {
+					s.hashes[key] = entry
+				}
+			}
+		}
+		for _, script := range entry.Scripts {
+			s.addScript(script, entry)
+		}
+	}
+	d := &Database{}
+	d.current.Store(s)
+	return d
+}
+
+// load returns the current snapshot of the database.
+func (d *Database) load() *snapshot {
+	return d.current.Load()
+}
+
+// Snapshot returns a Database fixed to the current entries, unaffected by
+// later calls to Replace on d, for a scan that must match against a single
+// version of the database.
+func (d *Database) Snapshot() *Database {
+	pinned := &Database{}
+	pinned.current.Store(d.load())
+	return pinned
+}
+
+// Replace atomically swaps in the entries of next, such as a freshly
+// reloaded feed. Lookups in progress finish against the previous entries;
+// Databases returned by Snapshot keep them.
+func (d *Database) Replace(next *Database) {
+	d.current.Store(next.load())
+}
+
+// hasRange reports whether spec is already listed for pkg.
+func (s *snapshot) hasRange(pkg, spec string) bool {
+	for _, entry := range s.ranges[pkg] {
+		if entry.spec == spec {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSource records that entry.Source listed the entry, merging repeated
+// rows of one source.
+func (s *snapshot) recordSource(entry Entry) {
+	key := entry.key()
+	for i, record := range s.sources[key] {
+		if record.Source == entry.Source {
+			s.sources[key][i].Advisory = record.Advisory.merge(entry.Advisory)
+			return
+		}
+	}
+	s.sources[key] = append(s.sources[key], SourceRecord{
+		Source:   entry.Source,
+		Range:    entry.Range,
+		Advisory: entry.Advisory,
+		Added:    entry.Added,
+		Row:      entry.Row,
+	})
+}
 
-	return &Database{
-		ioc: iocMap,
-	}, nil
+// entryKey returns the key used to index per-entry metadata.
+func entryKey(pkg, ver string) string {
+	return pkg + "@" + ver
 }
 
 // Lookup checks if a package at a specific version exists in the IoC database.
-// Returns true if the exact package and version combination is found, false otherwise.
-// The lookup is case-sensitive and exact-match only.
+// Returns true if the exact package and version combination is found, if
+// the version falls in one of the package's affected ranges, or if the
+// package is under a compromised scope listing the version.
+// The lookup is case-sensitive.
 //
 // Example:
 //
 //	db.Lookup("02-echo", "0.0.7")        // true (if in database)
 //	db.Lookup("02-echo", "0.0.8")        // false (version mismatch)
+//	db.Lookup("lodash", "4.17.20")       // true (if listed as "< 4.17.21")
+//	db.Lookup("@ctrl/tinycolor", "4.1.1") // true (if "@ctrl/*" is listed as "*")
 //	db.Lookup("nonexistent", "1.0.0")    // false (package not found)
 func (d *Database) Lookup(pkg, ver string) bool {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	s := d.load()
 
-	versions, exists := d.ioc[pkg]
-	if !exists {
-		return false
+	return s.lookup(pkg, ver)
+}
+
+// LookupBatch answers Lookup for each of pairs, in order, against a single
+// snapshot of the database, for callers checking the thousands of resolved
+// packages of a lockfile.
+func (d *Database) LookupBatch(pairs []PackageVersion) []bool {
+	s := d.load()
+
+	listed := make([]bool, len(pairs))
+	for i, pair := range pairs {
+		listed[i] = s.lookup(pair.Name, pair.Version)
 	}
+	return listed
+}
+
+// LookupSet is LookupBatch returning the set of pairs that are listed, for
+// callers that look up package versions by identity rather than position.
+// Pairs that are not listed are absent from the set.
+func (d *Database) LookupSet(pairs []PackageVersion) map[PackageVersion]bool {
+	s := d.load()
+
+	listed := make(map[PackageVersion]bool)
+	for _, pair := range pairs {
+		if s.lookup(pair.Name, pair.Version) {
+			listed[pair] = true
+		}
+	}
+	return listed
+}
 
-	for _, v := range versions {
+// lookup implements Lookup.
+func (s *snapshot) lookup(pkg, ver string) bool {
+	for _, v := range s.ioc[pkg] {
 		if v == ver {
 			return true
 		}
 	}
 
-	return false
+	if _, ok := s.matchRange(pkg, ver); ok {
+		return true
+	}
+	_, ok := s.matchScope(pkg, ver)
+	return ok
 }
 
-// Count returns the total number of unique packages in the IoC database.
+// MatchedRange returns the affected range that lists pkg@ver, for versions
+// that are in the database only through a range-based entry.
+func (d *Database) MatchedRange(pkg, ver string) (string, bool) {
+	s := d.load()
+
+	for _, v := range s.ioc[pkg] {
+		if v == ver {
+			return "", false
+		}
+	}
+	return s.matchRange(pkg, ver)
+}
+
+// matchRange returns the first range of pkg that ver satisfies.
+func (s *snapshot) matchRange(pkg, ver string) (string, bool) {
+	ranges := s.ranges[pkg]
+	if len(ranges) == 0 {
+		return "", false
+	}
+
+	v, err := semver.NewVersion(ver)
+	if err != nil {
+		return "", false
+	}
+	for _, entry := range ranges {
+		if entry.r.Satisfies(v) {
+			return entry.spec, true
+		}
+	}
+	return "", false
+}
+
+// GetRanges returns the affected version ranges listed for a package.
+// Returns nil if the package has no range-based entries.
+func (d *Database) GetRanges(pkg string) []string {
+	s := d.load()
+
+	ranges := s.ranges[pkg]
+	if len(ranges) == 0 {
+		return nil
+	}
+	specs := make([]string, len(ranges))
+	for i, entry := range ranges {
+		specs[i] = entry.spec
+	}
+	return specs
+}
+
+// Count returns the total number of unique packages in the IoC database,
+// counting each compromised scope once.
 func (d *Database) Count() int {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
-	return len(d.ioc)
+	s := d.load()
+
+	count := len(s.ioc) + len(s.scopes)
+	for pkg := range s.ranges {
+		if _, exists := s.ioc[pkg]; !exists {
+			count++
+		}
+	}
+	return count
 }
 
-// Size returns the total number of package-version entries in the database.
+// Size returns the total number of entries in the database: package-version
+// pairs plus range-based and compromised-scope entries.
 func (d *Database) Size() int {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	s := d.load()
 
 	size := 0
-	for _, versions := range d.ioc {
+	for _, versions := range s.ioc {
 		size += len(versions)
 	}
+	for _, ranges := range s.ranges {
+		size += len(ranges)
+	}
+	for _, scopes := range s.scopes {
+		size += len(scopes)
+	}
 	return size
 }
 
 // GetPackages returns all packages in the database (for testing/inspection).
 // The returned slice contains the keys from the internal map.
 func (d *Database) GetPackages() []string {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	s := d.load()
 
-	packages := make([]string, 0, len(d.ioc))
-	for pkg := range d.ioc {
+	packages := make([]string, 0, len(s.ioc))
+	for pkg := range s.ioc {
 		packages = append(packages, pkg)
 	}
 	return packages
 }
 
-// GetVersions returns all compromised versions for a given package.
-// Returns nil if the package is not in the database.
+// GetVersions returns all exact compromised versions for a given package;
+// range-based entries are available through GetRanges.
+// Returns nil if the package has no exact entries.
 func (d *Database) GetVersions(pkg string) []string {
-	d.mu.RLock()
-	defer d.mu.RUnlock()
+	s := d.load()
 
-	versions, exists := d.ioc[pkg]
+	versions, exists := s.ioc[pkg]
 	if !exists {
 		return nil
 	}
@@ -108,3 +397,170 @@ func (d *Database) GetVersions(pkg string) []string {
 	copy(result, versions)
 	return result
 }
+
+// LookupHash checks whether a tarball hash is listed as known-malicious.
+// integrity may be a lockfile integrity string ("sha512-<base64>", possibly
+// several) or a hex digest. Returns the entry the hash was listed for.
+//
+// Hashes are compared per algorithm, so a sha512 integrity only matches IoC
+// entries that list a sha512 digest.
+func (d *Database) LookupHash(integrity string) (Entry, bool) {
+	s := d.load()
+
+	for _, key := range hashKeys(integrity) {
+		if entry, ok := s.hashes[key]; ok {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// HashCount returns the number of known-malicious tarball hashes.
+func (d *Database) HashCount() int {
+	s := d.load()
+	return len(s.hashes)
+}
+
+// Digest returns a SHA-256 digest of the database entries, as
+// "sha256:<hex>", identifying the IoC data a scan ran against. It depends
+// only on the entries' contents, not on their order or line numbers, so the
+// same feed fetched twice has the same digest.
+func (d *Database) Digest() string {
+	s := d.load()
+
+	lines := make([]string, len(s.entries))
+	for i, entry := range s.entries {
+		added := ""
+		if !entry.Added.IsZero() {
+			added = entry.Added.UTC().Format(time.RFC3339)
+		}
+		hashes := append([]string(nil), entry.Hashes...)
+		sort.Strings(hashes)
+		fields := []string{
+			entry.Package,
+			entry.Version,
+			entry.Range,
+			added,
+			strings.Join(hashes, ","),
+			strings.Join(entry.Advisory.IDs, ","),
+			entry.Advisory.URL,
+			entry.Advisory.Campaign,
+			entry.Advisory.Severity,
+			entry.Source,
+		}
+		// Appended only when set, so digests of feeds without scripts are
+		// unchanged
+		if len(entry.Scripts) > 0 {
+			scripts := append([]string(nil), entry.Scripts...)
+			sort.Strings(scripts)
+			fields = append(fields, strings.Join(scripts, "\x01"))
+		}
+		lines[i] = strings.Join(fields, "\x00")
+	}
+	sort.Strings(lines)
+
+	h := sha256.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte("\n"))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// AddedAt returns when the package@version entry, or the range or scope
+// entry that lists the version, was added to the IoC database. The second return value
+// is false if the entry is unknown or the source data carried no date for it.
+func (d *Database) AddedAt(pkg, ver string) (time.Time, bool) {
+	s := d.load()
+
+	if added, ok := s.added[entryKey(pkg, ver)]; ok {
+		return added, true
+	}
+	if spec, ok := s.matchRange(pkg, ver); ok {
+		added, ok := s.added[entryKey(pkg, spec)]
+		return added, ok
+	}
+	if entry, ok := s.matchScope(pkg, ver); ok {
+		added, ok := s.added[entry.key]
+		return added, ok
+	}
+	return time.Time{}, false
+}
+
+// AdvisoryFor returns the advisory behind the package@version entry, or the
+// range or scope entry that lists the version. The second return value is false if
+// the entry is unknown or the source data carried no advisory for it.
+func (d *Database) AdvisoryFor(pkg, ver string) (Advisory, bool) {
+	s := d.load()
+
+	if advisory, ok := s.advisories[entryKey(pkg, ver)]; ok {
+		return advisory, true
+	}
+	if spec, ok := s.matchRange(pkg, ver); ok {
+		advisory, ok := s.advisories[entryKey(pkg, spec)]
+		return advisory, ok
+	}
+	if entry, ok := s.matchScope(pkg, ver); ok {
+		advisory, ok := s.advisories[entry.key]
+		return advisory, ok
+	}
+	return Advisory{}, false
+}
+
+// SourcesFor returns every source that lists pkg@ver, through an exact
+// entry, an affected range or a compromised scope, sorted by source name. Returns nil if the
+// version is not listed or its entries carry no source.
+func (d *Database) SourcesFor(pkg, ver string) []SourceRecord {
+	s := d.load()
+
+	records := append([]SourceRecord(nil), s.sources[entryKey(pkg, ver)]...)
+	if ranges := s.ranges[pkg]; len(ranges) > 0 {
+		if v, err := semver.NewVersion(ver); err == nil {
+			for _, entry := range ranges {
+				if entry.r.Satisfies(v) {
+					records = append(records, s.sources[entryKey(pkg, entry.spec)]...)
+				}
+			}
+		}
+	}
+	if entry, ok := s.matchScope(pkg, ver); ok {
+		records = append(records, s.sources[entry.key]...)
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Source < records[j].Source
+	})
+	return records
+}
+
+// Since returns a new Database containing only the entries added on or after
+// since, to restrict matching to a single incident window.
+//
+// Entries without a date are kept, since they cannot be placed outside the
+// window. A zero since returns a copy of the full database.
+func (d *Database) Since(since time.Time) *Database {
+	s := d.load()
+
+	var entries []Entry
+	for _, entry := range s.entries {
+		if !since.IsZero() && !entry.Added.IsZero() && entry.Added.Before(since) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return NewDatabaseFromEntries(entries)
+}
+
+// Merge combines databases from several sources into one, correlating
+// entries for the same package version (see NewDatabaseFromEntries). Nil
+// databases are skipped.
+func Merge(dbs ...*Database) *Database {
+	var entries []Entry
+	for _, d := range dbs {
+		if d == nil {
+			continue
+		}
+		entries = append(entries, d.load().entries...)
+	}
+	return NewDatabaseFromEntries(entries)
+}
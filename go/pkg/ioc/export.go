@@ -0,0 +1,49 @@
+package ioc
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// exportedEntry is the JSON form of an Entry.
+type exportedEntry struct {
+	Package  string     `json:"package"`
+	Version  string     `json:"version,omitempty"`
+	Range    string     `json:"range,omitempty"`
+	Added    *time.Time `json:"added,omitempty"`
+	Hashes   []string   `json:"hashes,omitempty"`
+	Scripts  []string   `json:"scripts,omitempty"`
+	IDs      []string   `json:"advisoryIds,omitempty"`
+	URL      string     `json:"advisoryUrl,omitempty"`
+	Campaign string     `json:"campaign,omitempty"`
+	Severity string     `json:"severity,omitempty"`
+	Source   string     `json:"source,omitempty"`
+	Row      int        `json:"row,omitempty"`
+}
+
+// ExportJSON formats entries as an indented JSON array, one object per
+// entry, for tools that do not read the CSV format. The output is itself a
+// FormatJSON database.
+func ExportJSON(entries []Entry) ([]byte, error) {
+	exported := make([]exportedEntry, len(entries))
+	for i, entry := range entries {
+		exported[i] = exportedEntry{
+			Package:  entry.Package,
+			Version:  entry.Version,
+			Range:    entry.Range,
+			Hashes:   entry.Hashes,
+			Scripts:  entry.Scripts,
+			IDs:      entry.Advisory.IDs,
+			URL:      entry.Advisory.URL,
+			Campaign: entry.Advisory.Campaign,
+			Severity: entry.Advisory.Severity,
+			Source:   entry.Source,
+			Row:      entry.Row,
+		}
+		if !entry.Added.IsZero() {
+			added := entry.Added
+			exported[i].Added = &added
+		}
+	}
+	return json.MarshalIndent(exported, "", "  ")
+}
@@ -6,11 +6,21 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"time"
+	"unicode"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
 )
 
 const (
 	// DefaultIoCURL is the default GitHub URL for the IoC CSV database
 	DefaultIoCURL = "https://raw.githubusercontent.com/wiz-sec-public/wiz-research-iocs/main/reports/shai-hulud-2-packages.csv"
+
+	// DefaultCampaignStart is the start of the shai-hulud 2 campaign window
+	// covered by the default IoC database (YYYY-MM-DD)
+	DefaultCampaignStart = "2025-11-24"
 )
 
 // FetchIoCDatabase fetches the IoC CSV database from the given URL.
@@ -22,13 +32,48 @@ const (
 //	02-echo,= 0.0.7
 //	@accordproject/concerto-analysis,= 3.24.1
 //
-// If url is empty, DefaultIoCURL is used.
+// If url is empty, DefaultIoCURL is used. The request goes through the
+// shared transport, honoring its proxy, rewrite, timeout, retry and
+// certificate settings.
 func FetchIoCDatabase(url string) ([]byte, error) {
+	feed, err := FetchFeed(url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return feed.Data, nil
+}
+
+// Feed is a fetched IoC database, with what is needed to tell its format.
+type Feed struct {
+	// URL is the URL the feed was fetched from
+	URL string
+	// ContentType is the Content-Type of the response
+	ContentType string
+	Data        []byte
+}
+
+// Format returns the format of the feed (FormatCSV, FormatJSON or
+// FormatYAML), as told by DetectFormat.
+func (f *Feed) Format() string {
+	return DetectFormat(f.Data, f.URL, f.ContentType)
+}
+
+// FetchFeed is FetchIoCDatabase with extra request headers, such as the
+// Authorization header of a private feed, returning the response's
+// content type along with the data.
+func FetchFeed(url string, header http.Header) (*Feed, error) {
 	if url == "" {
 		url = DefaultIoCURL
 	}
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch IoC database: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := transport.Client().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("fetch IoC database: %w", err)
 	}
@@ -43,7 +88,166 @@ func FetchIoCDatabase(url string) ([]byte, error) {
 		return nil, fmt.Errorf("read IoC database response: %w", err)
 	}
 
-	return data, nil
+	return &Feed{URL: url, ContentType: resp.Header.Get("Content-Type"), Data: data}, nil
+}
+
+// Entry is a single compromised package version from the IoC database.
+type Entry struct {
+	Package string
+	// Version is the compromised version of an exact entry
+	Version string
+	// Range is the affected npm version range (e.g. "< 4.17.21" or
+	// ">=1.0.0 <1.3.0") of a range-based entry; Version is empty for those
+	Range string
+	// Added is when the entry was added to the database. It is zero if the
+	// source data carries no date column.
+	Added time.Time
+	// Hashes lists known-malicious tarball hashes for the entry, as
+	// written in the source data (hex digests or SRI strings).
+	Hashes []string
+	// Scripts lists known-malicious strings of the package.json scripts
+	// the compromised releases run, such as a postinstall payload (see
+	// Database.MatchScript)
+	Scripts []string
+	// Advisory describes the advisory behind the entry, when the source
+	// data provides one.
+	Advisory Advisory
+	// Source names the feed that listed the entry (SourceCSV, SourceOSV).
+	// Entries of the same package version from several feeds are
+	// correlated by the Database.
+	Source string
+	// Row is the line of the entry in the source data, counting the CSV
+	// header as line 1. It is zero for feeds without lines, such as OSV.
+	Row int
+}
+
+// Advisory identifies the advisory and campaign an IoC entry comes from.
+type Advisory struct {
+	// IDs are advisory identifiers such as CVE, GHSA or OSV IDs
+	IDs []string
+	// URL links to the advisory
+	URL string
+	// Campaign names the attack campaign, e.g. "shai-hulud-2"
+	Campaign string
+	// Severity rates the advisory as the source does, lowercased, e.g.
+	// "critical" or "high"
+	Severity string
+}
+
+// IsZero reports whether the advisory carries no information.
+func (a Advisory) IsZero() bool {
+	return len(a.IDs) == 0 && a.URL == "" && a.Campaign == "" && a.Severity == ""
+}
+
+// merge combines two advisories for the same entry, keeping the IDs of both
+// and the first non-empty URL, campaign and severity.
+func (a Advisory) merge(other Advisory) Advisory {
+	for _, id := range other.IDs {
+		if !containsString(a.IDs, id) {
+			a.IDs = append(a.IDs, id)
+		}
+	}
+	if a.URL == "" {
+		a.URL = other.URL
+	}
+	if a.Campaign == "" {
+		a.Campaign = other.Campaign
+	}
+	if a.Severity == "" {
+		a.Severity = other.Severity
+	}
+	return a
+}
+
+// containsString reports whether values contains s.
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// dateColumns lists recognized (lowercased) header names for the optional
+// date-added column.
+var dateColumns = map[string]bool{
+	"date":       true,
+	"date added": true,
+	"date_added": true,
+	"dateadded":  true,
+	"added":      true,
+	"first seen": true,
+	"first_seen": true,
+}
+
+// hashColumns lists recognized (lowercased) header names for the optional
+// tarball hash column.
+var hashColumns = map[string]bool{
+	"hash":      true,
+	"hashes":    true,
+	"sha":       true,
+	"sha1":      true,
+	"sha256":    true,
+	"sha512":    true,
+	"shasum":    true,
+	"integrity": true,
+}
+
+// scriptColumns lists recognized (lowercased) header names for the
+// optional malicious script column.
+var scriptColumns = map[string]bool{
+	"script":         true,
+	"scripts":        true,
+	"script pattern": true,
+	"script_pattern": true,
+}
+
+// advisoryIDColumns lists recognized (lowercased) header names for optional
+// advisory identifier columns. A source may have several, e.g. one for CVEs
+// and one for GHSA IDs.
+var advisoryIDColumns = map[string]bool{
+	"cve":         true,
+	"cves":        true,
+	"ghsa":        true,
+	"osv":         true,
+	"advisory":    true,
+	"advisories":  true,
+	"advisory id": true,
+	"advisory_id": true,
+}
+
+// advisoryURLColumns lists recognized (lowercased) header names for the
+// optional advisory URL column.
+var advisoryURLColumns = map[string]bool{
+	"url":          true,
+	"link":         true,
+	"reference":    true,
+	"advisory url": true,
+	"advisory_url": true,
+}
+
+// campaignColumns lists recognized (lowercased) header names for the
+// optional campaign column.
+var campaignColumns = map[string]bool{
+	"campaign":      true,
+	"campaign name": true,
+	"campaign_name": true,
+}
+
+// severityColumns lists recognized (lowercased) header names for the
+// optional severity column.
+var severityColumns = map[string]bool{
+	"severity": true,
+	"risk":     true,
+}
+
+// dateLayouts lists the accepted formats for date-added values.
+var dateLayouts = []string{
+	"2006-01-02",
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
 }
 
 // ParseCSV parses IoC CSV data and returns package->versions mapping.
@@ -53,20 +257,77 @@ func FetchIoCDatabase(url string) ([]byte, error) {
 //
 // The version specification is trimmed and the "= " prefix is removed.
 // Multiple versions separated by || are split into individual entries.
+// Parts that are ranges rather than versions (e.g. "< 4.17.21") are kept
+// as written. A package name "@scope/*" lists every package under a
+// compromised scope; its version "= *" becomes AnyVersion ("*").
 // Malformed lines (missing columns or empty) are skipped.
 func ParseCSV(data []byte) (map[string][]string, error) {
+	entries, err := ParseEntries(data)
+	if err != nil {
+		return nil, err
+	}
+
+	iocMap := make(map[string][]string)
+	for _, entry := range entries {
+		if entry.Range != "" {
+			iocMap[entry.Package] = append(iocMap[entry.Package], entry.Range)
+		} else {
+			iocMap[entry.Package] = append(iocMap[entry.Package], entry.Version)
+		}
+	}
+
+	return iocMap, nil
+}
+
+// ParseEntries parses IoC CSV data into individual entries, in file order.
+// It accepts the same format as ParseCSV, plus optional date-added, tarball
+// hash, malicious script, advisory (ID, URL, campaign) and severity
+// columns identified by their headers (e.g. "Date Added", "SHA256",
+// "Script", "GHSA", "Advisory URL", "Campaign", "Severity").
+// Unparseable dates are treated as missing. A hash cell may hold several
+// hashes separated by whitespace or ||, a script cell several scripts
+// separated by ||, and an advisory ID cell several IDs separated by
+// whitespace, commas or ||; every version on the row shares them.
+func ParseEntries(data []byte) ([]Entry, error) {
 	reader := csv.NewReader(strings.NewReader(string(data)))
 
-	// Read header row (and skip it)
-	_, err := reader.Read()
+	// Read header row to locate the optional date and hash columns
+	header, err := reader.Read()
 	if err != nil {
 		if err == io.EOF {
-			return map[string][]string{}, nil // Empty file, return empty map
+			return []Entry{}, nil // Empty file, return no entries
 		}
 		return nil, fmt.Errorf("read CSV header: %w", err)
 	}
 
-	iocMap := make(map[string][]string)
+	dateColumn, hashColumn, scriptColumn, urlColumn, campaignColumn, severityColumn := -1, -1, -1, -1, -1, -1
+	var idColumns []int
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if dateColumns[name] && dateColumn < 0 {
+			dateColumn = i
+		}
+		if hashColumns[name] && hashColumn < 0 {
+			hashColumn = i
+		}
+		if scriptColumns[name] && scriptColumn < 0 {
+			scriptColumn = i
+		}
+		if advisoryIDColumns[name] {
+			idColumns = append(idColumns, i)
+		}
+		if advisoryURLColumns[name] && urlColumn < 0 {
+			urlColumn = i
+		}
+		if campaignColumns[name] && campaignColumn < 0 {
+			campaignColumn = i
+		}
+		if severityColumns[name] && severityColumn < 0 {
+			severityColumn = i
+		}
+	}
+
+	entries := []Entry{}
 
 	for {
 		record, err := reader.Read()
@@ -82,6 +343,7 @@ func ParseCSV(data []byte) (map[string][]string, error) {
 			continue
 		}
 
+		row, _ := reader.FieldPos(0)
 		packageName := strings.TrimSpace(record[0])
 		versionSpec := strings.TrimSpace(record[1])
 
@@ -89,20 +351,114 @@ func ParseCSV(data []byte) (map[string][]string, error) {
 			continue
 		}
 
+		var added time.Time
+		if dateColumn >= 0 && dateColumn < len(record) {
+			added = parseDate(strings.TrimSpace(record[dateColumn]))
+		}
+
+		var hashes []string
+		if hashColumn >= 0 && hashColumn < len(record) {
+			hashes = strings.Fields(strings.ReplaceAll(record[hashColumn], "||", " "))
+		}
+
+		var scripts []string
+		if scriptColumn >= 0 && scriptColumn < len(record) {
+			for _, script := range strings.Split(record[scriptColumn], "||") {
+				if script = strings.TrimSpace(script); script != "" {
+					scripts = append(scripts, script)
+				}
+			}
+		}
+
+		var advisory Advisory
+		for _, column := range idColumns {
+			if column < len(record) {
+				advisory = advisory.merge(Advisory{IDs: splitList(record[column])})
+			}
+		}
+		if urlColumn >= 0 && urlColumn < len(record) {
+			advisory.URL = strings.TrimSpace(record[urlColumn])
+		}
+		if campaignColumn >= 0 && campaignColumn < len(record) {
+			advisory.Campaign = strings.TrimSpace(record[campaignColumn])
+		}
+		if severityColumn >= 0 && severityColumn < len(record) {
+			advisory.Severity = normalizeSeverity(record[severityColumn])
+		}
+
 		// Split on || to handle multiple versions in one entry
 		// Example: "= 0.1.18 || = 0.1.19 || = 0.1.20" -> ["= 0.1.18", "= 0.1.19", "= 0.1.20"]
 		versionParts := strings.Split(versionSpec, "||")
 
 		for _, versionPart := range versionParts {
+			versionPart = strings.TrimSpace(versionPart)
+
 			// Strip "= " prefix from version (e.g., "= 0.0.7" -> "0.0.7")
-			version := strings.TrimPrefix(strings.TrimSpace(versionPart), "=")
+			version := strings.TrimPrefix(versionPart, "=")
 			version = strings.TrimSpace(version)
 
-			if version != "" {
-				iocMap[packageName] = append(iocMap[packageName], version)
+			if version == "" {
+				continue
 			}
+
+			entry := Entry{
+				Package:  packageName,
+				Version:  version,
+				Added:    added,
+				Hashes:   hashes,
+				Scripts:  scripts,
+				Advisory: advisory,
+				Row:      row,
+			}
+			if isRangeSpec(versionPart) && !isScopeWildcard(packageName, versionPart) {
+				entry.Version, entry.Range = "", versionPart
+			}
+			entries = append(entries, entry)
 		}
 	}
 
-	return iocMap, nil
+	return entries, nil
+}
+
+// isRangeSpec reports whether a version cell part is an npm range rather
+// than a single version, e.g. "< 4.17.21", ">=1.0.0 <1.3.0" or "1.2.x".
+func isRangeSpec(spec string) bool {
+	if _, err := semver.StrictNewVersion(strings.TrimSpace(strings.TrimPrefix(spec, "="))); err == nil {
+		return false
+	}
+	_, err := npmsemver.ParseRange(spec)
+	return err == nil
+}
+
+// key returns the index key of the entry: package@version, or
+// package@range for range-based entries.
+func (e Entry) key() string {
+	if e.Range != "" {
+		return entryKey(e.Package, e.Range)
+	}
+	return entryKey(e.Package, e.Version)
+}
+
+// splitList splits a cell holding several values separated by whitespace,
+// commas, semicolons or ||.
+func splitList(cell string) []string {
+	return strings.FieldsFunc(cell, func(r rune) bool {
+		return r == ',' || r == ';' || r == '|' || unicode.IsSpace(r)
+	})
+}
+
+// normalizeSeverity returns a severity value trimmed and lowercased.
+func normalizeSeverity(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+// parseDate parses a date-added value, returning the zero time if it
+// matches none of the accepted layouts.
+func parseDate(value string) time.Time {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
 }
@@ -0,0 +1,340 @@
+package ioc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	neturl "net/url"
+	"path"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/yamlite"
+)
+
+// IoC database formats.
+const (
+	// FormatCSV is the Package,Version CSV of the default feed
+	FormatCSV = "csv"
+	// FormatJSON is a JSON array of package objects, or an object holding
+	// one under "packages"
+	FormatJSON = "json"
+	// FormatYAML is the YAML equivalent of FormatJSON
+	FormatYAML = "yaml"
+)
+
+// feedPackage is one package of a JSON or YAML IoC database. The field
+// names match the output of ExportJSON, so an export can be read back.
+type feedPackage struct {
+	Package string `json:"package"`
+	// Name is accepted in place of Package
+	Name string `json:"name"`
+	// Version and Versions list compromised versions. A listed value that
+	// is an npm range is treated as one.
+	Version  string   `json:"version"`
+	Versions []string `json:"versions"`
+	// Range and Ranges list affected npm ranges
+	Range       string   `json:"range"`
+	Ranges      []string `json:"ranges"`
+	Added       string   `json:"added"`
+	Hashes      []string `json:"hashes"`
+	Scripts     []string `json:"scripts"`
+	AdvisoryIDs []string `json:"advisoryIds"`
+	AdvisoryURL string   `json:"advisoryUrl"`
+	Campaign    string   `json:"campaign"`
+	Severity    string   `json:"severity"`
+}
+
+// DetectFormat tells the format of IoC database data fetched from url:
+// by the response's contentType when it names one, else by the extension
+// of the URL path (.json, .yaml, .yml, .csv), else by the content itself.
+// Data that looks like neither JSON nor YAML is CSV.
+func DetectFormat(data []byte, url, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		switch {
+		case mediaType == "text/csv":
+			return FormatCSV
+		case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") || mediaType == "text/json":
+			return FormatJSON
+		case strings.HasSuffix(mediaType, "yaml"):
+			return FormatYAML
+		}
+	}
+
+	if u, err := neturl.Parse(url); err == nil {
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".csv":
+			return FormatCSV
+		case ".json":
+			return FormatJSON
+		case ".yaml", ".yml":
+			return FormatYAML
+		}
+	}
+
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(yamlite.StripComment(line))
+		switch {
+		case line == "" || line == "---":
+			continue
+		case line == "packages:" || strings.HasPrefix(line, "- "):
+			return FormatYAML
+		}
+		break
+	}
+	return FormatCSV
+}
+
+// ParseFeed parses IoC database data in format into entries, in file
+// order. An empty format is FormatCSV.
+func ParseFeed(data []byte, format string) ([]Entry, error) {
+	switch format {
+	case "", FormatCSV:
+		return ParseEntries(data)
+	case FormatJSON:
+		return ParseJSONEntries(data)
+	case FormatYAML:
+		return ParseYAMLEntries(data)
+	}
+	return nil, fmt.Errorf("unknown IoC database format %q (expected %s, %s or %s)", format, FormatCSV, FormatJSON, FormatYAML)
+}
+
+// ParseJSONEntries parses a JSON IoC database: an array of package objects,
+// or an object with the array under "packages". A package object has a
+// "package" (or "name"), "version"/"versions" and "range"/"ranges", and
+// optionally "added", "hashes", "scripts", "advisoryIds", "advisoryUrl",
+// "campaign" and "severity":
+//
+//	{"packages": [
+//	  {"package": "evil", "versions": ["1.0.1", "1.0.2"], "severity": "critical"},
+//	  {"package": "lodash", "range": "< 4.17.21", "advisoryIds": ["GHSA-35jh-r3h4-6jhm"]}
+//	]}
+//
+// A package "@scope/*" with version "*" lists every package under a
+// compromised scope. Unknown fields are ignored. JSON entries have no Row.
+func ParseJSONEntries(data []byte) ([]Entry, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	var packages []feedPackage
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var wrapper struct {
+			Packages *[]feedPackage `json:"packages"`
+		}
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return nil, fmt.Errorf("parse JSON: %w", err)
+		}
+		if wrapper.Packages == nil {
+			return nil, errors.New(`parse JSON: expected an array or an object with "packages"`)
+		}
+		packages = *wrapper.Packages
+	} else if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, fmt.Errorf("parse JSON: %w", err)
+	}
+
+	entries := []Entry{}
+	for i, pkg := range packages {
+		pkgEntries, err := pkg.entries(0)
+		if err != nil {
+			return nil, fmt.Errorf("package #%d: %w", i+1, err)
+		}
+		entries = append(entries, pkgEntries...)
+	}
+	return entries, nil
+}
+
+// ParseYAMLEntries parses a YAML IoC database with the structure of a JSON
+// one: a list of packages, at the top level or under "packages:". Lists
+// are block lists or flow lists ([a, b]) of scalars:
+//
+//	packages:
+//	  - package: evil
+//	    versions: ["1.0.1", "1.0.2"]
+//	    severity: critical
+//	  - package: lodash
+//	    range: "< 4.17.21"
+//	    advisoryIds:
+//	      - GHSA-35jh-r3h4-6jhm
+//
+// An entry's Row is the line of its package's list item. Errors name the
+// offending line.
+func ParseYAMLEntries(data []byte) ([]Entry, error) {
+	entries := []Entry{}
+	var pkg *feedPackage
+	pkgLine, pkgIndent := 0, -1
+	// list is the list field that block list items are appended to
+	var list *[]string
+
+	finish := func() error {
+		if pkg == nil {
+			return nil
+		}
+		pkgEntries, err := pkg.entries(pkgLine)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", pkgLine, err)
+		}
+		entries = append(entries, pkgEntries...)
+		pkg = nil
+		return nil
+	}
+
+	for i, raw := range strings.Split(string(bytes.TrimPrefix(data, utf8BOM)), "\n") {
+		lineNo := i + 1
+		line := strings.TrimRight(yamlite.StripComment(raw), " \t\r")
+		content := strings.TrimSpace(line)
+		if content == "" || content == "---" || line == "packages:" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		isItem := strings.HasPrefix(content, "- ") || content == "-"
+		if isItem && list != nil && indent > pkgIndent {
+			value, err := yamlite.Unquote(strings.TrimSpace(strings.TrimPrefix(content, "-")))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			*list = append(*list, value)
+			continue
+		}
+		list = nil
+
+		if isItem {
+			if pkg != nil && indent != pkgIndent {
+				return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+			}
+			if err := finish(); err != nil {
+				return nil, err
+			}
+			pkg, pkgLine, pkgIndent = &feedPackage{}, lineNo, indent
+			content = strings.TrimSpace(strings.TrimPrefix(content, "-"))
+			if content == "" {
+				continue
+			}
+		} else if pkg == nil {
+			return nil, fmt.Errorf("line %d: expected \"packages:\" or a list item, got %q", lineNo, content)
+		} else if indent <= pkgIndent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+		}
+
+		key, value, ok := strings.Cut(content, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value, got %q", lineNo, content)
+		}
+		var err error
+		list, err = pkg.set(strings.TrimSpace(key), strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// set assigns one YAML key of a package. A list field without a value
+// returns the list its block items are appended to. Unknown keys are
+// ignored, as in JSON.
+func (p *feedPackage) set(key, value string) (*[]string, error) {
+	lists := map[string]*[]string{
+		"versions":    &p.Versions,
+		"ranges":      &p.Ranges,
+		"hashes":      &p.Hashes,
+		"scripts":     &p.Scripts,
+		"advisoryIds": &p.AdvisoryIDs,
+	}
+	if field, ok := lists[key]; ok {
+		if value == "" {
+			return field, nil
+		}
+		values, err := yamlite.FlowList(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		*field = append(*field, values...)
+		return nil, nil
+	}
+
+	scalars := map[string]*string{
+		"package":     &p.Package,
+		"name":        &p.Name,
+		"version":     &p.Version,
+		"range":       &p.Range,
+		"added":       &p.Added,
+		"advisoryUrl": &p.AdvisoryURL,
+		"campaign":    &p.Campaign,
+		"severity":    &p.Severity,
+	}
+	if field, ok := scalars[key]; ok {
+		unquoted, err := yamlite.Unquote(value)
+		if err != nil {
+			return nil, err
+		}
+		*field = unquoted
+	}
+	return nil, nil
+}
+
+// entries returns the entries of a package, one per listed version and
+// range, recording row as their Row.
+func (p *feedPackage) entries(row int) ([]Entry, error) {
+	name := strings.TrimSpace(p.Package)
+	if name == "" {
+		name = strings.TrimSpace(p.Name)
+	}
+	if name == "" {
+		return nil, errors.New("package without a name")
+	}
+
+	advisory := Advisory{
+		IDs:      p.AdvisoryIDs,
+		URL:      strings.TrimSpace(p.AdvisoryURL),
+		Campaign: strings.TrimSpace(p.Campaign),
+		Severity: normalizeSeverity(p.Severity),
+	}
+	base := Entry{
+		Package:  name,
+		Added:    parseDate(strings.TrimSpace(p.Added)),
+		Hashes:   p.Hashes,
+		Scripts:  p.Scripts,
+		Advisory: advisory,
+		Row:      row,
+	}
+
+	var entries []Entry
+	for _, version := range append([]string{p.Version}, p.Versions...) {
+		version = strings.TrimSpace(version)
+		if version == "" {
+			continue
+		}
+		entry := base
+		if isRangeSpec(version) && !isScopeWildcard(name, version) {
+			entry.Range = version
+		} else {
+			entry.Version = strings.TrimSpace(strings.TrimPrefix(version, "="))
+		}
+		entries = append(entries, entry)
+	}
+	for _, r := range append([]string{p.Range}, p.Ranges...) {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		entry := base
+		if isScopeWildcard(name, r) {
+			entry.Version = AnyVersion
+		} else {
+			entry.Range = r
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("package %q lists no versions or ranges", name)
+	}
+	return entries, nil
+}
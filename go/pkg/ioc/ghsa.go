@@ -0,0 +1,242 @@
+package ioc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+const (
+	// DefaultGHSAURL is the GitHub global security advisories endpoint
+	DefaultGHSAURL = "https://api.github.com/advisories"
+
+	// ghsaBatchSize is the number of package names sent per query. The
+	// API accepts more, but long query strings are rejected.
+	ghsaBatchSize = 100
+
+	// ghsaPageSize is the number of advisories requested per page
+	ghsaPageSize = 100
+)
+
+// DefaultGHSATypes are the advisory types queried when GHSASource.Types is
+// empty. Without a type, the API returns reviewed advisories only, leaving
+// out malware such as the shai-hulud packages.
+var DefaultGHSATypes = []string{"reviewed", "malware"}
+
+// GHSASource looks up npm advisories in the GitHub Advisory Database. Each
+// vulnerable version range of an advisory becomes a range entry, so the
+// versions a scan finds are matched locally; with Packages set, only
+// advisories affecting those packages are fetched.
+type GHSASource struct {
+	// Packages are the npm package names to query. If empty, every npm
+	// advisory of Types is fetched.
+	Packages []string
+	// Types are the advisory types to query ("reviewed", "malware",
+	// "unreviewed"); if empty, DefaultGHSATypes is used
+	Types []string
+	// URL of the advisories endpoint; if empty, DefaultGHSAURL is used
+	URL string
+	// Token authenticates the requests, raising GitHub's rate limit. If
+	// empty, the GITHUB_TOKEN environment variable is used when set.
+	Token string
+	// Client is the HTTP client to use; if nil, the shared transport client
+	// is used
+	Client *http.Client
+}
+
+// ghsaAdvisory is an advisory in a GitHub advisories response.
+type ghsaAdvisory struct {
+	GHSAID          string     `json:"ghsa_id"`
+	CVEID           string     `json:"cve_id"`
+	HTMLURL         string     `json:"html_url"`
+	Severity        string     `json:"severity"`
+	PublishedAt     time.Time  `json:"published_at"`
+	WithdrawnAt     *time.Time `json:"withdrawn_at"`
+	Vulnerabilities []struct {
+		Package struct {
+			Ecosystem string `json:"ecosystem"`
+			Name      string `json:"name"`
+		} `json:"package"`
+		VulnerableVersionRange string `json:"vulnerable_version_range"`
+	} `json:"vulnerabilities"`
+}
+
+// Fetch queries the advisories of every package and type and returns an
+// entry per affected npm package and range. Withdrawn advisories are
+// skipped.
+func (s *GHSASource) Fetch(ctx context.Context) ([]Entry, error) {
+	types := s.Types
+	if len(types) == 0 {
+		types = DefaultGHSATypes
+	}
+
+	var entries []Entry
+	for _, advisoryType := range types {
+		if len(s.Packages) == 0 {
+			batch, err := s.query(ctx, advisoryType, nil)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, batch...)
+			continue
+		}
+		for start := 0; start < len(s.Packages); start += ghsaBatchSize {
+			end := start + ghsaBatchSize
+			if end > len(s.Packages) {
+				end = len(s.Packages)
+			}
+			batch, err := s.query(ctx, advisoryType, s.Packages[start:end])
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, batch...)
+		}
+	}
+	return entries, nil
+}
+
+// query fetches every page of advisories of advisoryType affecting
+// packages (all npm packages when nil).
+func (s *GHSASource) query(ctx context.Context, advisoryType string, packages []string) ([]Entry, error) {
+	base := s.URL
+	if base == "" {
+		base = DefaultGHSAURL
+	}
+	params := neturl.Values{}
+	params.Set("ecosystem", "npm")
+	params.Set("type", advisoryType)
+	params.Set("per_page", fmt.Sprint(ghsaPageSize))
+	if len(packages) > 0 {
+		params.Set("affects", strings.Join(packages, ","))
+	}
+	url := base + "?" + params.Encode()
+
+	wanted := make(map[string]bool, len(packages))
+	for _, name := range packages {
+		wanted[name] = true
+	}
+
+	var entries []Entry
+	for url != "" {
+		advisories, next, err := s.fetchPage(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		for _, advisory := range advisories {
+			if advisory.WithdrawnAt != nil {
+				continue
+			}
+			for _, entry := range advisory.entries() {
+				if len(wanted) == 0 || wanted[entry.Package] {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		url = next
+	}
+	return entries, nil
+}
+
+// fetchPage fetches one page of advisories, returning the URL of the next
+// page from the Link header ("" on the last page).
+func (s *GHSASource) fetchPage(ctx context.Context, url string) ([]ghsaAdvisory, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", fmt.Errorf("query GHSA: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	token := s.Token
+	if token == "" {
+		token = os.Getenv("GITHUB_TOKEN")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("query GHSA: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("query GHSA: HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("read GHSA response: %w", err)
+	}
+	var advisories []ghsaAdvisory
+	if err := json.Unmarshal(data, &advisories); err != nil {
+		return nil, "", fmt.Errorf("parse GHSA response: %w", err)
+	}
+	return advisories, nextLink(resp.Header.Get("Link")), nil
+}
+
+// entries returns an entry per npm package and vulnerable range of the
+// advisory. GitHub separates the comparators of a range with commas
+// (">= 1.0.0, < 1.0.2"); "= 1.0.1" becomes an exact entry.
+func (a ghsaAdvisory) entries() []Entry {
+	advisory := Advisory{URL: a.HTMLURL}
+	for _, id := range []string{a.GHSAID, a.CVEID} {
+		if id != "" {
+			advisory.IDs = append(advisory.IDs, id)
+		}
+	}
+	if severity := normalizeSeverity(a.Severity); severity != "unknown" {
+		advisory.Severity = severity
+	}
+
+	var entries []Entry
+	for _, vuln := range a.Vulnerabilities {
+		if vuln.Package.Ecosystem != "npm" || vuln.Package.Name == "" {
+			continue
+		}
+		spec := strings.Join(strings.Fields(strings.ReplaceAll(vuln.VulnerableVersionRange, ",", " ")), " ")
+		if spec == "" {
+			continue
+		}
+		entry := Entry{Package: vuln.Package.Name, Added: a.PublishedAt, Advisory: advisory, Source: SourceGHSA}
+		if version, ok := strings.CutPrefix(spec, "= "); ok && !strings.Contains(version, " ") {
+			entry.Version = version
+		} else {
+			entry.Range = spec
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// nextLink returns the rel="next" URL of a Link header, or "". URLs are
+// delimited by angle brackets, as they may contain commas.
+func nextLink(header string) string {
+	for {
+		start := strings.Index(header, "<")
+		end := strings.Index(header, ">")
+		if start < 0 || end < start {
+			return ""
+		}
+		target := header[start+1 : end]
+		header = header[end+1:]
+		params := header
+		if next := strings.Index(header, "<"); next >= 0 {
+			params = header[:next]
+		}
+		if strings.Contains(params, `rel="next"`) {
+			return target
+		}
+	}
+}
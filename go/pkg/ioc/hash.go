@@ -0,0 +1,75 @@
+package ioc
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// hashKeys normalizes a hash value to "algorithm:hexdigest" keys so that
+// IoC digests and lockfile integrity strings can be compared.
+//
+// Accepted forms are Subresource Integrity strings ("sha512-<base64>",
+// possibly several separated by spaces, as in lockfile integrity fields),
+// prefixed hex digests ("sha256:<hex>") and bare hex digests, whose
+// algorithm is inferred from their length. Unrecognized values are skipped.
+func hashKeys(value string) []string {
+	var keys []string
+
+	for _, field := range strings.Fields(value) {
+		// Strip SRI options ("sha512-...?foo")
+		if i := strings.Index(field, "?"); i != -1 {
+			field = field[:i]
+		}
+
+		if algo, digest, ok := strings.Cut(field, "-"); ok && isHashAlgorithm(algo) {
+			raw, err := base64.StdEncoding.DecodeString(digest)
+			if err != nil {
+				continue
+			}
+			keys = append(keys, strings.ToLower(algo)+":"+hex.EncodeToString(raw))
+			continue
+		}
+
+		algo, digest, ok := strings.Cut(field, ":")
+		if !ok {
+			digest = field
+			algo = algorithmForHexLength(len(digest))
+		}
+		algo = strings.ToLower(algo)
+		digest = strings.ToLower(digest)
+		if !isHashAlgorithm(algo) {
+			continue
+		}
+		if _, err := hex.DecodeString(digest); err != nil {
+			continue
+		}
+		keys = append(keys, algo+":"+digest)
+	}
+
+	return keys
+}
+
+// isHashAlgorithm reports whether algo is a supported digest algorithm.
+func isHashAlgorithm(algo string) bool {
+	switch strings.ToLower(algo) {
+	case "sha1", "sha256", "sha384", "sha512":
+		return true
+	}
+	return false
+}
+
+// algorithmForHexLength infers a digest algorithm from a hex digest length.
+func algorithmForHexLength(n int) string {
+	switch n {
+	case 40:
+		return "sha1"
+	case 64:
+		return "sha256"
+	case 96:
+		return "sha384"
+	case 128:
+		return "sha512"
+	}
+	return ""
+}
@@ -0,0 +1,149 @@
+package ioc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+// maxSignatureSize bounds the detached signature download.
+const maxSignatureSize = 64 << 10
+
+// Verify checks the integrity of feed data fetched from url: its SHA-256
+// digest must equal SHA256, and with PublicKey its detached signature must
+// verify. Unlike Check, Verify ignores Mode, since a feed that was tampered
+// with must never be used. Fetching the signature honors the shared
+// transport; header, the feed's request headers, is sent along only when
+// the signature is on the feed's host, so credentials do not leak.
+func (c FeedCheck) Verify(data []byte, url string, header http.Header) error {
+	if c.SHA256 != "" {
+		want, err := ParseSHA256(c.SHA256)
+		if err != nil {
+			return err
+		}
+		if got := sha256Hex(data); got != want {
+			return fmt.Errorf("feed has sha256 %s, expected the pinned %s", got, want)
+		}
+	}
+
+	if c.PublicKey == nil {
+		return nil
+	}
+	sigURL := c.SignatureURL
+	if sigURL == "" {
+		sigURL = cacheURL(url) + ".sig"
+	}
+	if !sameHost(sigURL, cacheURL(url)) {
+		header = nil
+	}
+	sig, err := fetchSignature(sigURL, header)
+	if err != nil {
+		return err
+	}
+	if err := VerifySignature(c.PublicKey, data, sig); err != nil {
+		return fmt.Errorf("feed signature from %s: %w", sigURL, err)
+	}
+	return nil
+}
+
+// ParseSHA256 validates a hex SHA-256 digest, optionally prefixed with
+// "sha256:", and returns it in lowercase without the prefix.
+func ParseSHA256(digest string) (string, error) {
+	hex := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(digest), "sha256:"))
+	if len(hex) != sha256.Size*2 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return "", fmt.Errorf("invalid sha256 %q: expected 64 hex digits", digest)
+	}
+	return hex, nil
+}
+
+// ParsePublicKey parses a PEM-encoded ECDSA, RSA or Ed25519 public key, as
+// written by cosign generate-key-pair or openssl pkey -pubout.
+func ParsePublicKey(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", key)
+}
+
+// VerifySignature checks a detached signature of data made with the
+// private key of key: an ASN.1 ECDSA or RSA (PKCS #1 v1.5 or PSS) signature
+// of the SHA-256 digest, or an Ed25519 signature of the data itself. The
+// signature may be raw or base64-encoded, as cosign sign-blob prints it.
+func VerifySignature(key crypto.PublicKey, data, sig []byte) error {
+	trimmed := strings.TrimSpace(string(sig))
+	if decoded, err := base64.StdEncoding.DecodeString(trimmed); err == nil && trimmed != "" {
+		sig = decoded
+	}
+	digest := sha256.Sum256(data)
+
+	var ok bool
+	switch key := key.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(key, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil ||
+			rsa.VerifyPSS(key, crypto.SHA256, digest[:], sig, nil) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(key, data, sig)
+	default:
+		return fmt.Errorf("unsupported public key type %T", key)
+	}
+	if !ok {
+		return errors.New("signature does not verify")
+	}
+	return nil
+}
+
+// fetchSignature downloads a detached signature.
+func fetchSignature(url string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed signature: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := transport.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch feed signature: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch feed signature %s: HTTP %d: %s", url, resp.StatusCode, resp.Status)
+	}
+	sig, err := io.ReadAll(io.LimitReader(resp.Body, maxSignatureSize))
+	if err != nil {
+		return nil, fmt.Errorf("read feed signature: %w", err)
+	}
+	return sig, nil
+}
+
+// sameHost reports whether URLs a and b name the same scheme and host.
+func sameHost(a, b string) bool {
+	ua, errA := neturl.Parse(a)
+	ub, errB := neturl.Parse(b)
+	return errA == nil && errB == nil && ua.Scheme == ub.Scheme && ua.Host == ub.Host
+}
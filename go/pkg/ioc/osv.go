@@ -0,0 +1,146 @@
+package ioc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+const (
+	// DefaultOSVURL is the OSV.dev batch query endpoint
+	DefaultOSVURL = "https://api.osv.dev/v1/querybatch"
+
+	// osvBatchSize is the maximum number of queries OSV accepts per batch
+	osvBatchSize = 1000
+
+	// osvAdvisoryURL is the prefix of OSV.dev vulnerability pages
+	osvAdvisoryURL = "https://osv.dev/vulnerability/"
+)
+
+// OSVSource looks up known vulnerabilities for a fixed set of package
+// versions in the OSV.dev database. Unlike the CSV, OSV cannot be
+// downloaded as a whole, so the source is created with the packages a scan
+// discovered and only returns entries for those that are affected.
+//
+// Since OSV is queried per concrete version, only exact matches (lockfile
+// resolutions and exact pins) are possible; ranges cannot be checked for
+// POTENTIAL matches.
+type OSVSource struct {
+	// Packages are the npm package versions to query
+	Packages []PackageVersion
+	// URL of the batch endpoint; if empty, DefaultOSVURL is used
+	URL string
+	// Client is the HTTP client to use; if nil, the shared transport client
+	// is used
+	Client *http.Client
+}
+
+// osvQuery is a single query in an OSV batch request.
+type osvQuery struct {
+	Package struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	} `json:"package"`
+	Version string `json:"version"`
+}
+
+// osvBatchResponse is the response to an OSV batch request. Results are in
+// the same order as the queries.
+type osvBatchResponse struct {
+	Results []struct {
+		Vulns []struct {
+			ID string `json:"id"`
+		} `json:"vulns"`
+	} `json:"results"`
+}
+
+// Fetch queries OSV for every package version and returns an entry for each
+// one with at least one known vulnerability. The entry's advisory lists the
+// OSV IDs (GHSA, MAL, ...) and links to the first one on OSV.dev.
+func (s *OSVSource) Fetch(ctx context.Context) ([]Entry, error) {
+	var entries []Entry
+	for start := 0; start < len(s.Packages); start += osvBatchSize {
+		end := start + osvBatchSize
+		if end > len(s.Packages) {
+			end = len(s.Packages)
+		}
+
+		batch, err := s.queryBatch(ctx, s.Packages[start:end])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, batch...)
+	}
+	return entries, nil
+}
+
+// queryBatch sends a single batch request.
+func (s *OSVSource) queryBatch(ctx context.Context, packages []PackageVersion) ([]Entry, error) {
+	queries := make([]osvQuery, len(packages))
+	for i, pkg := range packages {
+		queries[i].Package.Name = pkg.Name
+		queries[i].Package.Ecosystem = "npm"
+		queries[i].Version = pkg.Version
+	}
+
+	body, err := json.Marshal(map[string][]osvQuery{"queries": queries})
+	if err != nil {
+		return nil, err
+	}
+
+	url := s.URL
+	if url == "" {
+		url = DefaultOSVURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("query OSV: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("query OSV: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("query OSV: HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read OSV response: %w", err)
+	}
+
+	var result osvBatchResponse
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("parse OSV response: %w", err)
+	}
+	if len(result.Results) != len(packages) {
+		return nil, fmt.Errorf("parse OSV response: got %d results for %d queries", len(result.Results), len(packages))
+	}
+
+	var entries []Entry
+	for i, r := range result.Results {
+		if len(r.Vulns) == 0 {
+			continue
+		}
+		var advisory Advisory
+		for _, vuln := range r.Vulns {
+			advisory.IDs = append(advisory.IDs, vuln.ID)
+		}
+		advisory.URL = osvAdvisoryURL + advisory.IDs[0]
+		entries = append(entries, Entry{Package: packages[i].Name, Version: packages[i].Version, Advisory: advisory, Source: SourceOSV})
+	}
+	return entries, nil
+}
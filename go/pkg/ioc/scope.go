@@ -0,0 +1,149 @@
+package ioc
+
+import (
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+)
+
+// AnyVersion is the version of entries listing every version of a
+// package, as in the compromised-scope entry "@ctrl/*,= *".
+const AnyVersion = "*"
+
+// scopeEntry is a parsed compromised-scope entry, listing versions of
+// every package under a scope.
+type scopeEntry struct {
+	// pattern is the entry's package name, such as "@ctrl/*"
+	pattern string
+	// key indexes the entry's metadata
+	key string
+	// any is set when the entry lists every version
+	any bool
+	// r is the entry's version or range, unless any is set
+	r npmsemver.Range
+}
+
+// ScopePattern returns the scope of a compromised-scope entry name such as
+// "@ctrl/*", which lists every package under the scope ("@ctrl").
+func ScopePattern(name string) (string, bool) {
+	scope, rest, ok := strings.Cut(name, "/")
+	if !ok || rest != "*" || len(scope) < 2 || scope[0] != '@' {
+		return "", false
+	}
+	return scope, true
+}
+
+// scopeOf returns the scope of a scoped package name such as
+// "@ctrl/tinycolor".
+func scopeOf(pkg string) (string, bool) {
+	if !strings.HasPrefix(pkg, "@") {
+		return "", false
+	}
+	scope, rest, ok := strings.Cut(pkg, "/")
+	if !ok || rest == "" || rest == "*" {
+		return "", false
+	}
+	return scope, true
+}
+
+// isAnyVersion reports whether a version cell part such as "= *" lists
+// every version.
+func isAnyVersion(spec string) bool {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(spec), "=")) == AnyVersion
+}
+
+// isScopeWildcard reports whether spec lists every version of the packages
+// under a compromised-scope entry name. Such entries get Version
+// AnyVersion, which also covers prereleases, rather than the range "*".
+func isScopeWildcard(name, spec string) bool {
+	_, ok := ScopePattern(name)
+	return ok && isAnyVersion(spec)
+}
+
+// newScopeEntry parses a compromised-scope entry.
+func newScopeEntry(entry Entry) (scopeEntry, error) {
+	spec := entry.Version
+	if entry.Range != "" {
+		spec = entry.Range
+	}
+	scoped := scopeEntry{pattern: entry.Package, key: entry.key()}
+	if isAnyVersion(spec) {
+		scoped.any = true
+		return scoped, nil
+	}
+	r, err := npmsemver.ParseRange(spec)
+	if err != nil {
+		return scopeEntry{}, err
+	}
+	scoped.r = r
+	return scoped, nil
+}
+
+// hasScope reports whether an entry with key is already listed for scope.
+func (s *snapshot) hasScope(scope, key string) bool {
+	for _, entry := range s.scopes[scope] {
+		if entry.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// matchScope returns the first entry of pkg's scope that lists ver.
+func (s *snapshot) matchScope(pkg, ver string) (scopeEntry, bool) {
+	scope, ok := scopeOf(pkg)
+	if !ok {
+		return scopeEntry{}, false
+	}
+
+	var v *semver.Version
+	for _, entry := range s.scopes[scope] {
+		if entry.any {
+			return entry, true
+		}
+		if v == nil {
+			parsed, err := semver.NewVersion(ver)
+			if err != nil {
+				return scopeEntry{}, false
+			}
+			v = parsed
+		}
+		if entry.r.Satisfies(v) {
+			return entry, true
+		}
+	}
+	return scopeEntry{}, false
+}
+
+// MatchedScope returns the compromised-scope entry name, such as "@ctrl/*",
+// that lists pkg@ver, for versions that are in the database only through
+// a scope entry.
+func (d *Database) MatchedScope(pkg, ver string) (string, bool) {
+	s := d.load()
+
+	if containsString(s.ioc[pkg], ver) {
+		return "", false
+	}
+	if _, ok := s.matchRange(pkg, ver); ok {
+		return "", false
+	}
+	entry, ok := s.matchScope(pkg, ver)
+	return entry.pattern, ok
+}
+
+// CompromisedScope returns the compromised-scope entry name listing every
+// version of pkg, if there is one: whatever version of pkg is installed
+// is compromised.
+func (d *Database) CompromisedScope(pkg string) (string, bool) {
+	scope, ok := scopeOf(pkg)
+	if !ok {
+		return "", false
+	}
+	for _, entry := range d.load().scopes[scope] {
+		if entry.any {
+			return entry.pattern, true
+		}
+	}
+	return "", false
+}
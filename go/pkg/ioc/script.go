@@ -0,0 +1,90 @@
+package ioc
+
+import (
+	"regexp"
+	"strings"
+)
+
+// scriptRegexpPrefix marks a script indicator that is a regular expression
+// rather than a string, as in "re:curl .*\| *sh".
+const scriptRegexpPrefix = "re:"
+
+// ScriptIndicator is a known-malicious string of package.json scripts,
+// such as the postinstall payload a worm injects into every package it
+// republishes.
+type ScriptIndicator struct {
+	// Pattern is the indicator as listed by its source
+	Pattern string
+	// Entry is the first entry that listed the indicator
+	Entry Entry
+}
+
+// scriptIndicator is a parsed script indicator.
+type scriptIndicator struct {
+	ScriptIndicator
+	// text is the whitespace-normalized string of a string indicator
+	text string
+	// re is the expression of a regular expression indicator
+	re *regexp.Regexp
+}
+
+// addScript indexes the script indicator pattern listed by entry, unless
+// it is already indexed, up to whitespace. Invalid regular expressions are
+// skipped.
+func (s *snapshot) addScript(pattern string, entry Entry) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return
+	}
+
+	indicator := scriptIndicator{ScriptIndicator: ScriptIndicator{Pattern: pattern, Entry: entry}}
+	if expr, ok := strings.CutPrefix(pattern, scriptRegexpPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return
+		}
+		indicator.re = re
+	} else {
+		indicator.text = normalizeScript(pattern)
+	}
+
+	for _, existing := range s.scripts {
+		if existing.Pattern == pattern || (indicator.text != "" && existing.text == indicator.text) {
+			return
+		}
+	}
+	s.scripts = append(s.scripts, indicator)
+}
+
+// MatchScript returns the first script indicator found in script, the
+// command of a package.json script. String indicators match anywhere in
+// the command, ignoring differences in whitespace; indicators prefixed
+// with "re:" are regular expressions.
+func (d *Database) MatchScript(script string) (ScriptIndicator, bool) {
+	s := d.load()
+	if len(s.scripts) == 0 {
+		return ScriptIndicator{}, false
+	}
+
+	normalized := normalizeScript(script)
+	for _, indicator := range s.scripts {
+		if indicator.re != nil {
+			if indicator.re.MatchString(script) {
+				return indicator.ScriptIndicator, true
+			}
+		} else if strings.Contains(normalized, indicator.text) {
+			return indicator.ScriptIndicator, true
+		}
+	}
+	return ScriptIndicator{}, false
+}
+
+// ScriptCount returns the number of malicious script indicators.
+func (d *Database) ScriptCount() int {
+	return len(d.load().scripts)
+}
+
+// normalizeScript collapses the runs of whitespace in a script command.
+func normalizeScript(script string) string {
+	return strings.Join(strings.Fields(script), " ")
+}
@@ -0,0 +1,61 @@
+package ioc
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Refresh the embedded snapshot before cutting a release.
+//go:generate sh -c "curl -fsSL https://raw.githubusercontent.com/wiz-sec-public/wiz-research-iocs/main/reports/shai-hulud-2-packages.csv -o snapshot/shai-hulud-2-packages.csv && date -u +%Y-%m-%d > snapshot/DATE"
+
+var (
+	//go:embed snapshot/shai-hulud-2-packages.csv
+	snapshotCSV []byte
+
+	//go:embed snapshot/DATE
+	snapshotDate string
+)
+
+// ErrNoSnapshot is returned by LoadSnapshot when the binary was built
+// without an embedded IoC snapshot.
+var ErrNoSnapshot = errors.New("this build has no embedded IoC snapshot (run go generate ./pkg/ioc before building)")
+
+// LoadSnapshot returns the IoC database compiled into the binary, for
+// scanning without network access, along with the date the snapshot was
+// taken. The snapshot is only as current as the build and may be missing
+// recently added entries.
+func LoadSnapshot() (*Database, time.Time, error) {
+	return loadSnapshot(snapshotCSV, snapshotDate)
+}
+
+// SnapshotDate returns the date the embedded snapshot was taken, or false
+// if the build has none.
+func SnapshotDate() (time.Time, bool) {
+	taken, err := time.Parse("2006-01-02", strings.TrimSpace(snapshotDate))
+	return taken, err == nil
+}
+
+// loadSnapshot parses snapshot data and its YYYY-MM-DD date.
+func loadSnapshot(data []byte, date string) (*Database, time.Time, error) {
+	date = strings.TrimSpace(date)
+	if date == "" {
+		return nil, time.Time{}, ErrNoSnapshot
+	}
+
+	taken, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("invalid IoC snapshot date %q: %w", date, err)
+	}
+
+	db, err := NewDatabase(data)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("parse IoC snapshot: %w", err)
+	}
+	if db.Size() == 0 {
+		return nil, time.Time{}, ErrNoSnapshot
+	}
+	return db, taken, nil
+}
@@ -0,0 +1,194 @@
+package ioc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Source names accepted by scanners and the CLI.
+const (
+	// SourceCSV is the shai-hulud IoC CSV (the default)
+	SourceCSV = "csv"
+	// SourceOSV is the OSV.dev vulnerability database
+	SourceOSV = "osv"
+	// SourceGHSA is the GitHub Advisory Database
+	SourceGHSA = "ghsa"
+	// SourceFile names the entries of a FileSource. It cannot be selected
+	// by name, since the source needs a path.
+	SourceFile = "file"
+)
+
+var (
+	registryMu sync.RWMutex
+	// registry holds the sources added with RegisterSource by name
+	registry = make(map[string]Source)
+)
+
+// RegisterSource makes src selectable by name in source lists (see
+// ParseSources), so programs embedding the scanner can match against their
+// own feeds. Entries fetched from src are attributed to name. The name must
+// not be empty, contain commas or whitespace, name a built-in source or be
+// registered already.
+func RegisterSource(name string, src Source) error {
+	switch {
+	case src == nil:
+		return errors.New("register IoC source: nil source")
+	case name == "" || strings.ContainsAny(name, ", \t\n"):
+		return fmt.Errorf("register IoC source: invalid name %q", name)
+	case name == SourceCSV || name == SourceOSV || name == SourceGHSA || name == SourceFile:
+		return fmt.Errorf("register IoC source: %q is a built-in source", name)
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, ok := registry[name]; ok {
+		return fmt.Errorf("register IoC source: %q is already registered", name)
+	}
+	registry[name] = src
+	return nil
+}
+
+// RegisteredSource returns the source registered under name.
+func RegisteredSource(name string) (Source, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	src, ok := registry[name]
+	return src, ok
+}
+
+// IsRegisteredSource reports whether name is a source added with
+// RegisterSource, as opposed to a built-in one.
+func IsRegisteredSource(name string) bool {
+	_, ok := RegisteredSource(name)
+	return ok
+}
+
+// NewDatabaseFromRegistered fetches the entries of the source registered
+// under name into a new Database, attributing each entry to name.
+func NewDatabaseFromRegistered(ctx context.Context, name string) (*Database, error) {
+	src, ok := RegisteredSource(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown IoC source %q", name)
+	}
+	entries, err := src.Fetch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %w", name, err)
+	}
+	for i := range entries {
+		entries[i].Source = name
+	}
+	return NewDatabaseFromEntries(entries), nil
+}
+
+// ParseSources parses a comma-separated list of source names, such as
+// "csv,osv". Names are built-in sources (SourceCSV, SourceOSV, SourceGHSA)
+// or sources added with RegisterSource. An empty list selects SourceCSV.
+// Duplicates are dropped.
+func ParseSources(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return []string{SourceCSV}, nil
+	}
+
+	var sources []string
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case SourceCSV, SourceOSV, SourceGHSA:
+		default:
+			if !IsRegisteredSource(name) {
+				return nil, fmt.Errorf("unknown IoC source %q (expected %s, %s, %s or a registered source)", name, SourceCSV, SourceOSV, SourceGHSA)
+			}
+		}
+		if !containsString(sources, name) {
+			sources = append(sources, name)
+		}
+	}
+	return sources, nil
+}
+
+// Source supplies IoC entries from a vulnerability feed. Built-in
+// sources are CSVSource, FileSource, OSVSource and GHSASource; others can
+// be added with RegisterSource.
+type Source interface {
+	// Fetch returns the entries of the feed. Entries with a Range are
+	// matched against every version in it.
+	Fetch(ctx context.Context) ([]Entry, error)
+}
+
+// PackageVersion identifies a single version of a package.
+type PackageVersion struct {
+	Name    string
+	Version string
+}
+
+// CSVSource fetches IoC entries from a CSV database, such as the default
+// shai-hulud campaign list, or from a JSON or YAML one (see DetectFormat).
+type CSVSource struct {
+	// URL of the CSV; if empty, DefaultIoCURL is used
+	URL string
+	// Header holds extra request headers, such as the Authorization
+	// header of a private feed
+	Header http.Header
+}
+
+// Fetch downloads and parses the database.
+func (s CSVSource) Fetch(ctx context.Context) ([]Entry, error) {
+	feed, err := FetchFeed(s.URL, s.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := ParseFeed(feed.Data, feed.Format())
+	if err != nil {
+		return nil, fmt.Errorf("parse IoC database: %w", err)
+	}
+	for i := range entries {
+		entries[i].Source = SourceCSV
+	}
+	return entries, nil
+}
+
+// FileSource reads IoC entries from a local database file in any format
+// (see ParseFeed), such as a feed mirrored to disk.
+type FileSource struct {
+	// Path of the file
+	Path string
+	// Format of the file; if empty, it is detected from the extension of
+	// Path and the content (see DetectFormat)
+	Format string
+}
+
+// Fetch reads and parses the file.
+func (s FileSource) Fetch(ctx context.Context) ([]Entry, error) {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("read IoC database: %w", err)
+	}
+
+	format := s.Format
+	if format == "" {
+		format = DetectFormat(data, s.Path, "")
+	}
+	entries, err := ParseFeed(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("parse IoC database %s: %w", s.Path, err)
+	}
+	for i := range entries {
+		entries[i].Source = SourceFile
+	}
+	return entries, nil
+}
+
+// NewDatabaseFromSource fetches the entries of src into a new Database.
+func NewDatabaseFromSource(ctx context.Context, src Source) (*Database, error) {
+	entries, err := src.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewDatabaseFromEntries(entries), nil
+}
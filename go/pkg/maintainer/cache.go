@@ -0,0 +1,125 @@
+package maintainer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+// DefaultTTL is how long a cached lookup is used before it is refreshed.
+// Published versions never change, but a version unknown to the registry
+// may since have been published, and a cache of every package ever
+// scanned should not grow forever.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// Cache stores publication lookups in a JSON file, keyed by
+// package@version.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// cacheEntry is one cached lookup. Publication is nil for a version the
+// registry does not know.
+type cacheEntry struct {
+	Publication *registry.Publication `json:"publication"`
+	FetchedAt   time.Time             `json:"fetchedAt"`
+}
+
+// DefaultCachePath returns the default cache file, below the user cache
+// directory (such as ~/.cache on Linux).
+func DefaultCachePath() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate cache directory: %w", err)
+	}
+	return filepath.Join(base, "npm-scan", "maintainers.json"), nil
+}
+
+// LoadCache reads the cache stored at path, whose entries are used for
+// ttl. A missing or unreadable file yields an empty cache: the cache only
+// ever saves requests.
+func LoadCache(path string, ttl time.Duration) *Cache {
+	c := &Cache{path: path, ttl: ttl, entries: make(map[string]cacheEntry)}
+	if data, err := os.ReadFile(path); err == nil {
+		var entries map[string]cacheEntry
+		if json.Unmarshal(data, &entries) == nil && entries != nil {
+			c.entries = entries
+		}
+	}
+	return c
+}
+
+// Save writes the cache back to its file, if lookups added entries.
+// Expired entries are dropped.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.Sub(entry.FetchedAt) > c.ttl {
+			delete(c.entries, key)
+		}
+	}
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := readonly.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	if err := readonly.WriteFile(c.path, data, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// get returns the cached publication of pkg, if it is fresh.
+func (c *Cache) get(pkg Package, now time.Time) (*registry.Publication, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cacheKey(pkg)]
+	if !ok || now.Sub(entry.FetchedAt) > c.ttl {
+		return nil, false
+	}
+	return entry.Publication, true
+}
+
+// put caches the publication of pkg.
+func (c *Cache) put(pkg Package, publication *registry.Publication, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(pkg)] = cacheEntry{Publication: publication, FetchedAt: now.UTC()}
+	c.dirty = true
+}
+
+// cacheKey identifies a package version in the cache.
+func cacheKey(pkg Package) string {
+	return pkg.Name + "@" + pkg.Version
+}
@@ -0,0 +1,239 @@
+// Package maintainer flags dependencies published or maintained by npm
+// accounts known to be compromised or malicious. Campaigns such as
+// shai-hulud spread through stolen maintainer tokens, so every package an
+// affected account can publish is suspect, including releases no IoC feed
+// lists yet.
+//
+// Who published a version, and who maintained the package at the time, is
+// looked up in the registry (see registry.Client.Publication). The metadata
+// of a version never changes once published, so lookups are cached on disk
+// (see Cache) and a repeated scan of the same tree sends no requests.
+//
+// A maintainer IoC list holds one npm user name or email address per line:
+//
+//	# Comments and blank lines are ignored
+//	compromised-user
+//	~another-user
+//	attacker@example.com
+package maintainer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+// Roles of a flagged account in a Finding.
+const (
+	// RolePublisher is the account that published the version
+	RolePublisher = "publisher"
+	// RoleMaintainer is an account that maintained the package when the
+	// version was published
+	RoleMaintainer = "maintainer"
+)
+
+// maxListSize bounds a maintainer IoC list fetched from a URL.
+const maxListSize = 16 << 20
+
+// workers bounds the concurrent registry requests of Check.
+const workers = 8
+
+// List is a set of flagged npm accounts, by user name and email address.
+// Both are compared case-insensitively.
+type List struct {
+	names  map[string]bool
+	emails map[string]bool
+}
+
+// New parses entries, one per element, skipping blank lines and comments.
+// An entry with an "@" after its first character is an email address;
+// other entries are user names, optionally written "~name" as on
+// npmjs.com.
+func New(entries []string) (*List, error) {
+	l := &List{names: make(map[string]bool), emails: make(map[string]bool)}
+	for _, line := range entries {
+		entry := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(line, "\r")))
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+		if strings.ContainsAny(entry, " \t,/") {
+			return nil, fmt.Errorf("invalid maintainer entry %q: expected an npm user name or email address", entry)
+		}
+		if strings.LastIndex(entry, "@") > 0 {
+			l.emails[entry] = true
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(entry, "~"), "@")
+		if name == "" {
+			return nil, fmt.Errorf("invalid maintainer entry %q: expected an npm user name or email address", entry)
+		}
+		l.names[name] = true
+	}
+	return l, nil
+}
+
+// Load reads the list at location, a file path or an http(s) URL, if
+// location is not empty, followed by the extra entries.
+func Load(location string, extra []string) (*List, error) {
+	var lines []string
+	if location != "" {
+		data, err := read(location)
+		if err != nil {
+			return nil, fmt.Errorf("read maintainer IoCs: %w", err)
+		}
+		lines = strings.Split(string(data), "\n")
+	}
+	return New(append(lines, extra...))
+}
+
+// read returns the content of a file path or http(s) URL.
+func read(location string) ([]byte, error) {
+	if !strings.HasPrefix(location, "http://") && !strings.HasPrefix(location, "https://") {
+		return os.ReadFile(location)
+	}
+	resp, err := transport.Client().Get(location)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxListSize))
+}
+
+// Len returns the number of flagged names and email addresses.
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.names) + len(l.emails)
+}
+
+// Flagged reports whether account is on the list, by name or email.
+func (l *List) Flagged(account registry.Account) bool {
+	if l == nil {
+		return false
+	}
+	name, email := strings.ToLower(account.Name), strings.ToLower(account.Email)
+	return (name != "" && l.names[name]) || (email != "" && l.emails[email])
+}
+
+// Package is a package version to check.
+type Package struct {
+	Name    string
+	Version string
+}
+
+// Finding is a flagged account behind a package version.
+type Finding struct {
+	// Account is the flagged account
+	Account registry.Account
+	// Role is RolePublisher or RoleMaintainer
+	Role string
+}
+
+// Match returns the flagged account behind publication: its publisher if
+// flagged, else the first flagged maintainer.
+func (l *List) Match(publication *registry.Publication) (Finding, bool) {
+	if publication == nil {
+		return Finding{}, false
+	}
+	if l.Flagged(publication.Publisher) {
+		return Finding{Account: publication.Publisher, Role: RolePublisher}, true
+	}
+	for _, account := range publication.Maintainers {
+		if l.Flagged(account) {
+			return Finding{Account: account, Role: RoleMaintainer}, true
+		}
+	}
+	return Finding{}, false
+}
+
+// Checker looks up who is behind package versions and matches them
+// against a List.
+type Checker struct {
+	// Registry fetches the publication metadata
+	Registry *registry.Client
+	// List holds the flagged accounts
+	List *List
+	// Cache, if set, is consulted before and updated after each lookup
+	Cache *Cache
+}
+
+// Check looks up every package concurrently and returns the findings of
+// those published or maintained by a flagged account. Packages whose
+// lookup failed are skipped, and their errors are returned together.
+func (c *Checker) Check(ctx context.Context, packages []Package) (map[Package]Finding, error) {
+	findings := make(map[Package]Finding)
+	failed := make(map[Package]error)
+	var mu sync.Mutex
+
+	jobs := make(chan Package)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(packages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pkg := range jobs {
+				publication, err := c.publication(ctx, pkg)
+				mu.Lock()
+				if err != nil {
+					failed[pkg] = err
+				} else if finding, ok := c.List.Match(publication); ok {
+					findings[pkg] = finding
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, pkg := range packages {
+		jobs <- pkg
+	}
+	close(jobs)
+	wg.Wait()
+
+	failedPackages := make([]Package, 0, len(failed))
+	for pkg := range failed {
+		failedPackages = append(failedPackages, pkg)
+	}
+	sort.Slice(failedPackages, func(i, j int) bool {
+		if failedPackages[i].Name != failedPackages[j].Name {
+			return failedPackages[i].Name < failedPackages[j].Name
+		}
+		return failedPackages[i].Version < failedPackages[j].Version
+	})
+	errs := make([]error, len(failedPackages))
+	for i, pkg := range failedPackages {
+		errs[i] = failed[pkg]
+	}
+	return findings, errors.Join(errs...)
+}
+
+// publication returns the publication of pkg, from the cache if it holds
+// it. Versions unknown to the registry are cached too, as having none.
+func (c *Checker) publication(ctx context.Context, pkg Package) (*registry.Publication, error) {
+	if publication, ok := c.Cache.get(pkg, time.Now()); ok {
+		return publication, nil
+	}
+	client := c.Registry
+	if client == nil {
+		client = &registry.Client{}
+	}
+	publication, err := client.Publication(ctx, pkg.Name, pkg.Version)
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.put(pkg, publication, time.Now())
+	return publication, nil
+}
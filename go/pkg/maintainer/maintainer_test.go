@@ -0,0 +1,165 @@
+package maintainer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+// newTestRegistry serves versions published by alice, maintained by
+// mallory, and unknown to anyone; it fails for broken and counts requests
+func newTestRegistry(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/published/1.0.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "Alice", "email": "alice@example.com"}, "maintainers": [{"name": "alice"}]}`)
+		case "/maintained/2.0.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "bob"}, "maintainers": [{"name": "bob"}, {"name": "carol", "email": "MALLORY@example.com"}]}`)
+		case "/clean/3.0.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "bob"}, "maintainers": [{"name": "bob"}]}`)
+		case "/broken/1.0.0":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNew(t *testing.T) {
+	list, err := New([]string{"# flagged", "", "Alice\r", "~bob", "@carol", "mallory@example.com"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if list.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", list.Len())
+	}
+
+	tests := []struct {
+		account registry.Account
+		want    bool
+	}{
+		{registry.Account{Name: "alice"}, true},
+		{registry.Account{Name: "BOB"}, true},
+		{registry.Account{Name: "carol"}, true},
+		{registry.Account{Name: "dave", Email: "Mallory@Example.com"}, true},
+		{registry.Account{Name: "dave", Email: "dave@example.com"}, false},
+		{registry.Account{}, false},
+	}
+	for _, tt := range tests {
+		if got := list.Flagged(tt.account); got != tt.want {
+			t.Errorf("Flagged(%+v) = %v, want %v", tt.account, got, tt.want)
+		}
+	}
+
+	for _, entry := range []string{"two words", "~", "scope/name"} {
+		if _, err := New([]string{entry}); err == nil {
+			t.Errorf("New(%q) expected an error", entry)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maintainers.txt")
+	if err := os.WriteFile(path, []byte("alice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "alice\nbob\n")
+	}))
+	defer server.Close()
+
+	for _, tt := range []struct {
+		location string
+		want     int
+	}{
+		{path, 2},
+		{server.URL, 3},
+		{"", 1},
+	} {
+		list, err := Load(tt.location, []string{"carol"})
+		if err != nil {
+			t.Fatalf("Load(%q) error = %v", tt.location, err)
+		}
+		if list.Len() != tt.want {
+			t.Errorf("Load(%q).Len() = %d, want %d", tt.location, list.Len(), tt.want)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	var requests atomic.Int32
+	list, err := New([]string{"alice", "mallory@example.com"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	checker := &Checker{Registry: &registry.Client{URL: newTestRegistry(t, &requests).URL}, List: list}
+
+	findings, err := checker.Check(context.Background(), []Package{
+		{"published", "1.0.0"}, {"maintained", "2.0.0"}, {"clean", "3.0.0"}, {"gone", "1.0.0"}, {"broken", "1.0.0"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "broken@1.0.0: HTTP 503") {
+		t.Errorf("Check() error = %v, want the failed lookup of broken", err)
+	}
+	if len(findings) != 2 {
+		t.Errorf("Check() = %v, want 2 findings", findings)
+	}
+	if got := findings[Package{"published", "1.0.0"}]; got.Role != RolePublisher || got.Account.Name != "Alice" {
+		t.Errorf("findings[published] = %+v, want published by Alice", got)
+	}
+	if got := findings[Package{"maintained", "2.0.0"}]; got.Role != RoleMaintainer || got.Account.Name != "carol" {
+		t.Errorf("findings[maintained] = %+v, want maintained by carol", got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestRegistry(t, &requests)
+	path := filepath.Join(t.TempDir(), "npm-scan", "maintainers.json")
+	list, err := New([]string{"alice"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	packages := []Package{{"published", "1.0.0"}, {"gone", "1.0.0"}}
+
+	checker := &Checker{Registry: &registry.Client{URL: server.URL}, List: list, Cache: LoadCache(path, time.Hour)}
+	if _, err := checker.Check(context.Background(), packages); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if err := checker.Cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	requests.Store(0)
+	checker.Cache = LoadCache(path, time.Hour)
+	findings, err := checker.Check(context.Background(), packages)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(findings) != 1 || requests.Load() != 0 {
+		t.Errorf("Cached check = %v after %d requests, want 1 finding without requests", findings, requests.Load())
+	}
+
+	checker.Cache = LoadCache(path, 0)
+	if _, err := checker.Check(context.Background(), packages); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected expired entries to be fetched again, got %d requests", requests.Load())
+	}
+}
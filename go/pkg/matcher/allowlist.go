@@ -0,0 +1,74 @@
+package matcher
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// unapprovedReason is the reason recorded with UNAPPROVED findings.
+const unapprovedReason = "package is not on the allowlist"
+
+// MatchUnapprovedDeclared returns an UNAPPROVED finding for each declared
+// dependency the allowlist does not approve. npm aliases are judged by the
+// package they install, not the name they are declared under.
+//
+// Parameters:
+//   - deps: Dependencies declared in a manifest, from parser.ExtractDependencies
+//   - list: Approved package names and scopes; nil approves everything
+//
+// Returns:
+//   - []formatter.Match: UNAPPROVED findings located at the manifest
+func MatchUnapprovedDeclared(deps []parser.Dependency, list *allowlist.Allowlist) []formatter.Match {
+	findings := []formatter.Match{}
+	for _, dep := range deps {
+		if list.Allows(dep.Name) {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:  dep.Name,
+			Severity:     formatter.SeverityUnapproved,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Alias:        dep.Alias,
+			Reason:       unapprovedReason,
+		})
+	}
+
+	return findings
+}
+
+// MatchUnapprovedResolved returns an UNAPPROVED finding for each resolved
+// or installed package the allowlist does not approve, once per version
+// and location.
+//
+// Parameters:
+//   - packages: Resolved packages, from a lockfile or node_modules
+//   - list: Approved package names and scopes; nil approves everything
+//
+// Returns:
+//   - []formatter.Match: UNAPPROVED findings located at the packages' LockfilePath
+func MatchUnapprovedResolved(packages []parser.ResolvedPackage, list *allowlist.Allowlist) []formatter.Match {
+	findings := []formatter.Match{}
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		if list.Allows(pkg.Name) {
+			continue
+		}
+		version := cleanVersionSpec(pkg.Version)
+		key := pkg.Name + "@" + version + "\x00" + pkg.LockfilePath
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		findings = append(findings, formatter.Match{
+			PackageName: pkg.Name,
+			Version:     version,
+			Severity:    formatter.SeverityUnapproved,
+			Location:    pkg.LockfilePath,
+			Reason:      unapprovedReason,
+		})
+	}
+
+	return findings
+}
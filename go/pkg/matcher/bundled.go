@@ -0,0 +1,45 @@
+package matcher
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// ReasonUncheckedBundled explains an UNCHECKED_BUNDLED finding.
+const ReasonUncheckedBundled = "bundled dependency not installed, version unknown"
+
+// MatchBundled checks the manifest's bundledDependencies, which declare no
+// version spec, against the IoC database using the versions installed in
+// node_modules next to filePath.
+//
+// Installed bundles are matched like lockfile entries and returned as
+// TRANSITIVE matches located at the bundle's package.json. Bundles that are
+// not installed cannot be checked; they are returned as UNCHECKED_BUNDLED
+// findings so the gap is visible.
+//
+// Parameters:
+//   - manifest: Parsed package.json manifest
+//   - iocDB: IoC vulnerability database
+//   - filePath: The source file path of the manifest
+//
+// Returns:
+//   - []formatter.Match: TRANSITIVE matches of installed bundles
+//   - []formatter.Match: UNCHECKED_BUNDLED findings of missing bundles
+func MatchBundled(manifest *parser.Manifest, iocDB *ioc.Database, filePath string) (matches, unchecked []formatter.Match) {
+	resolved, unresolved := parser.ResolveBundledDependencies(manifest, filePath)
+
+	matches = MatchResolved(resolved, iocDB)
+
+	unchecked = []formatter.Match{}
+	for _, dep := range unresolved {
+		unchecked = append(unchecked, formatter.Match{
+			PackageName: dep.Name,
+			Severity:    formatter.SeverityUncheckedBundled,
+			Location:    dep.FilePath,
+			Reason:      ReasonUncheckedBundled,
+		})
+	}
+
+	return matches, unchecked
+}
@@ -0,0 +1,67 @@
+package matcher
+
+import (
+	"sync"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+)
+
+// maxCached bounds each parse cache. A long-running process scanning many
+// repositories starts a cache over once it is full rather than let it grow
+// without bound; the specs and versions of a single scan fit many times.
+const maxCached = 50000
+
+// POTENTIAL matching checks every declared range against every vulnerable
+// version of its package. The same ranges ("^4.17.0") recur across the
+// manifests of a monorepo and the same vulnerable versions across its
+// dependencies, so both are parsed once and shared by all scans.
+var (
+	rangeCache   = newParseCache(npmsemver.ParseRange)
+	versionCache = newParseCache(semver.NewVersion)
+)
+
+// parseCache memoizes a parse function, including its errors, for
+// concurrent use.
+type parseCache[T any] struct {
+	parse   func(string) (T, error)
+	mu      sync.RWMutex
+	entries map[string]parsed[T]
+}
+
+// parsed is the outcome of parsing one string.
+type parsed[T any] struct {
+	value T
+	err   error
+}
+
+// newParseCache returns an empty cache of the results of parse.
+func newParseCache[T any](parse func(string) (T, error)) *parseCache[T] {
+	return &parseCache[T]{parse: parse, entries: make(map[string]parsed[T])}
+}
+
+// get returns the result of parsing s, parsing it on first use.
+func (c *parseCache[T]) get(s string) (T, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[s]
+	c.mu.RUnlock()
+	if ok {
+		return entry.value, entry.err
+	}
+
+	value, err := c.parse(s)
+	c.mu.Lock()
+	if len(c.entries) >= maxCached {
+		c.entries = make(map[string]parsed[T])
+	}
+	c.entries[s] = parsed[T]{value: value, err: err}
+	c.mu.Unlock()
+	return value, err
+}
+
+// len returns the number of cached results.
+func (c *parseCache[T]) len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.entries)
+}
@@ -0,0 +1,30 @@
+package matcher
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+)
+
+// CheckPackage looks up a single package version in iocDB, with the
+// advisory details of the entry listing it. Unlike the scan matchers it
+// needs no manifest or lockfile, for ad-hoc lookups of a pkg@version.
+func CheckPackage(iocDB *ioc.Database, name, version string) formatter.PackageCheck {
+	check := formatter.PackageCheck{Package: name, Version: version}
+	if iocDB == nil || !iocDB.Lookup(name, version) {
+		return check
+	}
+
+	check.Compromised = true
+	if spec, ok := iocDB.MatchedRange(name, version); ok {
+		check.IOCRange = spec
+	}
+	if pattern, ok := iocDB.MatchedScope(name, version); ok {
+		check.IOCScope = pattern
+	}
+	check.Advisory = advisoryFor(iocDB, name, version)
+	check.Sources = sourcesFor(iocDB, name, version)
+	if added, ok := iocDB.AddedAt(name, version); ok {
+		check.IOCAdded = &added
+	}
+	return check
+}
@@ -0,0 +1,97 @@
+package matcher
+
+import (
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// Hygiene finding reasons.
+const (
+	// ReasonWildcard flags "*", "x" or empty specs that accept any version
+	ReasonWildcard = "wildcard version spec"
+	// ReasonLatest flags the "latest" dist-tag
+	ReasonLatest = "latest dist-tag"
+	// ReasonUnbounded flags ranges with a lower bound but no upper bound
+	ReasonUnbounded = "unbounded version range"
+	// ReasonNoLockfile flags projects without any lockfile
+	ReasonNoLockfile = "no lockfile"
+)
+
+// AuditHygiene checks package.json dependencies for unpinned specs that
+// accept any newly published version. Returns findings with HYGIENE severity.
+//
+// These are not compromises themselves, but they are the conditions under
+// which a POTENTIAL match becomes a real one on the next install.
+//
+// Parameters:
+//   - manifest: Parsed package.json manifest
+//   - filePath: The source file path for reference
+//
+// Returns:
+//   - []formatter.Match: Slice of HYGIENE findings
+func AuditHygiene(manifest *parser.Manifest, filePath string) []formatter.Match {
+	findings := []formatter.Match{}
+
+	for _, dep := range parser.ExtractDependencies(manifest, filePath) {
+		if dep.Type == "bundledDependencies" {
+			continue
+		}
+
+		reason := unpinnedReason(dep.VersionSpec)
+		if reason == "" {
+			continue
+		}
+
+		findings = append(findings, formatter.Match{
+			PackageName:  dep.Name,
+			Severity:     formatter.SeverityHygiene,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Reason:       reason,
+		})
+	}
+
+	return findings
+}
+
+// NoLockfileFinding returns the HYGIENE finding for a manifest whose project
+// has no lockfile, so installs resolve whatever is newest at install time.
+func NoLockfileFinding(manifest *parser.Manifest, filePath string) formatter.Match {
+	name := manifest.Name
+	if name == "" {
+		name = "(unnamed project)"
+	}
+	return formatter.Match{
+		PackageName: name,
+		Severity:    formatter.SeverityHygiene,
+		Location:    filePath,
+		Reason:      ReasonNoLockfile,
+	}
+}
+
+// unpinnedReason classifies a version spec, returning the hygiene reason if
+// it is extremely wide, or an empty string otherwise.
+func unpinnedReason(spec string) string {
+	spec = strings.TrimSpace(spec)
+
+	switch strings.ToLower(spec) {
+	case "*", "x", "x.x", "x.x.x", "":
+		return ReasonWildcard
+	case "latest":
+		return ReasonLatest
+	}
+
+	for _, part := range strings.Split(spec, "||") {
+		part = strings.TrimSpace(part)
+		if part == "*" || part == "x" {
+			return ReasonWildcard
+		}
+		if strings.HasPrefix(part, ">") && !strings.Contains(part, "<") {
+			return ReasonUnbounded
+		}
+	}
+
+	return ""
+}
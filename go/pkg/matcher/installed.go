@@ -0,0 +1,76 @@
+package matcher
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// Shadowed finding reasons.
+const (
+	// ReasonInstalledMismatch flags an installed version the lockfile does not resolve
+	ReasonInstalledMismatch = "installed version differs from lockfile"
+	// ReasonNotLocked flags an installed package absent from the lockfile
+	ReasonNotLocked = "installed package not in lockfile"
+)
+
+// MatchInstalled compares the packages installed in node_modules against
+// the versions a lockfile resolves and returns a SHADOWED finding for each
+// installed package whose version the lockfile does not list. A tampered
+// node_modules is invisible to lockfile scanning, so these are the
+// installed copies a lockfile scan vouches for but does not describe.
+//
+// Packages are compared by name: an installed version is expected if the
+// lockfile resolves the package to it anywhere in the tree.
+//
+// Parameters:
+//   - installed: Installed packages, from parser.FindInstalledPackages
+//   - locked: Packages resolved by the lockfile of the same project
+//
+// Returns:
+//   - []formatter.Match: SHADOWED findings located at the installed package.json
+func MatchInstalled(installed, locked []parser.ResolvedPackage) []formatter.Match {
+	lockedVersions := make(map[string]map[string]bool)
+	for _, pkg := range locked {
+		if lockedVersions[pkg.Name] == nil {
+			lockedVersions[pkg.Name] = make(map[string]bool)
+		}
+		lockedVersions[pkg.Name][cleanVersionSpec(pkg.Version)] = true
+	}
+
+	findings := []formatter.Match{}
+	for _, pkg := range installed {
+		version := cleanVersionSpec(pkg.Version)
+		versions, ok := lockedVersions[pkg.Name]
+		if ok && versions[version] {
+			continue
+		}
+
+		finding := formatter.Match{
+			PackageName: pkg.Name,
+			Version:     version,
+			Severity:    formatter.SeverityShadowed,
+			Location:    pkg.LockfilePath,
+			Reason:      ReasonNotLocked,
+		}
+		if ok {
+			finding.Reason = ReasonInstalledMismatch
+			finding.LockedVersion = joinVersions(versions)
+		}
+		findings = append(findings, finding)
+	}
+
+	return findings
+}
+
+// joinVersions lists a set of versions in sorted order.
+func joinVersions(versions map[string]bool) string {
+	list := make([]string, 0, len(versions))
+	for version := range versions {
+		list = append(list, version)
+	}
+	sort.Strings(list)
+	return strings.Join(list, ", ")
+}
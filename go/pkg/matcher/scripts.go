@@ -0,0 +1,81 @@
+package matcher
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// MatchScripts returns a SCRIPT finding for each script of a manifest whose
+// command holds a malicious script indicator of the IoC database, such as
+// the postinstall payload a worm injects into the packages it republishes.
+//
+// Parameters:
+//   - manifest: Parsed package.json manifest
+//   - iocDB: IoC database whose script indicators are matched
+//   - filePath: Path to the manifest, recorded as the findings' location
+//
+// Returns:
+//   - []formatter.Match: SCRIPT findings, in script name order
+func MatchScripts(manifest *parser.Manifest, iocDB *ioc.Database, filePath string) []formatter.Match {
+	return matchScripts(manifest.Name, manifest.Version, manifest.Scripts, iocDB, filePath)
+}
+
+// MatchScriptsInstalled returns the SCRIPT findings of installed packages,
+// from parser.FindInstalledPackages, located at their package.json.
+func MatchScriptsInstalled(installed []parser.ResolvedPackage, iocDB *ioc.Database) []formatter.Match {
+	findings := []formatter.Match{}
+	for _, pkg := range installed {
+		findings = append(findings, matchScripts(pkg.Name, pkg.Version, pkg.Scripts, iocDB, pkg.LockfilePath)...)
+	}
+	return findings
+}
+
+// matchScripts returns the SCRIPT findings of the scripts of package
+// name@version, whose package.json is at filePath.
+func matchScripts(name, version string, scripts map[string]string, iocDB *ioc.Database, filePath string) []formatter.Match {
+	if len(scripts) == 0 || iocDB.ScriptCount() == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(scripts))
+	for script := range scripts {
+		names = append(names, script)
+	}
+	sort.Strings(names)
+
+	var findings []formatter.Match
+	for _, script := range names {
+		indicator, ok := iocDB.MatchScript(scripts[script])
+		if !ok {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:     name,
+			Version:         version,
+			Severity:        formatter.SeverityScript,
+			Location:        filePath,
+			Script:          script,
+			ScriptIndicator: indicator.Pattern,
+			Reason:          fmt.Sprintf("%s script runs %q", script, truncateScript(scripts[script])),
+			Advisory:        convertAdvisory(indicator.Entry.Advisory),
+			Sources:         integritySources(indicator.Entry),
+		})
+	}
+	return findings
+}
+
+// maxScriptLength bounds the command quoted in a SCRIPT finding's reason,
+// since payloads may be long inline scripts.
+const maxScriptLength = 200
+
+// truncateScript shortens a command to maxScriptLength bytes.
+func truncateScript(command string) string {
+	if len(command) <= maxScriptLength {
+		return command
+	}
+	return command[:maxScriptLength] + "..."
+}
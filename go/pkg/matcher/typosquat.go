@@ -0,0 +1,41 @@
+package matcher
+
+import (
+	"fmt"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+)
+
+// MatchTyposquatsDeclared returns a WARNING finding for each declared
+// dependency whose name is one typo away from a popular package. npm
+// aliases are judged by the package they install, not the name they are
+// declared under.
+//
+// Parameters:
+//   - deps: Dependencies declared in a manifest, from parser.ExtractDependencies
+//   - detector: Popular package names; nil flags nothing
+//
+// Returns:
+//   - []formatter.Match: WARNING findings located at the manifest
+func MatchTyposquatsDeclared(deps []parser.Dependency, detector *typosquat.Detector) []formatter.Match {
+	findings := []formatter.Match{}
+	for _, dep := range deps {
+		suspect, ok := detector.Check(dep.Name)
+		if !ok {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:  dep.Name,
+			Severity:     formatter.SeverityWarning,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Alias:        dep.Alias,
+			Resembles:    suspect.Target,
+			Reason:       fmt.Sprintf("name resembles popular package %s (%s)", suspect.Target, suspect.Edit),
+		})
+	}
+
+	return findings
+}
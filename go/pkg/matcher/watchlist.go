@@ -0,0 +1,107 @@
+package matcher
+
+import (
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
+)
+
+// watchlistReason describes the pattern a WATCHLIST finding matched.
+func watchlistReason(pattern string) string {
+	return "name matches watchlist pattern " + pattern
+}
+
+// MatchWatchlistDeclared returns a WATCHLIST finding for each declared
+// dependency whose name, or npm alias, matches a watchlist pattern,
+// whatever its version. The declared range is recorded as DeclaredSpec.
+//
+// Parameters:
+//   - deps: Dependencies declared in a manifest, from parser.ExtractDependencies
+//   - list: Watchlist of package name patterns
+//
+// Returns:
+//   - []formatter.Match: WATCHLIST findings located at the manifest
+func MatchWatchlistDeclared(deps []parser.Dependency, list *watchlist.Watchlist) []formatter.Match {
+	findings := []formatter.Match{}
+	if list.Len() == 0 {
+		return findings
+	}
+
+	for _, dep := range deps {
+		pattern, ok := list.Match(dep.Name)
+		if !ok && dep.Alias != "" {
+			pattern, ok = list.Match(dep.Alias)
+		}
+		if !ok {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:  dep.Name,
+			Severity:     formatter.SeverityWatchlist,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Alias:        dep.Alias,
+			Reason:       watchlistReason(pattern),
+		})
+	}
+
+	return findings
+}
+
+// MatchWatchlistResolved returns a WATCHLIST finding for each resolved or
+// installed package whose name matches a watchlist pattern, once per
+// version and location, in name, version and location order.
+//
+// Parameters:
+//   - packages: Resolved packages, from a lockfile or node_modules
+//   - list: Watchlist of package name patterns
+//
+// Returns:
+//   - []formatter.Match: WATCHLIST findings located at the packages' LockfilePath
+func MatchWatchlistResolved(packages []parser.ResolvedPackage, list *watchlist.Watchlist) []formatter.Match {
+	findings := []formatter.Match{}
+	if list.Len() == 0 {
+		return findings
+	}
+
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		pattern, ok := list.Match(pkg.Name)
+		if !ok {
+			continue
+		}
+		version := cleanVersionSpec(pkg.Version)
+		key := pkg.Name + "@" + version + "\x00" + pkg.LockfilePath
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		findings = append(findings, formatter.Match{
+			PackageName: pkg.Name,
+			Version:     version,
+			Severity:    formatter.SeverityWatchlist,
+			Location:    pkg.LockfilePath,
+			Reason:      watchlistReason(pattern),
+		})
+	}
+
+	sortResolvedFindings(findings)
+	return findings
+}
+
+// sortResolvedFindings sorts findings on resolved packages by name, version
+// and location, since lockfile parsers list packages in no fixed order.
+func sortResolvedFindings(findings []formatter.Match) {
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if a.PackageName != b.PackageName {
+			return a.PackageName < b.PackageName
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Location < b.Location
+	})
+}
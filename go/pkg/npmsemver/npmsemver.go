@@ -0,0 +1,371 @@
+// Package npmsemver evaluates version ranges the way npm (node-semver) does.
+package npmsemver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// Masterminds/semver diverges from npm in a few ways that matter when
+// deciding whether a range admits a compromised version: prerelease
+// handling, partial versions in primitive comparators (">1.2" means
+// ">=1.3.0" to npm), and it accepts syntax npm rejects, such as commas. The
+// types below follow node-semver's desugaring rules so a range matches
+// exactly the versions npm would install.
+
+// Range is a set of comparator sets joined by "||".
+type Range []comparatorSet
+
+// comparatorSet is a whitespace-separated list of comparators that must all
+// hold. A set with none set to true can never match (e.g. "<0.0.0-0" or ">*").
+type comparatorSet struct {
+	comparators []comparator
+	none        bool
+}
+
+// comparator is a single primitive comparison such as ">=1.2.3".
+type comparator struct {
+	op      string
+	version *semver.Version
+}
+
+var (
+	// partialVersion matches a possibly partial version with x-range
+	// wildcards, e.g. "1", "1.2.x", "v1.2.3-beta.1+build"
+	partialVersion = regexp.MustCompile(`^v?(\d+|[xX*])(?:\.(\d+|[xX*]))?(?:\.(\d+|[xX*]))?(?:-([0-9A-Za-z.-]+))?(?:\+[0-9A-Za-z.-]+)?$`)
+
+	// hyphenRange matches "A - B"
+	hyphenRange = regexp.MustCompile(`^\s*(\S+)\s+-\s+(\S+)\s*$`)
+
+	// operatorSpace matches an operator separated from its version by
+	// whitespace, as in ">= 1.2.3"
+	operatorSpace = regexp.MustCompile(`(<=|>=|<|>|=|~>|~|\^)\s+`)
+)
+
+// partial is a parsed partial version. parts counts the leading numeric
+// components; anything after them is a wildcard.
+type partial struct {
+	major, minor, patch uint64
+	pre                 string
+	parts               int
+}
+
+// ParseRange parses an npm version range. An empty range matches any
+// release.
+func ParseRange(spec string) (Range, error) {
+	var r Range
+	for _, setSpec := range strings.Split(spec, "||") {
+		set, err := parseComparatorSet(setSpec)
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, set)
+	}
+	return r, nil
+}
+
+// parseComparatorSet parses one "||"-separated part of a range.
+func parseComparatorSet(spec string) (comparatorSet, error) {
+	if m := hyphenRange.FindStringSubmatch(spec); m != nil {
+		return parseHyphenRange(m[1], m[2])
+	}
+
+	spec = operatorSpace.ReplaceAllString(strings.TrimSpace(spec), "$1")
+
+	var set comparatorSet
+	for _, token := range strings.Fields(spec) {
+		op, version := splitOperator(token)
+		p, err := parsePartial(version)
+		if err != nil {
+			return comparatorSet{}, err
+		}
+
+		comparators, none := desugar(op, p)
+		set.comparators = append(set.comparators, comparators...)
+		set.none = set.none || none
+	}
+	return set, nil
+}
+
+// parseHyphenRange desugars "A - B" into ">=A <=B", widening partial
+// versions the way npm does ("1.2 - 2.3" is ">=1.2.0 <2.4.0-0").
+func parseHyphenRange(from, to string) (comparatorSet, error) {
+	low, err := parsePartial(from)
+	if err != nil {
+		return comparatorSet{}, err
+	}
+	high, err := parsePartial(to)
+	if err != nil {
+		return comparatorSet{}, err
+	}
+
+	var set comparatorSet
+	if low.parts > 0 {
+		set.comparators = append(set.comparators, comparator{">=", low.floor()})
+	}
+	switch high.parts {
+	case 0:
+	case 3:
+		set.comparators = append(set.comparators, comparator{"<=", high.floor()})
+	default:
+		set.comparators = append(set.comparators, comparator{"<", high.ceiling()})
+	}
+	return set, nil
+}
+
+// splitOperator splits the leading comparison operator from a token.
+func splitOperator(token string) (string, string) {
+	for _, op := range []string{">=", "<=", "~>", ">", "<", "=", "~", "^"} {
+		if strings.HasPrefix(token, op) {
+			return op, token[len(op):]
+		}
+	}
+	return "", token
+}
+
+// parsePartial parses a possibly partial version such as "1", "1.2.x" or
+// "1.2.3-beta.1".
+func parsePartial(s string) (partial, error) {
+	if s == "" {
+		return partial{}, nil
+	}
+
+	m := partialVersion.FindStringSubmatch(s)
+	if m == nil {
+		return partial{}, fmt.Errorf("invalid version %q", s)
+	}
+
+	var p partial
+	for i, field := range []*uint64{&p.major, &p.minor, &p.patch} {
+		component := m[i+1]
+		if component == "" || component == "x" || component == "X" || component == "*" {
+			break
+		}
+		n, err := strconv.ParseUint(component, 10, 64)
+		if err != nil {
+			return partial{}, fmt.Errorf("invalid version %q", s)
+		}
+		*field = n
+		p.parts++
+	}
+
+	// A prerelease only applies to a complete version
+	if p.parts == 3 {
+		p.pre = m[4]
+	}
+	return p, nil
+}
+
+// floor returns the lowest version the partial covers: "1.2" -> 1.2.0.
+func (p partial) floor() *semver.Version {
+	return semver.New(p.major, p.minor, p.patch, p.pre, "")
+}
+
+// ceiling returns the exclusive upper bound of the partial, below any
+// prerelease of the next version: "1" -> 2.0.0-0, "1.2" -> 1.3.0-0.
+func (p partial) ceiling() *semver.Version {
+	switch p.parts {
+	case 1:
+		return semver.New(p.major+1, 0, 0, "0", "")
+	case 2:
+		return semver.New(p.major, p.minor+1, 0, "0", "")
+	default:
+		return semver.New(p.major, p.minor, p.patch+1, "0", "")
+	}
+}
+
+// desugar converts an operator and partial version into primitive
+// comparators. none is true when the comparator can never match.
+func desugar(op string, p partial) (comparators []comparator, none bool) {
+	if p.parts == 0 {
+		// Wildcards: "*", ">=*" and "<=*" match anything, "<*" and ">*" nothing
+		return nil, op == "<" || op == ">"
+	}
+
+	switch op {
+	case "", "=":
+		if p.parts == 3 {
+			return []comparator{{"=", p.floor()}}, false
+		}
+		return []comparator{{">=", p.floor()}, {"<", p.ceiling()}}, false
+
+	case "~", "~>":
+		// ~1.2.3 := >=1.2.3 <1.3.0-0; ~1 := >=1.0.0 <2.0.0-0
+		upper := partial{major: p.major, minor: p.minor, parts: 2}
+		if p.parts == 1 {
+			upper.parts = 1
+		}
+		return []comparator{{">=", p.floor()}, {"<", upper.ceiling()}}, false
+
+	case "^":
+		// Caret allows changes that do not modify the left-most non-zero
+		// component among those given
+		var upper partial
+		switch {
+		case p.major > 0 || p.parts == 1:
+			upper = partial{major: p.major, parts: 1}
+		case p.minor > 0 || p.parts == 2:
+			upper = partial{minor: p.minor, parts: 2}
+		default:
+			upper = partial{patch: p.patch, parts: 3}
+		}
+		return []comparator{{">=", p.floor()}, {"<", upper.ceiling()}}, false
+
+	case ">":
+		if p.parts == 3 {
+			return []comparator{{">", p.floor()}}, false
+		}
+		// >1.2 := >=1.3.0
+		next := p.ceiling()
+		return []comparator{{">=", semver.New(next.Major(), next.Minor(), next.Patch(), "", "")}}, false
+
+	case ">=":
+		return []comparator{{">=", p.floor()}}, false
+
+	case "<":
+		if p.parts == 3 {
+			return []comparator{{"<", p.floor()}}, false
+		}
+		// <1.2 := <1.2.0-0
+		return []comparator{{"<", semver.New(p.major, p.minor, 0, "0", "")}}, false
+
+	case "<=":
+		if p.parts == 3 {
+			return []comparator{{"<=", p.floor()}}, false
+		}
+		// <=1.2 := <1.3.0-0
+		return []comparator{{"<", p.ceiling()}}, false
+	}
+
+	return nil, true
+}
+
+// test reports whether v satisfies the comparator.
+func (c comparator) test(v *semver.Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.op {
+	case "=":
+		return cmp == 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// test reports whether v satisfies every comparator in the set. As in npm, a
+// prerelease version only satisfies the set if one of its comparators names
+// a prerelease of the same major.minor.patch, so "^1.0.0" does not pull in
+// "1.5.0-beta" but "^1.5.0-alpha" does.
+func (s comparatorSet) test(v *semver.Version) bool {
+	if s.none {
+		return false
+	}
+	for _, c := range s.comparators {
+		if !c.test(v) {
+			return false
+		}
+	}
+
+	if v.Prerelease() == "" {
+		return true
+	}
+	for _, c := range s.comparators {
+		if c.version.Prerelease() != "" &&
+			c.version.Major() == v.Major() &&
+			c.version.Minor() == v.Minor() &&
+			c.version.Patch() == v.Patch() {
+			return true
+		}
+	}
+	return false
+}
+
+// Satisfies reports whether v satisfies any comparator set of the range.
+func (r Range) Satisfies(v *semver.Version) bool {
+	for _, set := range r {
+		if set.test(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaxSatisfying returns the highest of versions that satisfies the range,
+// the one npm would install, or "" if none does. Versions that are not
+// valid semver are skipped.
+func (r Range) MaxSatisfying(versions []string) string {
+	var best *semver.Version
+	var bestRaw string
+	for _, raw := range versions {
+		v, err := semver.StrictNewVersion(raw)
+		if err != nil || !r.Satisfies(v) {
+			continue
+		}
+		if best == nil || v.GreaterThan(best) {
+			best, bestRaw = v, raw
+		}
+	}
+	return bestRaw
+}
+
+// Wide reports whether the range admits a release in a newer minor or major
+// line than its lower bound, so a future publish can be installed without a
+// manifest change. "^1.2.0", "~1", ">=1.0.0" and "<2.0.0" are wide;
+// "1.2.3", "~1.2.0", "^0.2.0" and ">=1.0.0 <1.1.0" are not.
+func (r Range) Wide() bool {
+	if len(r) == 0 {
+		return true
+	}
+	for _, set := range r {
+		if set.wide() {
+			return true
+		}
+	}
+	return false
+}
+
+// wide reports whether the set's upper bound, if any, lies beyond the first
+// release of the minor line after its lower bound.
+func (s comparatorSet) wide() bool {
+	if s.none {
+		return false
+	}
+
+	lower := semver.New(0, 0, 0, "", "")
+	var upper *comparator
+	for i, c := range s.comparators {
+		switch c.op {
+		case "=":
+			return false
+		case ">", ">=":
+			if c.version.GreaterThan(lower) {
+				lower = c.version
+			}
+		case "<", "<=":
+			if upper == nil || c.version.LessThan(upper.version) {
+				upper = &s.comparators[i]
+			}
+		}
+	}
+	if upper == nil {
+		return true
+	}
+
+	// Compare release numbers only, so "<2.0.0-0" bounds like "<2.0.0"
+	nextMinor := semver.New(lower.Major(), lower.Minor()+1, 0, "", "")
+	bound := semver.New(upper.version.Major(), upper.version.Minor(), upper.version.Patch(), "", "")
+	if upper.op == "<=" {
+		return !bound.LessThan(nextMinor)
+	}
+	return bound.GreaterThan(nextMinor)
+}
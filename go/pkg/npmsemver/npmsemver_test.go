@@ -0,0 +1,137 @@
+package npmsemver
+
+import (
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// TestRangeSatisfies tests evaluating npm ranges, including the forms IoC
+// feeds use for affected versions.
+func TestRangeSatisfies(t *testing.T) {
+	tests := []struct {
+		spec    string
+		version string
+		want    bool
+	}{
+		{"< 4.17.21", "4.17.20", true},
+		{"< 4.17.21", "4.17.21", false},
+		{">=1.0.0 <1.3.0", "1.2.9", true},
+		{">=1.0.0 <1.3.0", "1.3.0", false},
+		{">=1.0.0 <1.3.0", "0.9.0", false},
+		{">= 1.0.0 < 1.3.0", "1.0.0", true},
+		{"1.0.0 - 1.2.0", "1.2.0", true},
+		{"1.2.x", "1.2.7", true},
+		{"1.2.x", "1.3.0", false},
+		{"^1.2.0 || ^2.0.0", "2.4.1", true},
+		{"<1.3.0", "1.3.0-beta.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec+"/"+tt.version, func(t *testing.T) {
+			r, err := ParseRange(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error = %v", tt.spec, err)
+			}
+			if got := r.Satisfies(semver.MustParse(tt.version)); got != tt.want {
+				t.Errorf("ParseRange(%q).Satisfies(%s) = %v, want %v", tt.spec, tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseRange_Invalid tests rejecting specs npm would not accept.
+func TestParseRange_Invalid(t *testing.T) {
+	for _, spec := range []string{"latest", ">=1.0.0, <2.0.0", "1.0.0 -"} {
+		if _, err := ParseRange(spec); err == nil {
+			t.Errorf("ParseRange(%q) should fail", spec)
+		}
+	}
+}
+
+// TestRangeMaxSatisfying tests picking the version npm would install.
+func TestRangeMaxSatisfying(t *testing.T) {
+	versions := []string{"1.2.0", "1.10.0", "1.9.3", "2.0.0", "2.1.0-beta.1", "not-a-version"}
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"^1.2.0", "1.10.0"},
+		{"~1.9.0", "1.9.3"},
+		{"*", "2.0.0"},
+		{">=3.0.0", ""},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRange(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) error = %v", tt.spec, err)
+		}
+		if got := r.MaxSatisfying(versions); got != tt.want {
+			t.Errorf("ParseRange(%q).MaxSatisfying() = %q, want %q", tt.spec, got, tt.want)
+		}
+	}
+}
+
+// TestRangeWide tests classifying ranges by whether they admit newer minor
+// or major releases, including partial tilde/caret versions and one-sided
+// comparators.
+func TestRangeWide(t *testing.T) {
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"*", true},
+		{"", true},
+		{"1.x", true},
+		{"1", true},
+		{"1.2.x", false},
+		{"1.2.3", false},
+		{"=1.2.3", false},
+		{"1.2.3 || 1.2.4", false},
+		{"1.2.3 || ^2.0.0", true},
+
+		// Tilde on partial versions
+		{"~1", true},
+		{"~1.2", false},
+		{"~1.2.0", false},
+		{"~0", true},
+
+		// Caret on partial versions
+		{"^1", true},
+		{"^1.2", true},
+		{"^1.2.0", true},
+		{"^0", true},
+		{"^0.2", false},
+		{"^0.2.0", false},
+		{"^0.0.3", false},
+
+		// One-sided comparators
+		{">=1.0.0", true},
+		{">1.2.3", true},
+		{"<2.0.0", true},
+		{"<=1.0.0", true},
+		{"<0.1.0", false},
+		{"<=0.0.9", false},
+
+		// Bounded comparators
+		{">=1.0.0 <1.1.0", false},
+		{">=1.0.0 <=1.1.0", true},
+		{">=1.2.0 <=1.2.9", false},
+		{">=1.0.0 <2.0.0", true},
+		{"1.2.0 - 1.2.9", false},
+		{"1.0.0 - 2.0.0", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			r, err := ParseRange(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseRange(%q) error = %v", tt.spec, err)
+			}
+			if got := r.Wide(); got != tt.want {
+				t.Errorf("ParseRange(%q).Wide() = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
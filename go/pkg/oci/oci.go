@@ -0,0 +1,305 @@
+// Package oci reads the npm files of container images, so built images can
+// be scanned like source trees. Images are read from archives written by
+// docker save (or podman save, skopeo and other OCI layout exporters) or
+// pulled from a registry over the Docker Registry HTTP API V2.
+//
+// Layers are applied in order, honoring whiteouts, but only manifests,
+// lockfiles and installed package.json files are kept, in memory. Nothing
+// is written to disk.
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// maxFileSize bounds the size of a single npm file read from a layer.
+// Larger files are skipped.
+const maxFileSize = 64 << 20
+
+// maxMetadataSize bounds the size of the JSON metadata of an archive, such
+// as manifest.json and OCI manifests.
+const maxMetadataSize = 4 << 20
+
+// Whiteout markers of the OCI image layer specification.
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// Image is the npm view of a container image's filesystem.
+type Image struct {
+	// Reference is the image reference or archive path it was read from
+	Reference string
+	// Digest is the digest of the image manifest, if known
+	Digest string
+	// Files maps absolute paths in the image filesystem to the contents of
+	// the package.json, package-lock.json, npm-shrinkwrap.json and
+	// yarn.lock files found there
+	Files map[string][]byte
+}
+
+// Paths returns the paths of the image's files, sorted.
+func (img *Image) Paths() []string {
+	paths := make([]string, 0, len(img.Files))
+	for p := range img.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// IsNPMFile reports whether a file name is one of the npm files kept from
+// image layers.
+func IsNPMFile(name string) bool {
+	switch name {
+	case "package.json", "package-lock.json", "npm-shrinkwrap.json", "yarn.lock":
+		return true
+	}
+	return false
+}
+
+// layer is the change set of a single image layer, restricted to npm files.
+type layer struct {
+	files map[string][]byte
+	// removed holds the paths deleted by whiteouts, with everything below them
+	removed []string
+	// opaque holds the directories whose lower-layer contents are hidden
+	opaque []string
+}
+
+// readLayer reads the change set of a layer tarball, which may be
+// gzip-compressed.
+func readLayer(r io.Reader) (*layer, error) {
+	br := bufio.NewReader(r)
+	var source io.Reader = br
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompress layer: %w", err)
+		}
+		defer gz.Close()
+		source = gz
+	}
+
+	l := &layer{files: make(map[string][]byte)}
+	tr := tar.NewReader(source)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return l, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read layer: %w", err)
+		}
+
+		name := cleanPath(header.Name)
+		dir, base := path.Split(name)
+		switch {
+		case base == opaqueWhiteout:
+			l.opaque = append(l.opaque, path.Clean(dir))
+		case strings.HasPrefix(base, whiteoutPrefix):
+			l.removed = append(l.removed, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+		case header.Typeflag == tar.TypeReg && IsNPMFile(base) && header.Size <= maxFileSize:
+			content, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("read %s from layer: %w", name, err)
+			}
+			l.files[name] = content
+		}
+	}
+}
+
+// apply applies the layer's changes to files: whiteouts hide lower-layer
+// files, then the layer's own files are added.
+func (l *layer) apply(files map[string][]byte) {
+	hidden := append(append([]string{}, l.opaque...), l.removed...)
+	for _, dir := range hidden {
+		for p := range files {
+			if p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/") {
+				delete(files, p)
+			}
+		}
+	}
+	for p, content := range l.files {
+		files[p] = content
+	}
+}
+
+// cleanPath turns a tar entry name into an absolute, clean path.
+func cleanPath(name string) string {
+	return path.Clean("/" + name)
+}
+
+// archiveManifest is an entry of the manifest.json of a docker save archive.
+type archiveManifest struct {
+	Config   string   `json:"Config"`
+	RepoTags []string `json:"RepoTags"`
+	Layers   []string `json:"Layers"`
+}
+
+// ReadArchive reads the image saved in the tarball at path by docker save
+// (or podman save), or in an OCI image layout tarball. An archive holding
+// several images is refused.
+//
+// The archive is read in a single pass: layers can appear before the
+// metadata listing them, so every entry is read as a potential layer and
+// the change sets of the listed ones are applied at the end.
+func ReadArchive(archivePath string) (*Image, error) {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("open image archive: %w", err)
+	}
+	defer f.Close()
+
+	metadata := make(map[string][]byte)
+	layers := make(map[string]*layer)
+	layerErrors := make(map[string]error)
+
+	tr := tar.NewReader(f)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read image archive %s: %w", archivePath, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+
+		br := bufio.NewReader(tr)
+		if first, err := br.Peek(1); err == nil && (first[0] == '{' || first[0] == '[') && header.Size <= maxMetadataSize {
+			content, err := io.ReadAll(br)
+			if err != nil {
+				return nil, fmt.Errorf("read %s from image archive: %w", name, err)
+			}
+			metadata[name] = content
+			continue
+		}
+		l, err := readLayer(br)
+		if err != nil {
+			// Not necessarily a layer; only an error if listed as one
+			layerErrors[name] = err
+			continue
+		}
+		layers[name] = l
+	}
+
+	layerNames, digest, err := archiveLayers(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("image archive %s: %w", archivePath, err)
+	}
+
+	img := &Image{Reference: archivePath, Digest: digest, Files: make(map[string][]byte)}
+	for _, name := range layerNames {
+		l, ok := layers[name]
+		if !ok {
+			if err := layerErrors[name]; err != nil {
+				return nil, fmt.Errorf("image archive %s: layer %s: %w", archivePath, name, err)
+			}
+			return nil, fmt.Errorf("image archive %s: layer %s is missing", archivePath, name)
+		}
+		l.apply(img.Files)
+	}
+	return img, nil
+}
+
+// archiveLayers returns the archive entries holding the image's layers, in
+// order, from the docker save manifest.json or, failing that, the OCI
+// layout index.json. The manifest digest is only known for OCI layouts.
+func archiveLayers(metadata map[string][]byte) ([]string, string, error) {
+	if data, ok := metadata["manifest.json"]; ok {
+		var manifests []archiveManifest
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, "", fmt.Errorf("parse manifest.json: %w", err)
+		}
+		switch len(manifests) {
+		case 0:
+			return nil, "", errors.New("manifest.json lists no image")
+		case 1:
+		default:
+			return nil, "", fmt.Errorf("archive holds %d images; save one image per archive", len(manifests))
+		}
+		layers := make([]string, len(manifests[0].Layers))
+		for i, name := range manifests[0].Layers {
+			layers[i] = strings.TrimPrefix(path.Clean(name), "./")
+		}
+		return layers, "", nil
+	}
+
+	data, ok := metadata["index.json"]
+	if !ok {
+		return nil, "", errors.New("not an image archive: neither manifest.json nor index.json found")
+	}
+	var index imageIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, "", fmt.Errorf("parse index.json: %w", err)
+	}
+	blob := func(digest string) ([]byte, error) {
+		name := "blobs/" + strings.Replace(digest, ":", "/", 1)
+		content, ok := metadata[name]
+		if !ok {
+			return nil, fmt.Errorf("blob %s is missing", digest)
+		}
+		return content, nil
+	}
+
+	// Nested indexes are followed down to a single manifest
+	descriptors := index.Manifests
+	for {
+		switch len(descriptors) {
+		case 0:
+			return nil, "", errors.New("index.json lists no image")
+		case 1:
+		default:
+			return nil, "", fmt.Errorf("archive holds %d images; save one image per archive", len(descriptors))
+		}
+		content, err := blob(descriptors[0].Digest)
+		if err != nil {
+			return nil, "", err
+		}
+		if isIndex(descriptors[0].MediaType) {
+			var nested imageIndex
+			if err := json.Unmarshal(content, &nested); err != nil {
+				return nil, "", fmt.Errorf("parse index %s: %w", descriptors[0].Digest, err)
+			}
+			descriptors = nested.Manifests
+			continue
+		}
+
+		var manifest imageManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return nil, "", fmt.Errorf("parse manifest %s: %w", descriptors[0].Digest, err)
+		}
+		layers := make([]string, len(manifest.Layers))
+		for i, l := range manifest.Layers {
+			layers[i] = "blobs/" + strings.Replace(l.Digest, ":", "/", 1)
+		}
+		return layers, descriptors[0].Digest, nil
+	}
+}
+
+// decodeJSON decodes a JSON document of at most maxMetadataSize bytes.
+func decodeJSON(r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(r, maxMetadataSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxMetadataSize {
+		return fmt.Errorf("document exceeds %d bytes", maxMetadataSize)
+	}
+	return json.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
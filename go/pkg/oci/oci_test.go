@@ -0,0 +1,292 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// tarball builds a tar archive of the given entries, in order. Entries
+// named with a trailing "/" are directories.
+func tarball(t *testing.T, entries ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry[0], Mode: 0644, Size: int64(len(entry[1])), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(entry[0], "/") {
+			header = &tar.Header{Name: entry[0], Mode: 0755, Typeflag: tar.TypeDir}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(entry[1])); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gzipped compresses data.
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// digestOf returns the sha256 digest of data.
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// testLayers returns two layers: a base with an application and a stale
+// project, and a gzipped upper layer deleting and replacing files.
+func testLayers(t *testing.T) [][]byte {
+	base := tarball(t,
+		[2]string{"app/", ""},
+		[2]string{"app/package.json", `{"name":"app","dependencies":{"evil":"^1.0.0"}}`},
+		[2]string{"app/package-lock.json", `{"lockfileVersion":3}`},
+		[2]string{"app/node_modules/evil/package.json", `{"name":"evil","version":"1.0.1"}`},
+		[2]string{"app/node_modules/evil/index.js", `steal()`},
+		[2]string{"old/yarn.lock", "# old"},
+		[2]string{"cache/package.json", `{}`},
+	)
+	upper := gzipped(t, tarball(t,
+		[2]string{"./app/.wh.package-lock.json", ""},
+		[2]string{"./cache/.wh..wh..opq", ""},
+		[2]string{"./old/.wh.yarn.lock", ""},
+		[2]string{"./app/npm-shrinkwrap.json", `{"lockfileVersion":3}`},
+	))
+	return [][]byte{base, upper}
+}
+
+// wantFiles are the npm files of the image built from testLayers.
+var wantFiles = []string{
+	"/app/node_modules/evil/package.json",
+	"/app/npm-shrinkwrap.json",
+	"/app/package.json",
+}
+
+// TestParseReference tests image reference parsing and defaults
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		ref     string
+		want    Reference
+		wantErr bool
+	}{
+		{ref: "node", want: Reference{Registry: "docker.io", Repository: "library/node", Tag: "latest"}},
+		{ref: "node:20-app", want: Reference{Registry: "docker.io", Repository: "library/node", Tag: "20-app"}},
+		{ref: "org/app:1.0", want: Reference{Registry: "docker.io", Repository: "org/app", Tag: "1.0"}},
+		{ref: "ghcr.io/org/app:1.4.0", want: Reference{Registry: "ghcr.io", Repository: "org/app", Tag: "1.4.0"}},
+		{ref: "localhost:5000/app", want: Reference{Registry: "localhost:5000", Repository: "app", Tag: "latest"}},
+		{ref: "ghcr.io/org/app@sha256:abc", want: Reference{Registry: "ghcr.io", Repository: "org/app", Digest: "sha256:abc"}},
+		{ref: "node:20@sha256:abc", want: Reference{Registry: "docker.io", Repository: "library/node", Tag: "20", Digest: "sha256:abc"}},
+		{ref: "node:", wantErr: true},
+		{ref: "node@abc", wantErr: true},
+		{ref: "Org/App", wantErr: true},
+		{ref: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParseReference(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReference() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseReference() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestReadArchive_DockerSave tests reading a docker save archive, whose
+// manifest.json follows the layers
+func TestReadArchive_DockerSave(t *testing.T) {
+	layers := testLayers(t)
+	manifest := `[{"Config":"config.json","RepoTags":["app:latest"],"Layers":["base/layer.tar","upper/layer.tar"]}]`
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	data := tarball(t,
+		[2]string{"upper/layer.tar", string(layers[1])},
+		[2]string{"base/layer.tar", string(layers[0])},
+		[2]string{"config.json", `{"architecture":"amd64"}`},
+		[2]string{"manifest.json", manifest},
+	)
+	if err := os.WriteFile(archive, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := ReadArchive(archive)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	if got := img.Paths(); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("Paths() = %v, want %v", got, wantFiles)
+	}
+	if got := string(img.Files["/app/node_modules/evil/package.json"]); !strings.Contains(got, "1.0.1") {
+		t.Errorf("installed package.json = %q", got)
+	}
+}
+
+// TestReadArchive_OCILayout tests reading an OCI image layout tarball
+func TestReadArchive_OCILayout(t *testing.T) {
+	layers := testLayers(t)
+	manifest, _ := json.Marshal(imageManifest{
+		MediaType: MediaTypeOCIManifest,
+		Layers: []descriptor{
+			{MediaType: "application/vnd.oci.image.layer.v1.tar", Digest: digestOf(layers[0])},
+			{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Digest: digestOf(layers[1])},
+		},
+	})
+	index, _ := json.Marshal(imageIndex{Manifests: []descriptor{{MediaType: MediaTypeOCIManifest, Digest: digestOf(manifest)}}})
+	blob := func(data []byte) string { return "blobs/sha256/" + strings.TrimPrefix(digestOf(data), "sha256:") }
+
+	archive := filepath.Join(t.TempDir(), "app-oci.tar")
+	data := tarball(t,
+		[2]string{"oci-layout", `{"imageLayoutVersion":"1.0.0"}`},
+		[2]string{"index.json", string(index)},
+		[2]string{blob(manifest), string(manifest)},
+		[2]string{blob(layers[0]), string(layers[0])},
+		[2]string{blob(layers[1]), string(layers[1])},
+	)
+	if err := os.WriteFile(archive, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := ReadArchive(archive)
+	if err != nil {
+		t.Fatalf("ReadArchive() error = %v", err)
+	}
+	if got := img.Paths(); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("Paths() = %v, want %v", got, wantFiles)
+	}
+	if img.Digest != digestOf(manifest) {
+		t.Errorf("Digest = %s, want %s", img.Digest, digestOf(manifest))
+	}
+}
+
+// TestReadArchive_Invalid tests archives that do not hold exactly one image
+func TestReadArchive_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries [][2]string
+		wantErr string
+	}{
+		{name: "no metadata", entries: [][2]string{{"hello.txt", "hi"}}, wantErr: "not an image archive"},
+		{name: "several images", entries: [][2]string{{"manifest.json", `[{"Layers":[]},{"Layers":[]}]`}}, wantErr: "2 images"},
+		{name: "missing layer", entries: [][2]string{{"manifest.json", `[{"Layers":["gone.tar"]}]`}}, wantErr: "gone.tar is missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			archive := filepath.Join(t.TempDir(), "image.tar")
+			if err := os.WriteFile(archive, tarball(t, tt.entries...), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := ReadArchive(archive)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ReadArchive() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestPull tests pulling a multi-platform image from a registry requiring
+// an anonymous bearer token
+func TestPull(t *testing.T) {
+	layers := testLayers(t)
+	manifest, _ := json.Marshal(imageManifest{
+		MediaType: MediaTypeDockerManifest,
+		Layers: []descriptor{
+			{MediaType: "application/vnd.docker.image.rootfs.diff.tar", Digest: digestOf(layers[0])},
+			{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip", Digest: digestOf(layers[1])},
+		},
+	})
+	index, _ := json.Marshal(imageIndex{
+		MediaType: MediaTypeDockerManifestList,
+		Manifests: []descriptor{
+			{MediaType: MediaTypeDockerManifest, Digest: "sha256:0000", Platform: &platform{OS: "linux", Architecture: "amd64"}},
+			{MediaType: MediaTypeDockerManifest, Digest: digestOf(manifest), Platform: &platform{OS: "linux", Architecture: "arm64", Variant: "v8"}},
+		},
+	})
+	blobs := map[string][]byte{
+		"/v2/org/app/manifests/1.0":                           index,
+		"/v2/org/app/manifests/" + digestOf(manifest):         manifest,
+		"/v2/org/app/blobs/" + digestOf(layers[0]):            layers[0],
+		"/v2/org/app/blobs/" + digestOf(layers[1]):            layers[1],
+		"/v2/org/app/blobs/sha256:" + strings.Repeat("0", 64): nil,
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:org/app:pull" || r.URL.Query().Get("service") != "test" {
+				http.Error(w, "bad scope", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"token":"secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test",scope="repository:org/app:pull"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		data, ok := blobs[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.Contains(r.URL.Path, "/manifests/") {
+			w.Header().Set("Docker-Content-Digest", digestOf(data))
+		}
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	ref := strings.TrimPrefix(server.URL, "http://") + "/org/app:1.0"
+	img, err := Pull(context.Background(), server.Client(), ref, "linux/arm64")
+	if err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if got := img.Paths(); !reflect.DeepEqual(got, wantFiles) {
+		t.Errorf("Paths() = %v, want %v", got, wantFiles)
+	}
+	if img.Digest != digestOf(manifest) {
+		t.Errorf("Digest = %s, want %s", img.Digest, digestOf(manifest))
+	}
+
+	if _, err := Pull(context.Background(), server.Client(), ref, "windows/amd64"); err == nil || !strings.Contains(err.Error(), "linux/arm64/v8") {
+		t.Errorf("Pull() of a missing platform error = %v, want the available platforms", err)
+	}
+
+	// A layer whose content does not match its digest is rejected
+	blobs["/v2/org/app/blobs/"+digestOf(layers[1])] = layers[0]
+	if _, err := Pull(context.Background(), server.Client(), ref, "linux/arm64"); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("Pull() of a corrupted layer error = %v, want a digest mismatch", err)
+	}
+}
@@ -0,0 +1,420 @@
+package oci
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"runtime"
+	"strings"
+)
+
+// Media types of image manifests and indexes.
+const (
+	MediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	MediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeOCIIndex           = "application/vnd.oci.image.index.v1+json"
+)
+
+// DefaultRegistry is the registry of references without a registry host.
+const DefaultRegistry = "docker.io"
+
+// dockerHubHost is the API host of DefaultRegistry.
+const dockerHubHost = "registry-1.docker.io"
+
+// descriptor references a manifest or blob.
+type descriptor struct {
+	MediaType string    `json:"mediaType"`
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	Platform  *platform `json:"platform,omitempty"`
+}
+
+// platform is the platform of an index entry.
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// imageIndex is an OCI image index or Docker manifest list.
+type imageIndex struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// imageManifest is an OCI or Docker image manifest.
+type imageManifest struct {
+	MediaType string       `json:"mediaType"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// isIndex reports whether mediaType is a multi-platform index.
+func isIndex(mediaType string) bool {
+	return mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerManifestList
+}
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Registry is the registry host, such as ghcr.io or localhost:5000
+	Registry string
+	// Repository is the repository path, with "library/" prepended to
+	// official Docker Hub images
+	Repository string
+	// Tag is the tag, "latest" if neither a tag nor a digest is given
+	Tag string
+	// Digest pins the manifest, taking precedence over Tag
+	Digest string
+}
+
+// ParseReference parses an image reference such as node:20,
+// ghcr.io/org/app:1.4.0 or localhost:5000/app@sha256:....
+func ParseReference(ref string) (Reference, error) {
+	var r Reference
+	name := ref
+	if i := strings.Index(name, "@"); i >= 0 {
+		name, r.Digest = name[:i], name[i+1:]
+		if !strings.Contains(r.Digest, ":") {
+			return Reference{}, fmt.Errorf("invalid image reference %q: malformed digest", ref)
+		}
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, r.Tag = name[:i], name[i+1:]
+	}
+	if name == "" || strings.HasSuffix(ref, ":") {
+		return Reference{}, fmt.Errorf("invalid image reference %q", ref)
+	}
+
+	r.Registry = DefaultRegistry
+	if i := strings.Index(name, "/"); i >= 0 {
+		host := name[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			r.Registry, name = host, name[i+1:]
+		}
+	}
+	if r.Registry == DefaultRegistry && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+	if name != strings.ToLower(name) {
+		return Reference{}, fmt.Errorf("invalid image reference %q: repository names must be lowercase", ref)
+	}
+	r.Repository = name
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	return r, nil
+}
+
+// String returns the reference in its canonical form.
+func (r Reference) String() string {
+	s := r.Registry + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// baseURL returns the registry API base URL. Registries on the loopback
+// interface are reached over plain HTTP, as by docker.
+func (r Reference) baseURL() string {
+	host := r.Registry
+	if host == DefaultRegistry {
+		host = dockerHubHost
+	}
+	hostname := host
+	if h, _, found := strings.Cut(host, ":"); found {
+		hostname = h
+	}
+	if hostname == "localhost" || hostname == "127.0.0.1" {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// DefaultPlatform is the platform pulled from multi-platform images by
+// default: linux on the host's architecture.
+func DefaultPlatform() string {
+	return "linux/" + runtime.GOARCH
+}
+
+// Pull downloads the image referenced by ref with client and reads its
+// layers. For multi-platform images, the manifest of plat (os/arch or
+// os/arch/variant, DefaultPlatform if empty) is used.
+//
+// Only anonymous pulls are supported: bearer tokens are requested without
+// credentials, which public repositories on Docker Hub, GHCR, Quay and
+// similar registries accept.
+func Pull(ctx context.Context, client *http.Client, ref, plat string) (*Image, error) {
+	r, err := ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+	if plat == "" {
+		plat = DefaultPlatform()
+	}
+	if _, err := parsePlatform(plat); err != nil {
+		return nil, err
+	}
+	p := &puller{ctx: ctx, client: client, ref: r}
+
+	reference := r.Digest
+	if reference == "" {
+		reference = r.Tag
+	}
+	manifest, digest, err := p.manifest(reference, plat)
+	if err != nil {
+		return nil, err
+	}
+
+	img := &Image{Reference: ref, Digest: digest, Files: make(map[string][]byte)}
+	for _, d := range manifest.Layers {
+		l, err := p.layer(d)
+		if err != nil {
+			return nil, err
+		}
+		l.apply(img.Files)
+	}
+	return img, nil
+}
+
+// puller talks to the registry of a single reference.
+type puller struct {
+	ctx    context.Context
+	client *http.Client
+	ref    Reference
+	// token is the bearer token of the repository, once requested
+	token string
+}
+
+// manifest fetches the image manifest for reference (a tag or digest),
+// resolving indexes to the manifest of plat. It returns the manifest and
+// its digest.
+func (p *puller) manifest(reference, plat string) (*imageManifest, string, error) {
+	accept := strings.Join([]string{MediaTypeOCIIndex, MediaTypeDockerManifestList, MediaTypeOCIManifest, MediaTypeDockerManifest}, ", ")
+	for {
+		resp, err := p.get("/manifests/"+reference, accept)
+		if err != nil {
+			return nil, "", err
+		}
+		var doc struct {
+			imageManifest
+			Manifests []descriptor `json:"manifests"`
+		}
+		err = decodeJSON(resp.Body, &doc)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("parse manifest of %s: %w", p.ref, err)
+		}
+
+		mediaType := doc.MediaType
+		if mediaType == "" {
+			mediaType = strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+		}
+		digest := resp.Header.Get("Docker-Content-Digest")
+		if digest == "" && strings.Contains(reference, ":") {
+			digest = reference
+		}
+
+		if !isIndex(mediaType) && doc.Manifests == nil {
+			return &doc.imageManifest, digest, nil
+		}
+		d, err := selectPlatform(doc.Manifests, plat)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", p.ref, err)
+		}
+		reference = d.Digest
+	}
+}
+
+// parsePlatform splits a platform into its os, architecture and optional
+// variant.
+func parsePlatform(plat string) ([]string, error) {
+	parts := strings.Split(plat, "/")
+	if len(parts) < 2 || len(parts) > 3 {
+		return nil, fmt.Errorf("invalid platform %q: expected os/arch or os/arch/variant", plat)
+	}
+	return parts, nil
+}
+
+// selectPlatform returns the index entry of plat.
+func selectPlatform(manifests []descriptor, plat string) (descriptor, error) {
+	parts, err := parsePlatform(plat)
+	if err != nil {
+		return descriptor{}, err
+	}
+	var available []string
+	for _, d := range manifests {
+		if d.Platform == nil {
+			continue
+		}
+		name := d.Platform.OS + "/" + d.Platform.Architecture
+		if d.Platform.Variant != "" {
+			name += "/" + d.Platform.Variant
+		}
+		available = append(available, name)
+		if d.Platform.OS != parts[0] || d.Platform.Architecture != parts[1] {
+			continue
+		}
+		if len(parts) == 3 && d.Platform.Variant != parts[2] {
+			continue
+		}
+		return d, nil
+	}
+	return descriptor{}, fmt.Errorf("no image for platform %s (available: %s)", plat, strings.Join(available, ", "))
+}
+
+// layer downloads a layer blob, verifying its digest, and reads its
+// change set.
+func (p *puller) layer(d descriptor) (*layer, error) {
+	if strings.Contains(d.MediaType, "zstd") {
+		return nil, fmt.Errorf("layer %s: unsupported media type %s", d.Digest, d.MediaType)
+	}
+	algorithm, want, ok := strings.Cut(d.Digest, ":")
+	if !ok || algorithm != "sha256" {
+		return nil, fmt.Errorf("layer %s: unsupported digest", d.Digest)
+	}
+
+	resp, err := p.get("/blobs/"+d.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	hash := sha256.New()
+	l, err := readLayer(io.TeeReader(resp.Body, hash))
+	if err != nil {
+		return nil, fmt.Errorf("layer %s: %w", d.Digest, err)
+	}
+	// Hash whatever follows the end of the tar stream as well
+	if _, err := io.Copy(hash, resp.Body); err != nil {
+		return nil, fmt.Errorf("layer %s: %w", d.Digest, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+		return nil, fmt.Errorf("layer %s: digest mismatch (got sha256:%s)", d.Digest, got)
+	}
+	return l, nil
+}
+
+// get requests a path below the repository's API endpoint, requesting an
+// anonymous bearer token when the registry asks for one.
+func (p *puller) get(apiPath, accept string) (*http.Response, error) {
+	endpoint := p.ref.baseURL() + "/v2/" + p.ref.Repository + apiPath
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(p.ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if p.token != "" {
+			req.Header.Set("Authorization", "Bearer "+p.token)
+		}
+
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("pull %s: %w", p.ref, err)
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 && challenge != "" {
+			if err := p.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		return nil, fmt.Errorf("pull %s: GET %s: %s", p.ref, apiPath, resp.Status)
+	}
+}
+
+// authenticate requests an anonymous pull token as directed by a Bearer
+// WWW-Authenticate challenge.
+func (p *puller) authenticate(challenge string) error {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if !strings.EqualFold(scheme, "Bearer") {
+		return fmt.Errorf("pull %s: unsupported authentication scheme %s (only anonymous pulls are supported)", p.ref, scheme)
+	}
+	attrs := parseChallenge(params)
+	realm, err := url.Parse(attrs["realm"])
+	if err != nil || attrs["realm"] == "" {
+		return fmt.Errorf("pull %s: invalid authentication realm %q", p.ref, attrs["realm"])
+	}
+	query := realm.Query()
+	if service := attrs["service"]; service != "" {
+		query.Set("service", service)
+	}
+	scope := attrs["scope"]
+	if scope == "" {
+		scope = "repository:" + p.ref.Repository + ":pull"
+	}
+	query.Set("scope", scope)
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("pull %s: request token: %w", p.ref, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("pull %s: request token: %s", p.ref, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&token); err != nil {
+		return fmt.Errorf("pull %s: parse token: %w", p.ref, err)
+	}
+	p.token = token.Token
+	if p.token == "" {
+		p.token = token.AccessToken
+	}
+	if p.token == "" {
+		return errors.New("pull " + p.ref.String() + ": registry returned no token")
+	}
+	return nil
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of a
+// WWW-Authenticate challenge.
+func parseChallenge(params string) map[string]string {
+	attrs := make(map[string]string)
+	for params != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(params, " ,"), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		attrs[strings.ToLower(strings.TrimSpace(key))] = value
+		params = rest
+	}
+	return attrs
+}
@@ -0,0 +1,160 @@
+// Package parity compares the results of the Go scanner with those of the
+// Node.js implementation in this repository, to confirm the Go port finds
+// everything the Node scanner did before the latter is retired.
+package parity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// Report lists the divergences between a Go and a Node scan of one root.
+type Report struct {
+	// Missing are matches only the Node scanner found; any is a regression
+	Missing []formatter.Match
+	// Extra are matches only the Go scanner found, such as range-based or
+	// integrity matches the Node scanner does not support
+	Extra []formatter.Match
+	// Counts describes differences in the number of files scanned
+	Counts []string
+}
+
+// OK reports whether the Go scan found every match of the Node scan.
+func (r *Report) OK() bool {
+	return len(r.Missing) == 0
+}
+
+// String formats the report for test logs.
+func (r *Report) String() string {
+	var b strings.Builder
+	section := func(title string, matches []formatter.Match) {
+		if len(matches) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "%s:\n", title)
+		for _, match := range matches {
+			fmt.Fprintf(&b, "  %s@%s %s at %s\n", match.PackageName, match.Version, match.Severity, match.Location)
+		}
+	}
+	section("Only found by Node", r.Missing)
+	section("Only found by Go", r.Extra)
+	for _, count := range r.Counts {
+		fmt.Fprintf(&b, "%s\n", count)
+	}
+	if b.Len() == 0 {
+		return "No divergences\n"
+	}
+	return b.String()
+}
+
+// Compare diffs the matches of a Go and a Node scan of root. Matches are
+// identified by package, version, severity and location relative to root;
+// duplicates within one result are ignored.
+func Compare(goResult, nodeResult *formatter.ScanResult, root string) *Report {
+	goMatches := matchSet(goResult.Matches, root)
+	nodeMatches := matchSet(nodeResult.Matches, root)
+
+	report := &Report{}
+	for key, match := range nodeMatches {
+		if _, ok := goMatches[key]; !ok {
+			report.Missing = append(report.Missing, match)
+		}
+	}
+	for key, match := range goMatches {
+		if _, ok := nodeMatches[key]; !ok {
+			report.Extra = append(report.Extra, match)
+		}
+	}
+	sortMatches(report.Missing)
+	sortMatches(report.Extra)
+
+	if goResult.ManifestsScanned != nodeResult.ManifestsScanned {
+		report.Counts = append(report.Counts, fmt.Sprintf("Manifests scanned: Go %d, Node %d", goResult.ManifestsScanned, nodeResult.ManifestsScanned))
+	}
+	if goResult.LockfilesScanned != nodeResult.LockfilesScanned {
+		report.Counts = append(report.Counts, fmt.Sprintf("Lockfiles scanned: Go %d, Node %d", goResult.LockfilesScanned, nodeResult.LockfilesScanned))
+	}
+	return report
+}
+
+// matchSet indexes matches by their identity, with locations normalized
+// against root.
+func matchSet(matches []formatter.Match, root string) map[string]formatter.Match {
+	set := make(map[string]formatter.Match, len(matches))
+	for _, match := range matches {
+		normalized := formatter.Match{
+			PackageName: match.PackageName,
+			Version:     match.Version,
+			Severity:    match.Severity,
+			Location:    formatter.NormalizeLocation(match.Location, root),
+		}
+		key := strings.Join([]string{normalized.PackageName, normalized.Version, string(normalized.Severity), normalized.Location}, "\x00")
+		set[key] = normalized
+	}
+	return set
+}
+
+// sortMatches orders matches by location, package and version.
+func sortMatches(matches []formatter.Match) {
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Location != b.Location {
+			return a.Location < b.Location
+		}
+		if a.PackageName != b.PackageName {
+			return a.PackageName < b.PackageName
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Severity < b.Severity
+	})
+}
+
+// RunNode scans root with the Node.js CLI at script (node/npm-scan.js) and
+// returns its JSON result. A non-zero exit status is expected when matches
+// are found; only status 2 (scan error) fails.
+func RunNode(ctx context.Context, script, root, csvURL string, lockfileOnly bool) (*formatter.ScanResult, error) {
+	args := []string{script, root, "--json", "--csv-url", csvURL}
+	if lockfileOnly {
+		args = append(args, "--lockfile-only")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "node", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return nil, fmt.Errorf("node scan failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+	}
+
+	return parseNodeOutput(stdout.Bytes())
+}
+
+// parseNodeOutput extracts the JSON result from the Node CLI's stdout, which
+// also carries its log lines. The result is the last top-level JSON object.
+func parseNodeOutput(output []byte) (*formatter.ScanResult, error) {
+	start := bytes.LastIndex(output, []byte("\n{\n"))
+	if start >= 0 {
+		start++
+	} else if bytes.HasPrefix(output, []byte("{")) {
+		start = 0
+	} else {
+		return nil, fmt.Errorf("no JSON result in node output")
+	}
+
+	var result formatter.ScanResult
+	if err := json.Unmarshal(output[start:], &result); err != nil {
+		return nil, fmt.Errorf("failed to parse node result: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,79 @@
+//go:build parity
+
+package parity
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// TestParity runs the Go and Node scanners against every fixture under
+// testdata/fixtures and fails on matches only the Node scanner finds.
+// Run with: go test -tags parity ./pkg/parity
+func TestParity(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node is not installed")
+	}
+
+	csvData, err := os.ReadFile(filepath.Join("testdata", "iocs.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(csvData)
+	}))
+	defer server.Close()
+
+	script, err := filepath.Abs(filepath.Join("..", "..", "..", "node", "npm-scan.js"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := os.ReadDir(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		root, err := filepath.Abs(filepath.Join("testdata", "fixtures", fixture.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, lockfileOnly := range []bool{false, true} {
+			name := fixture.Name()
+			if lockfileOnly {
+				name += "/lockfile-only"
+			}
+
+			t.Run(name, func(t *testing.T) {
+				goResult, err := scanner.RunScan(scanner.ScanOptions{
+					Path:            root,
+					CSVURL:          server.URL,
+					LockfileOnly:    lockfileOnly,
+					SkipGitMetadata: true,
+				})
+				if err != nil {
+					t.Fatalf("Go scan failed: %v", err)
+				}
+				nodeResult, err := RunNode(context.Background(), script, root, server.URL, lockfileOnly)
+				if err != nil {
+					t.Fatalf("Node scan failed: %v", err)
+				}
+
+				report := Compare(goResult, nodeResult, root)
+				if !report.OK() {
+					t.Errorf("Go scan diverges from Node:\n%s", report)
+				} else {
+					t.Logf("\n%s", report)
+				}
+			})
+		}
+	}
+}
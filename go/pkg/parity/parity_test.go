@@ -0,0 +1,63 @@
+package parity
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// TestCompare tests that matches are compared by identity, with locations
+// normalized against the scan root
+func TestCompare(t *testing.T) {
+	goResult := &formatter.ScanResult{
+		ManifestsScanned: 2,
+		Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "/repo/package.json", Fingerprint: "abc"},
+			{PackageName: "lodash", Version: "4.17.20", Severity: formatter.SeverityTransitive, Location: "/repo/package-lock.json", IOCRange: "< 4.17.21"},
+		},
+	}
+	nodeResult := &formatter.ScanResult{
+		ManifestsScanned: 1,
+		Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "/repo/package.json"},
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "/repo/package.json"},
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityTransitive, Location: "/repo/yarn.lock"},
+		},
+	}
+
+	report := Compare(goResult, nodeResult, "/repo")
+	if report.OK() {
+		t.Fatal("expected the chalk match missing from Go to fail parity")
+	}
+	if len(report.Missing) != 1 || report.Missing[0].PackageName != "chalk" || report.Missing[0].Location != "yarn.lock" {
+		t.Errorf("unexpected missing matches: %+v", report.Missing)
+	}
+	if len(report.Extra) != 1 || report.Extra[0].PackageName != "lodash" {
+		t.Errorf("unexpected extra matches: %+v", report.Extra)
+	}
+	if len(report.Counts) != 1 || !strings.Contains(report.Counts[0], "Manifests scanned: Go 2, Node 1") {
+		t.Errorf("unexpected count divergences: %v", report.Counts)
+	}
+	if output := report.String(); !strings.Contains(output, "Only found by Node:\n  chalk@5.6.1 TRANSITIVE at yarn.lock") {
+		t.Errorf("unexpected report:\n%s", output)
+	}
+}
+
+// TestParseNodeOutput tests extracting the JSON result from log output
+func TestParseNodeOutput(t *testing.T) {
+	output := "[INFO] Starting vulnerability scan of /repo\n[INFO] Scan complete: 1 matches found in 3ms\n" +
+		"{\n  \"manifestsScanned\": 1,\n  \"matches\": [\n    {\n      \"packageName\": \"evil\",\n      \"version\": \"1.0.1\",\n      \"severity\": \"DIRECT\"\n    }\n  ],\n  \"timestamp\": \"2025-11-24T10:00:00.000Z\",\n  \"elapsedMs\": 3\n}\n"
+
+	result, err := parseNodeOutput([]byte(output))
+	if err != nil {
+		t.Fatalf("parseNodeOutput() error = %v", err)
+	}
+	if result.ManifestsScanned != 1 || len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	if _, err := parseNodeOutput([]byte("[ERROR] Failed to fetch IoC database\n")); err == nil {
+		t.Error("expected an error for output without a result")
+	}
+}
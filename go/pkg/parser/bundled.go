@@ -0,0 +1,38 @@
+package parser
+
+import (
+	"path/filepath"
+)
+
+// ResolveBundledDependencies looks up the installed copy of each of the
+// manifest's bundledDependencies, which declare no version spec, in the
+// node_modules directory next to manifestPath.
+//
+// Installed bundles are returned as resolved packages located at their own
+// package.json, with Type DepTypeBundled. Bundles that are not installed,
+// or whose package.json has no version, are returned as unresolved.
+func ResolveBundledDependencies(manifest *Manifest, manifestPath string) (resolved []ResolvedPackage, unresolved []Dependency) {
+	dir := filepath.Dir(manifestPath)
+
+	for _, dep := range ExtractDependencies(manifest, manifestPath) {
+		if dep.Type != "bundledDependencies" {
+			continue
+		}
+
+		installedPath := filepath.Join(dir, "node_modules", filepath.FromSlash(dep.Name), "package.json")
+		installed, err := ParsePackageJSON(installedPath)
+		if err != nil || installed.Version == "" {
+			unresolved = append(unresolved, dep)
+			continue
+		}
+
+		resolved = append(resolved, ResolvedPackage{
+			Name:         dep.Name,
+			Version:      installed.Version,
+			LockfilePath: installedPath,
+			Type:         DepTypeBundled,
+		})
+	}
+
+	return resolved, unresolved
+}
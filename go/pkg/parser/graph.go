@@ -0,0 +1,267 @@
+package parser
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// nodeModulesDir separates install path segments in npm lockfiles.
+const nodeModulesDir = "node_modules/"
+
+// DependencyGraph links the packages installed by an npm lockfile to the
+// packages that depend on them, so a resolved package can be traced back to
+// the root dependency that pulled it in.
+//
+// Nodes are keyed by install path ("node_modules/a/node_modules/b"), which
+// distinguishes several installed copies of the same package. The root
+// project is the node with the empty key.
+type DependencyGraph struct {
+	nodes map[string]*graphNode
+}
+
+// graphNode is a single installed package and the install paths of the
+// packages it depends on.
+type graphNode struct {
+	name    string
+	version string
+	deps    []string
+}
+
+// BuildDependencyGraph builds the dependency graph of an npm lockfile.
+//
+// For v2/v3 lockfiles the edges come from the "dependencies" (and dev,
+// optional and peer) maps of each packages entry, with the root entry ""
+// supplying the project's own dependencies. For v1 lockfiles the edges come
+// from "requires"; since v1 does not record the root manifest, top-level
+// packages that nothing else requires are treated as root dependencies.
+//
+// Dependency names are resolved the way Node does: the nearest
+// node_modules directory at or above the dependent package wins.
+func BuildDependencyGraph(lockfile *Lockfile) *DependencyGraph {
+	graph := &DependencyGraph{nodes: make(map[string]*graphNode)}
+	if lockfile == nil {
+		return graph
+	}
+
+	if len(lockfile.Packages) > 0 {
+		graph.addPackages(lockfile.Packages)
+	} else if len(lockfile.Dependencies) > 0 {
+		graph.addV1Dependencies(lockfile.Dependencies)
+	}
+
+	return graph
+}
+
+// addPackages adds the entries of a v2/v3 "packages" map.
+func (g *DependencyGraph) addPackages(packages map[string]PackageInfo) {
+	for key, info := range packages {
+		if key == "." {
+			key = ""
+		}
+		name := installPathName(key)
+		if info.Name != "" {
+			name = info.Name
+		}
+		g.nodes[key] = &graphNode{name: name, version: info.Version}
+	}
+
+	for key, info := range packages {
+		if key == "." {
+			key = ""
+		}
+		node := g.nodes[key]
+		for _, dep := range dependencyNames(info) {
+			target, ok := g.resolve(packages, key, dep)
+			if ok {
+				node.deps = append(node.deps, target)
+			}
+		}
+	}
+}
+
+// resolve finds the install path that dep resolves to from the package
+// installed at key, following workspace links.
+func (g *DependencyGraph) resolve(packages map[string]PackageInfo, key, dep string) (string, bool) {
+	for _, candidate := range resolutionCandidates(key, dep) {
+		info, ok := packages[candidate]
+		if !ok {
+			continue
+		}
+		if info.Link && info.Resolved != "" {
+			if _, ok := g.nodes[info.Resolved]; ok {
+				return info.Resolved, true
+			}
+		}
+		return candidate, true
+	}
+	return "", false
+}
+
+// addV1Dependencies adds the nested "dependencies" tree of a v1 lockfile.
+func (g *DependencyGraph) addV1Dependencies(deps map[string]PackageInfo) {
+	requires := make(map[string]map[string]string)
+	g.addV1Level("", deps, requires)
+
+	required := make(map[string]bool)
+	for key, names := range requires {
+		node := g.nodes[key]
+		for dep := range names {
+			for _, candidate := range resolutionCandidates(key, dep) {
+				if _, ok := g.nodes[candidate]; ok {
+					node.deps = append(node.deps, candidate)
+					required[candidate] = true
+					break
+				}
+			}
+		}
+		sort.Strings(node.deps)
+	}
+
+	// Without a root manifest, top-level packages nothing requires are the
+	// project's own dependencies
+	root := &graphNode{}
+	for name := range deps {
+		key := nodeModulesDir + name
+		if !required[key] {
+			root.deps = append(root.deps, key)
+		}
+	}
+	sort.Strings(root.deps)
+	g.nodes[""] = root
+}
+
+// addV1Level adds one level of a v1 dependencies tree below parent,
+// recording each package's requires for later resolution.
+func (g *DependencyGraph) addV1Level(parent string, deps map[string]PackageInfo, requires map[string]map[string]string) {
+	for name, info := range deps {
+		key := nodeModulesDir + name
+		if parent != "" {
+			key = parent + "/" + key
+		}
+
+		resolvedName, version := name, info.Version
+		if realName, realVersion, ok := ParseAliasSpec(info.Version); ok {
+			resolvedName, version = realName, realVersion
+		}
+		g.nodes[key] = &graphNode{name: resolvedName, version: version}
+		requires[key] = info.Requires
+
+		if len(info.Dependencies) > 0 {
+			g.addV1Level(key, nestedPackageInfos(info.Dependencies), requires)
+		}
+	}
+}
+
+// Chain returns the shortest path from a root dependency to an installed
+// copy of name@version, as "name@version" strings starting with the root
+// dependency and ending with the package itself. Returns nil if the package
+// is not reachable from the root project.
+func (g *DependencyGraph) Chain(name, version string) []string {
+	if _, ok := g.nodes[""]; !ok {
+		return nil
+	}
+
+	// Breadth-first search from the root project
+	parent := map[string]string{"": ""}
+	queue := []string{""}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+
+		node := g.nodes[key]
+		if key != "" && node.name == name && node.version == version {
+			return g.path(parent, key)
+		}
+
+		for _, dep := range node.deps {
+			if _, seen := parent[dep]; seen {
+				continue
+			}
+			parent[dep] = key
+			queue = append(queue, dep)
+		}
+	}
+
+	return nil
+}
+
+// path walks the BFS parent links back from key to the root project.
+func (g *DependencyGraph) path(parent map[string]string, key string) []string {
+	var chain []string
+	for ; key != ""; key = parent[key] {
+		node := g.nodes[key]
+		chain = append(chain, node.name+"@"+node.version)
+	}
+
+	// Reverse so the chain reads from the root dependency down
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// dependencyNames returns the names of all dependencies declared by a v2/v3
+// packages entry, in a stable order.
+func dependencyNames(info PackageInfo) []string {
+	seen := make(map[string]bool)
+	for name := range info.Dependencies {
+		seen[name] = true
+	}
+	for _, deps := range []map[string]string{info.DevDependencies, info.OptionalDependencies, info.PeerDependencies} {
+		for name := range deps {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// resolutionCandidates lists the install paths Node would try, nearest
+// first, when the package installed at key requires dep.
+func resolutionCandidates(key, dep string) []string {
+	var candidates []string
+	for {
+		if key == "" {
+			return append(candidates, nodeModulesDir+dep)
+		}
+		candidates = append(candidates, key+"/"+nodeModulesDir+dep)
+
+		if i := strings.LastIndex(key, "/"+nodeModulesDir); i >= 0 {
+			key = key[:i]
+		} else {
+			key = ""
+		}
+	}
+}
+
+// installPathName extracts the package name from an install path:
+// "node_modules/a/node_modules/@scope/b" -> "@scope/b".
+func installPathName(key string) string {
+	if i := strings.LastIndex(key, nodeModulesDir); i >= 0 {
+		return key[i+len(nodeModulesDir):]
+	}
+	return key
+}
+
+// nestedPackageInfos decodes the nested dependencies of a v1 lockfile entry.
+func nestedPackageInfos(deps map[string]interface{}) map[string]PackageInfo {
+	nested := make(map[string]PackageInfo, len(deps))
+	for name, value := range deps {
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		var info PackageInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			continue
+		}
+		nested[name] = info
+	}
+	return nested
+}
@@ -0,0 +1,74 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/throttle"
+)
+
+// FindInstalledPackages lists the packages installed in the node_modules
+// directory of dir, including nested node_modules directories, by reading
+// each package's package.json, and records their scripts.
+//
+// Installed packages are returned as resolved packages located at their
+// package.json. Symlinked packages (workspace links, npm link) and packages
+// without a name or version are skipped. A missing node_modules directory
+// yields no packages. The walk is paced by t, which may be nil.
+func FindInstalledPackages(dir string, t *throttle.Throttle) []ResolvedPackage {
+	var installed []ResolvedPackage
+	walkNodeModules(filepath.Join(dir, "node_modules"), t, &installed)
+	return installed
+}
+
+// walkNodeModules appends the packages installed in the node_modules
+// directory modulesDir, and recursively those of their own node_modules.
+func walkNodeModules(modulesDir string, t *throttle.Throttle, installed *[]ResolvedPackage) {
+	entries, err := os.ReadDir(modulesDir)
+	if err != nil {
+		return
+	}
+	t.Directory(len(entries))
+
+	for _, entry := range entries {
+		name := entry.Name()
+		// .bin, .cache, .package-lock.json and similar are not packages
+		if strings.HasPrefix(name, ".") || !entry.IsDir() {
+			continue
+		}
+
+		if strings.HasPrefix(name, "@") {
+			scoped, err := os.ReadDir(filepath.Join(modulesDir, name))
+			if err != nil {
+				continue
+			}
+			t.Directory(len(scoped))
+			for _, pkg := range scoped {
+				if pkg.IsDir() {
+					readInstalled(filepath.Join(modulesDir, name, pkg.Name()), t, installed)
+				}
+			}
+			continue
+		}
+
+		readInstalled(filepath.Join(modulesDir, name), t, installed)
+	}
+}
+
+// readInstalled appends the package installed in pkgDir and walks its
+// nested node_modules.
+func readInstalled(pkgDir string, t *throttle.Throttle, installed *[]ResolvedPackage) {
+	manifestPath := filepath.Join(pkgDir, "package.json")
+	manifest, err := ParsePackageJSON(manifestPath)
+	if err == nil && manifest.Name != "" && manifest.Version != "" {
+		*installed = append(*installed, ResolvedPackage{
+			Name:         manifest.Name,
+			Version:      manifest.Version,
+			LockfilePath: manifestPath,
+			Scripts:      manifest.Scripts,
+		})
+	}
+
+	walkNodeModules(filepath.Join(pkgDir, "node_modules"), t, installed)
+}
@@ -1,10 +1,13 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strings"
+	"path/filepath"
 )
 
 // ResolvedPackage represents a package entry from a lockfile
@@ -12,12 +15,57 @@ type ResolvedPackage struct {
 	Name          string `json:"name"`
 	Version       string `json:"version"`
 	LockfilePath  string `json:"lockfilePath"`
+	// Integrity is the Subresource Integrity hash of the tarball
+	// (e.g. "sha512-..."), if the lockfile records one
+	Integrity     string `json:"integrity,omitempty"`
+	// Type is the dependency type of the entry (DepTypeProd, DepTypeDev,
+	// DepTypeOptional or DepTypePeer), or empty if the lockfile does not
+	// record it
+	Type          string `json:"type,omitempty"`
+	// Scripts holds the package.json scripts of an installed package;
+	// lockfiles do not record them
+	Scripts       map[string]string `json:"scripts,omitempty"`
 }
 
 // PackageInfo represents package metadata in npm lockfile
 type PackageInfo struct {
+	// Name is set on v2/v3 entries installed under an npm alias and holds
+	// the real package name
+	Name         string                 `json:"name,omitempty"`
 	Version      string                 `json:"version,omitempty"`
+	Integrity    string                 `json:"integrity,omitempty"`
 	Dependencies map[string]interface{} `json:"dependencies,omitempty"`
+	// Dependency edges of v2/v3 entries, alongside Dependencies
+	DevDependencies      map[string]string `json:"devDependencies,omitempty"`
+	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
+	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
+	// Requires lists the dependencies of a v1 entry by name and range
+	Requires map[string]string `json:"requires,omitempty"`
+	// Link and Resolved describe v2/v3 workspace symlinks
+	Link     bool   `json:"link,omitempty"`
+	Resolved string `json:"resolved,omitempty"`
+	// Dev, Optional, DevOptional and Peer flag entries that are only
+	// installed as dev, optional or peer dependencies
+	Dev         bool `json:"dev,omitempty"`
+	Optional    bool `json:"optional,omitempty"`
+	DevOptional bool `json:"devOptional,omitempty"`
+	Peer        bool `json:"peer,omitempty"`
+}
+
+// DependencyType returns the dependency type of a lockfile entry from its
+// flags. An entry that is both a dev and an optional dependency
+// (devOptional) is needed unless both are omitted, so it counts as optional.
+func (p PackageInfo) DependencyType() string {
+	switch {
+	case p.Dev:
+		return DepTypeDev
+	case p.Peer:
+		return DepTypePeer
+	case p.Optional || p.DevOptional:
+		return DepTypeOptional
+	default:
+		return DepTypeProd
+	}
 }
 
 // Lockfile represents the parsed contents of an npm package-lock.json file.
@@ -31,29 +79,139 @@ type Lockfile struct {
 
 // ParsePackageLock reads and parses an npm package-lock.json file.
 // Supports both npm lockfile v2/v3 format (npm 7+) and v1 format (npm 5-6).
+// npm-shrinkwrap.json files share the same structure and are accepted too.
+//
+// The file is decoded as it is read, one package entry at a time, so
+// lockfiles of tens of megabytes are never held in memory whole.
 //
 // Parameters:
-//   - path: Absolute path to the package-lock.json file
+//   - path: Absolute path to the package-lock.json or npm-shrinkwrap.json file
 //
 // Returns:
 //   - *Lockfile: Pointer to the parsed lockfile, or nil if error
 //   - error: Any error encountered during reading or parsing
 func ParsePackageLock(path string) (*Lockfile, error) {
-	// Read the file
-	content, err := os.ReadFile(path)
+	// Open the file
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read package-lock.json: %w", err)
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	return decodePackageLock(file)
+}
+
+// ParsePackageLockData parses package-lock.json content that has already
+// been read, such as a historical revision from git.
+func ParsePackageLockData(content []byte) (*Lockfile, error) {
+	return decodePackageLock(bytes.NewReader(content))
+}
+
+// decodePackageLock decodes a package-lock.json from r token by token.
+// Only the lockfile version and the packages and dependencies maps are
+// kept; other top-level fields are skipped.
+func decodePackageLock(r io.Reader) (*Lockfile, error) {
+	dec := json.NewDecoder(r)
+	lockfile, err := decodeLockfileObject(dec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse npm lockfile: %w", err)
+	}
+	return lockfile, nil
+}
+
+// decodeLockfileObject decodes the top-level lockfile object from dec,
+// which must hold nothing after it.
+func decodeLockfileObject(dec *json.Decoder) (*Lockfile, error) {
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, err
 	}
 
-	// Parse JSON
 	var lockfile Lockfile
-	if err := json.Unmarshal(content, &lockfile); err != nil {
-		return nil, fmt.Errorf("failed to parse package-lock.json: %w", err)
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case "lockfileVersion":
+			err = dec.Decode(&lockfile.Version)
+		case "packages":
+			lockfile.Packages, err = decodePackageInfos(dec)
+		case "dependencies":
+			lockfile.Dependencies, err = decodePackageInfos(dec)
+		default:
+			var skipped json.RawMessage
+			err = dec.Decode(&skipped)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		return nil, err
 	}
 
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
 	return &lockfile, nil
 }
 
+// decodePackageInfos decodes an object of package entries, such as the
+// packages map of a v2/v3 lockfile, one entry at a time. null decodes to a
+// nil map.
+func decodePackageInfos(dec *json.Decoder) (map[string]PackageInfo, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token == nil {
+		return nil, nil
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("expected an object of packages, got %v", token)
+	}
+
+	packages := make(map[string]PackageInfo)
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return nil, err
+		}
+		var info PackageInfo
+		if err := dec.Decode(&info); err != nil {
+			return nil, fmt.Errorf("package %q: %w", key, err)
+		}
+		packages[key] = info
+	}
+	return packages, expectDelim(dec, '}')
+}
+
+// objectKey reads the next key of an object from dec.
+func objectKey(dec *json.Decoder) (string, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("expected an object key, got %v", token)
+	}
+	return key, nil
+}
+
+// expectDelim reads the next token from dec, which must be delim.
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if got, ok := token.(json.Delim); !ok || got != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
 // ExtractResolvedPackages extracts all resolved packages from a Lockfile into a flat list.
 // Handles both v2/v3 format (packages field) and v1 format (dependencies field with recursion).
 //
@@ -80,13 +238,19 @@ func ExtractResolvedPackages(lockfile *Lockfile, filePath string) []ResolvedPack
 
 			// Extract package name from path
 			// node_modules/@scope/package -> @scope/package
-			// node_modules/package -> package
-			name := strings.TrimPrefix(pkgPath, "node_modules/")
+			// node_modules/a/node_modules/package -> package
+			name := installPathName(pkgPath)
+			if pkgInfo.Name != "" {
+				// Installed under an npm alias
+				name = pkgInfo.Name
+			}
 
 			packages = append(packages, ResolvedPackage{
 				Name:         name,
 				Version:      pkgInfo.Version,
 				LockfilePath: filePath,
+				Integrity:    pkgInfo.Integrity,
+				Type:         pkgInfo.DependencyType(),
 			})
 		}
 	} else if lockfile.Dependencies != nil && len(lockfile.Dependencies) > 0 {
@@ -110,10 +274,18 @@ func extractDepsRecursive(deps map[string]PackageInfo, packages *[]ResolvedPacka
 			continue
 		}
 
+		// v1 lockfiles record aliases as "version": "npm:real-pkg@1.2.3"
+		resolvedName, version := name, info.Version
+		if realName, realVersion, ok := ParseAliasSpec(info.Version); ok {
+			resolvedName, version = realName, realVersion
+		}
+
 		*packages = append(*packages, ResolvedPackage{
-			Name:         name,
-			Version:      info.Version,
+			Name:         resolvedName,
+			Version:      version,
 			LockfilePath: filePath,
+			Integrity:    info.Integrity,
+			Type:         info.DependencyType(),
 		})
 
 		// Recursively process nested dependencies if they exist
@@ -124,9 +296,15 @@ func extractDepsRecursive(deps map[string]PackageInfo, packages *[]ResolvedPacka
 				// Handle polymorphic nested dependencies
 				if nested, ok := v.(map[string]interface{}); ok {
 					version, _ := nested["version"].(string)
+					integrity, _ := nested["integrity"].(string)
+					dev, _ := nested["dev"].(bool)
+					optional, _ := nested["optional"].(bool)
 					nestedDeps[k] = PackageInfo{
 						Version:      version,
+						Integrity:    integrity,
 						Dependencies: nested,
+						Dev:          dev,
+						Optional:     optional,
 					}
 				}
 			}
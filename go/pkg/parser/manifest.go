@@ -4,6 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+)
+
+// aliasPrefix introduces an npm alias spec, e.g. "npm:real-pkg@^1.0.0".
+const aliasPrefix = "npm:"
+
+// Dependency types, as reported in scan statistics. Manifest sections map
+// to them through ManifestDependencyType.
+const (
+	DepTypeProd     = "prod"
+	DepTypeDev      = "dev"
+	DepTypePeer     = "peer"
+	DepTypeOptional = "optional"
+	DepTypeBundled  = "bundled"
+	// DepTypeOverride is a version forced by the npm "overrides" or yarn
+	// "resolutions" field (see ExtractOverrides)
+	DepTypeOverride = "override"
 )
 
 // Dependency represents a single package dependency entry
@@ -12,6 +29,12 @@ type Dependency struct {
 	VersionSpec string `json:"versionSpec"`
 	Type        string `json:"type"` // dependencies, devDependencies, etc.
 	FilePath    string `json:"filePath"`
+	// Alias is the declared name when the dependency is an npm alias
+	// ("my-alias": "npm:real-pkg@1.2.3"); Name then holds the real package
+	Alias string `json:"alias,omitempty"`
+	// Override is the key path of an override forcing the version, such
+	// as "resolutions > **/pkg", for dependencies from ExtractOverrides
+	Override string `json:"override,omitempty"`
 }
 
 // Manifest represents the parsed contents of a package.json file
@@ -23,6 +46,11 @@ type Manifest struct {
 	PeerDependencies     map[string]string `json:"peerDependencies,omitempty"`
 	OptionalDependencies map[string]string `json:"optionalDependencies,omitempty"`
 	BundledDependencies  []string          `json:"bundledDependencies,omitempty"`
+	Scripts              map[string]string `json:"scripts,omitempty"`
+	// Overrides and Resolutions are kept raw, so a malformed override does
+	// not fail the whole manifest (see ExtractOverrides)
+	Overrides   json.RawMessage `json:"overrides,omitempty"`
+	Resolutions json.RawMessage `json:"resolutions,omitempty"`
 }
 
 // ParsePackageJSON reads and parses a package.json file at the given path.
@@ -51,6 +79,28 @@ func ParsePackageJSON(path string) (*Manifest, error) {
 	return &manifest, nil
 }
 
+// ManifestDependencyType returns the dependency type of a package.json
+// section such as "devDependencies" (a Dependency's Type), or an empty
+// string for an unknown section.
+func ManifestDependencyType(section string) string {
+	switch section {
+	case "dependencies":
+		return DepTypeProd
+	case "devDependencies":
+		return DepTypeDev
+	case "peerDependencies":
+		return DepTypePeer
+	case "optionalDependencies":
+		return DepTypeOptional
+	case "bundledDependencies":
+		return DepTypeBundled
+	case "overrides", "resolutions":
+		return DepTypeOverride
+	default:
+		return ""
+	}
+}
+
 // ExtractDependencies extracts all dependencies from a Manifest into a flat list.
 // Each dependency entry includes its name, version spec, and type.
 //
@@ -80,12 +130,19 @@ func ExtractDependencies(manifest *Manifest, filePath string) []Dependency {
 				continue
 			}
 
-			dependencies = append(dependencies, Dependency{
+			dep := Dependency{
 				Name:        name,
 				VersionSpec: versionSpec,
 				Type:        depType,
 				FilePath:    filePath,
-			})
+			}
+			if realName, realSpec, ok := ParseAliasSpec(versionSpec); ok {
+				dep.Alias = name
+				dep.Name = realName
+				dep.VersionSpec = realSpec
+			}
+
+			dependencies = append(dependencies, dep)
 		}
 	}
 
@@ -108,3 +165,31 @@ func ExtractDependencies(manifest *Manifest, filePath string) []Dependency {
 
 	return dependencies
 }
+
+// ParseAliasSpec splits an npm alias spec ("npm:real-pkg@1.2.3" or
+// "npm:@scope/real-pkg@^2.0.0") into the real package name and its version
+// spec. An alias without a version resolves to the latest release and is
+// returned with the spec "*". ok is false if spec is not an alias.
+func ParseAliasSpec(spec string) (name, versionSpec string, ok bool) {
+	spec = strings.TrimSpace(spec)
+	if !strings.HasPrefix(spec, aliasPrefix) {
+		return "", "", false
+	}
+	target := strings.TrimPrefix(spec, aliasPrefix)
+
+	// Skip the leading "@" of a scoped name when looking for the separator
+	at := strings.LastIndex(target, "@")
+	if at <= 0 {
+		name, versionSpec = target, "*"
+	} else {
+		name, versionSpec = target[:at], target[at+1:]
+		if versionSpec == "" {
+			versionSpec = "*"
+		}
+	}
+	if name == "" || name == "@" {
+		return "", "", false
+	}
+
+	return name, versionSpec, true
+}
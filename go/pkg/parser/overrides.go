@@ -0,0 +1,184 @@
+package parser
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// overrideSeparator joins the keys of an override's path in Dependency.Override.
+const overrideSeparator = " > "
+
+// ExtractOverrides extracts the versions forced by the npm "overrides" and
+// yarn "resolutions" fields of a manifest. These replace the version of a
+// package wherever it appears in the tree, so an override pinning a
+// compromised release installs it even when no dependency declares it.
+//
+// Each forced version is returned as a Dependency with Type "overrides" or
+// "resolutions" and Override set to its key path, such as
+// "overrides > parent > pkg" or "resolutions > **/pkg". Overrides that
+// reference a dependency's spec ("$pkg") take that spec; entries that do not
+// set a version, or that cannot be parsed, are skipped.
+//
+// Parameters:
+//   - manifest: The manifest to extract overrides from
+//   - filePath: The source file path for reference
+//
+// Returns:
+//   - []Dependency: Slice of all overrides found, in key order
+func ExtractOverrides(manifest *Manifest, filePath string) []Dependency {
+	var overrides []Dependency
+
+	if len(manifest.Overrides) > 0 {
+		var tree map[string]json.RawMessage
+		if json.Unmarshal(manifest.Overrides, &tree) == nil {
+			overrides = appendNPMOverrides(overrides, manifest, tree, []string{"overrides"}, filePath)
+		}
+	}
+
+	if len(manifest.Resolutions) > 0 {
+		var resolutions map[string]string
+		if json.Unmarshal(manifest.Resolutions, &resolutions) == nil {
+			for _, key := range sortedKeys(resolutions) {
+				name := resolutionName(key)
+				if name == "" {
+					continue
+				}
+				overrides = appendOverride(overrides, name, resolutionSpec(resolutions[key]),
+					"resolutions", []string{"resolutions", key}, filePath)
+			}
+		}
+	}
+
+	return overrides
+}
+
+// appendNPMOverrides appends the overrides of an npm overrides object at
+// path. A key maps to the version of its package, or to an object overriding
+// the package itself (".") and the packages below it.
+func appendNPMOverrides(overrides []Dependency, manifest *Manifest, tree map[string]json.RawMessage, path []string, filePath string) []Dependency {
+	for _, key := range sortedKeys(tree) {
+		name := overrideKeyName(key)
+		if name == "" {
+			continue
+		}
+		keyPath := append(append([]string{}, path...), key)
+
+		var spec string
+		if json.Unmarshal(tree[key], &spec) == nil {
+			overrides = appendOverride(overrides, name, referencedSpec(manifest, spec), "overrides", keyPath, filePath)
+			continue
+		}
+
+		var nested map[string]json.RawMessage
+		if json.Unmarshal(tree[key], &nested) != nil {
+			continue
+		}
+		if raw, ok := nested["."]; ok {
+			if json.Unmarshal(raw, &spec) == nil {
+				overrides = appendOverride(overrides, name, referencedSpec(manifest, spec), "overrides", keyPath, filePath)
+			}
+			delete(nested, ".")
+		}
+		overrides = appendNPMOverrides(overrides, manifest, nested, keyPath, filePath)
+	}
+	return overrides
+}
+
+// appendOverride appends the override of name to spec, resolving npm
+// aliases to the real package.
+func appendOverride(overrides []Dependency, name, spec, section string, keyPath []string, filePath string) []Dependency {
+	if spec == "" {
+		return overrides
+	}
+
+	dep := Dependency{
+		Name:        name,
+		VersionSpec: spec,
+		Type:        section,
+		FilePath:    filePath,
+		Override:    strings.Join(keyPath, overrideSeparator),
+	}
+	if realName, realSpec, ok := ParseAliasSpec(spec); ok {
+		dep.Alias = name
+		dep.Name = realName
+		dep.VersionSpec = realSpec
+	}
+	return append(overrides, dep)
+}
+
+// overrideKeyName returns the package name of an npm overrides key, which
+// may restrict the override to a range of the package ("pkg@^1.0.0").
+func overrideKeyName(key string) string {
+	if key == "." {
+		return ""
+	}
+	start := 0
+	if strings.HasPrefix(key, "@") {
+		start = 1
+	}
+	if at := strings.Index(key[start:], "@"); at != -1 {
+		key = key[:start+at]
+	}
+	if key == "" || key == "@" {
+		return ""
+	}
+	return key
+}
+
+// resolutionName returns the package a yarn resolutions key applies to: the
+// last package of its path ("**/parent/@scope/pkg"), without a descriptor
+// range ("pkg@npm:^1.0.0").
+func resolutionName(key string) string {
+	segments := strings.Split(key, "/")
+	last := segments[len(segments)-1]
+	if len(segments) > 1 && strings.HasPrefix(segments[len(segments)-2], "@") {
+		last = segments[len(segments)-2] + "/" + last
+	}
+	if last == "**" {
+		return ""
+	}
+	return overrideKeyName(last)
+}
+
+// resolutionSpec returns the version spec of a yarn resolution. Yarn Berry
+// writes registry versions with the npm protocol ("npm:1.2.3"), which is
+// not an alias.
+func resolutionSpec(spec string) string {
+	if version, ok := strings.CutPrefix(spec, aliasPrefix); ok && version != "" &&
+		!strings.Contains(version, "@") && strings.ContainsAny(version[:1], "0123456789^~<>=*") {
+		return version
+	}
+	return spec
+}
+
+// referencedSpec resolves an npm override that references the spec of a
+// dependency of the manifest ("$pkg"), returning "" if there is none.
+func referencedSpec(manifest *Manifest, spec string) string {
+	name, ok := strings.CutPrefix(spec, "$")
+	if !ok {
+		return spec
+	}
+	for _, deps := range []map[string]string{
+		manifest.Dependencies,
+		manifest.DevDependencies,
+		manifest.OptionalDependencies,
+		manifest.PeerDependencies,
+	} {
+		if referenced, ok := deps[name]; ok {
+			return referenced
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m in order, so overrides are extracted
+// deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,8 +1,11 @@
 package parser
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -193,6 +196,37 @@ func TestExtractResolvedPackages_v3(t *testing.T) {
 	}
 }
 
+// TestExtractResolvedPackages_Integrity tests carrying integrity hashes from lockfiles
+func TestExtractResolvedPackages_Integrity(t *testing.T) {
+	const lodashIntegrity = "sha512-v2kDEe57lecTulaDIuNTPy3Ry4gLGJ6Z1O3vE1krgXZNrsQ+LFTGHVxVjcXPs17LhbZVGedAJv8XZ1tvj5FvSg=="
+
+	lockPath := filepath.Join("testdata", "package-lock-v3.json")
+	lockfile, err := ParsePackageLock(lockPath)
+	if err != nil {
+		t.Fatalf("ParsePackageLock failed: %v", err)
+	}
+	for _, pkg := range ExtractResolvedPackages(lockfile, lockPath) {
+		want := ""
+		if pkg.Name == "lodash" {
+			want = lodashIntegrity
+		}
+		if pkg.Integrity != want {
+			t.Errorf("Expected %s integrity '%s', got '%s'", pkg.Name, want, pkg.Integrity)
+		}
+	}
+
+	yarnPath := filepath.Join("testdata", "yarn.lock")
+	yarnLock, err := ParseYarnLock(yarnPath)
+	if err != nil {
+		t.Fatalf("ParseYarnLock failed: %v", err)
+	}
+	for _, pkg := range ExtractYarnResolvedPackages(yarnLock) {
+		if pkg.Name == "lodash" && pkg.Integrity != lodashIntegrity {
+			t.Errorf("Expected yarn lodash integrity '%s', got '%s'", lodashIntegrity, pkg.Integrity)
+		}
+	}
+}
+
 // TestParsePackageLock_v1 tests parsing a v1 package-lock.json file
 func TestParsePackageLock_v1(t *testing.T) {
 	testPath := filepath.Join("testdata", "package-lock-v1.json")
@@ -211,6 +245,67 @@ func TestParsePackageLock_v1(t *testing.T) {
 	}
 }
 
+// TestParsePackageLock_Streaming tests that decoding package entries one at
+// a time yields what unmarshalling the whole file does
+func TestParsePackageLock_Streaming(t *testing.T) {
+	for _, name := range []string{"package-lock-v3.json", "package-lock-v1.json", "npm-shrinkwrap.json"} {
+		content, err := os.ReadFile(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want Lockfile
+		if err := json.Unmarshal(content, &want); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ParsePackageLock(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatalf("ParsePackageLock(%s) failed: %v", name, err)
+		}
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("ParsePackageLock(%s) = %+v, want %+v", name, *got, want)
+		}
+	}
+}
+
+// TestParsePackageLockData_Edges tests skipped fields, null maps and
+// malformed lockfiles
+func TestParsePackageLockData_Edges(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		packages int
+		wantErr  bool
+	}{
+		{"unknown fields skipped", `{"name": "app", "requires": true, "lockfileVersion": 3, "extra": {"a": [1, {"b": null}]}, "packages": {"node_modules/a": {"version": "1.0.0"}}}`, 1, false},
+		{"null packages", `{"lockfileVersion": 3, "packages": null}`, 0, false},
+		{"empty object", `{}`, 0, false},
+		{"not json", `not json`, 0, true},
+		{"not an object", `[]`, 0, true},
+		{"packages not an object", `{"packages": [1]}`, 0, true},
+		{"bad entry", `{"packages": {"node_modules/a": {"version": 1}}}`, 0, true},
+		{"truncated", `{"packages": {"node_modules/a": {"version": "1.0.0"}`, 0, true},
+		{"trailing data", `{"packages": {}} {}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockfile, err := ParsePackageLockData([]byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePackageLockData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !strings.HasPrefix(err.Error(), "failed to parse npm lockfile") {
+					t.Errorf("Expected a lockfile parse error, got %v", err)
+				}
+				return
+			}
+			if len(lockfile.Packages) != tt.packages {
+				t.Errorf("Expected %d packages, got %d", tt.packages, len(lockfile.Packages))
+			}
+		})
+	}
+}
+
 // TestExtractResolvedPackages_v1 tests extracting packages from v1 lockfile with nested dependencies
 func TestExtractResolvedPackages_v1(t *testing.T) {
 	testPath := filepath.Join("testdata", "package-lock-v1.json")
@@ -255,6 +350,88 @@ func TestExtractResolvedPackages_v1(t *testing.T) {
 	}
 }
 
+// TestExtractResolvedPackages_Type tests reading dependency types from
+// lockfile dev, optional, devOptional and peer flags
+func TestExtractResolvedPackages_Type(t *testing.T) {
+	tests := []struct {
+		name     string
+		lockfile string
+		want     map[string]string
+	}{
+		{
+			name: "v3 flags",
+			lockfile: `{"lockfileVersion": 3, "packages": {
+				"": {"name": "root"},
+				"node_modules/express": {"version": "4.18.2"},
+				"node_modules/jest": {"version": "29.0.0", "dev": true},
+				"node_modules/fsevents": {"version": "2.3.3", "optional": true},
+				"node_modules/chokidar": {"version": "3.5.3", "devOptional": true},
+				"node_modules/react": {"version": "18.2.0", "peer": true},
+				"node_modules/babel": {"version": "7.0.0", "dev": true, "optional": true}
+			}}`,
+			want: map[string]string{
+				"express":  DepTypeProd,
+				"jest":     DepTypeDev,
+				"fsevents": DepTypeOptional,
+				"chokidar": DepTypeOptional,
+				"react":    DepTypePeer,
+				"babel":    DepTypeDev,
+			},
+		},
+		{
+			name: "v1 flags with nesting",
+			lockfile: `{"lockfileVersion": 1, "dependencies": {
+				"express": {"version": "4.18.2", "dependencies": {
+					"debug": {"version": "2.6.9", "optional": true}
+				}},
+				"mocha": {"version": "10.0.0", "dev": true, "dependencies": {
+					"ms": {"version": "2.1.3", "dev": true}
+				}}
+			}}`,
+			want: map[string]string{
+				"express": DepTypeProd,
+				"debug":   DepTypeOptional,
+				"mocha":   DepTypeDev,
+				"ms":      DepTypeDev,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockfile, err := ParsePackageLockData([]byte(tt.lockfile))
+			if err != nil {
+				t.Fatalf("ParsePackageLockData failed: %v", err)
+			}
+			got := make(map[string]string)
+			for _, pkg := range ExtractResolvedPackages(lockfile, "package-lock.json") {
+				got[pkg.Name] = pkg.Type
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParsePackageLock_Shrinkwrap tests parsing an npm-shrinkwrap.json file
+func TestParsePackageLock_Shrinkwrap(t *testing.T) {
+	testPath := filepath.Join("testdata", "npm-shrinkwrap.json")
+
+	lockfile, err := ParsePackageLock(testPath)
+	if err != nil {
+		t.Fatalf("ParsePackageLock failed: %v", err)
+	}
+
+	packages := ExtractResolvedPackages(lockfile, testPath)
+	if len(packages) != 1 {
+		t.Fatalf("Expected 1 resolved package, got %d: %+v", len(packages), packages)
+	}
+	if packages[0].Name != "chalk" || packages[0].Version != "4.1.2" {
+		t.Errorf("Expected chalk@4.1.2, got %s@%s", packages[0].Name, packages[0].Version)
+	}
+}
+
 // TestParseYarnLock tests parsing a yarn.lock file
 func TestParseYarnLock(t *testing.T) {
 	testPath := filepath.Join("testdata", "yarn.lock")
@@ -335,6 +512,133 @@ func TestExtractYarnResolvedPackages(t *testing.T) {
 	}
 }
 
+// TestParseYarnLock_Berry tests parsing a yarn berry (v2+) lockfile
+func TestParseYarnLock_Berry(t *testing.T) {
+	testPath := filepath.Join("testdata", "yarn-berry.lock")
+
+	yarnLock, err := ParseYarnLock(testPath)
+	if err != nil {
+		t.Fatalf("ParseYarnLock failed: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, pkg := range ExtractYarnResolvedPackages(yarnLock) {
+		got[pkg.Name] = pkg.Version
+		if pkg.LockfilePath != testPath {
+			t.Errorf("Expected LockfilePath '%s', got '%s'", testPath, pkg.LockfilePath)
+		}
+	}
+
+	// The workspace entry is skipped; the alias and patch resolve to real names
+	want := map[string]string{
+		"@scope/package": "1.0.5",
+		"express":        "4.18.2",
+		"lodash":         "4.17.21",
+		"resolve":        "1.22.8",
+	}
+	if len(got) != len(want) {
+		t.Errorf("Expected %d packages, got %d: %v", len(want), len(got), got)
+	}
+	for name, version := range want {
+		if got[name] != version {
+			t.Errorf("Expected %s@%s, got version '%s'", name, version, got[name])
+		}
+	}
+}
+
+// TestParsePnP tests extracting packages from Yarn PnP data files, as JSON
+// and inlined in the loader by Yarn 2 and Yarn 3+
+func TestParsePnP(t *testing.T) {
+	want := []YarnResolvedPackage{
+		{Name: "@babel/code-frame", Version: "7.22.13"},
+		{Name: "chalk", Version: "5.6.1"},
+		{Name: "react-dom", Version: "18.2.0"},
+		{Name: "resolve", Version: "1.22.8"},
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "pnp.data.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yarn2 := filepath.Join(t.TempDir(), ".pnp.js")
+	content := "#!/usr/bin/env node\nfunction $$SETUP_STATE(hydrateRuntimeState, basePath) {\n  return hydrateRuntimeState(" +
+		string(data) + ", {basePath: basePath || __dirname});\n}\n"
+	if err := os.WriteFile(yarn2, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join("testdata", "pnp.data.json"), filepath.Join("testdata", "pnp.cjs"), yarn2} {
+		yarnLock, err := ParsePnP(path)
+		if err != nil {
+			t.Fatalf("ParsePnP(%s) failed: %v", path, err)
+		}
+		var got []YarnResolvedPackage
+		for _, pkg := range yarnLock.Packages {
+			if pkg.LockfilePath != path {
+				t.Errorf("%s@%s LockfilePath = %s, want %s", pkg.Name, pkg.Version, pkg.LockfilePath, path)
+			}
+			got = append(got, YarnResolvedPackage{Name: pkg.Name, Version: pkg.Version})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParsePnP(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+// TestParsePnPData_Invalid tests rejecting loaders without an inlined state
+// and malformed states
+func TestParsePnPData_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"not inlined", "#!/usr/bin/env node\nconst {readFileSync} = require(`fs`);\nfunction $$SETUP_STATE(hydrateRuntimeState, basePath) {\n  const data = readFileSync(`.pnp.data.json`);\n}\n"},
+		{"unterminated", "const RAW_RUNTIME_STATE =\n'{\\\n  \"packageRegistryData\": []\\\n"},
+		{"malformed", `{"packageRegistryData": [["a", "npm:1.0.0"]]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParsePnPData([]byte(tt.content), ".pnp.cjs"); err == nil {
+				t.Error("ParsePnPData() should fail")
+			}
+		})
+	}
+}
+
+// TestPnPVersion tests reading registry versions from locator references
+func TestPnPVersion(t *testing.T) {
+	tests := []struct {
+		reference string
+		want      string
+		wantOK    bool
+	}{
+		{"npm:1.2.3", "1.2.3", true},
+		{"virtual:0123abcd#npm:18.2.0", "18.2.0", true},
+		{"patch:resolve@npm%3A1.22.8#~builtin<compat/resolve>::version=1.22.8&hash=c3c19d", "1.22.8", true},
+		{"patch:@scope/pkg@npm%3A2.0.0#./.yarn/patches/pkg.patch::locator=app%40workspace%3A.", "2.0.0", true},
+		{"workspace:packages/ui", "", false},
+		{"link:../shared::locator=app%40workspace%3A.", "", false},
+		{"https://example.com/pkg.tgz", "", false},
+		{"virtual:0123abcd", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := pnpVersion(tt.reference)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("pnpVersion(%q) = %q, %v, want %q, %v", tt.reference, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// TestIsYarnBerryLock tests berry format detection
+func TestIsYarnBerryLock(t *testing.T) {
+	if IsYarnBerryLock([]byte("# yarn lockfile v1\n\n\"a@^1.0.0\":\n  version \"1.0.0\"\n")) {
+		t.Error("Expected yarn v1 lockfile not to be detected as berry")
+	}
+	if !IsYarnBerryLock([]byte("# comment\n\n__metadata:\n  version: 8\n")) {
+		t.Error("Expected __metadata block to be detected as berry")
+	}
+}
+
 // TestParseYarnLock_NonExistent tests parsing a non-existent yarn.lock file
 func TestParseYarnLock_NonExistent(t *testing.T) {
 	_, err := ParseYarnLock("nonexistent/yarn.lock")
@@ -387,6 +691,136 @@ func TestExtractPackageName(t *testing.T) {
 			t.Errorf("Expected '@scope/package', got '%s'", name)
 		}
 	})
+
+	t.Run("npm alias", func(t *testing.T) {
+		name := extractPackageName("\"my-alias@npm:@scope/real@^1.0.0\":")
+		if name != "@scope/real" {
+			t.Errorf("Expected '@scope/real', got '%s'", name)
+		}
+	})
+}
+
+// TestParseAliasSpec tests splitting npm alias specs
+func TestParseAliasSpec(t *testing.T) {
+	tests := []struct {
+		spec     string
+		wantName string
+		wantSpec string
+		wantOK   bool
+	}{
+		{"npm:real-pkg@1.2.3", "real-pkg", "1.2.3", true},
+		{"npm:@scope/real-pkg@^2.0.0", "@scope/real-pkg", "^2.0.0", true},
+		{"npm:real-pkg", "real-pkg", "*", true},
+		{"npm:@scope/real-pkg", "@scope/real-pkg", "*", true},
+		{"^1.2.3", "", "", false},
+		{"npm:", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			name, spec, ok := ParseAliasSpec(tt.spec)
+			if name != tt.wantName || spec != tt.wantSpec || ok != tt.wantOK {
+				t.Errorf("ParseAliasSpec(%q) = (%q, %q, %v), want (%q, %q, %v)",
+					tt.spec, name, spec, ok, tt.wantName, tt.wantSpec, tt.wantOK)
+			}
+		})
+	}
+}
+
+// TestExtractDependencies_Alias tests that npm aliases report the real package
+func TestExtractDependencies_Alias(t *testing.T) {
+	manifest := &Manifest{
+		Dependencies: map[string]string{"my-alias": "npm:real-pkg@1.2.3"},
+	}
+
+	deps := ExtractDependencies(manifest, "package.json")
+	if len(deps) != 1 {
+		t.Fatalf("Expected 1 dependency, got %d", len(deps))
+	}
+	if deps[0].Name != "real-pkg" || deps[0].VersionSpec != "1.2.3" || deps[0].Alias != "my-alias" {
+		t.Errorf("Unexpected alias dependency: %+v", deps[0])
+	}
+}
+
+// TestExtractOverrides tests the versions forced by npm overrides and yarn resolutions
+func TestExtractOverrides(t *testing.T) {
+	content := []byte(`{
+		"dependencies": {"evil": "^1.0.0"},
+		"overrides": {
+			"plain": "1.0.1",
+			"parent": {".": "2.0.0", "@scope/child@^1": "1.2.3", "ref": "$evil"},
+			"aliased": "npm:real-pkg@3.0.0",
+			"missing": "$nowhere",
+			"bad": 42
+		},
+		"resolutions": {
+			"**/evil": "1.0.1",
+			"parent/@scope/child": "npm:1.2.4",
+			"berry@npm:^1.0.0": "npm:1.0.5",
+			"@scope/top": "~2.0.0"
+		}
+	}`)
+	var manifest Manifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	var got []string
+	for _, dep := range ExtractOverrides(&manifest, "package.json") {
+		if dep.Type != "overrides" && dep.Type != "resolutions" {
+			t.Errorf("Unexpected type %q for %s", dep.Type, dep.Name)
+		}
+		if ManifestDependencyType(dep.Type) != DepTypeOverride {
+			t.Errorf("ManifestDependencyType(%q) = %q, want %q", dep.Type, ManifestDependencyType(dep.Type), DepTypeOverride)
+		}
+		entry := dep.Name + "@" + dep.VersionSpec + " [" + dep.Override + "]"
+		if dep.Alias != "" {
+			entry += " as " + dep.Alias
+		}
+		got = append(got, entry)
+	}
+
+	want := []string{
+		"real-pkg@3.0.0 [overrides > aliased] as aliased",
+		"parent@2.0.0 [overrides > parent]",
+		"@scope/child@1.2.3 [overrides > parent > @scope/child@^1]",
+		"ref@^1.0.0 [overrides > parent > ref]",
+		"plain@1.0.1 [overrides > plain]",
+		"evil@1.0.1 [resolutions > **/evil]",
+		"@scope/top@~2.0.0 [resolutions > @scope/top]",
+		"berry@1.0.5 [resolutions > berry@npm:^1.0.0]",
+		"@scope/child@1.2.4 [resolutions > parent/@scope/child]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ExtractOverrides() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if deps := ExtractDependencies(&manifest, "package.json"); len(deps) != 1 {
+		t.Errorf("ExtractDependencies() returned %d dependencies, want overrides excluded", len(deps))
+	}
+}
+
+// TestExtractResolvedPackages_Alias tests aliased entries in v1 and v3 lockfiles
+func TestExtractResolvedPackages_Alias(t *testing.T) {
+	v3 := &Lockfile{
+		Version: 3,
+		Packages: map[string]PackageInfo{
+			"node_modules/my-alias": {Name: "real-pkg", Version: "1.2.3"},
+		},
+	}
+	v1 := &Lockfile{
+		Version: 1,
+		Dependencies: map[string]PackageInfo{
+			"my-alias": {Version: "npm:real-pkg@1.2.3"},
+		},
+	}
+
+	for name, lockfile := range map[string]*Lockfile{"v3": v3, "v1": v1} {
+		packages := ExtractResolvedPackages(lockfile, "package-lock.json")
+		if len(packages) != 1 || packages[0].Name != "real-pkg" || packages[0].Version != "1.2.3" {
+			t.Errorf("%s: expected real-pkg@1.2.3, got %+v", name, packages)
+		}
+	}
 }
 
 // TestExtractVersionFromEntry tests the version extraction logic
@@ -437,6 +871,83 @@ func TestExtractVersionFromEntry(t *testing.T) {
 	})
 }
 
+// TestParseSBOM tests extracting npm components from CycloneDX, SPDX and syft documents
+func TestParseSBOM(t *testing.T) {
+	tests := []struct {
+		file       string
+		wantFormat string
+		want       map[string]string
+	}{
+		{
+			file:       "sbom.cdx.json",
+			wantFormat: SBOMFormatCycloneDX,
+			want:       map[string]string{"express": "4.18.2", "@scope/package": "1.0.0"},
+		},
+		{
+			file:       "sbom.spdx.json",
+			wantFormat: SBOMFormatSPDX,
+			want:       map[string]string{"lodash": "4.17.21"},
+		},
+		{
+			file:       "syft.json",
+			wantFormat: SBOMFormatSyft,
+			want:       map[string]string{"express": "4.18.2", "package": "1.0.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			testPath := filepath.Join("testdata", tt.file)
+			packages, format, err := ParseSBOM(testPath)
+			if err != nil {
+				t.Fatalf("ParseSBOM failed: %v", err)
+			}
+			if format != tt.wantFormat {
+				t.Errorf("Expected format '%s', got '%s'", tt.wantFormat, format)
+			}
+			if len(packages) != len(tt.want) {
+				t.Errorf("Expected %d packages, got %d: %+v", len(tt.want), len(packages), packages)
+			}
+			for _, pkg := range packages {
+				if tt.want[pkg.Name] != pkg.Version {
+					t.Errorf("Unexpected package %s@%s", pkg.Name, pkg.Version)
+				}
+				if pkg.LockfilePath != testPath {
+					t.Errorf("Expected LockfilePath '%s', got '%s'", testPath, pkg.LockfilePath)
+				}
+			}
+		})
+	}
+
+	if _, _, err := ParseSBOMData([]byte(`{"name": "not an sbom"}`), "x.json"); err == nil {
+		t.Error("Expected error for unrecognized SBOM format")
+	}
+}
+
+// TestParseNPMPurl tests package URL parsing
+func TestParseNPMPurl(t *testing.T) {
+	tests := []struct {
+		purl    string
+		name    string
+		version string
+		ok      bool
+	}{
+		{"pkg:npm/express@4.18.2", "express", "4.18.2", true},
+		{"pkg:npm/%40scope/package@1.0.0", "@scope/package", "1.0.0", true},
+		{"pkg:npm/@scope/package@1.0.0?vcs_url=x#sub", "@scope/package", "1.0.0", true},
+		{"pkg:npm/lodash", "lodash", "", true},
+		{"pkg:pypi/requests@2.31.0", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, version, ok := ParseNPMPurl(tt.purl)
+		if name != tt.name || version != tt.version || ok != tt.ok {
+			t.Errorf("ParseNPMPurl(%q) = %q, %q, %v; want %q, %q, %v", tt.purl, name, version, ok, tt.name, tt.version, tt.ok)
+		}
+	}
+}
+
 // BenchmarkParsePackageJSON benchmarks parsing a package.json file
 func BenchmarkParsePackageJSON(b *testing.B) {
 	testPath := filepath.Join("testdata", "package.json")
@@ -488,3 +999,57 @@ func BenchmarkExtractResolvedPackages(b *testing.B) {
 		ExtractResolvedPackages(lockfile, testPath)
 	}
 }
+
+// TestDependencyGraph_Chain tests tracing packages back to a root dependency
+func TestDependencyGraph_Chain(t *testing.T) {
+	v3 := &Lockfile{
+		Version: 3,
+		Packages: map[string]PackageInfo{
+			"": {Dependencies: map[string]interface{}{"app-lib": "^1.0.0", "direct": "^2.0.0"}},
+			"node_modules/app-lib": {
+				Version:      "1.0.0",
+				Dependencies: map[string]interface{}{"middle": "^1.0.0"},
+			},
+			"node_modules/middle": {
+				Version:      "1.0.0",
+				Dependencies: map[string]interface{}{"evil": "^1.0.0"},
+			},
+			// Nested copy shadows the hoisted one for middle
+			"node_modules/middle/node_modules/evil": {Version: "1.2.3"},
+			"node_modules/evil":                     {Version: "2.0.0"},
+			"node_modules/direct":                   {Version: "2.0.0"},
+		},
+	}
+
+	v1 := &Lockfile{
+		Version: 1,
+		Dependencies: map[string]PackageInfo{
+			"app-lib": {Version: "1.0.0", Requires: map[string]string{"middle": "^1.0.0"}},
+			"middle": {
+				Version:  "1.0.0",
+				Requires: map[string]string{"evil": "^1.0.0"},
+				Dependencies: map[string]interface{}{
+					"evil": map[string]interface{}{"version": "1.2.3"},
+				},
+			},
+			"evil": {Version: "2.0.0"},
+		},
+	}
+
+	want := []string{"app-lib@1.0.0", "middle@1.0.0", "evil@1.2.3"}
+	for name, lockfile := range map[string]*Lockfile{"v3": v3, "v1": v1} {
+		graph := BuildDependencyGraph(lockfile)
+
+		chain := graph.Chain("evil", "1.2.3")
+		if strings.Join(chain, " ") != strings.Join(want, " ") {
+			t.Errorf("%s: expected chain %v, got %v", name, want, chain)
+		}
+		if chain := graph.Chain("missing", "1.0.0"); chain != nil {
+			t.Errorf("%s: expected nil chain for missing package, got %v", name, chain)
+		}
+	}
+
+	if chain := BuildDependencyGraph(v3).Chain("direct", "2.0.0"); len(chain) != 1 {
+		t.Errorf("expected single-entry chain for a root dependency, got %v", chain)
+	}
+}
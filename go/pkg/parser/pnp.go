@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pnpState is the part of the Yarn Plug'n'Play runtime state that locates
+// packages. packageRegistryData lists, for each package name (null for the
+// top-level project), its locators as [reference, info] pairs:
+//
+//	["lodash", [["npm:4.17.21", {"packageLocation": "...", "linkType": "HARD"}]]]
+type pnpState struct {
+	PackageRegistryData [][2]json.RawMessage `json:"packageRegistryData"`
+}
+
+// pnpPackageInfo is the information of a locator.
+type pnpPackageInfo struct {
+	LinkType string `json:"linkType"`
+}
+
+// ParsePnP reads and parses a Yarn Plug'n'Play data file, .pnp.data.json or
+// the .pnp.cjs loader with the state inlined (see ParsePnPData).
+func ParsePnP(path string) (*YarnLock, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return ParsePnPData(content, path)
+}
+
+// ParsePnPData parses the content of a Yarn Plug'n'Play data file into the
+// packages it locates, as a YarnLock. Zero-install repositories commit these
+// files instead of node_modules, and may not commit yarn.lock.
+//
+// .pnp.data.json holds the runtime state as JSON. .pnp.cjs embeds it, as a
+// string literal assigned to RAW_RUNTIME_STATE (Yarn 3+) or as the object
+// passed to hydrateRuntimeState (Yarn 2). A .pnp.cjs generated with
+// inlining disabled holds no state and yields an error; its .pnp.data.json
+// should be parsed instead.
+//
+// Packages are identified by their locators: "npm:1.2.3" references, also
+// behind virtual: and patch: protocols, are resolved packages. Soft-linked
+// workspaces and other references without a registry version are skipped.
+func ParsePnPData(content []byte, path string) (*YarnLock, error) {
+	data := bytes.TrimSpace(content)
+	if !bytes.HasPrefix(data, []byte("{")) {
+		var err error
+		data, err = pnpRuntimeState(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+		}
+	}
+
+	var state pnpState
+	if err := json.NewDecoder(bytes.NewReader(data)).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filepath.Base(path), err)
+	}
+
+	yarnLock := &YarnLock{Packages: []YarnResolvedPackage{}}
+	seen := make(map[string]bool)
+	for _, entry := range state.PackageRegistryData {
+		var name *string
+		if err := json.Unmarshal(entry[0], &name); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: package name: %w", filepath.Base(path), err)
+		}
+		if name == nil {
+			// The top-level project
+			continue
+		}
+
+		var locators [][2]json.RawMessage
+		if err := json.Unmarshal(entry[1], &locators); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: locators of %s: %w", filepath.Base(path), *name, err)
+		}
+		for _, locator := range locators {
+			var reference *string
+			var info pnpPackageInfo
+			if json.Unmarshal(locator[0], &reference) != nil || reference == nil || json.Unmarshal(locator[1], &info) != nil {
+				continue
+			}
+			if info.LinkType == "SOFT" {
+				continue
+			}
+			version, ok := pnpVersion(*reference)
+			if !ok || seen[*name+"@"+version] {
+				continue
+			}
+			// Virtual locators repeat a package for each set of peers
+			seen[*name+"@"+version] = true
+			yarnLock.Packages = append(yarnLock.Packages, YarnResolvedPackage{
+				Name:         *name,
+				Version:      version,
+				LockfilePath: path,
+			})
+		}
+	}
+
+	return yarnLock, nil
+}
+
+// pnpVersion returns the registry version of a locator reference such as
+// "npm:1.2.3", "virtual:<hash>#npm:1.2.3" or
+// "patch:pkg@npm%3A1.2.3#./patch.diff::locator=...". ok is false for
+// references that do not name a registry version.
+func pnpVersion(reference string) (version string, ok bool) {
+	if strings.HasPrefix(reference, "virtual:") {
+		_, reference, ok = strings.Cut(reference, "#")
+		if !ok {
+			return "", false
+		}
+	}
+
+	if inner, found := strings.CutPrefix(reference, "patch:"); found {
+		// The patched locator is URL-encoded up to the patch path
+		inner, _, _ = strings.Cut(inner, "#")
+		unescaped, err := url.PathUnescape(inner)
+		if err != nil {
+			return "", false
+		}
+		name := berryResolutionName(unescaped)
+		if name == "" {
+			return "", false
+		}
+		return pnpVersion(unescaped[len(name)+1:])
+	}
+
+	version, found := strings.CutPrefix(reference, "npm:")
+	if !found || version == "" {
+		return "", false
+	}
+	return version, true
+}
+
+// pnpRuntimeState extracts the JSON runtime state embedded in a .pnp.cjs
+// loader.
+func pnpRuntimeState(content []byte) ([]byte, error) {
+	// Yarn 3+: const RAW_RUNTIME_STATE =\n'{\...}';
+	if _, rest, found := bytes.Cut(content, []byte("RAW_RUNTIME_STATE")); found {
+		start := bytes.IndexAny(rest, `'"`)
+		if start == -1 {
+			return nil, errors.New("RAW_RUNTIME_STATE is not a string literal")
+		}
+		state, err := unquoteJSString(rest[start:])
+		if err != nil {
+			return nil, fmt.Errorf("RAW_RUNTIME_STATE: %w", err)
+		}
+		return state, nil
+	}
+
+	// Yarn 2: return hydrateRuntimeState({...}, {basePath: ...}); the
+	// object is JSON, and decoding stops at its end
+	if _, rest, found := bytes.Cut(content, []byte("hydrateRuntimeState(")); found {
+		return rest, nil
+	}
+
+	return nil, errors.New("no inlined runtime state (parse .pnp.data.json instead)")
+}
+
+// unquoteJSString decodes the JavaScript string literal at the start of
+// s, quoted with ' or ", including line continuations.
+func unquoteJSString(s []byte) ([]byte, error) {
+	quote := s[0]
+	var b bytes.Buffer
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.Bytes(), nil
+		case c == '\n':
+			return nil, errors.New("unterminated string literal")
+		case c != '\\':
+			b.WriteByte(c)
+			continue
+		}
+
+		i++
+		if i == len(s) {
+			break
+		}
+		switch e := s[i]; e {
+		case '\n':
+			// Line continuation
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		case '0':
+			b.WriteByte(0)
+		case 'x', 'u':
+			digits := 2
+			if e == 'u' {
+				digits = 4
+			}
+			if i+digits >= len(s) {
+				return nil, fmt.Errorf("truncated \\%c escape", e)
+			}
+			code, err := strconv.ParseUint(string(s[i+1:i+1+digits]), 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid \\%c escape", e)
+			}
+			b.WriteRune(rune(code))
+			i += digits
+		default:
+			// \\, \', \" and other characters escape themselves
+			b.WriteByte(e)
+		}
+	}
+	return nil, errors.New("unterminated string literal")
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// SBOM formats accepted by ParseSBOM.
+const (
+	// SBOMFormatCycloneDX is a CycloneDX JSON document
+	SBOMFormatCycloneDX = "CycloneDX"
+	// SBOMFormatSPDX is an SPDX 2.x JSON document
+	SBOMFormatSPDX = "SPDX"
+	// SBOMFormatSyft is syft's native JSON output
+	SBOMFormatSyft = "syft"
+)
+
+// cycloneDXComponent is the subset of a CycloneDX component used for matching.
+type cycloneDXComponent struct {
+	Name       string               `json:"name"`
+	Version    string               `json:"version"`
+	PURL       string               `json:"purl"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+// sbomDocument holds the fields of either supported SBOM format.
+type sbomDocument struct {
+	// CycloneDX
+	BOMFormat  string               `json:"bomFormat"`
+	Components []cycloneDXComponent `json:"components"`
+
+	// SPDX
+	SPDXVersion string `json:"spdxVersion"`
+	Packages    []struct {
+		Name         string `json:"name"`
+		VersionInfo  string `json:"versionInfo"`
+		ExternalRefs []struct {
+			ReferenceType    string `json:"referenceType"`
+			ReferenceLocator string `json:"referenceLocator"`
+		} `json:"externalRefs"`
+	} `json:"packages"`
+
+	// syft
+	Descriptor struct {
+		Name string `json:"name"`
+	} `json:"descriptor"`
+	Artifacts []struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+		Type    string `json:"type"`
+		PURL    string `json:"purl"`
+	} `json:"artifacts"`
+}
+
+// ParseSBOM reads a CycloneDX or SPDX JSON SBOM, or syft JSON output, and
+// returns its npm components as resolved packages located at path.
+//
+// Components are identified as npm packages by their package URL
+// (pkg:npm/...); components without an npm purl are skipped.
+//
+// Returns the detected format (SBOMFormatCycloneDX, SBOMFormatSPDX or
+// SBOMFormatSyft) along with the packages, or an error if the file is not a
+// recognized SBOM.
+func ParseSBOM(path string) ([]ResolvedPackage, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read SBOM: %w", err)
+	}
+
+	return ParseSBOMData(content, path)
+}
+
+// ParseSBOMData parses SBOM content that has already been read.
+// path is recorded as the LockfilePath of each package.
+func ParseSBOMData(content []byte, path string) ([]ResolvedPackage, string, error) {
+	var doc sbomDocument
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, "", fmt.Errorf("failed to parse SBOM: %w", err)
+	}
+
+	packages := []ResolvedPackage{}
+
+	switch {
+	case strings.EqualFold(doc.BOMFormat, SBOMFormatCycloneDX):
+		collectCycloneDX(doc.Components, &packages, path)
+		return packages, SBOMFormatCycloneDX, nil
+
+	case strings.HasPrefix(doc.SPDXVersion, "SPDX-"):
+		for _, pkg := range doc.Packages {
+			for _, ref := range pkg.ExternalRefs {
+				if ref.ReferenceType != "purl" {
+					continue
+				}
+				name, version, ok := ParseNPMPurl(ref.ReferenceLocator)
+				if !ok {
+					continue
+				}
+				if version == "" {
+					version = pkg.VersionInfo
+				}
+				packages = append(packages, ResolvedPackage{
+					Name:         name,
+					Version:      version,
+					LockfilePath: path,
+				})
+				break
+			}
+		}
+		return packages, SBOMFormatSPDX, nil
+
+	case doc.Descriptor.Name == "syft" || doc.Artifacts != nil:
+		for _, artifact := range doc.Artifacts {
+			name, version, ok := ParseNPMPurl(artifact.PURL)
+			if !ok {
+				if artifact.Type != "npm" || artifact.Name == "" {
+					continue
+				}
+				name = artifact.Name
+			}
+			if version == "" {
+				version = artifact.Version
+			}
+			packages = append(packages, ResolvedPackage{
+				Name:         name,
+				Version:      version,
+				LockfilePath: path,
+			})
+		}
+		return packages, SBOMFormatSyft, nil
+	}
+
+	return nil, "", fmt.Errorf("unrecognized SBOM format: expected CycloneDX, SPDX or syft JSON")
+}
+
+// collectCycloneDX appends npm components, including nested ones, to packages.
+func collectCycloneDX(components []cycloneDXComponent, packages *[]ResolvedPackage, path string) {
+	for _, component := range components {
+		if name, version, ok := ParseNPMPurl(component.PURL); ok {
+			if version == "" {
+				version = component.Version
+			}
+			*packages = append(*packages, ResolvedPackage{
+				Name:         name,
+				Version:      version,
+				LockfilePath: path,
+			})
+		}
+		collectCycloneDX(component.Components, packages, path)
+	}
+}
+
+// ParseNPMPurl extracts the package name and version from an npm package
+// URL, e.g. "pkg:npm/%40scope/name@1.0.0" -> "@scope/name", "1.0.0".
+// Returns false if purl is not an npm package URL.
+func ParseNPMPurl(purl string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(purl, "pkg:npm/")
+	if !ok {
+		return "", "", false
+	}
+
+	// Drop subpath and qualifiers
+	if i := strings.Index(rest, "#"); i != -1 {
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "?"); i != -1 {
+		rest = rest[:i]
+	}
+
+	var version string
+	if i := strings.LastIndex(rest, "@"); i > 0 {
+		version = rest[i+1:]
+		rest = rest[:i]
+	}
+
+	name, err := url.PathUnescape(rest)
+	if err != nil || name == "" {
+		return "", "", false
+	}
+	version, err = url.PathUnescape(version)
+	if err != nil {
+		return "", "", false
+	}
+
+	return name, version, true
+}
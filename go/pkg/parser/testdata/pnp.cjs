@@ -0,0 +1,105 @@
+#!/usr/bin/env node
+/* eslint-disable */
+"use strict";
+
+const RAW_RUNTIME_STATE =
+'{\
+  "__info": [\
+    "This file is automatically generated. Do not touch it, or risk",\
+    "your modifications being lost."\
+  ],\
+  "dependencyTreeRoots": [\
+    {\
+      "name": "app",\
+      "reference": "workspace:."\
+    }\
+  ],\
+  "enableTopLevelFallback": true,\
+  "ignorePatternData": "(^(?:\\\\.yarn\\\\/sdks(?:\\\\/(?!\\\\.{1,2}(?:\\\\/|$))(?:(?:(?!(?:^|\\\\/)\\\\.{1,2}(?:\\\\/|$)).)*?)|$))$)",\
+  "fallbackExclusionList": [\
+    ["app", ["workspace:."]]\
+  ],\
+  "fallbackPool": [\
+  ],\
+  "packageRegistryData": [\
+    [null, [\
+      [null, {\
+        "packageLocation": "./",\
+        "packageDependencies": [\
+          ["chalk", "npm:5.6.1"],\
+          ["lodash", "npm:4.17.21"]\
+        ],\
+        "linkType": "SOFT"\
+      }]\
+    ]],\
+    ["@babel/code-frame", [\
+      ["npm:7.22.13", {\
+        "packageLocation": "./.yarn/cache/@babel-code-frame-npm-7.22.13-2782581d20-22e342c8.zip/node_modules/@babel/code-frame/",\
+        "packageDependencies": [\
+          ["@babel/code-frame", "npm:7.22.13"]\
+        ],\
+        "linkType": "HARD"\
+      }]\
+    ]],\
+    ["app", [\
+      ["workspace:.", {\
+        "packageLocation": "./",\
+        "packageDependencies": [\
+          ["app", "workspace:."]\
+        ],\
+        "linkType": "SOFT"\
+      }]\
+    ]],\
+    ["chalk", [\
+      ["npm:5.6.1", {\
+        "packageLocation": "./.yarn/cache/chalk-npm-5.6.1-ba3e2d6f00-ab3a3b7a.zip/node_modules/chalk/",\
+        "packageDependencies": [\
+          ["chalk", "npm:5.6.1"]\
+        ],\
+        "linkType": "HARD"\
+      }]\
+    ]],\
+    ["react-dom", [\
+      ["npm:18.2.0", {\
+        "packageLocation": "./.yarn/cache/react-dom-npm-18.2.0-dd675bca1c-7d323310.zip/node_modules/react-dom/",\
+        "packageDependencies": [\
+          ["react-dom", "npm:18.2.0"]\
+        ],\
+        "linkType": "HARD"\
+      }],\
+      ["virtual:f1e2d3c4b5a6978812345678901234567890abcdef1234567890abcdef123456#npm:18.2.0", {\
+        "packageLocation": "./.yarn/__virtual__/react-dom-virtual-0e4d5c2a1b/0/cache/react-dom-npm-18.2.0-dd675bca1c-7d323310.zip/node_modules/react-dom/",\
+        "packageDependencies": [\
+          ["react", "npm:18.2.0"],\
+          ["react-dom", "virtual:f1e2d3c4b5a6978812345678901234567890abcdef1234567890abcdef123456#npm:18.2.0"]\
+        ],\
+        "packagePeers": [\
+          "react"\
+        ],\
+        "linkType": "HARD"\
+      }]\
+    ]],\
+    ["resolve", [\
+      ["patch:resolve@npm%3A1.22.8#~builtin<compat/resolve>::version=1.22.8&hash=c3c19d", {\
+        "packageLocation": "./.yarn/cache/resolve-patch-4254c24959-5479b7d4.zip/node_modules/resolve/",\
+        "packageDependencies": [\
+          ["resolve", "patch:resolve@npm%3A1.22.8#~builtin<compat/resolve>::version=1.22.8&hash=c3c19d"]\
+        ],\
+        "linkType": "HARD"\
+      }]\
+    ]],\
+    ["shared-utils", [\
+      ["link:../shared-utils::locator=app%40workspace%3A.", {\
+        "packageLocation": "../shared-utils/",\
+        "packageDependencies": [\
+          ["shared-utils", "link:../shared-utils::locator=app%40workspace%3A."]\
+        ],\
+        "linkType": "SOFT"\
+      }]\
+    ]]\
+  ]\
+}';
+
+function $$SETUP_STATE(hydrateRuntimeState, basePath) {
+  return hydrateRuntimeState(JSON.parse(RAW_RUNTIME_STATE), {basePath: basePath || __dirname});
+}
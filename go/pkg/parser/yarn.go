@@ -12,6 +12,7 @@ type YarnResolvedPackage struct {
 	Name         string `json:"name"`
 	Version      string `json:"version"`
 	LockfilePath string `json:"lockfilePath"`
+	Integrity    string `json:"integrity,omitempty"` // yarn v1 only
 }
 
 // YarnLock represents the parsed contents of a yarn.lock file.
@@ -21,9 +22,10 @@ type YarnLock struct {
 }
 
 // ParseYarnLock reads and parses a yarn.lock file using a custom text parser.
-// Supports both yarn v1 and v2/berry formats.
+// Supports both yarn v1 and v2/berry formats; berry lockfiles (identified by
+// their __metadata block) are handled by ParseYarnBerryLockData.
 //
-// The yarn.lock format consists of entries separated by blank lines:
+// The yarn v1 format consists of entries separated by blank lines:
 //   package-name@^1.0.0:
 //     version "1.0.5"
 //     resolved "https://..."
@@ -41,6 +43,17 @@ func ParseYarnLock(path string) (*YarnLock, error) {
 		return nil, fmt.Errorf("failed to read yarn.lock: %w", err)
 	}
 
+	return ParseYarnLockData(content, path), nil
+}
+
+// ParseYarnLockData parses yarn.lock content that has already been read,
+// such as a historical revision from git. path is recorded as the
+// LockfilePath of each package.
+func ParseYarnLockData(content []byte, path string) *YarnLock {
+	if IsYarnBerryLock(content) {
+		return ParseYarnBerryLockData(content, path)
+	}
+
 	yarnLock := &YarnLock{
 		Packages: []YarnResolvedPackage{},
 	}
@@ -87,10 +100,11 @@ func ParseYarnLock(path string) (*YarnLock, error) {
 			Name:         nameMatch,
 			Version:      version,
 			LockfilePath: path,
+			Integrity:    extractIntegrityFromEntry(lines),
 		})
 	}
 
-	return yarnLock, nil
+	return yarnLock
 }
 
 // extractPackageName extracts the package name from a yarn.lock header line.
@@ -100,6 +114,7 @@ func ParseYarnLock(path string) (*YarnLock, error) {
 //   "package@^1.0.0:" -> "package"
 //   "@scope/package@^1.0.0:" -> "@scope/package"
 //   "package@^1.0.0, package@^1.1.0:" -> "package"
+//   "alias@npm:package@^1.0.0:" -> "package"
 func extractPackageName(header string) string {
 	// Remove trailing colon
 	header = strings.TrimSuffix(header, ":")
@@ -117,6 +132,13 @@ func extractPackageName(header string) string {
 		header = strings.Trim(header, "\"")
 	}
 
+	// npm aliases resolve to the real package named after "npm:"
+	if i := strings.Index(header, "@"+aliasPrefix); i > 0 {
+		if name, _, ok := ParseAliasSpec(header[i+1:]); ok {
+			return name
+		}
+	}
+
 	// Extract name before the last @ sign (but handle @scope/package)
 	// Strategy: find the last @ that's followed by a version (not part of scope)
 	// For @scope/package@1.0.0, we want @scope/package (keep the first @)
@@ -152,6 +174,19 @@ func extractVersionFromEntry(lines []string) string {
 	return ""
 }
 
+// extractIntegrityFromEntry extracts the integrity hash from yarn.lock entry
+// lines. Looks for a line containing: integrity sha512-...
+func extractIntegrityFromEntry(lines []string) string {
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "integrity" {
+			return strings.Trim(fields[1], "\"")
+		}
+	}
+
+	return ""
+}
+
 // ExtractYarnResolvedPackages extracts all resolved packages from a YarnLock into a flat list.
 // This is a convenience wrapper that returns the packages slice directly.
 //
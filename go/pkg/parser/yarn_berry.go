@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// IsYarnBerryLock reports whether yarn.lock content is in the yarn berry
+// (v2+) format, which is YAML with a top-level __metadata block.
+func IsYarnBerryLock(content []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "__metadata:") || strings.HasPrefix(line, `"__metadata":`) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseYarnBerryLockData parses yarn berry (v2+) lockfile content.
+//
+// Berry lockfiles are YAML documents with one top-level mapping per resolved
+// package, keyed by the descriptors that resolved to it:
+//
+//	"@babel/code-frame@npm:^7.0.0, @babel/code-frame@npm:^7.10.4":
+//	  version: 7.22.13
+//	  resolution: "@babel/code-frame@npm:7.22.13"
+//	  languageName: node
+//	  linkType: hard
+//
+// The package name is taken from the resolution, not the descriptor, so npm
+// aliases ("alias@npm:real@^1.0.0") and patch: protocols
+// ("resolve@patch:resolve@npm%3A1.22.8#...") report the real package.
+// Workspace and other soft-linked entries are local code and are skipped, as
+// is the __metadata block.
+//
+// Only the block mapping subset of YAML emitted by yarn is supported; nested
+// mappings such as dependencies are skipped.
+func ParseYarnBerryLockData(content []byte, path string) *YarnLock {
+	yarnLock := &YarnLock{
+		Packages: []YarnResolvedPackage{},
+	}
+
+	var fields map[string]string
+	flush := func() {
+		if fields == nil {
+			return
+		}
+		if pkg, ok := berryPackage(fields, path); ok {
+			yarnLock.Packages = append(yarnLock.Packages, pkg)
+		}
+		fields = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		switch {
+		case indent == 0:
+			// New top-level entry
+			flush()
+			key, _ := splitYAMLPair(trimmed)
+			if key == "__metadata" {
+				continue
+			}
+			fields = map[string]string{"": key}
+		case indent == 2 && fields != nil:
+			key, value := splitYAMLPair(trimmed)
+			if value != "" {
+				fields[key] = value
+			}
+		}
+	}
+	flush()
+
+	return yarnLock
+}
+
+// berryPackage builds a resolved package from the fields of a berry entry.
+// The descriptor list is stored under the empty key.
+func berryPackage(fields map[string]string, path string) (YarnResolvedPackage, bool) {
+	if fields["linkType"] == "soft" {
+		return YarnResolvedPackage{}, false
+	}
+
+	version := fields["version"]
+	if version == "" {
+		return YarnResolvedPackage{}, false
+	}
+
+	name := berryResolutionName(fields["resolution"])
+	if name == "" {
+		// Fall back to the first descriptor
+		descriptor := strings.TrimSpace(strings.Split(fields[""], ",")[0])
+		name = berryResolutionName(descriptor)
+	}
+	if name == "" {
+		return YarnResolvedPackage{}, false
+	}
+
+	return YarnResolvedPackage{
+		Name:         name,
+		Version:      version,
+		LockfilePath: path,
+	}, true
+}
+
+// berryResolutionName extracts the package name from a berry locator or
+// descriptor such as "@scope/pkg@npm:1.0.0" or "pkg@patch:pkg@npm%3A1.0.0#...".
+// Returns an empty string for workspace: and link: locators.
+func berryResolutionName(locator string) string {
+	// The name ends at the first @ after an optional scope prefix
+	start := 0
+	if strings.HasPrefix(locator, "@") {
+		start = 1
+	}
+	at := strings.Index(locator[start:], "@")
+	if at == -1 {
+		return ""
+	}
+	name := locator[:start+at]
+	reference := locator[start+at+1:]
+
+	if strings.HasPrefix(reference, "workspace:") ||
+		strings.HasPrefix(reference, "link:") ||
+		strings.HasPrefix(reference, "portal:") {
+		return ""
+	}
+
+	return name
+}
+
+// splitYAMLPair splits a "key: value" line, unquoting both sides. A line
+// ending in a bare colon yields an empty value.
+func splitYAMLPair(line string) (string, string) {
+	line = strings.TrimSuffix(line, ":")
+
+	var key, value string
+	if strings.HasPrefix(line, `"`) {
+		end := strings.Index(line[1:], `"`)
+		if end == -1 {
+			return unquoteYAML(line), ""
+		}
+		key = line[1 : end+1]
+		value = strings.TrimPrefix(line[end+2:], ":")
+	} else {
+		var ok bool
+		key, value, ok = strings.Cut(line, ": ")
+		if !ok {
+			return line, ""
+		}
+	}
+
+	return key, unquoteYAML(strings.TrimSpace(value))
+}
+
+// unquoteYAML strips surrounding single or double quotes from a scalar.
+func unquoteYAML(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') ||
+			(value[0] == '\'' && value[len(value)-1] == '\'') {
+			return value[1 : len(value)-1]
+		}
+	}
+	return value
+}
@@ -0,0 +1,126 @@
+// Package policy enforces required safe versions: after an incident, every
+// copy of a package can be required to satisfy a version range, such as
+// lodash >= 4.17.21, and every copy that does not is reported.
+//
+// Requirements are written as name@range, with npm range syntax:
+//
+//	lodash@>=4.17.21
+//	@scope/pkg@^2.3.1 || >=3.0.0
+//
+// A package with several requirements must satisfy all of them. Versions
+// are evaluated the way npm evaluates ranges, so prereleases only satisfy
+// ranges naming a prerelease of the same version.
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+)
+
+// Requirement is a single name@range policy entry.
+type Requirement struct {
+	// Package is the package name
+	Package string
+	// Range is the version range every copy of the package must satisfy,
+	// as written
+	Range string
+
+	r npmsemver.Range
+}
+
+// String returns the requirement as written.
+func (r Requirement) String() string {
+	return r.Package + "@" + r.Range
+}
+
+// Policy is a set of requirements. A nil or empty Policy requires nothing.
+type Policy struct {
+	requirements map[string][]Requirement
+	count        int
+}
+
+// ParseRequirement parses a name@range entry.
+func ParseRequirement(entry string) (Requirement, error) {
+	entry = strings.TrimSpace(entry)
+	at := strings.LastIndex(entry, "@")
+	if at <= 0 {
+		return Requirement{}, fmt.Errorf("invalid version requirement %q: expected name@range, e.g. lodash@>=4.17.21", entry)
+	}
+	name, spec := strings.TrimSpace(entry[:at]), strings.TrimSpace(entry[at+1:])
+	if spec == "" {
+		return Requirement{}, fmt.Errorf("invalid version requirement %q: empty range", entry)
+	}
+	r, err := npmsemver.ParseRange(spec)
+	if err != nil {
+		return Requirement{}, fmt.Errorf("invalid version requirement %q: %w", entry, err)
+	}
+	return Requirement{Package: name, Range: spec, r: r}, nil
+}
+
+// New parses requirements, one per entry.
+func New(entries []string) (*Policy, error) {
+	p := &Policy{requirements: make(map[string][]Requirement)}
+	for _, entry := range entries {
+		requirement, err := ParseRequirement(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.requirements[requirement.Package] = append(p.requirements[requirement.Package], requirement)
+		p.count++
+	}
+	return p, nil
+}
+
+// Len returns the number of requirements.
+func (p *Policy) Len() int {
+	if p == nil {
+		return 0
+	}
+	return p.count
+}
+
+// Requirements returns the requirements, sorted by package and in the order
+// given for each package.
+func (p *Policy) Requirements() []Requirement {
+	if p == nil {
+		return nil
+	}
+	names := make([]string, 0, len(p.requirements))
+	for name := range p.requirements {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	requirements := make([]Requirement, 0, p.count)
+	for _, name := range names {
+		requirements = append(requirements, p.requirements[name]...)
+	}
+	return requirements
+}
+
+// Check returns the first requirement of the package that version does not
+// satisfy. Versions that are not semver, such as git URLs and tags, cannot
+// be evaluated and satisfy every requirement.
+func (p *Policy) Check(name, version string) (Requirement, bool) {
+	if p == nil {
+		return Requirement{}, false
+	}
+	requirements := p.requirements[name]
+	if len(requirements) == 0 {
+		return Requirement{}, false
+	}
+	v, err := semver.NewVersion(version)
+	if err != nil {
+		return Requirement{}, false
+	}
+	for _, requirement := range requirements {
+		if !requirement.r.Satisfies(v) {
+			return requirement, true
+		}
+	}
+	return Requirement{}, false
+}
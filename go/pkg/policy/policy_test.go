@@ -0,0 +1,72 @@
+package policy
+
+import "testing"
+
+// TestParseRequirement tests name@range parsing
+func TestParseRequirement(t *testing.T) {
+	tests := []struct {
+		entry       string
+		wantPackage string
+		wantRange   string
+		wantErr     bool
+	}{
+		{entry: "lodash@>=4.17.21", wantPackage: "lodash", wantRange: ">=4.17.21"},
+		{entry: " @scope/pkg@^2.3.1 || >=3.0.0 ", wantPackage: "@scope/pkg", wantRange: "^2.3.1 || >=3.0.0"},
+		{entry: "lodash", wantErr: true},
+		{entry: "@scope/pkg", wantErr: true},
+		{entry: "lodash@", wantErr: true},
+		{entry: "lodash@>=banana", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			got, err := ParseRequirement(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequirement() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Package != tt.wantPackage || got.Range != tt.wantRange) {
+				t.Errorf("ParseRequirement() = %s@%s, want %s@%s", got.Package, got.Range, tt.wantPackage, tt.wantRange)
+			}
+		})
+	}
+}
+
+// TestCheck tests which versions violate the requirements
+func TestCheck(t *testing.T) {
+	p, err := New([]string{"lodash@>=4.17.21", "lodash@<5", "@scope/pkg@^2.3.1 || >=3.0.0"})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name, version string
+		wantRange     string
+	}{
+		{name: "lodash", version: "4.17.20", wantRange: ">=4.17.21"},
+		{name: "lodash", version: "4.17.21"},
+		{name: "lodash", version: "5.0.0", wantRange: "<5"},
+		{name: "lodash", version: "4.17.22-beta.1", wantRange: ">=4.17.21"},
+		{name: "lodash", version: "github:lodash/lodash#main"},
+		{name: "@scope/pkg", version: "2.3.0", wantRange: "^2.3.1 || >=3.0.0"},
+		{name: "@scope/pkg", version: "2.9.0"},
+		{name: "@scope/pkg", version: "3.0.0"},
+		{name: "chalk", version: "0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"@"+tt.version, func(t *testing.T) {
+			requirement, violated := p.Check(tt.name, tt.version)
+			if violated != (tt.wantRange != "") || requirement.Range != tt.wantRange {
+				t.Errorf("Check() = %q, %v, want %q", requirement.Range, violated, tt.wantRange)
+			}
+		})
+	}
+
+	if p.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", p.Len())
+	}
+	var none *Policy
+	if _, violated := none.Check("lodash", "1.0.0"); violated || none.Len() != 0 {
+		t.Error("nil Policy reported a violation")
+	}
+}
@@ -0,0 +1,113 @@
+package popularity
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// DefaultTTL is how long a cached lookup is used before it is refreshed.
+const DefaultTTL = 24 * time.Hour
+
+// Cache stores popularity lookups in a JSON file, keyed by package@version.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	dirty   bool
+}
+
+// cacheEntry is one cached lookup.
+type cacheEntry struct {
+	Popularity formatter.Popularity `json:"popularity"`
+	FetchedAt  time.Time            `json:"fetchedAt"`
+}
+
+// DefaultCachePath returns the default cache file, below the user cache
+// directory (such as ~/.cache on Linux).
+func DefaultCachePath() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate cache directory: %w", err)
+	}
+	return filepath.Join(base, "npm-scan", "popularity.json"), nil
+}
+
+// LoadCache reads the cache stored at path, whose entries are used for
+// ttl. A missing or unreadable file yields an empty cache: the cache only
+// ever saves requests.
+func LoadCache(path string, ttl time.Duration) *Cache {
+	c := &Cache{path: path, ttl: ttl, entries: make(map[string]cacheEntry)}
+	if data, err := os.ReadFile(path); err == nil {
+		var entries map[string]cacheEntry
+		if json.Unmarshal(data, &entries) == nil && entries != nil {
+			c.entries = entries
+		}
+	}
+	return c
+}
+
+// Save writes the cache back to its file, if lookups added entries.
+func (c *Cache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := readonly.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+	if err := readonly.WriteFile(c.path, data, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// get returns the cached popularity of name@version, if it is fresh.
+func (c *Cache) get(name, version string, now time.Time) (formatter.Popularity, bool) {
+	if c == nil {
+		return formatter.Popularity{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[cacheKey(name, version)]
+	if !ok || now.Sub(entry.FetchedAt) > c.ttl {
+		return formatter.Popularity{}, false
+	}
+	return entry.Popularity, true
+}
+
+// put caches the popularity of name@version.
+func (c *Cache) put(name, version string, popularity formatter.Popularity, now time.Time) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[cacheKey(name, version)] = cacheEntry{Popularity: popularity, FetchedAt: now.UTC()}
+	c.dirty = true
+}
+
+// cacheKey identifies a package version in the cache.
+func cacheKey(name, version string) string {
+	return name + "@" + version
+}
@@ -0,0 +1,166 @@
+// Package popularity looks up how widely npm packages are used, to help
+// triage matches: a compromised package with millions of weekly downloads
+// pulled in transitively calls for a different response than an obscure one
+// pinned directly.
+//
+// Weekly downloads come from the npm downloads API and dependent counts
+// from deps.dev. Lookups are cached on disk (see Cache), since popularity
+// changes slowly and a fleet of scans reports the same packages.
+package popularity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+const (
+	// DefaultDownloadsURL is the npm API reporting a package's downloads in
+	// the last week; the package name is appended
+	DefaultDownloadsURL = "https://api.npmjs.org/downloads/point/last-week/"
+	// DefaultDependentsURL is the deps.dev API for npm packages; the
+	// package name and version are appended
+	DefaultDependentsURL = "https://api.deps.dev/v3alpha/systems/npm/packages/"
+)
+
+// maxResponseSize bounds the responses of both APIs, which are a few
+// hundred bytes.
+const maxResponseSize = 1 << 20
+
+// Client looks up the popularity of package versions.
+type Client struct {
+	// HTTPClient sends the requests; nil uses the shared client (see
+	// package transport)
+	HTTPClient *http.Client
+	// DownloadsURL and DependentsURL override the API endpoints, such as
+	// for an internal mirror; empty uses the defaults
+	DownloadsURL  string
+	DependentsURL string
+	// Cache, if set, is consulted before and updated after each lookup
+	Cache *Cache
+}
+
+// Lookup returns the popularity of name at version. Packages unknown to an
+// API, such as those unpublished after a compromise, count as zero.
+func (c *Client) Lookup(ctx context.Context, name, version string) (formatter.Popularity, error) {
+	if popularity, ok := c.Cache.get(name, version, time.Now()); ok {
+		return popularity, nil
+	}
+
+	var downloads struct {
+		Downloads int64 `json:"downloads"`
+	}
+	if err := c.get(ctx, withDefault(c.DownloadsURL, DefaultDownloadsURL)+name, &downloads); err != nil {
+		return formatter.Popularity{}, fmt.Errorf("weekly downloads of %s: %w", name, err)
+	}
+
+	var dependents struct {
+		DependentCount       int `json:"dependentCount"`
+		DirectDependentCount int `json:"directDependentCount"`
+	}
+	endpoint := withDefault(c.DependentsURL, DefaultDependentsURL) + url.PathEscape(name) + "/versions/" + url.PathEscape(version) + ":dependents"
+	if err := c.get(ctx, endpoint, &dependents); err != nil {
+		return formatter.Popularity{}, fmt.Errorf("dependents of %s@%s: %w", name, version, err)
+	}
+
+	popularity := formatter.Popularity{
+		WeeklyDownloads:  downloads.Downloads,
+		Dependents:       dependents.DependentCount,
+		DirectDependents: dependents.DirectDependentCount,
+	}
+	c.Cache.put(name, version, popularity, time.Now())
+	return popularity, nil
+}
+
+// Annotate sets the Popularity of every match in result, including the
+// per-project copies, looking up each package version once. Matches whose
+// lookup fails are left unannotated; the failures are returned together.
+func (c *Client) Annotate(ctx context.Context, result *formatter.ScanResult) error {
+	found := make(map[string]formatter.Popularity)
+	failed := make(map[string]error)
+
+	annotate := func(matches []formatter.Match) {
+		for i := range matches {
+			key := cacheKey(matches[i].PackageName, matches[i].Version)
+			if _, ok := failed[key]; ok {
+				continue
+			}
+			popularity, ok := found[key]
+			if !ok {
+				var err error
+				popularity, err = c.Lookup(ctx, matches[i].PackageName, matches[i].Version)
+				if err != nil {
+					failed[key] = err
+					continue
+				}
+				found[key] = popularity
+			}
+			matches[i].Popularity = &popularity
+		}
+	}
+	annotate(result.Matches)
+	for i := range result.Projects {
+		annotate(result.Projects[i].Matches)
+	}
+
+	keys := make([]string, 0, len(failed))
+	for key := range failed {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	errs := make([]error, len(keys))
+	for i, key := range keys {
+		errs[i] = failed[key]
+	}
+	return errors.Join(errs...)
+}
+
+// get fetches endpoint and decodes its JSON response into v. A 404 leaves
+// v unchanged.
+func (c *Client) get(ctx context.Context, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	return nil
+}
+
+// withDefault returns value, or fallback if value is empty.
+func withDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
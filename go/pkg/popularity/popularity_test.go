@@ -0,0 +1,118 @@
+package popularity
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// newTestServer serves both APIs: chalk is popular, gone was unpublished
+// and broken fails
+func newTestServer(t *testing.T, requests *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		path := r.URL.EscapedPath()
+		switch {
+		case path == "/downloads/chalk":
+			fmt.Fprint(w, `{"downloads": 299000000, "package": "chalk"}`)
+		case path == "/deps/chalk/versions/5.6.1:dependents":
+			fmt.Fprint(w, `{"dependentCount": 1200, "directDependentCount": 34, "indirectDependentCount": 1166}`)
+		case path == "/downloads/@scope/pkg":
+			fmt.Fprint(w, `{"downloads": 12}`)
+		case path == "/deps/%40scope%2Fpkg/versions/1.0.0:dependents":
+			fmt.Fprint(w, `{"dependentCount": 0, "directDependentCount": 0}`)
+		case strings.Contains(path, "broken"):
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAnnotate(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, &requests)
+	client := &Client{DownloadsURL: server.URL + "/downloads/", DependentsURL: server.URL + "/deps/"}
+
+	result := &formatter.ScanResult{
+		Matches: []formatter.Match{
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityTransitive},
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityDirect},
+			{PackageName: "@scope/pkg", Version: "1.0.0", Severity: formatter.SeverityDirect},
+			{PackageName: "gone", Version: "0.0.1", Severity: formatter.SeverityDirect},
+			{PackageName: "broken", Version: "1.0.0", Severity: formatter.SeverityDirect},
+		},
+		Projects: []formatter.ProjectResult{
+			{Matches: []formatter.Match{{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityTransitive}}},
+		},
+	}
+
+	err := client.Annotate(context.Background(), result)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf("Annotate() error = %v, want the failed lookup of broken", err)
+	}
+
+	want := []*formatter.Popularity{
+		{WeeklyDownloads: 299000000, Dependents: 1200, DirectDependents: 34},
+		{WeeklyDownloads: 299000000, Dependents: 1200, DirectDependents: 34},
+		{WeeklyDownloads: 12},
+		{},
+		nil,
+	}
+	for i, match := range result.Matches {
+		got := match.Popularity
+		if (got == nil) != (want[i] == nil) || (got != nil && *got != *want[i]) {
+			t.Errorf("%s@%s Popularity = %+v, want %+v", match.PackageName, match.Version, got, want[i])
+		}
+	}
+	if got := result.Projects[0].Matches[0].Popularity; got == nil || got.WeeklyDownloads != 299000000 {
+		t.Errorf("Project match Popularity = %+v, want chalk's", got)
+	}
+	// chalk, @scope/pkg and gone take two requests each; broken fails on the first
+	if got := requests.Load(); got != 7 {
+		t.Errorf("Expected 7 requests, one per package version and API, got %d", got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	var requests atomic.Int32
+	server := newTestServer(t, &requests)
+	path := filepath.Join(t.TempDir(), "popularity", "cache.json")
+
+	client := &Client{DownloadsURL: server.URL + "/downloads/", DependentsURL: server.URL + "/deps/", Cache: LoadCache(path, time.Hour)}
+	first, err := client.Lookup(context.Background(), "chalk", "5.6.1")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if err := client.Cache.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	requests.Store(0)
+	client.Cache = LoadCache(path, time.Hour)
+	second, err := client.Lookup(context.Background(), "chalk", "5.6.1")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if second != first || requests.Load() != 0 {
+		t.Errorf("Cached lookup = %+v after %d requests, want %+v without requests", second, requests.Load(), first)
+	}
+
+	client.Cache = LoadCache(path, 0)
+	if _, err := client.Lookup(context.Background(), "chalk", "5.6.1"); err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if requests.Load() != 2 {
+		t.Errorf("Expected an expired entry to be fetched again, got %d requests", requests.Load())
+	}
+}
@@ -0,0 +1,538 @@
+// Package provenance verifies the npm provenance attestations of package
+// versions: sigstore bundles, published along with a version, that tie it
+// to the source repository and CI workflow that built it. Compromised
+// releases are usually published from a stolen token, by hand, and so lack
+// the provenance of their legitimate neighbours, or carry one pointing at
+// a repository the package does not declare.
+//
+// An attestation is verified against its signing certificate: the DSSE
+// signature must verify with the certificate's key, and the in-toto
+// statement must name the published tarball. The certificate chain is only
+// verified when trusted roots (the sigstore Fulcio roots) are configured,
+// and transparency log inclusion is not checked.
+package provenance
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+// DefaultURL is the public npm registry.
+const DefaultURL = "https://registry.npmjs.org/"
+
+// PredicateSLSA is the predicate type prefix of SLSA provenance statements,
+// which npm publishes as "https://slsa.dev/provenance/v1" (or v0.2 for
+// older releases).
+const PredicateSLSA = "https://slsa.dev/provenance/"
+
+// payloadType is the DSSE payload type of in-toto statements.
+const payloadType = "application/vnd.in-toto+json"
+
+// maxResponseSize bounds a version manifest or attestation bundle.
+const maxResponseSize = 16 << 20
+
+// workers bounds the concurrent checks of CheckAll.
+const workers = 8
+
+// Fulcio certificate extensions naming the source repository of the
+// workflow the certificate was issued to.
+var (
+	oidSourceRepositoryURI = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 12}
+	// oidGitHubRepository is the deprecated extension, holding "owner/repo"
+	// as raw bytes rather than DER
+	oidGitHubRepository = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 5}
+)
+
+// Client checks the provenance of package versions published in a
+// registry.
+type Client struct {
+	// HTTPClient sends the requests; nil uses the shared client (see
+	// package transport)
+	HTTPClient *http.Client
+	// URL is the registry, such as an internal mirror; empty uses
+	// DefaultURL
+	URL string
+	// Roots, if set, are the trusted roots signing certificates must chain
+	// to, such as the sigstore Fulcio roots. Without them, only the
+	// signature against the bundled certificate is verified.
+	Roots *x509.CertPool
+}
+
+// Package identifies a package version.
+type Package struct {
+	Name    string
+	Version string
+}
+
+// Result is the provenance of a package version.
+type Result struct {
+	// Attested is set if the version was published with a provenance
+	// attestation
+	Attested bool
+	// Repository is the source repository the attestation names, once
+	// verified, such as "https://github.com/owner/repo"
+	Repository string
+	// Declared is the repository the version's package.json declares, if any
+	Declared string
+	// Problem describes why the attestation is not trustworthy: it does
+	// not verify, names another tarball, or names a repository other than
+	// Declared. Empty if the attestation is sound or there is none.
+	Problem string
+}
+
+// versionManifest is the part of a registry version document that
+// locates its tarball and attestations.
+type versionManifest struct {
+	Repository json.RawMessage `json:"repository"`
+	Dist       struct {
+		Integrity    string `json:"integrity"`
+		Attestations *struct {
+			URL string `json:"url"`
+		} `json:"attestations"`
+	} `json:"dist"`
+}
+
+// attestations is the response of the registry's attestations endpoint.
+type attestations struct {
+	Attestations []struct {
+		PredicateType string `json:"predicateType"`
+		Bundle        bundle `json:"bundle"`
+	} `json:"attestations"`
+}
+
+// bundle is a sigstore bundle holding a DSSE envelope. Versions 0.1 and
+// 0.2 carry a certificate chain, 0.3 a single certificate.
+type bundle struct {
+	VerificationMaterial struct {
+		X509CertificateChain *struct {
+			Certificates []rawCertificate `json:"certificates"`
+		} `json:"x509CertificateChain"`
+		Certificate *rawCertificate `json:"certificate"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *struct {
+		Payload     []byte      `json:"payload"`
+		PayloadType string      `json:"payloadType"`
+		Signatures  []signature `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// signature is a DSSE signature.
+type signature struct {
+	Sig []byte `json:"sig"`
+}
+
+// rawCertificate is a DER certificate of a bundle.
+type rawCertificate struct {
+	RawBytes []byte `json:"rawBytes"`
+}
+
+// statement is an in-toto statement.
+type statement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+// Check looks up the provenance of name@version and verifies it. It returns
+// nil, without an error, if the registry does not publish the version.
+// Errors are failed lookups; an attestation that does not verify is a
+// Result with a Problem.
+func (c *Client) Check(ctx context.Context, name, version string) (*Result, error) {
+	var manifest versionManifest
+	found, err := c.get(ctx, c.endpoint(url.PathEscape(name), url.PathEscape(version)), &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s@%s: %w", name, version, err)
+	}
+	if !found {
+		return nil, nil
+	}
+
+	result := &Result{Declared: declaredRepository(manifest.Repository)}
+	if manifest.Dist.Attestations == nil || manifest.Dist.Attestations.URL == "" {
+		return result, nil
+	}
+	result.Attested = true
+
+	var published attestations
+	found, err = c.get(ctx, manifest.Dist.Attestations.URL, &published)
+	if err != nil {
+		return nil, fmt.Errorf("fetch attestations of %s@%s: %w", name, version, err)
+	}
+	if !found {
+		result.Problem = "attestations are advertised but not published"
+		return result, nil
+	}
+
+	var provenance *bundle
+	for i, attestation := range published.Attestations {
+		if strings.HasPrefix(attestation.PredicateType, PredicateSLSA) {
+			provenance = &published.Attestations[i].Bundle
+			break
+		}
+	}
+	if provenance == nil {
+		result.Problem = "attestations hold no provenance statement"
+		return result, nil
+	}
+
+	repository, err := c.verify(provenance, name, version, manifest.Dist.Integrity)
+	if err != nil {
+		result.Problem = err.Error()
+		return result, nil
+	}
+	result.Repository = repository
+	if result.Declared != "" && normalizeRepository(result.Declared) != normalizeRepository(repository) {
+		result.Problem = fmt.Sprintf("provenance names repository %s, but the package declares %s", repository, result.Declared)
+	}
+	return result, nil
+}
+
+// CheckAll checks each of packages concurrently. Versions the registry does
+// not publish are missing from the map, as are those whose check failed;
+// their errors are returned together.
+func (c *Client) CheckAll(ctx context.Context, packages []Package) (map[Package]*Result, error) {
+	results := make(map[Package]*Result, len(packages))
+	failed := make(map[Package]error)
+	var mu sync.Mutex
+
+	jobs := make(chan Package)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(packages); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pkg := range jobs {
+				result, err := c.Check(ctx, pkg.Name, pkg.Version)
+				mu.Lock()
+				if err != nil {
+					failed[pkg] = err
+				} else if result != nil {
+					results[pkg] = result
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, pkg := range packages {
+		jobs <- pkg
+	}
+	close(jobs)
+	wg.Wait()
+
+	failedPackages := make([]Package, 0, len(failed))
+	for pkg := range failed {
+		failedPackages = append(failedPackages, pkg)
+	}
+	sort.Slice(failedPackages, func(i, j int) bool {
+		if failedPackages[i].Name != failedPackages[j].Name {
+			return failedPackages[i].Name < failedPackages[j].Name
+		}
+		return failedPackages[i].Version < failedPackages[j].Version
+	})
+	errs := make([]error, len(failedPackages))
+	for i, pkg := range failedPackages {
+		errs[i] = failed[pkg]
+	}
+	return results, errors.Join(errs...)
+}
+
+// endpoint returns the registry URL of path.
+func (c *Client) endpoint(path ...string) string {
+	base := c.URL
+	if base == "" {
+		base = DefaultURL
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.Join(path, "/")
+}
+
+// get fetches endpoint and decodes its JSON into v. found is false if the
+// registry answered 404.
+func (c *Client) get(ctx context.Context, endpoint string, v any) (found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return false, err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("parse response: %w", err)
+	}
+	return true, nil
+}
+
+// verify verifies a provenance bundle for name@version, whose tarball has
+// the given SRI integrity, and returns the source repository it names.
+func (c *Client) verify(b *bundle, name, version, integrity string) (string, error) {
+	var chain []rawCertificate
+	if b.VerificationMaterial.X509CertificateChain != nil {
+		chain = b.VerificationMaterial.X509CertificateChain.Certificates
+	} else if b.VerificationMaterial.Certificate != nil {
+		chain = []rawCertificate{*b.VerificationMaterial.Certificate}
+	}
+	if len(chain) == 0 {
+		return "", errors.New("provenance has no signing certificate")
+	}
+	leaf, err := x509.ParseCertificate(chain[0].RawBytes)
+	if err != nil {
+		return "", fmt.Errorf("provenance signing certificate is invalid: %v", err)
+	}
+
+	if c.Roots != nil {
+		intermediates := x509.NewCertPool()
+		for _, raw := range chain[1:] {
+			cert, err := x509.ParseCertificate(raw.RawBytes)
+			if err != nil {
+				return "", fmt.Errorf("provenance certificate chain is invalid: %v", err)
+			}
+			intermediates.AddCert(cert)
+		}
+		// Signing certificates live for minutes; the signature was made
+		// while the certificate was valid
+		_, err := leaf.Verify(x509.VerifyOptions{
+			Roots:         c.Roots,
+			Intermediates: intermediates,
+			CurrentTime:   leaf.NotBefore,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		})
+		if err != nil {
+			return "", fmt.Errorf("provenance signing certificate is not trusted: %v", err)
+		}
+	}
+
+	envelope := b.DSSEEnvelope
+	if envelope == nil || envelope.PayloadType != payloadType {
+		return "", errors.New("provenance is not an in-toto statement")
+	}
+	if !verifySignatures(leaf, envelope.PayloadType, envelope.Payload, envelope.Signatures) {
+		return "", errors.New("provenance signature does not verify")
+	}
+
+	var stmt statement
+	if err := json.Unmarshal(envelope.Payload, &stmt); err != nil {
+		return "", fmt.Errorf("provenance statement is invalid: %v", err)
+	}
+	if err := checkSubject(stmt, name, version, integrity); err != nil {
+		return "", err
+	}
+
+	certified := certificateRepository(leaf)
+	stated := statementRepository(stmt)
+	switch {
+	case certified == "" && stated == "":
+		return "", errors.New("provenance names no source repository")
+	case certified == "":
+		return stated, nil
+	case stated != "" && normalizeRepository(stated) != normalizeRepository(certified):
+		return "", fmt.Errorf("provenance statement names repository %s, but its certificate was issued to %s", stated, certified)
+	}
+	return certified, nil
+}
+
+// verifySignatures reports whether one of signatures verifies the DSSE
+// payload with the key of cert.
+func verifySignatures(cert *x509.Certificate, payloadType string, payload []byte, signatures []signature) bool {
+	key, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+	var h hash.Hash
+	switch key.Curve {
+	case elliptic.P256():
+		h = sha256.New()
+	case elliptic.P384():
+		h = sha512.New384()
+	case elliptic.P521():
+		h = sha512.New()
+	default:
+		return false
+	}
+	h.Write(pae(payloadType, payload))
+	digest := h.Sum(nil)
+
+	for _, signature := range signatures {
+		if ecdsa.VerifyASN1(key, digest, signature.Sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// pae returns the DSSE pre-authentication encoding of a payload, the bytes
+// actually signed.
+func pae(payloadType string, payload []byte) []byte {
+	prefix := fmt.Sprintf("DSSEv1 %d %s %d ", len(payloadType), payloadType, len(payload))
+	return append([]byte(prefix), payload...)
+}
+
+// checkSubject checks that the statement is about name@version and, when
+// its SRI integrity is known, about the published tarball.
+func checkSubject(stmt statement, name, version, integrity string) error {
+	want := PackageURL(name, version)
+	for _, subject := range stmt.Subject {
+		if subject.Name != want {
+			continue
+		}
+		digest, ok := sha512Hex(integrity)
+		if ok && !strings.EqualFold(subject.Digest["sha512"], digest) {
+			return errors.New("provenance does not match the published tarball")
+		}
+		return nil
+	}
+	return fmt.Errorf("provenance is not about %s@%s", name, version)
+}
+
+// PackageURL returns the package URL npm attestations use as the subject
+// of name@version, such as "pkg:npm/%40scope/pkg@1.0.0".
+func PackageURL(name, version string) string {
+	return "pkg:npm/" + strings.Replace(name, "@", "%40", 1) + "@" + version
+}
+
+// sha512Hex returns the hex digest of an SRI "sha512-<base64>" integrity.
+func sha512Hex(integrity string) (string, bool) {
+	for _, field := range strings.Fields(integrity) {
+		encoded, ok := strings.CutPrefix(field, "sha512-")
+		if !ok {
+			continue
+		}
+		digest, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return "", false
+		}
+		return hex.EncodeToString(digest), true
+	}
+	return "", false
+}
+
+// certificateRepository returns the source repository a Fulcio signing
+// certificate was issued to, or "".
+func certificateRepository(cert *x509.Certificate) string {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSourceRepositoryURI) {
+			var uri string
+			if _, err := asn1.Unmarshal(ext.Value, &uri); err == nil {
+				return uri
+			}
+		}
+	}
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidGitHubRepository) {
+			return "https://github.com/" + string(ext.Value)
+		}
+	}
+	return ""
+}
+
+// statementRepository returns the source repository a SLSA provenance
+// predicate names, or "".
+func statementRepository(stmt statement) string {
+	var predicate struct {
+		// SLSA v1
+		BuildDefinition struct {
+			ExternalParameters struct {
+				Workflow struct {
+					Repository string `json:"repository"`
+				} `json:"workflow"`
+			} `json:"externalParameters"`
+		} `json:"buildDefinition"`
+		// SLSA v0.2: "git+https://github.com/owner/repo@refs/heads/main"
+		Invocation struct {
+			ConfigSource struct {
+				URI string `json:"uri"`
+			} `json:"configSource"`
+		} `json:"invocation"`
+	}
+	if json.Unmarshal(stmt.Predicate, &predicate) != nil {
+		return ""
+	}
+	if repository := predicate.BuildDefinition.ExternalParameters.Workflow.Repository; repository != "" {
+		return repository
+	}
+	uri := strings.TrimPrefix(predicate.Invocation.ConfigSource.URI, "git+")
+	if at := strings.LastIndex(uri, "@"); at > strings.Index(uri, "://") {
+		uri = uri[:at]
+	}
+	return uri
+}
+
+// declaredRepository returns the repository URL of a package.json
+// repository field, a string or a {type, url} object.
+func declaredRepository(raw json.RawMessage) string {
+	var repository string
+	if json.Unmarshal(raw, &repository) == nil {
+		return repository
+	}
+	var object struct {
+		URL string `json:"url"`
+	}
+	if json.Unmarshal(raw, &object) == nil {
+		return object.URL
+	}
+	return ""
+}
+
+// normalizeRepository reduces the forms of a repository URL
+// ("git+https://github.com/Owner/Repo.git", "git@github.com:owner/repo",
+// "github:owner/repo", "owner/repo") to "github.com/owner/repo", for
+// comparison.
+func normalizeRepository(repository string) string {
+	r := strings.ToLower(strings.TrimSpace(repository))
+	r = strings.TrimPrefix(r, "git+")
+	if rest, ok := strings.CutPrefix(r, "github:"); ok {
+		r = "github.com/" + rest
+	} else if rest, ok := strings.CutPrefix(r, "git@"); ok {
+		r = strings.Replace(rest, ":", "/", 1)
+	} else if _, rest, ok := strings.Cut(r, "://"); ok {
+		r = rest
+		if user, host, ok := strings.Cut(r, "@"); ok && !strings.Contains(user, "/") {
+			// Strip credentials or the git user
+			r = host
+		}
+	} else if owner, _, ok := strings.Cut(r, "/"); ok && strings.Count(r, "/") == 1 && !strings.Contains(owner, ".") {
+		// The "owner/repo" shorthand of GitHub repositories
+		r = "github.com/" + r
+	}
+	r, _, _ = strings.Cut(r, "#")
+	return strings.TrimSuffix(strings.TrimSuffix(r, "/"), ".git")
+}
@@ -0,0 +1,285 @@
+package provenance
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigner is a certificate authority and a signing certificate issued
+// by it, as Fulcio issues to a CI workflow.
+type testSigner struct {
+	root    *x509.Certificate
+	leaf    []byte
+	leafKey *ecdsa.PrivateKey
+}
+
+func newTestSigner(t *testing.T, repository string) *testSigner {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := asn1.Marshal(repository)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber:    big.NewInt(2),
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		ExtraExtensions: []pkix.Extension{{Id: oidSourceRepositoryURI, Value: uri}},
+	}
+	leaf, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testSigner{root: root, leaf: leaf, leafKey: leafKey}
+}
+
+// bundle returns a provenance bundle of a statement about the tarball
+// with the given content, naming repository.
+func (s *testSigner) bundle(t *testing.T, subject string, tarball []byte, repository string) bundle {
+	t.Helper()
+	digest := sha512.Sum512(tarball)
+	payload, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []any{map[string]any{"name": subject, "digest": map[string]string{"sha512": hex.EncodeToString(digest[:])}}},
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"predicate": map[string]any{
+			"buildDefinition": map[string]any{
+				"externalParameters": map[string]any{"workflow": map[string]any{"repository": repository}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256(pae(payloadType, payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, s.leafKey, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bundle
+	b.VerificationMaterial.Certificate = &rawCertificate{RawBytes: s.leaf}
+	b.DSSEEnvelope = &struct {
+		Payload     []byte      `json:"payload"`
+		PayloadType string      `json:"payloadType"`
+		Signatures  []signature `json:"signatures"`
+	}{Payload: payload, PayloadType: payloadType, Signatures: []signature{{Sig: sig}}}
+	return b
+}
+
+// newTestRegistry serves version documents and attestations: "good" is
+// soundly attested, "plain" has no provenance, "moved" declares another
+// repository, "tampered" has a modified statement and "swapped" names
+// another tarball.
+func newTestRegistry(t *testing.T, signer *testSigner) *httptest.Server {
+	t.Helper()
+	const repository = "https://github.com/owner/good"
+	tarball := []byte("tarball")
+	digest := sha512.Sum512(tarball)
+	integrity := "sha512-" + base64.StdEncoding.EncodeToString(digest[:])
+
+	tampered := signer.bundle(t, PackageURL("tampered", "1.0.0"), tarball, repository)
+	tampered.DSSEEnvelope.Payload = []byte(strings.Replace(string(tampered.DSSEEnvelope.Payload), "owner/good", "owner/evil", 1))
+	bundles := map[string]bundle{
+		"good":          signer.bundle(t, PackageURL("good", "1.0.0"), tarball, repository),
+		"@scope/scoped": signer.bundle(t, PackageURL("@scope/scoped", "1.0.0"), tarball, repository),
+		"moved":         signer.bundle(t, PackageURL("moved", "1.0.0"), tarball, repository),
+		"tampered":      tampered,
+		"swapped":       signer.bundle(t, PackageURL("swapped", "1.0.0"), []byte("other tarball"), repository),
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if spec, ok := strings.CutPrefix(r.URL.Path, "/-/npm/v1/attestations/"); ok {
+			name := spec[:strings.LastIndex(spec, "@")]
+			json.NewEncoder(w).Encode(map[string]any{"attestations": []any{
+				map[string]any{"predicateType": "https://github.com/npm/attestation/tree/main/specs/publish/v0.1"},
+				map[string]any{"predicateType": "https://slsa.dev/provenance/v1", "bundle": bundles[name]},
+			}})
+			return
+		}
+
+		path := strings.TrimPrefix(r.URL.Path, "/")
+		name, version := path[:strings.LastIndex(path, "/")], path[strings.LastIndex(path, "/")+1:]
+		if version != "1.0.0" || (name != "plain" && bundles[name].DSSEEnvelope == nil) {
+			http.NotFound(w, r)
+			return
+		}
+		doc := map[string]any{
+			"name":       name,
+			"version":    version,
+			"repository": map[string]string{"type": "git", "url": "git+https://github.com/owner/good.git"},
+			"dist":       map[string]any{"integrity": integrity},
+		}
+		if name == "moved" {
+			doc["repository"] = "github:someone/else"
+		}
+		if name != "plain" {
+			doc["dist"] = map[string]any{
+				"integrity":    integrity,
+				"attestations": map[string]any{"url": server.URL + "/-/npm/v1/attestations/" + name + "@" + version},
+			}
+		}
+		json.NewEncoder(w).Encode(doc)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheck(t *testing.T) {
+	signer := newTestSigner(t, "https://github.com/owner/good")
+	server := newTestRegistry(t, signer)
+	client := &Client{URL: server.URL}
+
+	tests := []struct {
+		name        string
+		wantNil     bool
+		wantAttest  bool
+		wantProblem string
+	}{
+		{name: "good", wantAttest: true},
+		{name: "@scope/scoped", wantAttest: true},
+		{name: "plain"},
+		{name: "moved", wantAttest: true, wantProblem: "but the package declares github:someone/else"},
+		{name: "tampered", wantAttest: true, wantProblem: "signature does not verify"},
+		{name: "swapped", wantAttest: true, wantProblem: "does not match the published tarball"},
+		{name: "unknown", wantNil: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := client.Check(context.Background(), tt.name, "1.0.0")
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if tt.wantNil {
+				if result != nil {
+					t.Errorf("Expected no result, got %+v", result)
+				}
+				return
+			}
+			if result == nil {
+				t.Fatal("Expected a result, got nil")
+			}
+			if result.Attested != tt.wantAttest {
+				t.Errorf("Attested = %v, want %v", result.Attested, tt.wantAttest)
+			}
+			if tt.wantProblem == "" && result.Problem != "" {
+				t.Errorf("Unexpected problem %q", result.Problem)
+			}
+			if !strings.Contains(result.Problem, tt.wantProblem) {
+				t.Errorf("Problem = %q, want it to contain %q", result.Problem, tt.wantProblem)
+			}
+			if tt.wantAttest && tt.wantProblem == "" && result.Repository != "https://github.com/owner/good" {
+				t.Errorf("Repository = %q", result.Repository)
+			}
+		})
+	}
+}
+
+func TestCheck_Roots(t *testing.T) {
+	signer := newTestSigner(t, "https://github.com/owner/good")
+	server := newTestRegistry(t, signer)
+
+	trusted := x509.NewCertPool()
+	trusted.AddCert(signer.root)
+	result, err := (&Client{URL: server.URL, Roots: trusted}).Check(context.Background(), "good", "1.0.0")
+	if err != nil || result == nil || result.Problem != "" {
+		t.Errorf("Expected a verified result with the signing root trusted, got %+v (%v)", result, err)
+	}
+
+	other := x509.NewCertPool()
+	other.AddCert(newTestSigner(t, "https://github.com/owner/good").root)
+	result, err = (&Client{URL: server.URL, Roots: other}).Check(context.Background(), "good", "1.0.0")
+	if err != nil || result == nil || !strings.Contains(result.Problem, "not trusted") {
+		t.Errorf("Expected an untrusted certificate, got %+v (%v)", result, err)
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	signer := newTestSigner(t, "https://github.com/owner/good")
+	server := newTestRegistry(t, signer)
+
+	packages := []Package{{"good", "1.0.0"}, {"plain", "1.0.0"}, {"unknown", "1.0.0"}}
+	results, err := (&Client{URL: server.URL}).CheckAll(context.Background(), packages)
+	if err != nil {
+		t.Fatalf("CheckAll failed: %v", err)
+	}
+	if len(results) != 2 || !results[packages[0]].Attested || results[packages[1]].Attested {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	results, err = (&Client{URL: closed.URL}).CheckAll(context.Background(), packages[:1])
+	if err == nil || len(results) != 0 {
+		t.Errorf("Expected a failed lookup, got %+v (%v)", results, err)
+	}
+}
+
+func TestNormalizeRepository(t *testing.T) {
+	tests := []struct {
+		repository string
+		want       string
+	}{
+		{"https://github.com/owner/repo", "github.com/owner/repo"},
+		{"git+https://github.com/Owner/Repo.git", "github.com/owner/repo"},
+		{"git+ssh://git@github.com/owner/repo.git", "github.com/owner/repo"},
+		{"git@github.com:owner/repo.git", "github.com/owner/repo"},
+		{"github:owner/repo", "github.com/owner/repo"},
+		{"owner/repo", "github.com/owner/repo"},
+		{"https://github.com/owner/repo/#readme", "github.com/owner/repo"},
+		{"https://gitlab.com/group/sub/repo", "gitlab.com/group/sub/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.repository, func(t *testing.T) {
+			if got := normalizeRepository(tt.repository); got != tt.want {
+				t.Errorf("normalizeRepository(%q) = %q, want %q", tt.repository, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Package readonly enforces read-only mode, in which the scanner writes
+// nothing outside an explicitly provided output directory, so it can run in
+// locked-down forensic environments against mounted evidence images.
+//
+// Every file the module writes goes through WriteFile or MkdirAll, which
+// refuse paths outside the allowed directory once Enable has been called.
+// Operations that would write elsewhere (package manager runs, tool caches)
+// check Enabled or Check first.
+package readonly
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ErrReadOnly is returned for writes refused in read-only mode.
+var ErrReadOnly = errors.New("refused in read-only mode")
+
+var (
+	mu      sync.RWMutex
+	enabled bool
+	// allowed is the resolved directory writes are allowed below, or empty
+	allowed string
+)
+
+// Enable turns read-only mode on. Writes are then only allowed below
+// outputDir; an empty outputDir allows no writes at all. Calling Enable
+// again replaces the allowed directory.
+func Enable(outputDir string) error {
+	dir := ""
+	if outputDir != "" {
+		var err error
+		dir, err = resolve(outputDir)
+		if err != nil {
+			return fmt.Errorf("read-only output directory: %w", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = true
+	allowed = dir
+	return nil
+}
+
+// Disable turns read-only mode off.
+func Disable() {
+	mu.Lock()
+	defer mu.Unlock()
+	enabled = false
+	allowed = ""
+}
+
+// Enabled reports whether read-only mode is on.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return enabled
+}
+
+// Check returns an error wrapping ErrReadOnly if writing path is refused,
+// that is if read-only mode is on and path is not below the allowed
+// directory. Symbolic links are resolved, so a link inside the allowed
+// directory cannot redirect writes outside it.
+func Check(path string) error {
+	mu.RLock()
+	on, dir := enabled, allowed
+	mu.RUnlock()
+	if !on {
+		return nil
+	}
+
+	if dir != "" {
+		resolved, err := resolve(path)
+		if err == nil && within(dir, resolved) {
+			return nil
+		}
+		return fmt.Errorf("write to %s %w: only %s may be written", path, ErrReadOnly, dir)
+	}
+	return fmt.Errorf("write to %s %w", path, ErrReadOnly)
+}
+
+// WriteFile is os.WriteFile, refused outside the allowed directory in
+// read-only mode.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if err := Check(path); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, perm)
+}
+
+// MkdirAll is os.MkdirAll, refused outside the allowed directory in
+// read-only mode.
+func MkdirAll(path string, perm os.FileMode) error {
+	if err := Check(path); err != nil {
+		return err
+	}
+	return os.MkdirAll(path, perm)
+}
+
+// resolve returns the absolute path with symbolic links resolved in its
+// longest existing prefix, so paths that do not exist yet can be checked.
+// Dangling links are not resolved and yield an error.
+func resolve(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	var rest []string
+	current := abs
+	for {
+		if real, err := filepath.EvalSymlinks(current); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		// A dangling link could still be written through
+		if _, err := os.Lstat(current); err == nil {
+			return "", fmt.Errorf("cannot resolve %s", current)
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		rest = append([]string{filepath.Base(current)}, rest...)
+		current = parent
+	}
+}
+
+// within reports whether path is dir or below it.
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
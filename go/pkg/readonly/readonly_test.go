@@ -0,0 +1,99 @@
+package readonly
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestCheck tests which paths may be written in read-only mode
+func TestCheck(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "out")
+	if err := os.MkdirAll(filepath.Join(out, "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "evidence"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Links inside the output directory pointing at the evidence
+	if err := os.Symlink(filepath.Join(root, "evidence"), filepath.Join(out, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "evidence", "missing"), filepath.Join(out, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+
+	defer Disable()
+
+	tests := []struct {
+		name    string
+		allowed string
+		path    string
+		wantErr bool
+	}{
+		{name: "below the output directory", allowed: out, path: filepath.Join(out, "run", "summary.json")},
+		{name: "new subdirectory", allowed: out, path: filepath.Join(out, "new", "deeper", "result.json")},
+		{name: "the output directory itself", allowed: out, path: out},
+		{name: "outside", allowed: out, path: filepath.Join(root, "evidence", "package-lock.json"), wantErr: true},
+		{name: "sibling with common prefix", allowed: out, path: out + "-other", wantErr: true},
+		{name: "relative escape", allowed: out, path: filepath.Join(out, "..", "cache.json"), wantErr: true},
+		{name: "symlink escape", allowed: out, path: filepath.Join(out, "escape", "package.json"), wantErr: true},
+		{name: "dangling symlink", allowed: out, path: filepath.Join(out, "dangling", "package.json"), wantErr: true},
+		{name: "no output directory", allowed: "", path: filepath.Join(out, "summary.json"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Enable(tt.allowed); err != nil {
+				t.Fatalf("Enable() error = %v", err)
+			}
+			err := Check(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check(%s) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrReadOnly) {
+				t.Errorf("Check(%s) error = %v, want ErrReadOnly", tt.path, err)
+			}
+		})
+	}
+
+	Disable()
+	if Enabled() || Check(filepath.Join(root, "anything")) != nil {
+		t.Error("expected every write to be allowed once disabled")
+	}
+}
+
+// TestWriteFile tests that refused writes leave the filesystem untouched
+func TestWriteFile(t *testing.T) {
+	root := t.TempDir()
+	out := filepath.Join(root, "out")
+
+	if err := Enable(out); err != nil {
+		t.Fatalf("Enable() error = %v", err)
+	}
+	defer Disable()
+
+	outside := filepath.Join(root, "results")
+	if err := MkdirAll(outside, 0755); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("MkdirAll() outside error = %v, want ErrReadOnly", err)
+	}
+	if err := WriteFile(filepath.Join(root, "x.json"), []byte("{}"), 0644); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteFile() outside error = %v, want ErrReadOnly", err)
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected nothing to be written, found %v", entries)
+	}
+
+	if err := MkdirAll(filepath.Join(out, "run"), 0755); err != nil {
+		t.Fatalf("MkdirAll() inside error = %v", err)
+	}
+	if err := WriteFile(filepath.Join(out, "run", "summary.json"), []byte("{}"), 0644); err != nil {
+		t.Errorf("WriteFile() inside error = %v", err)
+	}
+}
@@ -0,0 +1,275 @@
+// Package registry looks up the publication status of npm packages in the
+// registry: whether a version is deprecated, and whether it (or the whole
+// package) was unpublished. Both are strong signals during supply-chain
+// incidents, when maintainers deprecate compromised releases and the
+// registry takes malicious ones down. It also looks up who published a
+// version, for matching against accounts known to be compromised.
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+// DefaultURL is the public npm registry.
+const DefaultURL = "https://registry.npmjs.org/"
+
+// maxResponseSize bounds a packument, which lists every version of a
+// package and reaches a few megabytes for the most prolific ones.
+const maxResponseSize = 64 << 20
+
+// workers bounds the concurrent requests of Packuments.
+const workers = 8
+
+// Client fetches package metadata from a registry.
+type Client struct {
+	// HTTPClient sends the requests; nil uses the shared client (see
+	// package transport)
+	HTTPClient *http.Client
+	// URL is the registry, such as an internal mirror; empty uses
+	// DefaultURL
+	URL string
+}
+
+// Packument is the abbreviated metadata of a package, listing its
+// published versions.
+type Packument struct {
+	// Name is the package name
+	Name string `json:"name"`
+	// DistTags maps tags such as "latest" to versions
+	DistTags map[string]string `json:"dist-tags"`
+	// Versions holds the versions currently published
+	Versions map[string]VersionInfo `json:"versions"`
+}
+
+// VersionInfo is the metadata of a published version.
+type VersionInfo struct {
+	// Deprecated is the deprecation message, which npm prints on install;
+	// empty if the version is not deprecated
+	Deprecated deprecation `json:"deprecated,omitempty"`
+}
+
+// deprecation decodes the deprecated field, which a few old packages set
+// to a boolean rather than a message.
+type deprecation string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *deprecation) UnmarshalJSON(data []byte) error {
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		*d = deprecation(message)
+		return nil
+	}
+	var flag bool
+	if err := json.Unmarshal(data, &flag); err != nil {
+		return err
+	}
+	*d = ""
+	if flag {
+		*d = "deprecated"
+	}
+	return nil
+}
+
+// Status is the publication status of a package version.
+type Status struct {
+	// Version is the version checked: the one asked for or, if none was,
+	// the one the latest tag points to
+	Version string
+	// Deprecated is the deprecation message of Version, if it is
+	// deprecated
+	Deprecated string
+	// Unpublished is set if Version, or the whole package, is no longer
+	// published
+	Unpublished bool
+}
+
+// Status returns the status of version, or of the latest version if
+// version is empty. A nil packument, for a package unknown to the
+// registry, is unpublished.
+func (p *Packument) Status(version string) Status {
+	if p == nil || len(p.Versions) == 0 {
+		return Status{Version: version, Unpublished: true}
+	}
+	if version == "" {
+		version = p.DistTags["latest"]
+	}
+	info, ok := p.Versions[version]
+	if !ok {
+		return Status{Version: version, Unpublished: true}
+	}
+	return Status{Version: version, Deprecated: string(info.Deprecated)}
+}
+
+// Resolve returns the published version in r that npm would install, or
+// "" if none is. Like npm-pick-manifest, it prefers the version tagged
+// latest when the range allows it, then the highest version, and avoids
+// deprecated versions while the range allows another.
+func (p *Packument) Resolve(r npmsemver.Range) string {
+	if p == nil {
+		return ""
+	}
+	var current, deprecated []string
+	for version, info := range p.Versions {
+		if info.Deprecated != "" {
+			deprecated = append(deprecated, version)
+		} else {
+			current = append(current, version)
+		}
+	}
+
+	if latest, ok := p.DistTags["latest"]; ok {
+		if info, published := p.Versions[latest]; published && info.Deprecated == "" && r.MaxSatisfying([]string{latest}) != "" {
+			return latest
+		}
+	}
+	if best := r.MaxSatisfying(current); best != "" {
+		return best
+	}
+	return r.MaxSatisfying(deprecated)
+}
+
+// Packument fetches the metadata of name. It returns nil, without an
+// error, if the registry does not know the package, such as after it was
+// unpublished entirely.
+func (c *Client) Packument(ctx context.Context, name string) (*Packument, error) {
+	var packument Packument
+	// The abbreviated form lists versions without their READMEs
+	found, err := c.get(ctx, name, c.endpoint(name), "application/vnd.npm.install-v1+json", &packument)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &packument, nil
+}
+
+// Account is an npm user account.
+type Account struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// Publication records who published a version and who maintained the
+// package at the time.
+type Publication struct {
+	// Publisher is the account that ran npm publish
+	Publisher Account `json:"_npmUser"`
+	// Maintainers are the accounts allowed to publish the package
+	Maintainers []Account `json:"maintainers"`
+}
+
+// Publication fetches the publisher and maintainers of name@version. It
+// returns nil, without an error, if the registry does not know the
+// version. Unlike a packument, the metadata of a single version stays
+// small, and never changes once published.
+func (c *Client) Publication(ctx context.Context, name, version string) (*Publication, error) {
+	var publication Publication
+	found, err := c.get(ctx, name+"@"+version, c.endpoint(name, version), "application/json", &publication)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &publication, nil
+}
+
+// Packuments fetches the metadata of each of names concurrently. Packages
+// unknown to the registry map to nil; packages whose fetch failed are
+// missing from the map, and their errors are returned together.
+func (c *Client) Packuments(ctx context.Context, names []string) (map[string]*Packument, error) {
+	packuments := make(map[string]*Packument, len(names))
+	failed := make(map[string]error)
+	var mu sync.Mutex
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(names); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				packument, err := c.Packument(ctx, name)
+				mu.Lock()
+				if err != nil {
+					failed[name] = err
+				} else {
+					packuments[name] = packument
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+
+	failedNames := make([]string, 0, len(failed))
+	for name := range failed {
+		failedNames = append(failedNames, name)
+	}
+	sort.Strings(failedNames)
+	errs := make([]error, len(failedNames))
+	for i, name := range failedNames {
+		errs[i] = failed[name]
+	}
+	return packuments, errors.Join(errs...)
+}
+
+// endpoint returns the registry URL of a package, or of one of its
+// versions. Scoped names escape their slash (@scope%2Fname), as npm does.
+func (c *Client) endpoint(name string, version ...string) string {
+	base := c.URL
+	if base == "" {
+		base = DefaultURL
+	}
+	endpoint := strings.TrimSuffix(base, "/") + "/" + url.PathEscape(name)
+	for _, v := range version {
+		endpoint += "/" + url.PathEscape(v)
+	}
+	return endpoint
+}
+
+// get fetches endpoint, accepting accept, into v. found is false if the
+// registry answers 404. Errors name what, the package being fetched.
+func (c *Client) get(ctx context.Context, what, endpoint, accept string, v any) (found bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Accept", accept)
+
+	client := c.HTTPClient
+	if client == nil {
+		client = transport.Client()
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("fetch %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("fetch %s: HTTP %d", what, resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return false, fmt.Errorf("fetch %s: %w", what, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("parse metadata of %s: %w", what, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+)
+
+// newTestRegistry serves request, deprecated as a whole, @scope/pkg with an
+// unpublished version, and fails for broken; other packages are unknown
+func newTestRegistry(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/vnd.npm.install-v1+json" {
+			t.Errorf("Accept = %q, want the abbreviated metadata", accept)
+		}
+		switch r.URL.EscapedPath() {
+		case "/request":
+			fmt.Fprint(w, `{"name": "request", "dist-tags": {"latest": "2.88.2"}, "versions": {
+				"2.88.0": {"deprecated": "request has been deprecated"},
+				"2.88.2": {"deprecated": "request has been deprecated"},
+				"1.0.0": {"deprecated": false}}}`)
+		case "/@scope%2Fpkg":
+			fmt.Fprint(w, `{"name": "@scope/pkg", "dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {}}}`)
+		case "/broken":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPackumentStatus(t *testing.T) {
+	client := &Client{URL: newTestRegistry(t).URL}
+	ctx := context.Background()
+
+	request, err := client.Packument(ctx, "request")
+	if err != nil {
+		t.Fatalf("Packument(request) error = %v", err)
+	}
+	scoped, err := client.Packument(ctx, "@scope/pkg")
+	if err != nil {
+		t.Fatalf("Packument(@scope/pkg) error = %v", err)
+	}
+	gone, err := client.Packument(ctx, "gone")
+	if err != nil || gone != nil {
+		t.Fatalf("Packument(gone) = %v, %v, want nil for an unknown package", gone, err)
+	}
+
+	tests := []struct {
+		name      string
+		packument *Packument
+		version   string
+		want      Status
+	}{
+		{"deprecated version", request, "2.88.0", Status{Version: "2.88.0", Deprecated: "request has been deprecated"}},
+		{"latest", request, "", Status{Version: "2.88.2", Deprecated: "request has been deprecated"}},
+		{"boolean deprecation", request, "1.0.0", Status{Version: "1.0.0"}},
+		{"published", scoped, "1.0.0", Status{Version: "1.0.0"}},
+		{"unpublished version", scoped, "1.0.1", Status{Version: "1.0.1", Unpublished: true}},
+		{"unpublished package", gone, "1.0.0", Status{Version: "1.0.0", Unpublished: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.packument.Status(tt.version); got != tt.want {
+				t.Errorf("Status(%q) = %+v, want %+v", tt.version, got, tt.want)
+			}
+		})
+	}
+
+	r, err := npmsemver.ParseRange("^2.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := request.Resolve(r); got != "2.88.2" {
+		t.Errorf("Resolve(^2.0.0) = %q, want 2.88.2", got)
+	}
+}
+
+// TestPackumentResolve tests picking the version npm installs for a range
+func TestPackumentResolve(t *testing.T) {
+	versions := func(deprecated ...string) map[string]VersionInfo {
+		infos := make(map[string]VersionInfo)
+		for _, version := range []string{"1.2.2", "1.2.3", "1.3.0", "1.4.0", "2.0.0"} {
+			infos[version] = VersionInfo{}
+		}
+		for _, version := range deprecated {
+			infos[version] = VersionInfo{Deprecated: "do not use"}
+		}
+		return infos
+	}
+
+	tests := []struct {
+		name      string
+		packument *Packument
+		spec      string
+		want      string
+	}{
+		{"latest in range", &Packument{DistTags: map[string]string{"latest": "1.2.3"}, Versions: versions()}, "^1.0.0", "1.2.3"},
+		{"latest out of range", &Packument{DistTags: map[string]string{"latest": "1.2.3"}, Versions: versions()}, "^2.0.0", "2.0.0"},
+		{"no latest", &Packument{Versions: versions()}, "^1.0.0", "1.4.0"},
+		{"latest unpublished", &Packument{DistTags: map[string]string{"latest": "1.5.0"}, Versions: versions()}, "^1.0.0", "1.4.0"},
+		{"deprecated highest", &Packument{Versions: versions("1.4.0")}, "^1.0.0", "1.3.0"},
+		{"deprecated latest", &Packument{DistTags: map[string]string{"latest": "1.2.3"}, Versions: versions("1.2.3")}, "~1.2.0", "1.2.2"},
+		{"only deprecated", &Packument{Versions: versions("1.2.2", "1.2.3")}, "~1.2.0", "1.2.3"},
+		{"none", &Packument{Versions: versions()}, "^3.0.0", ""},
+		{"nil", nil, "^1.0.0", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := npmsemver.ParseRange(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tt.packument.Resolve(r); got != tt.want {
+				t.Errorf("Resolve(%s) = %q, want %q", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackuments(t *testing.T) {
+	client := &Client{URL: newTestRegistry(t).URL}
+
+	packuments, err := client.Packuments(context.Background(), []string{"request", "gone", "broken"})
+	if err == nil || !strings.Contains(err.Error(), "broken: HTTP 503") {
+		t.Errorf("Packuments() error = %v, want the failed fetch of broken", err)
+	}
+	if packuments["request"] == nil {
+		t.Error("Expected the metadata of request")
+	}
+	if p, ok := packuments["gone"]; !ok || p != nil {
+		t.Errorf("packuments[gone] = %v, %v, want nil for an unknown package", p, ok)
+	}
+	if _, ok := packuments["broken"]; ok {
+		t.Error("Expected no entry for a failed fetch")
+	}
+}
+
+func TestPublication(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/@scope%2Fpkg/1.0.0":
+			fmt.Fprint(w, `{"name": "@scope/pkg", "version": "1.0.0",
+				"_npmUser": {"name": "alice", "email": "alice@example.com"},
+				"maintainers": [{"name": "alice", "email": "alice@example.com"}, {"name": "bob"}]}`)
+		case "/broken/1.0.0":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	client := &Client{URL: server.URL}
+	ctx := context.Background()
+
+	publication, err := client.Publication(ctx, "@scope/pkg", "1.0.0")
+	if err != nil {
+		t.Fatalf("Publication() error = %v", err)
+	}
+	if publication.Publisher.Name != "alice" || len(publication.Maintainers) != 2 || publication.Maintainers[1].Name != "bob" {
+		t.Errorf("Publication() = %+v, want published by alice and maintained by alice and bob", publication)
+	}
+
+	if gone, err := client.Publication(ctx, "@scope/pkg", "9.9.9"); err != nil || gone != nil {
+		t.Errorf("Publication(unknown version) = %v, %v, want nil", gone, err)
+	}
+	if _, err := client.Publication(ctx, "broken", "1.0.0"); err == nil || !strings.Contains(err.Error(), "HTTP 503") {
+		t.Errorf("Publication(broken) error = %v, want the failed fetch", err)
+	}
+}
@@ -0,0 +1,134 @@
+package remediation
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// FalsePositive is an IoC entry that triage marked as a false positive, in
+// a form that can be shared with the maintainers of the IoC feed. Locations
+// are deliberately left out so the export does not reveal internal paths.
+type FalsePositive struct {
+	Package     string   `json:"package"`
+	Version     string   `json:"version"`
+	Severities  []string `json:"severities"`
+	Occurrences int      `json:"occurrences"`
+	Notes       []string `json:"notes,omitempty"`
+	Tickets     []string `json:"tickets,omitempty"`
+}
+
+// Feedback is the false-positive export document.
+type Feedback struct {
+	GeneratedAt    time.Time       `json:"generatedAt"`
+	FalsePositives []FalsePositive `json:"falsePositives"`
+}
+
+// CollectFalsePositives gathers the matches marked false-positive in the
+// given scan results, grouped by package and version. Remediation state in
+// the store takes precedence over the state recorded in the results.
+func CollectFalsePositives(store *Store, results []*formatter.ScanResult) []FalsePositive {
+	byEntry := make(map[string]*FalsePositive)
+	seen := make(map[string]bool)
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		if store != nil {
+			store.Annotate(result)
+		}
+
+		for _, match := range result.Matches {
+			r := match.Remediation
+			if r == nil || r.Status != StatusFalsePositive {
+				continue
+			}
+			// The same finding may appear in several reports of one fleet
+			if match.Fingerprint != "" {
+				if seen[match.Fingerprint] {
+					continue
+				}
+				seen[match.Fingerprint] = true
+			}
+
+			key := match.PackageName + "@" + match.Version
+			fp := byEntry[key]
+			if fp == nil {
+				fp = &FalsePositive{Package: match.PackageName, Version: match.Version}
+				byEntry[key] = fp
+			}
+			fp.Occurrences++
+			fp.Severities = appendUnique(fp.Severities, string(match.Severity))
+			fp.Notes = appendUnique(fp.Notes, r.Note)
+			fp.Tickets = appendUnique(fp.Tickets, r.Ticket)
+		}
+	}
+
+	falsePositives := make([]FalsePositive, 0, len(byEntry))
+	for _, fp := range byEntry {
+		sort.Strings(fp.Severities)
+		sort.Strings(fp.Notes)
+		sort.Strings(fp.Tickets)
+		falsePositives = append(falsePositives, *fp)
+	}
+	sort.Slice(falsePositives, func(i, j int) bool {
+		if falsePositives[i].Package != falsePositives[j].Package {
+			return falsePositives[i].Package < falsePositives[j].Package
+		}
+		return falsePositives[i].Version < falsePositives[j].Version
+	})
+	return falsePositives
+}
+
+// FormatFeedbackJSON formats false positives as a JSON document with 2-space
+// indentation.
+func FormatFeedbackJSON(falsePositives []FalsePositive, now time.Time) (string, error) {
+	data, err := json.MarshalIndent(Feedback{GeneratedAt: now, FalsePositives: falsePositives}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FormatFeedbackCSV formats false positives as CSV, with the Package and
+// Version columns of the IoC feed followed by the triage details.
+func FormatFeedbackCSV(falsePositives []FalsePositive) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Package", "Version", "Severities", "Occurrences", "Notes", "Tickets"})
+	for _, fp := range falsePositives {
+		w.Write([]string{
+			fp.Package,
+			"= " + fp.Version,
+			strings.Join(fp.Severities, ";"),
+			strconv.Itoa(fp.Occurrences),
+			strings.Join(fp.Notes, "; "),
+			strings.Join(fp.Tickets, ";"),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// appendUnique appends s to values unless it is empty or already present.
+func appendUnique(values []string, s string) []string {
+	if s == "" {
+		return values
+	}
+	for _, v := range values {
+		if v == s {
+			return values
+		}
+	}
+	return append(values, s)
+}
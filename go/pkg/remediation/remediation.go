@@ -0,0 +1,123 @@
+// Package remediation records the triage state of findings across scans, so
+// repeated reports show remediation progress instead of the same findings
+// over and over.
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// DefaultStorePath is where remediation state is kept unless configured
+// otherwise.
+const DefaultStorePath = "npm-scan-remediation.json"
+
+// Remediation statuses.
+const (
+	StatusOpen          = "open"
+	StatusInProgress    = "in-progress"
+	StatusFixed         = "fixed"
+	StatusAcceptedRisk  = "accepted-risk"
+	StatusFalsePositive = "false-positive"
+)
+
+// statuses lists the valid statuses in workflow order.
+var statuses = []string{StatusOpen, StatusInProgress, StatusFixed, StatusAcceptedRisk, StatusFalsePositive}
+
+// ValidateStatus returns an error if status is not a known remediation status.
+func ValidateStatus(status string) error {
+	for _, s := range statuses {
+		if status == s {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid status %q (expected %s)", status, strings.Join(statuses, ", "))
+}
+
+// Store maps match fingerprints to their remediation state.
+type Store struct {
+	Entries map[string]*formatter.Remediation `json:"entries"`
+}
+
+// Load reads a store from path. A missing file yields an empty store.
+func Load(path string) (*Store, error) {
+	store := &Store{Entries: make(map[string]*formatter.Remediation)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read remediation store: %w", err)
+	}
+
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("parse remediation store %s: %w", path, err)
+	}
+	if store.Entries == nil {
+		store.Entries = make(map[string]*formatter.Remediation)
+	}
+	return store, nil
+}
+
+// Save writes the store to path.
+func (s *Store) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return readonly.WriteFile(path, data, 0644)
+}
+
+// Set records the remediation state of a fingerprint. An empty ticket or
+// note keeps the previously recorded value.
+func (s *Store) Set(fingerprint, status, ticket, note string, now time.Time) (*formatter.Remediation, error) {
+	if err := ValidateStatus(status); err != nil {
+		return nil, err
+	}
+
+	entry := s.Entries[fingerprint]
+	if entry == nil {
+		entry = &formatter.Remediation{}
+		s.Entries[fingerprint] = entry
+	}
+	entry.Status = status
+	if ticket != "" {
+		entry.Ticket = ticket
+	}
+	if note != "" {
+		entry.Note = note
+	}
+	entry.UpdatedAt = now
+	return entry, nil
+}
+
+// Annotate attaches the recorded remediation state to every matching finding
+// in result, including the per-project copies. It returns how many of the
+// result's matches and hygiene findings were annotated.
+func (s *Store) Annotate(result *formatter.ScanResult) int {
+	annotated := s.annotate(result.Matches) + s.annotate(result.Hygiene)
+	for i := range result.Projects {
+		s.annotate(result.Projects[i].Matches)
+	}
+	return annotated
+}
+
+// annotate attaches remediation state to matches and returns the count.
+func (s *Store) annotate(matches []formatter.Match) int {
+	annotated := 0
+	for i := range matches {
+		if entry, ok := s.Entries[matches[i].Fingerprint]; ok {
+			remediation := *entry
+			matches[i].Remediation = &remediation
+			annotated++
+		}
+	}
+	return annotated
+}
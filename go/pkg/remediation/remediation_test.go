@@ -0,0 +1,124 @@
+package remediation
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// TestStore tests recording, persisting and applying remediation state.
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "remediation.json")
+	now := time.Date(2025, 11, 28, 0, 0, 0, 0, time.UTC)
+
+	store, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load of missing store failed: %v", err)
+	}
+	if len(store.Entries) != 0 {
+		t.Fatalf("expected empty store, got %v", store.Entries)
+	}
+
+	if _, err := store.Set("abc", "done", "", "", now); err == nil {
+		t.Error("expected error for invalid status")
+	}
+	if _, err := store.Set("abc", StatusInProgress, "JIRA-123", "", now); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	// A later update without a ticket keeps the ticket
+	if _, err := store.Set("abc", StatusFixed, "", "bumped chalk", now); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := store.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	entry := loaded.Entries["abc"]
+	if entry == nil || entry.Status != StatusFixed || entry.Ticket != "JIRA-123" || entry.Note != "bumped chalk" {
+		t.Fatalf("unexpected entry: %+v", entry)
+	}
+
+	result := &formatter.ScanResult{
+		Matches: []formatter.Match{
+			{PackageName: "chalk", Fingerprint: "abc"},
+			{PackageName: "debug", Fingerprint: "def"},
+		},
+		Projects: []formatter.ProjectResult{
+			{Matches: []formatter.Match{{PackageName: "chalk", Fingerprint: "abc"}}},
+		},
+	}
+	if got := loaded.Annotate(result); got != 1 {
+		t.Errorf("Annotate() = %d, want 1", got)
+	}
+	if r := result.Matches[0].Remediation; r == nil || r.Status != StatusFixed {
+		t.Errorf("match not annotated: %+v", result.Matches[0])
+	}
+	if result.Matches[1].Remediation != nil {
+		t.Errorf("unexpected annotation: %+v", result.Matches[1])
+	}
+	if result.Projects[0].Matches[0].Remediation == nil {
+		t.Error("project match not annotated")
+	}
+}
+
+// TestCollectFalsePositives tests grouping and export of false positives.
+func TestCollectFalsePositives(t *testing.T) {
+	store := &Store{Entries: map[string]*formatter.Remediation{
+		"a1": {Status: StatusFalsePositive, Note: "internal fork", Ticket: "SEC-1"},
+		"a2": {Status: StatusFalsePositive, Note: "internal fork"},
+		"b1": {Status: StatusInProgress},
+	}}
+	results := []*formatter.ScanResult{
+		{Matches: []formatter.Match{
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityDirect, Location: "/srv/a/package.json", Fingerprint: "a1"},
+			{PackageName: "debug", Version: "4.4.2", Severity: formatter.SeverityTransitive, Fingerprint: "b1"},
+		}},
+		{Matches: []formatter.Match{
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityTransitive, Fingerprint: "a2"},
+			// Same finding reported again, e.g. by a second run
+			{PackageName: "chalk", Version: "5.6.1", Severity: formatter.SeverityDirect, Fingerprint: "a1"},
+			// Triaged in an earlier report but not in the store
+			{PackageName: "ms", Version: "2.1.3", Severity: formatter.SeverityPotential, Fingerprint: "c1",
+				Remediation: &formatter.Remediation{Status: StatusFalsePositive}},
+		}},
+	}
+
+	got := CollectFalsePositives(store, results)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 false positives, got %+v", got)
+	}
+
+	chalk := got[0]
+	if chalk.Package != "chalk" || chalk.Occurrences != 2 ||
+		strings.Join(chalk.Severities, ",") != "DIRECT,TRANSITIVE" ||
+		strings.Join(chalk.Notes, ",") != "internal fork" ||
+		strings.Join(chalk.Tickets, ",") != "SEC-1" {
+		t.Errorf("unexpected chalk entry: %+v", chalk)
+	}
+	if got[1].Package != "ms" {
+		t.Errorf("expected ms entry, got %+v", got[1])
+	}
+
+	csvOut, err := FormatFeedbackCSV(got)
+	if err != nil {
+		t.Fatalf("FormatFeedbackCSV failed: %v", err)
+	}
+	if !strings.Contains(csvOut, "chalk,= 5.6.1,DIRECT;TRANSITIVE,2,internal fork,SEC-1") {
+		t.Errorf("unexpected CSV output:\n%s", csvOut)
+	}
+
+	jsonOut, err := FormatFeedbackJSON(got, time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("FormatFeedbackJSON failed: %v", err)
+	}
+	if strings.Contains(jsonOut, "/srv/a") {
+		t.Error("export must not contain locations")
+	}
+}
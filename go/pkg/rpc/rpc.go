@@ -0,0 +1,296 @@
+// Package rpc serves the scan engine over JSON-RPC 2.0 on a byte stream,
+// typically stdio, so tools written in other languages (such as the Node.js
+// implementation) can delegate detection to it.
+//
+// Messages are newline-delimited: each request is one JSON object on its own
+// line, and each response is written as one line. Requests are handled in
+// order. Requests without an id are notifications and get no response.
+package rpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// Methods served.
+const (
+	// MethodVersion returns VersionResult
+	MethodVersion = "version"
+	// MethodScan scans a directory (ScanParams) and returns a scan result
+	MethodScan = "scan"
+	// MethodScanInventory scans in-memory dependency declarations
+	// (InventoryParams) and returns a scan result
+	MethodScanInventory = "scanInventory"
+)
+
+// JSON-RPC 2.0 error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	// CodeScanFailed reports a scan that could not complete, such as a
+	// missing path or an unreachable IoC database
+	CodeScanFailed = -32000
+)
+
+// maxMessageSize bounds a single request line; inventories of large
+// monorepos can be several megabytes.
+const maxMessageSize = 64 << 20
+
+// Request is a JSON-RPC 2.0 request.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC 2.0 response. Exactly one of Result and Error is set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC 2.0 error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// DatabaseParams selects the IoC database, as the CLI flags of the same
+// names do.
+type DatabaseParams struct {
+	CSVURL  string `json:"csvUrl,omitempty"`
+	Offline bool   `json:"offline,omitempty"`
+	// Since is a YYYY-MM-DD date restricting matching to newer IoC entries
+	Since string `json:"since,omitempty"`
+}
+
+// ScanParams are the parameters of MethodScan.
+type ScanParams struct {
+	DatabaseParams
+	Path         string `json:"path"`
+	Source       string `json:"source,omitempty"`
+	LockfileOnly bool   `json:"lockfileOnly,omitempty"`
+	PerProject   bool   `json:"perProject,omitempty"`
+	Hygiene      bool   `json:"hygiene,omitempty"`
+}
+
+// InventoryParams are the parameters of MethodScanInventory. Manifests and
+// lockfiles are given as their parsed JSON content.
+type InventoryParams struct {
+	DatabaseParams
+	Manifests []struct {
+		Path     string          `json:"path"`
+		Manifest parser.Manifest `json:"manifest"`
+	} `json:"manifests,omitempty"`
+	Lockfiles []struct {
+		Path     string          `json:"path"`
+		Lockfile parser.Lockfile `json:"lockfile"`
+	} `json:"lockfiles,omitempty"`
+	Packages []parser.ResolvedPackage `json:"packages,omitempty"`
+}
+
+// VersionResult is the result of MethodVersion.
+type VersionResult struct {
+	Version string   `json:"version"`
+	Methods []string `json:"methods"`
+}
+
+// Server handles JSON-RPC requests against the scan engine.
+type Server struct {
+	version string
+
+	// databases caches IoC databases for inventory scans, which are
+	// typically issued many times per session
+	mu        sync.Mutex
+	databases map[DatabaseParams]*ioc.Database
+}
+
+// NewServer creates a server reporting version from MethodVersion.
+func NewServer(version string) *Server {
+	return &Server{version: version, databases: make(map[DatabaseParams]*ioc.Database)}
+}
+
+// Serve reads requests from in and writes responses to out until in is
+// exhausted or ctx is canceled. Malformed lines get a parse error response;
+// only I/O errors end the session early.
+func (s *Server) Serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	reader := bufio.NewScanner(in)
+	reader.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	encoder := json.NewEncoder(out)
+
+	for reader.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := reader.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		resp, ok := s.handle(ctx, line)
+		if !ok {
+			continue
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return fmt.Errorf("write response: %w", err)
+		}
+	}
+	return reader.Err()
+}
+
+// handle processes one request line. The second return value is false for
+// notifications, which get no response.
+func (s *Server) handle(ctx context.Context, line []byte) (Response, bool) {
+	var req Request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(json.RawMessage("null"), CodeParseError, "parse error: "+err.Error()), true
+	}
+	if len(req.ID) == 0 {
+		return Response{}, false
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, CodeInvalidRequest, "invalid request"), true
+	}
+
+	var result interface{}
+	var rpcErr *Error
+	switch req.Method {
+	case MethodVersion:
+		result = VersionResult{Version: s.version, Methods: []string{MethodVersion, MethodScan, MethodScanInventory}}
+	case MethodScan:
+		var params ScanParams
+		if rpcErr = decodeParams(req.Params, &params); rpcErr == nil {
+			result, rpcErr = s.scan(ctx, params)
+		}
+	case MethodScanInventory:
+		var params InventoryParams
+		if rpcErr = decodeParams(req.Params, &params); rpcErr == nil {
+			result, rpcErr = s.scanInventory(ctx, params)
+		}
+	default:
+		rpcErr = &Error{Code: CodeMethodNotFound, Message: "method not found: " + req.Method}
+	}
+
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr.Code, rpcErr.Message), true
+	}
+	return Response{JSONRPC: "2.0", ID: req.ID, Result: result}, true
+}
+
+// scan handles MethodScan.
+func (s *Server) scan(ctx context.Context, params ScanParams) (interface{}, *Error) {
+	if params.Path == "" {
+		return nil, &Error{Code: CodeInvalidParams, Message: "path is required"}
+	}
+	since, rpcErr := parseSince(params.Since)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	result, err := scanner.RunScan(scanner.ScanOptions{
+		Path:            params.Path,
+		CSVURL:          params.CSVURL,
+		Offline:         params.Offline,
+		Source:          params.Source,
+		LockfileOnly:    params.LockfileOnly,
+		PerProject:      params.PerProject,
+		Hygiene:         params.Hygiene,
+		Since:           since,
+		SkipGitMetadata: true,
+		Context:         ctx,
+	})
+	if err != nil {
+		return nil, &Error{Code: CodeScanFailed, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// scanInventory handles MethodScanInventory.
+func (s *Server) scanInventory(ctx context.Context, params InventoryParams) (interface{}, *Error) {
+	iocDB, rpcErr := s.database(params.DatabaseParams)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var inventory scanner.Inventory
+	for i := range params.Manifests {
+		m := &params.Manifests[i]
+		inventory.Manifests = append(inventory.Manifests, scanner.InventoryManifest{Path: m.Path, Manifest: &m.Manifest})
+	}
+	for i := range params.Lockfiles {
+		l := &params.Lockfiles[i]
+		inventory.Lockfiles = append(inventory.Lockfiles, scanner.InventoryLockfile{Path: l.Path, Lockfile: &l.Lockfile})
+	}
+	inventory.Packages = params.Packages
+
+	result, err := scanner.ScanInventory(ctx, inventory, iocDB)
+	if err != nil {
+		return nil, &Error{Code: CodeScanFailed, Message: err.Error()}
+	}
+	return result, nil
+}
+
+// database returns the IoC database selected by params, loading it on
+// first use.
+func (s *Server) database(params DatabaseParams) (*ioc.Database, *Error) {
+	since, rpcErr := parseSince(params.Since)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if iocDB, ok := s.databases[params]; ok {
+		return iocDB, nil
+	}
+	iocDB, err := scanner.LoadDatabase(scanner.ScanOptions{CSVURL: params.CSVURL, Offline: params.Offline, Since: since})
+	if err != nil {
+		return nil, &Error{Code: CodeScanFailed, Message: err.Error()}
+	}
+	s.databases[params] = iocDB
+	return iocDB, nil
+}
+
+// decodeParams unmarshals request params into v.
+func decodeParams(raw json.RawMessage, v interface{}) *Error {
+	if len(raw) == 0 {
+		return &Error{Code: CodeInvalidParams, Message: "params are required"}
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &Error{Code: CodeInvalidParams, Message: "invalid params: " + err.Error()}
+	}
+	return nil
+}
+
+// parseSince parses the since parameter.
+func parseSince(value string) (time.Time, *Error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	since, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, &Error{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid since %q: expected YYYY-MM-DD", value)}
+	}
+	return since, nil
+}
+
+// errorResponse builds an error response.
+func errorResponse(id json.RawMessage, code int, message string) Response {
+	return Response{JSONRPC: "2.0", ID: id, Error: &Error{Code: code, Message: message}}
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// TestServe tests a session covering every method and the error responses
+func TestServe(t *testing.T) {
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte("Package,Version\nevil,= 1.0.1\n"))
+	}))
+	defer server.Close()
+
+	inventory := `{"csvUrl": "` + server.URL + `", "manifests": [{"path": "gen/package.json", "manifest": {"dependencies": {"evil": "1.0.1"}}}]}`
+	requests := []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": "version"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "scanInventory", "params": ` + inventory + `}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "scanInventory", "params": ` + inventory + `}`,
+		`{"jsonrpc": "2.0", "method": "scan", "params": {"path": "."}}`,
+		`not json`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "scan", "params": {}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "scan", "params": {"path": ".", "since": "yesterday"}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "delete"}`,
+	}
+
+	var out bytes.Buffer
+	if err := NewServer("1.2.3").Serve(context.Background(), strings.NewReader(strings.Join(requests, "\n")), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	type response struct {
+		ID     json.RawMessage
+		Result json.RawMessage
+		Error  *Error
+	}
+	var responses []response
+	lines := bufio.NewScanner(&out)
+	for lines.Scan() {
+		var resp response
+		if err := json.Unmarshal(lines.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response line %q: %v", lines.Text(), err)
+		}
+		responses = append(responses, resp)
+	}
+
+	// The notification gets no response
+	if len(responses) != 7 {
+		t.Fatalf("expected 7 responses, got %d:\n%s", len(responses), out.String())
+	}
+
+	var version VersionResult
+	if err := json.Unmarshal(responses[0].Result, &version); err != nil || version.Version != "1.2.3" {
+		t.Errorf("unexpected version result %s (%v)", responses[0].Result, err)
+	}
+
+	for _, resp := range responses[1:3] {
+		var result formatter.ScanResult
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			t.Fatalf("unexpected scan result %s: %v", resp.Result, err)
+		}
+		if len(result.Matches) != 1 || result.Matches[0].Location != "gen/package.json" || result.Matches[0].Severity != formatter.SeverityDirect {
+			t.Errorf("unexpected matches: %+v", result.Matches)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("expected the database to be fetched once per session, got %d fetches", fetches)
+	}
+
+	wantErrors := []struct {
+		id   string
+		code int
+	}{
+		{"null", CodeParseError},
+		{"4", CodeInvalidParams},
+		{"5", CodeInvalidParams},
+		{"6", CodeMethodNotFound},
+	}
+	for i, want := range wantErrors {
+		resp := responses[3+i]
+		if string(resp.ID) != want.id || resp.Error == nil || resp.Error.Code != want.code {
+			t.Errorf("response %d: expected error %d for id %s, got id %s error %+v", 3+i, want.code, want.id, resp.ID, resp.Error)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package scanner
+
+import (
+	"os"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/gitinfo"
+)
+
+// Sources for lockfile modification times.
+const (
+	// AgeSourceGit means the time is the last commit touching the lockfile
+	AgeSourceGit = "git"
+	// AgeSourceMtime means the time is the filesystem modification time
+	AgeSourceMtime = "mtime"
+)
+
+// lockfileAge determines when a lockfile was last regenerated, preferring
+// git history over the filesystem mtime (which a fresh clone resets).
+// The lockfile is stale if it was last modified before campaignStart.
+func lockfileAge(path string, now, campaignStart time.Time) (formatter.LockfileAge, error) {
+	age := formatter.LockfileAge{Path: path}
+
+	if modified, err := gitinfo.LastCommitTime(path); err == nil {
+		age.LastModified = modified
+		age.Source = AgeSourceGit
+	} else {
+		info, err := os.Stat(path)
+		if err != nil {
+			return age, err
+		}
+		age.LastModified = info.ModTime()
+		age.Source = AgeSourceMtime
+	}
+
+	age.AgeDays = int(now.Sub(age.LastModified).Hours() / 24)
+	age.Stale = !campaignStart.IsZero() && age.LastModified.Before(campaignStart)
+
+	return age, nil
+}
@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/archive"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/oci"
+)
+
+// ScanArchive scans the npm files inside the release archive at
+// options.Path: an npm pack tarball, another .tgz, .tar.gz or .tar
+// tarball, or a .zip archive. The archive is read in memory; nothing is
+// extracted.
+//
+// Manifests get DIRECT and POTENTIAL matching and lockfiles TRANSITIVE
+// matching, as in a source tree; packages bundled in node_modules
+// directories are matched as in installed mode. Matches are located at
+// the archive path followed by the entry path, such as
+// app-1.0.0.tgz/package/package.json, and the archive path and sha256
+// digest are recorded in ScanResult.Metadata.
+//
+// Only the csv source is supported. CSVURL, Offline, Database, Since,
+// LockfileOnly, Watchlist, Policy, Allowlist, Typosquat, Verbose and
+// Context are used from options.
+func ScanArchive(options ScanOptions) (*formatter.ScanResult, error) {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	iocDB, err := detachedDatabase(options, "archives")
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Verbose {
+		options.logf("Reading archive %s...\n", options.Path)
+	}
+	a, err := archive.Read(options.Path, oci.IsNPMFile)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := fileDigest(options.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash archive: %w", err)
+	}
+
+	location := func(p string) string { return options.Path + "/" + p }
+	inventory, skipped := filesInventory(a.Paths(), a.Files, location, options.LockfileOnly)
+	if options.Verbose {
+		options.logf("Found %d manifests, %d lockfiles and %d other packages in %s\n",
+			len(inventory.Manifests), len(inventory.Lockfiles), len(inventory.Packages), options.Path)
+		for _, file := range skipped {
+			options.logf("Warning: skipped %s\n", file.Error)
+		}
+	}
+
+	result, err := scanDetached(options, inventory, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = skipped
+	result.Metadata = map[string]string{
+		formatter.MetaArchivePath:   options.Path,
+		formatter.MetaArchiveDigest: digest,
+	}
+	return result, nil
+}
+
+// fileDigest returns the sha256 digest of the file at path, in the
+// sha256:<hex> form of registry and image digests.
+func fileDigest(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,181 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+)
+
+// cacheVersion is mixed into every cache key; bump it when the cached
+// results of the same file change, so stale entries are never read.
+const cacheVersion = "1"
+
+// ResultCache stores the per-file results of scans, keyed by the file's
+// path and sha256, the IoC database digest and the options that shape the
+// results, so re-scanning unchanged files skips parsing and matching.
+//
+// Files whose results depend on more than their own contents are not
+// cached: manifests with bundledDependencies (matched against node_modules)
+// and, with Installed or ExposureWindow, lockfiles. How long TRANSITIVE
+// matches have been exposed is recomputed on every hit.
+//
+// Entries are JSON files under the cache directory, one per key. They are
+// never evicted; deleting the directory is always safe.
+type ResultCache struct {
+	dir string
+}
+
+// cacheEntry is the serialized form of a fileResult.
+type cacheEntry struct {
+	Name       string                     `json:"name,omitempty"`
+	Packages   int                        `json:"packages"`
+	Counts     formatter.DependencyCounts `json:"counts"`
+	Matches    []formatter.Match          `json:"matches,omitempty"`
+	Hygiene    []formatter.Match          `json:"hygiene,omitempty"`
+	Unchecked  []formatter.Match          `json:"unchecked,omitempty"`
+	Watched    []formatter.Match          `json:"watched,omitempty"`
+	Violations []formatter.Match          `json:"violations,omitempty"`
+	Unapproved []formatter.Match          `json:"unapproved,omitempty"`
+	Typosquats []formatter.Match          `json:"typosquats,omitempty"`
+	Scripts    []formatter.Match          `json:"scripts,omitempty"`
+}
+
+// DefaultCacheDir returns the default result cache directory, below the
+// user cache directory (such as ~/.cache on Linux).
+func DefaultCacheDir() (string, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locate cache directory: %w", err)
+	}
+	return filepath.Join(base, "npm-scan", "results"), nil
+}
+
+// OpenResultCache returns the cache stored in dir. The directory is created
+// when the first entry is written.
+func OpenResultCache(dir string) *ResultCache {
+	return &ResultCache{dir: dir}
+}
+
+// Dir returns the cache directory.
+func (c *ResultCache) Dir() string {
+	return c.dir
+}
+
+// scan returns the cached result of scanning the file at path, or runs scan
+// and caches its result. salt holds everything besides the file contents
+// that the result depends on. Results that failed or are marked uncacheable
+// are not stored; files that cannot be read are scanned without the cache.
+func (c *ResultCache) scan(path, salt string, now time.Time, scan func() fileResult) fileResult {
+	// Hashed in chunks, so huge lockfiles are not held in memory
+	digest, err := fileDigest(path)
+	if err != nil {
+		return scan()
+	}
+	h := sha256.New()
+	for _, part := range []string{cacheVersion, path, digest, salt} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+	entryPath := filepath.Join(c.dir, key[:2], key+".json")
+
+	if data, err := os.ReadFile(entryPath); err == nil {
+		var entry cacheEntry
+		if err := json.Unmarshal(data, &entry); err == nil {
+			return entry.result(now)
+		}
+	}
+
+	result := scan()
+	if result.err == nil && !result.uncacheable {
+		c.store(entryPath, result)
+	}
+	return result
+}
+
+// store writes an entry, ignoring failures, such as in read-only mode: the
+// cache only ever saves work. The entry is renamed into place so concurrent
+// scans never read a partial file.
+func (c *ResultCache) store(entryPath string, result fileResult) {
+	data, err := json.Marshal(cacheEntry{
+		Name:       result.name,
+		Packages:   result.packages,
+		Counts:     result.counts,
+		Matches:    result.matches,
+		Hygiene:    result.hygiene,
+		Unchecked:  result.unchecked,
+		Watched:    result.watched,
+		Violations: result.violations,
+		Unapproved: result.unapproved,
+		Typosquats: result.typosquats,
+		Scripts:    result.scripts,
+	})
+	if err != nil {
+		return
+	}
+	if err := readonly.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return
+	}
+	tmp := entryPath + "." + strconv.Itoa(os.Getpid()) + ".tmp"
+	if err := readonly.WriteFile(tmp, data, 0644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, entryPath); err != nil {
+		os.Remove(tmp)
+	}
+}
+
+// result turns a cached entry back into a fileResult, refreshing how long
+// TRANSITIVE matches have been exposed.
+func (e cacheEntry) result(now time.Time) fileResult {
+	for i := range e.Matches {
+		e.Matches[i].ExposedSince = nil
+		e.Matches[i].ExposureDays = 0
+	}
+	annotateExposure(e.Matches, now)
+	return fileResult{
+		name:       e.Name,
+		packages:   e.Packages,
+		counts:     e.Counts,
+		matches:    e.Matches,
+		hygiene:    e.Hygiene,
+		unchecked:  e.Unchecked,
+		watched:    e.Watched,
+		violations: e.Violations,
+		unapproved: e.Unapproved,
+		typosquats: e.Typosquats,
+		scripts:    e.Scripts,
+	}
+}
+
+// cacheSalt returns the part of the cache key shared by every file of a
+// scan: the IoC database digest and the options that shape per-file
+// results.
+func cacheSalt(iocDigest string, options ScanOptions) string {
+	var requirements []string
+	for _, requirement := range options.Policy.Requirements() {
+		requirements = append(requirements, requirement.String())
+	}
+	return strings.Join([]string{
+		iocDigest,
+		strconv.FormatBool(options.Hygiene),
+		strconv.FormatBool(options.UncheckedBundled),
+		strconv.FormatBool(options.Watchlist != nil),
+		strings.Join(options.Watchlist.Patterns(), "\n"),
+		strconv.FormatBool(options.Policy != nil),
+		strings.Join(requirements, "\n"),
+		strconv.FormatBool(options.Allowlist != nil),
+		strings.Join(options.Allowlist.Entries(), "\n"),
+		strconv.FormatBool(options.Typosquat != nil),
+		strings.Join(options.Typosquat.Names(), "\n"),
+	}, "\x00")
+}
@@ -0,0 +1,49 @@
+package scanner
+
+import (
+	"context"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+)
+
+// CheckPackages reports whether each of packages is listed in the IoC
+// database, without a project on disk. The database is loaded as for
+// RunScan from options.Source, options.CSVURL, options.Offline and
+// options.Since, or taken from options.Database; the OSV and GHSA sources
+// are queried for exactly the given packages. Options about scanned files
+// are ignored.
+//
+// Checks are returned in the order of packages.
+func CheckPackages(options ScanOptions, packages []ioc.PackageVersion) ([]formatter.PackageCheck, error) {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	sources, err := ioc.ParseSources(options.Source)
+	if err != nil {
+		return nil, err
+	}
+	iocDB := preloadedDatabase(options)
+	if iocDB == nil && containsSource(sources, ioc.SourceCSV) {
+		iocDB, err = LoadDatabase(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := checkOfflineSources(options, sources); err != nil {
+		return nil, err
+	}
+	iocDB, err = addSources(options, sources, iocDB, func() []ioc.PackageVersion { return packages })
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]formatter.PackageCheck, 0, len(packages))
+	for _, pkg := range packages {
+		checks = append(checks, matcher.CheckPackage(iocDB, pkg.Name, pkg.Version))
+	}
+	return checks, nil
+}
@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/oci"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/transport"
+)
+
+// RunContainerScan scans the npm files in the filesystem of a container
+// image: the image saved in the archive at options.Path (docker save or an
+// OCI layout tarball) or, if no such file exists, the image options.Path
+// references, pulled from its registry.
+//
+// Manifests get DIRECT and POTENTIAL matching and lockfiles TRANSITIVE
+// matching, as in a source tree; packages installed in node_modules
+// directories are matched as in installed mode. Matches are located at
+// their path in the image, and the image reference and manifest digest are
+// recorded in ScanResult.Metadata.
+//
+// Only the csv source is supported. CSVURL, Offline, Database, Since,
+// LockfileOnly, Watchlist, Policy, Allowlist, Typosquat, Platform, Verbose
+// and Context are used from options.
+func RunContainerScan(options ScanOptions) (*formatter.ScanResult, error) {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	iocDB, err := detachedDatabase(options, "container images")
+	if err != nil {
+		return nil, err
+	}
+
+	var img *oci.Image
+	if info, statErr := os.Stat(options.Path); statErr == nil && !info.IsDir() {
+		if options.Verbose {
+			options.logf("Reading image archive %s...\n", options.Path)
+		}
+		img, err = oci.ReadArchive(options.Path)
+	} else {
+		if options.Verbose {
+			options.logf("Pulling image %s...\n", options.Path)
+		}
+		// Layers may take longer than the request timeout to download
+		img, err = oci.Pull(options.Context, transport.Streaming(transport.Client()), options.Path, options.Platform)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	inventory, skipped := ImageInventory(img, options.LockfileOnly)
+	if options.Verbose {
+		options.logf("Found %d manifests, %d lockfiles and %d other packages in %s\n",
+			len(inventory.Manifests), len(inventory.Lockfiles), len(inventory.Packages), options.Path)
+		for _, file := range skipped {
+			options.logf("Warning: skipped %s\n", file.Error)
+		}
+	}
+
+	result, err := scanDetached(options, inventory, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = skipped
+	result.Metadata = map[string]string{formatter.MetaImageReference: options.Path}
+	if img.Digest != "" {
+		result.Metadata[formatter.MetaImageDigest] = img.Digest
+	}
+	return result, nil
+}
+
+// ImageInventory sorts the npm files of a container image into an
+// inventory: package.json files of installed packages (those directly below
+// a node_modules directory) become Packages, other package.json files
+// Manifests unless lockfileOnly is set, and lockfiles Lockfiles or, for
+// yarn.lock, Packages. Other package.json files inside node_modules, such
+// as test fixtures, are ignored.
+//
+// Files that cannot be parsed are skipped and returned, since an image
+// holds many files the scan has no stake in.
+func ImageInventory(img *oci.Image, lockfileOnly bool) (Inventory, []formatter.FileError) {
+	return filesInventory(img.Paths(), img.Files, func(p string) string { return p }, lockfileOnly)
+}
+
+// filesInventory sorts npm files read from outside the filesystem, keyed
+// by slash-separated path and visited in the order of paths, into an
+// inventory as described for ImageInventory. location maps each path to
+// the location its matches are recorded at.
+func filesInventory(paths []string, files map[string][]byte, location func(string) string, lockfileOnly bool) (Inventory, []formatter.FileError) {
+	var inventory Inventory
+	var skipped []formatter.FileError
+	for _, p := range paths {
+		content := files[p]
+		name := path.Base(p)
+		loc := location(p)
+
+		if name == "package.json" && strings.Contains("/"+p, "/node_modules/") {
+			if !isInstalledManifest("/" + p) {
+				continue
+			}
+			var manifest parser.Manifest
+			if err := json.Unmarshal(content, &manifest); err != nil {
+				skipped = append(skipped, formatter.FileError{Path: loc, Error: fmt.Sprintf("failed to parse %s: %v", loc, err)})
+				continue
+			}
+			if manifest.Name != "" && manifest.Version != "" {
+				inventory.Packages = append(inventory.Packages, parser.ResolvedPackage{
+					Name:         manifest.Name,
+					Version:      manifest.Version,
+					LockfilePath: loc,
+					Scripts:      manifest.Scripts,
+				})
+			}
+			continue
+		}
+
+		if lockfileOnly && isManifestName(name) {
+			continue
+		}
+		if err := inventory.AddFile(loc, content); err != nil {
+			skipped = append(skipped, formatter.FileError{Path: loc, Error: err.Error()})
+		}
+	}
+	return inventory, skipped
+}
+
+// isInstalledManifest reports whether the package.json at p is the manifest
+// of a package installed in a node_modules directory.
+func isInstalledManifest(p string) bool {
+	dir := path.Dir(p)
+	parent := path.Dir(dir)
+	if path.Base(parent) == "node_modules" {
+		return !strings.HasPrefix(path.Base(dir), ".")
+	}
+	return strings.HasPrefix(path.Base(parent), "@") && path.Base(path.Dir(parent)) == "node_modules"
+}
@@ -0,0 +1,44 @@
+package scanner
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// countDependency counts one dependency of depType (a parser.DepType
+// constant) in counts. Other types, including none, count as unknown.
+func countDependency(counts *formatter.DependencyCounts, depType string) {
+	switch depType {
+	case parser.DepTypeProd:
+		counts.Prod++
+	case parser.DepTypeDev:
+		counts.Dev++
+	case parser.DepTypePeer:
+		counts.Peer++
+	case parser.DepTypeOptional:
+		counts.Optional++
+	case parser.DepTypeBundled:
+		counts.Bundled++
+	default:
+		counts.Unknown++
+	}
+}
+
+// manifestCounts counts the declarations of a manifest by type.
+func manifestCounts(deps []parser.Dependency) formatter.DependencyCounts {
+	var counts formatter.DependencyCounts
+	for _, dep := range deps {
+		countDependency(&counts, parser.ManifestDependencyType(dep.Type))
+	}
+	return counts
+}
+
+// resolvedCounts counts resolved packages by the type their lockfile
+// records for them.
+func resolvedCounts(packages []parser.ResolvedPackage) formatter.DependencyCounts {
+	var counts formatter.DependencyCounts
+	for _, pkg := range packages {
+		countDependency(&counts, pkg.Type)
+	}
+	return counts
+}
@@ -0,0 +1,186 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// defaultSampleSize is the number of files parsed to measure throughput.
+const defaultSampleSize = 20
+
+// EstimateOptions configures EstimateScan.
+type EstimateOptions struct {
+	// Find holds the discovery options of the planned scan
+	Find FindOptions
+
+	// LockfileOnly and Installed describe the planned scan; see ScanOptions
+	LockfileOnly bool
+	Installed    bool
+
+	// Workers lists the worker counts to estimate durations for (default:
+	// 1, 2, 4, ... up to the number of CPUs)
+	Workers []int
+
+	// SampleSize is the number of files parsed to measure throughput
+	// (default 20)
+	SampleSize int
+}
+
+// EstimateScan walks roots like a scan would and reports the dependency
+// files found, their size and the node_modules trees next to them, with
+// estimated scan durations. No IoC database is fetched; a sample of the
+// files is parsed to measure throughput.
+//
+// Estimates assume files are parsed in parallel, and that several roots are
+// walked in parallel as in a bulk scan. They are rough: the walk here warms
+// filesystem caches, and the IoC database fetch is not included.
+func EstimateScan(roots []string, options EstimateOptions) (*formatter.DiscoveryStats, error) {
+	stats := &formatter.DiscoveryStats{Paths: len(roots)}
+	var discovery time.Duration
+	var manifests, lockfiles []string
+	sizes := make(map[string]int64)
+
+	for _, root := range roots {
+		if err := checkFile(root, options.LockfileOnly); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		files, err := findFiles(root, options.Find, func(name string) bool {
+			return isLockfileName(name) || (!options.LockfileOnly && isManifestName(name))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover files in %s: %w", root, err)
+		}
+		walk := time.Since(start)
+		// A scan walks once for manifests and once for lockfiles
+		if !options.LockfileOnly {
+			walk *= 2
+		}
+		discovery += walk
+
+		modules := make(map[string]bool)
+		for _, path := range files {
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			sizes[path] = info.Size()
+			if isManifestName(filepath.Base(path)) {
+				manifests = append(manifests, path)
+				stats.ManifestBytes += info.Size()
+			} else {
+				lockfiles = append(lockfiles, path)
+				stats.LockfileBytes += info.Size()
+			}
+
+			dir := filepath.Dir(path)
+			if modules[dir] {
+				continue
+			}
+			if info, err := os.Stat(filepath.Join(dir, "node_modules")); err == nil && info.IsDir() {
+				modules[dir] = true
+			}
+		}
+
+		stats.NodeModules += len(modules)
+		if options.Installed {
+			start := time.Now()
+			for dir := range modules {
+				stats.InstalledPackages += len(parser.FindInstalledPackages(dir, options.Find.Throttle))
+			}
+			discovery += time.Since(start)
+		}
+	}
+	stats.Manifests = len(manifests)
+	stats.Lockfiles = len(lockfiles)
+	stats.DiscoverySeconds = discovery.Seconds()
+
+	throughput, err := measureThroughput(append(manifests, lockfiles...), sizes, options.SampleSize)
+	if err != nil {
+		return nil, err
+	}
+	stats.ParseBytesPerSecond = throughput
+
+	parse := 0.0
+	if throughput > 0 {
+		parse = float64(stats.ManifestBytes+stats.LockfileBytes) / throughput
+	}
+	workers := options.Workers
+	if len(workers) == 0 {
+		workers = defaultWorkerCounts(runtime.NumCPU())
+	}
+	for _, w := range workers {
+		if w <= 0 {
+			return nil, fmt.Errorf("invalid worker count %d: must be positive", w)
+		}
+		walkers := w
+		if walkers > len(roots) {
+			walkers = len(roots)
+		}
+		if walkers == 0 {
+			walkers = 1
+		}
+		stats.Estimates = append(stats.Estimates, formatter.ScanEstimate{
+			Workers: w,
+			Seconds: stats.DiscoverySeconds/float64(walkers) + parse/float64(w),
+		})
+	}
+
+	return stats, nil
+}
+
+// measureThroughput parses and matches up to sampleSize of the files,
+// spread evenly over them, against an empty IoC database and returns the
+// bytes processed per second. Returns 0 if there are no files.
+func measureThroughput(files []string, sizes map[string]int64, sampleSize int) (float64, error) {
+	if sampleSize <= 0 {
+		sampleSize = defaultSampleSize
+	}
+	if len(files) == 0 {
+		return 0, nil
+	}
+	if sampleSize > len(files) {
+		sampleSize = len(files)
+	}
+
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\n"))
+	if err != nil {
+		return 0, err
+	}
+
+	options := ScanOptions{Context: context.Background()}
+	var bytes int64
+	start := time.Now()
+	for i := 0; i < sampleSize; i++ {
+		path := files[i*len(files)/sampleSize]
+		bytes += sizes[path]
+		if isManifestName(filepath.Base(path)) {
+			scanManifest(path, iocDB, options, nil, start)
+		} else {
+			scanLockfile(path, iocDB, options, start)
+		}
+	}
+	elapsed := time.Since(start)
+	if elapsed <= 0 || bytes == 0 {
+		return 0, nil
+	}
+	return float64(bytes) / elapsed.Seconds(), nil
+}
+
+// defaultWorkerCounts returns 1, 2, 4, ... up to cpus, ending with cpus.
+func defaultWorkerCounts(cpus int) []int {
+	var counts []int
+	for w := 1; w < cpus; w *= 2 {
+		counts = append(counts, w)
+	}
+	return append(counts, cpus)
+}
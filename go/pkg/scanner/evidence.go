@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// attachEvidence records the occurrence behind each match that has no
+// evidence yet. content is the file the matches were found in, used to
+// locate the line of each declaration or lockfile entry; it may be nil for
+// inventories that do not exist on disk.
+func attachEvidence(matches []formatter.Match, content []byte) {
+	var lines [][]byte
+	if content != nil {
+		lines = bytes.Split(content, []byte("\n"))
+	}
+
+	for i := range matches {
+		match := &matches[i]
+		if len(match.Evidence) > 0 {
+			continue
+		}
+
+		evidence := formatter.Evidence{
+			File:         match.Location,
+			DeclaredSpec: match.DeclaredSpec,
+			Chain:        match.Chain,
+			Feed:         feedRows(*match),
+		}
+		if match.Severity == formatter.SeverityTransitive {
+			evidence.LockfilePath = match.Location
+		}
+		if lines != nil {
+			evidence.Line = declarationLine(lines, *match)
+		}
+		match.Evidence = []formatter.Evidence{evidence}
+	}
+}
+
+// feedRows lists the IoC entries a match was reported by.
+func feedRows(match formatter.Match) []formatter.FeedRow {
+	var rows []formatter.FeedRow
+	for _, source := range match.Sources {
+		spec := source.Range
+		if spec == "" {
+			spec = "= " + match.Version
+		}
+		rows = append(rows, formatter.FeedRow{Source: source.Source, Row: source.Row, Spec: spec})
+	}
+	return rows
+}
+
+// versionLine matches the version field of a package-lock.json or yarn.lock
+// entry and captures the version.
+var versionLine = regexp.MustCompile(`^\s*"?version"?:?\s*"?([^",\s]+)`)
+
+// declarationLine returns the 1-based line of the match's declaration in a
+// package.json, or of its entry in a lockfile, or 0 if it cannot be found.
+// Lockfile entries are matched on their version when a package is resolved
+// at several versions.
+func declarationLine(lines [][]byte, match formatter.Match) int {
+	name := regexp.QuoteMeta(match.PackageName)
+
+	var entry *regexp.Regexp
+	switch filepath.Base(match.Location) {
+	case "package.json":
+		declared := match.PackageName
+		if match.Alias != "" {
+			declared = match.Alias
+		}
+		value := `"`
+		if match.Override != "" {
+			// The last key of the override, whose value may be an object
+			// overriding the package itself with "."
+			keys := strings.Split(match.Override, " > ")
+			declared, value = keys[len(keys)-1], `["{]`
+		}
+		entry = regexp.MustCompile(`^\s*"` + regexp.QuoteMeta(declared) + `"\s*:\s*` + value)
+		for i, line := range lines {
+			if entry.Match(line) {
+				return i + 1
+			}
+		}
+		return 0
+	case "yarn.lock":
+		entry = regexp.MustCompile(`^(?:[^\s,]+, *)*"?(?:[^\s"@]+@npm:)?` + name + `@`)
+	default:
+		entry = regexp.MustCompile(`^\s*"(?:[^"]*node_modules/)?` + name + `"\s*:\s*\{`)
+	}
+
+	first := 0
+	for i, line := range lines {
+		if !entry.Match(line) {
+			continue
+		}
+		if first == 0 {
+			first = i + 1
+		}
+		if entryVersion(lines[i+1:]) == match.Version {
+			return i + 1
+		}
+	}
+	return first
+}
+
+// entryVersion returns the version field of the lockfile entry starting
+// at lines, looking no further than the entry's next few lines.
+func entryVersion(lines [][]byte) string {
+	for i, line := range lines {
+		if i >= 8 || strings.TrimSpace(string(line)) == "" {
+			break
+		}
+		if m := versionLine.FindSubmatch(line); m != nil {
+			return string(m[1])
+		}
+	}
+	return ""
+}
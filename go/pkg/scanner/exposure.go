@@ -0,0 +1,149 @@
+package scanner
+
+import (
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/gitinfo"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// annotateMatches fills in IoC entry dates and, for TRANSITIVE matches, how
+// long the compromised version has likely been present.
+//
+// The exposure estimate is a lower bound: the lockfile's last commit produced
+// its current contents, so the version has been resolved at least since then.
+// Lockfiles without git history are left unannotated, since a filesystem
+// mtime says nothing about when the version was introduced.
+func annotateMatches(matches []formatter.Match, iocDB *ioc.Database, now time.Time) {
+	annotateIOCDates(matches, iocDB)
+	annotateExposure(matches, now)
+}
+
+// annotateExposure sets ExposedSince and ExposureDays of TRANSITIVE matches
+// from the last commit of their lockfile, as described for annotateMatches.
+func annotateExposure(matches []formatter.Match, now time.Time) {
+	lastCommits := make(map[string]*time.Time)
+
+	for i := range matches {
+		match := &matches[i]
+
+		if match.Severity != formatter.SeverityTransitive {
+			continue
+		}
+
+		since, seen := lastCommits[match.Location]
+		if !seen {
+			if modified, err := gitinfo.LastCommitTime(match.Location); err == nil {
+				since = &modified
+			}
+			lastCommits[match.Location] = since
+		}
+		if since == nil {
+			continue
+		}
+
+		match.ExposedSince = since
+		match.ExposureDays = int(now.Sub(*since).Hours() / 24)
+	}
+}
+
+// annotateIOCDates fills in when each match's entry was added to the IoC
+// database, for sources that carry dates.
+func annotateIOCDates(matches []formatter.Match, iocDB *ioc.Database) {
+	for i := range matches {
+		if added, ok := iocDB.AddedAt(matches[i].PackageName, matches[i].Version); ok {
+			matches[i].IOCAdded = &added
+		}
+	}
+}
+
+// traceExposure walks the git history of lockfilePath and sets the Timeline
+// of each TRANSITIVE match to the most recent run of commits in which the
+// compromised version was resolved. A run that ended before HEAD records the
+// commit that removed the version.
+//
+// When a timeline is found, ExposedSince and ExposureDays are narrowed to the
+// introducing commit (and the removal, if any) instead of the lockfile's last
+// change. Lockfiles without git history are left untouched.
+func traceExposure(matches []formatter.Match, lockfilePath string, now time.Time, log Logger) {
+	if len(matches) == 0 {
+		return
+	}
+
+	commits, err := gitinfo.FileHistory(lockfilePath)
+	if err != nil {
+		if log != nil && err != gitinfo.ErrNotTracked {
+			log.Printf("Warning: failed to read history of %s: %v\n", lockfilePath, err)
+		}
+		return
+	}
+
+	timelines := make([]*formatter.ExposureTimeline, len(matches))
+	for _, commit := range commits {
+		content, err := gitinfo.FileAt(lockfilePath, commit.Hash)
+		resolved := map[string]bool{}
+		if err == nil {
+			resolved = resolvedSet(content, lockfilePath)
+		}
+
+		for i, match := range matches {
+			present := resolved[match.PackageName+"@"+match.Version]
+			timeline := timelines[i]
+			switch {
+			case present && (timeline == nil || timeline.RemovedCommit != ""):
+				timelines[i] = &formatter.ExposureTimeline{
+					IntroducedCommit: commit.Hash,
+					IntroducedAt:     commit.Time,
+				}
+			case !present && timeline != nil && timeline.RemovedCommit == "":
+				removedAt := commit.Time
+				timeline.RemovedCommit = commit.Hash
+				timeline.RemovedAt = &removedAt
+			}
+		}
+	}
+
+	for i := range matches {
+		timeline := timelines[i]
+		if timeline == nil {
+			continue
+		}
+		matches[i].Timeline = timeline
+
+		end := now
+		if timeline.RemovedAt != nil {
+			end = *timeline.RemovedAt
+		}
+		introducedAt := timeline.IntroducedAt
+		matches[i].ExposedSince = &introducedAt
+		matches[i].ExposureDays = int(end.Sub(introducedAt).Hours() / 24)
+	}
+}
+
+// resolvedSet parses lockfile content and returns the set of resolved
+// name@version pairs. Unparseable content yields an empty set.
+func resolvedSet(content []byte, lockfilePath string) map[string]bool {
+	set := make(map[string]bool)
+
+	if isYarnLockfile(lockfilePath) {
+		yarnLock, err := parseYarnLockfileData(content, lockfilePath)
+		if err != nil {
+			return set
+		}
+		for _, pkg := range parser.ExtractYarnResolvedPackages(yarnLock) {
+			set[pkg.Name+"@"+pkg.Version] = true
+		}
+		return set
+	}
+
+	lockfile, err := parser.ParsePackageLockData(content)
+	if err != nil {
+		return set
+	}
+	for _, pkg := range parser.ExtractResolvedPackages(lockfile, lockfilePath) {
+		set[pkg.Name+"@"+pkg.Version] = true
+	}
+	return set
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package scanner
+
+import "io/fs"
+
+// fileID would identify a directory; platforms without inode numbers fall
+// back to comparing visited directories with os.SameFile.
+type fileID struct{}
+
+// identify reports that files cannot be identified on this platform.
+func identify(info fs.FileInfo) (fileID, bool) {
+	return fileID{}, false
+}
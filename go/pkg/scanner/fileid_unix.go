@@ -0,0 +1,23 @@
+//go:build unix
+
+package scanner
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileID identifies a directory by device and inode number.
+type fileID struct {
+	dev uint64
+	ino uint64
+}
+
+// identify returns the identity of the file described by info.
+func identify(info fs.FileInfo) (fileID, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileID{}, false
+	}
+	return fileID{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -3,74 +3,306 @@ package scanner
 import (
 	"fmt"
 	"io/fs"
+	"os"
 	"path/filepath"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ignore"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/throttle"
 )
 
+// FindOptions configures file discovery.
+type FindOptions struct {
+	// Exclude holds gitignore-style patterns of paths to skip, in addition
+	// to the root's .npmscanignore file (see package ignore)
+	Exclude []string
+
+	// FollowSymlinks descends into symlinked directories, such as shared
+	// packages linked into a workspace. Each directory is visited once, so
+	// symlink cycles are not followed.
+	FollowSymlinks bool
+
+	// Throttle limits the pace of the walk, or is nil for full speed
+	Throttle *throttle.Throttle
+}
+
 // FindManifests finds all package.json files in the given root directory,
 // skipping node_modules and other non-relevant directories.
 //
-// It uses filepath.WalkDir for efficient directory traversal.
+// Paths matching the root's .npmscanignore file or the exclude patterns
+// (gitignore-style, see package ignore) are skipped.
+//
 // Returns a slice of absolute paths to found package.json files.
-func FindManifests(root string) ([]string, error) {
-	var manifests []string
+func FindManifests(root string, exclude ...string) ([]string, error) {
+	return FindManifestsWith(root, FindOptions{Exclude: exclude})
+}
 
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
+// FindManifestsWith is FindManifests with additional discovery options.
+func FindManifestsWith(root string, options FindOptions) ([]string, error) {
+	manifests, err := findFiles(root, options, isManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("find manifests: %w", err)
+	}
+	return manifests, nil
+}
 
-		// Skip node_modules directories
-		if d.IsDir() && d.Name() == "node_modules" {
-			return filepath.SkipDir
+// FindLockfiles finds all lockfile files (package-lock.json, npm-shrinkwrap.json,
+// yarn.lock and the Yarn PnP data files .pnp.cjs and .pnp.data.json) in the
+// given root directory, skipping node_modules and other non-relevant
+// directories.
+//
+// PnP data files describe the packages their directory's yarn.lock
+// resolves, so they are only returned for directories without one, and a
+// .pnp.cjs only without a .pnp.data.json beside it.
+//
+// Paths matching the root's .npmscanignore file or the exclude patterns
+// (gitignore-style, see package ignore) are skipped.
+//
+// Returns a slice of absolute paths to found lockfiles.
+func FindLockfiles(root string, exclude ...string) ([]string, error) {
+	return FindLockfilesWith(root, FindOptions{Exclude: exclude})
+}
+
+// FindLockfilesWith is FindLockfiles with additional discovery options.
+func FindLockfilesWith(root string, options FindOptions) ([]string, error) {
+	lockfiles, err := findFiles(root, options, isLockfileName)
+	if err != nil {
+		return nil, fmt.Errorf("find lockfiles: %w", err)
+	}
+	return dropRedundantPnP(lockfiles), nil
+}
+
+// dropRedundantPnP removes the PnP data files of directories that hold a
+// yarn.lock, and the .pnp.cjs of directories that hold a .pnp.data.json.
+func dropRedundantPnP(lockfiles []string) []string {
+	names := make(map[string]map[string]bool)
+	for _, path := range lockfiles {
+		dir := filepath.Dir(path)
+		if names[dir] == nil {
+			names[dir] = make(map[string]bool)
 		}
+		names[dir][filepath.Base(path)] = true
+	}
 
-		// Check if this is a package.json file
-		if !d.IsDir() && d.Name() == "package.json" {
-			manifests = append(manifests, path)
+	kept := lockfiles[:0:0]
+	for _, path := range lockfiles {
+		siblings := names[filepath.Dir(path)]
+		switch filepath.Base(path) {
+		case pnpDataName:
+			if siblings["yarn.lock"] {
+				continue
+			}
+		case pnpLoaderName:
+			if siblings["yarn.lock"] || siblings[pnpDataName] {
+				continue
+			}
 		}
+		kept = append(kept, path)
+	}
+	return kept
+}
 
+// isManifestName reports whether name is the file name of a manifest.
+func isManifestName(name string) bool {
+	return name == "package.json"
+}
+
+// Yarn PnP data files: the loader, which inlines the runtime state unless
+// pnpEnableInlining is off, and the state on its own.
+const (
+	pnpLoaderName = ".pnp.cjs"
+	pnpDataName   = ".pnp.data.json"
+)
+
+// isLockfileName reports whether name is the file name of a supported
+// lockfile.
+func isLockfileName(name string) bool {
+	return name == "package-lock.json" || name == "npm-shrinkwrap.json" || name == "yarn.lock" || isPnPName(name)
+}
+
+// isPnPName reports whether name is the file name of a Yarn PnP data file.
+func isPnPName(name string) bool {
+	return name == pnpLoaderName || name == pnpDataName
+}
+
+// checkFile validates a scan root that is a single file rather than a
+// directory, which is scanned with the parser its name selects. Other
+// files are rejected instead of yielding an empty, clean scan. A directory
+// root is always valid.
+func checkFile(root string, lockfileOnly bool) error {
+	info, err := os.Stat(root)
+	if err != nil || info.IsDir() {
 		return nil
-	})
+	}
+
+	name := info.Name()
+	switch {
+	case isLockfileName(name):
+		return nil
+	case isManifestName(name) && lockfileOnly:
+		return fmt.Errorf("cannot scan %s in lockfile-only mode: not a lockfile", root)
+	case isManifestName(name):
+		return nil
+	}
+	return fmt.Errorf("unsupported file %s: expected a directory, package.json, package-lock.json, npm-shrinkwrap.json, yarn.lock, .pnp.cjs, .pnp.data.json or a .tgz, .tar.gz, .tar or .zip archive", root)
+}
+
+// lockfilesBeside returns the lockfiles in the directory of manifestPath,
+// for a manifest scanned on its own.
+func lockfilesBeside(manifestPath string) []string {
+	var lockfiles []string
+	for _, name := range []string{"package-lock.json", "npm-shrinkwrap.json", "yarn.lock", pnpLoaderName, pnpDataName} {
+		path := filepath.Join(filepath.Dir(manifestPath), name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			lockfiles = append(lockfiles, path)
+		}
+	}
+	return dropRedundantPnP(lockfiles)
+}
 
+// walker walks a directory tree in lexical order, collecting the files
+// whose name is accepted by keep.
+type walker struct {
+	root     string
+	excluder *ignore.Matcher
+	follow   bool
+	throttle *throttle.Throttle
+	keep     func(name string) bool
+	// visited holds the identity of every directory entered when
+	// following symlinks, to break cycles
+	visited map[fileID]bool
+	// unidentified holds visited directories without a fileID, compared
+	// with os.SameFile
+	unidentified []fs.FileInfo
+	// links queues symlinked directories, walked after the real tree so
+	// directories reachable both ways are reported at their real path
+	links []link
+	found []string
+}
+
+// link is a symlinked directory queued for walking.
+type link struct {
+	path string
+	info fs.FileInfo
+}
+
+// findFiles walks root, which may also be a single file, and returns the
+// files accepted by keep. node_modules directories and excluded paths are
+// skipped.
+func findFiles(root string, options FindOptions, keep func(name string) bool) ([]string, error) {
+	excluder, err := ignore.Load(root, options.Exclude)
 	if err != nil {
-		return nil, fmt.Errorf("find manifests: %w", err)
+		return nil, err
 	}
 
-	return manifests, nil
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		if keep(info.Name()) {
+			return []string{root}, nil
+		}
+		return nil, nil
+	}
+
+	w := &walker{root: root, excluder: excluder, follow: options.FollowSymlinks, throttle: options.Throttle, keep: keep}
+	if w.follow {
+		w.visited = make(map[fileID]bool)
+		w.enter(info)
+	}
+	if err := w.walk(root); err != nil {
+		return nil, err
+	}
+	for len(w.links) > 0 {
+		next := w.links[0]
+		w.links = w.links[1:]
+		if !w.enter(next.info) {
+			continue
+		}
+		if err := w.walk(next.path); err != nil {
+			return nil, err
+		}
+	}
+	return w.found, nil
 }
 
-// FindLockfiles finds all lockfile files (package-lock.json, yarn.lock) in the given
-// root directory, skipping node_modules and other non-relevant directories.
-//
-// It uses filepath.WalkDir for efficient directory traversal.
-// Returns a slice of absolute paths to found lockfiles.
-func FindLockfiles(root string) ([]string, error) {
-	var lockfiles []string
+// walk collects the accepted files below dir.
+func (w *walker) walk(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	w.throttle.Directory(len(entries))
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		isDir := entry.IsDir()
 
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
+		// Resolve symlinks when following them
+		var info fs.FileInfo
+		if w.follow && entry.Type()&fs.ModeSymlink != 0 {
+			info, err = os.Stat(path)
+			if err != nil {
+				// Dangling link
+				continue
+			}
+			isDir = info.IsDir()
 		}
 
 		// Skip node_modules directories
-		if d.IsDir() && d.Name() == "node_modules" {
-			return filepath.SkipDir
+		if isDir && entry.Name() == "node_modules" {
+			continue
 		}
 
-		// Check if this is a lockfile
-		if !d.IsDir() {
-			name := d.Name()
-			if name == "package-lock.json" || name == "yarn.lock" {
-				lockfiles = append(lockfiles, path)
-			}
+		// Skip excluded files and directories
+		if rel, err := filepath.Rel(w.root, path); err == nil && w.excluder.Match(filepath.ToSlash(rel), isDir) {
+			continue
 		}
 
-		return nil
-	})
+		if isDir {
+			// Visit each directory once, whichever links lead to it
+			if w.follow {
+				if info != nil {
+					w.links = append(w.links, link{path: path, info: info})
+					continue
+				}
+				if info, err = entry.Info(); err != nil {
+					return err
+				}
+				if !w.enter(info) {
+					continue
+				}
+			}
+			if err := w.walk(path); err != nil {
+				return err
+			}
+			continue
+		}
 
-	if err != nil {
-		return nil, fmt.Errorf("find lockfiles: %w", err)
+		if w.keep(entry.Name()) {
+			w.found = append(w.found, path)
+		}
 	}
 
-	return lockfiles, nil
+	return nil
+}
+
+// enter records the directory described by info as visited and reports
+// whether it was not visited before.
+func (w *walker) enter(info fs.FileInfo) bool {
+	id, ok := identify(info)
+	if !ok {
+		for _, seen := range w.unidentified {
+			if os.SameFile(seen, info) {
+				return false
+			}
+		}
+		w.unidentified = append(w.unidentified, info)
+		return true
+	}
+	if w.visited[id] {
+		return false
+	}
+	w.visited[id] = true
+	return true
 }
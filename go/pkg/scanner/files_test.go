@@ -3,6 +3,7 @@ package scanner
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"testing"
 )
@@ -134,6 +135,14 @@ func TestFindLockfiles(t *testing.T) {
 			expected: 1,
 			wantErr:  false,
 		},
+		{
+			name: "single npm-shrinkwrap.json",
+			structure: map[string]string{
+				"npm-shrinkwrap.json": "",
+			},
+			expected: 1,
+			wantErr:  false,
+		},
 		{
 			name: "multiple lockfiles mixed",
 			structure: map[string]string{
@@ -157,6 +166,19 @@ func TestFindLockfiles(t *testing.T) {
 			expected: 2,
 			wantErr:  false,
 		},
+		{
+			name: "yarn pnp data files",
+			structure: map[string]string{
+				"zero-install/.pnp.cjs":       "",
+				"split/.pnp.cjs":              "",
+				"split/.pnp.data.json":        "",
+				"with-lock/yarn.lock":         "",
+				"with-lock/.pnp.cjs":          "",
+				"with-lock/.pnp.data.json":    "",
+			},
+			expected: 3,
+			wantErr:  false,
+		},
 		{
 			name: "no lockfiles",
 			structure: map[string]string{
@@ -289,3 +311,135 @@ func isSubpath(root, candidate string) bool {
 	}
 	return !filepath.IsAbs(relPath) && relPath != ".."
 }
+
+// TestFindFiles_Exclude tests that .npmscanignore and exclude patterns are
+// honored by file discovery.
+func TestFindFiles_Exclude(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		".npmscanignore":                  "# vendored fixtures\nfixtures/\n",
+		"package.json":                    "{}",
+		"package-lock.json":               "{}",
+		"fixtures/evil/package.json":      "{}",
+		"fixtures/evil/package-lock.json": "{}",
+		"e2e/package.json":                "{}",
+		"e2e/yarn.lock":                   "",
+		"apps/web/package.json":           "{}",
+		"apps/web/test/package-lock.json": "{}",
+	})
+
+	tests := []struct {
+		name          string
+		exclude       []string
+		wantManifests []string
+		wantLockfiles []string
+	}{
+		{
+			name:          "ignore file only",
+			wantManifests: []string{"apps/web/package.json", "e2e/package.json", "package.json"},
+			wantLockfiles: []string{"apps/web/test/package-lock.json", "e2e/yarn.lock", "package-lock.json"},
+		},
+		{
+			name:          "with exclude patterns",
+			exclude:       []string{"/e2e/", "**/test/package-lock.json"},
+			wantManifests: []string{"apps/web/package.json", "package.json"},
+			wantLockfiles: []string{"package-lock.json"},
+		},
+	}
+
+	relative := func(paths []string) []string {
+		var rel []string
+		for _, path := range paths {
+			r, err := filepath.Rel(root, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		sort.Strings(rel)
+		return rel
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifests, err := FindManifests(root, tt.exclude...)
+			if err != nil {
+				t.Fatalf("FindManifests() error = %v", err)
+			}
+			if got := relative(manifests); !reflect.DeepEqual(got, tt.wantManifests) {
+				t.Errorf("FindManifests() = %v, want %v", got, tt.wantManifests)
+			}
+
+			lockfiles, err := FindLockfiles(root, tt.exclude...)
+			if err != nil {
+				t.Fatalf("FindLockfiles() error = %v", err)
+			}
+			if got := relative(lockfiles); !reflect.DeepEqual(got, tt.wantLockfiles) {
+				t.Errorf("FindLockfiles() = %v, want %v", got, tt.wantLockfiles)
+			}
+		})
+	}
+
+	if _, err := FindManifests(root, "src/[a-"); err == nil {
+		t.Error("expected an invalid exclude pattern to be rejected")
+	}
+}
+
+// TestFindFiles_FollowSymlinks tests that symlinked directories are only
+// walked on request, once each, without following cycles.
+func TestFindFiles_FollowSymlinks(t *testing.T) {
+	shared := writeTestFiles(t, map[string]string{
+		"lib/package.json":      "{}",
+		"lib/package-lock.json": "{}",
+	})
+	root := writeTestFiles(t, map[string]string{
+		"package.json":              "{}",
+		"packages/app/package.json": "{}",
+	})
+	links := map[string]string{
+		"packages/shared":   filepath.Join(shared, "lib"),           // shared package outside the root
+		"alias":             filepath.Join(root, "packages", "app"), // second route to a real directory
+		"packages/app/loop": root,                                   // cycle back to the root
+		"dangling":          filepath.Join(root, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	relative := func(paths []string) []string {
+		var rel []string
+		for _, path := range paths {
+			r, err := filepath.Rel(root, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rel = append(rel, filepath.ToSlash(r))
+		}
+		return rel
+	}
+
+	manifests, err := FindManifests(root)
+	if err != nil {
+		t.Fatalf("FindManifests() error = %v", err)
+	}
+	if got, want := relative(manifests), []string{"package.json", "packages/app/package.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindManifests() = %v, want %v", got, want)
+	}
+
+	manifests, err = FindManifestsWith(root, FindOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("FindManifestsWith() error = %v", err)
+	}
+	if got, want := relative(manifests), []string{"package.json", "packages/app/package.json", "packages/shared/package.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FindManifestsWith() = %v, want %v", got, want)
+	}
+
+	lockfiles, err := FindLockfilesWith(root, FindOptions{FollowSymlinks: true, Exclude: []string{"shared/"}})
+	if err != nil {
+		t.Fatalf("FindLockfilesWith() error = %v", err)
+	}
+	if len(lockfiles) != 0 {
+		t.Errorf("Expected the excluded link not to be followed, got %v", lockfiles)
+	}
+}
@@ -0,0 +1,65 @@
+package scanner
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Fingerprint hashes the dependency files a scan of root would read: every
+// package.json (unless lockfileOnly) and lockfile, keyed by their path
+// relative to root. Two scans of a root with the same fingerprint see the
+// same dependency data. find holds the scan's discovery options.
+//
+// The result has the form "sha256:<hex>".
+func Fingerprint(root string, lockfileOnly bool, find FindOptions) (string, error) {
+	var paths []string
+	if !lockfileOnly {
+		manifests, err := FindManifestsWith(root, find)
+		if err != nil {
+			return "", err
+		}
+		paths = append(paths, manifests...)
+	}
+	lockfiles, err := FindLockfilesWith(root, find)
+	if err != nil {
+		return "", err
+	}
+	paths = append(paths, lockfiles...)
+	sort.Strings(paths)
+
+	h := sha256.New()
+	for _, path := range paths {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			rel = path
+		}
+
+		fileHash, err := hashFile(path)
+		if err != nil {
+			return "", fmt.Errorf("fingerprint %s: %w", path, err)
+		}
+		fmt.Fprintf(h, "%s\x00%s\n", filepath.ToSlash(rel), fileHash)
+	}
+
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile returns the hex SHA-256 of a file's contents.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,40 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+)
+
+// queryGHSA queries the GitHub Advisory Database for the advisories of
+// packages and builds a Database of their vulnerable ranges.
+func queryGHSA(options ScanOptions, packages []ioc.PackageVersion) (*ioc.Database, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		if !seen[pkg.Name] {
+			seen[pkg.Name] = true
+			names = append(names, pkg.Name)
+		}
+	}
+	if len(names) == 0 {
+		// An empty package list would fetch every npm advisory
+		return ioc.NewDatabaseFromEntries(nil), nil
+	}
+
+	if options.Verbose {
+		options.logf("Querying GHSA for %d packages...\n", len(names))
+	}
+
+	src := &ioc.GHSASource{Packages: names, URL: options.GHSAURL}
+	iocDB, err := ioc.NewDatabaseFromSource(options.Context, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IoC database: %w", unavailableError{err})
+	}
+
+	if options.Verbose {
+		options.logf("GHSA lists %d vulnerable ranges and versions\n", iocDB.Size())
+	}
+
+	return iocDB, nil
+}
@@ -0,0 +1,383 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
+)
+
+// Inventory is an in-memory set of dependency declarations to scan, such
+// as the manifests and lockfiles a code generator is about to write. Paths
+// are only recorded as match locations; nothing is read from disk.
+type Inventory struct {
+	Manifests []InventoryManifest
+	Lockfiles []InventoryLockfile
+	// Packages are already resolved packages from any other source, such as
+	// a yarn.lock or an SBOM, located at their LockfilePath
+	Packages []parser.ResolvedPackage
+}
+
+// InventoryManifest is a package.json of an Inventory.
+type InventoryManifest struct {
+	Path     string
+	Manifest *parser.Manifest
+}
+
+// InventoryLockfile is a package-lock.json or npm-shrinkwrap.json of an
+// Inventory.
+type InventoryLockfile struct {
+	Path     string
+	Lockfile *parser.Lockfile
+}
+
+// AddFile parses the content of a manifest or lockfile, whose type is
+// selected by the file name of path, and adds it to the inventory. yarn.lock
+// and Yarn PnP entries are added as Packages.
+func (inventory *Inventory) AddFile(path string, content []byte) error {
+	switch name := filepath.Base(path); {
+	case isManifestName(name):
+		var manifest parser.Manifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		inventory.Manifests = append(inventory.Manifests, InventoryManifest{Path: path, Manifest: &manifest})
+	case name == "yarn.lock" || isPnPName(name):
+		yarnLock, err := parseYarnLockfileData(content, path)
+		if err != nil {
+			return err
+		}
+		for _, pkg := range parser.ExtractYarnResolvedPackages(yarnLock) {
+			inventory.Packages = append(inventory.Packages, parser.ResolvedPackage{
+				Name:         pkg.Name,
+				Version:      pkg.Version,
+				LockfilePath: pkg.LockfilePath,
+				Integrity:    pkg.Integrity,
+			})
+		}
+	case isLockfileName(name):
+		lockfile, err := parser.ParsePackageLockData(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		inventory.Lockfiles = append(inventory.Lockfiles, InventoryLockfile{Path: path, Lockfile: lockfile})
+	default:
+		return fmt.Errorf("unsupported file %q: expected package.json, package-lock.json, npm-shrinkwrap.json, yarn.lock, .pnp.cjs or .pnp.data.json", path)
+	}
+	return nil
+}
+
+// ScanContent scans a single manifest or lockfile that is not on disk, such
+// as one read from standard input, with the parser selected by the file
+// name of path. path is only recorded as the match location.
+//
+// The IoC database is loaded as for RunScan, but only the csv source is
+// supported. The watchlist, policy, allowlist and typosquat detection
+// apply. Options that inspect the file's surroundings (hygiene, installed
+// packages, exposure windows, git metadata) do not apply.
+func ScanContent(options ScanOptions, path string, content []byte) (*formatter.ScanResult, error) {
+	if options.LockfileOnly && isManifestName(filepath.Base(path)) {
+		return nil, fmt.Errorf("cannot scan %s in lockfile-only mode: not a lockfile", path)
+	}
+
+	var inventory Inventory
+	if err := inventory.AddFile(path, content); err != nil {
+		return nil, err
+	}
+
+	iocDB, err := detachedDatabase(options, path+", which is not on disk")
+	if err != nil {
+		return nil, err
+	}
+	return scanDetached(options, inventory, iocDB)
+}
+
+// detachedDatabase loads the IoC database for a scan of files that are not
+// on disk, which the csv and registered sources support. subject names
+// what is scanned in the error refusing the OSV and GHSA sources.
+func detachedDatabase(options ScanOptions, subject string) (*ioc.Database, error) {
+	sources, err := ioc.ParseSources(options.Source)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if source == ioc.SourceOSV || source == ioc.SourceGHSA {
+			return nil, fmt.Errorf("the %s source cannot scan %s", source, subject)
+		}
+	}
+
+	iocDB := preloadedDatabase(options)
+	if iocDB == nil && containsSource(sources, ioc.SourceCSV) {
+		iocDB, err = LoadDatabase(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return addSources(options, sources, iocDB, nil)
+}
+
+// scanDetached scans an inventory of files that are not on disk against
+// iocDB, then applies the watchlist, policy and allowlist of options.
+func scanDetached(options ScanOptions, inventory Inventory, iocDB *ioc.Database) (*formatter.ScanResult, error) {
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+	result, err := ScanInventory(options.Context, inventory, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	result.IOCSnapshot = snapshotDate(options)
+	if options.OnMatch != nil && len(result.Matches) > 0 {
+		options.OnMatch(options.Path, append([]formatter.Match(nil), result.Matches...))
+	}
+
+	if options.Watchlist != nil {
+		result.Watchlist = watchInventory(inventory, options.Watchlist)
+	}
+	if options.Policy != nil {
+		result.PolicyViolations = checkInventoryPolicy(inventory, options.Policy)
+	}
+	if options.Allowlist != nil {
+		result.Unapproved = checkInventoryAllowlist(inventory, options.Allowlist)
+	}
+	if options.Typosquat != nil {
+		result.Typosquats = checkInventoryTyposquats(inventory, options.Typosquat)
+	}
+	return result, nil
+}
+
+// watchInventory returns the dependencies of inventory whose name matches
+// a pattern of list.
+func watchInventory(inventory Inventory, list *watchlist.Watchlist) []formatter.Match {
+	watched := []formatter.Match{}
+	for _, m := range inventory.Manifests {
+		deps := parser.ExtractDependencies(m.Manifest, m.Path)
+		watched = append(watched, matcher.MatchWatchlistDeclared(deps, list)...)
+	}
+	for _, l := range inventory.Lockfiles {
+		resolved := parser.ExtractResolvedPackages(l.Lockfile, l.Path)
+		watched = append(watched, matcher.MatchWatchlistResolved(resolved, list)...)
+	}
+	return append(watched, matcher.MatchWatchlistResolved(inventory.Packages, list)...)
+}
+
+// checkInventoryPolicy returns the dependencies of inventory whose version
+// violates a requirement of p.
+func checkInventoryPolicy(inventory Inventory, p *policy.Policy) []formatter.Match {
+	violations := []formatter.Match{}
+	for _, m := range inventory.Manifests {
+		deps := parser.ExtractDependencies(m.Manifest, m.Path)
+		violations = append(violations, matcher.MatchPolicyDeclared(deps, p)...)
+	}
+	for _, l := range inventory.Lockfiles {
+		resolved := parser.ExtractResolvedPackages(l.Lockfile, l.Path)
+		violations = append(violations, matcher.MatchPolicyResolved(resolved, p)...)
+	}
+	return append(violations, matcher.MatchPolicyResolved(inventory.Packages, p)...)
+}
+
+// matchResolvedContext matches resolved packages by version and by tarball
+// integrity hash, stopping with ctx's error if ctx is canceled.
+func matchResolvedContext(ctx context.Context, packages []parser.ResolvedPackage, iocDB *ioc.Database) ([]formatter.Match, error) {
+	matches, err := matcher.MatchResolvedContext(ctx, packages, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	integrity, err := matcher.MatchIntegrityContext(ctx, packages, iocDB)
+	if err != nil {
+		return nil, err
+	}
+	return append(matches, integrity...), nil
+}
+
+// ScanInventory matches an in-memory inventory against iocDB without
+// touching the filesystem, so dependency sets can be validated before they
+// are written. Manifests get DIRECT and POTENTIAL matching; lockfiles and
+// packages get TRANSITIVE matching, with dependency chains for lockfiles.
+// The scripts of manifests, and of packages that record theirs, are
+// matched against the malicious script indicators.
+//
+// Git-based annotations (exposure, metadata) are not available, since the
+// files do not exist yet. Returns ctx's error if it is canceled.
+func ScanInventory(ctx context.Context, inventory Inventory, iocDB *ioc.Database) (*formatter.ScanResult, error) {
+	startTime := time.Now()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	canceled := func() error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+			return nil
+		}
+	}
+
+	var matches []formatter.Match
+	var scripts []formatter.Match
+	packagesChecked := 0
+	dependencyStats := &formatter.DependencyStats{}
+
+	for _, m := range inventory.Manifests {
+		if err := canceled(); err != nil {
+			return nil, err
+		}
+		if m.Manifest == nil {
+			return nil, fmt.Errorf("manifest %s is nil", m.Path)
+		}
+		deps := parser.ExtractDependencies(m.Manifest, m.Path)
+		packagesChecked += len(deps)
+		dependencyStats.Manifest.Add(manifestCounts(deps))
+		direct, err := matcher.MatchDirectContext(ctx, m.Manifest, iocDB, m.Path)
+		if err != nil {
+			return nil, err
+		}
+		potential, err := matcher.MatchPotentialContext(ctx, m.Manifest, iocDB, m.Path)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, direct...)
+		matches = append(matches, potential...)
+		scripts = append(scripts, matcher.MatchScripts(m.Manifest, iocDB, m.Path)...)
+	}
+
+	for _, l := range inventory.Lockfiles {
+		if err := canceled(); err != nil {
+			return nil, err
+		}
+		if l.Lockfile == nil {
+			return nil, fmt.Errorf("lockfile %s is nil", l.Path)
+		}
+		resolved := parser.ExtractResolvedPackages(l.Lockfile, l.Path)
+		packagesChecked += len(resolved)
+		dependencyStats.Lockfile.Add(resolvedCounts(resolved))
+		lockfileMatches, err := matchResolvedContext(ctx, resolved, iocDB)
+		if err != nil {
+			return nil, err
+		}
+		attachChains(lockfileMatches, parser.BuildDependencyGraph(l.Lockfile))
+		matches = append(matches, lockfileMatches...)
+	}
+
+	if len(inventory.Packages) > 0 {
+		if err := canceled(); err != nil {
+			return nil, err
+		}
+		packagesChecked += len(inventory.Packages)
+		counts := resolvedCounts(inventory.Packages)
+		dependencyStats.Inventory = &counts
+		packageMatches, err := matchResolvedContext(ctx, inventory.Packages, iocDB)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, packageMatches...)
+		scripts = append(scripts, matcher.MatchScriptsInstalled(inventory.Packages, iocDB)...)
+	}
+
+	annotateIOCDates(matches, iocDB)
+	attachEvidence(matches, nil)
+
+	result := &formatter.ScanResult{
+		ManifestsScanned: len(inventory.Manifests),
+		LockfilesScanned: len(inventory.Lockfiles),
+		PackagesChecked:  packagesChecked,
+		Matches:          matcher.DeduplicateMatches(matches),
+		Timestamp:        startTime.UTC(),
+		IOCCount:         iocDB.Size(),
+		IOCDigest:        iocDB.Digest(),
+		DependencyStats:  dependencyStats,
+		Scripts:          scripts,
+	}
+	if len(inventory.Packages) > 0 {
+		result.InventoriesScanned = 1
+	}
+	formatter.AssignFingerprints(result, "")
+	return result, nil
+}
+
+// CollectComponents returns every concrete package version found under
+// root, independent of any IoC matches: exact pins from package.json files
+// (unless lockfileOnly is set) and every resolved lockfile entry. Each
+// component lists the files it was found in.
+//
+// Components are sorted by name and version, for SBOM export.
+func CollectComponents(root string, lockfileOnly bool) ([]formatter.Component, error) {
+	var manifestPaths []string
+	if !lockfileOnly {
+		var err error
+		manifestPaths, err = FindManifests(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find manifests: %w", err)
+		}
+	}
+	lockfilePaths, err := FindLockfiles(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lockfiles: %w", err)
+	}
+
+	index := make(map[string]*formatter.Component)
+	forEachPackage(manifestPaths, lockfilePaths, func(name, version, path string) {
+		key := name + "@" + version
+		component, ok := index[key]
+		if !ok {
+			component = &formatter.Component{Name: name, Version: version}
+			index[key] = component
+		}
+		for _, location := range component.Locations {
+			if location == path {
+				return
+			}
+		}
+		component.Locations = append(component.Locations, path)
+	})
+
+	components := make([]formatter.Component, 0, len(index))
+	for _, component := range index {
+		components = append(components, *component)
+	}
+	sort.Slice(components, func(i, j int) bool {
+		if components[i].Name != components[j].Name {
+			return components[i].Name < components[j].Name
+		}
+		return components[i].Version < components[j].Version
+	})
+	return components, nil
+}
+
+// checkInventoryAllowlist returns the dependencies of inventory that list
+// does not approve.
+func checkInventoryAllowlist(inventory Inventory, list *allowlist.Allowlist) []formatter.Match {
+	unapproved := []formatter.Match{}
+	for _, m := range inventory.Manifests {
+		deps := parser.ExtractDependencies(m.Manifest, m.Path)
+		unapproved = append(unapproved, matcher.MatchUnapprovedDeclared(deps, list)...)
+	}
+	for _, l := range inventory.Lockfiles {
+		resolved := parser.ExtractResolvedPackages(l.Lockfile, l.Path)
+		unapproved = append(unapproved, matcher.MatchUnapprovedResolved(resolved, list)...)
+	}
+	return append(unapproved, matcher.MatchUnapprovedResolved(inventory.Packages, list)...)
+}
+
+// checkInventoryTyposquats returns the dependencies declared in the
+// manifests of inventory whose name resembles a popular package.
+func checkInventoryTyposquats(inventory Inventory, detector *typosquat.Detector) []formatter.Match {
+	typosquats := []formatter.Match{}
+	for _, m := range inventory.Manifests {
+		deps := parser.ExtractDependencies(m.Manifest, m.Path)
+		typosquats = append(typosquats, matcher.MatchTyposquatsDeclared(deps, detector)...)
+	}
+	return typosquats
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Logger receives the progress messages and warnings of a scan. Each call
+// is one complete message, usually ending in a newline. Scans of several
+// files may log from several goroutines at once.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// writerLogger is a Logger writing to an io.Writer.
+type writerLogger struct {
+	w io.Writer
+}
+
+// NewWriterLogger returns a Logger writing every message to w, such as
+// os.Stderr to keep verbose output out of JSON written to stdout.
+func NewWriterLogger(w io.Writer) Logger {
+	return writerLogger{w: w}
+}
+
+func (l writerLogger) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(l.w, format, args...)
+}
+
+// logger returns options.Logger, or a Logger writing to stdout.
+func (options ScanOptions) logger() Logger {
+	if options.Logger != nil {
+		return options.Logger
+	}
+	return writerLogger{w: os.Stdout}
+}
+
+// logf writes a verbose message of the scan to its logger. Callers check
+// options.Verbose first.
+func (options ScanOptions) logf(format string, args ...interface{}) {
+	options.logger().Printf(format, args...)
+}
+
+// warnf writes a warning that is shown even without options.Verbose to
+// options.Logger, or to stderr.
+func (options ScanOptions) warnf(format string, args ...interface{}) {
+	if options.Logger != nil {
+		options.Logger.Printf(format, args...)
+		return
+	}
+	fmt.Fprintf(os.Stderr, format, args...)
+}
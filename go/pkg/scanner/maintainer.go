@@ -0,0 +1,69 @@
+package scanner
+
+import (
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/maintainer"
+)
+
+// checkMaintainers looks up, with options.Maintainers, who published each
+// package version pinned in manifestPaths or resolved in lockfilePaths,
+// and reports those published or maintained by a flagged account. Each
+// version is looked up once and reported at the first file it was found
+// in. Lookups that fail are warned about: the check is informational, and
+// must not fail the scan.
+func checkMaintainers(options ScanOptions, manifestPaths, lockfilePaths []string) []formatter.Match {
+	var packages []maintainer.Package
+	locations := make(map[maintainer.Package]string)
+	forEachPackage(manifestPaths, lockfilePaths, func(name, version, path string) {
+		pkg := maintainer.Package{Name: name, Version: version}
+		if _, ok := locations[pkg]; ok {
+			return
+		}
+		locations[pkg] = path
+		packages = append(packages, pkg)
+	})
+	if len(packages) == 0 {
+		return nil
+	}
+
+	if options.Verbose {
+		options.logf("Checking the maintainers of %d package versions...\n", len(packages))
+	}
+	results, err := options.Maintainers.Check(options.Context, packages)
+	if err != nil {
+		options.warnf("Warning: maintainer lookup failed, some packages were not checked: %v\n", err)
+	}
+
+	var findings []formatter.Match
+	for _, pkg := range packages {
+		result, ok := results[pkg]
+		if !ok {
+			continue
+		}
+		account := result.Account.Name
+		if account == "" {
+			account = result.Account.Email
+		}
+		reason := "published by flagged account " + account
+		if result.Role == maintainer.RoleMaintainer {
+			reason = "maintained by flagged account " + account
+		}
+		findings = append(findings, formatter.Match{
+			PackageName: pkg.Name,
+			Version:     pkg.Version,
+			Severity:    formatter.SeverityMaintainer,
+			Location:    locations[pkg],
+			Reason:      reason,
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Location != findings[j].Location {
+			return findings[i].Location < findings[j].Location
+		}
+		return findings[i].PackageName < findings[j].PackageName
+	})
+	return findings
+}
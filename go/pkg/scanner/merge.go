@@ -0,0 +1,100 @@
+package scanner
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+)
+
+// MergeResults combines the results of several scan roots into a single
+// ScanResult. File, package and dependency type counts are summed, matches
+// are concatenated and deduplicated, and the earliest timestamp is kept.
+// Metadata is kept only where every root agrees on the value. If a root was
+// not scanned, the merged result is marked not scanned with its reason.
+func MergeResults(roots []formatter.RootResult) *formatter.ScanResult {
+	merged := &formatter.ScanResult{
+		Matches: []formatter.Match{},
+	}
+
+	for _, root := range roots {
+		result := root.Result
+		if result == nil {
+			continue
+		}
+
+		merged.ManifestsScanned += result.ManifestsScanned
+		merged.LockfilesScanned += result.LockfilesScanned
+		merged.InventoriesScanned += result.InventoriesScanned
+		merged.PackagesChecked += result.PackagesChecked
+		merged.Matches = append(merged.Matches, result.Matches...)
+		merged.Projects = append(merged.Projects, result.Projects...)
+		merged.Hygiene = append(merged.Hygiene, result.Hygiene...)
+		merged.UncheckedBundled = append(merged.UncheckedBundled, result.UncheckedBundled...)
+		merged.Shadowed = append(merged.Shadowed, result.Shadowed...)
+		merged.Watchlist = append(merged.Watchlist, result.Watchlist...)
+		merged.RegistryStatus = append(merged.RegistryStatus, result.RegistryStatus...)
+		merged.Provenance = append(merged.Provenance, result.Provenance...)
+		merged.Maintainers = append(merged.Maintainers, result.Maintainers...)
+		merged.PolicyViolations = append(merged.PolicyViolations, result.PolicyViolations...)
+		merged.Unapproved = append(merged.Unapproved, result.Unapproved...)
+		merged.Scripts = append(merged.Scripts, result.Scripts...)
+		merged.Typosquats = append(merged.Typosquats, result.Typosquats...)
+		merged.LockfileAges = append(merged.LockfileAges, result.LockfileAges...)
+		merged.Warnings = append(merged.Warnings, result.Warnings...)
+		merged.Suppressed = append(merged.Suppressed, result.Suppressed...)
+
+		if merged.Timestamp.IsZero() || result.Timestamp.Before(merged.Timestamp) {
+			merged.Timestamp = result.Timestamp
+		}
+		if result.IOCCount > merged.IOCCount {
+			merged.IOCCount = result.IOCCount
+		}
+		if result.IOCSnapshot != nil {
+			merged.IOCSnapshot = result.IOCSnapshot
+		}
+		if result.IOCDigest != "" && merged.IOCDigest == "" {
+			merged.IOCDigest = result.IOCDigest
+		}
+		if result.NotScanned != "" && merged.NotScanned == "" {
+			merged.NotScanned = result.NotScanned
+		}
+		if result.DependencyStats != nil {
+			if merged.DependencyStats == nil {
+				merged.DependencyStats = &formatter.DependencyStats{}
+			}
+			merged.DependencyStats.Add(result.DependencyStats)
+		}
+	}
+
+	merged.Matches = matcher.DeduplicateMatches(merged.Matches)
+	if merged.Suppressed != nil {
+		merged.Suppressed = matcher.DeduplicateMatches(merged.Suppressed)
+	}
+	merged.Metadata = commonMetadata(roots)
+
+	return merged
+}
+
+// commonMetadata returns the metadata entries shared by every root.
+func commonMetadata(roots []formatter.RootResult) map[string]string {
+	var common map[string]string
+	first := true
+	for _, root := range roots {
+		if root.Result == nil {
+			continue
+		}
+		if first {
+			common = formatter.MergeMetadata(root.Result.Metadata, nil)
+			first = false
+			continue
+		}
+		for key, value := range common {
+			if root.Result.Metadata[key] != value {
+				delete(common, key)
+			}
+		}
+	}
+	if len(common) == 0 {
+		return nil
+	}
+	return common
+}
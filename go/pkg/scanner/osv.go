@@ -0,0 +1,96 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// queryOSV queries OSV.dev for packages and builds a Database of the
+// affected ones.
+func queryOSV(options ScanOptions, packages []ioc.PackageVersion) (*ioc.Database, error) {
+	if options.Verbose {
+		options.logf("Querying OSV for %d package versions...\n", len(packages))
+	}
+
+	src := &ioc.OSVSource{Packages: packages, URL: options.OSVURL}
+	iocDB, err := ioc.NewDatabaseFromSource(options.Context, src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch IoC database: %w", unavailableError{err})
+	}
+
+	if options.Verbose {
+		options.logf("OSV reports %d affected package versions\n", iocDB.Size())
+	}
+
+	return iocDB, nil
+}
+
+// discoveredPackages returns the distinct concrete package versions in the
+// given files: exact pins from manifests and every resolved lockfile entry.
+// Files that fail to parse are skipped; the scan reports them later.
+func discoveredPackages(manifestPaths, lockfilePaths []string) []ioc.PackageVersion {
+	seen := make(map[ioc.PackageVersion]bool)
+	forEachPackage(manifestPaths, lockfilePaths, func(name, version, path string) {
+		seen[ioc.PackageVersion{Name: name, Version: version}] = true
+	})
+
+	packages := make([]ioc.PackageVersion, 0, len(seen))
+	for pkg := range seen {
+		packages = append(packages, pkg)
+	}
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Version < packages[j].Version
+	})
+	return packages
+}
+
+// forEachPackage calls fn for every concrete package version in the given
+// files, with the file it was found in: exact pins from manifests and every
+// resolved lockfile entry. Files that fail to parse are skipped.
+func forEachPackage(manifestPaths, lockfilePaths []string, fn func(name, version, path string)) {
+	add := func(name, version, path string) {
+		if name != "" && version != "" {
+			fn(name, version, path)
+		}
+	}
+
+	for _, manifestPath := range manifestPaths {
+		manifest, err := parser.ParsePackageJSON(manifestPath)
+		if err != nil {
+			continue
+		}
+		for _, dep := range parser.ExtractDependencies(manifest, manifestPath) {
+			if version, ok := matcher.ExactVersion(dep.VersionSpec); ok {
+				add(dep.Name, version, manifestPath)
+			}
+		}
+	}
+
+	for _, lockfilePath := range lockfilePaths {
+		if isYarnLockfile(lockfilePath) {
+			yarnLock, err := parseYarnLockfile(lockfilePath)
+			if err != nil {
+				continue
+			}
+			for _, pkg := range parser.ExtractYarnResolvedPackages(yarnLock) {
+				add(pkg.Name, pkg.Version, lockfilePath)
+			}
+			continue
+		}
+
+		lockfile, err := parser.ParsePackageLock(lockfilePath)
+		if err != nil {
+			continue
+		}
+		for _, pkg := range parser.ExtractResolvedPackages(lockfile, lockfilePath) {
+			add(pkg.Name, pkg.Version, lockfilePath)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// fileResult is the outcome of parsing and matching a single manifest or
+// lockfile.
+type fileResult struct {
+	// err is the parse error of a file that was skipped
+	err error
+	// name is the package name of a manifest
+	name     string
+	packages int
+	// counts breaks packages down by dependency type
+	counts  formatter.DependencyCounts
+	matches []formatter.Match
+	hygiene []formatter.Match
+	// unchecked holds the bundledDependencies that are not installed
+	unchecked []formatter.Match
+	// shadowed holds installed packages the lockfile does not resolve
+	shadowed []formatter.Match
+	// watched holds the packages matching the watchlist
+	watched []formatter.Match
+	// violations holds the packages violating a required safe version
+	violations []formatter.Match
+	// unapproved holds the packages missing from the allowlist
+	unapproved []formatter.Match
+	// typosquats holds the declared packages named like popular ones
+	typosquats []formatter.Match
+	// scripts holds the scripts running a known-malicious command
+	scripts []formatter.Match
+	// uncacheable marks results that depend on more than the file's
+	// contents, such as installed packages, and must not be cached
+	uncacheable bool
+}
+
+// scanFiles runs scan on every path with up to workers goroutines (the
+// number of CPUs if workers is not positive) and returns the results in the
+// order of paths, so the outcome does not depend on scheduling. No further
+// files are started once ctx is canceled, files in progress stop at their
+// next cancellation checkpoint or are given up on (see scanWithin), and
+// ctx's error is returned.
+func scanFiles(ctx context.Context, paths []string, workers int, scan func(path string) fileResult) ([]fileResult, error) {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	results := make([]fileResult, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = scan(paths[i])
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := range paths {
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		case jobs <- i:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	// Files started before the cancellation stop at a checkpoint and
+	// report it as their error, so the results are incomplete
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// scanWithin runs scan on a single file, giving up on it once
+// options.FileTimeout has passed or options.Context is done. A file that
+// is given up on yields a timeout (or the context's) error while scan
+// finishes in the background, stopping at its next cancellation
+// checkpoint; parsing a file cannot be interrupted, so waiting for it
+// could stall the whole scan.
+func scanWithin(options ScanOptions, scan func(options ScanOptions) fileResult) fileResult {
+	parent := options.Context
+	if options.FileTimeout <= 0 && parent.Done() == nil {
+		return scan(options)
+	}
+
+	ctx := parent
+	if options.FileTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(parent, options.FileTimeout)
+		defer cancel()
+	}
+	options.Context = ctx
+
+	done := make(chan fileResult, 1)
+	go func() { done <- scan(options) }()
+	select {
+	case result := <-done:
+		if result.err == nil || ctx.Err() == nil {
+			return result
+		}
+	case <-ctx.Done():
+	}
+	if err := parent.Err(); err != nil {
+		return fileResult{err: err}
+	}
+	return fileResult{err: fmt.Errorf("timed out after %s: %w", options.FileTimeout, context.DeadlineExceeded)}
+}
+
+// scanManifest runs DIRECT and POTENTIAL matching, TRANSITIVE matching of
+// installed bundledDependencies, script matching, and the hygiene audit
+// when enabled, on the manifest at manifestPath. lockfileDirs holds the
+// directories containing a lockfile, to flag lockfile-less projects.
+func scanManifest(manifestPath string, iocDB *ioc.Database, options ScanOptions, lockfileDirs map[string]bool, now time.Time) fileResult {
+	manifest, err := parser.ParsePackageJSON(manifestPath)
+	if err != nil {
+		return fileResult{err: err}
+	}
+
+	// Extract dependencies for counting
+	deps := parser.ExtractDependencies(manifest, manifestPath)
+	result := fileResult{name: manifest.Name, packages: len(deps), counts: manifestCounts(deps)}
+	// Bundles are matched by their installed versions
+	result.uncacheable = len(manifest.BundledDependencies) > 0
+
+	// Read the manifest again to locate declarations for evidence
+	content := fileContent(manifestPath)
+
+	// Run direct matching
+	directMatches, err := matcher.MatchDirectContext(options.Context, manifest, iocDB, manifestPath)
+	if err != nil {
+		return fileResult{err: err}
+	}
+	annotateMatches(directMatches, iocDB, now)
+	attachEvidence(directMatches, content)
+	result.matches = append(result.matches, directMatches...)
+
+	// Run potential matching
+	potentialMatches, err := matcher.MatchPotentialContext(options.Context, manifest, iocDB, manifestPath)
+	if err != nil {
+		return fileResult{err: err}
+	}
+	annotateMatches(potentialMatches, iocDB, now)
+	attachEvidence(potentialMatches, content)
+	result.matches = append(result.matches, potentialMatches...)
+
+	// Check bundled dependencies against their installed versions
+	bundledMatches, unchecked := matcher.MatchBundled(manifest, iocDB, manifestPath)
+	annotateMatches(bundledMatches, iocDB, now)
+	attachEvidence(bundledMatches, nil)
+	result.matches = append(result.matches, bundledMatches...)
+	if options.UncheckedBundled {
+		result.unchecked = unchecked
+	}
+
+	if options.Watchlist != nil {
+		result.watched = matcher.MatchWatchlistDeclared(deps, options.Watchlist)
+	}
+	if options.Policy != nil {
+		result.violations = matcher.MatchPolicyDeclared(deps, options.Policy)
+	}
+	if options.Allowlist != nil {
+		result.unapproved = matcher.MatchUnapprovedDeclared(deps, options.Allowlist)
+	}
+	if options.Typosquat != nil {
+		result.typosquats = matcher.MatchTyposquatsDeclared(deps, options.Typosquat)
+	}
+	result.scripts = matcher.MatchScripts(manifest, iocDB, manifestPath)
+
+	if options.Hygiene {
+		result.hygiene = matcher.AuditHygiene(manifest, manifestPath)
+		if len(deps) > 0 && !hasAncestorIn(manifestPath, lockfileDirs, options.Path) {
+			result.hygiene = append(result.hygiene, matcher.NoLockfileFinding(manifest, manifestPath))
+		}
+	}
+
+	return result
+}
+
+// scanLockfile runs TRANSITIVE matching, by version and by tarball
+// integrity hash, on the package-lock.json, npm-shrinkwrap.json or
+// yarn.lock at lockfilePath. With options.Installed, the packages installed
+// in the node_modules next to it are matched and compared with it as well.
+func scanLockfile(lockfilePath string, iocDB *ioc.Database, options ScanOptions, now time.Time) fileResult {
+	result := fileResult{uncacheable: options.Installed || options.ExposureWindow}
+	var transitiveMatches []formatter.Match
+	var resolvedPackages []parser.ResolvedPackage
+	// graph traces dependency chains; yarn.lock records none
+	var graph *parser.DependencyGraph
+
+	// Determine lockfile type and parse accordingly
+	if isYarnLockfile(lockfilePath) {
+		yarnLock, err := parseYarnLockfile(lockfilePath)
+		if err != nil {
+			return fileResult{err: err}
+		}
+
+		// Extract resolved packages from yarn.lock
+		yarnPackages := parser.ExtractYarnResolvedPackages(yarnLock)
+		result.packages = len(yarnPackages)
+		result.counts.Unknown = len(yarnPackages)
+
+		// Convert yarn packages to ResolvedPackage format
+		for _, yp := range yarnPackages {
+			resolvedPackages = append(resolvedPackages, parser.ResolvedPackage{
+				Name:         yp.Name,
+				Version:      yp.Version,
+				LockfilePath: yp.LockfilePath,
+				Integrity:    yp.Integrity,
+			})
+		}
+
+		// Create a temporary lockfile structure for MatchTransitive
+		tempLockfile := convertYarnToLockfile(resolvedPackages)
+		transitiveMatches, err = matcher.MatchTransitiveContext(options.Context, tempLockfile, iocDB, lockfilePath)
+		if err != nil {
+			return fileResult{err: err}
+		}
+	} else {
+		lockfile, err := parser.ParsePackageLock(lockfilePath)
+		if err != nil {
+			return fileResult{err: err}
+		}
+
+		resolvedPackages = parser.ExtractResolvedPackages(lockfile, lockfilePath)
+		result.packages = len(resolvedPackages)
+		result.counts = resolvedCounts(resolvedPackages)
+
+		// Run transitive matching
+		transitiveMatches, err = matcher.MatchTransitiveContext(options.Context, lockfile, iocDB, lockfilePath)
+		if err != nil {
+			return fileResult{err: err}
+		}
+		graph = parser.BuildDependencyGraph(lockfile)
+	}
+
+	integrityMatches, err := matcher.MatchIntegrityContext(options.Context, resolvedPackages, iocDB)
+	if err != nil {
+		return fileResult{err: err}
+	}
+	transitiveMatches = append(transitiveMatches, integrityMatches...)
+	if graph != nil {
+		attachChains(transitiveMatches, graph)
+	}
+
+	annotateMatches(transitiveMatches, iocDB, now)
+	// Huge lockfiles are only read whole to locate matches in them
+	var content []byte
+	if len(transitiveMatches) > 0 {
+		content = fileContent(lockfilePath)
+	}
+	attachEvidence(transitiveMatches, content)
+	if options.ExposureWindow {
+		var log Logger
+		if options.Verbose {
+			log = options.logger()
+		}
+		traceExposure(transitiveMatches, lockfilePath, now, log)
+	}
+	result.matches = transitiveMatches
+	if options.Watchlist != nil {
+		result.watched = matcher.MatchWatchlistResolved(resolvedPackages, options.Watchlist)
+	}
+	if options.Policy != nil {
+		result.violations = matcher.MatchPolicyResolved(resolvedPackages, options.Policy)
+	}
+	if options.Allowlist != nil {
+		result.unapproved = matcher.MatchUnapprovedResolved(resolvedPackages, options.Allowlist)
+	}
+
+	if options.Installed {
+		installed := parser.FindInstalledPackages(filepath.Dir(lockfilePath), options.Throttle)
+		installedMatches, err := matcher.MatchResolvedContext(options.Context, installed, iocDB)
+		if err != nil {
+			return fileResult{err: err}
+		}
+		annotateMatches(installedMatches, iocDB, now)
+		attachEvidence(installedMatches, nil)
+		result.matches = append(result.matches, installedMatches...)
+		result.shadowed = matcher.MatchInstalled(installed, resolvedPackages)
+		result.scripts = matcher.MatchScriptsInstalled(installed, iocDB)
+		if options.Watchlist != nil {
+			result.watched = append(result.watched, matcher.MatchWatchlistResolved(installed, options.Watchlist)...)
+		}
+		if options.Policy != nil {
+			result.violations = append(result.violations, matcher.MatchPolicyResolved(installed, options.Policy)...)
+		}
+		if options.Allowlist != nil {
+			result.unapproved = append(result.unapproved, matcher.MatchUnapprovedResolved(installed, options.Allowlist)...)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,73 @@
+package scanner
+
+import (
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+// potentialResolver tells which POTENTIAL matches name the version npm
+// would install for their declared range.
+type potentialResolver struct {
+	packuments map[string]*registry.Packument
+}
+
+// resolvePotential looks up, with options.ResolvePotential, every package
+// with POTENTIAL matches. Lookups that fail are warned about, and the
+// matches of their packages kept.
+func resolvePotential(options ScanOptions, matches []formatter.Match) *potentialResolver {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		if match.Severity != formatter.SeverityPotential || seen[match.PackageName] {
+			continue
+		}
+		seen[match.PackageName] = true
+		names = append(names, match.PackageName)
+	}
+	if len(names) == 0 {
+		return &potentialResolver{}
+	}
+
+	if options.Verbose {
+		options.logf("Resolving the POTENTIAL matches of %d packages in the registry...\n", len(names))
+	}
+	packuments, err := options.ResolvePotential.Packuments(options.Context, names)
+	if err != nil {
+		options.warnf("Warning: registry lookup failed, some POTENTIAL matches were not resolved: %v\n", err)
+	}
+	return &potentialResolver{packuments: packuments}
+}
+
+// filter returns the matches but the POTENTIAL ones of a compromised
+// version other than the one npm would install for their range. Matches of a compromised scope, which cover
+// every version, are kept, and so are those whose range resolves to no
+// published version: the filter only removes matches shown to be safe.
+func (p *potentialResolver) filter(matches []formatter.Match) []formatter.Match {
+	var kept []formatter.Match
+	for _, match := range matches {
+		if match.Severity == formatter.SeverityPotential && match.Version != ioc.AnyVersion {
+			if best, ok := bestVersion(p.packuments[match.PackageName], match.DeclaredSpec); ok && best != match.Version {
+				continue
+			}
+		}
+		kept = append(kept, match)
+	}
+	return kept
+}
+
+// bestVersion returns the version npm would install today for spec (see
+// registry.Packument.Resolve). ok is false if packument is unknown or spec
+// is not a range with a published version.
+func bestVersion(packument *registry.Packument, spec string) (string, bool) {
+	if packument == nil {
+		return "", false
+	}
+	r, err := npmsemver.ParseRange(spec)
+	if err != nil {
+		return "", false
+	}
+	best := packument.Resolve(r)
+	return best, best != ""
+}
@@ -0,0 +1,53 @@
+package scanner
+
+import (
+	"sync"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// ProgressFunc reports the progress of a scan: current of total files have
+// been parsed and matched, file being the one just finished. Calls are
+// serialized, and current grows by one with each call.
+type ProgressFunc func(current, total int, file string)
+
+// MatchFunc receives the matches found in file as soon as the file has been
+// matched, before the scan completes. Calls are serialized, and made only
+// for files with matches. The final ScanResult holds each package version
+// once per severity, so a version found in several files is delivered
+// more often than it is reported.
+type MatchFunc func(file string, matches []formatter.Match)
+
+// progressCounter counts finished files for a ProgressFunc and streams
+// their matches to a MatchFunc, either of which may be nil, from concurrent
+// workers.
+type progressCounter struct {
+	mu       sync.Mutex
+	progress ProgressFunc
+	onMatch  MatchFunc
+	// root is the scan root, against which streamed matches are
+	// fingerprinted as in the final result
+	root    string
+	current int
+	total   int
+}
+
+// done records that file has been processed with the given result.
+func (c *progressCounter) done(file string, result fileResult) {
+	if c.progress == nil && c.onMatch == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.onMatch != nil && result.err == nil && len(result.matches) > 0 {
+		matches := append([]formatter.Match(nil), result.matches...)
+		for i := range matches {
+			matches[i].Fingerprint = formatter.Fingerprint(matches[i], c.root)
+		}
+		c.onMatch(file, matches)
+	}
+	c.current++
+	if c.progress != nil {
+		c.progress(c.current, c.total, file)
+	}
+}
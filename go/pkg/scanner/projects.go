@@ -0,0 +1,70 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+)
+
+// projectIndex assigns scanned files to the project that owns them.
+// A project is any directory containing a package.json; files belong to
+// the nearest such ancestor, falling back to the scan root.
+type projectIndex struct {
+	root     string
+	projects map[string]*formatter.ProjectResult
+}
+
+// newProjectIndex creates a projectIndex with one project per manifest directory.
+func newProjectIndex(root string, manifestPaths []string) *projectIndex {
+	idx := &projectIndex{
+		root:     filepath.Clean(root),
+		projects: make(map[string]*formatter.ProjectResult),
+	}
+	for _, manifestPath := range manifestPaths {
+		dir := filepath.Dir(manifestPath)
+		idx.projects[dir] = &formatter.ProjectResult{Path: dir}
+	}
+	return idx
+}
+
+// lookup returns the project owning filePath, creating a project for the
+// scan root if no package.json ancestor exists.
+func (idx *projectIndex) lookup(filePath string) *formatter.ProjectResult {
+	dir := filepath.Dir(filePath)
+	for {
+		if project, ok := idx.projects[dir]; ok {
+			return project
+		}
+		if dir == idx.root {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	project := &formatter.ProjectResult{Path: idx.root}
+	idx.projects[idx.root] = project
+	return project
+}
+
+// results returns all projects sorted by path, with matches deduplicated
+// per project.
+func (idx *projectIndex) results() []formatter.ProjectResult {
+	results := make([]formatter.ProjectResult, 0, len(idx.projects))
+	for _, project := range idx.projects {
+		p := *project
+		p.Matches = matcher.DeduplicateMatches(p.Matches)
+		results = append(results, p)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	return results
+}
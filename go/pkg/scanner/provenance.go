@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/provenance"
+)
+
+// provenanceTarget is a package version whose provenance is checked, with
+// where it was found.
+type provenanceTarget struct {
+	pkg          provenance.Package
+	location     string
+	declaredSpec string
+}
+
+// checkProvenance verifies, with options.Provenance, the provenance of the
+// DIRECT and TRANSITIVE matches and, with options.ProvenanceAll, of every
+// direct dependency declared in manifestPaths. A dependency's version is
+// its exact pin or the one locked next to its manifest; dependencies with
+// neither are skipped. Versions published without provenance, or whose
+// provenance does not verify or names another repository than the package
+// declares, are reported. Lookups that fail are warned about: the check is
+// informational, and must not fail the scan.
+func checkProvenance(options ScanOptions, matches []formatter.Match, manifestPaths, lockfilePaths []string) []formatter.Match {
+	var targets []provenanceTarget
+	seen := make(map[provenance.Package]bool)
+	add := func(target provenanceTarget) {
+		if target.pkg.Version == "" || seen[target.pkg] {
+			return
+		}
+		seen[target.pkg] = true
+		targets = append(targets, target)
+	}
+
+	for _, match := range matches {
+		if match.Severity == formatter.SeverityDirect || match.Severity == formatter.SeverityTransitive {
+			add(provenanceTarget{pkg: provenance.Package{Name: match.PackageName, Version: match.Version}, location: match.Location})
+		}
+	}
+
+	if options.ProvenanceAll {
+		locked := lockedVersions(lockfilePaths)
+		for _, manifestPath := range manifestPaths {
+			manifest, err := parser.ParsePackageJSON(manifestPath)
+			if err != nil {
+				// Reported in ScanResult.Warnings by the scan itself
+				continue
+			}
+			for _, dep := range parser.ExtractDependencies(manifest, manifestPath) {
+				if !isRegistrySpec(dep.VersionSpec) {
+					continue
+				}
+				version, exact := matcher.ExactVersion(dep.VersionSpec)
+				if !exact {
+					r, err := npmsemver.ParseRange(dep.VersionSpec)
+					if err != nil {
+						continue
+					}
+					version = r.MaxSatisfying(locked[filepath.Dir(manifestPath)][dep.Name])
+				}
+				add(provenanceTarget{
+					pkg:          provenance.Package{Name: dep.Name, Version: version},
+					location:     manifestPath,
+					declaredSpec: dep.VersionSpec,
+				})
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	packages := make([]provenance.Package, len(targets))
+	for i, target := range targets {
+		packages[i] = target.pkg
+	}
+	if options.Verbose {
+		options.logf("Checking the provenance of %d package versions...\n", len(packages))
+	}
+	results, err := options.Provenance.CheckAll(options.Context, packages)
+	if err != nil {
+		options.warnf("Warning: provenance lookup failed, some packages were not checked: %v\n", err)
+	}
+
+	var findings []formatter.Match
+	for _, target := range targets {
+		result, ok := results[target.pkg]
+		if !ok {
+			continue
+		}
+
+		reason := result.Problem
+		if !result.Attested {
+			reason = "published without provenance"
+		}
+		if reason == "" {
+			continue
+		}
+		findings = append(findings, formatter.Match{
+			PackageName:  target.pkg.Name,
+			Version:      target.pkg.Version,
+			Severity:     formatter.SeverityProvenance,
+			Location:     target.location,
+			DeclaredSpec: target.declaredSpec,
+			Reason:       reason,
+		})
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Location != findings[j].Location {
+			return findings[i].Location < findings[j].Location
+		}
+		return findings[i].PackageName < findings[j].PackageName
+	})
+	return findings
+}
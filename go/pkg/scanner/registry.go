@@ -0,0 +1,143 @@
+package scanner
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/npmsemver"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+)
+
+// checkRegistry looks up every direct dependency declared in manifestPaths
+// in options.Registry and reports those whose version is deprecated or no
+// longer published. The version checked is the one locked by a lockfile
+// next to the manifest or, failing that, the one npm would install today.
+// Lookups that fail are warned about and their dependencies skipped: the
+// check is informational, and must not fail the scan.
+func checkRegistry(options ScanOptions, manifestPaths, lockfilePaths []string) []formatter.Match {
+	locked := lockedVersions(lockfilePaths)
+
+	var deps []parser.Dependency
+	seen := make(map[string]bool)
+	var names []string
+	for _, manifestPath := range manifestPaths {
+		manifest, err := parser.ParsePackageJSON(manifestPath)
+		if err != nil {
+			// Reported in ScanResult.Warnings by the scan itself
+			continue
+		}
+		for _, dep := range parser.ExtractDependencies(manifest, manifestPath) {
+			if !isRegistrySpec(dep.VersionSpec) {
+				continue
+			}
+			deps = append(deps, dep)
+			if !seen[dep.Name] {
+				seen[dep.Name] = true
+				names = append(names, dep.Name)
+			}
+		}
+	}
+	if len(deps) == 0 {
+		return nil
+	}
+
+	if options.Verbose {
+		options.logf("Checking %d direct dependencies in the registry...\n", len(names))
+	}
+	packuments, err := options.Registry.Packuments(options.Context, names)
+	if err != nil {
+		options.warnf("Warning: registry lookup failed, some dependencies were not checked: %v\n", err)
+	}
+
+	sort.SliceStable(deps, func(i, j int) bool {
+		if deps[i].FilePath != deps[j].FilePath {
+			return deps[i].FilePath < deps[j].FilePath
+		}
+		return deps[i].Name < deps[j].Name
+	})
+
+	var findings []formatter.Match
+	for _, dep := range deps {
+		packument, ok := packuments[dep.Name]
+		if !ok {
+			continue
+		}
+		status, ok := dependencyStatus(dep, packument, locked[filepath.Dir(dep.FilePath)][dep.Name])
+		if !ok {
+			continue
+		}
+
+		finding := formatter.Match{
+			PackageName:  dep.Name,
+			Version:      status.Version,
+			Location:     dep.FilePath,
+			DeclaredSpec: dep.VersionSpec,
+			Alias:        dep.Alias,
+		}
+		switch {
+		case packument == nil || len(packument.Versions) == 0:
+			finding.Severity = formatter.SeverityUnpublished
+			finding.Reason = "package is no longer published in the registry"
+		case status.Unpublished && status.Version == "":
+			finding.Severity = formatter.SeverityUnpublished
+			finding.Reason = "no published version satisfies " + dep.VersionSpec
+		case status.Unpublished:
+			finding.Severity = formatter.SeverityUnpublished
+			finding.Reason = "version is no longer published in the registry"
+		default:
+			finding.Severity = formatter.SeverityDeprecated
+			finding.Reason = "deprecated: " + status.Deprecated
+		}
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// lockedVersions returns the versions resolved by the lockfiles of each
+// directory, by package name.
+func lockedVersions(lockfilePaths []string) map[string]map[string][]string {
+	locked := make(map[string]map[string][]string)
+	forEachPackage(nil, lockfilePaths, func(name, version, path string) {
+		dir := filepath.Dir(path)
+		if locked[dir] == nil {
+			locked[dir] = make(map[string][]string)
+		}
+		locked[dir][name] = append(locked[dir][name], version)
+	})
+	return locked
+}
+
+// dependencyStatus returns the registry status of the version of dep in
+// use: its exact pin, the highest of lockedVersions it admits, or the
+// version npm would install. ok is false if the version is published and
+// not deprecated.
+func dependencyStatus(dep parser.Dependency, packument *registry.Packument, lockedVersions []string) (status registry.Status, ok bool) {
+	var version string
+	if pinned, exact := matcher.ExactVersion(dep.VersionSpec); exact {
+		version = pinned
+	} else if r, err := npmsemver.ParseRange(dep.VersionSpec); err == nil {
+		// Dist-tags such as "latest" do not parse and check the latest
+		// version
+		version = r.MaxSatisfying(lockedVersions)
+		if version == "" {
+			version = packument.Resolve(r)
+		}
+		if version == "" {
+			return registry.Status{Unpublished: true}, true
+		}
+	}
+
+	status = packument.Status(version)
+	return status, status.Unpublished || status.Deprecated != ""
+}
+
+// isRegistrySpec reports whether spec installs a package from the
+// registry, by version, range or dist-tag, rather than from a git
+// repository, tarball URL, local path or workspace.
+func isRegistrySpec(spec string) bool {
+	return spec != "" && !strings.ContainsAny(spec, ":/#")
+}
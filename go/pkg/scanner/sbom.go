@@ -0,0 +1,133 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/attestation"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// RunSBOMScan matches the npm components of an existing CycloneDX or SPDX
+// SBOM at options.Path against the IoC database, without touching the
+// filesystem of the project it describes.
+//
+// Components are resolved versions, so matches have TRANSITIVE severity and
+// are located at the SBOM file. Only CSVURL, Offline, Database, Since,
+// Verbose and Context are used from options.
+func RunSBOMScan(options ScanOptions) (*formatter.ScanResult, error) {
+	result, err := scanInventories(options, func(ctx context.Context) ([][]parser.ResolvedPackage, error) {
+		packages, format, err := parser.ParseSBOM(options.Path)
+		if err != nil {
+			return nil, err
+		}
+		if options.Verbose {
+			options.logf("Read %d npm components from %s SBOM %s\n", len(packages), format, options.Path)
+		}
+		return [][]parser.ResolvedPackage{packages}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Fingerprint by SBOM file name, wherever the file was read from
+	formatter.AssignFingerprints(result, options.Path)
+	return result, nil
+}
+
+// RunImageScan matches the npm components listed in the SBOM attestations
+// attached to the container image referenced by options.Path, without
+// pulling the image. Attestations are downloaded with cosign.
+//
+// Matches are located at the image reference. Options are used as in
+// RunSBOMScan.
+func RunImageScan(options ScanOptions) (*formatter.ScanResult, error) {
+	result, err := scanInventories(options, func(ctx context.Context) ([][]parser.ResolvedPackage, error) {
+		if options.Verbose {
+			options.logf("Downloading SBOM attestations for %s...\n", options.Path)
+		}
+		sboms, err := attestation.Download(ctx, options.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download attestations: %w", err)
+		}
+
+		var inventories [][]parser.ResolvedPackage
+		for _, sbom := range sboms {
+			packages, format, err := parser.ParseSBOMData(sbom.Document, options.Path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse %s attestation: %w", sbom.PredicateType, err)
+			}
+			if options.Verbose {
+				options.logf("Read %d npm components from %s attestation\n", len(packages), format)
+			}
+			inventories = append(inventories, packages)
+		}
+		return inventories, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Image references are not paths; fingerprint them as given
+	formatter.AssignFingerprints(result, "")
+	return result, nil
+}
+
+// scanInventories loads the IoC database unless it is preloaded, reads inventories with load and
+// matches every package in them.
+func scanInventories(options ScanOptions, load func(ctx context.Context) ([][]parser.ResolvedPackage, error)) (*formatter.ScanResult, error) {
+	startTime := time.Now()
+
+	if options.Context == nil {
+		options.Context = context.Background()
+	}
+
+	iocDB := preloadedDatabase(options)
+	if iocDB == nil {
+		var err error
+		iocDB, err = LoadDatabase(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	select {
+	case <-options.Context.Done():
+		return nil, options.Context.Err()
+	default:
+	}
+
+	inventories, err := load(options.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []formatter.Match
+	packagesChecked := 0
+	counts := formatter.DependencyCounts{}
+	for _, packages := range inventories {
+		packagesChecked += len(packages)
+		counts.Add(resolvedCounts(packages))
+		packageMatches, err := matcher.MatchResolvedContext(options.Context, packages, iocDB)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, packageMatches...)
+	}
+	annotateIOCDates(matches, iocDB)
+	attachEvidence(matches, nil)
+
+	return &formatter.ScanResult{
+		InventoriesScanned: len(inventories),
+		PackagesChecked:    packagesChecked,
+		Matches:            matcher.DeduplicateMatches(matches),
+		Timestamp:          startTime.UTC(),
+		IOCCount:           iocDB.Size(),
+		IOCSnapshot:        snapshotDate(options),
+		IOCDigest:          iocDB.Digest(),
+		DependencyStats:    &formatter.DependencyStats{Inventory: &counts},
+	}, nil
+}
@@ -4,13 +4,28 @@ package scanner
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/archive"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/gitinfo"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/maintainer"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/provenance"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/throttle"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
 )
 
 // ScanOptions configures the behavior of a vulnerability scan.
@@ -22,18 +37,203 @@ type ScanOptions struct {
 	// If empty, the default URL will be used.
 	CSVURL string
 
+	// CSVHeader holds extra headers for the IoC database request, such as
+	// the Authorization header of a private feed.
+	CSVHeader http.Header
+
+	// Offline uses the IoC snapshot embedded in the binary instead of
+	// fetching the database, so scans need no network access. The copy of
+	// CSVURL in DatabaseCache is used instead of the snapshot when there
+	// is one.
+	Offline bool
+
+	// DatabaseCache holds the IoC databases stored by npm-scan db update,
+	// for offline scans. nil uses the embedded snapshot.
+	DatabaseCache *ioc.DBCache
+
+	// FeedCheck configures the sanity checks run on the fetched CSV. A
+	// feed failing them is treated as unavailable unless its Mode is
+	// ioc.FeedCheckWarn; a feed failing its pinned checksum or signature
+	// always is.
+	FeedCheck ioc.FeedCheck
+
 	// LockfileOnly determines whether to skip package.json manifest files
 	// and only scan lockfiles (package-lock.json, yarn.lock).
 	LockfileOnly bool
 
+	// Installed also checks the packages installed in the node_modules
+	// next to each lockfile, and reports installed versions the lockfile
+	// does not resolve in ScanResult.Shadowed.
+	Installed bool
+
+	// Watchlist reports every dependency whose name matches one of its
+	// patterns, whatever the version, in ScanResult.Watchlist: declared
+	// ones in manifests, resolved ones in lockfiles and, with Installed,
+	// installed ones.
+	Watchlist *watchlist.Watchlist
+
+	// Policy reports every dependency whose version violates a required
+	// safe version range in ScanResult.PolicyViolations: exact pins in
+	// manifests, resolved versions in lockfiles and, with Installed,
+	// installed versions.
+	Policy *policy.Policy
+
+	// Allowlist turns on strict mode: every dependency it does not approve
+	// is reported in ScanResult.Unapproved, declared ones in manifests,
+	// resolved ones in lockfiles and, with Installed, installed ones.
+	Allowlist *allowlist.Allowlist
+
+	// Typosquat reports every dependency declared in a manifest whose name
+	// is one typo away from one of its popular packages in
+	// ScanResult.Typosquats.
+	Typosquat *typosquat.Detector
+
+	// Registry, if set, is asked about every direct dependency declared in
+	// a manifest; versions that are deprecated or were unpublished are
+	// reported in ScanResult.RegistryStatus. Lookup failures are warnings.
+	Registry *registry.Client
+
+	// ResolvePotential, if set, is asked for the highest published version
+	// of each range with POTENTIAL matches: only the match of the version
+	// npm would install is reported, and ranges resolving to a safe
+	// version are not. Lookup failures are warnings, and keep the matches.
+	ResolvePotential *registry.Client
+
+	// Provenance, if set, verifies the npm provenance of the DIRECT and
+	// TRANSITIVE matches and, with ProvenanceAll, of every direct
+	// dependency; versions without sound provenance are reported in
+	// ScanResult.Provenance. Lookup failures are warnings.
+	Provenance    *provenance.Client
+	ProvenanceAll bool
+
+	// Maintainers, if set, looks up who published every package version
+	// pinned in a manifest or resolved in a lockfile; versions published
+	// or maintained by a flagged account are reported in
+	// ScanResult.Maintainers. Lookup failures are warnings.
+	Maintainers *maintainer.Checker
+
+	// Cache reuses the per-file results of earlier scans of unchanged
+	// files against the same IoC database. nil scans every file.
+	Cache *ResultCache
+
+	// Platform selects the image pulled from a multi-platform container
+	// image by RunContainerScan, as os/arch or os/arch/variant. If empty,
+	// oci.DefaultPlatform is used.
+	Platform string
+
+	// Exclude holds gitignore-style patterns of paths to skip during file
+	// discovery, in addition to the .npmscanignore file at Path.
+	Exclude []string
+
+	// FollowSymlinks descends into symlinked directories during file
+	// discovery, visiting each directory once.
+	FollowSymlinks bool
+
+	// Throttle limits the pace of file discovery and of the node_modules
+	// walk of installed mode, so sweeps of live production volumes do not
+	// degrade them. Scans sharing a Throttle are limited together. nil
+	// walks at full speed.
+	Throttle *throttle.Throttle
+
 	// Verbose enables detailed logging during the scan.
 	Verbose bool
 
+	// Logger receives the verbose output of the scan and its warnings, so
+	// callers can keep them apart from results written to stdout. nil
+	// writes verbose output to stdout and warnings to stderr.
+	Logger Logger
+
+	// PerProject segments the result by project boundary (the nearest
+	// ancestor directory containing a package.json) and fills
+	// ScanResult.Projects with per-project summaries.
+	PerProject bool
+
+	// Hygiene enables the unpinned-dependency audit, reporting wildcard,
+	// "latest" and unbounded specs and lockfile-less projects in
+	// ScanResult.Hygiene.
+	Hygiene bool
+
+	// UncheckedBundled reports bundledDependencies that are not installed
+	// in node_modules, and so could not be checked, in
+	// ScanResult.UncheckedBundled. Installed bundles are always checked.
+	UncheckedBundled bool
+
+	// LockfileAge reports when each lockfile was last regenerated (from git
+	// history, falling back to mtime) in ScanResult.LockfileAges.
+	LockfileAge bool
+
+	// CampaignStart marks lockfiles last modified before it as stale.
+	// If zero, ioc.DefaultCampaignStart is used.
+	CampaignStart time.Time
+
+	// ExposureWindow walks the git history of lockfiles with TRANSITIVE
+	// matches to find the commits that introduced and removed each
+	// compromised version, filling Match.Timeline.
+	ExposureWindow bool
+
+	// Since restricts matching to IoC entries added on or after this date.
+	// Entries without a date are always considered. If zero, all entries are used.
+	Since time.Time
+
+	// Source selects the IoC source: ioc.SourceCSV (the default, fetched
+	// from CSVURL), ioc.SourceOSV or ioc.SourceGHSA, which query OSV.dev
+	// and the GitHub Advisory Database for the packages discovered in the
+	// scan, or a source added with ioc.RegisterSource. Several sources may
+	// be combined as a comma-separated list ("csv,osv"); a package version
+	// listed by more than one is reported as a single match naming each
+	// source.
+	Source string
+
+	// OSVURL overrides the OSV batch query endpoint (ioc.DefaultOSVURL).
+	OSVURL string
+
+	// GHSAURL overrides the GitHub advisories endpoint (ioc.DefaultGHSAURL).
+	GHSAURL string
+
+	// Database is a preloaded IoC database used in place of fetching the
+	// csv source, so long-running callers can scan many times against one
+	// copy, or scan against entries from their own feed (see
+	// ioc.NewDatabase and ioc.NewDatabaseFromEntries). Every kind of scan
+	// uses it. CSVURL is ignored when it is set, and Offline only marks the
+	// result as using the embedded snapshot; Since still applies. The
+	// database is only read, so concurrent scans may share it.
+	Database *ioc.Database
+
+	// NumWorkers is the number of files parsed and matched concurrently.
+	// If zero, the number of CPUs is used.
+	NumWorkers int
+
+	// Progress, if set, is called as each manifest and lockfile is
+	// processed, so callers can show that a long scan is advancing.
+	Progress ProgressFunc
+
+	// OnMatch, if set, receives the matches of each manifest and lockfile
+	// as soon as the file has been matched, so callers can show findings
+	// while a long scan is still running. Archives and container images
+	// are matched in one pass, so their matches are delivered together.
+	// The returned ScanResult is unaffected.
+	OnMatch MatchFunc
+
+	// SkipGitMetadata disables recording the remote URL, branch and HEAD
+	// commit of the scan root's git repository in ScanResult.Metadata.
+	SkipGitMetadata bool
+
+	// Timeout bounds the whole scan; a scan running longer fails with an
+	// error wrapping context.DeadlineExceeded. Zero means no limit.
+	Timeout time.Duration
+
+	// FileTimeout bounds the parsing and matching of each manifest and
+	// lockfile. A file taking longer is abandoned and reported in
+	// ScanResult.Warnings, so one pathological file cannot stall the scan.
+	// Zero means no limit.
+	FileTimeout time.Duration
+
 	// Context for cancellation and timeout support
 	Context context.Context
 }
 
-// RunScan orchestrates a complete vulnerability scan.
+// RunScan orchestrates a complete vulnerability scan. A Path naming a
+// .tgz, .tar.gz, .tar or .zip file is scanned with ScanArchive.
 // It performs the following steps:
 //  1. Fetch the IoC database from the specified URL
 //  2. Discover package.json and lockfile files in the scan path
@@ -44,188 +244,568 @@ type ScanOptions struct {
 // Returns a ScanResult containing all detected vulnerabilities, or an error if
 // any critical step fails (e.g., network error, file not found).
 func RunScan(options ScanOptions) (*formatter.ScanResult, error) {
-	startTime := time.Now()
-
 	// Set default context if not provided
 	if options.Context == nil {
 		options.Context = context.Background()
 	}
+	if options.Timeout <= 0 {
+		return runScan(options)
+	}
 
-	// Step 1: Fetch IoC database
-	if options.Verbose {
-		fmt.Printf("Fetching IoC database from %s...\n", options.CSVURL)
+	ctx, cancel := context.WithTimeout(options.Context, options.Timeout)
+	defer cancel()
+	options.Context = ctx
+	result, err := runScan(options)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("scan timed out after %s: %w", options.Timeout, context.DeadlineExceeded)
 	}
+	return result, err
+}
 
-	csvData, err := ioc.FetchIoCDatabase(options.CSVURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to fetch IoC database: %w", err)
+// runScan implements RunScan within options.Context.
+func runScan(options ScanOptions) (*formatter.ScanResult, error) {
+	startTime := time.Now()
+
+	// Release archives are read in memory instead of walked
+	if archive.IsArchive(options.Path) {
+		if info, err := os.Stat(options.Path); err == nil && !info.IsDir() {
+			return ScanArchive(options)
+		}
 	}
 
-	iocDB, err := ioc.NewDatabase(csvData)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse IoC database: %w", err)
+	// A single file is scanned as is, without discovery
+	if err := checkFile(options.Path, options.LockfileOnly); err != nil {
+		return nil, err
+	}
+	singleFile := false
+	if info, err := os.Stat(options.Path); err == nil && !info.IsDir() {
+		singleFile = true
 	}
 
-	if options.Verbose {
-		fmt.Printf("Loaded %d IoC entries\n", iocDB.Size())
+	// Step 1: Fetch IoC database. OSV and GHSA are queried per package, so
+	// they are loaded once the files have been discovered.
+	sources, err := ioc.ParseSources(options.Source)
+	if err != nil {
+		return nil, err
+	}
+	iocDB := preloadedDatabase(options)
+	if iocDB == nil && containsSource(sources, ioc.SourceCSV) {
+		iocDB, err = LoadDatabase(options)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := checkOfflineSources(options, sources); err != nil {
+		return nil, err
 	}
 
 	// Step 2: Discover files
+	find := FindOptions{Exclude: options.Exclude, FollowSymlinks: options.FollowSymlinks, Throttle: options.Throttle}
 	var manifestPaths []string
 	var lockfilePaths []string
 
 	if !options.LockfileOnly {
 		if options.Verbose {
-			fmt.Printf("Discovering package.json files in %s...\n", options.Path)
+			options.logf("Discovering package.json files in %s...\n", options.Path)
 		}
-		manifestPaths, err = FindManifests(options.Path)
+		manifestPaths, err = FindManifestsWith(options.Path, find)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find manifests: %w", err)
 		}
 		if options.Verbose {
-			fmt.Printf("Found %d package.json files\n", len(manifestPaths))
+			options.logf("Found %d package.json files\n", len(manifestPaths))
 		}
 	}
 
 	if options.Verbose {
-		fmt.Printf("Discovering lockfiles in %s...\n", options.Path)
+		options.logf("Discovering lockfiles in %s...\n", options.Path)
 	}
-	lockfilePaths, err = FindLockfiles(options.Path)
+	lockfilePaths, err = FindLockfilesWith(options.Path, find)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find lockfiles: %w", err)
 	}
 	if options.Verbose {
-		fmt.Printf("Found %d lockfiles\n", len(lockfilePaths))
+		options.logf("Found %d lockfiles\n", len(lockfilePaths))
+	}
+
+	iocDB, err = addSources(options, sources, iocDB, func() []ioc.PackageVersion {
+		return discoveredPackages(manifestPaths, lockfilePaths)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Project boundaries are needed even in lockfile-only mode
+	var projects *projectIndex
+	if options.PerProject {
+		boundaries := manifestPaths
+		if options.LockfileOnly {
+			boundaries, err = FindManifestsWith(options.Path, find)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find manifests: %w", err)
+			}
+		}
+		projects = newProjectIndex(options.Path, boundaries)
 	}
 
 	// Step 3: Parse files and run matching
 	var allMatches []formatter.Match
+	var hygieneFindings []formatter.Match
+	var uncheckedBundled []formatter.Match
+	var shadowed []formatter.Match
+	var watched []formatter.Match
+	var violations []formatter.Match
+	var unapproved []formatter.Match
+	var typosquats []formatter.Match
+	var scripts []formatter.Match
+	var warnings []formatter.FileError
 	packagesChecked := 0
+	dependencyStats := &formatter.DependencyStats{}
+	lockfileDirs := dirSet(lockfilePaths)
+	if singleFile && len(manifestPaths) == 1 {
+		// The hygiene audit still sees the manifest's own lockfile
+		lockfileDirs = dirSet(lockfilesBeside(manifestPaths[0]))
+	}
+
+	progress := &progressCounter{progress: options.Progress, onMatch: options.OnMatch, root: options.Path, total: len(manifestPaths) + len(lockfilePaths)}
 
-	// Process manifests (unless lockfile-only mode)
+	// OSV and GHSA entries are fetched for the discovered packages only, so
+	// results against them are not cached
+	cache := options.Cache
+	var salt string
+	if queriesPackages(sources) || iocDB == nil {
+		cache = nil
+	} else if cache != nil {
+		salt = cacheSalt(iocDB.Digest(), options)
+	}
+
+	// Process manifests (unless lockfile-only mode). Files are parsed and
+	// matched concurrently; results are merged in discovery order.
 	if !options.LockfileOnly {
-		for _, manifestPath := range manifestPaths {
-			// Check context for cancellation
-			select {
-			case <-options.Context.Done():
-				return nil, options.Context.Err()
-			default:
-			}
+		results, err := scanFiles(options.Context, manifestPaths, options.NumWorkers, func(path string) (result fileResult) {
+			defer func() { progress.done(path, result) }()
+			return scanWithin(options, func(options ScanOptions) fileResult {
+				if cache == nil {
+					return scanManifest(path, iocDB, options, lockfileDirs, startTime)
+				}
+				// The hygiene audit flags manifests without a lockfile
+				hasLockfile := hasAncestorIn(path, lockfileDirs, options.Path)
+				return cache.scan(path, salt+"\x00manifest\x00"+strconv.FormatBool(hasLockfile), startTime, func() fileResult {
+					return scanManifest(path, iocDB, options, lockfileDirs, startTime)
+				})
+			})
+		})
+		if err != nil {
+			return nil, err
+		}
 
+		for i, manifestPath := range manifestPaths {
+			r := results[i]
 			if options.Verbose {
-				fmt.Printf("Parsing %s...\n", manifestPath)
+				options.logf("Parsing %s...\n", manifestPath)
 			}
-
-			manifest, err := parser.ParsePackageJSON(manifestPath)
-			if err != nil {
-				// Log error but continue scanning other files
+			if r.err != nil {
+				// Record the error but continue scanning other files
 				if options.Verbose {
-					fmt.Printf("Warning: failed to parse %s: %v\n", manifestPath, err)
+					options.logf("Warning: failed to parse %s: %v\n", manifestPath, r.err)
 				}
+				warnings = append(warnings, formatter.FileError{Path: manifestPath, Error: r.err.Error()})
 				continue
 			}
 
-			// Extract dependencies for counting
-			deps := parser.ExtractDependencies(manifest, manifestPath)
-			packagesChecked += len(deps)
-
-			// Run direct matching
-			directMatches := matcher.MatchDirect(manifest, iocDB, manifestPath)
-			allMatches = append(allMatches, directMatches...)
-
-			// Run potential matching
-			potentialMatches := matcher.MatchPotential(manifest, iocDB, manifestPath)
-			allMatches = append(allMatches, potentialMatches...)
+			packagesChecked += r.packages
+			dependencyStats.Manifest.Add(r.counts)
+			allMatches = append(allMatches, r.matches...)
+			hygieneFindings = append(hygieneFindings, r.hygiene...)
+			uncheckedBundled = append(uncheckedBundled, r.unchecked...)
+			watched = append(watched, r.watched...)
+			violations = append(violations, r.violations...)
+			unapproved = append(unapproved, r.unapproved...)
+			typosquats = append(typosquats, r.typosquats...)
+			scripts = append(scripts, r.scripts...)
+
+			if projects != nil {
+				project := projects.lookup(manifestPath)
+				project.Name = r.name
+				project.ManifestsScanned++
+				project.PackagesChecked += r.packages
+				project.Matches = append(project.Matches, r.matches...)
+			}
 		}
 	}
 
 	// Process lockfiles
-	for _, lockfilePath := range lockfilePaths {
-		// Check context for cancellation
-		select {
-		case <-options.Context.Done():
-			return nil, options.Context.Err()
-		default:
-		}
+	results, err := scanFiles(options.Context, lockfilePaths, options.NumWorkers, func(path string) (result fileResult) {
+		defer func() { progress.done(path, result) }()
+		return scanWithin(options, func(options ScanOptions) fileResult {
+			if cache == nil {
+				return scanLockfile(path, iocDB, options, startTime)
+			}
+			return cache.scan(path, salt+"\x00lockfile", startTime, func() fileResult {
+				return scanLockfile(path, iocDB, options, startTime)
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	for i, lockfilePath := range lockfilePaths {
+		r := results[i]
 		if options.Verbose {
-			fmt.Printf("Parsing %s...\n", lockfilePath)
+			options.logf("Parsing %s...\n", lockfilePath)
+		}
+		if r.err != nil {
+			if options.Verbose {
+				options.logf("Warning: failed to parse %s: %v\n", lockfilePath, r.err)
+			}
+			warnings = append(warnings, formatter.FileError{Path: lockfilePath, Error: r.err.Error()})
+			continue
 		}
 
-		// Determine lockfile type and parse accordingly
-		var lockfile *parser.Lockfile
-		var yarnLock *parser.YarnLock
+		packagesChecked += r.packages
+		dependencyStats.Lockfile.Add(r.counts)
+		allMatches = append(allMatches, r.matches...)
+		shadowed = append(shadowed, r.shadowed...)
+		scripts = append(scripts, r.scripts...)
+		watched = append(watched, r.watched...)
+		violations = append(violations, r.violations...)
+		unapproved = append(unapproved, r.unapproved...)
+
+		if projects != nil {
+			project := projects.lookup(lockfilePath)
+			project.LockfilesScanned++
+			project.PackagesChecked += r.packages
+			project.Matches = append(project.Matches, r.matches...)
+		}
+	}
 
-		if isYarnLockfile(lockfilePath) {
-			yarnLock, err = parser.ParseYarnLock(lockfilePath)
+	// Determine lockfile staleness relative to the campaign window
+	var lockfileAges []formatter.LockfileAge
+	if options.LockfileAge {
+		campaignStart := options.CampaignStart
+		if campaignStart.IsZero() {
+			campaignStart, _ = time.Parse("2006-01-02", ioc.DefaultCampaignStart)
+		}
+		for _, lockfilePath := range lockfilePaths {
+			age, err := lockfileAge(lockfilePath, startTime, campaignStart)
 			if err != nil {
 				if options.Verbose {
-					fmt.Printf("Warning: failed to parse %s: %v\n", lockfilePath, err)
+					options.logf("Warning: failed to determine age of %s: %v\n", lockfilePath, err)
 				}
 				continue
 			}
-
-			// Extract resolved packages from yarn.lock
-			yarnPackages := parser.ExtractYarnResolvedPackages(yarnLock)
-			packagesChecked += len(yarnPackages)
-
-			// Convert yarn packages to ResolvedPackage format
-			var resolvedPackages []parser.ResolvedPackage
-			for _, yp := range yarnPackages {
-				resolvedPackages = append(resolvedPackages, parser.ResolvedPackage{
-					Name:         yp.Name,
-					Version:      yp.Version,
-					LockfilePath: yp.LockfilePath,
-				})
+			if age.Stale && options.Verbose {
+				options.logf("Warning: %s was last modified %s, before the IoC campaign window\n", lockfilePath, age.LastModified.Format("2006-01-02"))
 			}
+			lockfileAges = append(lockfileAges, age)
+		}
+	}
 
-			// Create a temporary lockfile structure for MatchTransitive
-			tempLockfile := convertYarnToLockfile(resolvedPackages)
-			transitiveMatches := matcher.MatchTransitive(tempLockfile, iocDB, lockfilePath)
-			allMatches = append(allMatches, transitiveMatches...)
-		} else {
-			lockfile, err = parser.ParsePackageLock(lockfilePath)
-			if err != nil {
-				if options.Verbose {
-					fmt.Printf("Warning: failed to parse %s: %v\n", lockfilePath, err)
-				}
-				continue
-			}
+	// Ask the registry about the direct dependencies
+	var registryStatus []formatter.Match
+	if options.Registry != nil && !options.LockfileOnly {
+		registryStatus = checkRegistry(options, manifestPaths, lockfilePaths)
+	}
 
-			resolvedPackages := parser.ExtractResolvedPackages(lockfile, lockfilePath)
-			packagesChecked += len(resolvedPackages)
+	// Ask the registry who is behind every package version
+	var maintainerFindings []formatter.Match
+	if options.Maintainers != nil {
+		maintainerFindings = checkMaintainers(options, manifestPaths, lockfilePaths)
+	}
 
-			// Run transitive matching
-			transitiveMatches := matcher.MatchTransitive(lockfile, iocDB, lockfilePath)
-			allMatches = append(allMatches, transitiveMatches...)
+	// Keep only the POTENTIAL matches of the versions npm would install
+	if options.ResolvePotential != nil {
+		resolver := resolvePotential(options, allMatches)
+		before := len(allMatches)
+		allMatches = resolver.filter(allMatches)
+		if projects != nil {
+			for _, project := range projects.projects {
+				project.Matches = resolver.filter(project.Matches)
+			}
+		}
+		if options.Verbose && len(allMatches) < before {
+			options.logf("Dropped %d POTENTIAL matches whose range resolves to a safe version\n", before-len(allMatches))
 		}
 	}
 
 	// Step 4: Deduplicate matches
 	allMatches = matcher.DeduplicateMatches(allMatches)
 
+	// Verify the provenance of matched packages and direct dependencies
+	var provenanceFindings []formatter.Match
+	if options.Provenance != nil {
+		provenanceFindings = checkProvenance(options, allMatches, manifestPaths, lockfilePaths)
+	}
+
 	// Step 5: Build result
 	result := &formatter.ScanResult{
 		ManifestsScanned: len(manifestPaths),
 		LockfilesScanned: len(lockfilePaths),
 		PackagesChecked:  packagesChecked,
 		Matches:          allMatches,
-		Timestamp:        startTime,
+		Timestamp:        startTime.UTC(),
 		IOCCount:         iocDB.Size(),
+		IOCDigest:        iocDB.Digest(),
+		DependencyStats:  dependencyStats,
+	}
+
+	if projects != nil {
+		result.Projects = projects.results()
+	}
+	if options.Hygiene {
+		result.Hygiene = hygieneFindings
+	}
+	if options.UncheckedBundled {
+		result.UncheckedBundled = uncheckedBundled
+	}
+	if options.Installed {
+		result.Shadowed = shadowed
+	}
+	if options.Watchlist != nil {
+		result.Watchlist = watched
+	}
+	if options.Policy != nil {
+		result.PolicyViolations = violations
+	}
+	if options.Allowlist != nil {
+		result.Unapproved = unapproved
+	}
+	if options.Typosquat != nil {
+		result.Typosquats = typosquats
+	}
+	result.Scripts = scripts
+	result.RegistryStatus = registryStatus
+	result.Provenance = provenanceFindings
+	result.Maintainers = maintainerFindings
+	result.LockfileAges = lockfileAges
+	result.Warnings = warnings
+	formatter.AssignFingerprints(result, options.Path)
+	result.IOCSnapshot = snapshotDate(options)
+	if !options.SkipGitMetadata {
+		dir := options.Path
+		if singleFile {
+			dir = filepath.Dir(options.Path)
+		}
+		result.Metadata = gitMetadata(dir)
 	}
 
 	if options.Verbose {
 		duration := time.Since(startTime)
-		fmt.Printf("\nScan completed in %v\n", duration)
-		fmt.Printf("Found %d matches\n", len(allMatches))
+		options.logf("\nScan completed in %v\n", duration)
+		options.logf("Found %d matches\n", len(allMatches))
 	}
 
 	return result, nil
 }
 
-// isYarnLockfile determines if a path points to a yarn.lock file.
+// attachChains records how each TRANSITIVE match is reached from a root
+// dependency of its lockfile.
+func attachChains(matches []formatter.Match, graph *parser.DependencyGraph) {
+	for i := range matches {
+		matches[i].Chain = graph.Chain(matches[i].PackageName, matches[i].Version)
+	}
+}
+
+// fileContent returns the contents of a manifest or lockfile for locating
+// match evidence, or nil if it cannot be read.
+func fileContent(path string) []byte {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	return content
+}
+
+// gitMetadata describes the git repository containing path as result
+// metadata. Returns nil if path is not inside a git work tree.
+func gitMetadata(path string) map[string]string {
+	repo, err := gitinfo.Describe(path)
+	if err != nil {
+		return nil
+	}
+
+	metadata := map[string]string{formatter.MetaGitCommit: repo.Head}
+	if repo.Branch != "" {
+		metadata[formatter.MetaGitBranch] = repo.Branch
+	}
+	if repo.RemoteURL != "" {
+		metadata[formatter.MetaGitRemote] = repo.RemoteURL
+	}
+	return metadata
+}
+
+// ErrDatabaseUnavailable is wrapped by errors of scans that could not run
+// because the IoC database could not be fetched, as opposed to errors in
+// the scanned files.
+var ErrDatabaseUnavailable = errors.New("IoC database unavailable")
+
+// unavailableError marks a database fetch error as ErrDatabaseUnavailable
+// without changing its message.
+type unavailableError struct {
+	err error
+}
+
+func (e unavailableError) Error() string { return e.err.Error() }
+
+func (e unavailableError) Unwrap() error { return e.err }
+
+func (e unavailableError) Is(target error) bool { return target == ErrDatabaseUnavailable }
+
+// preloadedDatabase returns options.Database restricted to options.Since,
+// or nil if no database was preloaded.
+func preloadedDatabase(options ScanOptions) *ioc.Database {
+	if options.Database == nil || options.Since.IsZero() {
+		return options.Database
+	}
+	return options.Database.Since(options.Since)
+}
+
+// LoadDatabase fetches and parses the IoC database for a scan, or loads the
+// embedded snapshot when options.Offline is set, restricting it to
+// options.Since when set.
+func LoadDatabase(options ScanOptions) (*ioc.Database, error) {
+	var iocDB *ioc.Database
+	if options.Offline {
+		data, info, err := options.DatabaseCache.Load(options.CSVURL)
+		switch {
+		case err == nil:
+			iocDB, err = ioc.NewDatabaseFormat(data, info.Format)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse cached IoC database: %w", err)
+			}
+			if options.Verbose {
+				options.logf("Using IoC database cached on %s (may be stale)\n", info.FetchedAt.Format("2006-01-02"))
+			}
+		case errors.Is(err, ioc.ErrNotCached):
+			db, taken, err := ioc.LoadSnapshot()
+			if err != nil {
+				return nil, fmt.Errorf("failed to load IoC snapshot: %w", err)
+			}
+			if options.Verbose {
+				options.logf("Using embedded IoC snapshot from %s (may be stale)\n", taken.Format("2006-01-02"))
+			}
+			iocDB = db
+		default:
+			return nil, fmt.Errorf("failed to load cached IoC database: %w", err)
+		}
+	} else {
+		if options.Verbose {
+			options.logf("Fetching IoC database from %s...\n", options.CSVURL)
+		}
+
+		feed, err := ioc.FetchFeed(options.CSVURL, options.CSVHeader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch IoC database: %w", unavailableError{err})
+		}
+		format := feed.Format()
+
+		if err := options.FeedCheck.Verify(feed.Data, options.CSVURL, options.CSVHeader); err != nil {
+			return nil, fmt.Errorf("IoC database failed integrity verification: %w", unavailableError{err})
+		}
+		if err := options.FeedCheck.CheckFormat(feed.Data, options.CSVURL, format); err != nil {
+			if options.FeedCheck.Mode != ioc.FeedCheckWarn {
+				return nil, fmt.Errorf("IoC database failed sanity checks: %w", unavailableError{err})
+			}
+			options.warnf("WARNING: IoC database failed sanity checks, results may be incomplete: %v\n", err)
+		}
+
+		iocDB, err = ioc.NewDatabaseFormat(feed.Data, format)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse IoC database: %w", err)
+		}
+	}
+
+	if options.Verbose {
+		options.logf("Loaded %d IoC entries\n", iocDB.Size())
+	}
+
+	if !options.Since.IsZero() {
+		iocDB = iocDB.Since(options.Since)
+		if options.Verbose {
+			options.logf("Considering %d IoC entries added since %s\n", iocDB.Size(), options.Since.Format("2006-01-02"))
+		}
+	}
+
+	return iocDB, nil
+}
+
+// snapshotDate returns the date of the cached database or embedded IoC
+// snapshot for offline scans, or nil when the database was fetched.
+func snapshotDate(options ScanOptions) *time.Time {
+	if !options.Offline {
+		return nil
+	}
+	if info, err := options.DatabaseCache.Info(options.CSVURL); err == nil {
+		return &info.FetchedAt
+	}
+	taken, ok := ioc.SnapshotDate()
+	if !ok {
+		return nil
+	}
+	return &taken
+}
+
+// containsSource reports whether sources includes name.
+func containsSource(sources []string, name string) bool {
+	for _, source := range sources {
+		if source == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isYarnLockfile determines if a path points to a yarn.lock file or a Yarn
+// PnP data file, which are both parsed into a parser.YarnLock.
 func isYarnLockfile(path string) bool {
-	return len(path) >= 9 && path[len(path)-9:] == "yarn.lock"
+	return (len(path) >= 9 && path[len(path)-9:] == "yarn.lock") || isPnPName(filepath.Base(path))
+}
+
+// parseYarnLockfile parses the yarn.lock or Yarn PnP data file at path.
+func parseYarnLockfile(path string) (*parser.YarnLock, error) {
+	if isPnPName(filepath.Base(path)) {
+		return parser.ParsePnP(path)
+	}
+	return parser.ParseYarnLock(path)
+}
+
+// parseYarnLockfileData is parseYarnLockfile for content that has already
+// been read, such as a historical revision from git.
+func parseYarnLockfileData(content []byte, path string) (*parser.YarnLock, error) {
+	if isPnPName(filepath.Base(path)) {
+		return parser.ParsePnPData(content, path)
+	}
+	return parser.ParseYarnLockData(content, path), nil
+}
+
+// dirSet returns the set of parent directories of the given file paths.
+func dirSet(paths []string) map[string]bool {
+	dirs := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		dirs[filepath.Dir(path)] = true
+	}
+	return dirs
+}
+
+// hasAncestorIn reports whether the directory of filePath, or any ancestor
+// up to and including root, is in dirs.
+func hasAncestorIn(filePath string, dirs map[string]bool, root string) bool {
+	root = filepath.Clean(root)
+	dir := filepath.Dir(filePath)
+	for {
+		if dirs[dir] {
+			return true
+		}
+		if dir == root {
+			return false
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
 }
 
 // convertYarnToLockfile converts resolved packages to a Lockfile structure
@@ -240,7 +820,8 @@ func convertYarnToLockfile(resolvedPackages []parser.ResolvedPackage) *parser.Lo
 		// Create a node_modules path for consistency with npm lockfiles
 		pkgPath := "node_modules/" + pkg.Name
 		lockfile.Packages[pkgPath] = parser.PackageInfo{
-			Version: pkg.Version,
+			Version:   pkg.Version,
+			Integrity: pkg.Integrity,
 		}
 	}
 
@@ -1,12 +1,40 @@
 package scanner
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/allowlist"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/maintainer"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/oci"
 	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/policy"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/provenance"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/registry"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/typosquat"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/watchlist"
 )
 
 // TestRunScan_Integration tests the full scanner orchestration
@@ -171,94 +199,2538 @@ func TestRunScan_EmptyDirectory(t *testing.T) {
 	}
 }
 
+// TestRunScan_OSV tests scanning against a local OSV batch endpoint.
+func TestRunScan_OSV(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifest := `{"name": "app", "dependencies": {"chalk": "5.6.1", "lodash": "^4.17.0"}}`
+	lockfile := `{
+  "name": "app",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "dependencies": {"chalk": "5.6.1", "lodash": "^4.17.0"}},
+    "node_modules/chalk": {"version": "5.6.1"},
+    "node_modules/lodash": {"version": "4.17.21"}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "package.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "package-lock.json"), []byte(lockfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Packages are queried in sorted order: chalk, lodash
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"results":[{"vulns":[{"id":"MAL-2025-1"}]},{}]}`))
+	}))
+	defer server.Close()
+
+	result, err := RunScan(ScanOptions{
+		Path:            tmpDir,
+		Source:          ioc.SourceOSV,
+		OSVURL:          server.URL,
+		SkipGitMetadata: true,
+		Context:         context.Background(),
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	if result.IOCCount != 1 {
+		t.Errorf("IOCCount = %d, want 1", result.IOCCount)
+	}
+	for _, match := range result.Matches {
+		if match.PackageName != "chalk" {
+			t.Errorf("unexpected match for %s@%s", match.PackageName, match.Version)
+		}
+	}
+	if len(result.Matches) == 0 {
+		t.Error("expected a match for chalk@5.6.1")
+	}
+}
+
+// TestRunScan_GHSA tests that GHSA advisories are fetched for the
+// discovered packages and matched by range
+func TestRunScan_GHSA(t *testing.T) {
+	tmpDir := t.TempDir()
+	lockfile := `{
+  "name": "app",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "dependencies": {"lodash": "^4.17.0"}},
+    "node_modules/lodash": {"version": "4.17.20"}
+  }
+}`
+	if err := os.WriteFile(filepath.Join(tmpDir, "package-lock.json"), []byte(lockfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var affects []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		affects = append(affects, r.URL.Query().Get("affects"))
+		w.Write([]byte(`[{"ghsa_id": "GHSA-35jh-r3h4-6jhm", "severity": "high",
+			"vulnerabilities": [{"package": {"ecosystem": "npm", "name": "lodash"}, "vulnerable_version_range": "< 4.17.21"}]}]`))
+	}))
+	defer server.Close()
+
+	result, err := RunScan(ScanOptions{
+		Path:            tmpDir,
+		Source:          ioc.SourceGHSA,
+		GHSAURL:         server.URL,
+		LockfileOnly:    true,
+		SkipGitMetadata: true,
+		Context:         context.Background(),
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(affects) == 0 || affects[0] != "lodash" {
+		t.Errorf("GHSA queried for %v, want the discovered packages", affects)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].PackageName != "lodash" || result.Matches[0].IOCRange != "< 4.17.21" {
+		t.Errorf("Expected a range match for lodash@4.17.20, got %+v", result.Matches)
+	}
+
+	_, err = RunScan(ScanOptions{Path: tmpDir, Source: ioc.SourceGHSA, Offline: true, SkipGitMetadata: true})
+	if err == nil || !strings.Contains(err.Error(), "offline") {
+		t.Errorf("RunScan() of the ghsa source offline error = %v, want it refused", err)
+	}
+}
+
+// registeredSource is a Source returning fixed entries.
+type registeredSource []ioc.Entry
+
+func (s registeredSource) Fetch(ctx context.Context) ([]ioc.Entry, error) {
+	return append([]ioc.Entry(nil), s...), nil
+}
+
+// TestRunScan_RegisteredSource tests that sources added with
+// ioc.RegisterSource can be selected and combined with built-in ones
+func TestRunScan_RegisteredSource(t *testing.T) {
+	if err := ioc.RegisterSource("scanner-test", registeredSource{{Package: "evil", Version: "1.0.1"}}); err != nil {
+		t.Fatalf("RegisterSource failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	csvDB := ioc.NewDatabaseFromEntries([]ioc.Entry{{Package: "evil", Version: "1.0.1", Source: ioc.SourceCSV}})
+	result, err := RunScan(ScanOptions{Path: dir, Source: "csv,scanner-test", Database: csvDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" {
+		t.Fatalf("Expected a single match for evil@1.0.1, got %+v", result.Matches)
+	}
+	if sources := result.Matches[0].Sources; len(sources) != 2 || sources[0].Source != ioc.SourceCSV || sources[1].Source != "scanner-test" {
+		t.Errorf("Match sources = %+v, want csv and the registered source", sources)
+	}
+
+	// Registered sources need not use the network
+	result, err = RunScan(ScanOptions{Path: dir, Source: "scanner-test", Offline: true, SkipGitMetadata: true})
+	if err != nil || len(result.Matches) != 1 {
+		t.Errorf("RunScan() of a registered source offline = %+v, %v", result, err)
+	}
+
+	checks, err := CheckPackages(ScanOptions{Source: "scanner-test"}, []ioc.PackageVersion{{Name: "evil", Version: "1.0.1"}, {Name: "evil", Version: "1.0.2"}})
+	if err != nil {
+		t.Fatalf("CheckPackages failed: %v", err)
+	}
+	if len(checks) != 2 || !checks[0].Compromised || checks[1].Compromised {
+		t.Errorf("CheckPackages() = %+v, want only evil@1.0.1 compromised", checks)
+	}
+}
+
+// TestRunScan_DatabaseUnavailable tests that fetch failures are told apart
+// from other scan errors.
+func TestRunScan_DatabaseUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	_, err := RunScan(ScanOptions{Path: t.TempDir(), CSVURL: server.URL, SkipGitMetadata: true})
+	if !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Errorf("RunScan() error = %v, want ErrDatabaseUnavailable", err)
+	}
+
+	// A proxy error page is as unusable as no feed at all
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><body>Access denied</body></html>\n"))
+	}))
+	defer page.Close()
+
+	_, err = RunScan(ScanOptions{Path: t.TempDir(), CSVURL: page.URL, SkipGitMetadata: true})
+	if !errors.Is(err, ErrDatabaseUnavailable) || !strings.Contains(err.Error(), "sanity checks") {
+		t.Errorf("RunScan() of an HTML page error = %v, want a failed sanity check", err)
+	}
+	_, err = RunScan(ScanOptions{Path: t.TempDir(), CSVURL: page.URL, FeedCheck: ioc.FeedCheck{Mode: ioc.FeedCheckOff}, SkipGitMetadata: true})
+	if err != nil {
+		t.Errorf("RunScan() with feed checks off error = %v", err)
+	}
+
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Package,Version\nevil,= 1.0.1\n"))
+	}))
+	defer feed.Close()
+	private := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("Package,Version\nevil,= 1.0.1\n"))
+	}))
+	defer private.Close()
+	_, err = RunScan(ScanOptions{Path: t.TempDir(), CSVURL: private.URL, SkipGitMetadata: true})
+	if !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Errorf("RunScan() of a private feed without credentials error = %v, want ErrDatabaseUnavailable", err)
+	}
+	header := http.Header{"Authorization": {"Bearer secret"}}
+	_, err = RunScan(ScanOptions{Path: t.TempDir(), CSVURL: private.URL, CSVHeader: header, SkipGitMetadata: true})
+	if err != nil {
+		t.Errorf("RunScan() of a private feed with credentials error = %v", err)
+	}
+
+	pinned := ioc.FeedCheck{Mode: ioc.FeedCheckOff, SHA256: strings.Repeat("0", 64)}
+	_, err = RunScan(ScanOptions{Path: t.TempDir(), CSVURL: feed.URL, FeedCheck: pinned, SkipGitMetadata: true})
+	if !errors.Is(err, ErrDatabaseUnavailable) || !strings.Contains(err.Error(), "integrity") {
+		t.Errorf("RunScan() of a feed not matching its pinned checksum error = %v, want a failed integrity verification", err)
+	}
+
+	_, err = RunScan(ScanOptions{Path: filepath.Join(t.TempDir(), "missing"), Offline: true, SkipGitMetadata: true})
+	if err == nil || errors.Is(err, ErrDatabaseUnavailable) {
+		t.Errorf("RunScan() of a missing path error = %v, want a non-database error", err)
+	}
+}
+
+// TestRunScan_OfflineCache tests that offline scans prefer the cached IoC
+// database of their feed to the embedded snapshot
+func TestRunScan_OfflineCache(t *testing.T) {
+	const url = "https://mirror.example.com/iocs.csv"
+	cache := ioc.OpenDBCache(t.TempDir())
+	fetched := time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)
+	if _, err := cache.Store(url, []byte("Package,Version\nevil,= 1.0.1\n"), ioc.FormatCSV, fetched); err != nil {
+		t.Fatalf("Store failed: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	result, err := RunScan(ScanOptions{Path: dir, CSVURL: url, Offline: true, DatabaseCache: cache, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" {
+		t.Errorf("Expected a match for evil@1.0.1 from the cached database, got %+v", result.Matches)
+	}
+	if result.IOCSnapshot == nil || !result.IOCSnapshot.Equal(fetched) {
+		t.Errorf("IOCSnapshot = %v, want the fetch time %v", result.IOCSnapshot, fetched)
+	}
+}
+
+// TestRunScan_StructuredFeed tests that JSON and YAML feeds are detected
+// and matched like CSV ones
+func TestRunScan_StructuredFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/iocs":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"packages": [{"package": "evil", "version": "1.0.1", "severity": "critical"}]}`))
+		case "/iocs.yaml":
+			w.Write([]byte("packages:\n  - package: evil\n    range: \"< 2.0.0\"\n    severity: high\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		url      string
+		severity string
+	}{
+		{server.URL + "/iocs", "critical"},
+		{server.URL + "/iocs.yaml", "high"},
+	}
+	for _, tt := range tests {
+		result, err := RunScan(ScanOptions{Path: dir, CSVURL: tt.url, SkipGitMetadata: true})
+		if err != nil {
+			t.Fatalf("RunScan(%s) failed: %v", tt.url, err)
+		}
+		if len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" {
+			t.Fatalf("RunScan(%s) expected a match for evil@1.0.1, got %+v", tt.url, result.Matches)
+		}
+		if advisory := result.Matches[0].Advisory; advisory == nil || advisory.Severity != tt.severity {
+			t.Errorf("RunScan(%s) advisory = %+v, want severity %q", tt.url, advisory, tt.severity)
+		}
+	}
+}
+
+// TestRunScan_Workers tests that concurrent parsing yields the same result
+// as a serial scan
+func TestRunScan_Workers(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\nchalk,= 5.6.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	files := map[string]string{}
+	for i := 0; i < 20; i++ {
+		dir := fmt.Sprintf("packages/p%02d/", i)
+		files[dir+"package.json"] = fmt.Sprintf(`{"name": "p%02d", "dependencies": {"evil": "1.0.1", "wrapper": "^1.0.0"}}`, i)
+		files[dir+"package-lock.json"] = `{"lockfileVersion": 3, "packages": {
+			"": {"dependencies": {"wrapper": "^1.0.0"}},
+			"node_modules/wrapper": {"version": "1.0.0", "dependencies": {"chalk": "^5.0.0"}},
+			"node_modules/chalk": {"version": "5.6.1"}
+		}}`
+	}
+	files["packages/broken/package.json"] = `{not json`
+	root := writeTestFiles(t, files)
+
+	scan := func(workers int) *formatter.ScanResult {
+		result, err := RunScan(ScanOptions{Path: root, Database: iocDB, PerProject: true, SkipGitMetadata: true, NumWorkers: workers})
+		if err != nil {
+			t.Fatalf("RunScan(NumWorkers: %d) failed: %v", workers, err)
+		}
+		return result
+	}
+
+	serial, parallel := scan(1), scan(8)
+	// Each match merges the evidence of all 20 projects, in discovery order
+	if len(serial.Matches) != 2 || len(serial.Matches[0].Evidence) != 20 || serial.PackagesChecked != 80 {
+		t.Errorf("Expected 2 matches with 20 occurrences in 80 packages, got %+v in %d", serial.Matches, serial.PackagesChecked)
+	}
+	if !reflect.DeepEqual(serial.Matches, parallel.Matches) || !reflect.DeepEqual(serial.Projects, parallel.Projects) || serial.PackagesChecked != parallel.PackagesChecked {
+		t.Errorf("Concurrent scan differs from serial scan:\nserial:   %+v\nparallel: %+v", serial.Matches, parallel.Matches)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunScan() with a canceled context error = %v, want context.Canceled", err)
+	}
+
+	// Canceled while files are being matched, even once all have started
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	progress := func(current, total int, file string) { cancel() }
+	if _, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, NumWorkers: 8, Progress: progress, Context: ctx}); !errors.Is(err, context.Canceled) {
+		t.Errorf("RunScan() canceled mid-scan error = %v, want context.Canceled", err)
+	}
+}
+
+// TestRunScan_DependencyStats tests counting checked dependencies by type
+// and file kind
+func TestRunScan_DependencyStats(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app",
+			"dependencies": {"express": "^4.18.0"},
+			"devDependencies": {"jest": "^29.0.0", "eslint": "^8.0.0"},
+			"peerDependencies": {"react": "^18.0.0"},
+			"optionalDependencies": {"fsevents": "^2.3.0"},
+			"bundledDependencies": ["express"]}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/express": {"version": "4.18.2"},
+			"node_modules/jest": {"version": "29.0.0", "dev": true},
+			"node_modules/fsevents": {"version": "2.3.3", "optional": true}
+		}}`,
+		"web/yarn.lock": "evil@^1.0.0:\n  version \"1.0.1\"\n",
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	want := &formatter.DependencyStats{
+		Manifest: formatter.DependencyCounts{Prod: 1, Dev: 2, Peer: 1, Optional: 1, Bundled: 1},
+		Lockfile: formatter.DependencyCounts{Prod: 1, Dev: 1, Optional: 1, Unknown: 1},
+	}
+	if !reflect.DeepEqual(result.DependencyStats, want) {
+		t.Errorf("DependencyStats = %+v, want %+v", result.DependencyStats, want)
+	}
+	if result.IOCDigest != iocDB.Digest() {
+		t.Errorf("IOCDigest = %q, want the database digest %q", result.IOCDigest, iocDB.Digest())
+	}
+	if total := want.Manifest.Total() + want.Lockfile.Total(); total != result.PackagesChecked {
+		t.Errorf("Dependency types add up to %d, want PackagesChecked %d", total, result.PackagesChecked)
+	}
+
+	merged := MergeResults([]formatter.RootResult{{Path: "a", Result: result}, {Path: "b", Result: result}})
+	if merged.DependencyStats.Manifest.Dev != 4 || merged.DependencyStats.Lockfile.Unknown != 2 || merged.DependencyStats.Inventory != nil {
+		t.Errorf("Expected merged stats to be summed, got %+v", merged.DependencyStats)
+	}
+}
+
+func TestRunScan_UncheckedBundled(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":                   `{"name": "app", "bundledDependencies": ["evil", "missing"]}`,
+		"node_modules/evil/package.json": `{"name": "evil", "version": "1.0.1"}`,
+	})
+
+	tests := []struct {
+		name          string
+		report        bool
+		wantUnchecked int
+	}{
+		{name: "installed bundles are always checked", report: false, wantUnchecked: 0},
+		{name: "missing bundles are reported on request", report: true, wantUnchecked: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, UncheckedBundled: tt.report})
+			if err != nil {
+				t.Fatalf("RunScan failed: %v", err)
+			}
+			if len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" || result.Matches[0].Severity != formatter.SeverityTransitive {
+				t.Errorf("Expected a TRANSITIVE match of the bundled evil, got %+v", result.Matches)
+			}
+			if len(result.UncheckedBundled) != tt.wantUnchecked {
+				t.Fatalf("UncheckedBundled = %+v, want %d findings", result.UncheckedBundled, tt.wantUnchecked)
+			}
+			if tt.wantUnchecked > 0 && result.UncheckedBundled[0].PackageName != "missing" {
+				t.Errorf("Expected missing to be unchecked, got %+v", result.UncheckedBundled)
+			}
+		})
+	}
+}
+
+func TestRunScan_Installed(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nchalk,= 5.6.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"chalk": "5.6.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/chalk": {"version": "5.6.0"},
+			"node_modules/debug": {"version": "4.3.4"}
+		}}`,
+		"node_modules/chalk/package.json":                 `{"name": "chalk", "version": "5.6.1"}`,
+		"node_modules/debug/package.json":                 `{"name": "debug", "version": "4.3.4"}`,
+		"node_modules/@scope/extra/package.json":          `{"name": "@scope/extra", "version": "1.0.0"}`,
+		"node_modules/.bin/placeholder":                   "",
+		"node_modules/debug/node_modules/ms/package.json": `{"name": "ms", "version": "2.1.2"}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) != 0 || result.Shadowed != nil {
+		t.Fatalf("Expected node_modules to be ignored by default, got %+v and %+v", result.Matches, result.Shadowed)
+	}
+
+	result, err = RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Installed: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) != 1 || result.Matches[0].PackageName != "chalk" || result.Matches[0].Severity != formatter.SeverityTransitive {
+		t.Errorf("Expected a TRANSITIVE match of the installed chalk, got %+v", result.Matches)
+	}
+
+	shadowed := make(map[string]string)
+	for _, finding := range result.Shadowed {
+		shadowed[finding.PackageName+"@"+finding.Version] = finding.Reason
+	}
+	want := map[string]string{
+		"chalk@5.6.1":        matcher.ReasonInstalledMismatch,
+		"@scope/extra@1.0.0": matcher.ReasonNotLocked,
+		"ms@2.1.2":           matcher.ReasonNotLocked,
+	}
+	if !reflect.DeepEqual(shadowed, want) {
+		t.Errorf("Shadowed = %v, want %v", shadowed, want)
+	}
+}
+
+// TestRunScan_Scripts tests SCRIPT findings of the scanned manifests and,
+// with Installed, of the installed packages
+func TestRunScan_Scripts(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version,Script\nevil,= 1.0.1,node bundle.js\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "version": "1.0.0", "scripts": {"postinstall": "node bundle.js", "test": "jest"},
+			"dependencies": {"dropper": "2.0.0"}}`,
+		"package-lock.json":                 `{"lockfileVersion": 3, "packages": {"node_modules/dropper": {"version": "2.0.0"}}}`,
+		"node_modules/dropper/package.json": `{"name": "dropper", "version": "2.0.0", "scripts": {"preinstall": "node  bundle.js"}}`,
+	})
+
+	scan := func(installed bool) []string {
+		t.Helper()
+		result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Installed: installed})
+		if err != nil {
+			t.Fatalf("RunScan failed: %v", err)
+		}
+		if !formatter.Fails(result, formatter.SeverityDirect) {
+			t.Error("Expected malicious scripts to fail the scan")
+		}
+		var got []string
+		for _, finding := range result.Scripts {
+			rel, _ := filepath.Rel(root, finding.Location)
+			got = append(got, fmt.Sprintf("%s@%s %s in %s", finding.PackageName, finding.Version, finding.Script, filepath.ToSlash(rel)))
+		}
+		return got
+	}
+
+	if got, want := scan(false), []string{"app@1.0.0 postinstall in package.json"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Scripts = %v, want %v", got, want)
+	}
+	want := []string{"app@1.0.0 postinstall in package.json", "dropper@2.0.0 preinstall in node_modules/dropper/package.json"}
+	if got := scan(true); !reflect.DeepEqual(got, want) {
+		t.Errorf("Scripts with Installed = %v, want %v", got, want)
+	}
+}
+
+// TestRunScan_ReadOnly tests that a scan with every file-based feature
+// enabled leaves the scanned tree untouched in read-only mode
+func TestRunScan_ReadOnly(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":                   `{"name": "app", "dependencies": {"evil": "1.0.1", "left-pad": "*"}, "bundledDependencies": ["gone"]}`,
+		"package-lock.json":              `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.1"}}}`,
+		"node_modules/evil/package.json": `{"name": "evil", "version": "1.0.1"}`,
+		".npmscanignore":                 "fixtures/\n",
+	})
+
+	snapshot := func() map[string]string {
+		files := make(map[string]string)
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files[path] = fmt.Sprintf("%v %d %v", info.Mode(), info.Size(), info.ModTime().UnixNano())
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return files
+	}
+
+	if err := readonly.Enable(""); err != nil {
+		t.Fatalf("Enable failed: %v", err)
+	}
+	defer readonly.Disable()
+
+	before := snapshot()
+	result, err := RunScan(ScanOptions{
+		Path:             root,
+		Database:         iocDB,
+		Hygiene:          true,
+		LockfileAge:      true,
+		ExposureWindow:   true,
+		PerProject:       true,
+		Installed:        true,
+		UncheckedBundled: true,
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) == 0 {
+		t.Error("Expected the read-only scan to still find matches")
+	}
+	if after := snapshot(); !reflect.DeepEqual(before, after) {
+		t.Errorf("Read-only scan modified the tree:\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+// TestRunScan_CompromisedScope tests that a compromised-scope entry matches
+// every package under the scope in manifests and lockfiles
+func TestRunScan_CompromisedScope(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\n@ctrl/*,= *\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"@ctrl/tinycolor": "4.1.1", "@ctrl/deluge": "^7.0.0", "chalk": "5.6.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/@ctrl/tinycolor": {"version": "4.1.1"},
+			"node_modules/@ctrl/deluge": {"version": "7.2.1"},
+			"node_modules/chalk": {"version": "5.6.0"}
+		}}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	var got []string
+	for _, match := range result.Matches {
+		rel, _ := filepath.Rel(root, match.Location)
+		got = append(got, fmt.Sprintf("%s %s@%s %s %s", match.Severity, match.PackageName, match.Version, match.IOCScope, filepath.ToSlash(rel)))
+	}
+	sort.Strings(got)
+	want := []string{
+		"DIRECT @ctrl/tinycolor@4.1.1 @ctrl/* package.json",
+		"POTENTIAL @ctrl/deluge@* @ctrl/* package.json",
+		"TRANSITIVE @ctrl/deluge@7.2.1 @ctrl/* package-lock.json",
+		"TRANSITIVE @ctrl/tinycolor@4.1.1 @ctrl/* package-lock.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Matches = %v, want %v", got, want)
+	}
+}
+
+// TestRunScan_Watchlist tests that watchlisted names are reported from every source, whatever their version
+func TestRunScan_Watchlist(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	list, err := watchlist.New([]string{"@mycorp-*", "*-utils-js"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"@mycorp-ui/button": "^2.0.0", "chalk": "5.6.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/@mycorp-ui/button": {"version": "2.1.0"},
+			"node_modules/array-utils-js": {"version": "1.0.0"}
+		}}`,
+		"node_modules/array-utils-js/package.json": `{"name": "array-utils-js", "version": "1.0.0"}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if result.Watchlist != nil {
+		t.Errorf("Expected no watchlist findings without a watchlist, got %+v", result.Watchlist)
+	}
+
+	result, err = RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Watchlist: list, Installed: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	var got []string
+	for _, finding := range result.Watchlist {
+		rel, _ := filepath.Rel(root, finding.Location)
+		got = append(got, finding.PackageName+"@"+finding.Version+" "+filepath.ToSlash(rel))
+	}
+	want := []string{
+		"@mycorp-ui/button@ package.json",
+		"@mycorp-ui/button@2.1.0 package-lock.json",
+		"array-utils-js@1.0.0 package-lock.json",
+		"array-utils-js@1.0.0 node_modules/array-utils-js/package.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Watchlist = %v, want %v", got, want)
+	}
+	if len(result.Matches) != 0 {
+		t.Errorf("Expected watchlist findings to stay out of Matches, got %+v", result.Matches)
+	}
+}
+
+// TestRunScan_Policy tests that versions below a required safe version are reported from every source
+func TestRunScan_Policy(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	required, err := policy.New([]string{"lodash@>=4.17.21"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"lodash": "4.17.20", "chalk": "5.6.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/lodash": {"version": "4.17.21"},
+			"node_modules/a/node_modules/lodash": {"version": "4.17.11"}
+		}}`,
+		"node_modules/lodash/package.json": `{"name": "lodash", "version": "4.17.15"}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if result.PolicyViolations != nil {
+		t.Errorf("Expected no policy violations without a policy, got %+v", result.PolicyViolations)
+	}
+
+	result, err = RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Policy: required, Installed: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	var got []string
+	for _, finding := range result.PolicyViolations {
+		rel, _ := filepath.Rel(root, finding.Location)
+		got = append(got, finding.PackageName+"@"+finding.Version+" "+filepath.ToSlash(rel))
+	}
+	want := []string{
+		"lodash@4.17.20 package.json",
+		"lodash@4.17.11 package-lock.json",
+		"lodash@4.17.15 node_modules/lodash/package.json",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PolicyViolations = %v, want %v", got, want)
+	}
+	if !formatter.Fails(result, formatter.SeverityDirect) {
+		t.Error("Expected policy violations to fail the scan")
+	}
+}
+
+// TestRunScan_Allowlist tests that strict mode reports every package the
+// allowlist does not approve
+func TestRunScan_Allowlist(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	approved, err := allowlist.New([]string{"lodash", "@mycorp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"lodash": "^4.17.21", "@mycorp/ui": "1.0.0", "left-pad": "^1.3.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/lodash": {"version": "4.17.21"},
+			"node_modules/@mycorp/ui": {"version": "1.0.0"},
+			"node_modules/left-pad": {"version": "1.3.0"},
+			"node_modules/@mycorp/ui/node_modules/is-odd": {"version": "3.0.1"}
+		}}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if result.Unapproved != nil {
+		t.Errorf("Expected no unapproved packages without an allowlist, got %+v", result.Unapproved)
+	}
+
+	result, err = RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Allowlist: approved})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	var got []string
+	for _, finding := range result.Unapproved {
+		rel, _ := filepath.Rel(root, finding.Location)
+		got = append(got, finding.PackageName+"@"+finding.Version+" "+filepath.ToSlash(rel))
+	}
+	want := []string{
+		"is-odd@3.0.1 package-lock.json",
+		"left-pad@ package.json",
+		"left-pad@1.3.0 package-lock.json",
+	}
+	// Lockfile packages are not resolved in a fixed order
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unapproved = %v, want %v", got, want)
+	}
+	if !formatter.Fails(result, formatter.SeverityDirect) {
+		t.Error("Expected unapproved packages to fail the scan")
+	}
+}
+
+// TestRunScan_Typosquat tests that declared dependencies named like popular
+// packages are reported as warnings, including from the result cache
+func TestRunScan_Typosquat(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"lodahs": "^4.17.21", "react_dom": "^18.0.0", "express": "^4.18.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {"name": "app"},
+			"node_modules/lodahs": {"version": "4.17.21"},
+			"node_modules/expres": {"version": "1.0.0"}
+		}}`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if result.Typosquats != nil {
+		t.Errorf("Expected no typosquats without detection, got %+v", result.Typosquats)
+	}
+
+	cache := OpenResultCache(t.TempDir())
+	for run := 0; run < 2; run++ {
+		result, err = RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Typosquat: typosquat.Default(), Cache: cache})
+		if err != nil {
+			t.Fatalf("RunScan failed: %v", err)
+		}
+		var got []string
+		for _, finding := range result.Typosquats {
+			got = append(got, finding.PackageName+" "+finding.Resembles+" "+string(finding.Severity))
+		}
+		sort.Strings(got)
+		// Only declared dependencies are checked
+		want := []string{"lodahs lodash WARNING", "react_dom react-dom WARNING"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d: Typosquats = %v, want %v", run, got, want)
+		}
+		if formatter.Fails(result, formatter.SeverityPotential) {
+			t.Errorf("run %d: Expected typosquats not to fail the scan", run)
+		}
+	}
+}
+
+// TestRunScan_Cache tests that unchanged files are served from the result
+// cache, and that changing a file or the IoC database invalidates it
+func TestRunScan_Cache(t *testing.T) {
+	evilDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	otherDB, err := ioc.NewDatabase([]byte("Package,Version\nother,= 2.0.0\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":      `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.1"}}}`,
+	})
+	cache := OpenResultCache(t.TempDir())
+
+	// entries returns the paths of the cache entries
+	entries := func() []string {
+		var paths []string
+		filepath.WalkDir(cache.Dir(), func(path string, d os.DirEntry, err error) error {
+			if err == nil && !d.IsDir() {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		return paths
+	}
+	scan := func(options ScanOptions) *formatter.ScanResult {
+		t.Helper()
+		options.Path = root
+		options.SkipGitMetadata = true
+		options.Cache = cache
+		result, err := RunScan(options)
+		if err != nil {
+			t.Fatalf("RunScan failed: %v", err)
+		}
+		return result
+	}
+
+	first := scan(ScanOptions{Database: evilDB})
+	if len(first.Matches) != 2 {
+		t.Fatalf("Expected 2 matches, got %d", len(first.Matches))
+	}
+	if got := len(entries()); got != 2 {
+		t.Fatalf("Expected 2 cache entries, got %d", got)
+	}
+
+	// Emptied entries prove the second scan is served from the cache
+	for _, path := range entries() {
+		if err := os.WriteFile(path, []byte(`{"packages": 0, "counts": {}}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if cached := scan(ScanOptions{Database: evilDB}); len(cached.Matches) != 0 {
+		t.Errorf("Expected the cached (emptied) results, got %d matches", len(cached.Matches))
+	}
+
+	// A different IoC database or different options miss the cache
+	if result := scan(ScanOptions{Database: otherDB}); len(result.Matches) != 0 {
+		t.Errorf("Expected no matches against the other database, got %d", len(result.Matches))
+	}
+	if result := scan(ScanOptions{Database: evilDB, Hygiene: true}); len(result.Matches) != 2 {
+		t.Errorf("Expected a rescan with hygiene to find 2 matches, got %d", len(result.Matches))
+	}
+
+	// A changed file misses the cache
+	manifest := filepath.Join(root, "package.json")
+	if err := os.WriteFile(manifest, []byte(`{"name": "app", "dependencies": {"evil": "1.0.1", "lodash": "4.17.21"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if result := scan(ScanOptions{Database: evilDB}); len(result.Matches) != 1 {
+		t.Errorf("Expected only the changed manifest to be rescanned, got %d matches", len(result.Matches))
+	}
+
+	// Installed packages are not part of the lockfile's contents
+	before := len(entries())
+	scan(ScanOptions{Database: evilDB, Installed: true})
+	if got := len(entries()); got != before {
+		t.Errorf("Expected installed-mode lockfile results not to be cached, entries went from %d to %d", before, got)
+	}
+}
+
+// TestRunScan_OnMatch tests that matches are streamed per file, with the
+// fingerprints of the final result
+func TestRunScan_OnMatch(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":          `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"package-lock.json":     `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.1"}}}`,
+		"clean/package.json":    `{"name": "clean", "dependencies": {"lodash": "4.17.21"}}`,
+		"nested/a/package.json": `{"name": "a", "devDependencies": {"evil": "1.0.1"}}`,
+	})
+
+	streamed := make(map[string]int)
+	fingerprints := make(map[string]bool)
+	result, err := RunScan(ScanOptions{
+		Path:            root,
+		Database:        iocDB,
+		SkipGitMetadata: true,
+		NumWorkers:      4,
+		OnMatch: func(file string, matches []formatter.Match) {
+			rel, _ := filepath.Rel(root, file)
+			streamed[filepath.ToSlash(rel)] += len(matches)
+			for _, match := range matches {
+				fingerprints[match.Fingerprint] = true
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	want := map[string]int{"package.json": 1, "package-lock.json": 1, "nested/a/package.json": 1}
+	if !reflect.DeepEqual(streamed, want) {
+		t.Errorf("streamed matches = %v, want %v", streamed, want)
+	}
+	for _, match := range result.Matches {
+		if !fingerprints[match.Fingerprint] {
+			t.Errorf("Match %s@%s %s was not streamed", match.PackageName, match.Version, match.Location)
+		}
+	}
+}
+
+// lockedLogger is a Logger collecting messages from concurrent workers
+type lockedLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *lockedLogger) Printf(format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+// TestRunScan_Logger tests that verbose output goes to the Logger instead
+// of stdout
+func TestRunScan_Logger(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":        `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"package-lock.json":   `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.1"}}}`,
+		"broken/package.json": `{`,
+	})
+
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe failed: %v", err)
+	}
+	os.Stdout = w
+	logger := &lockedLogger{}
+	_, scanErr := RunScan(ScanOptions{
+		Path:            root,
+		Database:        iocDB,
+		SkipGitMetadata: true,
+		NumWorkers:      4,
+		Verbose:         true,
+		Logger:          logger,
+	})
+	os.Stdout = stdout
+	w.Close()
+	written, _ := io.ReadAll(r)
+	if scanErr != nil {
+		t.Fatalf("RunScan failed: %v", scanErr)
+	}
+
+	if len(written) > 0 {
+		t.Errorf("RunScan wrote to stdout: %q", written)
+	}
+	logged := strings.Join(logger.messages, "")
+	for _, want := range []string{"Discovering package.json files", "Parsing " + filepath.Join(root, "package-lock.json"), "Warning: failed to parse", "Found 2 matches"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("Logged output missing %q:\n%s", want, logged)
+		}
+	}
+}
+
+// TestRunScan_Warnings tests that files that cannot be parsed are reported
+// in the result, also without verbose output
+func TestRunScan_Warnings(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":             `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"broken/package.json":      `{`,
+		"broken/package-lock.json": `not json`,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	var skipped []string
+	for _, warning := range result.Warnings {
+		if warning.Error == "" {
+			t.Errorf("Warning for %s has no error", warning.Path)
+		}
+		rel, _ := filepath.Rel(root, warning.Path)
+		skipped = append(skipped, filepath.ToSlash(rel))
+	}
+	want := []string{"broken/package.json", "broken/package-lock.json"}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("Warnings = %v, want %v", skipped, want)
+	}
+	if len(result.Matches) != 1 {
+		t.Errorf("Expected the match of the readable manifest, got %d matches", len(result.Matches))
+	}
+}
+
+// TestRunScan_RegistryStatus tests reporting direct dependencies that are
+// deprecated or unpublished, checking the versions locked next to each
+// manifest
+func TestRunScan_RegistryStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/request":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "2.88.2"}, "versions": {"2.88.0": {"deprecated": "no longer maintained"}, "2.88.2": {}}}`)
+		case "/evil":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "1.0.0"}, "versions": {"1.0.0": {}}}`)
+		case "/chalk":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "5.6.2"}, "versions": {"5.6.2": {}}}`)
+		case "/broken":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"request": "^2.88.0", "evil": "1.0.1", "chalk": "^5.0.0",
+			"gone": "^1.0.0", "broken": "1.0.0", "local": "file:../local"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {"node_modules/request": {"version": "2.88.0"}}}`,
+	})
+
+	var warnings strings.Builder
+	result, err := RunScan(ScanOptions{
+		Path:            root,
+		Database:        iocDB,
+		Registry:        &registry.Client{URL: server.URL},
+		Logger:          NewWriterLogger(&warnings),
+		SkipGitMetadata: true,
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	var got []string
+	for _, finding := range result.RegistryStatus {
+		got = append(got, fmt.Sprintf("%s %s@%s", finding.Severity, finding.PackageName, finding.Version))
+	}
+	want := []string{"UNPUBLISHED evil@1.0.1", "UNPUBLISHED gone@", "DEPRECATED request@2.88.0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RegistryStatus = %v, want %v", got, want)
+	}
+	if !strings.Contains(warnings.String(), "broken: HTTP 503") {
+		t.Errorf("Expected a warning about the failed lookup, got %q", warnings.String())
+	}
+}
+
+// TestRunScan_ResolvePotential tests that POTENTIAL matches are only kept
+// for the compromised versions npm would install
+func TestRunScan_ResolvePotential(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/patched":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "1.0.2"}, "versions": {"1.0.0": {}, "1.0.1": {}, "1.0.2": {}}}`)
+		case "/current":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "2.0.1"}, "versions": {"2.0.0": {}, "2.0.1": {}}}`)
+		case "/tagged":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "1.2.3"}, "versions": {"1.2.3": {}, "1.4.0": {}}}`)
+		case "/avoided":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "3.0.0"}, "versions": {"1.0.0": {}, "1.0.1": {}, "1.1.0": {"deprecated": "broken build"}, "3.0.0": {}}}`)
+		case "/@ctrl/tinycolor":
+			fmt.Fprint(w, `{"dist-tags": {"latest": "4.1.0"}, "versions": {"4.1.0": {}}}`)
+		case "/broken":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\npatched,= 1.0.1\ncurrent,= 2.0.1\nbroken,= 1.0.1\ngone,= 1.0.1\n@ctrl/*,= *\n" +
+		"tagged,= 1.2.3\navoided,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"patched": "^1.0.0", "current": "^2.0.0", "broken": "^1.0.0",
+			"gone": "^1.0.0", "@ctrl/tinycolor": "^4.0.0", "tagged": "^1.0.0", "avoided": "^1.0.0"}}`,
+	})
+
+	var warnings strings.Builder
+	result, err := RunScan(ScanOptions{
+		Path:             root,
+		Database:         iocDB,
+		ResolvePotential: &registry.Client{URL: server.URL},
+		PerProject:       true,
+		Logger:           NewWriterLogger(&warnings),
+		SkipGitMetadata:  true,
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	var got []string
+	for _, match := range result.Matches {
+		got = append(got, match.PackageName+"@"+match.Version)
+	}
+	sort.Strings(got)
+	// tagged installs its latest, not the higher 1.4.0, and avoided skips
+	// the deprecated 1.1.0
+	want := []string{"@ctrl/tinycolor@*", "avoided@1.0.1", "broken@1.0.1", "current@2.0.1", "gone@1.0.1", "tagged@1.2.3"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Matches = %v, want %v", got, want)
+	}
+	if len(result.Projects) != 1 || len(result.Projects[0].Matches) != len(want) {
+		t.Errorf("Projects = %+v, want the %d kept matches", result.Projects, len(want))
+	}
+	if !strings.Contains(warnings.String(), "broken: HTTP 503") {
+		t.Errorf("Expected a warning about the failed lookup, got %q", warnings.String())
+	}
+}
+
+// TestRunScan_Provenance tests the provenance check of matched packages
+// and, optionally, of every direct dependency
+func TestRunScan_Provenance(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/evil/1.0.1", "/plain/3.0.0", "/locked/1.2.0":
+			fmt.Fprint(w, `{"dist": {"integrity": "sha512-AAAA"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"evil": "1.0.1", "plain": "3.0.0", "locked": "^1.0.0",
+			"gone": "1.0.0", "local": "file:../local"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {"node_modules/locked": {"version": "1.2.0"}}}`,
+	})
+
+	for _, tt := range []struct {
+		all  bool
+		want []string
+	}{
+		{false, []string{"evil@1.0.1"}},
+		{true, []string{"evil@1.0.1", "locked@1.2.0", "plain@3.0.0"}},
+	} {
+		result, err := RunScan(ScanOptions{
+			Path:            root,
+			Database:        iocDB,
+			Provenance:      &provenance.Client{URL: server.URL},
+			ProvenanceAll:   tt.all,
+			SkipGitMetadata: true,
+		})
+		if err != nil {
+			t.Fatalf("RunScan failed: %v", err)
+		}
+
+		var got []string
+		for _, finding := range result.Provenance {
+			if finding.Severity != formatter.SeverityProvenance || finding.Reason != "published without provenance" {
+				t.Errorf("Unexpected finding %+v", finding)
+			}
+			got = append(got, finding.PackageName+"@"+finding.Version)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Provenance (all=%v) = %v, want %v", tt.all, got, tt.want)
+		}
+	}
+}
+
+// TestRunScan_Maintainers tests the report of package versions published
+// or maintained by a flagged account
+func TestRunScan_Maintainers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hijacked/1.0.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "mallory"}, "maintainers": [{"name": "mallory"}]}`)
+		case "/shared/2.1.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "alice"}, "maintainers": [{"name": "alice"}, {"name": "mallory"}]}`)
+		case "/clean/3.0.0":
+			fmt.Fprint(w, `{"_npmUser": {"name": "alice"}, "maintainers": [{"name": "alice"}]}`)
+		case "/broken/1.0.0":
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	list, err := maintainer.New([]string{"mallory"})
+	if err != nil {
+		t.Fatalf("maintainer.New failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"hijacked": "1.0.0", "clean": "3.0.0", "broken": "1.0.0", "shared": "^2.0.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {"node_modules/hijacked": {"version": "1.0.0"},
+			"node_modules/shared": {"version": "2.1.0"}}}`,
+	})
+
+	var warnings strings.Builder
+	result, err := RunScan(ScanOptions{
+		Path:            root,
+		Database:        iocDB,
+		Maintainers:     &maintainer.Checker{Registry: &registry.Client{URL: server.URL}, List: list},
+		Logger:          NewWriterLogger(&warnings),
+		SkipGitMetadata: true,
+	})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+
+	var got []string
+	for _, finding := range result.Maintainers {
+		if finding.Severity != formatter.SeverityMaintainer {
+			t.Errorf("Unexpected finding %+v", finding)
+		}
+		got = append(got, fmt.Sprintf("%s@%s in %s: %s", finding.PackageName, finding.Version, filepath.Base(finding.Location), finding.Reason))
+	}
+	want := []string{
+		"shared@2.1.0 in package-lock.json: maintained by flagged account mallory",
+		"hijacked@1.0.0 in package.json: published by flagged account mallory",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Maintainers = %v, want %v", got, want)
+	}
+	if !strings.Contains(warnings.String(), "broken@1.0.0: HTTP 503") {
+		t.Errorf("Expected a warning about the failed lookup, got %q", warnings.String())
+	}
+}
+
+// TestRunScan_PnP tests TRANSITIVE matching of the packages located by the
+// Yarn PnP loader of a zero-install repository without yarn.lock
+func TestRunScan_PnP(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	state := `{"packageRegistryData": [
+		[null, [[null, {"packageLocation": "./", "linkType": "SOFT"}]]],
+		["evil", [["virtual:abc123#npm:1.0.1", {"packageLocation": "./.yarn/__virtual__/evil/", "linkType": "HARD"}]]],
+		["safe", [["npm:2.0.0", {"packageLocation": "./.yarn/cache/safe/", "linkType": "HARD"}]]]]}`
+	loader := "#!/usr/bin/env node\nconst RAW_RUNTIME_STATE =\n'" + strings.ReplaceAll(state, "\n", "\\\n") + "';\n"
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"safe": "^2.0.0"}}`,
+		".pnp.cjs":     loader,
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if result.LockfilesScanned != 1 || result.PackagesChecked != 3 {
+		t.Errorf("Expected 1 lockfile and 3 packages checked, got %d and %d", result.LockfilesScanned, result.PackagesChecked)
+	}
+	if len(result.Matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d: %+v", len(result.Matches), result.Matches)
+	}
+	match := result.Matches[0]
+	if match.PackageName != "evil" || match.Version != "1.0.1" || match.Severity != formatter.SeverityTransitive || filepath.Base(match.Location) != ".pnp.cjs" {
+		t.Errorf("Unexpected match %+v", match)
+	}
+}
+
+// TestRunScan_Overrides tests that versions forced by overrides are matched
+// and located at their override key
+func TestRunScan_Overrides(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json": "{\n  \"name\": \"app\",\n  \"dependencies\": {\"safe\": \"1.0.0\"},\n  \"overrides\": {\n    \"safe\": {\n      \"evil\": \"1.0.1\"\n    }\n  }\n}\n",
+	})
+
+	result, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true})
+	if err != nil {
+		t.Fatalf("RunScan failed: %v", err)
+	}
+	if len(result.Matches) != 1 {
+		t.Fatalf("Expected 1 match, got %d: %+v", len(result.Matches), result.Matches)
+	}
+	match := result.Matches[0]
+	if match.PackageName != "evil" || match.Severity != formatter.SeverityDirect || match.Override != "overrides > safe > evil" {
+		t.Errorf("Unexpected match %+v", match)
+	}
+	if len(match.Evidence) != 1 || match.Evidence[0].Line != 6 {
+		t.Errorf("Expected evidence at line 6, got %+v", match.Evidence)
+	}
+}
+
+// TestScan_PreloadedDatabase tests that every kind of scan uses a preloaded
+// database, built from entries of the caller's own feed, without fetching
+func TestScan_PreloadedDatabase(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		http.Error(w, "unexpected fetch", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	iocDB := ioc.NewDatabaseFromEntries([]ioc.Entry{{Package: "evil", Version: "1.0.1", Source: "internal-feed"}})
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"bom.json":     `{"bomFormat": "CycloneDX", "components": [{"name": "evil", "version": "1.0.1", "purl": "pkg:npm/evil@1.0.1"}]}`,
+	})
+	options := ScanOptions{CSVURL: server.URL, Database: iocDB, SkipGitMetadata: true}
+
+	tests := []struct {
+		name string
+		scan func(options ScanOptions) (*formatter.ScanResult, error)
+	}{
+		{"directory", func(options ScanOptions) (*formatter.ScanResult, error) {
+			options.Path = root
+			return RunScan(options)
+		}},
+		{"sbom", func(options ScanOptions) (*formatter.ScanResult, error) {
+			options.Path = filepath.Join(root, "bom.json")
+			return RunSBOMScan(options)
+		}},
+		{"content", func(options ScanOptions) (*formatter.ScanResult, error) {
+			return ScanContent(options, "package.json", []byte(`{"dependencies": {"evil": "1.0.1"}}`))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.scan(options)
+			if err != nil {
+				t.Fatalf("scan failed: %v", err)
+			}
+			if len(result.Matches) != 1 || result.Matches[0].PackageName != "evil" {
+				t.Errorf("Expected a match of evil from the preloaded database, got %+v", result.Matches)
+			}
+			if result.IOCDigest != iocDB.Digest() {
+				t.Errorf("IOCDigest = %s, want the preloaded database's %s", result.IOCDigest, iocDB.Digest())
+			}
+		})
+	}
+
+	checks, err := CheckPackages(options, []ioc.PackageVersion{{Name: "evil", Version: "1.0.1"}})
+	if err != nil {
+		t.Fatalf("CheckPackages failed: %v", err)
+	}
+	if len(checks) != 1 || !checks[0].Compromised {
+		t.Errorf("Expected evil@1.0.1 to be compromised, got %+v", checks)
+	}
+
+	if got := fetches.Load(); got != 0 {
+		t.Errorf("Expected no fetches with a preloaded database, got %d", got)
+	}
+}
+
+// TestRunScan_SingleFile tests scanning one explicitly given manifest or lockfile
+func TestRunScan_SingleFile(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	root := writeTestFiles(t, map[string]string{
+		"package.json":          `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+		"package-lock.json":     `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/evil": {"version": "1.0.1"}}}`,
+		"sub/yarn.lock":         "evil@^1.0.0:\n  version \"1.0.1\"\n",
+		"pnpm-lock.yaml":        "lockfileVersion: '9.0'\n",
+		"nested/package.json":   `{"name": "nested", "dependencies": {"evil": "1.0.1"}}`,
+		"nested/.npmscanignore": "package.json\n",
+	})
+
+	tests := []struct {
+		name         string
+		file         string
+		lockfileOnly bool
+		wantErr      bool
+		manifests    int
+		lockfiles    int
+		severity     formatter.Severity
+		noLockfile   int
+	}{
+		{name: "manifest", file: "package.json", manifests: 1, severity: formatter.SeverityDirect},
+		{name: "npm lockfile", file: "package-lock.json", lockfiles: 1, severity: formatter.SeverityTransitive},
+		{name: "yarn lockfile", file: "sub/yarn.lock", lockfiles: 1, severity: formatter.SeverityTransitive},
+		{name: "lockfile in lockfile-only mode", file: "package-lock.json", lockfileOnly: true, lockfiles: 1, severity: formatter.SeverityTransitive},
+		{name: "manifest excluded by its ignore file, without a lockfile", file: "nested/package.json", manifests: 1, severity: formatter.SeverityDirect, noLockfile: 1},
+		{name: "manifest in lockfile-only mode", file: "package.json", lockfileOnly: true, wantErr: true},
+		{name: "unsupported file", file: "pnpm-lock.yaml", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunScan(ScanOptions{
+				Path:            filepath.Join(root, filepath.FromSlash(tt.file)),
+				Database:        iocDB,
+				LockfileOnly:    tt.lockfileOnly,
+				Hygiene:         true,
+				SkipGitMetadata: true,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunScan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if result.ManifestsScanned != tt.manifests || result.LockfilesScanned != tt.lockfiles {
+				t.Errorf("scanned %d manifests and %d lockfiles, want %d and %d",
+					result.ManifestsScanned, result.LockfilesScanned, tt.manifests, tt.lockfiles)
+			}
+			if len(result.Matches) != 1 || result.Matches[0].Severity != tt.severity {
+				t.Errorf("Matches = %+v, want one %s match", result.Matches, tt.severity)
+			}
+			// The lockfile beside a manifest counts, though only the manifest is scanned
+			noLockfile := 0
+			for _, finding := range result.Hygiene {
+				if finding.Reason == matcher.ReasonNoLockfile {
+					noLockfile++
+				}
+			}
+			if noLockfile != tt.noLockfile {
+				t.Errorf("Hygiene = %+v, want %d missing lockfile findings", result.Hygiene, tt.noLockfile)
+			}
+		})
+	}
+}
+
+// TestRunScan_Progress tests that every file is reported once, in order of
+// completion, whatever the number of workers
+func TestRunScan_Progress(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	files := map[string]string{}
+	for i := 0; i < 10; i++ {
+		dir := fmt.Sprintf("p%d/", i)
+		files[dir+"package.json"] = `{"dependencies": {"evil": "1.0.1"}}`
+		files[dir+"package-lock.json"] = `{"lockfileVersion": 3, "packages": {"node_modules/evil": {"version": "1.0.1"}}}`
+	}
+	root := writeTestFiles(t, files)
+
+	for _, lockfileOnly := range []bool{false, true} {
+		var currents []int
+		reported := make(map[string]bool)
+		_, err := RunScan(ScanOptions{
+			Path:            root,
+			Database:        iocDB,
+			LockfileOnly:    lockfileOnly,
+			NumWorkers:      4,
+			SkipGitMetadata: true,
+			Progress: func(current, total int, file string) {
+				wantTotal := 20
+				if lockfileOnly {
+					wantTotal = 10
+				}
+				if total != wantTotal {
+					t.Errorf("Progress total = %d, want %d", total, wantTotal)
+				}
+				currents = append(currents, current)
+				reported[file] = true
+			},
+		})
+		if err != nil {
+			t.Fatalf("RunScan failed: %v", err)
+		}
+
+		for i, current := range currents {
+			if current != i+1 {
+				t.Fatalf("Progress currents = %v, want 1..%d in order", currents, len(currents))
+			}
+		}
+		if len(reported) != len(currents) || (lockfileOnly && len(currents) != 10) || (!lockfileOnly && len(currents) != 20) {
+			t.Errorf("lockfileOnly=%v: %d progress calls for %d distinct files", lockfileOnly, len(currents), len(reported))
+		}
+	}
+}
+
+// TestScanFiles tests that results keep the order of the paths whatever
+// order the workers finish in
+func TestScanFiles(t *testing.T) {
+	var paths []string
+	for i := 0; i < 50; i++ {
+		paths = append(paths, fmt.Sprint(i))
+	}
+
+	results, err := scanFiles(context.Background(), paths, 4, func(path string) fileResult {
+		n, _ := strconv.Atoi(path)
+		time.Sleep(time.Duration(50-n) * 10 * time.Microsecond)
+		return fileResult{name: path, packages: n}
+	})
+	if err != nil {
+		t.Fatalf("scanFiles failed: %v", err)
+	}
+	for i, r := range results {
+		if r.name != paths[i] || r.packages != i {
+			t.Errorf("results[%d] = %+v, want the result of %s", i, r, paths[i])
+		}
+	}
+
+	if results, err := scanFiles(context.Background(), nil, 0, nil); err != nil || len(results) != 0 {
+		t.Errorf("scanFiles() of no paths = %v, %v", results, err)
+	}
+}
+
+// TestScanWithin tests giving up on files that run past the file timeout
+// or the scan's cancellation
+func TestScanWithin(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	stuck := func(ScanOptions) fileResult {
+		<-block
+		return fileResult{name: "too late"}
+	}
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name    string
+		options ScanOptions
+		scan    func(ScanOptions) fileResult
+		want    string
+		wantErr error
+	}{
+		{"no limit", ScanOptions{Context: context.Background()}, func(ScanOptions) fileResult { return fileResult{name: "app"} }, "app", nil},
+		{"within the file timeout", ScanOptions{Context: context.Background(), FileTimeout: time.Minute}, func(options ScanOptions) fileResult {
+			if _, ok := options.Context.Deadline(); !ok {
+				return fileResult{err: errors.New("no deadline")}
+			}
+			return fileResult{name: "app"}
+		}, "app", nil},
+		{"past the file timeout", ScanOptions{Context: context.Background(), FileTimeout: 10 * time.Millisecond}, stuck, "", context.DeadlineExceeded},
+		{"canceled scan", ScanOptions{Context: canceled, FileTimeout: time.Minute}, stuck, "", context.Canceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := scanWithin(tt.options, tt.scan)
+			if result.name != tt.want || !errors.Is(result.err, tt.wantErr) || (tt.wantErr == nil) != (result.err == nil) {
+				t.Errorf("scanWithin() = %q, %v; want %q, %v", result.name, result.err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRunScan_Timeout tests that a scan running past its timeout fails with
+// a deadline error
+func TestRunScan_Timeout(t *testing.T) {
+	iocDB := ioc.NewDatabaseFromEntries([]ioc.Entry{{Package: "evil", Version: "1.0.1"}})
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "app", "dependencies": {"evil": "1.0.1"}}`,
+	})
+
+	_, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Timeout: time.Nanosecond})
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "timed out after 1ns") {
+		t.Errorf("RunScan() error = %v, want a timeout", err)
+	}
+
+	if _, err := RunScan(ScanOptions{Path: root, Database: iocDB, SkipGitMetadata: true, Timeout: time.Minute, FileTimeout: time.Minute}); err != nil {
+		t.Errorf("RunScan() within the timeouts failed: %v", err)
+	}
+}
+
+// TestRunScan_UnknownSource tests that an unknown IoC source is rejected.
+func TestRunScan_UnknownSource(t *testing.T) {
+	_, err := RunScan(ScanOptions{Path: t.TempDir(), Source: "nvd"})
+	if err == nil {
+		t.Error("expected error for unknown source")
+	}
+}
+
+// TestFingerprint tests that fingerprints follow dependency file contents.
+func TestFingerprint(t *testing.T) {
+	tmpDir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fingerprint := func(lockfileOnly bool) string {
+		t.Helper()
+		fp, err := Fingerprint(tmpDir, lockfileOnly, FindOptions{})
+		if err != nil {
+			t.Fatalf("Fingerprint failed: %v", err)
+		}
+		return fp
+	}
+
+	write("package.json", `{"name": "app"}`)
+	write("package-lock.json", `{"lockfileVersion": 3}`)
+	base := fingerprint(false)
+
+	write("README.md", "unrelated")
+	write("node_modules/dep/package.json", `{"name": "dep"}`)
+	if got := fingerprint(false); got != base {
+		t.Errorf("unrelated files changed the fingerprint")
+	}
+
+	write("package.json", `{"name": "app", "dependencies": {"chalk": "5.6.1"}}`)
+	changed := fingerprint(false)
+	if changed == base {
+		t.Errorf("manifest change did not change the fingerprint")
+	}
+	if lockfileOnly := fingerprint(true); lockfileOnly == changed {
+		t.Errorf("lockfile-only fingerprint should ignore manifests")
+	}
+
+	if _, err := Fingerprint(filepath.Join(tmpDir, "missing"), false, FindOptions{}); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
+
 // TestIsYarnLockfile tests the yarn.lock file detection
 func TestIsYarnLockfile(t *testing.T) {
 	tests := []struct {
-		name     string
-		path     string
-		expected bool
+		name     string
+		path     string
+		expected bool
+	}{
+		{
+			name:     "yarn.lock file",
+			path:     "/path/to/yarn.lock",
+			expected: true,
+		},
+		{
+			name:     "package-lock.json file",
+			path:     "/path/to/package-lock.json",
+			expected: false,
+		},
+		{
+			name:     "yarn.lock in subdirectory",
+			path:     "/path/to/project/yarn.lock",
+			expected: true,
+		},
+		{
+			name:     "not a lockfile",
+			path:     "/path/to/package.json",
+			expected: false,
+		},
+		{
+			name:     "short path",
+			path:     "yarn",
+			expected: false,
+		},
+		{
+			name:     "empty path",
+			path:     "",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := isYarnLockfile(tt.path)
+			if result != tt.expected {
+				t.Errorf("isYarnLockfile(%q) = %v, expected %v", tt.path, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestConvertYarnToLockfile tests the conversion of Yarn packages to lockfile format
+func TestConvertYarnToLockfile(t *testing.T) {
+	resolvedPackages := []parser.ResolvedPackage{
+		{
+			Name:         "test-package",
+			Version:      "1.0.0",
+			LockfilePath: "/path/to/yarn.lock",
+		},
+		{
+			Name:         "@scope/package",
+			Version:      "2.0.0",
+			LockfilePath: "/path/to/yarn.lock",
+		},
+	}
+
+	lockfile := convertYarnToLockfile(resolvedPackages)
+
+	if lockfile == nil {
+		t.Fatal("Expected non-nil lockfile")
+	}
+
+	if lockfile.Version != 1 {
+		t.Errorf("Expected version 1, got %d", lockfile.Version)
+	}
+
+	if len(lockfile.Packages) != 2 {
+		t.Errorf("Expected 2 packages, got %d", len(lockfile.Packages))
+	}
+
+	// Check that packages are correctly mapped
+	if pkg, ok := lockfile.Packages["node_modules/test-package"]; !ok {
+		t.Error("Expected test-package to be in Packages")
+	} else if pkg.Version != "1.0.0" {
+		t.Errorf("Expected version 1.0.0, got %s", pkg.Version)
+	}
+
+	if pkg, ok := lockfile.Packages["node_modules/@scope/package"]; !ok {
+		t.Error("Expected @scope/package to be in Packages")
+	} else if pkg.Version != "2.0.0" {
+		t.Errorf("Expected version 2.0.0, got %s", pkg.Version)
+	}
+}
+
+// TestMergeResults tests combining results from multiple scan roots
+func TestMergeResults(t *testing.T) {
+	early := time.Date(2025, 11, 28, 3, 0, 0, 0, time.UTC)
+	late := early.Add(time.Minute)
+
+	roots := []formatter.RootResult{
+		{
+			Path: "/a",
+			Result: &formatter.ScanResult{
+				ManifestsScanned: 2,
+				LockfilesScanned: 1,
+				PackagesChecked:  10,
+				Matches: []formatter.Match{
+					{PackageName: "pkg", Version: "1.0.0", Severity: formatter.SeverityDirect, Location: "/a/package.json"},
+				},
+				Timestamp: late,
+				IOCCount:  100,
+				Metadata:  map[string]string{"build": "42", formatter.MetaGitCommit: "aaa"},
+			},
+		},
+		{
+			Path: "/b",
+			Result: &formatter.ScanResult{
+				ManifestsScanned: 1,
+				PackagesChecked:  5,
+				Matches: []formatter.Match{
+					{PackageName: "other", Version: "2.0.0", Severity: formatter.SeverityTransitive, Location: "/b/yarn.lock"},
+				},
+				Timestamp: early,
+				IOCCount:  100,
+				Metadata:  map[string]string{"build": "42", formatter.MetaGitCommit: "bbb"},
+			},
+		},
+	}
+
+	merged := MergeResults(roots)
+
+	if merged.ManifestsScanned != 3 {
+		t.Errorf("Expected 3 manifests, got %d", merged.ManifestsScanned)
+	}
+	if merged.LockfilesScanned != 1 {
+		t.Errorf("Expected 1 lockfile, got %d", merged.LockfilesScanned)
+	}
+	if merged.PackagesChecked != 15 {
+		t.Errorf("Expected 15 packages, got %d", merged.PackagesChecked)
+	}
+	if len(merged.Matches) != 2 {
+		t.Errorf("Expected 2 matches, got %d", len(merged.Matches))
+	}
+	if !merged.Timestamp.Equal(early) {
+		t.Errorf("Expected earliest timestamp, got %v", merged.Timestamp)
+	}
+	if merged.IOCCount != 100 {
+		t.Errorf("Expected IoC count 100, got %d", merged.IOCCount)
+	}
+	if merged.Metadata["build"] != "42" {
+		t.Errorf("Expected shared metadata to be kept, got %v", merged.Metadata)
+	}
+	if _, ok := merged.Metadata[formatter.MetaGitCommit]; ok {
+		t.Errorf("Expected conflicting metadata to be dropped, got %v", merged.Metadata)
+	}
+}
+
+// TestProjectIndex tests assigning files to their nearest project
+func TestProjectIndex(t *testing.T) {
+	root := filepath.Join("/repo")
+	idx := newProjectIndex(root, []string{
+		filepath.Join(root, "package.json"),
+		filepath.Join(root, "packages", "api", "package.json"),
+	})
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+	}{
+		{"root manifest", filepath.Join(root, "package.json"), root},
+		{"root lockfile", filepath.Join(root, "yarn.lock"), root},
+		{"workspace manifest", filepath.Join(root, "packages", "api", "package.json"), filepath.Join(root, "packages", "api")},
+		{"nested file without manifest", filepath.Join(root, "packages", "api", "sub", "package-lock.json"), filepath.Join(root, "packages", "api")},
+		{"sibling without manifest", filepath.Join(root, "packages", "web", "yarn.lock"), root},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := idx.lookup(tt.file)
+			if project.Path != tt.expected {
+				t.Errorf("lookup(%q) = %q, expected %q", tt.file, project.Path, tt.expected)
+			}
+		})
+	}
+
+	idx.lookup(filepath.Join(root, "package.json")).Matches = []formatter.Match{
+		{PackageName: "pkg", Version: "1.0.0", Severity: formatter.SeverityDirect},
+		{PackageName: "pkg", Version: "1.0.0", Severity: formatter.SeverityDirect},
+	}
+
+	results := idx.results()
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(results))
+	}
+	if results[0].Path != root {
+		t.Errorf("Expected projects sorted by path, got %q first", results[0].Path)
+	}
+	if len(results[0].Matches) != 1 {
+		t.Errorf("Expected matches deduplicated per project, got %d", len(results[0].Matches))
+	}
+}
+
+// writeTestFiles creates files with the given contents under a temporary
+// directory and returns the directory path.
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+
+	for name, content := range files {
+		fullPath := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("failed to create directory for %s: %v", name, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	return tmpDir
+}
+
+// TestCollectProjectStats tests exposure statistics and ranking
+func TestCollectProjectStats(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"package.json": `{"name": "root", "dependencies": {"a": "^1.0.0", "b": "1.0.0"}}`,
+		"package-lock.json": `{"lockfileVersion": 3, "packages": {
+			"": {},
+			"node_modules/a": {"version": "1.2.0"},
+			"node_modules/b": {"version": "1.0.0"},
+			"node_modules/c": {"version": "3.0.0"}
+		}}`,
+		"packages/web/package.json": `{"name": "web", "dependencies": {"a": "*", "d": ">=2.0.0"}}`,
+	})
+
+	stats, err := CollectProjectStats(root)
+	if err != nil {
+		t.Fatalf("CollectProjectStats failed: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(stats))
+	}
+
+	byResolved, err := TopProjects(stats, SortByResolved, 1)
+	if err != nil {
+		t.Fatalf("TopProjects failed: %v", err)
+	}
+	if len(byResolved) != 1 || byResolved[0].Name != "root" {
+		t.Errorf("Expected root project first by resolved count, got %+v", byResolved)
+	}
+	if byResolved[0].ResolvedDependencies != 3 {
+		t.Errorf("Expected 3 resolved dependencies, got %d", byResolved[0].ResolvedDependencies)
+	}
+	if byResolved[0].WideRanges != 1 {
+		t.Errorf("Expected 1 wide range, got %d", byResolved[0].WideRanges)
+	}
+
+	byWide, err := TopProjects(stats, SortByWideRanges, 0)
+	if err != nil {
+		t.Fatalf("TopProjects failed: %v", err)
+	}
+	if byWide[0].Name != "web" || byWide[0].WideRangeRatio != 1 {
+		t.Errorf("Expected web project first by wide ratio, got %+v", byWide[0])
+	}
+
+	if _, err := TopProjects(stats, "size", 0); err == nil {
+		t.Error("Expected error for unknown sort key")
+	}
+}
+
+// TestHasAncestorIn tests lockfile lookup for hygiene auditing
+func TestHasAncestorIn(t *testing.T) {
+	root := filepath.Join("/repo")
+	dirs := dirSet([]string{filepath.Join(root, "yarn.lock")})
+
+	if !hasAncestorIn(filepath.Join(root, "packages", "api", "package.json"), dirs, root) {
+		t.Error("Expected workspace to be covered by root lockfile")
+	}
+
+	nested := dirSet([]string{filepath.Join(root, "packages", "api", "package-lock.json")})
+	if hasAncestorIn(filepath.Join(root, "packages", "web", "package.json"), nested, root) {
+		t.Error("Expected sibling project without lockfile to be uncovered")
+	}
+}
+
+// TestLockfileAge tests staleness detection from the file modification time
+func TestLockfileAge(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{"package-lock.json": "{}"})
+	path := filepath.Join(root, "package-lock.json")
+
+	modified := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	now := time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC)
+	campaignStart := time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)
+
+	age, err := lockfileAge(path, now, campaignStart)
+	if err != nil {
+		t.Fatalf("lockfileAge failed: %v", err)
+	}
+	if age.Source != AgeSourceMtime {
+		t.Errorf("Expected mtime source outside git, got %q", age.Source)
+	}
+	if age.AgeDays != 91 {
+		t.Errorf("Expected 91 days, got %d", age.AgeDays)
+	}
+	if !age.Stale {
+		t.Error("Expected lockfile predating the campaign to be stale")
+	}
+
+	fresh, err := lockfileAge(path, now, modified.AddDate(0, 0, -1))
+	if err != nil {
+		t.Fatalf("lockfileAge failed: %v", err)
+	}
+	if fresh.Stale {
+		t.Error("Expected lockfile after campaign start not to be stale")
+	}
+}
+
+// TestScanInventory tests scanning in-memory manifests, lockfiles and
+// packages that do not exist on disk
+func TestScanInventory(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version,Date Added\nevil,= 1.0.1,2025-11-24\nchalk,= 5.6.1,\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	inventory := Inventory{
+		Manifests: []InventoryManifest{{
+			Path:     "generated/package.json",
+			Manifest: &parser.Manifest{Dependencies: map[string]string{"evil": "1.0.1", "left-pad": "1.3.0"}},
+		}},
+		Lockfiles: []InventoryLockfile{{
+			Path: "generated/package-lock.json",
+			Lockfile: &parser.Lockfile{Version: 3, Packages: map[string]parser.PackageInfo{
+				"":                     {Dependencies: map[string]interface{}{"wrapper": "^1.0.0"}},
+				"node_modules/wrapper": {Version: "1.0.0", Dependencies: map[string]interface{}{"chalk": "^5.0.0"}},
+				"node_modules/chalk":   {Version: "5.6.1"},
+			}},
+		}},
+		Packages: []parser.ResolvedPackage{{Name: "evil", Version: "1.0.1", LockfilePath: "generated/yarn.lock"}},
+	}
+
+	result, err := ScanInventory(context.Background(), inventory, iocDB)
+	if err != nil {
+		t.Fatalf("ScanInventory failed: %v", err)
+	}
+	if result.ManifestsScanned != 1 || result.LockfilesScanned != 1 || result.InventoriesScanned != 1 || result.PackagesChecked != 5 {
+		t.Errorf("Unexpected counts: %+v", result)
+	}
+	if stats := result.DependencyStats; stats == nil || stats.Manifest.Prod != 2 || stats.Lockfile.Prod != 2 || stats.Inventory == nil || stats.Inventory.Unknown != 1 {
+		t.Errorf("Unexpected dependency stats: %+v", result.DependencyStats)
+	}
+	if len(result.Matches) != 3 {
+		t.Fatalf("Expected DIRECT evil, TRANSITIVE chalk and TRANSITIVE evil, got %+v", result.Matches)
+	}
+	for _, match := range result.Matches {
+		if match.Fingerprint == "" {
+			t.Errorf("Expected a fingerprint on %+v", match)
+		}
+		if match.PackageName == "evil" && (match.IOCAdded == nil || match.IOCAdded.Format("2006-01-02") != "2025-11-24") {
+			t.Errorf("Expected IoC date on %+v", match)
+		}
+		if match.PackageName == "chalk" && !reflect.DeepEqual(match.Chain, []string{"wrapper@1.0.0", "chalk@5.6.1"}) {
+			t.Errorf("Expected dependency chain on %+v", match)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ScanInventory(ctx, inventory, iocDB); err == nil {
+		t.Error("Expected an error for a canceled context")
+	}
+}
+
+// TestScanContent tests scanning a manifest or lockfile that is not on disk
+func TestScanContent(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		path         string
+		content      string
+		lockfileOnly bool
+		source       string
+		wantErr      bool
+		severity     formatter.Severity
 	}{
-		{
-			name:     "yarn.lock file",
-			path:     "/path/to/yarn.lock",
-			expected: true,
-		},
-		{
-			name:     "package-lock.json file",
-			path:     "/path/to/package-lock.json",
-			expected: false,
-		},
-		{
-			name:     "yarn.lock in subdirectory",
-			path:     "/path/to/project/yarn.lock",
-			expected: true,
-		},
-		{
-			name:     "not a lockfile",
-			path:     "/path/to/package.json",
-			expected: false,
-		},
-		{
-			name:     "short path",
-			path:     "yarn",
-			expected: false,
-		},
-		{
-			name:     "empty path",
-			path:     "",
-			expected: false,
-		},
+		{name: "manifest", path: "(stdin)/package.json", content: `{"dependencies": {"evil": "1.0.1"}}`, severity: formatter.SeverityDirect},
+		{name: "npm lockfile", path: "(stdin)/package-lock.json", content: `{"lockfileVersion": 3, "packages": {"": {}, "node_modules/evil": {"version": "1.0.1"}}}`, severity: formatter.SeverityTransitive},
+		{name: "shrinkwrap", path: "(stdin)/npm-shrinkwrap.json", content: `{"lockfileVersion": 3, "packages": {"": {}, "node_modules/evil": {"version": "1.0.1"}}}`, severity: formatter.SeverityTransitive},
+		{name: "yarn lockfile", path: "(stdin)/yarn.lock", content: "evil@^1.0.0:\n  version \"1.0.1\"\n", severity: formatter.SeverityTransitive},
+		{name: "invalid JSON", path: "(stdin)/package-lock.json", content: "not json", wantErr: true},
+		{name: "unsupported file", path: "(stdin)/pnpm-lock.yaml", content: "lockfileVersion: '9.0'\n", wantErr: true},
+		{name: "manifest in lockfile-only mode", path: "(stdin)/package.json", content: `{}`, lockfileOnly: true, wantErr: true},
+		{name: "OSV source", path: "(stdin)/package.json", content: `{}`, source: "osv", wantErr: true},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := isYarnLockfile(tt.path)
-			if result != tt.expected {
-				t.Errorf("isYarnLockfile(%q) = %v, expected %v", tt.path, result, tt.expected)
+			result, err := ScanContent(ScanOptions{Database: iocDB, LockfileOnly: tt.lockfileOnly, Source: tt.source}, tt.path, []byte(tt.content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScanContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(result.Matches) != 1 || result.Matches[0].Severity != tt.severity || result.Matches[0].Location != tt.path {
+				t.Errorf("Matches = %+v, want one %s match at %s", result.Matches, tt.severity, tt.path)
 			}
 		})
 	}
 }
 
-// TestConvertYarnToLockfile tests the conversion of Yarn packages to lockfile format
-func TestConvertYarnToLockfile(t *testing.T) {
-	resolvedPackages := []parser.ResolvedPackage{
-		{
-			Name:         "test-package",
-			Version:      "1.0.0",
-			LockfilePath: "/path/to/yarn.lock",
-		},
-		{
-			Name:         "@scope/package",
-			Version:      "2.0.0",
-			LockfilePath: "/path/to/yarn.lock",
-		},
+// TestEstimateScan tests discovery statistics and duration estimates
+func TestEstimateScan(t *testing.T) {
+	root := writeTestFiles(t, map[string]string{
+		"package.json":                       `{"name": "app", "dependencies": {"chalk": "^5.0.0"}}`,
+		"package-lock.json":                  `{"lockfileVersion": 3, "packages": {"": {"name": "app"}, "node_modules/chalk": {"version": "5.6.0"}}}`,
+		"node_modules/chalk/package.json":    `{"name": "chalk", "version": "5.6.0"}`,
+		"node_modules/@scope/x/package.json": `{"name": "@scope/x", "version": "1.0.0"}`,
+		"packages/api/package.json":          `{"name": "api"}`,
+		"packages/api/yarn.lock":             "chalk@^5.0.0:\n  version \"5.6.0\"\n",
+		"packages/api/node_modules/.keep":    "",
+		"other/package.json":                 `{"name": "other"}`,
+	})
+	other := filepath.Join(root, "other")
+
+	tests := []struct {
+		name              string
+		roots             []string
+		options           EstimateOptions
+		manifests         int
+		lockfiles         int
+		nodeModules       int
+		installedPackages int
+	}{
+		{name: "full scan", roots: []string{root}, manifests: 3, lockfiles: 2, nodeModules: 2},
+		{name: "lockfile only", roots: []string{root}, options: EstimateOptions{LockfileOnly: true}, lockfiles: 2, nodeModules: 2},
+		{name: "installed packages", roots: []string{root}, options: EstimateOptions{Installed: true}, manifests: 3, lockfiles: 2, nodeModules: 2, installedPackages: 2},
+		{name: "excluded", roots: []string{root}, options: EstimateOptions{Find: FindOptions{Exclude: []string{"packages/"}}}, manifests: 2, lockfiles: 1, nodeModules: 1},
+		{name: "single file", roots: []string{filepath.Join(root, "package-lock.json")}, lockfiles: 1, nodeModules: 1},
+		{name: "empty root", roots: []string{t.TempDir()}},
+		{name: "several roots", roots: []string{filepath.Join(root, "packages"), other}, manifests: 2, lockfiles: 1, nodeModules: 1},
 	}
 
-	lockfile := convertYarnToLockfile(resolvedPackages)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.Workers = []int{1, 4}
+			stats, err := EstimateScan(tt.roots, tt.options)
+			if err != nil {
+				t.Fatalf("EstimateScan() error = %v", err)
+			}
+			if stats.Paths != len(tt.roots) || stats.Manifests != tt.manifests || stats.Lockfiles != tt.lockfiles ||
+				stats.NodeModules != tt.nodeModules || stats.InstalledPackages != tt.installedPackages {
+				t.Errorf("EstimateScan() = %+v, want %d manifests, %d lockfiles, %d node_modules, %d installed",
+					stats, tt.manifests, tt.lockfiles, tt.nodeModules, tt.installedPackages)
+			}
+			if (stats.ManifestBytes > 0) != (tt.manifests > 0) || (stats.LockfileBytes > 0) != (tt.lockfiles > 0) {
+				t.Errorf("sizes = %d and %d bytes", stats.ManifestBytes, stats.LockfileBytes)
+			}
+			if len(stats.Estimates) != 2 || stats.Estimates[0].Workers != 1 || stats.Estimates[1].Seconds > stats.Estimates[0].Seconds {
+				t.Errorf("Estimates = %+v, want fewer seconds with more workers", stats.Estimates)
+			}
+		})
+	}
 
-	if lockfile == nil {
-		t.Fatal("Expected non-nil lockfile")
+	if _, err := EstimateScan([]string{root}, EstimateOptions{Workers: []int{0}}); err == nil {
+		t.Error("EstimateScan() with 0 workers succeeded, want an error")
+	}
+	if got := defaultWorkerCounts(6); !reflect.DeepEqual(got, []int{1, 2, 4, 6}) {
+		t.Errorf("defaultWorkerCounts(6) = %v", got)
 	}
+	if got := defaultWorkerCounts(1); !reflect.DeepEqual(got, []int{1}) {
+		t.Errorf("defaultWorkerCounts(1) = %v", got)
+	}
+}
 
-	if lockfile.Version != 1 {
-		t.Errorf("Expected version 1, got %d", lockfile.Version)
+// TestCheckPackages tests package version lookups against a preloaded
+// database, without a project on disk
+func TestCheckPackages(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version,Date Added\nevil,= 1.0.1,2025-11-24\nold,= 1.0.0,2024-01-01\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+	packages := []ioc.PackageVersion{
+		{Name: "old", Version: "1.0.0"},
+		{Name: "evil", Version: "1.0.1"},
+		{Name: "@scope/pkg", Version: "2.0.0"},
 	}
 
-	if len(lockfile.Packages) != 2 {
-		t.Errorf("Expected 2 packages, got %d", len(lockfile.Packages))
+	checks, err := CheckPackages(ScanOptions{Database: iocDB}, packages)
+	if err != nil {
+		t.Fatalf("CheckPackages failed: %v", err)
+	}
+	var got []string
+	for _, check := range checks {
+		got = append(got, fmt.Sprintf("%s@%s=%v", check.Package, check.Version, check.Compromised))
+	}
+	want := []string{"old@1.0.0=true", "evil@1.0.1=true", "@scope/pkg@2.0.0=false"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CheckPackages() = %v, want %v", got, want)
 	}
 
-	// Check that packages are correctly mapped
-	if pkg, ok := lockfile.Packages["node_modules/test-package"]; !ok {
-		t.Error("Expected test-package to be in Packages")
-	} else if pkg.Version != "1.0.0" {
-		t.Errorf("Expected version 1.0.0, got %s", pkg.Version)
+	since := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	checks, err = CheckPackages(ScanOptions{Database: iocDB, Since: since}, packages)
+	if err != nil {
+		t.Fatalf("CheckPackages failed: %v", err)
+	}
+	if checks[0].Compromised || !checks[1].Compromised {
+		t.Errorf("Expected only entries added since %s, got %+v", since.Format("2006-01-02"), checks)
 	}
 
-	if pkg, ok := lockfile.Packages["node_modules/@scope/package"]; !ok {
-		t.Error("Expected @scope/package to be in Packages")
-	} else if pkg.Version != "2.0.0" {
-		t.Errorf("Expected version 2.0.0, got %s", pkg.Version)
+	if _, err := CheckPackages(ScanOptions{Offline: true, Source: "osv"}, packages); err == nil {
+		t.Error("Expected an error for the osv source offline")
+	}
+}
+
+// TestAnnotateMatches tests IoC date annotation and that lockfiles without
+// git history get no exposure estimate
+func TestAnnotateMatches(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version,Date Added\nbad-pkg,= 1.0.0,2025-11-24\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	root := writeTestFiles(t, map[string]string{"package-lock.json": "{}"})
+	matches := []formatter.Match{
+		{PackageName: "bad-pkg", Version: "1.0.0", Severity: formatter.SeverityTransitive, Location: filepath.Join(root, "package-lock.json")},
+		{PackageName: "other-pkg", Version: "2.0.0", Severity: formatter.SeverityDirect, Location: filepath.Join(root, "package.json")},
+	}
+
+	annotateMatches(matches, iocDB, time.Now())
+
+	if matches[0].IOCAdded == nil || matches[0].IOCAdded.Format("2006-01-02") != "2025-11-24" {
+		t.Errorf("Expected IoC added date 2025-11-24, got %v", matches[0].IOCAdded)
+	}
+	if matches[0].ExposedSince != nil {
+		t.Error("Expected no exposure estimate for a lockfile outside git")
+	}
+	if matches[1].IOCAdded != nil {
+		t.Error("Expected no IoC date for an entry not in the database")
+	}
+}
+
+// TestTraceExposure tests locating introducing and removing commits in
+// lockfile history
+func TestTraceExposure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "package-lock.json")
+	commit := func(date, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, args := range [][]string{{"add", "package-lock.json"}, {"commit", "-q", "-m", "update"}} {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			cmd.Env = append(os.Environ(),
+				"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@example.com",
+				"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@example.com",
+				"GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date,
+			)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v failed: %v\n%s", args, err, out)
+			}
+		}
+	}
+
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v\n%s", err, out)
+	}
+	commit("2025-06-01T12:00:00Z", `{"lockfileVersion": 3, "packages": {}}`)
+	commit("2025-07-01T12:00:00Z", `{"lockfileVersion": 3, "packages": {
+		"node_modules/kept": {"version": "1.0.0"},
+		"node_modules/fixed": {"version": "2.0.0"}}}`)
+	commit("2025-08-01T12:00:00Z", `{"lockfileVersion": 3, "packages": {
+		"node_modules/kept": {"version": "1.0.0"},
+		"node_modules/fixed": {"version": "2.0.1"}}}`)
+
+	matches := []formatter.Match{
+		{PackageName: "kept", Version: "1.0.0", Severity: formatter.SeverityTransitive, Location: path},
+		{PackageName: "fixed", Version: "2.0.0", Severity: formatter.SeverityTransitive, Location: path},
+	}
+	now := time.Date(2025, 9, 1, 12, 0, 0, 0, time.UTC)
+	traceExposure(matches, path, now, nil)
+
+	kept := matches[0].Timeline
+	if kept == nil {
+		t.Fatal("Expected a timeline for the kept version")
+	}
+	if kept.IntroducedAt.UTC().Format("2006-01-02") != "2025-07-01" || kept.RemovedAt != nil {
+		t.Errorf("Expected kept version introduced 2025-07-01 and not removed, got %+v", kept)
+	}
+	if matches[0].ExposureDays != 62 {
+		t.Errorf("Expected 62 days of exposure, got %d", matches[0].ExposureDays)
+	}
+
+	fixed := matches[1].Timeline
+	if fixed == nil || fixed.RemovedAt == nil {
+		t.Fatalf("Expected a removal for the fixed version, got %+v", fixed)
+	}
+	if fixed.RemovedAt.UTC().Format("2006-01-02") != "2025-08-01" {
+		t.Errorf("Expected removal on 2025-08-01, got %v", fixed.RemovedAt)
+	}
+	if matches[1].ExposureDays != 31 {
+		t.Errorf("Expected 31 days of exposure, got %d", matches[1].ExposureDays)
+	}
+}
+
+// TestCollectComponents tests inventorying exact pins and resolved versions
+func TestCollectComponents(t *testing.T) {
+	tmpDir := t.TempDir()
+	manifest := `{"name": "app", "dependencies": {"chalk": "5.6.1", "lodash": "^4.17.0"}}`
+	lockfile := `{
+  "name": "app",
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "dependencies": {"chalk": "5.6.1", "lodash": "^4.17.0"}},
+    "node_modules/chalk": {"version": "5.6.1"},
+    "node_modules/lodash": {"version": "4.17.21"}
+  }
+}`
+	manifestPath := filepath.Join(tmpDir, "package.json")
+	lockfilePath := filepath.Join(tmpDir, "package-lock.json")
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(lockfilePath, []byte(lockfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	components, err := CollectComponents(tmpDir, false)
+	if err != nil {
+		t.Fatalf("CollectComponents() error = %v", err)
+	}
+	want := []formatter.Component{
+		{Name: "chalk", Version: "5.6.1", Locations: []string{manifestPath, lockfilePath}},
+		{Name: "lodash", Version: "4.17.21", Locations: []string{lockfilePath}},
+	}
+	if !reflect.DeepEqual(components, want) {
+		t.Errorf("CollectComponents() = %+v, want %+v", components, want)
+	}
+
+	components, err = CollectComponents(tmpDir, true)
+	if err != nil {
+		t.Fatalf("CollectComponents() error = %v", err)
+	}
+	if len(components) != 2 || len(components[0].Locations) != 1 {
+		t.Errorf("expected lockfile-only components, got %+v", components)
+	}
+}
+
+// TestAttachEvidence tests locating the declaration line of each match
+func TestAttachEvidence(t *testing.T) {
+	manifest := []byte(`{
+  "name": "app",
+  "dependencies": {
+    "left-pad": "1.3.0",
+    "my-evil": "npm:evil@1.0.1"
+  }
+}`)
+	packageLock := []byte(`{
+  "lockfileVersion": 3,
+  "packages": {
+    "node_modules/evil": {
+      "version": "2.0.0"
+    },
+    "node_modules/wrapper/node_modules/evil": {
+      "version": "1.0.1"
+    }
+  }
+}`)
+	yarnLock := []byte(`# yarn lockfile v1
+
+evil@^2.0.0:
+  version "2.0.0"
+
+"chalk@^5.0.0", evil@^1.0.0:
+  version "1.0.1"
+`)
+
+	tests := []struct {
+		name    string
+		content []byte
+		match   formatter.Match
+		want    int
+	}{
+		{"manifest alias", manifest, formatter.Match{PackageName: "evil", Version: "1.0.1", Alias: "my-evil", Location: "app/package.json"}, 5},
+		{"nested lockfile entry", packageLock, formatter.Match{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive, Location: "app/package-lock.json"}, 7},
+		{"yarn entry", yarnLock, formatter.Match{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive, Location: "app/yarn.lock"}, 6},
+		{"missing", manifest, formatter.Match{PackageName: "absent", Version: "1.0.0", Location: "app/package.json"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.match.Sources = []formatter.SourceReport{{Source: "csv", Row: 4}}
+			matches := []formatter.Match{tt.match}
+			attachEvidence(matches, tt.content)
+
+			if len(matches[0].Evidence) != 1 {
+				t.Fatalf("Expected one evidence record, got %+v", matches[0].Evidence)
+			}
+			evidence := matches[0].Evidence[0]
+			if evidence.File != tt.match.Location || evidence.Line != tt.want {
+				t.Errorf("Evidence at %s:%d, want %s:%d", evidence.File, evidence.Line, tt.match.Location, tt.want)
+			}
+			if (evidence.LockfilePath != "") != (tt.match.Severity == formatter.SeverityTransitive) {
+				t.Errorf("Unexpected lockfile path %q", evidence.LockfilePath)
+			}
+			if !reflect.DeepEqual(evidence.Feed, []formatter.FeedRow{{Source: "csv", Row: 4, Spec: "= " + tt.match.Version}}) {
+				t.Errorf("Unexpected feed rows %+v", evidence.Feed)
+			}
+		})
+	}
+}
+
+// TestImageInventory tests how the npm files of an image are sorted into an inventory
+func TestImageInventory(t *testing.T) {
+	img := &oci.Image{Files: map[string][]byte{
+		"/app/package.json":                                []byte(`{"name":"app","dependencies":{"evil":"^1.0.0"}}`),
+		"/app/package-lock.json":                           []byte(`{"lockfileVersion":3,"packages":{}}`),
+		"/app/yarn.lock":                                   []byte("evil@^1.0.0:\n  version \"1.0.1\"\n"),
+		"/app/node_modules/evil/package.json":              []byte(`{"name":"evil","version":"1.0.1"}`),
+		"/app/node_modules/@scope/pkg/package.json":        []byte(`{"name":"@scope/pkg","version":"2.0.0"}`),
+		"/app/node_modules/evil/test/fixture/package.json": []byte(`{"name":"fixture","version":"0.0.1"}`),
+		"/usr/local/lib/node_modules/npm/package.json":     []byte(`{"name":"npm","version":"10.0.0"}`),
+		"/usr/local/lib/node_modules/broken/package.json":  []byte(`{`),
+		"/opt/tool/package-lock.json":                      []byte(`not json`),
+	}}
+
+	inventory, skipped := ImageInventory(img, false)
+	if len(inventory.Manifests) != 1 || inventory.Manifests[0].Path != "/app/package.json" {
+		t.Errorf("Manifests = %+v, want /app/package.json", inventory.Manifests)
+	}
+	if len(inventory.Lockfiles) != 1 || inventory.Lockfiles[0].Path != "/app/package-lock.json" {
+		t.Errorf("Lockfiles = %+v, want /app/package-lock.json", inventory.Lockfiles)
+	}
+	var packages []string
+	for _, pkg := range inventory.Packages {
+		packages = append(packages, pkg.Name+"@"+pkg.Version+" "+pkg.LockfilePath)
+	}
+	want := []string{
+		"@scope/pkg@2.0.0 /app/node_modules/@scope/pkg/package.json",
+		"evil@1.0.1 /app/node_modules/evil/package.json",
+		"evil@1.0.1 /app/yarn.lock",
+		"npm@10.0.0 /usr/local/lib/node_modules/npm/package.json",
+	}
+	if !reflect.DeepEqual(packages, want) {
+		t.Errorf("Packages = %v, want %v", packages, want)
+	}
+	if len(skipped) != 2 {
+		t.Errorf("skipped %v, want the broken package.json and lockfile", skipped)
+	}
+
+	inventory, _ = ImageInventory(img, true)
+	if len(inventory.Manifests) != 0 {
+		t.Errorf("lockfile-only Manifests = %+v, want none", inventory.Manifests)
+	}
+}
+
+// TestRunContainerScan tests scanning a docker save archive
+func TestRunContainerScan(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	tarball := func(files map[string]string, order ...string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, name := range order {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(files[name])); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	layer := tarball(map[string]string{
+		"app/package.json":                   `{"name":"app","dependencies":{"evil":"1.0.1"}}`,
+		"app/node_modules/evil/package.json": `{"name":"evil","version":"1.0.1"}`,
+	}, "app/package.json", "app/node_modules/evil/package.json")
+	archive := filepath.Join(t.TempDir(), "app.tar")
+	data := tarball(map[string]string{
+		"layer.tar":     string(layer),
+		"manifest.json": `[{"Config":"config.json","RepoTags":["app:latest"],"Layers":["layer.tar"]}]`,
+	}, "layer.tar", "manifest.json")
+	if err := os.WriteFile(archive, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := RunContainerScan(ScanOptions{Path: archive, Database: iocDB})
+	if err != nil {
+		t.Fatalf("RunContainerScan failed: %v", err)
+	}
+	var got []string
+	for _, m := range result.Matches {
+		got = append(got, string(m.Severity)+" "+m.Location)
+	}
+	want := []string{"DIRECT /app/package.json", "TRANSITIVE /app/node_modules/evil/package.json"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("matches = %v, want %v", got, want)
+	}
+	if result.Metadata[formatter.MetaImageReference] != archive {
+		t.Errorf("Metadata = %v, want the image reference", result.Metadata)
+	}
+
+	if _, err := RunContainerScan(ScanOptions{Path: archive, Database: iocDB, Source: "osv"}); err == nil {
+		t.Error("RunContainerScan with the osv source succeeded, want an error")
+	}
+}
+
+func TestRunScan_Archive(t *testing.T) {
+	iocDB, err := ioc.NewDatabase([]byte("Package,Version\nevil,= 1.0.1\n"))
+	if err != nil {
+		t.Fatalf("NewDatabase failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range [][2]string{
+		{"dist/package.json", `{"name":"app","dependencies":{"evil":"1.0.1"}}`},
+		{"dist/node_modules/evil/package.json", `{"name":"evil","version":"1.0.1"}`},
+		{"dist/node_modules/evil/test/package.json", `{"name":"fixture","version":"0.0.0"}`},
+		{"dist/index.js", `require("evil")`},
+	} {
+		w, err := zw.Create(entry[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "release.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		lockfileOnly bool
+		want         []string
+	}{
+		{
+			name: "manifests and bundled packages",
+			want: []string{
+				"DIRECT " + path + "/dist/package.json",
+				"TRANSITIVE " + path + "/dist/node_modules/evil/package.json",
+			},
+		},
+		{
+			name:         "lockfile-only skips manifests",
+			lockfileOnly: true,
+			want:         []string{"TRANSITIVE " + path + "/dist/node_modules/evil/package.json"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := RunScan(ScanOptions{Path: path, Database: iocDB, LockfileOnly: tt.lockfileOnly})
+			if err != nil {
+				t.Fatalf("RunScan failed: %v", err)
+			}
+			var got []string
+			for _, m := range result.Matches {
+				got = append(got, string(m.Severity)+" "+m.Location)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+			if result.Metadata[formatter.MetaArchivePath] != path || !strings.HasPrefix(result.Metadata[formatter.MetaArchiveDigest], "sha256:") {
+				t.Errorf("Metadata = %v, want the archive path and digest", result.Metadata)
+			}
+		})
+	}
+
+	if _, err := RunScan(ScanOptions{Path: path, Database: iocDB, Source: "osv"}); err == nil {
+		t.Error("RunScan of an archive with the osv source succeeded, want an error")
 	}
 }
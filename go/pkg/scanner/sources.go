@@ -0,0 +1,76 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+)
+
+// queriesPackages reports whether sources includes one that is queried
+// for the packages a scan discovers (OSV, GHSA).
+func queriesPackages(sources []string) bool {
+	return containsSource(sources, ioc.SourceOSV) || containsSource(sources, ioc.SourceGHSA)
+}
+
+// checkOfflineSources refuses the sources that query a service per
+// package when options.Offline is set. Registered sources are allowed,
+// since they need not use the network.
+func checkOfflineSources(options ScanOptions, sources []string) error {
+	if !options.Offline {
+		return nil
+	}
+	for _, source := range sources {
+		if source == ioc.SourceOSV || source == ioc.SourceGHSA {
+			return fmt.Errorf("the %s source requires network access and cannot be used offline", source)
+		}
+	}
+	return nil
+}
+
+// addSources merges the entries of the selected sources other than csv
+// into iocDB, which may be nil: OSV and GHSA are queried for the package
+// versions returned by packages, called at most once, and registered
+// sources are fetched whole. options.Since applies to each. Entries listed by several sources are
+// correlated into single matches.
+func addSources(options ScanOptions, sources []string, iocDB *ioc.Database, packages func() []ioc.PackageVersion) (*ioc.Database, error) {
+	var discovered []ioc.PackageVersion
+	loaded := false
+	for _, source := range sources {
+		var db *ioc.Database
+		var err error
+		switch {
+		case source == ioc.SourceCSV:
+			continue
+		case source == ioc.SourceOSV || source == ioc.SourceGHSA:
+			if !loaded {
+				discovered, loaded = packages(), true
+			}
+			if source == ioc.SourceOSV {
+				db, err = queryOSV(options, discovered)
+			} else {
+				db, err = queryGHSA(options, discovered)
+			}
+		default:
+			if options.Verbose {
+				options.logf("Fetching IoC source %s...\n", source)
+			}
+			db, err = ioc.NewDatabaseFromRegistered(options.Context, source)
+			if err != nil {
+				err = fmt.Errorf("failed to fetch IoC database: %w", unavailableError{err})
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !options.Since.IsZero() {
+			db = db.Since(options.Since)
+		}
+
+		if iocDB == nil {
+			iocDB = db
+		} else {
+			iocDB = ioc.Merge(iocDB, db)
+		}
+	}
+	return iocDB, nil
+}
@@ -0,0 +1,137 @@
+package scanner
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/matcher"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/parser"
+)
+
+// Sort keys accepted by TopProjects.
+const (
+	// SortByResolved orders projects by resolved dependency count
+	SortByResolved = "resolved"
+	// SortByWideRanges orders projects by the proportion of wide version ranges
+	SortByWideRanges = "wide"
+)
+
+// CollectProjectStats gathers dependency exposure statistics for every
+// project under root. No IoC database is needed: the statistics describe
+// how much supply-chain surface each project has, independent of current hits.
+//
+// Files that fail to parse are skipped.
+func CollectProjectStats(root string) ([]formatter.ProjectStats, error) {
+	manifestPaths, err := FindManifests(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find manifests: %w", err)
+	}
+	lockfilePaths, err := FindLockfiles(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find lockfiles: %w", err)
+	}
+
+	projects := newProjectIndex(root, manifestPaths)
+	stats := make(map[string]*formatter.ProjectStats)
+	statsFor := func(filePath string) *formatter.ProjectStats {
+		project := projects.lookup(filePath)
+		s, ok := stats[project.Path]
+		if !ok {
+			s = &formatter.ProjectStats{Path: project.Path}
+			stats[project.Path] = s
+		}
+		return s
+	}
+
+	for _, manifestPath := range manifestPaths {
+		manifest, err := parser.ParsePackageJSON(manifestPath)
+		if err != nil {
+			continue
+		}
+
+		s := statsFor(manifestPath)
+		s.Name = manifest.Name
+		for _, dep := range parser.ExtractDependencies(manifest, manifestPath) {
+			if dep.Type == "bundledDependencies" {
+				continue
+			}
+			s.DeclaredDependencies++
+			if matcher.IsWideRange(dep.VersionSpec) {
+				s.WideRanges++
+			}
+		}
+	}
+
+	for _, lockfilePath := range lockfilePaths {
+		var count int
+		if isYarnLockfile(lockfilePath) {
+			yarnLock, err := parseYarnLockfile(lockfilePath)
+			if err != nil {
+				continue
+			}
+			count = len(parser.ExtractYarnResolvedPackages(yarnLock))
+		} else {
+			lockfile, err := parser.ParsePackageLock(lockfilePath)
+			if err != nil {
+				continue
+			}
+			count = len(parser.ExtractResolvedPackages(lockfile, lockfilePath))
+		}
+		statsFor(lockfilePath).ResolvedDependencies += count
+	}
+
+	result := make([]formatter.ProjectStats, 0, len(stats))
+	for _, s := range stats {
+		if s.DeclaredDependencies > 0 {
+			s.WideRangeRatio = float64(s.WideRanges) / float64(s.DeclaredDependencies)
+		}
+		result = append(result, *s)
+	}
+
+	return result, nil
+}
+
+// TopProjects sorts stats by the given key (SortByResolved or
+// SortByWideRanges) in descending order and returns at most n entries.
+// A non-positive n returns all entries.
+func TopProjects(stats []formatter.ProjectStats, sortBy string, n int) ([]formatter.ProjectStats, error) {
+	sorted := make([]formatter.ProjectStats, len(stats))
+	copy(sorted, stats)
+
+	var less func(a, b formatter.ProjectStats) bool
+	switch sortBy {
+	case SortByResolved, "":
+		less = func(a, b formatter.ProjectStats) bool {
+			if a.ResolvedDependencies != b.ResolvedDependencies {
+				return a.ResolvedDependencies > b.ResolvedDependencies
+			}
+			return a.WideRangeRatio > b.WideRangeRatio
+		}
+	case SortByWideRanges:
+		less = func(a, b formatter.ProjectStats) bool {
+			if a.WideRangeRatio != b.WideRangeRatio {
+				return a.WideRangeRatio > b.WideRangeRatio
+			}
+			return a.ResolvedDependencies > b.ResolvedDependencies
+		}
+	default:
+		return nil, fmt.Errorf("unknown sort key %q (expected %s or %s)", sortBy, SortByResolved, SortByWideRanges)
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if less(sorted[i], sorted[j]) {
+			return true
+		}
+		if less(sorted[j], sorted[i]) {
+			return false
+		}
+		return sorted[i].Path < sorted[j].Path
+	})
+
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,316 @@
+// Package serve exposes the scan engine over HTTP, so internal tooling can
+// run scans as a service instead of starting a process per scan.
+//
+// The IoC database is loaded once and kept in memory, and reloaded in the
+// background every TTL. A failed reload keeps the previous copy in service.
+//
+// Endpoints:
+//
+//	POST /scan     scan a directory on the server or uploaded files (ScanRequest)
+//	GET  /healthz  report the state of the IoC database (Health)
+//
+// Scan results have the same shape as npm-scan --json; errors are returned
+// as {"error": "..."} with a 4xx or 5xx status.
+package serve
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/ioc"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// maxRequestSize bounds a request body; uploaded lockfiles of large
+// monorepos can be several megabytes.
+const maxRequestSize = 64 << 20
+
+// shutdownTimeout bounds how long in-flight scans may finish on shutdown.
+const shutdownTimeout = 30 * time.Second
+
+// Health statuses.
+const (
+	// StatusOK means the database is loaded and the last reload succeeded
+	StatusOK = "ok"
+	// StatusStale means the last reload failed and an older copy is in use
+	StatusStale = "stale"
+	// StatusUnavailable means no database has been loaded
+	StatusUnavailable = "unavailable"
+)
+
+// Options configure a Server.
+type Options struct {
+	// Database selects the IoC database as for a scan (CSVURL, Offline,
+	// Since, Verbose); its other fields are ignored
+	Database scanner.ScanOptions
+
+	// TTL is how often the database is reloaded; zero disables reloading
+	TTL time.Duration
+
+	// Version is reported by /healthz
+	Version string
+}
+
+// File is an uploaded dependency file.
+type File struct {
+	// Path identifies the file by its base name (package.json,
+	// package-lock.json, npm-shrinkwrap.json or yarn.lock) and is recorded
+	// as the location of its matches
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// ScanRequest is the body of POST /scan. Exactly one of Path and Files is
+// set.
+type ScanRequest struct {
+	// Path is a directory on the server to scan
+	Path string `json:"path,omitempty"`
+	// Files are uploaded dependency files to scan instead of a directory
+	Files        []File `json:"files,omitempty"`
+	LockfileOnly bool   `json:"lockfileOnly,omitempty"`
+	// PerProject and Hygiene apply to directory scans only
+	PerProject bool `json:"perProject,omitempty"`
+	Hygiene    bool `json:"hygiene,omitempty"`
+	// Since is a YYYY-MM-DD date restricting matching to newer IoC entries
+	Since string `json:"since,omitempty"`
+}
+
+// Health is the body of GET /healthz.
+type Health struct {
+	Status   string     `json:"status"`
+	Version  string     `json:"version"`
+	IOCCount int        `json:"iocCount"`
+	LoadedAt *time.Time `json:"loadedAt,omitempty"`
+	// Error is the reason the last reload failed, while it is not fixed
+	Error string `json:"error,omitempty"`
+}
+
+// Server serves scans against an in-memory IoC database.
+type Server struct {
+	options Options
+
+	mu       sync.RWMutex
+	database *ioc.Database
+	loadedAt time.Time
+	loadErr  error
+}
+
+// NewServer creates a server. No database is loaded until Refresh or
+// ListenAndServe is called.
+func NewServer(options Options) *Server {
+	return &Server{options: options}
+}
+
+// Refresh loads the IoC database. If loading fails, the previously loaded
+// database stays in use and the error is reported by /healthz. A reload is
+// swapped into the database in service, so scans sharing it never wait on
+// a lock; each scan matches against the snapshot current when it started.
+func (s *Server) Refresh() error {
+	database, err := scanner.LoadDatabase(s.options.Database)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.loadErr = err
+		return err
+	}
+	if s.database == nil {
+		s.database = database
+	} else {
+		s.database.Replace(database)
+	}
+	s.loadedAt, s.loadErr = time.Now().UTC(), nil
+	return nil
+}
+
+// ListenAndServe loads the database, then serves on addr until ctx is
+// canceled, reloading the database every TTL. On cancellation, in-flight
+// requests are given time to finish before it returns.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	if err := s.Refresh(); err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() { errs <- server.ListenAndServe() }()
+	go s.refreshEvery(ctx)
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(shutdownCtx)
+}
+
+// refreshEvery reloads the database every TTL until ctx is canceled.
+func (s *Server) refreshEvery(ctx context.Context) {
+	if s.options.TTL <= 0 {
+		return
+	}
+	ticker := time.NewTicker(s.options.TTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: IoC database reload failed, still serving the previous copy: %v\n", err)
+			}
+		}
+	}
+}
+
+// Handler returns the HTTP handler serving the endpoints.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /scan", s.handleScan)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return mux
+}
+
+// handleHealth serves GET /healthz.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	health := Health{Status: StatusOK, Version: s.options.Version}
+	if s.database != nil {
+		health.IOCCount = s.database.Size()
+		loadedAt := s.loadedAt
+		health.LoadedAt = &loadedAt
+	}
+	if s.loadErr != nil {
+		health.Status = StatusStale
+		health.Error = s.loadErr.Error()
+	}
+	if s.database == nil {
+		health.Status = StatusUnavailable
+	}
+	s.mu.RUnlock()
+
+	status := http.StatusOK
+	if health.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, health)
+}
+
+// handleScan serves POST /scan.
+func (s *Server) handleScan(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	database := s.database
+	s.mu.RUnlock()
+	if database == nil {
+		writeError(w, http.StatusServiceUnavailable, "IoC database not loaded")
+		return
+	}
+	database = database.Snapshot()
+
+	var req ScanRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return
+	}
+	if (req.Path == "") == (len(req.Files) == 0) {
+		writeError(w, http.StatusBadRequest, "exactly one of path and files is required")
+		return
+	}
+
+	var since time.Time
+	if req.Since != "" {
+		var err error
+		since, err = time.Parse("2006-01-02", req.Since)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since %q: expected YYYY-MM-DD", req.Since))
+			return
+		}
+	}
+
+	var result *formatter.ScanResult
+	var err error
+	if req.Path != "" {
+		if _, statErr := os.Stat(req.Path); statErr != nil {
+			writeError(w, http.StatusBadRequest, "path does not exist: "+req.Path)
+			return
+		}
+		result, err = scanner.RunScan(scanner.ScanOptions{
+			Path:            req.Path,
+			Database:        database,
+			Offline:         s.options.Database.Offline,
+			DatabaseCache:   s.options.Database.DatabaseCache,
+			LockfileOnly:    req.LockfileOnly,
+			PerProject:      req.PerProject,
+			Hygiene:         req.Hygiene,
+			Since:           since,
+			SkipGitMetadata: true,
+			Context:         r.Context(),
+		})
+	} else {
+		inventory, inventoryErr := inventoryOf(req.Files, req.LockfileOnly)
+		if inventoryErr != nil {
+			writeError(w, http.StatusBadRequest, inventoryErr.Error())
+			return
+		}
+		if !since.IsZero() {
+			database = database.Since(since)
+		}
+		result, err = scanner.ScanInventory(r.Context(), inventory, database)
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "scan failed: "+err.Error())
+		return
+	}
+
+	output, err := formatter.FormatJSON(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to format result: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(output))
+}
+
+// inventoryOf parses uploaded files into an inventory. Manifests are
+// skipped when lockfileOnly is set.
+func inventoryOf(files []File, lockfileOnly bool) (scanner.Inventory, error) {
+	var inventory scanner.Inventory
+	for _, file := range files {
+		if lockfileOnly && filepath.Base(file.Path) == "package.json" {
+			continue
+		}
+		if err := inventory.AddFile(file.Path, []byte(file.Content)); err != nil {
+			return inventory, err
+		}
+	}
+	return inventory, nil
+}
+
+// writeJSON writes v as a JSON response with status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error response.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{message})
+}
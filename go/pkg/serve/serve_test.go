@@ -0,0 +1,152 @@
+package serve
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// newTestServer returns a server whose database is fetched from a stub feed
+// listing evil@1.0.1, and the number of fetches made so far.
+func newTestServer(t *testing.T) (*Server, *atomic.Int32) {
+	t.Helper()
+	fetches := &atomic.Int32{}
+	feed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte("Package,Version\nevil,= 1.0.1\n"))
+	}))
+	t.Cleanup(feed.Close)
+
+	return NewServer(Options{Database: scanner.ScanOptions{CSVURL: feed.URL}, Version: "1.2.3"}), fetches
+}
+
+// do sends a request to the server's handler.
+func do(server *Server, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+// TestHealthz tests the health report before and after loading the database
+func TestHealthz(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	if resp := do(server, http.MethodGet, "/healthz", ""); resp.Code != http.StatusServiceUnavailable || !strings.Contains(resp.Body.String(), StatusUnavailable) {
+		t.Errorf("GET /healthz before loading = %d %s, want 503 unavailable", resp.Code, resp.Body.String())
+	}
+	if err := server.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	resp := do(server, http.MethodGet, "/healthz", "")
+	var health Health
+	if err := json.Unmarshal(resp.Body.Bytes(), &health); err != nil {
+		t.Fatalf("invalid health body %q: %v", resp.Body.String(), err)
+	}
+	if resp.Code != http.StatusOK || health.Status != StatusOK || health.IOCCount != 1 || health.Version != "1.2.3" || health.LoadedAt == nil {
+		t.Errorf("GET /healthz = %d %+v", resp.Code, health)
+	}
+
+	// A failed reload keeps the loaded copy in service
+	server.options.Database.CSVURL = "http://127.0.0.1:0/unreachable.csv"
+	if err := server.Refresh(); err == nil {
+		t.Fatal("Refresh() of an unreachable feed should fail")
+	}
+	resp = do(server, http.MethodGet, "/healthz", "")
+	if resp.Code != http.StatusOK || !strings.Contains(resp.Body.String(), `"status":"stale"`) || !strings.Contains(resp.Body.String(), `"iocCount":1`) {
+		t.Errorf("GET /healthz after a failed reload = %d %s, want stale", resp.Code, resp.Body.String())
+	}
+}
+
+// TestScan tests directory and uploaded file scans against the loaded database
+func TestScan(t *testing.T) {
+	server, fetches := newTestServer(t)
+	if err := server.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"dependencies": {"evil": "1.0.1"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	pathBody, _ := json.Marshal(ScanRequest{Path: dir})
+	filesBody, _ := json.Marshal(ScanRequest{Files: []File{
+		{Path: "app/package.json", Content: `{"dependencies": {"evil": "^1.0.0"}}`},
+		{Path: "app/yarn.lock", Content: "evil@^1.0.0:\n  version \"1.0.1\"\n"},
+	}})
+
+	tests := []struct {
+		name      string
+		body      string
+		wantCount int
+		wantFile  string
+	}{
+		{"directory", string(pathBody), 1, filepath.Join(dir, "package.json")},
+		{"uploaded files", string(filesBody), 2, "app/yarn.lock"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(server, http.MethodPost, "/scan", tt.body)
+			if resp.Code != http.StatusOK {
+				t.Fatalf("POST /scan = %d %s", resp.Code, resp.Body.String())
+			}
+			var result formatter.ScanResult
+			if err := json.Unmarshal(resp.Body.Bytes(), &result); err != nil {
+				t.Fatalf("invalid result %q: %v", resp.Body.String(), err)
+			}
+			if len(result.Matches) != tt.wantCount || !strings.Contains(resp.Body.String(), tt.wantFile) {
+				t.Errorf("POST /scan matches = %+v, want %d in %s", result.Matches, tt.wantCount, tt.wantFile)
+			}
+		})
+	}
+
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected scans to reuse the loaded database, got %d fetches", got)
+	}
+}
+
+// TestScan_Errors tests the error responses of POST /scan
+func TestScan_Errors(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	if resp := do(server, http.MethodPost, "/scan", `{"path": "."}`); resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("POST /scan before loading = %d, want 503", resp.Code)
+	}
+	if err := server.Refresh(); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{"invalid JSON", http.MethodPost, `{"path":`, http.StatusBadRequest, "invalid request body"},
+		{"neither path nor files", http.MethodPost, `{}`, http.StatusBadRequest, "exactly one"},
+		{"missing path", http.MethodPost, `{"path": "/does/not/exist"}`, http.StatusBadRequest, "does not exist"},
+		{"invalid since", http.MethodPost, `{"path": ".", "since": "yesterday"}`, http.StatusBadRequest, "invalid since"},
+		{"unsupported file", http.MethodPost, `{"files": [{"path": "pnpm-lock.yaml", "content": ""}]}`, http.StatusBadRequest, "unsupported file"},
+		{"malformed lockfile", http.MethodPost, `{"files": [{"path": "package-lock.json", "content": "{"}]}`, http.StatusBadRequest, "package-lock.json"},
+		{"wrong method", http.MethodGet, ``, http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := do(server, tt.method, "/scan", tt.body)
+			if resp.Code != tt.wantCode || !strings.Contains(resp.Body.String(), tt.want) {
+				t.Errorf("%s /scan = %d %s, want %d mentioning %q", tt.method, resp.Code, resp.Body.String(), tt.wantCode, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package suppress acknowledges known findings through a suppression file
+// (.npmscan-ignore.yaml), so accepted matches are still reported but no
+// longer fail builds.
+//
+// The file is a small YAML subset, parsed without a YAML dependency:
+//
+//	# Comments and blank lines are ignored
+//	ignore:
+//	  - package: "evil@1.0.1"
+//	    reason: "vendored copy, never executed"
+//	    expires: 2026-12-31
+//	  - package: "@scope/pkg@*"
+//
+// Each rule names a package@version; a version of * covers every version of
+// the package. reason and expires are optional. A rule applies through its
+// expiry date and is ignored afterwards.
+package suppress
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/readonly"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/yamlite"
+)
+
+// DefaultPath is the suppression file read unless configured otherwise.
+const DefaultPath = ".npmscan-ignore.yaml"
+
+// anyVersion is the rule version covering every version of a package.
+const anyVersion = "*"
+
+// Rule acknowledges matches of one package version.
+type Rule struct {
+	Package string
+	// Version is the acknowledged version, or * for every version
+	Version string
+	Reason  string
+	// Expires is the last day the rule applies; zero means it never expires
+	Expires time.Time
+}
+
+// String returns the rule's package@version.
+func (r Rule) String() string {
+	return r.Package + "@" + r.Version
+}
+
+// Expired reports whether the rule's expiry date has passed at now.
+func (r Rule) Expired(now time.Time) bool {
+	return !r.Expires.IsZero() && !now.Before(r.Expires.AddDate(0, 0, 1))
+}
+
+// covers reports whether the rule acknowledges match.
+func (r Rule) covers(match formatter.Match) bool {
+	return r.Package == match.PackageName && (r.Version == anyVersion || r.Version == match.Version)
+}
+
+// File is a parsed suppression file.
+type File struct {
+	Rules []Rule
+}
+
+// Load reads a suppression file from path. A missing file yields an empty
+// file.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read suppression file: %w", err)
+	}
+
+	file, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse suppression file %s: %w", path, err)
+	}
+	return file, nil
+}
+
+// Parse parses suppression file data. Errors name the offending line.
+func Parse(data []byte) (*File, error) {
+	file := &File{}
+	var rule *Rule
+
+	finish := func() error {
+		if rule == nil {
+			return nil
+		}
+		if rule.Package == "" {
+			return fmt.Errorf("rule without a package")
+		}
+		file.Rules = append(file.Rules, *rule)
+		rule = nil
+		return nil
+	}
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		lineNo := i + 1
+		line := strings.TrimRight(yamlite.StripComment(raw), " \t\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line == "ignore:" {
+			continue
+		}
+
+		content := strings.TrimSpace(line)
+		if strings.HasPrefix(content, "- ") || content == "-" {
+			if err := finish(); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			rule = &Rule{}
+			content = strings.TrimSpace(strings.TrimPrefix(content, "-"))
+			if content == "" {
+				continue
+			}
+			// A bare list item is shorthand for a package rule
+			if !strings.Contains(content, ": ") && !strings.HasSuffix(content, ":") {
+				value, err := yamlite.Unquote(content)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				if err := rule.set("package", value); err != nil {
+					return nil, fmt.Errorf("line %d: %w", lineNo, err)
+				}
+				continue
+			}
+		} else if rule == nil {
+			return nil, fmt.Errorf("line %d: expected \"ignore:\" or a list item, got %q", lineNo, content)
+		}
+
+		key, value, ok := strings.Cut(content, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key: value, got %q", lineNo, content)
+		}
+		value, err := yamlite.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if err := rule.set(strings.TrimSpace(key), value); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+	}
+
+	if err := finish(); err != nil {
+		return nil, fmt.Errorf("last rule: %w", err)
+	}
+	return file, nil
+}
+
+// set assigns one key of a rule.
+func (r *Rule) set(key, value string) error {
+	switch key {
+	case "package":
+		at := strings.LastIndex(value, "@")
+		if at <= 0 || at == len(value)-1 {
+			return fmt.Errorf("invalid package %q: expected name@version or name@*", value)
+		}
+		r.Package, r.Version = value[:at], value[at+1:]
+	case "reason":
+		r.Reason = value
+	case "expires":
+		expires, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return fmt.Errorf("invalid expires %q: expected YYYY-MM-DD", value)
+		}
+		r.Expires = expires
+	default:
+		return fmt.Errorf("unknown key %q (expected package, reason or expires)", key)
+	}
+	return nil
+}
+
+// Marshal formats the file in the format read by Parse, with rules sorted
+// by package and version.
+func (f *File) Marshal() []byte {
+	rules := append([]Rule(nil), f.Rules...)
+	sort.SliceStable(rules, func(i, j int) bool {
+		if rules[i].Package != rules[j].Package {
+			return rules[i].Package < rules[j].Package
+		}
+		return rules[i].Version < rules[j].Version
+	})
+
+	var b bytes.Buffer
+	b.WriteString("# npm-scan suppression file: matches listed here are reported as\n")
+	b.WriteString("# suppressed and do not affect the exit code.\n")
+	b.WriteString("ignore:\n")
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "  - package: %s\n", strconv.Quote(rule.String()))
+		if rule.Reason != "" {
+			fmt.Fprintf(&b, "    reason: %s\n", strconv.Quote(rule.Reason))
+		}
+		if !rule.Expires.IsZero() {
+			fmt.Fprintf(&b, "    expires: %s\n", rule.Expires.Format("2006-01-02"))
+		}
+	}
+	return b.Bytes()
+}
+
+// Save writes the file to path.
+func (f *File) Save(path string) error {
+	return readonly.WriteFile(path, f.Marshal(), 0644)
+}
+
+// Add appends a rule unless the file already has one for the same package
+// version. It reports whether the rule was added.
+func (f *File) Add(rule Rule) bool {
+	for _, existing := range f.Rules {
+		if existing.Package == rule.Package && existing.Version == rule.Version {
+			return false
+		}
+	}
+	f.Rules = append(f.Rules, rule)
+	return true
+}
+
+// Apply moves the matches acknowledged by an unexpired rule from
+// result.Matches to result.Suppressed, annotated with their rule, and drops
+// them from per-project results. It returns the expired rules, which no
+// longer suppress anything.
+func (f *File) Apply(result *formatter.ScanResult, now time.Time) []Rule {
+	var active, expired []Rule
+	for _, rule := range f.Rules {
+		if rule.Expired(now) {
+			expired = append(expired, rule)
+		} else {
+			active = append(active, rule)
+		}
+	}
+	if len(active) == 0 {
+		return expired
+	}
+
+	kept := []formatter.Match{}
+	for _, match := range result.Matches {
+		if rule, ok := ruleFor(active, match); ok {
+			match.Suppression = suppressionFor(rule)
+			result.Suppressed = append(result.Suppressed, match)
+			continue
+		}
+		kept = append(kept, match)
+	}
+	result.Matches = kept
+
+	for i := range result.Projects {
+		var projectKept []formatter.Match
+		for _, match := range result.Projects[i].Matches {
+			if _, ok := ruleFor(active, match); !ok {
+				projectKept = append(projectKept, match)
+			}
+		}
+		result.Projects[i].Matches = projectKept
+	}
+
+	return expired
+}
+
+// ruleFor returns the first rule acknowledging match.
+func ruleFor(rules []Rule, match formatter.Match) (Rule, bool) {
+	for _, rule := range rules {
+		if rule.covers(match) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// suppressionFor describes rule for a suppressed match.
+func suppressionFor(rule Rule) *formatter.Suppression {
+	suppression := &formatter.Suppression{Rule: rule.String(), Reason: rule.Reason}
+	if !rule.Expires.IsZero() {
+		expires := rule.Expires
+		suppression.Expires = &expires
+	}
+	return suppression
+}
@@ -0,0 +1,139 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+)
+
+// TestParse tests parsing the supported YAML subset
+func TestParse(t *testing.T) {
+	data := []byte(`# Acknowledged findings
+ignore:
+  - package: "evil@1.0.1"   # vendored
+    reason: "reviewed: never executed"
+    expires: 2026-12-31
+
+  - package: '@scope/pkg@*'
+  - other@2.0.0
+`)
+
+	file, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	want := []Rule{
+		{Package: "evil", Version: "1.0.1", Reason: "reviewed: never executed", Expires: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)},
+		{Package: "@scope/pkg", Version: "*"},
+		{Package: "other", Version: "2.0.0"},
+	}
+	if !reflect.DeepEqual(file.Rules, want) {
+		t.Errorf("Parse() rules = %+v, want %+v", file.Rules, want)
+	}
+
+	roundTrip, err := Parse(file.Marshal())
+	if err != nil {
+		t.Fatalf("Parse(Marshal()) error = %v", err)
+	}
+	if len(roundTrip.Rules) != 3 || roundTrip.Rules[0].Package != "@scope/pkg" || roundTrip.Rules[1] != want[0] {
+		t.Errorf("Marshal() did not round-trip: %+v", roundTrip.Rules)
+	}
+}
+
+// TestParse_Errors tests that invalid files are rejected with their line
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"missing version", "ignore:\n  - package: evil\n", "line 2"},
+		{"bad date", "ignore:\n  - package: evil@1.0.1\n    expires: soon\n", "line 3"},
+		{"unknown key", "ignore:\n  - package: evil@1.0.1\n    owner: me\n", "unknown key"},
+		{"key outside a rule", "reason: none\n", "line 1"},
+		{"rule without package", "ignore:\n  - reason: none\n", "without a package"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestLoadAndSave tests reading a missing file and writing a new one
+func TestLoadAndSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultPath)
+
+	file, err := Load(path)
+	if err != nil || len(file.Rules) != 0 {
+		t.Fatalf("Load() of a missing file = %+v, %v; want an empty file", file, err)
+	}
+
+	if !file.Add(Rule{Package: "evil", Version: "1.0.1"}) || file.Add(Rule{Package: "evil", Version: "1.0.1", Reason: "again"}) {
+		t.Error("Add() should add a rule once per package version")
+	}
+	if err := file.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Save() did not write the file: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil || !reflect.DeepEqual(loaded.Rules, file.Rules) {
+		t.Errorf("Load() = %+v, %v; want %+v", loaded, err, file.Rules)
+	}
+}
+
+// TestApply tests moving acknowledged matches to the suppressed section
+func TestApply(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	file := &File{Rules: []Rule{
+		{Package: "evil", Version: "1.0.1", Reason: "vendored", Expires: time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{Package: "noisy", Version: "*"},
+		{Package: "stale", Version: "3.0.0", Expires: time.Date(2026, 5, 31, 0, 0, 0, 0, time.UTC)},
+	}}
+	result := &formatter.ScanResult{
+		Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect},
+			{PackageName: "evil", Version: "1.0.2", Severity: formatter.SeverityDirect},
+			{PackageName: "noisy", Version: "9.9.9", Severity: formatter.SeverityPotential},
+			{PackageName: "stale", Version: "3.0.0", Severity: formatter.SeverityTransitive},
+		},
+		Projects: []formatter.ProjectResult{{Path: "app", Matches: []formatter.Match{
+			{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect},
+			{PackageName: "stale", Version: "3.0.0", Severity: formatter.SeverityTransitive},
+		}}},
+	}
+
+	expired := file.Apply(result, now)
+
+	if len(expired) != 1 || expired[0].Package != "stale" {
+		t.Errorf("Apply() expired = %+v, want the stale rule", expired)
+	}
+	if len(result.Matches) != 2 || result.Matches[0].Version != "1.0.2" || result.Matches[1].PackageName != "stale" {
+		t.Errorf("Unexpected remaining matches: %+v", result.Matches)
+	}
+	if len(result.Suppressed) != 2 {
+		t.Fatalf("Expected 2 suppressed matches, got %+v", result.Suppressed)
+	}
+	s := result.Suppressed[0].Suppression
+	if s == nil || s.Rule != "evil@1.0.1" || s.Reason != "vendored" || s.Expires == nil {
+		t.Errorf("Unexpected suppression annotation: %+v", s)
+	}
+	if project := result.Projects[0].Matches; len(project) != 1 || project[0].PackageName != "stale" {
+		t.Errorf("Expected suppressed matches to be dropped from projects, got %+v", project)
+	}
+	if summary := formatter.Summarize(result); summary.TotalMatches != 2 || summary.Suppressed != 2 {
+		t.Errorf("Expected suppressed matches to be counted separately, got %+v", summary)
+	}
+}
@@ -0,0 +1,84 @@
+// Package throttle slows down filesystem walks, so sweeps of live
+// production volumes (NFS mounts in particular) do not degrade the
+// services using them. A Throttle caps the number of files visited per
+// second and can pause after every directory read, leaving the storage
+// idle gaps for other clients.
+//
+// A Throttle is safe for concurrent use: scans sharing one are limited to
+// the rate together. A nil Throttle does not throttle.
+package throttle
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Throttle limits the pace of a filesystem walk.
+type Throttle struct {
+	// interval is the time budgeted per file, or 0 for no rate limit
+	interval time.Duration
+	pause    time.Duration
+
+	mu sync.Mutex
+	// next is when the next file may be visited
+	next time.Time
+
+	// now and sleep are replaced in tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// New returns a Throttle allowing at most filesPerSecond files per second
+// (0 for no limit) and sleeping pause after each directory read. It returns
+// nil, which does not throttle, if neither is set.
+func New(filesPerSecond int, pause time.Duration) (*Throttle, error) {
+	if filesPerSecond < 0 {
+		return nil, fmt.Errorf("invalid file rate %d: must not be negative", filesPerSecond)
+	}
+	if pause < 0 {
+		return nil, fmt.Errorf("invalid pause %s: must not be negative", pause)
+	}
+	if filesPerSecond == 0 && pause == 0 {
+		return nil, nil
+	}
+
+	t := &Throttle{pause: pause, now: time.Now, sleep: time.Sleep}
+	if filesPerSecond > 0 {
+		t.interval = time.Second / time.Duration(filesPerSecond)
+	}
+	return t, nil
+}
+
+// Files blocks until n more files may be visited without exceeding the
+// rate. The first files after an idle period pass immediately.
+func (t *Throttle) Files(n int) {
+	if t == nil || t.interval == 0 || n <= 0 {
+		return
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	wait := t.next.Sub(now)
+	t.next = t.next.Add(time.Duration(n) * t.interval)
+	t.mu.Unlock()
+
+	if wait > 0 {
+		t.sleep(wait)
+	}
+}
+
+// Directory is called after each directory read. It visits the directory's
+// entries against the rate and then sleeps for the pause.
+func (t *Throttle) Directory(entries int) {
+	if t == nil {
+		return
+	}
+	t.Files(entries)
+	if t.pause > 0 {
+		t.sleep(t.pause)
+	}
+}
@@ -0,0 +1,95 @@
+package throttle
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock advanced only by sleeping
+type fakeClock struct {
+	now   time.Time
+	slept []time.Duration
+}
+
+func (c *fakeClock) install(t *Throttle) {
+	t.now = func() time.Time { return c.now }
+	t.sleep = func(d time.Duration) {
+		c.slept = append(c.slept, d)
+		c.now = c.now.Add(d)
+	}
+}
+
+// TestNew tests throttle settings validation
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    int
+		pause   time.Duration
+		wantNil bool
+		wantErr bool
+	}{
+		{name: "disabled", wantNil: true},
+		{name: "rate", rate: 100},
+		{name: "pause", pause: 10 * time.Millisecond},
+		{name: "negative rate", rate: -1, wantErr: true},
+		{name: "negative pause", pause: -time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.rate, tt.pause)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && (got == nil) != tt.wantNil {
+				t.Errorf("New() = %v, wantNil %v", got, tt.wantNil)
+			}
+		})
+	}
+}
+
+// TestThrottle tests the pacing of files and directories
+func TestThrottle(t *testing.T) {
+	throttle, err := New(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{now: time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC)}
+	clock.install(throttle)
+
+	// 10 files per second: each file is budgeted 100ms
+	throttle.Files(1)
+	throttle.Files(5)
+	throttle.Files(1)
+	want := []time.Duration{100 * time.Millisecond, 500 * time.Millisecond}
+	if len(clock.slept) != len(want) || clock.slept[0] != want[0] || clock.slept[1] != want[1] {
+		t.Errorf("slept %v, want %v", clock.slept, want)
+	}
+
+	// An idle period is not saved up as a burst budget
+	clock.now = clock.now.Add(time.Minute)
+	clock.slept = nil
+	throttle.Files(1)
+	throttle.Files(1)
+	if len(clock.slept) != 1 || clock.slept[0] != 100*time.Millisecond {
+		t.Errorf("after idling slept %v, want [100ms]", clock.slept)
+	}
+
+	// Directories pause after their entries
+	paused, err := New(0, 20*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.slept = nil
+	clock.install(paused)
+	paused.Directory(1000)
+	paused.Directory(0)
+	if len(clock.slept) != 2 || clock.slept[0] != 20*time.Millisecond || clock.slept[1] != 20*time.Millisecond {
+		t.Errorf("directories slept %v, want [20ms 20ms]", clock.slept)
+	}
+
+	// A nil throttle does nothing
+	var none *Throttle
+	none.Files(100)
+	none.Directory(100)
+}
@@ -0,0 +1,78 @@
+package transport
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryBackoff is the wait before the first retry unless
+// Config.RetryBackoff says otherwise.
+const DefaultRetryBackoff = time.Second
+
+// maxRetryAfter caps how long a Retry-After header can delay a retry.
+const maxRetryAfter = 30 * time.Second
+
+// retryTransport retries idempotent requests that fail transiently, with
+// exponential backoff.
+type retryTransport struct {
+	base    http.RoundTripper
+	retries int
+	backoff time.Duration
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return t.base.RoundTrip(req)
+	}
+
+	wait := t.backoff
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(req)
+		if attempt == t.retries || !retryable(resp, err) || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := wait
+		if resp != nil {
+			if after := retryAfter(resp); after > delay {
+				delay = after
+			}
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// retryable reports whether a request that ended with resp or err may
+// succeed when repeated: after a network error, a 429 Too Many Requests
+// or a server error other than 501 Not Implemented.
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
+
+// retryAfter returns the delay a 429 or 503 response asks for in seconds
+// in its Retry-After header, capped at maxRetryAfter, or zero.
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+}
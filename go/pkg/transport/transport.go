@@ -0,0 +1,207 @@
+// Package transport provides the HTTP client shared by every subsystem that
+// makes outbound requests, such as the IoC CSV and OSV fetches, so proxy
+// settings, URL rewrites, timeouts, retries and trusted certificates apply
+// to all of them uniformly.
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long a request may take, from connecting to
+// reading the last byte of the response, unless Config.Timeout says
+// otherwise.
+const DefaultTimeout = 60 * time.Second
+
+// Config configures outbound HTTP requests.
+type Config struct {
+	// Proxy selects the proxy for a request, e.g. by evaluating a
+	// corporate PAC script. If nil, HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	// are honored.
+	Proxy func(*http.Request) (*url.URL, error)
+	// Rewrite rewrites request URLs before they are sent, e.g. to route
+	// public feeds through an internal mirror. If nil, URLs are unchanged.
+	Rewrite func(*url.URL) *url.URL
+	// Timeout bounds how long a request may take, including retries and
+	// reading the response body, so a server that stalls mid-response
+	// cannot hang a scan. Clients from Streaming only bound the wait for
+	// the response headers. Zero means DefaultTimeout; a negative Timeout
+	// disables it.
+	Timeout time.Duration
+	// Retries is how many times a GET or HEAD request is retried after a
+	// network error or a 429 or 5xx response, waiting RetryBackoff, then
+	// twice as long before each further attempt. Zero disables retries.
+	Retries int
+	// RetryBackoff is the wait before the first retry. Zero means
+	// DefaultRetryBackoff.
+	RetryBackoff time.Duration
+	// RootCAs are the certificate authorities trusted for TLS, e.g. the
+	// system roots plus a TLS-intercepting corporate proxy's CA. If nil,
+	// the system roots are used.
+	RootCAs *x509.CertPool
+}
+
+var (
+	mu     sync.RWMutex
+	client = New(Config{})
+)
+
+// Configure replaces the shared client with one built from cfg. It should
+// be called before any request is made.
+func Configure(cfg Config) {
+	c := New(cfg)
+
+	mu.Lock()
+	defer mu.Unlock()
+	client = c
+}
+
+// Client returns the shared client. Subsystems must use it (or a client
+// from New) instead of http.DefaultClient.
+func Client() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// Streaming returns a copy of client without its overall request timeout,
+// for large downloads such as image layers that may take longer than
+// Config.Timeout. The wait for the response headers stays bounded; callers
+// bound the body read with their request's context.
+func Streaming(client *http.Client) *http.Client {
+	streaming := *client
+	streaming.Timeout = 0
+	return &streaming
+}
+
+// New builds a client from cfg on top of a copy of http.DefaultTransport.
+func New(cfg Config) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != nil {
+		base.Proxy = cfg.Proxy
+	}
+	timeout := cfg.Timeout
+	switch {
+	case timeout == 0:
+		timeout = DefaultTimeout
+	case timeout < 0:
+		timeout = 0
+	}
+	base.ResponseHeaderTimeout = timeout
+	if cfg.RootCAs != nil {
+		base.TLSClientConfig = &tls.Config{RootCAs: cfg.RootCAs}
+	}
+
+	var rt http.RoundTripper = base
+	if cfg.Rewrite != nil {
+		rt = &rewriteTransport{base: rt, rewrite: cfg.Rewrite}
+	}
+	if cfg.Retries > 0 {
+		backoff := cfg.RetryBackoff
+		if backoff <= 0 {
+			backoff = DefaultRetryBackoff
+		}
+		rt = &retryTransport{base: rt, retries: cfg.Retries, backoff: backoff}
+	}
+	return &http.Client{Transport: rt, Timeout: timeout}
+}
+
+// LoadCertPool returns the system roots plus the PEM certificates in
+// files. The system roots are left out where the platform cannot list
+// them.
+func LoadCertPool(files []string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificates found in %s", file)
+		}
+	}
+	return pool, nil
+}
+
+// rewriteTransport rewrites request URLs before handing requests to base.
+type rewriteTransport struct {
+	base    http.RoundTripper
+	rewrite func(*url.URL) *url.URL
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rewritten := t.rewrite(req.URL)
+	if rewritten == nil || rewritten.String() == req.URL.String() {
+		return t.base.RoundTrip(req)
+	}
+
+	out := req.Clone(req.Context())
+	out.URL = rewritten
+	out.Host = ""
+	return t.base.RoundTrip(out)
+}
+
+// ParseHeaders parses request headers of the form "Name: value", e.g.
+// "Authorization: Bearer <token>".
+func ParseHeaders(headers []string) (http.Header, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	parsed := make(http.Header)
+	for i, h := range headers {
+		// Headers carry secrets, so invalid ones are not echoed
+		name, value, ok := strings.Cut(h, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header #%d (expected Name: value)", i+1)
+		}
+		parsed.Add(name, strings.TrimSpace(value))
+	}
+	return parsed, nil
+}
+
+// ParseRewrites parses URL prefix rewrite rules of the form "from=to", e.g.
+// "https://raw.githubusercontent.com/=https://mirror.example.com/github/",
+// into a Rewrite hook. The first rule whose prefix matches applies.
+func ParseRewrites(rules []string) (func(*url.URL) *url.URL, error) {
+	type rule struct{ from, to string }
+
+	var parsed []rule
+	for _, r := range rules {
+		from, to, ok := strings.Cut(r, "=")
+		if !ok || from == "" || to == "" {
+			return nil, fmt.Errorf("invalid URL rewrite %q (expected from=to)", r)
+		}
+		if _, err := url.Parse(to); err != nil {
+			return nil, fmt.Errorf("invalid URL rewrite %q: %w", r, err)
+		}
+		parsed = append(parsed, rule{from, to})
+	}
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	return func(u *url.URL) *url.URL {
+		s := u.String()
+		for _, r := range parsed {
+			if strings.HasPrefix(s, r.from) {
+				if rewritten, err := url.Parse(r.to + strings.TrimPrefix(s, r.from)); err == nil {
+					return rewritten
+				}
+			}
+		}
+		return u
+	}, nil
+}
@@ -0,0 +1,228 @@
+package transport
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestRewrite tests routing requests to a mirror through a rewrite rule
+func TestRewrite(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	rewrite, err := ParseRewrites([]string{"https://feed.invalid/=" + server.URL + "/mirror/"})
+	if err != nil {
+		t.Fatalf("ParseRewrites() error = %v", err)
+	}
+
+	resp, err := New(Config{Rewrite: rewrite}).Get("https://feed.invalid/reports/iocs.csv")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "/mirror/reports/iocs.csv" {
+		t.Errorf("mirror received %q, want /mirror/reports/iocs.csv", body)
+	}
+
+	for _, rule := range []string{"no-separator", "=https://mirror.invalid/", "https://feed.invalid/="} {
+		if _, err := ParseRewrites([]string{rule}); err == nil {
+			t.Errorf("ParseRewrites(%q) should fail", rule)
+		}
+	}
+}
+
+// TestProxyHook tests that the proxy hook chooses the proxy for each request
+func TestProxyHook(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	var consulted bool
+	client := New(Config{Proxy: func(r *http.Request) (*url.URL, error) {
+		consulted = true
+		return proxyURL, nil
+	}})
+
+	resp, err := client.Get("http://api.invalid/v1/querybatch")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if !consulted || requested != "http://api.invalid/v1/querybatch" {
+		t.Errorf("proxy hook consulted = %v, proxy saw %q", consulted, requested)
+	}
+}
+
+// TestRetries tests that transient failures of idempotent requests are
+// retried with backoff, and other failures are not
+func TestRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := attempts.Add(1)
+		switch {
+		case r.URL.Path == "/missing":
+			http.NotFound(w, r)
+		case n < 3:
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name         string
+		method       string
+		path         string
+		retries      int
+		wantStatus   int
+		wantAttempts int32
+	}{
+		{"recovers", http.MethodGet, "/", 3, http.StatusOK, 3},
+		{"gives up", http.MethodGet, "/", 1, http.StatusServiceUnavailable, 2},
+		{"retries disabled", http.MethodGet, "/", 0, http.StatusServiceUnavailable, 1},
+		{"not found", http.MethodGet, "/missing", 3, http.StatusNotFound, 1},
+		{"post", http.MethodPost, "/", 3, http.StatusServiceUnavailable, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts.Store(0)
+			client := New(Config{Retries: tt.retries, RetryBackoff: time.Millisecond})
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("Do() error = %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus || attempts.Load() != tt.wantAttempts {
+				t.Errorf("got HTTP %d after %d attempts, want HTTP %d after %d", resp.StatusCode, attempts.Load(), tt.wantStatus, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// TestTimeout tests that a request waiting too long for the response
+// headers fails
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	if _, err := New(Config{Timeout: 20 * time.Millisecond}).Get(server.URL); err == nil {
+		t.Error("Get() of a stalled server should time out")
+	}
+}
+
+// TestTimeout_Body tests that a response stalling after its headers times
+// out while its body is read, except for streaming clients
+func TestTimeout_Body(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "Package,Version\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New(Config{Timeout: 50 * time.Millisecond, Retries: 2, RetryBackoff: time.Millisecond})
+	done := make(chan error, 1)
+	go func() {
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Reading a stalled body should time out")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Reading a stalled body did not time out")
+	}
+
+	if streaming := Streaming(client); streaming.Timeout != 0 || client.Timeout != 50*time.Millisecond {
+		t.Errorf("Streaming() timeout = %v, client timeout = %v; want 0 and unchanged", streaming.Timeout, client.Timeout)
+	}
+	if disabled := New(Config{Timeout: -1}); disabled.Timeout != 0 {
+		t.Errorf("New() with a negative timeout = %v, want no limit", disabled.Timeout)
+	}
+}
+
+// TestLoadCertPool tests trusting an extra CA certificate
+func TestLoadCertPool(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	if _, err := New(Config{}).Get(server.URL); err == nil {
+		t.Fatal("Get() of a server with an untrusted certificate should fail")
+	}
+
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(file, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := LoadCertPool([]string{file})
+	if err != nil {
+		t.Fatalf("LoadCertPool() error = %v", err)
+	}
+	resp, err := New(Config{RootCAs: pool}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() with the CA trusted error = %v", err)
+	}
+	resp.Body.Close()
+
+	if _, err := LoadCertPool([]string{filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("LoadCertPool() should fail for a missing file")
+	}
+}
+
+// TestParseHeaders tests parsing "Name: value" headers
+func TestParseHeaders(t *testing.T) {
+	header, err := ParseHeaders([]string{"Authorization: Bearer abc:def", "X-Team:payments"})
+	if err != nil {
+		t.Fatalf("ParseHeaders() error = %v", err)
+	}
+	if header.Get("Authorization") != "Bearer abc:def" || header.Get("X-Team") != "payments" {
+		t.Errorf("ParseHeaders() = %v", header)
+	}
+
+	for _, h := range []string{"Bearer secret", ": secret", "Bad Name: secret"} {
+		_, err := ParseHeaders([]string{h})
+		if err == nil {
+			t.Errorf("ParseHeaders(%q) should fail", h)
+		} else if strings.Contains(err.Error(), "secret") {
+			t.Errorf("ParseHeaders(%q) error %q echoes the header", h, err)
+		}
+	}
+}
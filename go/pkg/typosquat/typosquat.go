@@ -0,0 +1,299 @@
+// Package typosquat flags dependencies whose name is one typo away from a
+// popular npm package, the lookalike names supply-chain campaigns publish
+// to catch mistyped installs ("lodahs", "expres", "react_dom").
+//
+// A name is suspect when a single edit turns it into a popular name:
+// swapping two adjacent characters, substituting a character (noting
+// neighbouring keys on a QWERTY keyboard and lookalikes such as "1" for
+// "l"), dropping, adding or repeating one, or changing the separators
+// between words. Popular names shorter than MinLength only match on
+// separators, since nearly every short name is one edit away from another.
+//
+// The bundled list holds a few hundred of the most downloaded packages.
+// Lists can be extended, such as with internal package names that are
+// mistaken for typosquats, since names on the list are never suspect:
+//
+//	# Comments and blank lines are ignored
+//	@mycorp/logger
+//	left-pad
+package typosquat
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed popular.txt
+var bundled string
+
+// MinLength is the length from which popular names are compared by edit.
+const MinLength = 5
+
+// Edits telling how a suspect name differs from the popular one.
+const (
+	EditTransposition = "transposed characters"
+	EditAdjacentKey   = "adjacent key"
+	EditLookalike     = "lookalike character"
+	EditSubstitution  = "substituted character"
+	EditOmission      = "missing character"
+	EditAddition      = "extra character"
+	EditRepetition    = "repeated character"
+	EditSeparator     = "separators"
+)
+
+// Suspect describes a name resembling a popular package.
+type Suspect struct {
+	// Target is the popular package the name resembles
+	Target string
+	// Edit is how the name differs from Target (EditTransposition, ...)
+	Edit string
+}
+
+// Detector checks names against a ranked list of popular packages. It is
+// safe for concurrent use.
+type Detector struct {
+	// names are the popular names, most popular first
+	names []string
+	// ranks maps each popular name to its index in names
+	ranks map[string]int
+	// byLength indexes the names of at least MinLength by length
+	byLength map[int][]string
+	// bySeparators indexes names by their separator-free form
+	bySeparators map[string]string
+
+	// checked caches the results of Check by name
+	checked sync.Map
+}
+
+// result is a cached Check result.
+type result struct {
+	suspect Suspect
+	ok      bool
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultDetector *Detector
+)
+
+// Default returns the Detector of the bundled list.
+func Default() *Detector {
+	defaultOnce.Do(func() {
+		defaultDetector, _ = New(strings.Split(bundled, "\n"))
+	})
+	return defaultDetector
+}
+
+// New builds a Detector from popular names, most popular first, one per
+// element, skipping blank lines and comments.
+func New(lines []string) (*Detector, error) {
+	d := &Detector{
+		ranks:        make(map[string]int),
+		byLength:     make(map[int][]string),
+		bySeparators: make(map[string]string),
+	}
+	for _, line := range lines {
+		name := strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		if strings.ContainsAny(name, " \t*?") {
+			return nil, fmt.Errorf("invalid package name %q", name)
+		}
+		if _, ok := d.ranks[name]; ok {
+			continue
+		}
+		d.ranks[name] = len(d.names)
+		d.names = append(d.names, name)
+		if len(name) >= MinLength {
+			d.byLength[len(name)] = append(d.byLength[len(name)], name)
+		}
+		if key := stripSeparators(name); d.bySeparators[key] == "" {
+			d.bySeparators[key] = name
+		}
+	}
+	return d, nil
+}
+
+// Load returns a Detector of the bundled list followed by the names in the
+// file at path, if path is not empty, and the extra names.
+func Load(path string, extra []string) (*Detector, error) {
+	lines := strings.Split(bundled, "\n")
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read popular package list: %w", err)
+		}
+		lines = append(lines, strings.Split(string(data), "\n")...)
+	}
+	return New(append(lines, extra...))
+}
+
+// Names returns the popular names, most popular first.
+func (d *Detector) Names() []string {
+	if d == nil {
+		return nil
+	}
+	return append([]string(nil), d.names...)
+}
+
+// Check reports whether name resembles a popular package without being
+// one. Of several resembling packages, the most popular is returned.
+func (d *Detector) Check(name string) (Suspect, bool) {
+	if d == nil {
+		return Suspect{}, false
+	}
+	if _, ok := d.ranks[name]; ok {
+		return Suspect{}, false
+	}
+	if cached, ok := d.checked.Load(name); ok {
+		r := cached.(result)
+		return r.suspect, r.ok
+	}
+
+	var r result
+	if target := d.bySeparators[stripSeparators(name)]; target != "" {
+		r = result{Suspect{Target: target, Edit: EditSeparator}, true}
+	} else {
+		r = d.checkEdits(name)
+	}
+	d.checked.Store(name, r)
+	return r.suspect, r.ok
+}
+
+// checkEdits compares name with the popular names of a length one edit
+// away.
+func (d *Detector) checkEdits(name string) result {
+	best := result{}
+	bestRank := len(d.names)
+	for _, length := range []int{len(name) - 1, len(name), len(name) + 1} {
+		// Names of a length are indexed most popular first
+		for _, target := range d.byLength[length] {
+			edit, ok := singleEdit(name, target)
+			if !ok {
+				continue
+			}
+			if rank := d.ranks[target]; rank < bestRank {
+				best, bestRank = result{Suspect{Target: target, Edit: edit}, true}, rank
+			}
+			break
+		}
+	}
+	return best
+}
+
+// singleEdit returns the edit turning target into name, if there is one.
+func singleEdit(name, target string) (string, bool) {
+	switch {
+	case len(name) == len(target):
+		first := -1
+		for i := 0; i < len(name); i++ {
+			if name[i] == target[i] {
+				continue
+			}
+			if first >= 0 {
+				// A second difference is only a swap of neighbours
+				if i == first+1 && name[first] == target[i] && name[i] == target[first] && name[i+1:] == target[i+1:] {
+					return EditTransposition, true
+				}
+				return "", false
+			}
+			first = i
+		}
+		if first < 0 {
+			return "", false
+		}
+		switch a, b := target[first], name[first]; {
+		case lookalike(a, b):
+			return EditLookalike, true
+		case adjacentKeys(a, b):
+			return EditAdjacentKey, true
+		}
+		return EditSubstitution, true
+
+	case len(name) == len(target)+1:
+		i := firstDifference(name, target)
+		if name[i+1:] != target[i:] {
+			return "", false
+		}
+		if (i > 0 && name[i] == name[i-1]) || (i+1 < len(name) && name[i] == name[i+1]) {
+			return EditRepetition, true
+		}
+		return EditAddition, true
+
+	case len(name)+1 == len(target):
+		i := firstDifference(target, name)
+		if target[i+1:] != name[i:] {
+			return "", false
+		}
+		return EditOmission, true
+	}
+	return "", false
+}
+
+// firstDifference returns the first index at which longer differs from
+// shorter, which is one character shorter.
+func firstDifference(longer, shorter string) int {
+	for i := 0; i < len(shorter); i++ {
+		if longer[i] != shorter[i] {
+			return i
+		}
+	}
+	return len(shorter)
+}
+
+// separators removes the characters npm names separate words with.
+var separators = strings.NewReplacer("-", "", "_", "", ".", "")
+
+// stripSeparators returns name without separators.
+func stripSeparators(name string) string {
+	return separators.Replace(name)
+}
+
+// lookalikes pairs characters that are easily read as one another.
+var lookalikes = map[[2]byte]bool{
+	{'0', 'o'}: true,
+	{'1', 'l'}: true,
+	{'1', 'i'}: true,
+	{'i', 'l'}: true,
+	{'5', 's'}: true,
+}
+
+// lookalike reports whether a and b are easily read as one another.
+func lookalike(a, b byte) bool {
+	return lookalikes[[2]byte{a, b}] || lookalikes[[2]byte{b, a}]
+}
+
+// keyboardRows are the rows of a QWERTY keyboard. Each row is offset by
+// about half a key from the one above, so a key touches the keys at the
+// same and the previous index of the row below.
+var keyboardRows = []string{"1234567890-", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// adjacentKeys reports whether a and b are neighbouring keys.
+func adjacentKeys(a, b byte) bool {
+	for row, keys := range keyboardRows {
+		i := strings.IndexByte(keys, a)
+		if i < 0 {
+			continue
+		}
+		if (i > 0 && keys[i-1] == b) || (i+1 < len(keys) && keys[i+1] == b) {
+			return true
+		}
+		if row+1 < len(keyboardRows) {
+			below := keyboardRows[row+1]
+			if j := strings.IndexByte(below, b); j >= 0 && (j == i || j == i-1) {
+				return true
+			}
+		}
+		if row > 0 {
+			above := keyboardRows[row-1]
+			if j := strings.IndexByte(above, b); j >= 0 && (j == i || j == i+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
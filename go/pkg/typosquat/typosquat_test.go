@@ -0,0 +1,107 @@
+package typosquat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	d := Default()
+
+	tests := []struct {
+		name   string
+		target string
+		edit   string
+	}{
+		{"lodahs", "lodash", EditTransposition},
+		{"axois", "axios", EditTransposition},
+		{"expres", "express", EditOmission},
+		{"expresss", "express", EditRepetition},
+		{"reacts-dom", "react-dom", EditAddition},
+		{"lodasj", "lodash", EditAdjacentKey},
+		{"1odash", "lodash", EditLookalike},
+		{"lodakh", "lodash", EditSubstitution},
+		{"react_dom", "react-dom", EditSeparator},
+		{"reactdom", "react-dom", EditSeparator},
+		{"@types/nodee", "@types/node", EditRepetition},
+		{"@babel/cor", "@babel/core", EditOmission},
+		// The most popular of several resembling packages is reported
+		{"colors-name", "color-name", EditAddition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suspect, ok := d.Check(tt.name)
+			if !ok || suspect.Target != tt.target || suspect.Edit != tt.edit {
+				t.Errorf("Check(%q) = %+v, %v; want %s (%s)", tt.name, suspect, ok, tt.target, tt.edit)
+			}
+			// Cached results are the same
+			if again, _ := d.Check(tt.name); again != suspect {
+				t.Errorf("Check(%q) again = %+v, want %+v", tt.name, again, suspect)
+			}
+		})
+	}
+
+	for _, name := range []string{
+		"lodash",      // popular itself
+		"colors",      // popular, one edit from another popular name
+		"left-pad",    // unrelated
+		"lodash-es6x", // more than one edit
+		"qs2",         // short names only match on separators
+		"mz",
+		"@mycorp/react-dom",
+		"",
+	} {
+		if suspect, ok := d.Check(name); ok {
+			t.Errorf("Check(%q) = %+v, want no suspect", name, suspect)
+		}
+	}
+
+	var nilDetector *Detector
+	if _, ok := nilDetector.Check("lodahs"); ok {
+		t.Error("A nil Detector should flag nothing")
+	}
+}
+
+func TestAdjacentKeys(t *testing.T) {
+	for _, pair := range []string{"as", "sa", "qw", "qa", "sw", "se", "sz", "sx", "12", "1q", "2q", "bn", "hb", "az"} {
+		if !adjacentKeys(pair[0], pair[1]) {
+			t.Errorf("adjacentKeys(%q, %q) = false, want true", pair[0], pair[1])
+		}
+	}
+	for _, pair := range []string{"ad", "qs", "sc", "pm", "1w"} {
+		if adjacentKeys(pair[0], pair[1]) {
+			t.Errorf("adjacentKeys(%q, %q) = true, want false", pair[0], pair[1])
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "popular.txt")
+	if err := os.WriteFile(path, []byte("# internal packages\n@mycorp/logger\nloadash\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := Load(path, []string{"expresss"})
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	for _, name := range []string{"loadash", "expresss"} {
+		if suspect, ok := d.Check(name); ok {
+			t.Errorf("Check(%q) = %+v, want listed names never suspect", name, suspect)
+		}
+	}
+	if suspect, ok := d.Check("@mycorp/loger"); !ok || suspect.Target != "@mycorp/logger" {
+		t.Errorf("Check(@mycorp/loger) = %+v, %v; want the added name", suspect, ok)
+	}
+	if names := d.Names(); names[0] != Default().Names()[0] || names[len(names)-1] != "expresss" {
+		t.Errorf("Names() should list the bundled names first, then the file, then the extra names")
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("Load() of a missing file should fail")
+	}
+	if _, err := New([]string{"lo dash"}); err == nil {
+		t.Error("New() should reject names with whitespace")
+	}
+}
@@ -0,0 +1,272 @@
+// Package watch reruns scans as a project's dependency files change, so a
+// developer machine is rechecked the moment an install or upgrade pulls in
+// a compromised version.
+//
+// Changes are detected by polling: the manifests and lockfiles a scan would
+// discover are listed and stat'ed every interval. This costs a directory
+// walk per poll, which discovery keeps out of node_modules, but needs no
+// platform-specific notification API or additional dependency. A burst of
+// writes, such as an npm install rewriting the lockfile, is rescanned once
+// a poll finds nothing else changed.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+// DefaultInterval is the default time between polls.
+const DefaultInterval = 2 * time.Second
+
+// notifyTimeout bounds a single webhook delivery.
+const notifyTimeout = 10 * time.Second
+
+// FileState is what a poll records of a file to detect changes.
+type FileState struct {
+	ModTime time.Time
+	Size    int64
+}
+
+// Snapshot maps the paths of the watched files to their state at a poll.
+type Snapshot map[string]FileState
+
+// Options configures Run.
+type Options struct {
+	// Root is the watched directory or file
+	Root string
+	// Find configures discovery of the watched files, as for the scan
+	Find scanner.FindOptions
+	// LockfileOnly watches lockfiles only, for lockfile-only scans
+	LockfileOnly bool
+	// Interval is the time between polls. If zero, DefaultInterval is used.
+	Interval time.Duration
+}
+
+// Update is the outcome of one scan of a watch.
+type Update struct {
+	Time time.Time `json:"time"`
+	// Changed lists the files whose change triggered the scan; it is empty
+	// for the initial scan
+	Changed []string `json:"changed,omitempty"`
+	// New holds the matches the previous scan did not find; for the initial
+	// scan, every match
+	New []formatter.Match `json:"new"`
+	// Resolved holds the matches of the previous scan that are gone
+	Resolved []formatter.Match `json:"resolved"`
+	// Summary summarizes the complete result of the scan
+	Summary *formatter.Summary `json:"summary,omitempty"`
+	// Error is why the scan failed; New and Resolved are then empty and the
+	// next change is diffed against the last successful scan
+	Error string `json:"error,omitempty"`
+
+	// Result is the complete result of the scan, nil if it failed
+	Result *formatter.ScanResult `json:"-"`
+}
+
+// Take lists the files under root a scan would discover, manifests unless
+// lockfileOnly is set and lockfiles, and records their state. Files that
+// vanish while being listed are left out.
+func Take(root string, find scanner.FindOptions, lockfileOnly bool) (Snapshot, error) {
+	var paths []string
+	if !lockfileOnly {
+		manifests, err := scanner.FindManifestsWith(root, find)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, manifests...)
+	}
+	lockfiles, err := scanner.FindLockfilesWith(root, find)
+	if err != nil {
+		return nil, err
+	}
+	paths = append(paths, lockfiles...)
+
+	snapshot := make(Snapshot, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		snapshot[path] = FileState{ModTime: info.ModTime(), Size: info.Size()}
+	}
+	return snapshot, nil
+}
+
+// Changed returns the paths added, removed or modified between two
+// snapshots, sorted.
+func Changed(before, after Snapshot) []string {
+	var changed []string
+	for path, state := range after {
+		if previous, ok := before[path]; !ok || !previous.ModTime.Equal(state.ModTime) || previous.Size != state.Size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Diff returns the matches of current that previous does not have and
+// those of previous that current no longer has, compared by fingerprint. A
+// nil previous result has no matches.
+func Diff(previous, current *formatter.ScanResult) (added, resolved []formatter.Match) {
+	before := matchIDs(previous)
+	after := matchIDs(current)
+
+	added = []formatter.Match{}
+	if current != nil {
+		for _, match := range current.Matches {
+			if !before[matchID(match)] {
+				added = append(added, match)
+			}
+		}
+	}
+	resolved = []formatter.Match{}
+	if previous != nil {
+		for _, match := range previous.Matches {
+			if !after[matchID(match)] {
+				resolved = append(resolved, match)
+			}
+		}
+	}
+	return added, resolved
+}
+
+// matchIDs returns the set of match fingerprints of result.
+func matchIDs(result *formatter.ScanResult) map[string]bool {
+	ids := make(map[string]bool)
+	if result == nil {
+		return ids
+	}
+	for _, match := range result.Matches {
+		ids[matchID(match)] = true
+	}
+	return ids
+}
+
+// matchID identifies a match across scans: its fingerprint, computed if
+// the scan did not assign one.
+func matchID(match formatter.Match) string {
+	if match.Fingerprint != "" {
+		return match.Fingerprint
+	}
+	return formatter.Fingerprint(match, "")
+}
+
+// Run scans once, then polls options.Root and rescans after each change,
+// passing every scan's Update to report, until ctx is done. A failed scan
+// is reported rather than returned, so a half-written lockfile does not end
+// the watch; the next change is scanned again. Run returns ctx's error once
+// ctx is done, or an error if the watched files cannot be listed.
+func Run(ctx context.Context, options Options, scan func(ctx context.Context) (*formatter.ScanResult, error), report func(Update)) error {
+	interval := options.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	snapshot, err := Take(options.Root, options.Find, options.LockfileOnly)
+	if err != nil {
+		return err
+	}
+	var last *formatter.ScanResult
+	rescan := func(changed []string) {
+		update := Update{Time: time.Now(), Changed: changed}
+		result, err := scan(ctx)
+		if ctx.Err() != nil {
+			// Interrupted, not failed
+			return
+		}
+		if err != nil {
+			update.Error = err.Error()
+			update.New, update.Resolved = []formatter.Match{}, []formatter.Match{}
+		} else {
+			update.New, update.Resolved = Diff(last, result)
+			update.Summary = formatter.Summarize(result)
+			update.Result = result
+			last = result
+		}
+		report(update)
+	}
+	rescan(nil)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending []string
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		next, err := Take(options.Root, options.Find, options.LockfileOnly)
+		if err != nil {
+			return err
+		}
+		if changed := Changed(snapshot, next); len(changed) > 0 {
+			// Wait for the burst of writes to settle
+			pending = mergePaths(pending, changed)
+			snapshot = next
+			continue
+		}
+		if len(pending) > 0 {
+			rescan(pending)
+			pending = nil
+		}
+	}
+}
+
+// mergePaths returns the sorted union of two sorted path lists.
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	var merged []string
+	for _, path := range append(append([]string{}, a...), b...) {
+		if !seen[path] {
+			seen[path] = true
+			merged = append(merged, path)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// Notify posts update as JSON to the webhook at url. Any status other than
+// 2xx is an error.
+func Notify(ctx context.Context, client *http.Client, url string, update Update) error {
+	body, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/formatter"
+	"github.com/tuckertucker/tkr-npm-scan/go/pkg/scanner"
+)
+
+func TestChanged(t *testing.T) {
+	now := time.Now()
+	before := Snapshot{
+		"a/package.json":      {ModTime: now, Size: 10},
+		"a/package-lock.json": {ModTime: now, Size: 20},
+		"b/package.json":      {ModTime: now, Size: 30},
+	}
+
+	tests := []struct {
+		name  string
+		after Snapshot
+		want  []string
+	}{
+		{"unchanged", before, nil},
+		{
+			name: "modified, added and removed",
+			after: Snapshot{
+				"a/package.json":      {ModTime: now.Add(time.Second), Size: 10},
+				"a/package-lock.json": {ModTime: now, Size: 21},
+				"c/yarn.lock":         {ModTime: now, Size: 5},
+			},
+			want: []string{"a/package-lock.json", "a/package.json", "b/package.json", "c/yarn.lock"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Changed(before, tt.after); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Changed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	evil := formatter.Match{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: "package.json", Fingerprint: "aaaa"}
+	bad := formatter.Match{PackageName: "bad", Version: "2.0.0", Severity: formatter.SeverityTransitive, Location: "package-lock.json"}
+	worse := formatter.Match{PackageName: "worse", Version: "3.0.0", Severity: formatter.SeverityTransitive, Location: "package-lock.json"}
+
+	tests := []struct {
+		name         string
+		previous     *formatter.ScanResult
+		current      *formatter.ScanResult
+		wantAdded    []formatter.Match
+		wantResolved []formatter.Match
+	}{
+		{
+			name:         "initial scan",
+			current:      &formatter.ScanResult{Matches: []formatter.Match{evil}},
+			wantAdded:    []formatter.Match{evil},
+			wantResolved: []formatter.Match{},
+		},
+		{
+			name:         "new and resolved",
+			previous:     &formatter.ScanResult{Matches: []formatter.Match{evil, bad}},
+			current:      &formatter.ScanResult{Matches: []formatter.Match{bad, worse}},
+			wantAdded:    []formatter.Match{worse},
+			wantResolved: []formatter.Match{evil},
+		},
+		{
+			name:         "unchanged",
+			previous:     &formatter.ScanResult{Matches: []formatter.Match{evil}},
+			current:      &formatter.ScanResult{Matches: []formatter.Match{evil}},
+			wantAdded:    []formatter.Match{},
+			wantResolved: []formatter.Match{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, resolved := Diff(tt.previous, tt.current)
+			if !reflect.DeepEqual(added, tt.wantAdded) {
+				t.Errorf("added = %+v, want %+v", added, tt.wantAdded)
+			}
+			if !reflect.DeepEqual(resolved, tt.wantResolved) {
+				t.Errorf("resolved = %+v, want %+v", resolved, tt.wantResolved)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	root := t.TempDir()
+	manifest := filepath.Join(root, "package.json")
+	if err := os.WriteFile(manifest, []byte(`{"name":"app"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first scan is clean, the second fails and the third finds a match
+	evil := formatter.Match{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityDirect, Location: manifest}
+	scans := 0
+	scan := func(ctx context.Context) (*formatter.ScanResult, error) {
+		scans++
+		switch scans {
+		case 1:
+			return &formatter.ScanResult{Matches: []formatter.Match{}}, nil
+		case 2:
+			return nil, errors.New("unexpected end of JSON input")
+		}
+		return &formatter.ScanResult{Matches: []formatter.Match{evil}}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	updates := make(chan Update, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Options{Root: root, Interval: 10 * time.Millisecond}, scan, func(u Update) { updates <- u })
+	}()
+
+	touch := func(content string, age time.Duration) {
+		if err := os.WriteFile(manifest, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Distinct modification times, whatever the filesystem resolution
+		mtime := time.Now().Add(age)
+		if err := os.Chtimes(manifest, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	next := func() Update {
+		select {
+		case u := <-updates:
+			return u
+		case <-ctx.Done():
+			t.Fatal("timed out waiting for an update")
+		}
+		return Update{}
+	}
+
+	initial := next()
+	if initial.Changed != nil || len(initial.New) != 0 || initial.Summary == nil {
+		t.Errorf("initial update = %+v, want a clean scan with no changes", initial)
+	}
+
+	touch(`{"name":"app","dependencies":{`, -time.Hour)
+	failed := next()
+	if failed.Error == "" || !reflect.DeepEqual(failed.Changed, []string{manifest}) {
+		t.Errorf("update after a broken write = %+v, want an error for %s", failed, manifest)
+	}
+
+	touch(`{"name":"app","dependencies":{"evil":"1.0.1"}}`, -2*time.Hour)
+	found := next()
+	if found.Error != "" || !reflect.DeepEqual(found.New, []formatter.Match{evil}) || len(found.Resolved) != 0 {
+		t.Errorf("update after adding evil = %+v, want evil as new", found)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestRun_MissingRoot(t *testing.T) {
+	scan := func(ctx context.Context) (*formatter.ScanResult, error) {
+		t.Error("scan ran for a missing root")
+		return nil, nil
+	}
+	err := Run(context.Background(), Options{Root: filepath.Join(t.TempDir(), "missing")}, scan, func(Update) {})
+	if err == nil {
+		t.Error("Run() of a missing root succeeded, want an error")
+	}
+}
+
+func TestTake(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"package.json", "package-lock.json", "node_modules/dep/package.json", "README.md"} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		lockfileOnly bool
+		want         []string
+	}{
+		{"manifests and lockfiles", false, []string{"package-lock.json", "package.json"}},
+		{"lockfile only", true, []string{"package-lock.json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshot, err := Take(root, scanner.FindOptions{}, tt.lockfileOnly)
+			if err != nil {
+				t.Fatalf("Take() error = %v", err)
+			}
+			var got []string
+			for path := range snapshot {
+				rel, _ := filepath.Rel(root, path)
+				got = append(got, filepath.ToSlash(rel))
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Take() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotify(t *testing.T) {
+	var received Update
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(received.New) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	update := Update{
+		Time:     time.Now(),
+		Changed:  []string{"package-lock.json"},
+		New:      []formatter.Match{{PackageName: "evil", Version: "1.0.1", Severity: formatter.SeverityTransitive}},
+		Resolved: []formatter.Match{},
+	}
+	if err := Notify(context.Background(), server.Client(), server.URL, update); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if received.New[0].PackageName != "evil" || !reflect.DeepEqual(received.Changed, update.Changed) {
+		t.Errorf("webhook received %+v, want the update", received)
+	}
+
+	update.New = nil
+	if err := Notify(context.Background(), server.Client(), server.URL, update); err == nil {
+		t.Error("Notify() with a failing webhook succeeded, want an error")
+	}
+}
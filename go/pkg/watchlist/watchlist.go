@@ -0,0 +1,129 @@
+// Package watchlist flags packages by name, whatever their version, to hunt
+// for typosquats and brand-abuse packages that no IoC feed lists yet.
+// Patterns are globs, or regular expressions when prefixed with "re:":
+//
+//	# Comments and blank lines are ignored
+//	@mycorp-*
+//	*-utils-js
+//	re:^react-(dom|native)-[a-z]+-fix$
+//
+// In globs, "*" matches any run of characters (including "/", so "@corp*"
+// also matches scoped names) and "?" a single character. Both kinds of
+// patterns must match the whole package name; regular expressions are
+// anchored implicitly.
+package watchlist
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// regexpPrefix marks a pattern as a regular expression.
+const regexpPrefix = "re:"
+
+// pattern is a single compiled watchlist pattern.
+type pattern struct {
+	// text is the pattern as written, reported with matches
+	text string
+	re   *regexp.Regexp
+}
+
+// Watchlist matches package names against a set of patterns. A nil or
+// empty Watchlist matches nothing.
+type Watchlist struct {
+	patterns []pattern
+}
+
+// New compiles patterns, one per element, skipping blank lines and
+// comments.
+func New(patterns []string) (*Watchlist, error) {
+	w := &Watchlist{}
+	for _, line := range patterns {
+		text := strings.TrimSpace(strings.TrimSuffix(line, "\r"))
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var expr string
+		if strings.HasPrefix(text, regexpPrefix) {
+			expr = strings.TrimPrefix(text, regexpPrefix)
+			if expr == "" {
+				return nil, fmt.Errorf("invalid watchlist pattern %q: empty regular expression", text)
+			}
+		} else {
+			expr = globToRegexp(text)
+		}
+
+		re, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid watchlist pattern %q: %w", text, err)
+		}
+		w.patterns = append(w.patterns, pattern{text: text, re: re})
+	}
+	return w, nil
+}
+
+// Load reads the watchlist file at path, if path is not empty, followed by
+// the extra patterns.
+func Load(path string, extra []string) (*Watchlist, error) {
+	var lines []string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read watchlist: %w", err)
+		}
+		lines = strings.Split(string(data), "\n")
+	}
+	return New(append(lines, extra...))
+}
+
+// Len returns the number of patterns.
+func (w *Watchlist) Len() int {
+	if w == nil {
+		return 0
+	}
+	return len(w.patterns)
+}
+
+// Patterns returns the patterns, as written, in order.
+func (w *Watchlist) Patterns() []string {
+	if w == nil {
+		return nil
+	}
+	patterns := make([]string, len(w.patterns))
+	for i, p := range w.patterns {
+		patterns[i] = p.text
+	}
+	return patterns
+}
+
+// Match returns the first pattern matching the package name, as written.
+func (w *Watchlist) Match(name string) (string, bool) {
+	if w == nil {
+		return "", false
+	}
+	for _, p := range w.patterns {
+		if p.re.MatchString(name) {
+			return p.text, true
+		}
+	}
+	return "", false
+}
+
+// globToRegexp translates a glob into a regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
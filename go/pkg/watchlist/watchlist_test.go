@@ -0,0 +1,90 @@
+package watchlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMatch tests glob and regular expression patterns against package names
+func TestMatch(t *testing.T) {
+	w, err := New([]string{
+		"# brand abuse",
+		"@mycorp-*",
+		"",
+		"*-utils-js",
+		"re:^react-(dom|native)-[a-z]+-fix$",
+		"lod?sh",
+		"re:colou?rs",
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		wantPattern string
+	}{
+		{name: "@mycorp-tools/cli", wantPattern: "@mycorp-*"},
+		{name: "@mycorp-", wantPattern: "@mycorp-*"},
+		{name: "@mycorp/cli"},
+		{name: "string-utils-js", wantPattern: "*-utils-js"},
+		{name: "string-utils-json"},
+		{name: "react-dom-patch-fix", wantPattern: "re:^react-(dom|native)-[a-z]+-fix$"},
+		{name: "react-dom"},
+		{name: "lodash", wantPattern: "lod?sh"},
+		{name: "lodaash"},
+		{name: "colours", wantPattern: "re:colou?rs"},
+		// Regular expressions are anchored
+		{name: "supercolors"},
+		{name: "chalk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pattern, ok := w.Match(tt.name)
+			if ok != (tt.wantPattern != "") || pattern != tt.wantPattern {
+				t.Errorf("Match(%s) = %q, %v, want %q", tt.name, pattern, ok, tt.wantPattern)
+			}
+		})
+	}
+
+	if w.Len() != 5 {
+		t.Errorf("Len() = %d, want 5", w.Len())
+	}
+	var none *Watchlist
+	if _, ok := none.Match("anything"); ok || none.Len() != 0 {
+		t.Error("nil Watchlist matched")
+	}
+}
+
+// TestNew_Invalid tests that invalid patterns are rejected
+func TestNew_Invalid(t *testing.T) {
+	for _, pattern := range []string{"re:", "re:(unclosed", "re:a[b"} {
+		if _, err := New([]string{pattern}); err == nil {
+			t.Errorf("New(%q) succeeded, want an error", pattern)
+		}
+	}
+}
+
+// TestLoad tests reading a watchlist file with extra patterns
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchlist.txt")
+	if err := os.WriteFile(path, []byte("# hunt\r\n@mycorp-*\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := Load(path, []string{"*-utils-js"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for _, name := range []string{"@mycorp-x", "a-utils-js"} {
+		if _, ok := w.Match(name); !ok {
+			t.Errorf("Match(%s) = false, want true", name)
+		}
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.txt"), nil); err == nil {
+		t.Error("Load() of a missing file succeeded, want an error")
+	}
+}
@@ -0,0 +1,103 @@
+// Package yamlite holds the scalar handling shared by the small YAML subsets
+// read by this module (suppression, config and IoC database files), which
+// are parsed without a YAML dependency.
+package yamlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StripComment removes a # comment that is not inside a quoted value.
+func StripComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote == '"' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Unquote returns a scalar without its double or single quotes. Double
+// quoted scalars use Go escapes, which cover the YAML ones written by
+// this module.
+func Unquote(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return "", fmt.Errorf("invalid quoted value %s", value)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return "", fmt.Errorf("invalid quoted value %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	return value, nil
+}
+
+// FlowList returns the scalars of a flow list such as [a, "b, c"], or of
+// a lone scalar as a list of one.
+func FlowList(value string) ([]string, error) {
+	if !strings.HasPrefix(value, "[") {
+		scalar, err := Unquote(value)
+		if err != nil {
+			return nil, err
+		}
+		return []string{scalar}, nil
+	}
+	if !strings.HasSuffix(value, "]") {
+		return nil, fmt.Errorf("unterminated flow list %s", value)
+	}
+
+	var values []string
+	inner := value[1 : len(value)-1]
+	start, quote := 0, byte(0)
+	for i := 0; i <= len(inner); i++ {
+		if i < len(inner) {
+			c := inner[i]
+			switch {
+			case quote != 0:
+				if c == '\\' && quote == '"' {
+					i++
+				} else if c == quote {
+					quote = 0
+				}
+				continue
+			case c == '"' || c == '\'':
+				quote = c
+				continue
+			case c != ',':
+				continue
+			}
+		}
+		item := strings.TrimSpace(inner[start:i])
+		start = i + 1
+		if item == "" {
+			continue
+		}
+		scalar, err := Unquote(item)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, scalar)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in flow list %s", value)
+	}
+	return values, nil
+}
@@ -0,0 +1,118 @@
+/**
+ * Client for the Go scan engine's JSON-RPC mode (`npm-scan rpc`)
+ * Zero dependencies - uses child_process and readline
+ *
+ * Lets this implementation delegate detection to the Go engine, so the
+ * detection logic lives in one place. Results have the same shape as the
+ * ones returned by runScan().
+ */
+
+import { spawn } from 'node:child_process';
+import { createInterface } from 'node:readline';
+
+/**
+ * Starts a Go engine session
+ *
+ * @param {object} [options] - Engine options
+ * @param {string} [options.command] - Path to the Go npm-scan binary (default: npm-scan on PATH)
+ * @param {string[]} [options.args] - Arguments starting the RPC mode (default: ['rpc'])
+ * @returns {{scan: Function, scanInventory: Function, version: Function, close: Function}}
+ */
+export function startEngine(options = {}) {
+  const command = options.command || 'npm-scan';
+  const args = options.args || ['rpc'];
+
+  const child = spawn(command, args, { stdio: ['pipe', 'pipe', 'inherit'] });
+  const pending = new Map();
+  let nextId = 1;
+  let exitError = null;
+
+  const failAll = (error) => {
+    exitError = error;
+    for (const { reject } of pending.values()) {
+      reject(error);
+    }
+    pending.clear();
+  };
+
+  child.on('error', (error) => failAll(new Error(`Failed to start Go engine ${command}: ${error.message}`)));
+  child.on('exit', (code) => failAll(new Error(`Go engine exited with code ${code}`)));
+
+  createInterface({ input: child.stdout }).on('line', (line) => {
+    let response;
+    try {
+      response = JSON.parse(line);
+    } catch {
+      return; // Not a protocol message
+    }
+
+    const request = pending.get(response.id);
+    if (!request) {
+      return;
+    }
+    pending.delete(response.id);
+
+    if (response.error) {
+      const error = new Error(response.error.message);
+      error.code = response.error.code;
+      request.reject(error);
+    } else {
+      request.resolve(response.result);
+    }
+  });
+
+  /**
+   * Sends a request and resolves with its result
+   */
+  function call(method, params = {}) {
+    if (exitError) {
+      return Promise.reject(exitError);
+    }
+
+    const id = nextId++;
+    return new Promise((resolve, reject) => {
+      pending.set(id, { resolve, reject });
+      child.stdin.write(JSON.stringify({ jsonrpc: '2.0', id, method, params }) + '\n');
+    });
+  }
+
+  return {
+    /**
+     * Scans a directory, like runScan()
+     *
+     * @param {object} scanOptions - {path, csvUrl, offline, since, source, lockfileOnly, perProject, hygiene}
+     * @returns {Promise<object>} Scan results
+     */
+    scan: (scanOptions) => call('scan', scanOptions),
+
+    /**
+     * Scans in-memory manifests, lockfiles and resolved packages
+     *
+     * @param {object} inventory - {manifests: [{path, manifest}], lockfiles: [{path, lockfile}], packages, csvUrl, offline, since}
+     * @returns {Promise<object>} Scan results
+     */
+    scanInventory: (inventory) => call('scanInventory', inventory),
+
+    /**
+     * Returns the engine version and supported methods
+     *
+     * @returns {Promise<{version: string, methods: string[]}>}
+     */
+    version: () => call('version'),
+
+    /**
+     * Ends the session once pending requests are answered
+     *
+     * @returns {Promise<void>}
+     */
+    close: () =>
+      new Promise((resolve) => {
+        if (child.exitCode !== null || child.signalCode !== null) {
+          resolve();
+          return;
+        }
+        child.once('exit', () => resolve());
+        child.stdin.end();
+      }),
+  };
+}
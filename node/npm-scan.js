@@ -8,6 +8,7 @@ import { parseArgs } from 'node:util';
 import { resolve, relative } from 'node:path';
 import { existsSync } from 'node:fs';
 import { runScan } from './lib/scanner.js';
+import { startEngine } from './lib/engine.js';
 import { formatHumanReadable, formatJson } from './lib/formatter.js';
 import {
   readPathsFile,
@@ -56,6 +57,7 @@ OPTIONS:
   --csv-url <url>           Custom IoC CSV URL
   --lockfile-only           Only scan lockfiles, skip package.json
   --bulk <file>             Scan multiple paths from file, save to results/ directory
+  --engine <binary>         Delegate scanning to the Go engine (npm-scan rpc)
   -h, --help                Show this help message
 
 EXAMPLES:
@@ -65,6 +67,7 @@ EXAMPLES:
   npm-scan --verbose                Enable debug logging
   npm-scan --lockfile-only          Only check resolved dependencies
   npm-scan --bulk paths.txt -j -v   Scan multiple paths, save results by timestamp
+  npm-scan --engine ./go/npm-scan   Scan with the Go engine
 
 EXIT CODES:
   0  No vulnerabilities found
@@ -88,9 +91,10 @@ For more information, visit:
  * Performs bulk scanning of multiple paths
  * @param {Array} paths - Array of paths to scan
  * @param {Object} values - CLI options
+ * @param {Function} scan - Scan function (runScan or the Go engine)
  * @returns {number} - Exit code
  */
-async function runBulkScan(paths, values) {
+async function runBulkScan(paths, values, scan) {
   const timestampDir = createTimestampedDirectory('results');
   console.log(`\nBulk scan started`);
   console.log(`Results will be saved to: ${timestampDir}\n`);
@@ -131,7 +135,7 @@ async function runBulkScan(paths, values) {
       };
 
       // Run the scan
-      const results = await runScan(options, logger);
+      const results = await scan(options, logger);
 
       // Save results.json
       saveResultsJson(scanDir, results);
@@ -227,6 +231,9 @@ async function main() {
         bulk: {
           type: 'string',
         },
+        engine: {
+          type: 'string',
+        },
         help: {
           type: 'boolean',
           short: 'h',
@@ -242,6 +249,13 @@ async function main() {
       process.exit(0);
     }
 
+    // Delegate to the Go engine when requested
+    let scan = runScan;
+    if (values.engine) {
+      const engine = startEngine({ command: values.engine });
+      scan = (options) => engine.scan(options);
+    }
+
     // Handle bulk scanning mode
     if (values.bulk) {
       if (!existsSync(values.bulk)) {
@@ -254,7 +268,7 @@ async function main() {
         throw new Error(`No valid paths found in ${values.bulk}`);
       }
 
-      const exitCode = await runBulkScan(paths, values);
+      const exitCode = await runBulkScan(paths, values, scan);
       process.exit(exitCode);
     }
 
@@ -271,7 +285,7 @@ async function main() {
     const logger = createLogger(values.verbose);
 
     // Run the scan
-    const results = await runScan(options, logger);
+    const results = await scan(options, logger);
 
     // Format and output results
     if (values.json) {
@@ -0,0 +1,42 @@
+/**
+ * Tests for the Go engine client using Node.js test runner
+ */
+
+import { test } from 'node:test';
+import assert from 'node:assert/strict';
+import { startEngine } from '../lib/engine.js';
+
+// Fake engine answering the JSON-RPC protocol of `npm-scan rpc`
+const fakeEngine = `
+const rl = require('node:readline').createInterface({ input: process.stdin });
+rl.on('line', (line) => {
+  const req = JSON.parse(line);
+  let resp;
+  if (req.method === 'version') {
+    resp = { result: { version: '1.2.3', methods: ['version', 'scan', 'scanInventory'] } };
+  } else if (req.method === 'scan' && req.params.path) {
+    resp = { result: { matches: [{ packageName: 'evil', version: '1.0.1', severity: 'DIRECT', location: req.params.path + '/package.json' }] } };
+  } else {
+    resp = { error: { code: -32602, message: 'path is required' } };
+  }
+  process.stdout.write(JSON.stringify({ jsonrpc: '2.0', id: req.id, ...resp }) + '\\n');
+});
+`;
+
+test('startEngine() should delegate requests to the engine', async () => {
+  const engine = startEngine({ command: process.execPath, args: ['-e', fakeEngine] });
+
+  const [version, results] = await Promise.all([engine.version(), engine.scan({ path: '/project' })]);
+  assert.equal(version.version, '1.2.3');
+  assert.deepEqual(results.matches, [
+    { packageName: 'evil', version: '1.0.1', severity: 'DIRECT', location: '/project/package.json' },
+  ]);
+
+  await assert.rejects(engine.scan({}), { message: 'path is required', code: -32602 });
+  await engine.close();
+});
+
+test('startEngine() should reject requests when the engine cannot start', async () => {
+  const engine = startEngine({ command: '/nonexistent/npm-scan' });
+  await assert.rejects(engine.version(), /Failed to start Go engine/);
+});